@@ -2,31 +2,47 @@ package main
 
 import (
 	"context"
+	"crypto/rand"
+	"errors"
 	"log"
 	"net/http"
 	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/joho/godotenv"
 
+	"firelevel-backend/internal/aiactions"
 	"firelevel-backend/internal/auth"
+	"firelevel-backend/internal/backboard"
 	"firelevel-backend/internal/calendar"
 	"firelevel-backend/internal/calendarevents"
 	"firelevel-backend/internal/chat"
+	"firelevel-backend/internal/checkins"
 	"firelevel-backend/internal/database"
 	"firelevel-backend/internal/focus"
 	"firelevel-backend/internal/gcalendar"
 	"firelevel-backend/internal/gmail"
+	"firelevel-backend/internal/llm"
 	"firelevel-backend/internal/notifications"
 	"firelevel-backend/internal/onboarding"
+	"firelevel-backend/internal/proactive"
 	"firelevel-backend/internal/routines"
+	"firelevel-backend/internal/stats"
+	"firelevel-backend/internal/streak"
 	"firelevel-backend/internal/users"
+	"firelevel-backend/internal/weeklygoals"
 	"firelevel-backend/internal/quests"
+	"firelevel-backend/internal/reminders"
 	"firelevel-backend/internal/discover"
 	"firelevel-backend/internal/focusrooms"
 	"firelevel-backend/internal/challenges"
 	"firelevel-backend/internal/voice"
+	"firelevel-backend/internal/whatsapp"
 )
 
 // ===========================================
@@ -47,6 +63,10 @@ import (
 func main() {
 	_ = godotenv.Load()
 
+	// Cancelled on SIGINT/SIGTERM so background workers and the server stop cleanly
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
 	// 1. Initialize Postgres Pool
 	pool, err := database.NewPool(context.Background())
 	if err != nil {
@@ -67,12 +87,35 @@ func main() {
 
 	// 3. Initialize Handlers
 	usersHandler := users.NewHandler(pool)
+	devMode := os.Getenv("ENV") == "development"
+	if otpSecret := os.Getenv("PHONE_OTP_SECRET"); otpSecret != "" {
+		usersHandler.SetOTPSecret([]byte(otpSecret))
+	} else if devMode {
+		// Pending codes won't survive a restart (they expire within minutes anyway)
+		log.Println("⚠️ PHONE_OTP_SECRET not set — using a random key for phone linking codes")
+		key := make([]byte, 32)
+		if _, err := rand.Read(key); err != nil {
+			log.Fatalf("Failed to generate OTP key: %v", err)
+		}
+		usersHandler.SetOTPSecret(key)
+	} else {
+		log.Fatal("PHONE_OTP_SECRET is not set in .env")
+	}
+	if devMode {
+		// Replaced by the WhatsApp client below when it is configured
+		usersHandler.SetOTPSender(users.LogOTPSender{})
+	}
 	routinesHandler := routines.NewHandler(pool)
 	completionsHandler := routines.NewCompletionHandler(pool)
 	focusHandler := focus.NewHandler(pool)
 	onboardingHandler := onboarding.NewHandler(pool)
 	calendarHandler := calendar.NewHandler(pool)
-	chatHandler := chat.NewHandler(pool)
+	toolRegistry, err := backboard.NewRegistry()
+	if err != nil {
+		log.Fatalf("Failed to build the AI tool registry: %v", err)
+	}
+	chatHandler := chat.NewHandler(pool, toolRegistry)
+	usersHandler.SetMemoryEraser(chatHandler)
 
 	// Initialize Backboard API key for the new AI chat handler
 	if bbKey := os.Getenv("BACKBOARD_API_KEY"); bbKey != "" {
@@ -81,15 +124,90 @@ func main() {
 	} else {
 		log.Println("⚠️ BACKBOARD_API_KEY not set — /chat/v2 endpoints will be unavailable")
 	}
+
+	// Chat model for /chat/v2: Backboard unless LLM_PROVIDER says otherwise
+	switch provider := os.Getenv("LLM_PROVIDER"); provider {
+	case "", "backboard":
+	case "gemini":
+		chatHandler.SetLLMProvider(llm.NewGemini(os.Getenv("GEMINI_API_KEY"), os.Getenv("GEMINI_MODEL")))
+		log.Println("✅ /chat/v2 running on Gemini")
+	default:
+		log.Fatalf("Unknown LLM_PROVIDER %q (expected backboard or gemini)", provider)
+	}
 	notificationsHandler := notifications.NewHandler(pool)
+
+	// Push notifications: APNs when configured, log-only otherwise
+	var pusher notifications.Pusher = notifications.LogPusher{}
+	if apnsKey := os.Getenv("APNS_PRIVATE_KEY"); apnsKey != "" {
+		apnsClient, err := notifications.NewAPNsClient(notifications.APNsConfig{
+			KeyID:      os.Getenv("APNS_KEY_ID"),
+			TeamID:     os.Getenv("APNS_TEAM_ID"),
+			BundleID:   os.Getenv("APNS_BUNDLE_ID"),
+			PrivateKey: apnsKey,
+			Production: os.Getenv("APNS_PRODUCTION") == "true",
+		})
+		if err != nil {
+			log.Fatalf("Failed to init APNs client: %v", err)
+		}
+		pusher = apnsClient
+		log.Println("✅ APNs client loaded")
+	} else {
+		log.Println("⚠️ APNS_PRIVATE_KEY not set — push notifications will only be logged")
+	}
+	pushDispatcher := notifications.NewDispatcher(pool, pusher)
+	notificationsHandler.SetDispatcher(pushDispatcher)
 	gmailHandler := gmail.NewHandler(pool)
 	questsHandler := quests.NewHandler(pool)
 	voiceHandler := voice.NewHandler(jwtSecret)
 	gcalendarHandler := gcalendar.NewHandler(pool)
 	calendarEventsHandler := calendarevents.NewHandler(pool)
+	if credKey := os.Getenv("CALENDAR_CREDENTIALS_KEY"); credKey != "" {
+		key, err := calendarevents.ParseCredentialKey(credKey)
+		if err != nil {
+			log.Fatalf("Invalid CALENDAR_CREDENTIALS_KEY: %v", err)
+		}
+		calendarEventsHandler.SetCredentialKey(key)
+	} else {
+		log.Println("⚠️ CALENDAR_CREDENTIALS_KEY not set — CalDAV accounts with a password can't be added")
+	}
+	calendarHandler.SetGoogleCalendarSyncer(gcalendarHandler)
+	calendarEventsHandler.RegisterSyncer(calendarevents.ProviderGoogle, gcalendarHandler)
 	discoverHandler := discover.NewHandler(pool)
 	focusRoomsHandler := focusrooms.NewHandler(pool)
 	challengesHandler := challenges.NewHandler(pool)
+	streakHandler := streak.NewHandler(pool)
+	proactiveHandler := proactive.NewHandler(pool)
+	checkinsHandler := checkins.NewHandler(pool)
+	weeklyGoalsHandler := weeklygoals.NewHandler(pool)
+	statsHandler := stats.NewHandler(pool)
+	aiActionsHandler := aiactions.NewHandler(pool)
+
+	// WhatsApp channel: only wired when the Cloud API is configured
+	var whatsappHandler *whatsapp.Handler
+	whatsappConfig := whatsapp.Config{
+		PhoneNumberID: os.Getenv("WHATSAPP_PHONE_NUMBER_ID"),
+		AccessToken:   os.Getenv("WHATSAPP_ACCESS_TOKEN"),
+		AppSecret:     os.Getenv("WHATSAPP_APP_SECRET"),
+		VerifyToken:   os.Getenv("WHATSAPP_VERIFY_TOKEN"),
+		BaseURL:       os.Getenv("WHATSAPP_API_URL"),
+	}
+	if whatsappConfig.AccessToken != "" {
+		whatsappClient, err := whatsapp.NewClient(whatsappConfig)
+		if err != nil {
+			log.Fatalf("Failed to init WhatsApp client: %v", err)
+		}
+		if whatsappConfig.AppSecret == "" {
+			log.Println("⚠️ WHATSAPP_APP_SECRET not set — WhatsApp webhooks will be rejected")
+		}
+		whatsappHandler = whatsapp.NewHandler(pool, whatsappClient, chatHandler, whatsappConfig)
+		usersHandler.SetOTPSender(whatsappClient)
+		log.Println("✅ WhatsApp client loaded")
+	} else {
+		log.Println("⚠️ WHATSAPP_ACCESS_TOKEN not set — WhatsApp channel disabled")
+		if !devMode {
+			log.Println("⚠️ No OTP sender — phone linking will be unavailable")
+		}
+	}
 
 	// 4. Setup Router
 	r := chi.NewRouter()
@@ -107,7 +225,7 @@ func main() {
 			origin := r.Header.Get("Origin")
 			if allowedOrigins[origin] {
 				w.Header().Set("Access-Control-Allow-Origin", origin)
-			} else if os.Getenv("ENV") == "development" {
+			} else if devMode {
 				w.Header().Set("Access-Control-Allow-Origin", "*")
 			}
 			w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PATCH, PUT, DELETE, OPTIONS")
@@ -128,6 +246,13 @@ func main() {
 		w.Write([]byte("v3.0-backboard-server"))
 	})
 
+	// Webhooks (authenticated by their own tokens)
+	r.Post("/webhooks/google-calendar", gcalendarHandler.Webhook)
+	if whatsappHandler != nil {
+		r.Get("/webhooks/whatsapp", whatsappHandler.Verify)
+		r.Post("/webhooks/whatsapp", whatsappHandler.Webhook)
+	}
+
 	// Protected Routes
 	r.Group(func(r chi.Router) {
 		r.Use(authMW)
@@ -143,9 +268,22 @@ func main() {
 
 		// Backboard-powered chat (v2) — tools run server-side
 		r.Post("/chat/v2/message", chatHandler.SendMessageV2)
+		r.Post("/chat/v2/message/stream", chatHandler.StreamMessageV2)
 		r.Post("/chat/v2/voice", chatHandler.SendVoiceMessageV2)
 		r.Get("/chat/v2/history", chatHandler.GetHistoryV2)
+		r.Get("/chat/v2/search", chatHandler.SearchHistoryV2)
 		r.Delete("/chat/v2/history", chatHandler.ClearHistoryV2)
+		r.Post("/chat/v2/confirmations/{id}", chatHandler.ConfirmToolCallsV2)
+
+		// Memories (what Kai remembers, stored on the Backboard assistant)
+		r.Get("/chat/memories", chatHandler.ListMemories)
+		r.Get("/chat/memories/export", chatHandler.ExportMemories)
+		r.Post("/chat/memories", chatHandler.CreateMemory)
+		r.Patch("/chat/memories/{id}", chatHandler.UpdateMemory)
+		r.Delete("/chat/memories/{id}", chatHandler.DeleteMemory)
+		r.Delete("/chat/memories", chatHandler.ForgetMemories)
+		r.Get("/chat/v2/actions", aiActionsHandler.ListActions)
+		r.Post("/chat/v2/actions/{id}/undo", aiActionsHandler.UndoAction)
 
 		// =====================
 		// USER PROFILE
@@ -157,6 +295,9 @@ func main() {
 		r.Delete("/me/avatar", usersHandler.DeleteAvatar)
 		r.Put("/me/email", usersHandler.ChangeEmail)
 		r.Put("/me/password", usersHandler.ChangePassword)
+		r.Post("/me/phone", usersHandler.RequestPhoneLink)
+		r.Post("/me/phone/verify", usersHandler.VerifyPhoneLink)
+		r.Delete("/me/phone", usersHandler.UnlinkPhone)
 
 		// =====================
 		// TASKS (Calendar)
@@ -164,6 +305,9 @@ func main() {
 		r.Get("/calendar/tasks", calendarHandler.ListTasks)
 		r.Post("/calendar/tasks", calendarHandler.CreateTask)
 		r.Patch("/calendar/tasks/{id}", calendarHandler.UpdateTask)
+		r.Patch("/calendar/tasks/{id}/reschedule", calendarHandler.RescheduleTask)
+		r.Post("/calendar/auto-schedule", calendarHandler.ProposeAutoSchedule)
+		r.Post("/calendar/auto-schedule/{id}/accept", calendarHandler.AcceptAutoSchedule)
 		r.Post("/calendar/tasks/{id}/complete", calendarHandler.CompleteTask)
 		r.Post("/calendar/tasks/{id}/uncomplete", calendarHandler.UncompleteTask)
 		r.Delete("/calendar/tasks/{id}", calendarHandler.DeleteTask)
@@ -204,6 +348,7 @@ func main() {
 		r.Delete("/notifications/device-token", notificationsHandler.DeleteToken)
 		r.Get("/notifications/settings", notificationsHandler.GetSettings)
 		r.Patch("/notifications/settings", notificationsHandler.UpdateSettings)
+		r.Post("/notifications/test", notificationsHandler.SendTest)
 
 		// =====================
 		// QUESTS
@@ -235,12 +380,17 @@ func main() {
 		r.Delete("/google-calendar/config", gcalendarHandler.Disconnect)
 		r.Post("/google-calendar/sync", gcalendarHandler.Sync)
 		r.Get("/google-calendar/check-weekly", gcalendarHandler.CheckWeekly)
+		r.Get("/google-calendar/calendars", gcalendarHandler.ListCalendars)
 
 		// =====================
 		// CALENDAR EVENTS (cached external events)
 		// =====================
 		r.Get("/calendar/events", calendarEventsHandler.ListEvents)
 		r.Get("/calendar/providers", calendarEventsHandler.ListProviders)
+		r.Post("/calendar/providers", calendarEventsHandler.CreateProvider)
+		r.Delete("/calendar/providers/{id}", calendarEventsHandler.DeleteProvider)
+		r.Patch("/calendar/sources/{id}", calendarEventsHandler.UpdateSource)
+		r.Post("/calendar/sync-events", calendarEventsHandler.SyncEvents)
 		r.Get("/calendar/blocking-schedule", calendarEventsHandler.GetBlockingSchedule)
 		r.Patch("/calendar/events/{id}/blocking", calendarEventsHandler.UpdateBlocking)
 
@@ -262,16 +412,126 @@ func main() {
 		r.Get("/focus-rooms/{id}", focusRoomsHandler.Get)
 		r.Post("/focus-rooms/{id}/leave", focusRoomsHandler.Leave)
 
+		// =====================
+		// STREAK
+		// =====================
+		r.Get("/streak", streakHandler.GetStreak)
+		r.Get("/streak/history", streakHandler.GetHistory)
+
+		// =====================
+		// STATS & DASHBOARD
+		// =====================
+		r.Get("/dashboard", statsHandler.Dashboard)
+		r.Get("/firemode", statsHandler.FireMode)
+
+		// =====================
+		// PROACTIVE COACH
+		// =====================
+		r.Get("/coach/settings", proactiveHandler.GetSettings)
+		r.Patch("/coach/settings", proactiveHandler.UpdateSettings)
+
+		// =====================
+		// CHECK-INS & REFLECTIONS
+		// =====================
+		r.Get("/checkins/morning", checkinsHandler.ListMorning)
+		r.Get("/checkins/morning/{date}", checkinsHandler.GetMorning)
+		r.Put("/checkins/morning/{date}", checkinsHandler.SaveMorning)
+		r.Delete("/checkins/morning/{date}", checkinsHandler.DeleteMorning)
+		r.Get("/checkins/evening", checkinsHandler.ListEvening)
+		r.Get("/checkins/evening/{date}", checkinsHandler.GetEvening)
+		r.Put("/checkins/evening/{date}", checkinsHandler.SaveEvening)
+		r.Delete("/checkins/evening/{date}", checkinsHandler.DeleteEvening)
+		r.Get("/checkins/mood", checkinsHandler.GetMood)
+		r.Get("/reflections", checkinsHandler.ListReflections)
+		r.Get("/reflections/{date}", checkinsHandler.GetReflection)
+		r.Put("/reflections/{date}", checkinsHandler.UpsertReflection)
+
+		// =====================
+		// WEEKLY GOALS
+		// =====================
+		r.Get("/weekly-goals", weeklyGoalsHandler.GetWeek)
+		r.Get("/weekly-goals/history", weeklyGoalsHandler.GetHistory)
+		r.Post("/weekly-goals/items", weeklyGoalsHandler.CreateItem)
+		r.Patch("/weekly-goals/items/{id}", weeklyGoalsHandler.UpdateItem)
+		r.Delete("/weekly-goals/items/{id}", weeklyGoalsHandler.DeleteItem)
+		r.Post("/weekly-goals/items/{id}/complete", weeklyGoalsHandler.CompleteItem)
+		r.Delete("/weekly-goals/items/{id}/complete", weeklyGoalsHandler.UncompleteItem)
+		r.Put("/weekly-goals/order", weeklyGoalsHandler.Reorder)
+		r.Post("/weekly-goals/review", weeklyGoalsHandler.Review)
+
 		// =====================
 		// WAKE-UP CHALLENGES
 		// =====================
 		challengesHandler.Routes(r)
 	})
 
+	// 5. Background workers
+	var workers sync.WaitGroup
+	ritualScheduler := reminders.NewScheduler(pool, pushDispatcher)
+	workers.Add(1)
+	go func() {
+		defer workers.Done()
+		ritualScheduler.Run(ctx)
+	}()
+	proactiveEngine := proactive.NewEngine(pool, pushDispatcher)
+	if geminiKey := os.Getenv("GEMINI_API_KEY"); geminiKey != "" {
+		proactiveEngine.SetWriter(llm.NewGemini(geminiKey, os.Getenv("GEMINI_MODEL")))
+	} else {
+		log.Println("⚠️ GEMINI_API_KEY not set — proactive coach messages will use templates")
+	}
+	workers.Add(1)
+	go func() {
+		defer workers.Done()
+		proactiveEngine.Run(ctx)
+	}()
+	calendarSyncWorker := calendarevents.NewSyncWorker(calendarEventsHandler)
+	workers.Add(1)
+	go func() {
+		defer workers.Done()
+		calendarSyncWorker.Run(ctx)
+	}()
+	calendarChannelWorker := gcalendar.NewChannelWorker(gcalendarHandler)
+	workers.Add(1)
+	go func() {
+		defer workers.Done()
+		calendarChannelWorker.Run(ctx)
+	}()
+	otpSweeper := users.NewOTPSweeper(usersHandler)
+	workers.Add(1)
+	go func() {
+		defer workers.Done()
+		otpSweeper.Run(ctx)
+	}()
+
 	port := os.Getenv("PORT")
 	if port == "" {
 		port = "8080"
 	}
+	server := &http.Server{Addr: ":" + port, Handler: r}
+
+	workers.Add(1)
+	go func() {
+		defer workers.Done()
+		<-ctx.Done()
+		log.Println("🛑 Shutting down...")
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			log.Printf("HTTP server shutdown error: %v", err)
+		}
+		// WhatsApp replies run after the webhook returned
+		if whatsappHandler != nil {
+			if err := whatsappHandler.Wait(shutdownCtx); err != nil {
+				log.Printf("WhatsApp replies still running at shutdown: %v", err)
+			}
+		}
+	}()
+
 	log.Printf("🔥 Kai Backend starting on :%s", port)
-	log.Fatal(http.ListenAndServe(":"+port, r))
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		log.Fatal(err)
+	}
+
+	workers.Wait()
+	log.Println("👋 Kai Backend stopped")
 }
@@ -0,0 +1,339 @@
+package aiactions
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"firelevel-backend/internal/tools"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// ==========================================
+// AI ACTIONS
+// Audit log of the writes Kai made through tools. Each action keeps
+// full-row snapshots of what it changed, so it can be reverted as long
+// as nobody touched those rows since.
+// ==========================================
+
+var (
+	ErrNotFound      = errors.New("action not found")
+	ErrAlreadyUndone = errors.New("action already undone")
+	// ErrConflict means a row was modified after the action; undoing
+	// would overwrite the user's own change.
+	ErrConflict = errors.New("rows changed since the action")
+	// ErrNotUndoable means the action touched a table undo does not
+	// handle, or a snapshot is missing.
+	ErrNotUndoable = errors.New("action cannot be undone")
+)
+
+// refreshByTable lists the tables undo may write to, with what the app
+// has to reload afterwards. Other tables (weekly_goal_items has no
+// user_id, user_onboarding is keyed on it) are recorded but not undone.
+var refreshByTable = map[string][]tools.SideEffect{
+	"tasks":               {tools.RefreshTasks, tools.CalendarNeedsRefresh},
+	"routines":            {tools.RefreshRituals},
+	"routine_completions": {tools.RefreshRituals},
+	"calendar_events":     {tools.NewSideEffect("refresh_calendar_events")},
+	"morning_checkins":    {tools.NewSideEffect("refresh_reflection")},
+	"evening_checkins":    {tools.NewSideEffect("refresh_reflection")},
+}
+
+// syncColumns are written by background syncs after an action took its
+// snapshot: the Google Calendar push links a task to its event. They are
+// not the user's edits, so they don't block an undo.
+var syncColumns = map[string][]string{
+	"tasks": {"google_event_id", "updated_at"},
+}
+
+// linkColumns are kept as they are when a row is put back: the task stays
+// linked to its Google event, which the next push moves back too.
+var linkColumns = map[string][]string{
+	"tasks": {"google_event_id"},
+}
+
+// Action is one tool call that wrote to the database.
+type Action struct {
+	ID         string          `json:"id"`
+	UserID     string          `json:"-"`
+	Tool       string          `json:"tool"`
+	Arguments  json.RawMessage `json:"arguments"`
+	Summary    string          `json:"summary"`
+	Changes    []tools.Change  `json:"changes"`
+	ThreadID   string          `json:"thread_id,omitempty"`
+	RunID      string          `json:"run_id,omitempty"`
+	ToolCallID string          `json:"tool_call_id,omitempty"`
+	Status     string          `json:"status"` // applied, undone
+	UndoneAt   *time.Time      `json:"undone_at,omitempty"`
+	CreatedAt  time.Time       `json:"created_at"`
+}
+
+// Undoable reports whether Undo handles every table of changes.
+func Undoable(changes []tools.Change) bool {
+	for _, c := range changes {
+		if _, ok := refreshByTable[c.Table]; !ok {
+			return false
+		}
+	}
+	return len(changes) > 0
+}
+
+// UndoAvailable tells the app it can offer to undo the action (snackbar).
+func UndoAvailable(actionID, summary string) tools.SideEffect {
+	return tools.NewSideEffectWithData("undo_available", map[string]string{
+		"action_id": actionID,
+		"summary":   summary,
+	})
+}
+
+// Record stores a: the action has already been applied.
+func Record(ctx context.Context, db *pgxpool.Pool, a Action) (string, error) {
+	changes, err := json.Marshal(a.Changes)
+	if err != nil {
+		return "", fmt.Errorf("marshal changes: %w", err)
+	}
+	args := a.Arguments
+	if !json.Valid(args) {
+		args = json.RawMessage("{}")
+	}
+
+	var id string
+	err = db.QueryRow(ctx, `
+		INSERT INTO public.ai_actions (user_id, tool, arguments, summary, changes, thread_id, run_id, tool_call_id)
+		VALUES ($1, $2, $3, $4, $5, NULLIF($6, ''), NULLIF($7, ''), NULLIF($8, ''))
+		RETURNING id
+	`, a.UserID, a.Tool, args, a.Summary, changes, a.ThreadID, a.RunID, a.ToolCallID).Scan(&id)
+	return id, err
+}
+
+const actionColumns = `id, tool, arguments, summary, changes, COALESCE(thread_id, ''), COALESCE(run_id, ''),
+	COALESCE(tool_call_id, ''), status, undone_at, created_at`
+
+func scanAction(row pgx.Row) (*Action, error) {
+	var a Action
+	var changes []byte
+	if err := row.Scan(&a.ID, &a.Tool, &a.Arguments, &a.Summary, &changes, &a.ThreadID, &a.RunID,
+		&a.ToolCallID, &a.Status, &a.UndoneAt, &a.CreatedAt); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(changes, &a.Changes); err != nil {
+		return nil, fmt.Errorf("decode changes of action %s: %w", a.ID, err)
+	}
+	return &a, nil
+}
+
+// List returns the user's most recent actions, newest first.
+func List(ctx context.Context, db *pgxpool.Pool, userID string, limit int) ([]Action, error) {
+	rows, err := db.Query(ctx, `
+		SELECT `+actionColumns+`
+		FROM public.ai_actions
+		WHERE user_id = $1
+		ORDER BY created_at DESC
+		LIMIT $2
+	`, userID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	actions := []Action{}
+	for rows.Next() {
+		a, err := scanAction(rows)
+		if err != nil {
+			return nil, err
+		}
+		a.UserID = userID
+		actions = append(actions, *a)
+	}
+	return actions, rows.Err()
+}
+
+// Undo reverts every change of the action, newest first, in one
+// transaction. It returns the side effects the app needs to refresh.
+// Indirect effects of the tool (streak days, Google Calendar sync) are
+// not reverted.
+func Undo(ctx context.Context, db *pgxpool.Pool, userID, actionID string) (*Action, []tools.SideEffect, error) {
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	a, err := scanAction(tx.QueryRow(ctx, `
+		SELECT `+actionColumns+`
+		FROM public.ai_actions
+		WHERE id = $1 AND user_id = $2
+		FOR UPDATE
+	`, actionID, userID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	a.UserID = userID
+	if a.Status == "undone" {
+		return nil, nil, ErrAlreadyUndone
+	}
+
+	var effects []tools.SideEffect
+	seen := map[string]bool{}
+	for i := len(a.Changes) - 1; i >= 0; i-- {
+		c := a.Changes[i]
+		refresh, ok := refreshByTable[c.Table]
+		if !ok {
+			return nil, nil, fmt.Errorf("%w: table %s", ErrNotUndoable, c.Table)
+		}
+		if err := revert(ctx, tx, userID, c); err != nil {
+			return nil, nil, err
+		}
+		for _, se := range refresh {
+			if !seen[se.Type] {
+				seen[se.Type] = true
+				effects = append(effects, se)
+			}
+		}
+	}
+
+	if err := tx.QueryRow(ctx, `
+		UPDATE public.ai_actions SET status = 'undone', undone_at = now()
+		WHERE id = $1
+		RETURNING status, undone_at
+	`, a.ID).Scan(&a.Status, &a.UndoneAt); err != nil {
+		return nil, nil, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, nil, err
+	}
+	return a, effects, nil
+}
+
+// revert puts one row back in its Before state.
+func revert(ctx context.Context, tx pgx.Tx, userID string, c tools.Change) error {
+	table := pgx.Identifier{"public", c.Table}.Sanitize()
+
+	switch {
+	case c.Before == nil && c.After == nil:
+		return fmt.Errorf("%w: no snapshot of %s %s", ErrNotUndoable, c.Table, c.RowID)
+
+	case c.Before == nil:
+		// Created by the tool: delete it, unless it was edited since
+		if err := checkUnchanged(ctx, tx, table, c); err != nil {
+			return err
+		}
+		_, err := tx.Exec(ctx, fmt.Sprintf("DELETE FROM %s WHERE id = $1 AND user_id = $2", table), c.RowID, userID)
+		return err
+
+	case c.After == nil:
+		// Deleted by the tool: insert the snapshot back
+		if err := checkOwner(c, userID); err != nil {
+			return err
+		}
+		cols, err := snapshotColumns(c.Before)
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec(ctx, fmt.Sprintf(
+			"INSERT INTO %s (%s) SELECT %s FROM jsonb_populate_record(NULL::%s, $1)",
+			table, cols, cols, table,
+		), c.Before)
+		if err != nil {
+			return fmt.Errorf("%w: restore %s %s: %v", ErrConflict, c.Table, c.RowID, err)
+		}
+		return nil
+
+	default:
+		if err := checkUnchanged(ctx, tx, table, c); err != nil {
+			return err
+		}
+		cols, err := snapshotColumns(c.Before, linkColumns[c.Table]...)
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec(ctx, fmt.Sprintf(
+			"UPDATE %s SET (%s) = (SELECT %s FROM jsonb_populate_record(NULL::%s, $1)) WHERE id = $2 AND user_id = $3",
+			table, cols, cols, table,
+		), c.Before, c.RowID, userID)
+		return err
+	}
+}
+
+// checkUnchanged makes sure the row is still exactly as the action left it,
+// sync columns aside.
+func checkUnchanged(ctx context.Context, tx pgx.Tx, table string, c tools.Change) error {
+	var current json.RawMessage
+	err := tx.QueryRow(ctx,
+		fmt.Sprintf("SELECT to_jsonb(t) FROM %s t WHERE id = $1 FOR UPDATE", table),
+		c.RowID,
+	).Scan(&current)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return fmt.Errorf("%w: %s %s", ErrConflict, c.Table, c.RowID)
+	}
+	if err != nil {
+		return err
+	}
+	same, err := sameRow(c.Table, current, c.After)
+	if err != nil {
+		return fmt.Errorf("%w: bad snapshot: %v", ErrNotUndoable, err)
+	}
+	if !same {
+		return fmt.Errorf("%w: %s %s", ErrConflict, c.Table, c.RowID)
+	}
+	return nil
+}
+
+// sameRow compares two snapshots of a row of table, ignoring its sync columns.
+func sameRow(table string, current, after json.RawMessage) (bool, error) {
+	var a, b map[string]interface{}
+	if err := json.Unmarshal(current, &a); err != nil {
+		return false, err
+	}
+	if err := json.Unmarshal(after, &b); err != nil {
+		return false, err
+	}
+	for _, col := range syncColumns[table] {
+		delete(a, col)
+		delete(b, col)
+	}
+	return reflect.DeepEqual(a, b), nil
+}
+
+// checkOwner guards restores: the snapshot must belong to the user.
+func checkOwner(c tools.Change, userID string) error {
+	var row struct {
+		UserID string `json:"user_id"`
+	}
+	if err := json.Unmarshal(c.Before, &row); err != nil || row.UserID != userID {
+		return fmt.Errorf("%w: %s %s", ErrNotUndoable, c.Table, c.RowID)
+	}
+	return nil
+}
+
+// snapshotColumns returns the quoted column list of a row snapshot, in a
+// stable order, without the skipped ones. The "id" column is kept so
+// restored rows keep their ID.
+func snapshotColumns(snapshot json.RawMessage, skip ...string) (string, error) {
+	var row map[string]json.RawMessage
+	if err := json.Unmarshal(snapshot, &row); err != nil {
+		return "", fmt.Errorf("%w: bad snapshot: %v", ErrNotUndoable, err)
+	}
+	for _, name := range skip {
+		delete(row, name)
+	}
+	names := make([]string, 0, len(row))
+	for name := range row {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for i, name := range names {
+		names[i] = pgx.Identifier{name}.Sanitize()
+	}
+	return strings.Join(names, ", "), nil
+}
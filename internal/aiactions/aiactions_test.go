@@ -0,0 +1,77 @@
+package aiactions
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"firelevel-backend/internal/tools"
+)
+
+func TestUndoable(t *testing.T) {
+	task := tools.Change{Table: "tasks", RowID: "t1"}
+	checkin := tools.Change{Table: "evening_checkins", RowID: "e1"}
+	item := tools.Change{Table: "weekly_goal_items", RowID: "i1"}
+
+	cases := []struct {
+		name    string
+		changes []tools.Change
+		want    bool
+	}{
+		{"no changes", nil, false},
+		{"undoable tables", []tools.Change{task, checkin}, true},
+		{"one table undo does not handle", []tools.Change{task, item}, false},
+	}
+	for _, c := range cases {
+		if got := Undoable(c.changes); got != c.want {
+			t.Errorf("%s: Undoable = %v, want %v", c.name, got, c.want)
+		}
+	}
+}
+
+func TestSameRow(t *testing.T) {
+	after := `{"id": "t1", "title": "Call", "scheduled_start": "09:00:00", "google_event_id": null, "updated_at": "2026-10-16T09:00:00+00:00"}`
+
+	cases := []struct {
+		name    string
+		table   string
+		current string
+		want    bool
+	}{
+		{"untouched", "tasks", after, true},
+		{"key order and spacing", "tasks", `{"updated_at":"2026-10-16T09:00:00+00:00","google_event_id":null,"scheduled_start":"09:00:00","title":"Call","id":"t1"}`, true},
+		{"pushed to Google Calendar since", "tasks",
+			`{"id": "t1", "title": "Call", "scheduled_start": "09:00:00", "google_event_id": "evt1", "updated_at": "2026-10-16T09:00:02+00:00"}`, true},
+		{"edited by the user", "tasks",
+			`{"id": "t1", "title": "Call mum", "scheduled_start": "09:00:00", "google_event_id": "evt1", "updated_at": "2026-10-16T10:00:00+00:00"}`, false},
+		{"rescheduled", "tasks",
+			`{"id": "t1", "title": "Call", "scheduled_start": "10:00:00", "google_event_id": null, "updated_at": "2026-10-16T09:00:00+00:00"}`, false},
+		{"sync columns only apply to their table", "routines",
+			`{"id": "t1", "title": "Call", "scheduled_start": "09:00:00", "google_event_id": "evt1", "updated_at": "2026-10-16T09:00:00+00:00"}`, false},
+	}
+	for _, c := range cases {
+		same, err := sameRow(c.table, json.RawMessage(c.current), json.RawMessage(after))
+		if err != nil {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+		if same != c.want {
+			t.Errorf("%s: sameRow = %v, want %v", c.name, same, c.want)
+		}
+	}
+}
+
+func TestSnapshotColumns(t *testing.T) {
+	snapshot := json.RawMessage(`{"title": "Call", "id": "t1", "google_event_id": "evt1", "user_id": "u1"}`)
+
+	cols, err := snapshotColumns(snapshot)
+	if err != nil || cols != `"google_event_id", "id", "title", "user_id"` {
+		t.Errorf("snapshotColumns = %s, %v", cols, err)
+	}
+	cols, err = snapshotColumns(snapshot, linkColumns["tasks"]...)
+	if err != nil || cols != `"id", "title", "user_id"` {
+		t.Errorf("snapshotColumns without link columns = %s, %v", cols, err)
+	}
+	if _, err := snapshotColumns(json.RawMessage(`[]`)); !errors.Is(err, ErrNotUndoable) {
+		t.Errorf("err = %v, want ErrNotUndoable", err)
+	}
+}
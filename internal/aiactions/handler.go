@@ -0,0 +1,88 @@
+package aiactions
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"firelevel-backend/internal/auth"
+	"firelevel-backend/internal/tools"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const (
+	listDefaultLimit = 50
+	listMaxLimit     = 200
+)
+
+// Handler exposes the AI action log over HTTP.
+type Handler struct {
+	db *pgxpool.Pool
+}
+
+// NewHandler creates a new AI actions handler
+func NewHandler(db *pgxpool.Pool) *Handler {
+	return &Handler{db: db}
+}
+
+type listResponse struct {
+	Actions []Action `json:"actions"`
+}
+
+type undoResponse struct {
+	Action      *Action            `json:"action"`
+	SideEffects []tools.SideEffect `json:"side_effects"`
+}
+
+// ListActions returns what Kai changed, newest first
+// GET /chat/v2/actions?limit=50
+func (h *Handler) ListActions(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(auth.UserContextKey).(string)
+
+	limit := listDefaultLimit
+	if n, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && n > 0 {
+		limit = min(n, listMaxLimit)
+	}
+
+	actions, err := List(r.Context(), h.db, userID, limit)
+	if err != nil {
+		log.Printf("Failed to list AI actions for user %s: %v", userID, err)
+		http.Error(w, "Failed to list actions", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(listResponse{Actions: actions})
+}
+
+// UndoAction reverts an action and tells the app what to refresh
+// POST /chat/v2/actions/{id}/undo
+func (h *Handler) UndoAction(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(auth.UserContextKey).(string)
+	actionID := chi.URLParam(r, "id")
+
+	action, effects, err := Undo(r.Context(), h.db, userID, actionID)
+	switch {
+	case errors.Is(err, ErrNotFound):
+		http.Error(w, "Action not found", http.StatusNotFound)
+		return
+	case errors.Is(err, ErrAlreadyUndone), errors.Is(err, ErrConflict):
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	case errors.Is(err, ErrNotUndoable):
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	case err != nil:
+		log.Printf("Failed to undo AI action %s for user %s: %v", actionID, userID, err)
+		http.Error(w, "Failed to undo action", http.StatusInternalServerError)
+		return
+	}
+
+	log.Printf("↩️ Undid AI action %s (%s) for user %s", action.ID, action.Tool, userID)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(undoResponse{Action: action, SideEffects: effects})
+}
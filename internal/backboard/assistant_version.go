@@ -0,0 +1,84 @@
+package backboard
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+
+	"firelevel-backend/internal/tools"
+)
+
+// ==========================================
+// Assistant config versions
+// ==========================================
+// A Backboard assistant keeps the system prompt and tools it was created
+// with. users.backboard_assistant_config_hash records which config the
+// user's assistant runs; when the persona, harsh mode, timezone or the
+// prompt/tools shipped with the server change, the hash changes and
+// SyncAssistant brings the assistant up to date.
+// ==========================================
+
+// AssistantConfigHash identifies the config BuildAssistantConfig produces
+// for these settings. The date header is left out: it changes every
+// minute and the model gets the real date from get_user_context anyway.
+func AssistantConfigHash(registry *tools.Registry, companionName string, coachHarshMode bool, userTimezone string) string {
+	config := buildAssistantConfig(registry, companionName, coachHarshMode, "")
+	payload, _ := json.Marshal(struct {
+		Config   AssistantConfig `json:"config"`
+		Timezone string          `json:"timezone"`
+	}{config, userTimezone})
+	sum := sha256.Sum256(payload)
+	return hex.EncodeToString(sum[:])
+}
+
+// SyncAssistant applies config to the assistant and returns the ID of the
+// assistant that now carries it.
+//
+// Backboard answers PATCH /assistants/{id} with 405 today, so the update
+// is tried first and, when refused, a new assistant is created with the
+// old one's memories. The caller switches the user over, starts a new
+// thread and deletes the old assistant. If the copy fails the new
+// assistant is dropped and the old one is kept.
+func SyncAssistant(ctx context.Context, client *Client, assistantID string, config AssistantConfig) (string, error) {
+	err := client.UpdateAssistant(ctx, assistantID, config)
+	if err == nil {
+		return assistantID, nil
+	}
+	if !errors.Is(err, ErrRequest) && !errors.Is(err, ErrNotFound) {
+		return "", err
+	}
+
+	// The old assistant is gone: nothing to copy
+	if errors.Is(err, ErrNotFound) {
+		return client.CreateAssistant(ctx, config)
+	}
+
+	memories, err := client.ListMemories(ctx, assistantID)
+	if err != nil {
+		return "", fmt.Errorf("read memories to migrate: %w", err)
+	}
+
+	newID, err := client.CreateAssistant(ctx, config)
+	if err != nil {
+		return "", err
+	}
+
+	for _, m := range memories {
+		if m.Content == "" {
+			continue
+		}
+		if err := client.AddMemory(ctx, newID, m.Content); err != nil {
+			if delErr := client.DeleteAssistant(ctx, newID); delErr != nil {
+				log.Printf("⚠️ Failed to delete half-migrated assistant %s: %v", newID, delErr)
+			}
+			return "", fmt.Errorf("copy memory %s: %w", m.ID, err)
+		}
+	}
+
+	log.Printf("🔁 Migrated assistant %s to %s (%d memories)", assistantID, newID, len(memories))
+	return newID, nil
+}
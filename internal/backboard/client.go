@@ -4,8 +4,10 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"log"
 	"net/http"
 	"time"
 )
@@ -15,17 +17,49 @@ type Client struct {
 	baseURL    string
 	apiKey     string
 	httpClient *http.Client
+	retry      RetryPolicy
+	breaker    *CircuitBreaker
+}
+
+// ClientOption customizes a Client, e.g. to point it at an httptest server.
+type ClientOption func(*Client)
+
+// WithBaseURL sends requests to url instead of the Backboard API.
+func WithBaseURL(url string) ClientOption {
+	return func(c *Client) { c.baseURL = url }
+}
+
+// WithHTTPClient replaces the underlying HTTP client.
+func WithHTTPClient(hc *http.Client) ClientOption {
+	return func(c *Client) { c.httpClient = hc }
+}
+
+// WithRetryPolicy replaces DefaultRetryPolicy.
+func WithRetryPolicy(p RetryPolicy) ClientOption {
+	return func(c *Client) { c.retry = p }
+}
+
+// WithCircuitBreaker gives the client its own breaker instead of the
+// process-wide one.
+func WithCircuitBreaker(b *CircuitBreaker) ClientOption {
+	return func(c *Client) { c.breaker = b }
 }
 
 // NewClient creates a Backboard API client.
-func NewClient(apiKey string) *Client {
-	return &Client{
+func NewClient(apiKey string, opts ...ClientOption) *Client {
+	c := &Client{
 		baseURL: "https://app.backboard.io/api",
 		apiKey:  apiKey,
 		httpClient: &http.Client{
 			Timeout: 120 * time.Second, // Backboard can be slow due to LLM + tool calls
 		},
+		retry:   DefaultRetryPolicy,
+		breaker: sharedBreaker,
 	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
 }
 
 // ==========================================
@@ -70,6 +104,12 @@ func (c *Client) UpdateAssistant(ctx context.Context, assistantID string, config
 	return nil
 }
 
+// DeleteAssistant deletes an assistant with its threads and memories.
+func (c *Client) DeleteAssistant(ctx context.Context, assistantID string) error {
+	_, err := c.do(ctx, "DELETE", "/assistants/"+assistantID, nil)
+	return err
+}
+
 // ==========================================
 // Thread Management
 // ==========================================
@@ -198,14 +238,38 @@ func (c *Client) ListMemories(ctx context.Context, assistantID string) ([]Memory
 
 // AddMemory stores a new memory for the assistant.
 func (c *Client) AddMemory(ctx context.Context, assistantID string, content string) error {
+	_, err := c.CreateMemory(ctx, assistantID, content)
+	return err
+}
+
+// CreateMemory stores a new memory and returns it as Backboard saved it.
+// ID is empty if the response doesn't carry one.
+func (c *Client) CreateMemory(ctx context.Context, assistantID string, content string) (*Memory, error) {
 	req := MemoryCreateRequest{Content: content, Metadata: nil}
 	body, err := json.Marshal(req)
 	if err != nil {
-		return fmt.Errorf("marshal memory: %w", err)
+		return nil, fmt.Errorf("marshal memory: %w", err)
 	}
 
-	_, err = c.do(ctx, "POST", "/assistants/"+assistantID+"/memories", body)
-	return err
+	resp, err := c.do(ctx, "POST", "/assistants/"+assistantID+"/memories", body)
+	if err != nil {
+		return nil, err
+	}
+
+	var result struct {
+		Memory
+		MemoryID string `json:"memory_id"`
+	}
+	// The memory is saved whatever the body says
+	_ = json.Unmarshal(resp, &result)
+	memory := result.Memory
+	if memory.ID == "" {
+		memory.ID = result.MemoryID
+	}
+	if memory.Content == "" {
+		memory.Content = content
+	}
+	return &memory, nil
 }
 
 // DeleteMemory removes a specific memory.
@@ -218,7 +282,39 @@ func (c *Client) DeleteMemory(ctx context.Context, assistantID, memoryID string)
 // HTTP Helper
 // ==========================================
 
+// do sends a request, retrying what RetryPolicy allows. Non-2xx answers
+// are returned as *APIError.
 func (c *Client) do(ctx context.Context, method, path string, body []byte) ([]byte, error) {
+	attempts := max(c.retry.MaxAttempts, 1)
+	for attempt := 1; ; attempt++ {
+		if !c.breaker.allow() {
+			return nil, fmt.Errorf("%s %s: %w", method, path, ErrCircuitOpen)
+		}
+
+		respBody, err := c.doOnce(ctx, method, path, body)
+		c.breaker.record(err)
+		if err == nil || attempt >= attempts || !retryable(method, err) {
+			return respBody, err
+		}
+
+		var retryAfter time.Duration
+		var apiErr *APIError
+		if errors.As(err, &apiErr) {
+			retryAfter = apiErr.RetryAfter
+		}
+		wait, ok := c.retry.delay(attempt, retryAfter)
+		if !ok {
+			log.Printf("⏳ Backboard %s %s asks to retry in %s, giving up: %v", method, path, wait, err)
+			return respBody, err
+		}
+		log.Printf("⏳ Backboard %s %s failed (attempt %d/%d), retrying in %s: %v", method, path, attempt, attempts, wait, err)
+		if err := sleepCtx(ctx, wait); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (c *Client) doOnce(ctx context.Context, method, path string, body []byte) ([]byte, error) {
 	var bodyReader io.Reader
 	if body != nil {
 		bodyReader = bytes.NewReader(body)
@@ -246,7 +342,7 @@ func (c *Client) do(ctx context.Context, method, path string, body []byte) ([]by
 	}
 
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return nil, fmt.Errorf("backboard %s %s returned %d: %s", method, path, resp.StatusCode, string(respBody))
+		return nil, newAPIError(method, path, resp, respBody)
 	}
 
 	return respBody, nil
@@ -0,0 +1,352 @@
+package backboard
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"firelevel-backend/internal/llm"
+	"firelevel-backend/internal/tools"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// ==========================================
+// Tool confirmations
+// ==========================================
+// Tools with tools.PolicyConfirm don't run when the model calls them: the
+// run is paused, stored in pending_tool_runs and the app gets a
+// pending_confirmation side effect with a preview. When the user answers,
+// ResumeConfirmation runs (or declines) the calls and submits every
+// output of the round to the provider, which picks the run back up.
+//
+// Backboard keeps paused runs on its side. Providers that keep them in
+// memory (llm.Resumable, i.e. Gemini) have their run state stored with the
+// row, so a confirmation survives a restart of this server either way.
+// ==========================================
+
+// pendingConfirmationTTL is how long the user has to answer.
+const pendingConfirmationTTL = time.Hour
+
+var (
+	ErrConfirmationNotFound = errors.New("confirmation not found or expired")
+	ErrConfirmationProvider = errors.New("confirmation belongs to another provider")
+)
+
+// declinedOutput is what the model gets for a call the user refused.
+var declinedOutput = toJSON(map[string]interface{}{
+	"declined": true,
+	"message":  "L'utilisateur a refusé cette action. Ne la refais pas sans qu'il le demande.",
+})
+
+// pendingCall is a tool call waiting for the user.
+type pendingCall struct {
+	ToolCall llm.ToolCall  `json:"tool_call"`
+	Preview  tools.Preview `json:"preview"`
+}
+
+// pauseForConfirmation stores the paused run and returns the side effect
+// asking the app for approval.
+func (e *Executor) pauseForConfirmation(
+	ctx context.Context,
+	provider llm.Provider,
+	conv *llm.Conversation,
+	runID string,
+	pending []pendingCall,
+	outputs []llm.ToolOutput,
+	deviceCtx *DeviceContext,
+) (SideEffect, error) {
+	run := &pendingRun{
+		provider: provider.Name(),
+		conv:     *conv,
+		runID:    runID,
+		calls:    pending,
+		outputs:  outputs,
+		device:   deviceCtx,
+	}
+	if r, ok := provider.(llm.Resumable); ok {
+		state, err := r.SaveRun(runID)
+		if err != nil {
+			return SideEffect{}, fmt.Errorf("save %s run state: %w", provider.Name(), err)
+		}
+		run.state = state
+	}
+
+	id, expiresAt, err := e.runs.save(ctx, run, pendingConfirmationTTL)
+	if err != nil {
+		return SideEffect{}, fmt.Errorf("save pending run: %w", err)
+	}
+
+	type previewItem struct {
+		ToolCallID string      `json:"tool_call_id"`
+		Tool       string      `json:"tool"`
+		Summary    string      `json:"summary"`
+		Details    interface{} `json:"details,omitempty"`
+	}
+	items := make([]previewItem, 0, len(pending))
+	for _, p := range pending {
+		items = append(items, previewItem{
+			ToolCallID: p.ToolCall.ID,
+			Tool:       p.ToolCall.Name,
+			Summary:    p.Preview.Summary,
+			Details:    p.Preview.Details,
+		})
+	}
+
+	log.Printf("⏸️ Run %s paused for user %s: %d call(s) need confirmation (%s)", runID, conv.UserID, len(pending), id)
+	return NewSideEffectWithData("pending_confirmation", map[string]interface{}{
+		"confirmation_id": id,
+		"expires_at":      expiresAt.UTC().Format(time.RFC3339),
+		"actions":         items,
+	}), nil
+}
+
+// pendingRun is a row of pending_tool_runs being resolved.
+type pendingRun struct {
+	provider string
+	conv     llm.Conversation
+	runID    string
+	calls    []pendingCall
+	outputs  []llm.ToolOutput
+	device   *DeviceContext
+	state    []byte // llm.Resumable run state
+}
+
+// pendingRunStore keeps paused runs. pgPendingRuns is the real one; the
+// tests use an in-memory store.
+type pendingRunStore interface {
+	// save stores a new pending run and returns its ID and expiry.
+	save(ctx context.Context, run *pendingRun, ttl time.Duration) (string, time.Time, error)
+	// claim marks a pending run as resolved with status and returns it.
+	// Only one caller can claim a given run. A non-empty provider only
+	// claims runs of that provider: a run of another one is left pending
+	// and ErrConfirmationProvider is returned.
+	claim(ctx context.Context, userID, id, provider, status string) (*pendingRun, error)
+	// release puts a run claimed with status back to pending.
+	release(ctx context.Context, userID, id, status string) error
+	// pendingIDs lists the unexpired pending runs of a user.
+	pendingIDs(ctx context.Context, userID string) ([]string, error)
+}
+
+// pgPendingRuns stores paused runs in pending_tool_runs.
+type pgPendingRuns struct {
+	db *pgxpool.Pool
+}
+
+func (s pgPendingRuns) save(ctx context.Context, run *pendingRun, ttl time.Duration) (string, time.Time, error) {
+	outputs := run.outputs
+	if outputs == nil {
+		outputs = []llm.ToolOutput{}
+	}
+	pendingJSON, err := json.Marshal(run.calls)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("marshal pending calls: %w", err)
+	}
+	outputsJSON, err := json.Marshal(outputs)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("marshal tool outputs: %w", err)
+	}
+	var deviceJSON []byte
+	if run.device != nil {
+		if deviceJSON, err = json.Marshal(run.device); err != nil {
+			return "", time.Time{}, fmt.Errorf("marshal device context: %w", err)
+		}
+	}
+
+	var id string
+	var expiresAt time.Time
+	err = s.db.QueryRow(ctx, `
+		INSERT INTO public.pending_tool_runs
+			(user_id, provider, assistant_id, thread_id, run_id, pending_calls, outputs, device_context, provider_state, expires_at)
+		VALUES ($1, $2, NULLIF($3, ''), NULLIF($4, ''), $5, $6, $7, $8, $9, $10)
+		RETURNING id, expires_at
+	`, run.conv.UserID, run.provider, run.conv.AssistantID, run.conv.ThreadID, run.runID,
+		pendingJSON, outputsJSON, deviceJSON, run.state, time.Now().Add(ttl)).Scan(&id, &expiresAt)
+	return id, expiresAt, err
+}
+
+func (s pgPendingRuns) claim(ctx context.Context, userID, id, provider, status string) (*pendingRun, error) {
+	run := &pendingRun{conv: llm.Conversation{UserID: userID}}
+	var assistantID, threadID *string
+	var callsJSON, outputsJSON, deviceJSON []byte
+	err := s.db.QueryRow(ctx, `
+		UPDATE public.pending_tool_runs SET status = $3, resolved_at = now()
+		WHERE id = $1 AND user_id = $2 AND status = 'pending' AND expires_at > now()
+		  AND ($4 = '' OR provider = $4)
+		RETURNING provider, assistant_id, thread_id, run_id, pending_calls, outputs, device_context, provider_state
+	`, id, userID, status, provider).Scan(&run.provider, &assistantID, &threadID, &run.runID, &callsJSON, &outputsJSON, &deviceJSON, &run.state)
+	if errors.Is(err, pgx.ErrNoRows) {
+		if provider == "" {
+			return nil, ErrConfirmationNotFound
+		}
+		var other string
+		err = s.db.QueryRow(ctx, `
+			SELECT provider FROM public.pending_tool_runs
+			WHERE id = $1 AND user_id = $2 AND status = 'pending' AND expires_at > now()
+		`, id, userID).Scan(&other)
+		if err != nil {
+			return nil, ErrConfirmationNotFound
+		}
+		return nil, fmt.Errorf("%w (%s)", ErrConfirmationProvider, other)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if assistantID != nil {
+		run.conv.AssistantID = *assistantID
+	}
+	if threadID != nil {
+		run.conv.ThreadID = *threadID
+	}
+	if err := json.Unmarshal(callsJSON, &run.calls); err != nil {
+		return nil, fmt.Errorf("decode pending calls: %w", err)
+	}
+	if err := json.Unmarshal(outputsJSON, &run.outputs); err != nil {
+		return nil, fmt.Errorf("decode tool outputs: %w", err)
+	}
+	if len(deviceJSON) > 0 {
+		run.device = &DeviceContext{}
+		if err := json.Unmarshal(deviceJSON, run.device); err != nil {
+			return nil, fmt.Errorf("decode device context: %w", err)
+		}
+	}
+	return run, nil
+}
+
+func (s pgPendingRuns) release(ctx context.Context, userID, id, status string) error {
+	_, err := s.db.Exec(ctx, `
+		UPDATE public.pending_tool_runs SET status = 'pending', resolved_at = NULL
+		WHERE id = $1 AND user_id = $2 AND status = $3
+	`, id, userID, status)
+	return err
+}
+
+func (s pgPendingRuns) pendingIDs(ctx context.Context, userID string) ([]string, error) {
+	rows, err := s.db.Query(ctx, `
+		SELECT id FROM public.pending_tool_runs
+		WHERE user_id = $1 AND status = 'pending' AND expires_at > now()
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, pgx.RowTo[string])
+}
+
+// restoreRun loads the stored state of a run back into a provider that
+// keeps runs in memory.
+func restoreRun(ctx context.Context, provider llm.Provider, run *pendingRun) error {
+	r, ok := provider.(llm.Resumable)
+	if !ok || len(run.state) == 0 {
+		return nil
+	}
+	if err := r.RestoreRun(ctx, run.runID, run.state); err != nil {
+		return fmt.Errorf("restore %s run: %w", provider.Name(), err)
+	}
+	return nil
+}
+
+// ResumeConfirmation answers a pending confirmation: approved calls run,
+// refused ones tell the model the user said no. The run then continues
+// like RunToolLoop. deviceCtx replaces the one stored with the run when
+// the app sends a fresher one.
+func (e *Executor) ResumeConfirmation(
+	ctx context.Context,
+	provider llm.Provider,
+	userID, confirmationID string,
+	approved bool,
+	deviceCtx *DeviceContext,
+) (string, []SideEffect, error) {
+	status := "declined"
+	if approved {
+		status = "approved"
+	}
+	run, err := e.runs.claim(ctx, userID, confirmationID, provider.Name(), status)
+	if err != nil {
+		return "", nil, err
+	}
+	if err := restoreRun(ctx, provider, run); err != nil {
+		// Nothing ran yet: leave the confirmation open for another try
+		if rerr := e.runs.release(ctx, userID, confirmationID, status); rerr != nil {
+			log.Printf("⚠️ Failed to reopen confirmation %s for user %s: %v", confirmationID, userID, rerr)
+		}
+		return "", nil, err
+	}
+	if deviceCtx == nil {
+		deviceCtx = run.device
+	}
+
+	var allSideEffects []SideEffect
+	outputs := run.outputs
+	for _, p := range run.calls {
+		tc := p.ToolCall
+		output := declinedOutput
+		if approved {
+			log.Printf("🔧 Confirmed tool call [%s]: %s(%s)", provider.Name(), tc.Name, truncate(tc.Arguments, 200))
+			e.emit(StreamEvent{Type: EventToolStart, Tool: tc.Name, ToolCallID: tc.ID})
+			var effects []SideEffect
+			output, effects = e.executeToolCall(ctx, &run.conv, run.runID, tc, deviceCtx)
+			allSideEffects = append(allSideEffects, effects...)
+			e.emit(StreamEvent{Type: EventToolEnd, Tool: tc.Name, ToolCallID: tc.ID, SideEffects: effects})
+		}
+		outputs = append(outputs, llm.ToolOutput{ToolCallID: tc.ID, Output: output})
+	}
+
+	response, err := provider.SubmitToolOutputs(ctx, &run.conv, run.runID, outputs)
+	if err != nil {
+		return "", allSideEffects, fmt.Errorf("submit confirmed tool outputs: %w", err)
+	}
+
+	content, effects, err := e.RunToolLoop(ctx, provider, &run.conv, response, deviceCtx)
+	return content, append(allSideEffects, effects...), err
+}
+
+// SupersedePendingRuns declines the confirmations the user left
+// unanswered before sending a new message. A Backboard thread with a run
+// waiting for tool outputs would reject the message otherwise.
+func (e *Executor) SupersedePendingRuns(ctx context.Context, provider llm.Provider, userID string) {
+	ids, err := e.runs.pendingIDs(ctx, userID)
+	if err != nil {
+		log.Printf("Failed to load pending tool runs for user %s: %v", userID, err)
+		return
+	}
+
+	for _, id := range ids {
+		run, err := e.runs.claim(ctx, userID, id, "", "superseded")
+		if err != nil {
+			continue
+		}
+		if run.provider != provider.Name() {
+			continue
+		}
+		if err := restoreRun(ctx, provider, run); err != nil {
+			log.Printf("⚠️ Failed to close superseded run %s for user %s: %v", run.runID, userID, err)
+			continue
+		}
+		outputs := run.outputs
+		for _, p := range run.calls {
+			outputs = append(outputs, llm.ToolOutput{ToolCallID: p.ToolCall.ID, Output: declinedOutput})
+		}
+		// The model's answer to the refusal is not shown: the user moved on.
+		// Whatever else it asks for in this run is declined too.
+		runID := run.runID
+		for round := 0; round < maxToolCallRounds; round++ {
+			response, err := provider.SubmitToolOutputs(ctx, &run.conv, runID, outputs)
+			if err != nil {
+				log.Printf("⚠️ Failed to close superseded run %s for user %s: %v", runID, userID, err)
+				break
+			}
+			if !response.RequiresAction() {
+				break
+			}
+			runID = response.RunID
+			outputs = outputs[:0]
+			for _, tc := range response.ToolCalls {
+				outputs = append(outputs, llm.ToolOutput{ToolCallID: tc.ID, Output: declinedOutput})
+			}
+		}
+	}
+}
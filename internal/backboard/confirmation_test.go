@@ -0,0 +1,295 @@
+package backboard
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"firelevel-backend/internal/llm"
+	"firelevel-backend/internal/tools"
+)
+
+// memoryRuns is a pendingRunStore kept in memory.
+type memoryRuns struct {
+	mu   sync.Mutex
+	rows map[string]*memoryRun
+}
+
+type memoryRun struct {
+	run       pendingRun
+	status    string
+	expiresAt time.Time
+}
+
+func newMemoryRuns() *memoryRuns {
+	return &memoryRuns{rows: map[string]*memoryRun{}}
+}
+
+func (m *memoryRuns) save(ctx context.Context, run *pendingRun, ttl time.Duration) (string, time.Time, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	id := fmt.Sprintf("confirm-%d", len(m.rows)+1)
+	row := &memoryRun{run: *run, status: "pending", expiresAt: time.Now().Add(ttl)}
+	row.run.outputs = append([]llm.ToolOutput(nil), run.outputs...)
+	m.rows[id] = row
+	return id, row.expiresAt, nil
+}
+
+func (m *memoryRuns) claim(ctx context.Context, userID, id, provider, status string) (*pendingRun, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	row, ok := m.rows[id]
+	if !ok || row.run.conv.UserID != userID || row.status != "pending" || time.Now().After(row.expiresAt) {
+		return nil, ErrConfirmationNotFound
+	}
+	if provider != "" && row.run.provider != provider {
+		return nil, fmt.Errorf("%w (%s)", ErrConfirmationProvider, row.run.provider)
+	}
+	row.status = status
+	run := row.run
+	run.outputs = append([]llm.ToolOutput(nil), row.run.outputs...)
+	return &run, nil
+}
+
+func (m *memoryRuns) release(ctx context.Context, userID, id, status string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if row, ok := m.rows[id]; ok && row.run.conv.UserID == userID && row.status == status {
+		row.status = "pending"
+	}
+	return nil
+}
+
+func (m *memoryRuns) pendingIDs(ctx context.Context, userID string) ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var ids []string
+	for id, row := range m.rows {
+		if row.run.conv.UserID == userID && row.status == "pending" {
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+func (m *memoryRuns) status(id string) string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.rows[id].status
+}
+
+// resumableScripted is a Scripted provider keeping its runs in memory,
+// like Gemini.
+type resumableScripted struct {
+	*llm.Scripted
+	restoreErr error
+	restored   []string
+}
+
+func (r *resumableScripted) SaveRun(runID string) ([]byte, error) {
+	return []byte(`"state of ` + runID + `"`), nil
+}
+
+func (r *resumableScripted) RestoreRun(ctx context.Context, runID string, state []byte) error {
+	if r.restoreErr != nil {
+		return r.restoreErr
+	}
+	r.restored = append(r.restored, string(state))
+	return nil
+}
+
+type noteArgs struct {
+	Text string `json:"text" required:"true"`
+}
+
+// testExecutor runs add_note right away and asks before clear_notes.
+func testExecutor(t *testing.T) (*Executor, *memoryRuns, map[string]int) {
+	t.Helper()
+	ran := map[string]int{}
+	reg := tools.NewRegistry()
+	err := reg.Register(
+		tools.Func[noteArgs]{
+			Name: "add_note",
+			Run: func(ctx context.Context, call *tools.Call, args noteArgs) (tools.Result, error) {
+				ran["add_note"]++
+				return tools.Result{Output: map[string]string{"added": args.Text}}, nil
+			},
+		},
+		tools.Func[struct{}]{
+			Name:   "clear_notes",
+			Policy: tools.PolicyConfirm,
+			Run: func(ctx context.Context, call *tools.Call, args struct{}) (tools.Result, error) {
+				ran["clear_notes"]++
+				return tools.Result{Output: map[string]bool{"cleared": true}}, nil
+			},
+			Preview: func(ctx context.Context, call *tools.Call, args struct{}) (tools.Preview, error) {
+				return tools.Preview{Summary: "Effacer toutes les notes"}, nil
+			},
+		},
+	)
+	if err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	runs := newMemoryRuns()
+	return &Executor{registry: reg, runs: runs}, runs, ran
+}
+
+// pausedRound is a model turn calling an auto tool and a confirm tool.
+var pausedRound = llm.Response{RunID: "r1", ToolCalls: []llm.ToolCall{
+	{ID: "c1", Name: "add_note", Arguments: `{"text": "lait"}`},
+	{ID: "c2", Name: "clear_notes", Arguments: `{}`},
+}}
+
+// pause runs the tool loop until the confirm tool pauses it and returns
+// the confirmation ID.
+func pause(t *testing.T, e *Executor, provider llm.Provider) string {
+	t.Helper()
+	conv := &llm.Conversation{UserID: "u1"}
+	resp, err := provider.Send(context.Background(), conv, "Vide mes notes")
+	if err != nil {
+		t.Fatal(err)
+	}
+	content, effects, err := e.RunToolLoop(context.Background(), provider, conv, resp, &DeviceContext{AppsBlocked: true})
+	if err != nil {
+		t.Fatalf("RunToolLoop: %v", err)
+	}
+	if content != "Effacer toutes les notes : je confirme ?" {
+		t.Errorf("content = %q", content)
+	}
+	last := effects[len(effects)-1]
+	if last.Type != "pending_confirmation" {
+		t.Fatalf("side effects = %v, want a pending_confirmation last", effects)
+	}
+	var data struct {
+		ConfirmationID string `json:"confirmation_id"`
+	}
+	if err := json.Unmarshal(last.Data, &data); err != nil || data.ConfirmationID == "" {
+		t.Fatalf("confirmation data = %s, %v", last.Data, err)
+	}
+	return data.ConfirmationID
+}
+
+func TestConfirmationApproved(t *testing.T) {
+	e, runs, ran := testExecutor(t)
+	provider := llm.NewScripted(pausedRound, llm.Response{Content: "Notes effacées."})
+
+	id := pause(t, e, provider)
+	if ran["add_note"] != 1 || ran["clear_notes"] != 0 {
+		t.Fatalf("ran = %v before approval", ran)
+	}
+	if got := provider.Outputs(); len(got) != 0 {
+		t.Fatalf("outputs submitted before approval: %v", got)
+	}
+	if run := runs.rows[id].run; run.device == nil || !run.device.AppsBlocked || run.runID != "r1" {
+		t.Errorf("stored run = %+v", run)
+	}
+
+	content, _, err := e.ResumeConfirmation(context.Background(), provider, "u1", id, true, nil)
+	if err != nil {
+		t.Fatalf("ResumeConfirmation: %v", err)
+	}
+	if content != "Notes effacées." {
+		t.Errorf("content = %q", content)
+	}
+	if ran["clear_notes"] != 1 {
+		t.Errorf("clear_notes ran %d times, want 1", ran["clear_notes"])
+	}
+	outputs := provider.Outputs()
+	if len(outputs) != 1 || len(outputs[0]) != 2 || outputs[0][0].ToolCallID != "c1" || outputs[0][1].Output != `{"cleared":true}` {
+		t.Errorf("submitted outputs = %v, want the whole round", outputs)
+	}
+	if got := runs.status(id); got != "approved" {
+		t.Errorf("status = %s, want approved", got)
+	}
+
+	if _, _, err := e.ResumeConfirmation(context.Background(), provider, "u1", id, true, nil); !errors.Is(err, ErrConfirmationNotFound) {
+		t.Errorf("second answer: err = %v, want ErrConfirmationNotFound", err)
+	}
+}
+
+func TestConfirmationDeclined(t *testing.T) {
+	e, runs, ran := testExecutor(t)
+	provider := llm.NewScripted(pausedRound, llm.Response{Content: "D'accord, je n'y touche pas."})
+
+	id := pause(t, e, provider)
+	if _, _, err := e.ResumeConfirmation(context.Background(), provider, "u2", id, false, nil); !errors.Is(err, ErrConfirmationNotFound) {
+		t.Errorf("other user: err = %v, want ErrConfirmationNotFound", err)
+	}
+
+	content, _, err := e.ResumeConfirmation(context.Background(), provider, "u1", id, false, nil)
+	if err != nil {
+		t.Fatalf("ResumeConfirmation: %v", err)
+	}
+	if content != "D'accord, je n'y touche pas." || ran["clear_notes"] != 0 {
+		t.Errorf("content = %q, ran = %v", content, ran)
+	}
+	if outputs := provider.Outputs(); len(outputs) != 1 || outputs[0][1].Output != declinedOutput {
+		t.Errorf("submitted outputs = %v, want the refusal", outputs)
+	}
+	if got := runs.status(id); got != "declined" {
+		t.Errorf("status = %s, want declined", got)
+	}
+}
+
+func TestConfirmationRestoreFailure(t *testing.T) {
+	e, runs, ran := testExecutor(t)
+	provider := &resumableScripted{Scripted: llm.NewScripted(pausedRound, llm.Response{Content: "Notes effacées."})}
+
+	id := pause(t, e, provider)
+	if got := string(runs.rows[id].run.state); got != `"state of r1"` {
+		t.Fatalf("stored state = %s", got)
+	}
+
+	provider.restoreErr = errors.New("bad state")
+	if _, _, err := e.ResumeConfirmation(context.Background(), provider, "u1", id, true, nil); err == nil {
+		t.Fatal("ResumeConfirmation should fail when the run can't be restored")
+	}
+	if got := runs.status(id); got != "pending" {
+		t.Fatalf("status = %s after a failed restore, want pending", got)
+	}
+	if ran["clear_notes"] != 0 {
+		t.Fatal("clear_notes ran although the run was not restored")
+	}
+
+	provider.restoreErr = nil
+	if _, _, err := e.ResumeConfirmation(context.Background(), provider, "u1", id, true, nil); err != nil {
+		t.Fatalf("retry: %v", err)
+	}
+	if len(provider.restored) != 1 || ran["clear_notes"] != 1 {
+		t.Errorf("restored = %v, ran = %v", provider.restored, ran)
+	}
+}
+
+func TestSupersedePendingRuns(t *testing.T) {
+	e, runs, ran := testExecutor(t)
+	provider := llm.NewScripted(
+		pausedRound,
+		// The model answers the refusal with another tool call
+		llm.Response{RunID: "r2", ToolCalls: []llm.ToolCall{{ID: "c3", Name: "add_note", Arguments: `{"text": "oeufs"}`}}},
+		llm.Response{Content: "Compris."},
+	)
+
+	id := pause(t, e, provider)
+	e.SupersedePendingRuns(context.Background(), provider, "u1")
+
+	if got := runs.status(id); got != "superseded" {
+		t.Errorf("status = %s, want superseded", got)
+	}
+	outputs := provider.Outputs()
+	if len(outputs) != 2 {
+		t.Fatalf("submitted %d rounds, want 2", len(outputs))
+	}
+	if outputs[0][0].ToolCallID != "c1" || outputs[0][1].Output != declinedOutput {
+		t.Errorf("first round = %v", outputs[0])
+	}
+	if len(outputs[1]) != 1 || outputs[1][0].Output != declinedOutput {
+		t.Errorf("second round = %v, want the follow-up declined", outputs[1])
+	}
+	if ran["add_note"] != 1 || ran["clear_notes"] != 0 {
+		t.Errorf("ran = %v", ran)
+	}
+}
@@ -0,0 +1,113 @@
+package backboard
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ==========================================
+// Typed API errors
+// ==========================================
+// Every non-2xx answer from Backboard is an *APIError. Callers match the
+// kind with errors.Is against the sentinels below instead of parsing
+// messages:
+//
+//	if errors.Is(err, backboard.ErrRunInProgress) { ... }
+// ==========================================
+
+var (
+	// ErrRateLimited: 429, retried after Retry-After.
+	ErrRateLimited = errors.New("backboard: rate limited")
+	// ErrRunInProgress: the thread still has a run waiting for tool
+	// outputs, so it refuses new messages. Only a new thread fixes it.
+	ErrRunInProgress = errors.New("backboard: run in progress")
+	// ErrNotFound: the assistant, thread or run does not exist (anymore).
+	ErrNotFound = errors.New("backboard: not found")
+	// ErrServer: 5xx from Backboard or the model behind it.
+	ErrServer = errors.New("backboard: server error")
+	// ErrRequest: any other 4xx, i.e. a bug on our side.
+	ErrRequest = errors.New("backboard: bad request")
+	// ErrCircuitOpen: too many recent failures; the call was not made.
+	ErrCircuitOpen = errors.New("backboard: circuit open")
+)
+
+// APIError is a non-2xx response from Backboard.
+type APIError struct {
+	Kind       error // One of the sentinels above
+	StatusCode int
+	Method     string
+	Path       string
+	Body       string
+	RetryAfter time.Duration // From the Retry-After header, 0 if absent
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("backboard %s %s returned %d: %s", e.Method, e.Path, e.StatusCode, e.Body)
+}
+
+// Is lets errors.Is match the error kind.
+func (e *APIError) Is(target error) bool {
+	return target == e.Kind
+}
+
+// newAPIError classifies a non-2xx response.
+func newAPIError(method, path string, resp *http.Response, body []byte) *APIError {
+	e := &APIError{
+		StatusCode: resp.StatusCode,
+		Method:     method,
+		Path:       path,
+		Body:       string(body),
+		RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After")),
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests:
+		e.Kind = ErrRateLimited
+	case resp.StatusCode == http.StatusConflict || (resp.StatusCode == http.StatusBadRequest && mentionsActiveRun(body)):
+		e.Kind = ErrRunInProgress
+	case resp.StatusCode == http.StatusNotFound:
+		e.Kind = ErrNotFound
+	case resp.StatusCode >= 500:
+		e.Kind = ErrServer
+	default:
+		e.Kind = ErrRequest
+	}
+	return e
+}
+
+// mentionsActiveRun spots Backboard's 400 for a thread busy with a run
+// ("Thread already has an active run", "run ... is in progress").
+func mentionsActiveRun(body []byte) bool {
+	msg := strings.ToLower(string(body))
+	return strings.Contains(msg, "active run") ||
+		(strings.Contains(msg, "run") && strings.Contains(msg, "in progress")) ||
+		strings.Contains(msg, "requires_action")
+}
+
+// parseRetryAfter reads delay-seconds or an HTTP date.
+func parseRetryAfter(v string) time.Duration {
+	if v == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(strings.TrimSpace(v)); err == nil && secs > 0 {
+		return time.Duration(secs) * time.Second
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		if d := time.Until(t); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
+
+// NeedsNewThread reports whether err means the thread itself is unusable
+// (stuck run, deleted thread), so starting a new one is the fix. Outages
+// and rate limits are not: a new thread would fail the same way and the
+// history would be lost for nothing.
+func NeedsNewThread(err error) bool {
+	return errors.Is(err, ErrRunInProgress) || errors.Is(err, ErrNotFound)
+}
@@ -0,0 +1,72 @@
+package backboard
+
+import (
+	"strings"
+)
+
+// ==========================================
+// Memory categories
+// ==========================================
+// Backboard stores a memory as plain text. The category is kept as a
+// "[category] " prefix so Kai reads it with the fact and the app can sort
+// memories the way chat.SemanticMemory does.
+// ==========================================
+
+const (
+	MemoryPersonal    = "personal"
+	MemoryWork        = "work"
+	MemoryGoals       = "goals"
+	MemoryPreferences = "preferences"
+)
+
+// MemoryCategories lists the categories in display order.
+var MemoryCategories = []string{MemoryPersonal, MemoryWork, MemoryGoals, MemoryPreferences}
+
+// legacyMemoryCategories maps the categories save_memory used before the
+// user-facing ones to their closest match.
+var legacyMemoryCategories = map[string]string{
+	"goal":        MemoryGoals,
+	"preference":  MemoryPreferences,
+	"life_event":  MemoryPersonal,
+	"feeling":     MemoryPersonal,
+	"challenge":   MemoryPersonal,
+	"achievement": MemoryPersonal,
+	"fact":        MemoryPersonal,
+}
+
+// NormalizeMemoryCategory returns the category name for c, mapping legacy
+// names. ok is false for anything else.
+func NormalizeMemoryCategory(c string) (category string, ok bool) {
+	c = strings.ToLower(strings.TrimSpace(c))
+	for _, known := range MemoryCategories {
+		if c == known {
+			return c, true
+		}
+	}
+	category, ok = legacyMemoryCategories[c]
+	return category, ok
+}
+
+// FormatMemory builds the text stored for a fact. Unknown categories fall
+// back to personal.
+func FormatMemory(category, content string) string {
+	c, ok := NormalizeMemoryCategory(category)
+	if !ok {
+		c = MemoryPersonal
+	}
+	return "[" + c + "] " + strings.TrimSpace(content)
+}
+
+// ParseMemory splits stored text into its category and the fact. Memories
+// without a recognised prefix are personal.
+func ParseMemory(raw string) (category, content string) {
+	raw = strings.TrimSpace(raw)
+	if strings.HasPrefix(raw, "[") {
+		if end := strings.Index(raw, "]"); end > 0 {
+			if c, ok := NormalizeMemoryCategory(raw[1:end]); ok {
+				return c, strings.TrimSpace(raw[end+1:])
+			}
+		}
+	}
+	return MemoryPersonal, raw
+}
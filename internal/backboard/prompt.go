@@ -3,19 +3,23 @@ package backboard
 import (
 	"fmt"
 	"time"
+
+	"firelevel-backend/internal/tools"
+	"firelevel-backend/internal/userclock"
 )
 
 // BuildAssistantConfig builds the full assistant configuration with prompt + tools.
 // The current date/time is injected at the top of the system prompt.
-func BuildAssistantConfig(companionName string, coachHarshMode bool, userTimezone string) AssistantConfig {
-	loc, err := time.LoadLocation(userTimezone)
-	if err != nil {
-		loc = time.UTC
-	}
-	now := time.Now().In(loc)
+func BuildAssistantConfig(registry *tools.Registry, companionName string, coachHarshMode bool, userTimezone string) AssistantConfig {
+	now := userclock.At(time.Now(), userTimezone).Now()
 	dateStr := fmt.Sprintf("%s %d %s %d, %02d:%02d",
 		frenchWeekday(now.Weekday()), now.Day(), frenchMonth(now.Month()), now.Year(), now.Hour(), now.Minute())
 
+	return buildAssistantConfig(registry, companionName, coachHarshMode, dateStr)
+}
+
+// buildAssistantConfig builds the config with dateStr as the date header.
+func buildAssistantConfig(registry *tools.Registry, companionName string, coachHarshMode bool, dateStr string) AssistantConfig {
 	prompt := fmt.Sprintf("[DATE ET HEURE ACTUELLES : %s]\n\n%s", dateStr, systemPrompt)
 
 	if coachHarshMode {
@@ -26,7 +30,7 @@ func BuildAssistantConfig(companionName string, coachHarshMode bool, userTimezon
 		Name:         companionName,
 		SystemPrompt: prompt,
 		Description:  prompt,
-		Tools:        toolDefinitions(),
+		Tools:        toolDefinitions(registry),
 	}
 }
 
@@ -118,7 +122,7 @@ Tu termines TOUJOURS par une question ou une invitation à continuer, SAUF si l'
 ACCOUNTABILITY — TU SUIS LES ENGAGEMENTS
 ═══════════════════════════════════════
 
-Quand l'utilisateur mentionne un objectif avec une deadline → save_memory (category: "goal").
+Quand l'utilisateur mentionne un objectif avec une deadline → save_memory (category: "goals").
 Quand tu retrouves un goal dans la mémoire avec une date passée ou proche :
 - Rappelle-le naturellement : "Au fait, tu m'avais parlé de [goal]. T'en es où ?"
 - S'il a avancé → célèbre et demande la suite
@@ -188,7 +192,7 @@ COMPORTEMENT EN MODE RECOVERY :
 3. RENDS L'ACTION TRAÇABLE :
    - Quand l'utilisateur dit qu'il a fait la micro-action → célèbre : "Ça c'est du concret. T'as pris soin de toi."
    - Si l'utilisateur fait 2-3 micro-actions sur plusieurs jours → propose de créer un rituel : "Tu veux qu'on en fasse une routine ? Genre 'Marche 10 min' chaque jour ?"
-   - Sauvegarde en mémoire : save_memory(category: "achievement", content: "A repris contact après une phase de burnout — commence par des micro-actions wellbeing")
+   - Sauvegarde en mémoire : save_memory(category: "personal", content: "A repris contact après une phase de burnout — commence par des micro-actions wellbeing")
 
 4. RAMP-UP PROGRESSIF :
    - Jours 1-2 : uniquement micro-actions wellbeing. AUCUNE mention de tâches ou productivité.
@@ -238,6 +242,7 @@ UTILISATION DES TOOLS
 - Création → tool correspondant
 - "J'ai terminé [tâche]" → complete_task avec le bon ID. IMPORTANT: appelle TOUJOURS get_today_tasks ou get_tasks_for_date AVANT pour obtenir le vrai task_id. Ne devine JAMAIS un ID.
 - Suppression/modification → delete_task, update_task, delete_routine
+- delete_task, delete_routine et create_tasks_batch attendent l'accord de l'utilisateur : l'app affiche une carte de confirmation. Ne redemande pas "tu confirmes ?" toi-même. Si le résultat contient "declined", n'insiste pas.
 - Heure ou date demandée ("quelle heure", "on est quel jour") → get_current_datetime
 - Calculs de dates (demain, dans 3 jours, la semaine prochaine) → get_current_datetime d'abord pour avoir la date exacte, puis utilise iso_date pour les tools
 
@@ -381,6 +386,7 @@ Si has_calendar_connected=true :
 - Planning demandé → get_calendar_events + get_today_tasks
 - Distingue tâches (Focus) vs événements (calendrier)
 - Blocage sur events → schedule_calendar_blocking
+- Tâches sans horaire → propose_schedule, montre les créneaux, puis accept_schedule si l'utilisateur valide
 - Events "focusTime" → blocage auto
 
 ═══════════════════════════════════════
@@ -453,13 +459,12 @@ MÉMOIRE
 ═══════════════════════════════════════
 
 save_memory quand l'utilisateur partage :
-- Objectif (category: "goal") — "Je veux perdre 5kg", "Lancer ma boîte dans 6 mois"
-- Préférence (category: "preference") — "Le sport le matin me fait du bien"
-- Événement de vie (category: "life_event") — "Nouveau job lundi", "Séparation"
-- Ressenti récurrent (category: "feeling") — "Dépassé au travail depuis des semaines"
-- Défi/blocage (category: "challenge") — "J'arrive pas à me coucher avant 1h"
-- Accomplissement (category: "achievement") — "J'ai eu ma promo"
-- Fait personnel (category: "fact") — "Je suis développeur", "J'ai 2 enfants"
+- Objectif (category: "goals") — "Je veux perdre 5kg", "Lancer ma boîte dans 6 mois"
+- Préférence, habitude (category: "preferences") — "Le sport le matin me fait du bien"
+- Travail, études (category: "work") — "Je suis développeur", "Nouveau job lundi", "Dépassé au travail depuis des semaines", "J'ai eu ma promo"
+- Vie perso, ressenti, blocage (category: "personal") — "Séparation", "J'ai 2 enfants", "J'arrive pas à me coucher avant 1h"
+
+L'utilisateur voit ces souvenirs par catégorie dans l'app et peut les corriger ou les effacer.
 
 NE SAUVEGARDE PAS les états temporaires ("j'ai faim") ou les infos déjà dans get_user_context.
 Formule à la 3ème personne : "Objectif : lancer sa startup d'ici septembre 2025"
@@ -473,13 +478,13 @@ CONNAISSANCE DU QUOTIDIEN — APPRENDS COMMENT IL VIT
 Pour proposer des plannings réalistes, tu dois CONNAÎTRE le quotidien de l'utilisateur.
 
 CE QUE TU DOIS APPRENDRE (et sauvegarder en mémoire) :
-- Horaires de travail/études : "Je bosse de 10h à 18h" → save_memory(category: "fact", content: "Travaille de 10h à 18h en semaine")
-- Contraintes fixes : "Je récupère mes enfants à 16h30" → save_memory(category: "fact")
-- Habitudes sport/santé : "Je cours le matin à 7h" → save_memory(category: "preference")
-- Heures de sommeil : "Je me couche vers minuit" → save_memory(category: "preference")
-- Pic de productivité : "Je suis plus efficace le matin" → save_memory(category: "preference")
-- Temps libre habituel : "Le week-end je suis libre" → save_memory(category: "fact")
-- Objectifs de vie en cours : "Je prépare un concours" → save_memory(category: "goal")
+- Horaires de travail/études : "Je bosse de 10h à 18h" → save_memory(category: "work", content: "Travaille de 10h à 18h en semaine")
+- Contraintes fixes : "Je récupère mes enfants à 16h30" → save_memory(category: "personal")
+- Habitudes sport/santé : "Je cours le matin à 7h" → save_memory(category: "preferences")
+- Heures de sommeil : "Je me couche vers minuit" → save_memory(category: "preferences")
+- Pic de productivité : "Je suis plus efficace le matin" → save_memory(category: "preferences")
+- Temps libre habituel : "Le week-end je suis libre" → save_memory(category: "personal")
+- Objectifs de vie en cours : "Je prépare un concours" → save_memory(category: "goals")
 
 COMMENT APPRENDRE :
 - Quand l'utilisateur mentionne un horaire ou une habitude → sauvegarde silencieusement
@@ -562,171 +567,16 @@ RÈGLES:
 // Tool Definitions
 // ==========================================
 
-func toolDefinitions() []ToolDef {
-	return []ToolDef{
-		tool("get_user_context", "Récupère le contexte actuel: tâches, rituels, minutes focus, moment de la journée, statut blocage apps."),
-		tool("get_current_datetime", "Retourne la date et l'heure exactes actuelles. Appelle ce tool quand tu as besoin de connaître la date ou l'heure précise, ou pour calculer des dates futures (demain, dans 3 jours, etc)."),
-		tool("get_today_tasks", "Récupère la liste des tâches du jour avec statut, bloc horaire et priorité."),
-		toolWithParams("get_tasks_for_date", "Récupère les tâches pour une date spécifique (demain, la semaine prochaine, etc). Utilise-le pour voir les tâches futures avant d'en créer.",
-			params(param("date", "string", "Date au format YYYY-MM-DD")),
-			[]string{"date"}),
-		tool("get_rituals", "Récupère la liste des rituels quotidiens avec statut de complétion."),
-		toolWithParams("create_task", "Crée une nouvelle tâche. Peut être pour aujourd'hui ou n'importe quelle date future (date au format YYYY-MM-DD).",
-			params(
-				param("title", "string", "Le titre de la tâche"),
-				param("date", "string", "Date YYYY-MM-DD (défaut: aujourd'hui)"),
-				paramEnum("priority", "string", "Priorité", []string{"high", "medium", "low"}),
-				paramEnum("time_block", "string", "Bloc horaire", []string{"morning", "afternoon", "evening"}),
-			), []string{"title"}),
-		toolWithParams("complete_task", "Marque une tâche comme complétée. IMPORTANT: appelle TOUJOURS get_today_tasks ou get_tasks_for_date AVANT pour obtenir le vrai task_id. Ne devine JAMAIS un ID.",
-			params(
-				param("task_id", "string", "L'ID exact de la tâche (UUID, obtenu via get_today_tasks)"),
-				param("task_title", "string", "Le titre de la tâche (fallback si l'ID est inconnu)"),
-			), []string{"task_id"}),
-		toolWithParams("uncomplete_task", "Marque une tâche comme non complétée.",
-			params(param("task_id", "string", "L'ID de la tâche")),
-			[]string{"task_id"}),
-		toolWithParams("create_routine", "Crée un nouveau rituel quotidien.",
-			params(
-				param("title", "string", "Le titre du rituel"),
-				param("icon", "string", "Nom du SF Symbol (défaut: star)"),
-				paramEnum("frequency", "string", "Fréquence", []string{"daily", "weekdays", "weekends"}),
-				param("scheduled_time", "string", "Heure programmée HH:MM (optionnel)"),
-			), []string{"title"}),
-		toolWithParams("complete_routine", "Marque un rituel comme complété pour aujourd'hui.",
-			params(param("routine_id", "string", "L'ID du rituel")),
-			[]string{"routine_id"}),
-		toolWithParams("update_task", "Modifie une tâche existante.",
-			params(
-				param("task_id", "string", "L'ID de la tâche"),
-				param("title", "string", "Nouveau titre"),
-				param("date", "string", "Nouvelle date YYYY-MM-DD"),
-				paramEnum("priority", "string", "Nouvelle priorité", []string{"high", "medium", "low"}),
-				paramEnum("time_block", "string", "Nouveau bloc horaire", []string{"morning", "afternoon", "evening"}),
-			), []string{"task_id"}),
-		toolWithParams("delete_task", "Supprime une tâche.",
-			params(param("task_id", "string", "L'ID de la tâche")),
-			[]string{"task_id"}),
-		toolWithParams("delete_routine", "Supprime un rituel.",
-			params(param("routine_id", "string", "L'ID du rituel")),
-			[]string{"routine_id"}),
-		toolWithParams("start_focus_session", "Affiche le planning du jour en mode focus : les tâches avec boutons de sélection, choix de durée et timer intégré.",
-			params(
-				param("duration_minutes", "integer", "Durée en minutes"),
-				param("task_id", "string", "ID de la tâche (optionnel)"),
-				param("task_title", "string", "Titre de la tâche (optionnel)"),
-			), nil),
-		toolWithParams("block_apps", "Active le blocage d'apps pour la concentration.",
-			params(param("duration_minutes", "integer", "Durée en minutes (optionnel)")),
-			nil),
-		tool("unblock_apps", "Désactive le blocage d'apps."),
-		tool("show_force_unblock_card", "Affiche un bouton interactif pour forcer le déblocage quand l'utilisateur insiste que ses apps sont encore bloquées."),
-		toolWithParams("save_morning_checkin", "Sauvegarde le check-in matinal.",
-			params(
-				param("mood", "integer", "Humeur de 1 à 5"),
-				param("sleep_quality", "integer", "Qualité du sommeil de 1 à 5"),
-				param("intentions", "string", "Intentions pour la journée"),
-			), []string{"mood"}),
-		toolWithParams("save_evening_review", "Sauvegarde le bilan du soir.",
-			params(
-				param("biggest_win", "string", "Plus grande victoire"),
-				param("blockers", "string", "Blocages rencontrés"),
-				param("tomorrow_goal", "string", "Objectif pour demain"),
-			), nil),
-		toolWithParams("create_weekly_goals", "Crée les objectifs de la semaine.",
-			params(param("goals", "array", "Liste des objectifs (strings)")),
-			[]string{"goals"}),
-		toolWithParams("show_card", "Affiche une card interactive dans le chat.",
-			params(paramEnum("card_type", "string", "Type de card", []string{"tasks", "routines", "planning"})),
-			[]string{"card_type"}),
-		toolWithParams("save_favorite_video", "Sauvegarde la vidéo favorite de l'utilisateur.",
-			params(
-				param("url", "string", "URL YouTube"),
-				param("title", "string", "Titre de la vidéo (optionnel)"),
-			), []string{"url"}),
-		tool("get_favorite_video", "Récupère la vidéo favorite sauvegardée."),
-		toolWithParams("suggest_ritual_videos", "Suggère des vidéos populaires pour les rituels quotidiens.",
-			params(paramEnum("category", "string", "Catégorie", []string{"meditation", "breathing", "motivation", "prayer"})),
-			[]string{"category"}),
-		toolWithParams("set_morning_block", "Configure le blocage automatique matinal.",
-			params(
-				param("enabled", "boolean", "Activer/désactiver"),
-				param("start_hour", "integer", "Heure de début (0-23, défaut: 6)"),
-				param("start_minute", "integer", "Minute de début (0-59, défaut: 0)"),
-				param("end_hour", "integer", "Heure de fin (0-23, défaut: 9)"),
-				param("end_minute", "integer", "Minute de fin (0-59, défaut: 0)"),
-			), nil),
-		tool("get_morning_block_status", "Vérifie si le blocage matinal est configuré et retourne la plage horaire."),
-		tool("start_morning_flow", "Récupère TOUT le contexte matinal en un seul appel : user, tâches, rituels, blocage, check-in, streak, événements calendrier."),
-		toolWithParams("get_calendar_events", "Récupère les événements du calendrier externe (Google Calendar) pour une date.",
-			params(param("date", "string", "Date YYYY-MM-DD (défaut: aujourd'hui)")),
-			nil),
-		toolWithParams("schedule_calendar_blocking", "Active/désactive le blocage d'apps pendant des événements calendrier.",
-			params(
-				param("event_ids", "array", "IDs des événements"),
-				param("enabled", "boolean", "Activer/désactiver le blocage"),
-			), []string{"event_ids"}),
-		toolWithParams("get_planning_context", "Récupère le contexte complet de planification : tâches existantes + événements calendrier pour la période demandée. Utilise-le quand l'utilisateur veut planifier sa journée, demain ou sa semaine.",
-			params(paramEnum("scope", "string", "Période à planifier", []string{"today", "tomorrow", "2days", "week"})),
-			[]string{"scope"}),
-		toolWithParams("create_tasks_batch", "Crée plusieurs tâches d'un coup. Utilise après une session de planification pour créer toutes les tâches en une seule fois. Quand tu spécifies scheduled_start/scheduled_end avec block_apps=true, les apps sont automatiquement bloquées pendant ces créneaux.",
-			params(param("tasks", "array", "Liste des tâches à créer. Chaque tâche: {title, date (YYYY-MM-DD), time_block (morning/afternoon/evening), priority (high/medium/low), estimated_minutes, scheduled_start (HH:mm), scheduled_end (HH:mm), block_apps (boolean — true pour bloquer les apps pendant cette tâche)}")),
-			[]string{"tasks"}),
-		toolWithParams("save_memory", "Sauvegarde un fait important sur l'utilisateur dans la mémoire long terme.",
-			params(
-				param("content", "string", "Le fait à sauvegarder (formulé à la 3ème personne)"),
-				paramEnum("category", "string", "Catégorie du souvenir", []string{"goal", "preference", "life_event", "feeling", "challenge", "achievement", "fact"}),
-			), []string{"content", "category"}),
-		toolWithParams("save_productivity_challenges", "Sauvegarde les défis de productivité identifiés pendant le diagnostic coaching. Appelle ce tool quand l'utilisateur a confirmé ses principaux blocages (max 5).",
-			params(
-				param("challenges", "array", "Liste des IDs de défis: fatigue_decisionnelle, incapacite_prioriser, dispersion_deep_work, multitache_illusoire, perfectionnisme, peur_echec, syndrome_imposteur, culpabilite_repos, surestimation, absence_systemes, gestion_interruptions, perte_information, perte_pourquoi, absence_recompense, ennui_repetition, desordre, limites_pro_perso, dependance_outils, isolement_social, manque_feedback"),
-			), []string{"challenges"}),
-	}
-}
-
-// ==========================================
-// Tool Definition Helpers
-// ==========================================
-
-func tool(name, description string) ToolDef {
-	return ToolDef{Name: name, Description: description}
-}
-
-func toolWithParams(name, description string, parameters map[string]interface{}, required []string) ToolDef {
-	td := ToolDef{
-		Name:        name,
-		Description: description,
-		Parameters:  parameters,
-		Required:    required,
-	}
-	return td
-}
-
-func params(props ...map[string]interface{}) map[string]interface{} {
-	merged := make(map[string]interface{})
-	for _, p := range props {
-		for k, v := range p {
-			merged[k] = v
-		}
-	}
-	return merged
-}
-
-func param(name, typ, description string) map[string]interface{} {
-	return map[string]interface{}{
-		name: map[string]interface{}{
-			"type":        typ,
-			"description": description,
-		},
-	}
-}
-
-func paramEnum(name, typ, description string, values []string) map[string]interface{} {
-	return map[string]interface{}{
-		name: map[string]interface{}{
-			"type":        typ,
-			"description": description,
-			"enum":        values,
-		},
+func toolDefinitions(registry *tools.Registry) []ToolDef {
+	defs := registry.Definitions()
+	out := make([]ToolDef, 0, len(defs))
+	for _, d := range defs {
+		out = append(out, ToolDef{
+			Name:        d.Name,
+			Description: d.Description,
+			Parameters:  d.Parameters,
+			Required:    d.Required,
+		})
 	}
+	return out
 }
@@ -0,0 +1,90 @@
+package backboard
+
+import (
+	"context"
+	"errors"
+
+	"firelevel-backend/internal/llm"
+)
+
+// ==========================================
+// llm.Provider adapter
+// ==========================================
+// Backboard keeps the system prompt and tools on the assistant and the
+// history in the thread, so only Conversation.ThreadID is used here.
+// ==========================================
+
+// LLMProvider runs chat turns on a Backboard thread.
+type LLMProvider struct {
+	client *Client
+}
+
+// NewLLMProvider wraps client as an llm.Provider.
+func NewLLMProvider(client *Client) *LLMProvider {
+	return &LLMProvider{client: client}
+}
+
+// Name implements llm.Provider.
+func (p *LLMProvider) Name() string { return "backboard" }
+
+// Send implements llm.Provider.
+func (p *LLMProvider) Send(ctx context.Context, conv *llm.Conversation, content string) (*llm.Response, error) {
+	if conv.ThreadID == "" {
+		return nil, errors.New("backboard: conversation has no thread")
+	}
+	resp, err := p.client.SendMessage(ctx, conv.ThreadID, content)
+	if err != nil {
+		return nil, err
+	}
+	return toLLMResponse(resp), nil
+}
+
+// SubmitToolOutputs implements llm.Provider.
+func (p *LLMProvider) SubmitToolOutputs(ctx context.Context, conv *llm.Conversation, runID string, outputs []llm.ToolOutput) (*llm.Response, error) {
+	bbOutputs := make([]ToolOutput, 0, len(outputs))
+	for _, out := range outputs {
+		bbOutputs = append(bbOutputs, ToolOutput{ToolCallID: out.ToolCallID, Output: out.Output})
+	}
+	resp, err := p.client.SubmitToolOutputs(ctx, conv.ThreadID, runID, bbOutputs)
+	if err != nil {
+		return nil, err
+	}
+	return toLLMResponse(resp), nil
+}
+
+// toLLMResponse keeps tool calls only while the run is REQUIRES_ACTION.
+func toLLMResponse(resp *MessageResponse) *llm.Response {
+	out := &llm.Response{}
+	if resp.Content != nil {
+		out.Content = *resp.Content
+	}
+	if resp.Status == nil || *resp.Status != "REQUIRES_ACTION" || resp.RunID == nil {
+		return out
+	}
+	out.RunID = *resp.RunID
+	for _, tc := range resp.ToolCalls {
+		out.ToolCalls = append(out.ToolCalls, llm.ToolCall{
+			ID:        tc.ID,
+			Name:      tc.Function.Name,
+			Arguments: tc.Function.Arguments,
+		})
+	}
+	return out
+}
+
+// Tools returns the executor's tool registry as provider-agnostic
+// declarations, for providers that take tools with each turn. Backboard
+// gets the same registry through BuildAssistantConfig.
+func (e *Executor) Tools() []llm.Tool {
+	defs := toolDefinitions(e.registry)
+	tools := make([]llm.Tool, 0, len(defs))
+	for _, d := range defs {
+		tools = append(tools, llm.Tool{
+			Name:        d.Name,
+			Description: d.Description,
+			Parameters:  d.Parameters,
+			Required:    d.Required,
+		})
+	}
+	return tools
+}
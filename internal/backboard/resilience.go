@@ -0,0 +1,166 @@
+package backboard
+
+import (
+	"context"
+	"errors"
+	"log"
+	"math/rand"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ==========================================
+// Retries and circuit breaker
+// ==========================================
+// Client.do retries rate limits and transient failures with exponential
+// backoff (or whatever Retry-After asks for, as long as it fits in
+// MaxDelay; a longer wait ends the retries), and goes through a circuit
+// breaker shared by every Client: once Backboard keeps failing, calls fail
+// fast with ErrCircuitOpen instead of each user waiting for a timeout.
+// ==========================================
+
+// RetryPolicy bounds how a failed call is retried.
+type RetryPolicy struct {
+	MaxAttempts int           // Including the first one
+	BaseDelay   time.Duration // Doubled after each attempt, with jitter
+	MaxDelay    time.Duration // Cap on the backoff; a longer Retry-After is not retried
+}
+
+// DefaultRetryPolicy keeps the worst case well within the chat handlers'
+// 55s budget.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 3,
+	BaseDelay:   500 * time.Millisecond,
+	MaxDelay:    8 * time.Second,
+}
+
+// delay returns the wait before retry number attempt (1-based). ok is
+// false when the server asks for more than MaxDelay: retrying earlier
+// would be refused again, so the call gives up instead.
+func (p RetryPolicy) delay(attempt int, retryAfter time.Duration) (d time.Duration, ok bool) {
+	if retryAfter > 0 {
+		return retryAfter, retryAfter <= p.MaxDelay
+	}
+	d = p.BaseDelay << (attempt - 1)
+	d += time.Duration(rand.Int63n(int64(d)/2 + 1))
+	if d > p.MaxDelay {
+		d = p.MaxDelay
+	}
+	return d, true
+}
+
+// retryable reports whether a call that failed with err may be sent
+// again. A 429 or 503 was not processed, so any method is safe. Other
+// server and network errors may have been, so only idempotent methods
+// are retried: sending a chat message twice would duplicate it.
+func retryable(method string, err error) bool {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		switch {
+		case apiErr.StatusCode == http.StatusTooManyRequests, apiErr.StatusCode == http.StatusServiceUnavailable:
+			return true
+		case apiErr.StatusCode >= 500:
+			return idempotent(method)
+		default:
+			return false
+		}
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) || errors.Is(err, ErrCircuitOpen) {
+		return false
+	}
+	return idempotent(method)
+}
+
+func idempotent(method string) bool {
+	return method == http.MethodGet || method == http.MethodDelete || method == http.MethodPut
+}
+
+// sleepCtx waits d or until ctx is done.
+func sleepCtx(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+// CircuitBreaker opens after Threshold consecutive failures and lets a
+// single trial call through once Cooldown has passed.
+type CircuitBreaker struct {
+	Threshold int
+	Cooldown  time.Duration
+
+	mu        sync.Mutex
+	failures  int
+	openUntil time.Time
+	trial     bool // A half-open trial call is in flight
+}
+
+// NewCircuitBreaker creates a closed breaker.
+func NewCircuitBreaker(threshold int, cooldown time.Duration) *CircuitBreaker {
+	return &CircuitBreaker{Threshold: threshold, Cooldown: cooldown}
+}
+
+// sharedBreaker guards every Client created with NewClient: handlers
+// create a Client per request, the outage is the same for all of them.
+var sharedBreaker = NewCircuitBreaker(5, 30*time.Second)
+
+// allow reports whether a call may be made now.
+func (b *CircuitBreaker) allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.failures < b.Threshold {
+		return true
+	}
+	if time.Now().Before(b.openUntil) || b.trial {
+		return false
+	}
+	b.trial = true
+	return true
+}
+
+// record updates the breaker with the outcome of a call. Only failures
+// that say Backboard is unhealthy count; a 404 or a 400 is our problem and
+// proves Backboard answers. A canceled call or a rate limit says nothing
+// either way: the failure count is left alone, and a half-open breaker
+// stays half-open for the next trial.
+func (b *CircuitBreaker) record(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.trial = false
+
+	switch {
+	case neutral(err):
+		return
+	case err == nil || !unhealthy(err):
+		if b.failures >= b.Threshold {
+			log.Printf("✅ Backboard circuit closed")
+		}
+		b.failures = 0
+		return
+	}
+
+	b.failures++
+	if b.failures >= b.Threshold {
+		b.openUntil = time.Now().Add(b.Cooldown)
+		log.Printf("⚡ Backboard circuit open for %s after %d consecutive failures: %v", b.Cooldown, b.failures, err)
+	}
+}
+
+// neutral reports whether err tells nothing about Backboard's health.
+func neutral(err error) bool {
+	return errors.Is(err, context.Canceled) || errors.Is(err, ErrRateLimited)
+}
+
+func unhealthy(err error) bool {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return errors.Is(apiErr, ErrServer)
+	}
+	// Transport errors and timeouts
+	return true
+}
@@ -0,0 +1,157 @@
+package backboard
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// stubServer answers every request with the statuses in order, repeating
+// the last one, and counts the requests.
+func stubServer(t *testing.T, header http.Header, statuses ...int) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := int(calls.Add(1))
+		status := statuses[min(n, len(statuses))-1]
+		for k, v := range header {
+			w.Header()[k] = v
+		}
+		w.WriteHeader(status)
+		w.Write([]byte(`{"memories": []}`))
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &calls
+}
+
+var fastRetries = RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: 50 * time.Millisecond}
+
+func TestDoRetriesTransientFailures(t *testing.T) {
+	srv, calls := stubServer(t, nil, http.StatusServiceUnavailable, http.StatusOK)
+	c := NewClient("key", WithBaseURL(srv.URL), WithRetryPolicy(fastRetries), WithCircuitBreaker(NewCircuitBreaker(5, time.Minute)))
+
+	if _, err := c.ListMemories(context.Background(), "a1"); err != nil {
+		t.Fatalf("ListMemories: %v", err)
+	}
+	if got := calls.Load(); got != 2 {
+		t.Errorf("calls = %d, want 2", got)
+	}
+}
+
+func TestDoGivesUpOnLongRetryAfter(t *testing.T) {
+	srv, calls := stubServer(t, http.Header{"Retry-After": {"30"}}, http.StatusTooManyRequests)
+	c := NewClient("key", WithBaseURL(srv.URL), WithRetryPolicy(fastRetries), WithCircuitBreaker(NewCircuitBreaker(5, time.Minute)))
+
+	start := time.Now()
+	_, err := c.ListMemories(context.Background(), "a1")
+	if !errors.Is(err, ErrRateLimited) {
+		t.Fatalf("err = %v, want ErrRateLimited", err)
+	}
+	if got := calls.Load(); got != 1 {
+		t.Errorf("calls = %d, want 1", got)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("gave up after %s, want no wait", elapsed)
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	p := RetryPolicy{MaxAttempts: 3, BaseDelay: 500 * time.Millisecond, MaxDelay: 8 * time.Second}
+
+	if d, ok := p.delay(1, 2*time.Second); !ok || d != 2*time.Second {
+		t.Errorf("delay with Retry-After 2s = %s, %v; want 2s, true", d, ok)
+	}
+	if _, ok := p.delay(1, 20*time.Second); ok {
+		t.Error("Retry-After beyond MaxDelay should not be retried")
+	}
+	if d, ok := p.delay(10, 0); !ok || d != p.MaxDelay {
+		t.Errorf("backoff = %s, %v; want capped at %s", d, ok, p.MaxDelay)
+	}
+}
+
+func TestBreakerOpensOnServerErrors(t *testing.T) {
+	srv, calls := stubServer(t, nil, http.StatusInternalServerError)
+	b := NewCircuitBreaker(2, time.Minute)
+	c := NewClient("key", WithBaseURL(srv.URL), WithRetryPolicy(RetryPolicy{MaxAttempts: 1}), WithCircuitBreaker(b))
+
+	for i := 0; i < 2; i++ {
+		if _, err := c.ListMemories(context.Background(), "a1"); !errors.Is(err, ErrServer) {
+			t.Fatalf("call %d: err = %v, want ErrServer", i, err)
+		}
+	}
+	if _, err := c.ListMemories(context.Background(), "a1"); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("err = %v, want ErrCircuitOpen", err)
+	}
+	if got := calls.Load(); got != 2 {
+		t.Errorf("calls = %d, want 2", got)
+	}
+}
+
+func TestBreakerIgnoresRateLimits(t *testing.T) {
+	srv, _ := stubServer(t, nil, http.StatusTooManyRequests)
+	b := NewCircuitBreaker(2, time.Minute)
+	c := NewClient("key", WithBaseURL(srv.URL), WithRetryPolicy(RetryPolicy{MaxAttempts: 1}), WithCircuitBreaker(b))
+
+	for i := 0; i < 5; i++ {
+		if _, err := c.ListMemories(context.Background(), "a1"); !errors.Is(err, ErrRateLimited) {
+			t.Fatalf("call %d: err = %v, want ErrRateLimited", i, err)
+		}
+	}
+	if b.failures != 0 {
+		t.Errorf("failures = %d, want 0", b.failures)
+	}
+}
+
+func TestBreakerCanceledIsNeutral(t *testing.T) {
+	b := NewCircuitBreaker(2, time.Minute)
+	b.record(&APIError{Kind: ErrServer, StatusCode: 500})
+	b.record(context.Canceled)
+	if b.failures != 1 {
+		t.Errorf("failures = %d after a canceled call, want 1", b.failures)
+	}
+
+	// Half-open: the cooldown is over and a trial call is let through.
+	b.record(&APIError{Kind: ErrServer, StatusCode: 500})
+	b.openUntil = time.Now().Add(-time.Second)
+	if !b.allow() {
+		t.Fatal("half-open breaker should allow a trial call")
+	}
+	b.record(context.Canceled)
+	if b.failures < b.Threshold {
+		t.Fatal("a canceled trial closed the breaker")
+	}
+	if !b.allow() {
+		t.Fatal("a new trial should be allowed after a canceled one")
+	}
+	b.record(nil)
+	if b.failures != 0 {
+		t.Errorf("failures = %d after a successful trial, want 0", b.failures)
+	}
+}
+
+func TestBreakerCanceledRequest(t *testing.T) {
+	srv, _ := stubServer(t, nil, http.StatusOK)
+	b := NewCircuitBreaker(1, time.Minute)
+	b.record(&APIError{Kind: ErrServer, StatusCode: 500})
+	b.openUntil = time.Now().Add(-time.Second)
+	c := NewClient("key", WithBaseURL(srv.URL), WithRetryPolicy(fastRetries), WithCircuitBreaker(b))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := c.ListMemories(ctx, "a1"); !errors.Is(err, context.Canceled) {
+		t.Fatalf("err = %v, want context.Canceled", err)
+	}
+	if b.failures != 1 {
+		t.Errorf("failures = %d, want 1", b.failures)
+	}
+	if _, err := c.ListMemories(context.Background(), "a1"); err != nil {
+		t.Fatalf("trial after a canceled call: %v", err)
+	}
+	if b.failures != 0 {
+		t.Errorf("failures = %d, want 0", b.failures)
+	}
+}
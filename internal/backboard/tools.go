@@ -3,102 +3,265 @@ package backboard
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"time"
+
+	"firelevel-backend/internal/calendar"
+	"firelevel-backend/internal/checkins"
+	"firelevel-backend/internal/focus"
+	"firelevel-backend/internal/routines"
+	"firelevel-backend/internal/stats"
+	"firelevel-backend/internal/tools"
+	"firelevel-backend/internal/userclock"
+	"firelevel-backend/internal/weeklygoals"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+// ==========================================
+// Tool Registry
+// ==========================================
+// Domain tools are registered by their own packages; the coach tools
+// below (context, reflections, memory, cards, planning) live here.
+// ==========================================
+
+// NewRegistry builds the registry of every tool Kai can call. main.go
+// builds it once at startup, so a duplicate tool name stops the server
+// before it takes traffic.
+func NewRegistry() (*tools.Registry, error) {
+	reg := tools.NewRegistry()
+	for _, register := range []func(*tools.Registry) error{
+		registerContextTools,
+		calendar.RegisterTools,
+		routines.RegisterTools,
+		focus.RegisterTools,
+		registerCoachTools,
+	} {
+		if err := register(reg); err != nil {
+			return nil, err
+		}
+	}
+	return reg, nil
+}
+
+func registerContextTools(reg *tools.Registry) error {
+	return reg.Register(
+		tools.Func[tools.NoArgs]{
+			Name:        "get_user_context",
+			Description: "Récupère le contexte actuel: tâches, rituels, minutes focus, moment de la journée, statut blocage apps.",
+			Run: func(ctx context.Context, call *tools.Call, _ tools.NoArgs) (tools.Result, error) {
+				result, err := userContext(ctx, call)
+				return tools.Result{Output: result}, err
+			},
+		},
+		tools.Func[tools.NoArgs]{
+			Name:        "get_current_datetime",
+			Description: "Retourne la date et l'heure exactes actuelles. Appelle ce tool quand tu as besoin de connaître la date ou l'heure précise, ou pour calculer des dates futures (demain, dans 3 jours, etc).",
+			Run:         runGetCurrentDatetime,
+		},
+	)
+}
+
+func registerCoachTools(reg *tools.Registry) error {
+	return reg.Register(
+		tools.Func[morningCheckinArgs]{
+			Name:        "save_morning_checkin",
+			Description: "Sauvegarde le check-in matinal.",
+			Run:         runSaveMorningCheckin,
+		},
+		tools.Func[eveningReviewArgs]{
+			Name:        "save_evening_review",
+			Description: "Sauvegarde le bilan du soir.",
+			Run:         runSaveEveningReview,
+		},
+		tools.Func[weeklyGoalsArgs]{
+			Name:        "create_weekly_goals",
+			Description: "Crée les objectifs de la semaine.",
+			Run:         runCreateWeeklyGoals,
+		},
+		tools.Func[showCardArgs]{
+			Name:        "show_card",
+			Description: "Affiche une card interactive dans le chat.",
+			Run: func(ctx context.Context, call *tools.Call, args showCardArgs) (tools.Result, error) {
+				return tools.Result{
+					Output:      map[string]interface{}{"shown": true},
+					SideEffects: []tools.SideEffect{tools.ShowCard(args.CardType)},
+				}, nil
+			},
+		},
+		tools.Func[favoriteVideoArgs]{
+			Name:        "save_favorite_video",
+			Description: "Sauvegarde la vidéo favorite de l'utilisateur.",
+			Run: func(ctx context.Context, call *tools.Call, args favoriteVideoArgs) (tools.Result, error) {
+				return tools.Result{
+					Output:      map[string]interface{}{"saved": true},
+					SideEffects: []tools.SideEffect{tools.NewSideEffectWithData("save_favorite_video", map[string]string{"url": args.URL, "title": args.Title})},
+				}, nil
+			},
+		},
+		tools.Func[tools.NoArgs]{
+			Name:        "get_favorite_video",
+			Description: "Récupère la vidéo favorite sauvegardée.",
+			Run: func(ctx context.Context, call *tools.Call, _ tools.NoArgs) (tools.Result, error) {
+				// TODO: migrate to DB; for now return empty
+				return tools.Result{Output: map[string]interface{}{"url": nil, "title": nil}}, nil
+			},
+		},
+		tools.Func[ritualVideosArgs]{
+			Name:        "suggest_ritual_videos",
+			Description: "Suggère des vidéos populaires pour les rituels quotidiens.",
+			Run: func(ctx context.Context, call *tools.Call, args ritualVideosArgs) (tools.Result, error) {
+				return tools.Result{
+					Output:      map[string]interface{}{"category": args.Category, "shown": true},
+					SideEffects: []tools.SideEffect{tools.NewSideEffectWithData("show_video_suggestions", map[string]string{"category": args.Category})},
+				}, nil
+			},
+		},
+		tools.Func[tools.NoArgs]{
+			Name:        "start_morning_flow",
+			Description: "Récupère TOUT le contexte matinal en un seul appel : user, tâches, rituels, blocage, check-in, streak, événements calendrier.",
+			Run:         runStartMorningFlow,
+		},
+		tools.Func[calendarEventsArgs]{
+			Name:        "get_calendar_events",
+			Description: "Récupère les événements du calendrier externe (Google Calendar) pour une date.",
+			Run:         runGetCalendarEvents,
+		},
+		tools.Func[calendarBlockingArgs]{
+			Name:        "schedule_calendar_blocking",
+			Description: "Active/désactive le blocage d'apps pendant des événements calendrier.",
+			Run:         runScheduleCalendarBlocking,
+		},
+		tools.Func[planningContextArgs]{
+			Name:        "get_planning_context",
+			Description: "Récupère le contexte complet de planification : tâches existantes + événements calendrier pour la période demandée. Utilise-le quand l'utilisateur veut planifier sa journée, demain ou sa semaine.",
+			Run:         runGetPlanningContext,
+		},
+		tools.Func[saveMemoryArgs]{
+			Name:        "save_memory",
+			Description: "Sauvegarde un fait important sur l'utilisateur dans la mémoire long terme.",
+			Run:         runSaveMemory,
+		},
+		tools.Func[productivityChallengesArgs]{
+			Name:        "save_productivity_challenges",
+			Description: "Sauvegarde les défis de productivité identifiés pendant le diagnostic coaching. Appelle ce tool quand l'utilisateur a confirmé ses principaux blocages (max 5).",
+			Run:         runSaveProductivityChallenges,
+		},
+	)
+}
+
+// ==========================================
+// Arguments
+// ==========================================
+
+type morningCheckinArgs struct {
+	Mood         int    `json:"mood" desc:"Humeur de 1 à 5" required:"true" min:"1" max:"5"`
+	SleepQuality int    `json:"sleep_quality,omitempty" desc:"Qualité du sommeil de 1 à 5" min:"0" max:"5"`
+	Intentions   string `json:"intentions,omitempty" desc:"Intentions pour la journée"`
+}
+
+type eveningReviewArgs struct {
+	BiggestWin   string `json:"biggest_win,omitempty" desc:"Plus grande victoire"`
+	Blockers     string `json:"blockers,omitempty" desc:"Blocages rencontrés"`
+	TomorrowGoal string `json:"tomorrow_goal,omitempty" desc:"Objectif pour demain"`
+}
+
+type weeklyGoalsArgs struct {
+	Goals []string `json:"goals" desc:"Liste des objectifs" required:"true"`
+}
+
+type showCardArgs struct {
+	CardType string `json:"card_type" desc:"Type de card" required:"true" enum:"tasks,routines,planning"`
+}
+
+type favoriteVideoArgs struct {
+	URL   string `json:"url" desc:"URL YouTube" required:"true"`
+	Title string `json:"title,omitempty" desc:"Titre de la vidéo (optionnel)"`
+}
+
+type ritualVideosArgs struct {
+	Category string `json:"category" desc:"Catégorie" required:"true" enum:"meditation,breathing,motivation,prayer"`
+}
+
+type calendarEventsArgs struct {
+	Date string `json:"date,omitempty" desc:"Date YYYY-MM-DD (défaut: aujourd'hui)" format:"date"`
+}
+
+type calendarBlockingArgs struct {
+	EventIDs []string `json:"event_ids" desc:"IDs des événements" required:"true"`
+	Enabled  *bool    `json:"enabled,omitempty" desc:"Activer/désactiver le blocage"`
+}
+
+type planningContextArgs struct {
+	Scope string `json:"scope" desc:"Période à planifier" required:"true" enum:"today,tomorrow,2days,week"`
+}
+
+type saveMemoryArgs struct {
+	Content  string `json:"content" desc:"Le fait à sauvegarder (formulé à la 3ème personne)" required:"true"`
+	Category string `json:"category" desc:"Catégorie du souvenir" required:"true" enum:"personal,work,goals,preferences"`
+}
+
+type productivityChallengesArgs struct {
+	Challenges []string `json:"challenges" desc:"Liste des IDs de défis (max 5)" required:"true" enum:"fatigue_decisionnelle,incapacite_prioriser,dispersion_deep_work,multitache_illusoire,perfectionnisme,peur_echec,syndrome_imposteur,culpabilite_repos,surestimation,absence_systemes,gestion_interruptions,perte_information,perte_pourquoi,absence_recompense,ennui_repetition,desordre,limites_pro_perso,dependance_outils,isolement_social,manque_feedback"`
+}
+
 // ==========================================
 // Context & DateTime Tools
 // ==========================================
 
-func (e *Executor) getUserContext(ctx context.Context, userID string, deviceCtx *DeviceContext) (string, error) {
+// UserContext returns the get_user_context snapshot for userID, for code
+// that talks about the user outside a chat turn (proactive messages).
+func UserContext(ctx context.Context, db *pgxpool.Pool, userID string) (map[string]interface{}, error) {
+	return userContext(ctx, &tools.Call{DB: db, UserID: userID})
+}
+
+// userContext is the snapshot behind get_user_context and the composite
+// morning and planning tools.
+func userContext(ctx context.Context, call *tools.Call) (map[string]interface{}, error) {
 	// Fetch user info
 	var userName, companionName, userLanguage string
 	var satisfactionScore int
-	err := e.db.QueryRow(ctx, `
+	err := call.DB.QueryRow(ctx, `
 		SELECT COALESCE(pseudo, first_name, ''),
 		       COALESCE(companion_name, 'Kai'),
 		       COALESCE(language, 'fr'),
 		       COALESCE(satisfaction_score, 45)
 		FROM public.users WHERE id = $1
-	`, userID).Scan(&userName, &companionName, &userLanguage, &satisfactionScore)
+	`, call.UserID).Scan(&userName, &companionName, &userLanguage, &satisfactionScore)
 	if err != nil {
-		return "", fmt.Errorf("get user: %w", err)
+		return nil, fmt.Errorf("get user: %w", err)
 	}
 
-	today := todayStr(ctx, userID, e.db)
+	clock := userclock.For(ctx, call.DB, call.UserID)
+	today := clock.Today()
 
-	// Count tasks
-	var tasksTotal, tasksCompleted int
-	if err := e.db.QueryRow(ctx, "SELECT COUNT(*) FROM tasks WHERE user_id = $1 AND date = $2", userID, today).Scan(&tasksTotal); err != nil {
-		log.Printf("Failed to count tasks for user %s: %v", userID, err)
-	}
-	if err := e.db.QueryRow(ctx, "SELECT COUNT(*) FROM tasks WHERE user_id = $1 AND date = $2 AND status = 'completed'", userID, today).Scan(&tasksCompleted); err != nil {
-		log.Printf("Failed to count completed tasks for user %s: %v", userID, err)
-	}
-
-	// Count rituals
-	var ritualsTotal, ritualsCompleted int
-	if err := e.db.QueryRow(ctx, "SELECT COUNT(*) FROM routines WHERE user_id = $1", userID).Scan(&ritualsTotal); err != nil {
-		log.Printf("Failed to count routines for user %s: %v", userID, err)
-	}
-	if err := e.db.QueryRow(ctx, `
-		SELECT COUNT(*) FROM routine_completions
-		WHERE user_id = $1 AND completion_date = $2
-	`, userID, today).Scan(&ritualsCompleted); err != nil {
-		log.Printf("Failed to count routine completions for user %s: %v", userID, err)
-	}
-
-	// Focus minutes today
-	var focusMinutes int
-	if err := e.db.QueryRow(ctx, `
-		SELECT COALESCE(SUM(duration_minutes), 0) FROM focus_sessions
-		WHERE user_id = $1 AND DATE(started_at) = $2
-	`, userID, today).Scan(&focusMinutes); err != nil {
-		log.Printf("Failed to sum focus minutes for user %s: %v", userID, err)
+	// Tasks, rituals, focus and streak
+	day, err := stats.GetToday(ctx, call.DB, call.UserID, clock)
+	if err != nil {
+		log.Printf("Failed to get today's stats for user %s: %v", call.UserID, err)
+		day = &stats.Today{Date: today}
 	}
 
 	// Time of day
-	now := time.Now()
-	hour := now.Hour()
-	timeOfDay := "night"
-	switch {
-	case hour >= 5 && hour < 12:
-		timeOfDay = "morning"
-	case hour >= 12 && hour < 18:
-		timeOfDay = "afternoon"
-	case hour >= 18 && hour < 22:
-		timeOfDay = "evening"
-	}
+	timeOfDay := clock.TimeOfDay()
 
 	// Check-in status
-	var morningCheckinDone, eveningReviewDone bool
-	if err := e.db.QueryRow(ctx, `
-		SELECT EXISTS(SELECT 1 FROM daily_reflections WHERE user_id = $1 AND date = $2 AND mood IS NOT NULL)
-	`, userID, today).Scan(&morningCheckinDone); err != nil {
-		log.Printf("Failed to check morning checkin for user %s: %v", userID, err)
-	}
-	if err := e.db.QueryRow(ctx, `
-		SELECT EXISTS(SELECT 1 FROM daily_reflections WHERE user_id = $1 AND date = $2 AND biggest_win IS NOT NULL)
-	`, userID, today).Scan(&eveningReviewDone); err != nil {
-		log.Printf("Failed to check evening review for user %s: %v", userID, err)
-	}
-
-	// Streak
-	var currentStreak int
-	if err := e.db.QueryRow(ctx, "SELECT COALESCE(current_streak, 0) FROM public.users WHERE id = $1", userID).Scan(&currentStreak); err != nil {
-		log.Printf("Failed to fetch streak for user %s: %v", userID, err)
+	morningCheckinDone, eveningReviewDone, err := checkins.Done(ctx, call.DB, call.UserID, today)
+	if err != nil {
+		log.Printf("Failed to check check-ins for user %s: %v", call.UserID, err)
 	}
 
 	// Days since last message
 	var daysSinceLastMessage int
-	err = e.db.QueryRow(ctx, `
+	err = call.DB.QueryRow(ctx, `
 		SELECT COALESCE(
-			(CURRENT_DATE - last_active_date)::int,
+			($2::date - last_active_date)::int,
 			-1
 		) FROM public.users WHERE id = $1
-	`, userID).Scan(&daysSinceLastMessage)
+	`, call.UserID, today).Scan(&daysSinceLastMessage)
 	if err != nil {
 		daysSinceLastMessage = -1
 	}
@@ -107,19 +270,19 @@ func (e *Executor) getUserContext(ctx context.Context, userID string, deviceCtx
 	morningBlockEnabled := false
 	morningBlockStart := "06:00"
 	morningBlockEnd := "09:00"
-	if deviceCtx != nil {
-		appsBlocked = deviceCtx.AppsBlocked
-		morningBlockEnabled = deviceCtx.MorningBlockEnabled
-		morningBlockStart = deviceCtx.MorningBlockStart
-		morningBlockEnd = deviceCtx.MorningBlockEnd
+	if call.Device != nil {
+		appsBlocked = call.Device.AppsBlocked
+		morningBlockEnabled = call.Device.MorningBlockEnabled
+		morningBlockStart = call.Device.MorningBlockStart
+		morningBlockEnd = call.Device.MorningBlockEnd
 	}
 
 	// Fetch user's productivity challenges from onboarding diagnostic
 	var productivityChallenges []string
 	var onboardingResponses json.RawMessage
-	err = e.db.QueryRow(ctx, `
+	err = call.DB.QueryRow(ctx, `
 		SELECT COALESCE(responses, '{}'::jsonb) FROM public.user_onboarding WHERE user_id = $1
-	`, userID).Scan(&onboardingResponses)
+	`, call.UserID).Scan(&onboardingResponses)
 	if err == nil && len(onboardingResponses) > 0 {
 		var respMap map[string]interface{}
 		if json.Unmarshal(onboardingResponses, &respMap) == nil {
@@ -136,54 +299,62 @@ func (e *Executor) getUserContext(ctx context.Context, userID string, deviceCtx
 	}
 
 	result := map[string]interface{}{
-		"user_name":             userName,
-		"companion_name":        companionName,
-		"today_date":            today,
-		"tasks_today":           tasksTotal,
-		"tasks_completed":       tasksCompleted,
-		"rituals_today":         ritualsTotal,
-		"rituals_completed":     ritualsCompleted,
-		"focus_minutes_today":   focusMinutes,
-		"time_of_day":           timeOfDay,
-		"apps_blocked":          appsBlocked,
-		"satisfaction_score":     satisfactionScore,
-		"morning_checkin_done":  morningCheckinDone,
-		"evening_review_done":  eveningReviewDone,
-		"current_streak":        currentStreak,
-		"all_tasks_completed":   tasksTotal > 0 && tasksCompleted == tasksTotal,
-		"all_rituals_completed": ritualsTotal > 0 && ritualsCompleted == ritualsTotal,
-		"user_language":         userLanguage,
-		"morning_block_enabled":    morningBlockEnabled,
-		"morning_block_start":      morningBlockStart,
-		"morning_block_end":        morningBlockEnd,
-		"days_since_last_message":  daysSinceLastMessage,
+		"user_name":               userName,
+		"companion_name":          companionName,
+		"today_date":              today,
+		"tasks_today":             day.TasksTotal,
+		"tasks_completed":         day.TasksCompleted,
+		"rituals_today":           day.RitualsTotal,
+		"rituals_completed":       day.RitualsCompleted,
+		"focus_minutes_today":     day.FocusMinutes,
+		"time_of_day":             timeOfDay,
+		"apps_blocked":            appsBlocked,
+		"satisfaction_score":      satisfactionScore,
+		"morning_checkin_done":    morningCheckinDone,
+		"evening_review_done":     eveningReviewDone,
+		"current_streak":          day.CurrentStreak,
+		"all_tasks_completed":     day.TasksTotal > 0 && day.TasksCompleted == day.TasksTotal,
+		"all_rituals_completed":   day.RitualsTotal > 0 && day.RitualsCompleted == day.RitualsTotal,
+		"user_language":           userLanguage,
+		"morning_block_enabled":   morningBlockEnabled,
+		"morning_block_start":     morningBlockStart,
+		"morning_block_end":       morningBlockEnd,
+		"days_since_last_message": daysSinceLastMessage,
 	}
 
 	if len(productivityChallenges) > 0 {
 		result["productivity_challenges"] = productivityChallenges
 	}
 
-	return toJSON(result), nil
+	return result, nil
 }
 
 // ==========================================
 // Coaching Diagnostic
 // ==========================================
 
-func (e *Executor) saveProductivityChallenges(ctx context.Context, userID string, args map[string]interface{}) (string, error) {
-	challenges := stringArrayArg(args, "challenges")
-	if len(challenges) == 0 {
-		return errorJSON(fmt.Errorf("no challenges provided")), nil
-	}
-
+func runSaveProductivityChallenges(ctx context.Context, call *tools.Call, args productivityChallengesArgs) (tools.Result, error) {
 	// Cap at 5
+	challenges := args.Challenges
 	if len(challenges) > 5 {
 		challenges = challenges[:5]
 	}
 
-	challengesJSON, _ := json.Marshal(map[string]interface{}{
+	challengesJSON, err := json.Marshal(map[string]interface{}{
 		"productivity_challenges": challenges,
 	})
+	if err != nil {
+		return tools.Result{}, fmt.Errorf("marshal challenges: %w", err)
+	}
+
+	// user_onboarding has one row per user: the change is keyed on user_id
+	var before json.RawMessage
+	err = call.DB.QueryRow(ctx, `
+		SELECT to_jsonb(o) FROM public.user_onboarding o WHERE user_id = $1
+	`, call.UserID).Scan(&before)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return tools.Result{}, fmt.Errorf("snapshot onboarding: %w", err)
+	}
 
 	// Merge into user_onboarding.responses JSONB
 	query := `
@@ -193,429 +364,205 @@ func (e *Executor) saveProductivityChallenges(ctx context.Context, userID string
 		DO UPDATE SET
 			responses = COALESCE(user_onboarding.responses, '{}'::jsonb) || $2::jsonb,
 			updated_at = NOW()
+		RETURNING to_jsonb(user_onboarding.*)
 	`
-	_, err := e.db.Exec(ctx, query, userID, string(challengesJSON))
-	if err != nil {
-		return "", fmt.Errorf("save challenges: %w", err)
-	}
-
-	log.Printf("Saved %d productivity challenges for user %s: %v", len(challenges), userID, challenges)
-
-	return toJSON(map[string]interface{}{
-		"saved":      true,
-		"challenges": challenges,
-		"count":      len(challenges),
-	}), nil
+	var after json.RawMessage
+	if err := call.DB.QueryRow(ctx, query, call.UserID, string(challengesJSON)).Scan(&after); err != nil {
+		return tools.Result{}, fmt.Errorf("save challenges: %w", err)
+	}
+
+	log.Printf("Saved %d productivity challenges for user %s: %v", len(challenges), call.UserID, challenges)
+
+	return tools.Result{
+		Output: map[string]interface{}{
+			"saved":      true,
+			"challenges": challenges,
+			"count":      len(challenges),
+		},
+		Changes: []tools.Change{{Table: "user_onboarding", RowID: call.UserID, Before: before, After: after}},
+		Summary: "Défis de productivité enregistrés",
+	}, nil
 }
 
-func (e *Executor) getCurrentDatetime(ctx context.Context, userID string) string {
-	tz := "Europe/Paris"
-	if err := e.db.QueryRow(ctx, "SELECT COALESCE(timezone, 'Europe/Paris') FROM public.users WHERE id = $1", userID).Scan(&tz); err != nil {
-		log.Printf("Failed to fetch timezone for user %s: %v", userID, err)
-	}
-	loc, _ := time.LoadLocation(tz)
-	if loc == nil {
-		loc = time.UTC
-	}
-	now := time.Now().In(loc)
+func runGetCurrentDatetime(ctx context.Context, call *tools.Call, _ tools.NoArgs) (tools.Result, error) {
+	now := userclock.For(ctx, call.DB, call.UserID).Now()
 
-	return toJSON(map[string]interface{}{
+	return tools.Result{Output: map[string]interface{}{
 		"date":     fmt.Sprintf("%s %d %s %d", frenchWeekday(now.Weekday()), now.Day(), frenchMonth(now.Month()), now.Year()),
 		"time":     fmt.Sprintf("%02d:%02d", now.Hour(), now.Minute()),
 		"iso_date": now.Format("2006-01-02"),
-	})
+	}}, nil
 }
 
 // ==========================================
-// Task Tools (direct SQL)
+// Reflections & Goals
 // ==========================================
 
-func (e *Executor) getTasksForDate(ctx context.Context, userID, dateStr string) (string, error) {
-	rows, err := e.db.Query(ctx, `
-		SELECT id, title, COALESCE(status, 'pending'), COALESCE(time_block, ''), COALESCE(priority, 'medium'), date
-		FROM tasks WHERE user_id = $1 AND date = $2
-		ORDER BY position, created_at
-	`, userID, dateStr)
-	if err != nil {
-		return "", err
+func runSaveMorningCheckin(ctx context.Context, call *tools.Call, args morningCheckinArgs) (tools.Result, error) {
+	today := userclock.For(ctx, call.DB, call.UserID).Today()
+	in := checkins.MorningInput{Mood: args.Mood}
+	if args.SleepQuality > 0 {
+		in.SleepQuality = &args.SleepQuality
 	}
-	defer rows.Close()
-
-	var tasks []map[string]interface{}
-	for rows.Next() {
-		var id, title, status, timeBlock, priority, date string
-		if err := rows.Scan(&id, &title, &status, &timeBlock, &priority, &date); err != nil {
-			continue
-		}
-		tasks = append(tasks, map[string]interface{}{
-			"id":         id,
-			"title":      title,
-			"status":     status,
-			"time_block": timeBlock,
-			"priority":   priority,
-			"date":       date,
-		})
+	if args.Intentions != "" {
+		in.Intentions = []string{args.Intentions}
 	}
-	if tasks == nil {
-		tasks = []map[string]interface{}{}
-	}
-
-	return toJSON(map[string]interface{}{"date": dateStr, "tasks": tasks}), nil
-}
 
-func (e *Executor) createTask(ctx context.Context, userID string, args map[string]interface{}) (string, error) {
-	title := stringArg(args, "title", "Nouvelle tâche")
-	dateStr := stringArg(args, "date", todayStr(ctx, userID, e.db))
-	priority := stringArg(args, "priority", "medium")
-	timeBlock := stringArg(args, "time_block", "morning")
-
-	var taskID string
-	err := e.db.QueryRow(ctx, `
-		INSERT INTO tasks (user_id, title, date, priority, time_block, is_ai_generated)
-		VALUES ($1, $2, $3, $4, $5, true)
-		RETURNING id
-	`, userID, title, dateStr, priority, timeBlock).Scan(&taskID)
+	before, err := checkinSnapshot(ctx, call, "morning_checkins", today)
 	if err != nil {
-		return "", fmt.Errorf("create task: %w", err)
+		return tools.Result{}, err
 	}
-
-	return toJSON(map[string]interface{}{"created": true, "task_id": taskID, "title": title}), nil
-}
-
-func (e *Executor) completeTask(ctx context.Context, userID, taskID string) (string, error) {
-	if taskID == "" {
-		return "", fmt.Errorf("task_id is required")
-	}
-
-	tag, err := e.db.Exec(ctx, `
-		UPDATE tasks SET status = 'completed', completed_at = now(), updated_at = now()
-		WHERE id = $1 AND user_id = $2
-	`, taskID, userID)
+	saved, err := checkins.SaveMorning(ctx, call.DB, call.UserID, today, in)
 	if err != nil {
-		return "", fmt.Errorf("complete task: %w", err)
-	}
-	if tag.RowsAffected() == 0 {
-		return "", fmt.Errorf("task not found: %s", taskID)
+		return tools.Result{}, fmt.Errorf("save morning checkin: %w", err)
 	}
 
-	return toJSON(map[string]interface{}{"completed": true, "task_id": taskID}), nil
+	return tools.Result{
+		Output:      map[string]interface{}{"saved": true},
+		SideEffects: []tools.SideEffect{tools.NewSideEffect("refresh_reflection")},
+		Changes:     []tools.Change{savedChange(ctx, call, "morning_checkins", saved.ID, before)},
+		Summary:     "Check-in du matin enregistré",
+	}, nil
 }
 
-func (e *Executor) uncompleteTask(ctx context.Context, userID, taskID string) (string, error) {
-	if taskID == "" {
-		return "", fmt.Errorf("task_id is required")
-	}
+func runSaveEveningReview(ctx context.Context, call *tools.Call, args eveningReviewArgs) (tools.Result, error) {
+	today := userclock.For(ctx, call.DB, call.UserID).Today()
 
-	tag, err := e.db.Exec(ctx, `
-		UPDATE tasks SET status = 'pending', completed_at = NULL, updated_at = now()
-		WHERE id = $1 AND user_id = $2
-	`, taskID, userID)
+	before, err := checkinSnapshot(ctx, call, "evening_checkins", today)
 	if err != nil {
-		return "", fmt.Errorf("uncomplete task: %w", err)
-	}
-	if tag.RowsAffected() == 0 {
-		return "", fmt.Errorf("task not found: %s", taskID)
-	}
-
-	return toJSON(map[string]interface{}{"uncompleted": true, "task_id": taskID}), nil
-}
-
-func (e *Executor) updateTask(ctx context.Context, userID string, args map[string]interface{}) (string, error) {
-	taskID := stringArg(args, "task_id", "")
-	if taskID == "" {
-		return "", fmt.Errorf("task_id is required")
-	}
-
-	// Build dynamic SET clause
-	sets := []string{}
-	sqlArgs := []interface{}{}
-	argIdx := 1
-
-	if v, ok := args["title"].(string); ok && v != "" {
-		sets = append(sets, fmt.Sprintf("title = $%d", argIdx))
-		sqlArgs = append(sqlArgs, v)
-		argIdx++
+		return tools.Result{}, err
 	}
-	if v, ok := args["date"].(string); ok && v != "" {
-		sets = append(sets, fmt.Sprintf("date = $%d", argIdx))
-		sqlArgs = append(sqlArgs, v)
-		argIdx++
-	}
-	if v, ok := args["priority"].(string); ok && v != "" {
-		sets = append(sets, fmt.Sprintf("priority = $%d", argIdx))
-		sqlArgs = append(sqlArgs, v)
-		argIdx++
-	}
-	if v, ok := args["time_block"].(string); ok && v != "" {
-		sets = append(sets, fmt.Sprintf("time_block = $%d", argIdx))
-		sqlArgs = append(sqlArgs, v)
-		argIdx++
-	}
-
-	if len(sets) == 0 {
-		return toJSON(map[string]interface{}{"updated": false, "reason": "no fields to update"}), nil
-	}
-
-	sets = append(sets, "updated_at = now()")
-
-	query := fmt.Sprintf("UPDATE tasks SET %s WHERE id = $%d AND user_id = $%d",
-		joinStrings(sets, ", "), argIdx, argIdx+1)
-	sqlArgs = append(sqlArgs, taskID, userID)
-
-	tag, err := e.db.Exec(ctx, query, sqlArgs...)
+	// Empty arguments keep what was already said today
+	saved, err := checkins.SaveEvening(ctx, call.DB, call.UserID, today, checkins.EveningInput{
+		BiggestWin:      nonEmpty(args.BiggestWin),
+		Blockers:        nonEmpty(args.Blockers),
+		GoalForTomorrow: nonEmpty(args.TomorrowGoal),
+	})
 	if err != nil {
-		return "", fmt.Errorf("update task: %w", err)
-	}
-	if tag.RowsAffected() == 0 {
-		return "", fmt.Errorf("task not found: %s", taskID)
+		return tools.Result{}, fmt.Errorf("save evening review: %w", err)
 	}
 
-	return toJSON(map[string]interface{}{"updated": true, "task_id": taskID}), nil
+	return tools.Result{
+		Output:      map[string]interface{}{"saved": true},
+		SideEffects: []tools.SideEffect{tools.NewSideEffect("refresh_reflection")},
+		Changes:     []tools.Change{savedChange(ctx, call, "evening_checkins", saved.ID, before)},
+		Summary:     "Bilan du soir enregistré",
+	}, nil
 }
 
-func (e *Executor) deleteTask(ctx context.Context, userID, taskID string) (string, error) {
-	if taskID == "" {
-		return "", fmt.Errorf("task_id is required")
+// checkinSnapshot returns the check-in of date in table before a save, nil
+// when the save is going to create it. table is a constant.
+func checkinSnapshot(ctx context.Context, call *tools.Call, table, date string) (json.RawMessage, error) {
+	var row json.RawMessage
+	err := call.DB.QueryRow(ctx,
+		fmt.Sprintf("SELECT to_jsonb(t) FROM %s t WHERE user_id = $1 AND date = $2::date", pgx.Identifier{"public", table}.Sanitize()),
+		call.UserID, date,
+	).Scan(&row)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
 	}
-
-	tag, err := e.db.Exec(ctx, "DELETE FROM tasks WHERE id = $1 AND user_id = $2", taskID, userID)
 	if err != nil {
-		return "", fmt.Errorf("delete task: %w", err)
-	}
-	if tag.RowsAffected() == 0 {
-		return "", fmt.Errorf("task not found: %s", taskID)
+		return nil, fmt.Errorf("snapshot %s: %w", table, err)
 	}
-
-	return toJSON(map[string]interface{}{"deleted": true, "task_id": taskID}), nil
+	return row, nil
 }
 
-// ==========================================
-// Routine Tools
-// ==========================================
-
-func (e *Executor) getRituals(ctx context.Context, userID string) (string, error) {
-	today := todayStr(ctx, userID, e.db)
-	rows, err := e.db.Query(ctx, `
-		SELECT r.id, r.title, COALESCE(r.icon, '✨'),
-		       EXISTS(SELECT 1 FROM routine_completions rc WHERE rc.routine_id = r.id AND rc.user_id = $1 AND rc.completion_date = $2) as is_completed
-		FROM routines r
-		WHERE r.user_id = $1
-		ORDER BY r.created_at
-	`, userID, today)
-	if err != nil {
-		return "", err
-	}
-	defer rows.Close()
-
-	var rituals []map[string]interface{}
-	for rows.Next() {
-		var id, title, icon string
-		var isCompleted bool
-		if err := rows.Scan(&id, &title, &icon, &isCompleted); err != nil {
-			continue
-		}
-		rituals = append(rituals, map[string]interface{}{
-			"id":           id,
-			"title":        title,
-			"icon":         icon,
-			"is_completed": isCompleted,
-		})
+// savedChange records an upsert: created when there was no row before.
+func savedChange(ctx context.Context, call *tools.Call, table, id string, before json.RawMessage) tools.Change {
+	if before == nil {
+		return call.Created(ctx, table, id)
 	}
-	if rituals == nil {
-		rituals = []map[string]interface{}{}
-	}
-
-	return toJSON(map[string]interface{}{"rituals": rituals}), nil
+	return call.Updated(ctx, table, id, before)
 }
 
-func (e *Executor) createRoutine(ctx context.Context, userID string, args map[string]interface{}) (string, error) {
-	title := stringArg(args, "title", "Nouveau rituel")
-	icon := stringArg(args, "icon", "star")
-	frequency := stringArg(args, "frequency", "daily")
-	scheduledTime := stringArg(args, "scheduled_time", "")
-
-	// Get first area as default
-	var areaID string
-	if err := e.db.QueryRow(ctx, "SELECT id FROM areas WHERE user_id = $1 LIMIT 1", userID).Scan(&areaID); err != nil {
-		log.Printf("Failed to fetch default area for user %s: %v", userID, err)
+func nonEmpty(s string) *string {
+	if s == "" {
+		return nil
 	}
-
-	var routineID string
-	var err error
-	if scheduledTime != "" {
-		err = e.db.QueryRow(ctx, `
-			INSERT INTO routines (user_id, area_id, title, frequency, icon, scheduled_time)
-			VALUES ($1, $2, $3, $4, $5, $6) RETURNING id
-		`, userID, areaID, title, frequency, icon, scheduledTime).Scan(&routineID)
-	} else {
-		err = e.db.QueryRow(ctx, `
-			INSERT INTO routines (user_id, area_id, title, frequency, icon)
-			VALUES ($1, $2, $3, $4, $5) RETURNING id
-		`, userID, areaID, title, frequency, icon).Scan(&routineID)
-	}
-	if err != nil {
-		return "", fmt.Errorf("create routine: %w", err)
-	}
-
-	return toJSON(map[string]interface{}{"created": true, "routine_id": routineID}), nil
+	return &s
 }
 
-func (e *Executor) completeRoutine(ctx context.Context, userID, routineID string) (string, error) {
-	today := todayStr(ctx, userID, e.db)
-	_, err := e.db.Exec(ctx, `
-		INSERT INTO routine_completions (user_id, routine_id, completion_date)
-		VALUES ($1, $2, $3)
-		ON CONFLICT (user_id, routine_id, completion_date) DO NOTHING
-	`, userID, routineID, today)
+func runCreateWeeklyGoals(ctx context.Context, call *tools.Call, args weeklyGoalsArgs) (tools.Result, error) {
+	weekStart := userclock.For(ctx, call.DB, call.UserID).WeekStart()
+	count, changes, err := weeklygoals.SetGoals(ctx, call.DB, call.UserID, weekStart, args.Goals)
 	if err != nil {
-		return "", fmt.Errorf("complete routine: %w", err)
+		return tools.Result{}, fmt.Errorf("create weekly goals: %w", err)
 	}
 
-	return toJSON(map[string]interface{}{"completed": true, "routine_id": routineID}), nil
-}
-
-func (e *Executor) deleteRoutine(ctx context.Context, userID, routineID string) (string, error) {
-	_, err := e.db.Exec(ctx, "DELETE FROM routines WHERE id = $1 AND user_id = $2", routineID, userID)
-	if err != nil {
-		return "", fmt.Errorf("delete routine: %w", err)
-	}
-	return toJSON(map[string]interface{}{"deleted": true, "routine_id": routineID}), nil
+	return tools.Result{
+		Output:      map[string]interface{}{"created": true, "count": count},
+		SideEffects: []tools.SideEffect{tools.NewSideEffect("refresh_weekly_goals")},
+		Changes:     changes,
+		Summary:     fmt.Sprintf("%d objectifs de la semaine définis", count),
+	}, nil
 }
 
 // ==========================================
-// Reflections & Goals
+// Memory
 // ==========================================
 
-func (e *Executor) saveMorningCheckin(ctx context.Context, userID string, args map[string]interface{}) (string, error) {
-	today := todayStr(ctx, userID, e.db)
-	mood := intArg(args, "mood", 3)
-	sleepQuality := intArg(args, "sleep_quality", 3)
-	intentions := stringArg(args, "intentions", "")
-
-	_, err := e.db.Exec(ctx, `
-		INSERT INTO daily_reflections (user_id, date, mood, sleep_quality, intentions)
-		VALUES ($1, $2, $3, $4, $5)
-		ON CONFLICT (user_id, date) DO UPDATE SET mood = $3, sleep_quality = $4, intentions = $5
-	`, userID, today, mood, sleepQuality, intentions)
-	if err != nil {
-		return "", fmt.Errorf("save morning checkin: %w", err)
+func runSaveMemory(ctx context.Context, call *tools.Call, args saveMemoryArgs) (tools.Result, error) {
+	if call.Memory == nil {
+		return tools.Result{}, errors.New("long-term memory is not available")
 	}
-
-	return toJSON(map[string]interface{}{"saved": true}), nil
-}
-
-func (e *Executor) saveEveningReview(ctx context.Context, userID string, args map[string]interface{}) (string, error) {
-	today := todayStr(ctx, userID, e.db)
-	biggestWin := stringArg(args, "biggest_win", "")
-	blockers := stringArg(args, "blockers", "")
-	tomorrowGoal := stringArg(args, "tomorrow_goal", "")
-
-	_, err := e.db.Exec(ctx, `
-		INSERT INTO daily_reflections (user_id, date, biggest_win, blockers, tomorrow_goal)
-		VALUES ($1, $2, $3, $4, $5)
-		ON CONFLICT (user_id, date) DO UPDATE SET biggest_win = $3, blockers = $4, tomorrow_goal = $5
-	`, userID, today, biggestWin, blockers, tomorrowGoal)
-	if err != nil {
-		return "", fmt.Errorf("save evening review: %w", err)
+	if err := call.Memory.AddMemory(ctx, FormatMemory(args.Category, args.Content)); err != nil {
+		return tools.Result{}, err
 	}
-
-	return toJSON(map[string]interface{}{"saved": true}), nil
+	return tools.Result{Output: map[string]interface{}{"saved": true}}, nil
 }
 
-func (e *Executor) createWeeklyGoals(ctx context.Context, userID string, args map[string]interface{}) (string, error) {
-	goalsRaw, ok := args["goals"].([]interface{})
-	if !ok || len(goalsRaw) == 0 {
-		return "", fmt.Errorf("goals array is required")
-	}
-
-	// Get current week start (Monday)
-	now := time.Now()
-	weekday := int(now.Weekday())
-	if weekday == 0 {
-		weekday = 7
-	}
-	monday := now.AddDate(0, 0, -(weekday - 1))
-	weekStart := monday.Format("2006-01-02")
-
-	// Upsert weekly goals
-	var goalSetID string
-	err := e.db.QueryRow(ctx, `
-		INSERT INTO weekly_goals (user_id, week_start)
-		VALUES ($1, $2)
-		ON CONFLICT (user_id, week_start) DO UPDATE SET updated_at = now()
-		RETURNING id
-	`, userID, weekStart).Scan(&goalSetID)
-	if err != nil {
-		return "", fmt.Errorf("create weekly goals: %w", err)
-	}
-
-	// Delete old items and insert new
-	if _, err := e.db.Exec(ctx, "DELETE FROM weekly_goal_items WHERE weekly_goal_id = $1", goalSetID); err != nil {
-		log.Printf("Failed to delete old weekly goal items for %s: %v", goalSetID, err)
-	}
-	for _, g := range goalsRaw {
-		if content, ok := g.(string); ok {
-			if _, err := e.db.Exec(ctx, `
-				INSERT INTO weekly_goal_items (weekly_goal_id, content) VALUES ($1, $2)
-			`, goalSetID, content); err != nil {
-				log.Printf("Failed to insert weekly goal item '%s': %v", content, err)
-			}
-		}
-	}
+// assistantMemory writes memories to a Backboard assistant.
+type assistantMemory struct {
+	client      *Client
+	assistantID string
+}
 
-	return toJSON(map[string]interface{}{"created": true, "count": len(goalsRaw)}), nil
+func (m assistantMemory) AddMemory(ctx context.Context, content string) error {
+	return m.client.AddMemory(ctx, m.assistantID, content)
 }
 
 // ==========================================
 // Morning Flow (composite)
 // ==========================================
 
-func (e *Executor) getMorningFlowContext(ctx context.Context, userID string, deviceCtx *DeviceContext) (string, error) {
-	userCtx, err := e.getUserContext(ctx, userID, deviceCtx)
+func runStartMorningFlow(ctx context.Context, call *tools.Call, _ tools.NoArgs) (tools.Result, error) {
+	combined, err := userContext(ctx, call)
 	if err != nil {
-		return "", err
+		return tools.Result{}, err
 	}
 
-	tasksResult, err := e.getTasksForDate(ctx, userID, todayStr(ctx, userID, e.db))
+	tasks, err := calendar.TasksForDate(ctx, call.DB, call.UserID, userclock.For(ctx, call.DB, call.UserID).Today())
 	if err != nil {
-		return "", err
+		return tools.Result{}, err
 	}
 
-	ritualsResult, err := e.getRituals(ctx, userID)
+	rituals, err := routines.RitualsForToday(ctx, call.DB, call.UserID)
 	if err != nil {
-		return "", err
+		return tools.Result{}, err
 	}
 
-	// Combine all into one response
-	var userCtxMap, tasksMap, ritualsMap map[string]interface{}
-	json.Unmarshal([]byte(userCtx), &userCtxMap)
-	json.Unmarshal([]byte(tasksResult), &tasksMap)
-	json.Unmarshal([]byte(ritualsResult), &ritualsMap)
-
-	combined := userCtxMap
-	combined["tasks"] = tasksMap["tasks"]
-	combined["rituals"] = ritualsMap["rituals"]
-
-	return toJSON(combined), nil
+	combined["tasks"] = tasks
+	combined["rituals"] = rituals
+	return tools.Result{Output: combined}, nil
 }
 
 // ==========================================
 // Calendar Events
 // ==========================================
 
-func (e *Executor) getCalendarEvents(ctx context.Context, userID, dateStr string) (string, error) {
-	rows, err := e.db.Query(ctx, `
+func calendarEventsForDate(ctx context.Context, call *tools.Call, dateStr string) ([]map[string]interface{}, error) {
+	rows, err := call.DB.Query(ctx, `
 		SELECT id, title, COALESCE(start_time, ''), COALESCE(end_time, ''), COALESCE(block_apps, false)
 		FROM calendar_events
 		WHERE user_id = $1 AND date = $2
 		ORDER BY start_time
-	`, userID, dateStr)
+	`, call.UserID, dateStr)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 	defer rows.Close()
 
-	var events []map[string]interface{}
+	events := []map[string]interface{}{}
 	for rows.Next() {
 		var id, title, startTime, endTime string
 		var blockApps bool
@@ -630,56 +577,67 @@ func (e *Executor) getCalendarEvents(ctx context.Context, userID, dateStr string
 			"block_apps": blockApps,
 		})
 	}
-	if events == nil {
-		events = []map[string]interface{}{}
-	}
-
-	return toJSON(map[string]interface{}{"date": dateStr, "events": events}), nil
+	return events, rows.Err()
 }
 
-func (e *Executor) scheduleCalendarBlocking(ctx context.Context, userID string, args map[string]interface{}) (string, error) {
-	eventIDs, ok := args["event_ids"].([]interface{})
-	if !ok {
-		return "", fmt.Errorf("event_ids is required")
+func runGetCalendarEvents(ctx context.Context, call *tools.Call, args calendarEventsArgs) (tools.Result, error) {
+	date := args.Date
+	if date == "" {
+		date = userclock.For(ctx, call.DB, call.UserID).Today()
 	}
-	enabled := true
-	if v, ok := args["enabled"].(bool); ok {
-		enabled = v
+	events, err := calendarEventsForDate(ctx, call, date)
+	if err != nil {
+		return tools.Result{}, err
 	}
+	return tools.Result{Output: map[string]interface{}{"date": date, "events": events}}, nil
+}
 
-	for _, eid := range eventIDs {
-		if id, ok := eid.(string); ok {
-			if _, err := e.db.Exec(ctx, `
-				UPDATE calendar_events SET block_apps = $1 WHERE id = $2 AND user_id = $3
-			`, enabled, id, userID); err != nil {
-				log.Printf("Failed to update block_apps for event %s: %v", id, err)
-			}
+func runScheduleCalendarBlocking(ctx context.Context, call *tools.Call, args calendarBlockingArgs) (tools.Result, error) {
+	enabled := args.Enabled == nil || *args.Enabled
+
+	var changes []tools.Change
+	for _, id := range args.EventIDs {
+		before, err := tools.Snapshot(ctx, call.DB, "calendar_events", call.UserID, id)
+		if err != nil {
+			log.Printf("Failed to snapshot event %s: %v", id, err)
+		}
+		tag, err := call.DB.Exec(ctx, `
+			UPDATE calendar_events SET block_apps = $1 WHERE id = $2 AND user_id = $3
+		`, enabled, id, call.UserID)
+		if err != nil {
+			log.Printf("Failed to update block_apps for event %s: %v", id, err)
+			continue
+		}
+		if tag.RowsAffected() > 0 && before != nil {
+			changes = append(changes, call.Updated(ctx, "calendar_events", id, before))
 		}
 	}
 
-	return toJSON(map[string]interface{}{"updated": true, "count": len(eventIDs)}), nil
+	summary := "Blocage désactivé pendant les événements"
+	if enabled {
+		summary = "Blocage activé pendant les événements"
+	}
+	return tools.Result{
+		Output:      map[string]interface{}{"updated": true, "count": len(args.EventIDs)},
+		SideEffects: []tools.SideEffect{tools.NewSideEffect("refresh_calendar_events")},
+		Changes:     changes,
+		Summary:     summary,
+	}, nil
 }
 
 // ==========================================
 // Planning Tools
 // ==========================================
 
-func (e *Executor) getPlanningContext(ctx context.Context, userID, scope string, deviceCtx *DeviceContext) (string, error) {
-	today := todayStr(ctx, userID, e.db)
+func runGetPlanningContext(ctx context.Context, call *tools.Call, args planningContextArgs) (tools.Result, error) {
+	clock := userclock.For(ctx, call.DB, call.UserID)
+	today := clock.Today()
 
 	// Determine date range based on scope
-	loc := time.UTC
-	var tz string
-	if err := e.db.QueryRow(ctx, "SELECT COALESCE(timezone, 'Europe/Paris') FROM public.users WHERE id = $1", userID).Scan(&tz); err != nil {
-		log.Printf("Failed to fetch timezone for planning context, user %s: %v", userID, err)
-	}
-	if l, err := time.LoadLocation(tz); err == nil {
-		loc = l
-	}
-	now := time.Now().In(loc)
+	now := clock.Now()
 
 	var dates []string
-	switch scope {
+	switch args.Scope {
 	case "tomorrow":
 		d := now.AddDate(0, 0, 1)
 		dates = []string{d.Format("2006-01-02")}
@@ -698,135 +656,39 @@ func (e *Executor) getPlanningContext(ctx context.Context, userID, scope string,
 	var days []map[string]interface{}
 	for _, dateStr := range dates {
 		d, _ := time.Parse("2006-01-02", dateStr)
-		dayName := frenchWeekday(d.Weekday())
-
-		tasksJSON, _ := e.getTasksForDate(ctx, userID, dateStr)
-		var tasksResult map[string]interface{}
-		json.Unmarshal([]byte(tasksJSON), &tasksResult)
-		tasks := tasksResult["tasks"]
 
-		eventsJSON, _ := e.getCalendarEvents(ctx, userID, dateStr)
-		var eventsResult map[string]interface{}
-		json.Unmarshal([]byte(eventsJSON), &eventsResult)
-		events := eventsResult["events"]
+		tasks, err := calendar.TasksForDate(ctx, call.DB, call.UserID, dateStr)
+		if err != nil {
+			log.Printf("Failed to load tasks of %s for planning, user %s: %v", dateStr, call.UserID, err)
+		}
+		events, err := calendarEventsForDate(ctx, call, dateStr)
+		if err != nil {
+			log.Printf("Failed to load events of %s for planning, user %s: %v", dateStr, call.UserID, err)
+		}
 
 		days = append(days, map[string]interface{}{
 			"date":            dateStr,
-			"day_name":        dayName,
+			"day_name":        frenchWeekday(d.Weekday()),
 			"tasks":           tasks,
 			"calendar_events": events,
 		})
 	}
 
-	// Fetch rituals
-	ritualsJSON, _ := e.getRituals(ctx, userID)
-	var ritualsResult map[string]interface{}
-	json.Unmarshal([]byte(ritualsJSON), &ritualsResult)
+	rituals, err := routines.RitualsForToday(ctx, call.DB, call.UserID)
+	if err != nil {
+		log.Printf("Failed to load rituals for planning, user %s: %v", call.UserID, err)
+	}
 
 	// Basic user context
-	userCtxJSON, _ := e.getUserContext(ctx, userID, deviceCtx)
-	var userCtx map[string]interface{}
-	json.Unmarshal([]byte(userCtxJSON), &userCtx)
+	userCtx, err := userContext(ctx, call)
+	if err != nil {
+		log.Printf("Failed to load user context for planning, user %s: %v", call.UserID, err)
+	}
 
-	result := map[string]interface{}{
-		"scope":        scope,
+	return tools.Result{Output: map[string]interface{}{
+		"scope":        args.Scope,
 		"days":         days,
-		"rituals":      ritualsResult,
+		"rituals":      map[string]interface{}{"rituals": rituals},
 		"user_context": userCtx,
-	}
-
-	return toJSON(result), nil
-}
-
-func (e *Executor) createTasksBatch(ctx context.Context, userID string, args map[string]interface{}) (string, error) {
-	tasksRaw, ok := args["tasks"].([]interface{})
-	if !ok || len(tasksRaw) == 0 {
-		return "", fmt.Errorf("tasks array is required and must not be empty")
-	}
-
-	today := todayStr(ctx, userID, e.db)
-	var created []map[string]interface{}
-
-	for _, t := range tasksRaw {
-		taskMap, ok := t.(map[string]interface{})
-		if !ok {
-			continue
-		}
-
-		title := ""
-		if v, ok := taskMap["title"].(string); ok {
-			title = v
-		}
-		if title == "" {
-			continue
-		}
-
-		dateStr := today
-		if v, ok := taskMap["date"].(string); ok && v != "" {
-			dateStr = v
-		}
-		priority := "medium"
-		if v, ok := taskMap["priority"].(string); ok && v != "" {
-			priority = v
-		}
-		timeBlock := "morning"
-		if v, ok := taskMap["time_block"].(string); ok && v != "" {
-			timeBlock = v
-		}
-		estimatedMinutes := 0
-		if v, ok := taskMap["estimated_minutes"].(float64); ok {
-			estimatedMinutes = int(v)
-		}
-		scheduledStart := ""
-		if v, ok := taskMap["scheduled_start"].(string); ok {
-			scheduledStart = v
-		}
-		scheduledEnd := ""
-		if v, ok := taskMap["scheduled_end"].(string); ok {
-			scheduledEnd = v
-		}
-		blockApps := false
-		if v, ok := taskMap["block_apps"].(bool); ok {
-			blockApps = v
-		}
-
-		var taskID string
-		err := e.db.QueryRow(ctx, `
-			INSERT INTO tasks (user_id, title, date, priority, time_block, estimated_minutes, scheduled_start, scheduled_end, block_apps, is_ai_generated)
-			VALUES ($1, $2, $3, $4, $5, NULLIF($6, 0), NULLIF($7, '')::time, NULLIF($8, '')::time, $9, true)
-			RETURNING id
-		`, userID, title, dateStr, priority, timeBlock, estimatedMinutes, scheduledStart, scheduledEnd, blockApps).Scan(&taskID)
-		if err != nil {
-			log.Printf("create_tasks_batch: failed to create task '%s': %v", title, err)
-			continue
-		}
-
-		created = append(created, map[string]interface{}{
-			"id":         taskID,
-			"title":      title,
-			"date":       dateStr,
-			"time_block": timeBlock,
-			"priority":   priority,
-		})
-	}
-
-	return toJSON(map[string]interface{}{
-		"created": len(created),
-		"tasks":   created,
-	}), nil
-}
-
-// ==========================================
-// String Helpers
-// ==========================================
-
-func joinStrings(strs []string, sep string) string {
-	result := ""
-	for i, s := range strs {
-		if i > 0 {
-			result += sep
-		}
-		result += s
-	}
-	return result
+	}}, nil
 }
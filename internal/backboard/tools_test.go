@@ -0,0 +1,13 @@
+package backboard
+
+import "testing"
+
+func TestNewRegistry(t *testing.T) {
+	reg, err := NewRegistry()
+	if err != nil {
+		t.Fatalf("NewRegistry: %v", err)
+	}
+	if len(reg.Definitions()) == 0 {
+		t.Fatal("no tools registered")
+	}
+}
@@ -1,6 +1,6 @@
 package backboard
 
-import "encoding/json"
+import "firelevel-backend/internal/tools"
 
 // ==========================================
 // Backboard API Request/Response Types
@@ -106,18 +106,45 @@ type MemoryCreateRequest struct {
 }
 
 // SideEffect represents an action the frontend should take after an AI response.
-type SideEffect struct {
-	Type string          `json:"type"`
-	Data json.RawMessage `json:"data,omitempty"`
-}
+type SideEffect = tools.SideEffect
+
+// DeviceContext holds device-only state sent by the frontend.
+type DeviceContext = tools.DeviceContext
 
 // NewSideEffect creates a side effect with no data.
 func NewSideEffect(typ string) SideEffect {
-	return SideEffect{Type: typ}
+	return tools.NewSideEffect(typ)
 }
 
 // NewSideEffectWithData creates a side effect with JSON data.
 func NewSideEffectWithData(typ string, data interface{}) SideEffect {
-	b, _ := json.Marshal(data)
-	return SideEffect{Type: typ, Data: b}
+	return tools.NewSideEffectWithData(typ, data)
 }
+
+// ==========================================
+// Tool loop progress events
+// ==========================================
+
+// Stream event types emitted by Executor.RunToolLoop.
+const (
+	EventText      = "text"       // Assistant text sent alongside tool calls
+	EventToolStart = "tool_start" // A tool is about to run
+	EventToolEnd   = "tool_end"   // A tool finished; carries its side effects
+	// The run paused for the user's approval; carries the
+	// pending_confirmation side effect
+	EventConfirmation = "confirmation_required"
+)
+
+// StreamEvent reports progress of a tool loop as it happens.
+type StreamEvent struct {
+	Type        string       `json:"type"`
+	Round       int          `json:"round,omitempty"`
+	Tool        string       `json:"tool,omitempty"`
+	ToolCallID  string       `json:"tool_call_id,omitempty"`
+	Content     string       `json:"content,omitempty"`
+	SideEffects []SideEffect `json:"side_effects,omitempty"`
+}
+
+// EventSink receives StreamEvents. It is called synchronously from the tool
+// loop, so it must not block for long.
+type EventSink func(StreamEvent)
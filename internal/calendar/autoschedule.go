@@ -0,0 +1,441 @@
+package calendar
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"time"
+
+	"firelevel-backend/internal/auth"
+	"firelevel-backend/internal/tools"
+	"firelevel-backend/internal/userclock"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// ==========================================
+// AUTO-SCHEDULER
+// Places the day's unscheduled tasks (no scheduled time)
+// into free slots: busy calendar events, app-blocking
+// windows and already scheduled tasks are avoided. High
+// priority tasks go first and into the user's
+// productivity_peak block. The result is stored as a
+// proposal; accepting it writes every slot in one
+// transaction, or nothing if the day changed meanwhile.
+// ==========================================
+
+// proposalTTL is how long a proposal can be accepted.
+const proposalTTL = time.Hour
+
+var (
+	// ErrProposalNotFound is returned for unknown, expired or already used proposals.
+	ErrProposalNotFound = errors.New("schedule proposal not found or expired")
+
+	// ErrProposalStale is returned when a proposed slot is no longer free or
+	// a task was scheduled meanwhile.
+	ErrProposalStale = errors.New("schedule changed since the proposal was made")
+)
+
+// ScheduleSlot is one task placed by the auto-scheduler.
+type ScheduleSlot struct {
+	TaskID         string `json:"taskId"`
+	Title          string `json:"title"`
+	Priority       string `json:"priority"`
+	TimeBlock      string `json:"timeBlock"`
+	ScheduledStart string `json:"scheduledStart"` // HH:mm
+	ScheduledEnd   string `json:"scheduledEnd"`   // HH:mm
+	InPeak         bool   `json:"inPeak"`         // Placed in the user's productivity peak
+}
+
+// UnplacedTask is a task the auto-scheduler found no room for.
+type UnplacedTask struct {
+	TaskID string `json:"taskId"`
+	Title  string `json:"title"`
+	Reason string `json:"reason"`
+}
+
+// SchedulePlan is a proposed timetable for one day.
+type SchedulePlan struct {
+	ID               string         `json:"id"`
+	Date             string         `json:"date"`
+	ProductivityPeak *string        `json:"productivityPeak,omitempty"`
+	Slots            []ScheduleSlot `json:"slots"`
+	Unplaced         []UnplacedTask `json:"unplaced"`
+	Status           string         `json:"status"` // pending, accepted
+	ExpiresAt        time.Time      `json:"expiresAt"`
+}
+
+// ProposeAutoSchedule proposes slots for the unscheduled tasks of a day
+// POST /calendar/auto-schedule?date=YYYY-MM-DD
+func (h *Handler) ProposeAutoSchedule(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(auth.UserContextKey).(string)
+
+	today := userclock.For(r.Context(), h.db, userID).Today()
+	date := r.URL.Query().Get("date")
+	if date == "" {
+		date = today
+	}
+	if _, err := time.Parse("2006-01-02", date); err != nil {
+		http.Error(w, "Invalid date format (expected YYYY-MM-DD)", http.StatusBadRequest)
+		return
+	}
+	if date < today {
+		http.Error(w, "Cannot schedule a past day", http.StatusBadRequest)
+		return
+	}
+
+	plan, err := h.proposeSchedule(r.Context(), userID, date)
+	if err != nil {
+		log.Printf("[AutoSchedule] Failed to propose schedule for %s: %v", date, err)
+		http.Error(w, "Failed to build schedule", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(plan)
+}
+
+// AcceptAutoSchedule applies every slot of a proposal at once
+// POST /calendar/auto-schedule/{id}/accept
+func (h *Handler) AcceptAutoSchedule(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(auth.UserContextKey).(string)
+
+	plan, _, err := h.acceptSchedule(r.Context(), userID, chi.URLParam(r, "id"))
+	switch {
+	case errors.Is(err, ErrProposalNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	case errors.Is(err, ErrProposalStale):
+		http.Error(w, err.Error()+", ask for a new one", http.StatusConflict)
+		return
+	case err != nil:
+		log.Printf("[AutoSchedule] Failed to accept proposal: %v", err)
+		http.Error(w, "Failed to apply schedule", http.StatusInternalServerError)
+		return
+	}
+
+	// Sync to Google Calendar (async, don't block response)
+	if h.googleCalSvc != nil {
+		go func() {
+			ctx := context.Background()
+			for _, slot := range plan.Slots {
+				var title string
+				var description *string
+				if err := h.db.QueryRow(ctx, `SELECT title, description FROM tasks WHERE id = $1`, slot.TaskID).Scan(&title, &description); err != nil {
+					continue
+				}
+				start, end := slot.ScheduledStart, slot.ScheduledEnd
+				if err := h.googleCalSvc.SyncTaskToGoogleCalendar(ctx, userID, slot.TaskID, title, description, plan.Date, &start, &end); err != nil {
+					log.Printf("[AutoSchedule] Google Calendar sync failed for %s: %v", slot.TaskID, err)
+				}
+			}
+		}()
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(plan)
+}
+
+// proposeSchedule places the unscheduled tasks of date and stores the plan.
+// Older pending proposals for the same day are superseded.
+func (h *Handler) proposeSchedule(ctx context.Context, userID, date string) (*SchedulePlan, error) {
+	clock := userclock.For(ctx, h.db, userID)
+	if date < clock.Today() {
+		return nil, fmt.Errorf("cannot schedule a past day (%s)", date)
+	}
+
+	tasks, err := h.getTasksForDay(ctx, userID, date, nil)
+	if err != nil {
+		return nil, err
+	}
+	agenda, err := h.loadDayAgenda(ctx, userID, date)
+	if err != nil {
+		return nil, err
+	}
+
+	var peak *string
+	if err := h.db.QueryRow(ctx, `SELECT productivity_peak FROM public.users WHERE id = $1`, userID).Scan(&peak); err != nil {
+		log.Printf("[AutoSchedule] Failed to read productivity_peak for %s: %v", userID, err)
+	}
+
+	notBefore := 0
+	if date == clock.Today() {
+		now := clock.Now()
+		notBefore = now.Hour()*60 + now.Minute()
+	}
+
+	plan := &SchedulePlan{
+		Date:             date,
+		ProductivityPeak: peak,
+		Slots:            []ScheduleSlot{},
+		Unplaced:         []UnplacedTask{},
+		Status:           "pending",
+	}
+
+	for _, t := range unscheduledTasks(tasks) {
+		duration := defaultDuration(t.TimeBlock)
+		if t.EstimatedMinutes != nil && *t.EstimatedMinutes > 0 {
+			duration = *t.EstimatedMinutes
+		}
+
+		placed := false
+		for _, block := range blockPreference(t, peak) {
+			windowStart, windowEnd := timeBlockWindow(block)
+			preferred, _ := parseClock(getDefaultStartTime(block))
+			start, found := agenda.nextFreeSlot(duration, windowStart, windowEnd, preferred, notBefore)
+			if !found {
+				continue
+			}
+
+			slot := ScheduleSlot{
+				TaskID:         t.ID,
+				Title:          t.Title,
+				Priority:       t.Priority,
+				TimeBlock:      block,
+				ScheduledStart: formatClock(start),
+				ScheduledEnd:   formatClock(start + duration),
+				InPeak:         peak != nil && *peak == block,
+			}
+			plan.Slots = append(plan.Slots, slot)
+			// Later tasks must not overlap this one
+			agenda.busy = append(agenda.busy, busyInterval{start: start, end: start + duration, conflict: Conflict{
+				Source:  "task",
+				ID:      t.ID,
+				Title:   t.Title,
+				StartAt: agenda.at(start).Format(time.RFC3339),
+				EndAt:   agenda.at(start + duration).Format(time.RFC3339),
+			}})
+			placed = true
+			break
+		}
+		if !placed {
+			plan.Unplaced = append(plan.Unplaced, UnplacedTask{
+				TaskID: t.ID,
+				Title:  t.Title,
+				Reason: fmt.Sprintf("No free %d-minute slot left on %s", duration, date),
+			})
+		}
+	}
+
+	slotsJSON, err := json.Marshal(plan.Slots)
+	if err != nil {
+		return nil, fmt.Errorf("encode slots: %w", err)
+	}
+	unplacedJSON, err := json.Marshal(plan.Unplaced)
+	if err != nil {
+		return nil, fmt.Errorf("encode unplaced tasks: %w", err)
+	}
+
+	tx, err := h.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `
+		UPDATE public.schedule_proposals SET status = 'superseded'
+		WHERE user_id = $1 AND date = $2 AND status = 'pending'
+	`, userID, date); err != nil {
+		return nil, err
+	}
+	err = tx.QueryRow(ctx, `
+		INSERT INTO public.schedule_proposals (user_id, date, slots, unplaced, expires_at)
+		VALUES ($1, $2, $3, $4, NOW() + $5::interval)
+		RETURNING id, expires_at
+	`, userID, date, slotsJSON, unplacedJSON, fmt.Sprintf("%d seconds", int(proposalTTL.Seconds()))).Scan(&plan.ID, &plan.ExpiresAt)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+
+	log.Printf("[AutoSchedule] Proposal %s for %s: %d placed, %d unplaced", plan.ID, date, len(plan.Slots), len(plan.Unplaced))
+	return plan, nil
+}
+
+// acceptSchedule writes every slot of a pending proposal in one transaction.
+// Slots are re-checked against the current agenda first, so nothing is
+// written when the day changed since the proposal.
+func (h *Handler) acceptSchedule(ctx context.Context, userID, proposalID string) (*SchedulePlan, []tools.Change, error) {
+	plan := &SchedulePlan{ID: proposalID}
+	var slotsJSON, unplacedJSON []byte
+	err := h.db.QueryRow(ctx, `
+		SELECT TO_CHAR(date, 'YYYY-MM-DD'), slots, unplaced, status, expires_at
+		FROM public.schedule_proposals
+		WHERE id = $1 AND user_id = $2 AND status = 'pending' AND expires_at > NOW()
+	`, proposalID, userID).Scan(&plan.Date, &slotsJSON, &unplacedJSON, &plan.Status, &plan.ExpiresAt)
+	if err != nil {
+		return nil, nil, ErrProposalNotFound
+	}
+	if err := json.Unmarshal(slotsJSON, &plan.Slots); err != nil {
+		return nil, nil, err
+	}
+	if err := json.Unmarshal(unplacedJSON, &plan.Unplaced); err != nil {
+		return nil, nil, err
+	}
+
+	exclude := make([]string, 0, len(plan.Slots))
+	for _, slot := range plan.Slots {
+		exclude = append(exclude, slot.TaskID)
+	}
+	agenda, err := h.loadDayAgenda(ctx, userID, plan.Date, exclude...)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, slot := range plan.Slots {
+		start, _ := parseClock(slot.ScheduledStart)
+		end, _ := parseClock(slot.ScheduledEnd)
+		if conflicts := agenda.conflicts(start, end); len(conflicts) > 0 {
+			log.Printf("[AutoSchedule] Proposal %s: %s now conflicts with %s", proposalID, slot.TaskID, conflicts[0].Title)
+			return nil, nil, ErrProposalStale
+		}
+	}
+
+	tx, err := h.db.Begin(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	// Claim the proposal so a double tap cannot apply it twice
+	tag, err := tx.Exec(ctx, `
+		UPDATE public.schedule_proposals SET status = 'accepted', accepted_at = NOW()
+		WHERE id = $1 AND status = 'pending'
+	`, proposalID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if tag.RowsAffected() == 0 {
+		return nil, nil, ErrProposalNotFound
+	}
+
+	// Occurrences of recurring tasks get their own row inside the
+	// transaction, so a stale proposal leaves none behind. Existing rows
+	// are snapshotted before the write (nil: materialised here).
+	taskIDs := make([]string, len(plan.Slots))
+	befores := make([]json.RawMessage, len(plan.Slots))
+	for i, slot := range plan.Slots {
+		ref, err := resolveTask(ctx, tx, userID, slot.TaskID)
+		if err != nil {
+			return nil, nil, ErrProposalStale
+		}
+		if ref.TaskID != "" {
+			taskIDs[i] = ref.TaskID
+			if befores[i], err = tools.Snapshot(ctx, tx, "tasks", userID, ref.TaskID); err != nil {
+				return nil, nil, err
+			}
+			continue
+		}
+		if taskIDs[i], err = materializeOccurrence(ctx, tx, userID, ref.SeriesID, ref.OccurrenceDate); err != nil {
+			return nil, nil, ErrProposalStale
+		}
+	}
+
+	changes := make([]tools.Change, 0, len(plan.Slots))
+	for i, slot := range plan.Slots {
+		tag, err := tx.Exec(ctx, `
+			UPDATE tasks
+			SET scheduled_start = $3::time, scheduled_end = $4::time, time_block = $5, updated_at = NOW()
+			WHERE id = $1 AND user_id = $2
+			  AND (scheduled_start IS NULL OR scheduled_end IS NULL)
+			  AND COALESCE(status, 'pending') != 'completed'
+		`, taskIDs[i], userID, slot.ScheduledStart, slot.ScheduledEnd, slot.TimeBlock)
+		if err != nil {
+			return nil, nil, err
+		}
+		if tag.RowsAffected() == 0 {
+			return nil, nil, ErrProposalStale
+		}
+		plan.Slots[i].TaskID = taskIDs[i]
+
+		after, err := tools.Snapshot(ctx, tx, "tasks", userID, taskIDs[i])
+		if err != nil {
+			return nil, nil, err
+		}
+		changes = append(changes, tools.Change{Table: "tasks", RowID: taskIDs[i], Before: befores[i], After: after})
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, nil, err
+	}
+
+	plan.Status = "accepted"
+	log.Printf("[AutoSchedule] Proposal %s accepted: %d tasks scheduled on %s", proposalID, len(plan.Slots), plan.Date)
+	return plan, changes, nil
+}
+
+// unscheduledTasks returns the open tasks without a user-chosen time,
+// most important first.
+func unscheduledTasks(tasks []Task) []Task {
+	var out []Task
+	for _, t := range tasks {
+		if t.timesDefaulted && t.Status != "completed" {
+			out = append(out, t)
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		a, b := out[i], out[j]
+		if priorityRank(a.Priority) != priorityRank(b.Priority) {
+			return priorityRank(a.Priority) < priorityRank(b.Priority)
+		}
+		if (a.DueAt == nil) != (b.DueAt == nil) {
+			return a.DueAt != nil
+		}
+		if a.DueAt != nil && !a.DueAt.Equal(*b.DueAt) {
+			return a.DueAt.Before(*b.DueAt)
+		}
+		return a.Position < b.Position
+	})
+	return out
+}
+
+func priorityRank(priority string) int {
+	switch priority {
+	case "high":
+		return 0
+	case "low":
+		return 2
+	default:
+		return 1
+	}
+}
+
+// blockPreference lists the time blocks to try for t: the peak first for
+// high priority tasks, then the task's own block, then the rest of the day.
+func blockPreference(t Task, peak *string) []string {
+	var order []string
+	add := func(block string) {
+		if !containsString(order, block) {
+			order = append(order, block)
+		}
+	}
+	if t.Priority == "high" && peak != nil && isTimeBlock(*peak) {
+		add(*peak)
+	}
+	if isTimeBlock(t.TimeBlock) {
+		add(t.TimeBlock)
+	}
+	for _, block := range []string{"morning", "afternoon", "evening"} {
+		add(block)
+	}
+	return order
+}
+
+func isTimeBlock(s string) bool {
+	return s == "morning" || s == "afternoon" || s == "evening"
+}
+
+// defaultDuration is the length of the block's default slot.
+func defaultDuration(timeBlock string) int {
+	start, err1 := parseClock(getDefaultStartTime(timeBlock))
+	end, err2 := parseClock(getDefaultEndTime(timeBlock))
+	if err1 != nil || err2 != nil || end <= start {
+		return 60
+	}
+	return end - start
+}
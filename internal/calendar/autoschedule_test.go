@@ -0,0 +1,66 @@
+package calendar
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestUnscheduledTasks(t *testing.T) {
+	due := func(s string) *time.Time {
+		d := date(s)
+		return &d
+	}
+	tasks := []Task{
+		{ID: "fixed", Priority: "high", timesDefaulted: false},
+		{ID: "done", Priority: "high", Status: "completed", timesDefaulted: true},
+		{ID: "low", Priority: "low", timesDefaulted: true},
+		{ID: "medium-2", Priority: "medium", Position: 2, timesDefaulted: true},
+		{ID: "medium-1", Priority: "medium", Position: 1, timesDefaulted: true},
+		{ID: "medium-due-late", Priority: "medium", DueAt: due("2026-10-20"), timesDefaulted: true},
+		{ID: "medium-due-soon", Priority: "medium", DueAt: due("2026-10-17"), timesDefaulted: true},
+		{ID: "high", Priority: "high", timesDefaulted: true},
+		{ID: "unset", Position: 3, timesDefaulted: true},
+	}
+
+	var got []string
+	for _, task := range unscheduledTasks(tasks) {
+		got = append(got, task.ID)
+	}
+	want := "high,medium-due-soon,medium-due-late,medium-1,medium-2,unset,low"
+	if strings.Join(got, ",") != want {
+		t.Errorf("order = %s\nwant    %s", strings.Join(got, ","), want)
+	}
+}
+
+func TestBlockPreference(t *testing.T) {
+	peak := func(s string) *string { return &s }
+	tests := []struct {
+		name string
+		task Task
+		peak *string
+		want string
+	}{
+		{"high priority goes to the peak first", Task{Priority: "high", TimeBlock: "afternoon"}, peak("evening"), "evening,afternoon,morning"},
+		{"peak equals the task block", Task{Priority: "high", TimeBlock: "morning"}, peak("morning"), "morning,afternoon,evening"},
+		{"medium priority ignores the peak", Task{Priority: "medium", TimeBlock: "afternoon"}, peak("evening"), "afternoon,morning,evening"},
+		{"no peak", Task{Priority: "high", TimeBlock: "evening"}, nil, "evening,morning,afternoon"},
+		{"invalid peak", Task{Priority: "high", TimeBlock: "afternoon"}, peak("night"), "afternoon,morning,evening"},
+		{"no block", Task{Priority: "low"}, nil, "morning,afternoon,evening"},
+	}
+	for _, tt := range tests {
+		if got := strings.Join(blockPreference(tt.task, tt.peak), ","); got != tt.want {
+			t.Errorf("%s: blockPreference = %s, want %s", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestDefaultDuration(t *testing.T) {
+	for _, block := range []string{"morning", "afternoon", "evening", ""} {
+		start, _ := parseClock(getDefaultStartTime(block))
+		end, _ := parseClock(getDefaultEndTime(block))
+		if got := defaultDuration(block); got != end-start || got <= 0 {
+			t.Errorf("defaultDuration(%q) = %d, want %d", block, got, end-start)
+		}
+	}
+}
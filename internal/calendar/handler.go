@@ -9,6 +9,7 @@ import (
 
 	"firelevel-backend/internal/auth"
 	"firelevel-backend/internal/streak"
+	"firelevel-backend/internal/userclock"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
@@ -61,6 +62,13 @@ type Task struct {
 	QuestTitle       *string    `json:"questTitle,omitempty"`
 	AreaName         *string    `json:"areaName,omitempty"`
 	AreaIcon         *string    `json:"areaIcon,omitempty"`
+	// Recurrence (set on series masters, overrides and virtual occurrences)
+	IsRecurring    bool    `json:"isRecurring"`
+	RecurrenceRule *string `json:"recurrenceRule,omitempty"` // RRULE, e.g. FREQ=WEEKLY;BYDAY=MO,WE
+	SeriesID       *string `json:"seriesId,omitempty"`
+	OccurrenceDate *string `json:"occurrenceDate,omitempty"` // YYYY-MM-DD of the occurrence in its series
+
+	timesDefaulted bool // Scheduled times come from the time block, not the user
 }
 
 // ==========================================
@@ -82,6 +90,7 @@ type CreateTaskRequest struct {
 	DueAt            *time.Time `json:"due_at,omitempty"`
 	IsPrivate        *bool      `json:"is_private,omitempty"`
 	BlockApps        *bool      `json:"block_apps,omitempty"`       // If true, iOS blocks apps during this task
+	RecurrenceRule   *string    `json:"recurrence_rule,omitempty"`  // RRULE subset: DAILY/WEEKLY/MONTHLY, INTERVAL, BYDAY, COUNT, UNTIL
 }
 
 type UpdateTaskRequest struct {
@@ -100,6 +109,7 @@ type UpdateTaskRequest struct {
 	QuestID          *string    `json:"quest_id,omitempty"`
 	AreaID           *string    `json:"area_id,omitempty"`
 	IsPrivate        *bool      `json:"is_private,omitempty"`
+	RecurrenceRule   *string    `json:"recurrence_rule,omitempty"` // Only with ?scope=all or following
 }
 
 type CalendarDayResponse struct {
@@ -122,7 +132,7 @@ func (h *Handler) ListTasks(w http.ResponseWriter, r *http.Request) {
 		tasks, err = h.getTasksForDay(r.Context(), userID, date, nil)
 	} else {
 		// Return today's tasks
-		tasks, err = h.getTasksForDay(r.Context(), userID, time.Now().Format("2006-01-02"), nil)
+		tasks, err = h.getTasksForDay(r.Context(), userID, userclock.For(r.Context(), h.db, userID).Today(), nil)
 	}
 
 	if err != nil {
@@ -180,16 +190,32 @@ func (h *Handler) CreateTask(w http.ResponseWriter, r *http.Request) {
 		blockApps = *req.BlockApps
 	}
 
+	// Normalise the recurrence rule; the task date is the series start
+	var recurrenceRule *string
+	if req.RecurrenceRule != nil && *req.RecurrenceRule != "" {
+		rr, err := ParseRRule(*req.RecurrenceRule)
+		if err != nil {
+			http.Error(w, "Invalid recurrence_rule: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		if _, err := time.Parse("2006-01-02", req.Date); err != nil {
+			http.Error(w, "date is required for recurring tasks", http.StatusBadRequest)
+			return
+		}
+		normalized := rr.String()
+		recurrenceRule = &normalized
+	}
+
 	var task Task
 	var scheduledStartStr, scheduledEndStr *string
 	err := h.db.QueryRow(r.Context(), `
-		INSERT INTO tasks (user_id, quest_id, area_id, title, description, date, scheduled_start, scheduled_end, time_block, position, estimated_minutes, priority, due_at, is_private, block_apps)
-		VALUES ($1, $2, $3, $4, $5, $6, $7::time, $8::time, $9, $10, $11, $12, $13, $14, $15)
+		INSERT INTO tasks (user_id, quest_id, area_id, title, description, date, scheduled_start, scheduled_end, time_block, position, estimated_minutes, priority, due_at, is_private, block_apps, recurrence_rule)
+		VALUES ($1, $2, $3, $4, $5, $6, $7::time, $8::time, $9, $10, $11, $12, $13, $14, $15, $16)
 		RETURNING id, user_id, quest_id, area_id, title, description, date,
 			TO_CHAR(scheduled_start, 'HH24:MI') as scheduled_start,
 			TO_CHAR(scheduled_end, 'HH24:MI') as scheduled_end,
 			time_block, position, estimated_minutes, actual_minutes, priority, status, due_at, completed_at, is_ai_generated, ai_notes, is_private, block_apps, created_at, updated_at
-	`, userID, req.QuestID, req.AreaID, req.Title, req.Description, req.Date, req.ScheduledStart, req.ScheduledEnd, timeBlock, position, req.EstimatedMinutes, priority, req.DueAt, isPrivate, blockApps).Scan(
+	`, userID, req.QuestID, req.AreaID, req.Title, req.Description, req.Date, req.ScheduledStart, req.ScheduledEnd, timeBlock, position, req.EstimatedMinutes, priority, req.DueAt, isPrivate, blockApps, recurrenceRule).Scan(
 		&task.ID, &task.UserID, &task.QuestID, &task.AreaID,
 		&task.Title, &task.Description, &task.Date, &scheduledStartStr, &scheduledEndStr,
 		&task.TimeBlock, &task.Position, &task.EstimatedMinutes, &task.ActualMinutes,
@@ -214,8 +240,15 @@ func (h *Handler) CreateTask(w http.ResponseWriter, r *http.Request) {
 		task.ScheduledEnd = scheduledEndStr
 	}
 
-	// Sync to Google Calendar (async, don't block response)
-	if h.googleCalSvc != nil {
+	if recurrenceRule != nil {
+		task.IsRecurring = true
+		task.RecurrenceRule = recurrenceRule
+		task.SeriesID = &task.ID
+	}
+
+	// Sync to Google Calendar (async, don't block response).
+	// Series are not mirrored: the syncer only handles single events.
+	if h.googleCalSvc != nil && recurrenceRule == nil {
 		go func() {
 			ctx := context.Background()
 			err := h.googleCalSvc.SyncTaskToGoogleCalendar(ctx, userID, task.ID, task.Title, task.Description, task.Date, task.ScheduledStart, task.ScheduledEnd)
@@ -240,6 +273,61 @@ func (h *Handler) UpdateTask(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	scope, err := parseScope(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var recurrenceRule *string
+	if req.RecurrenceRule != nil {
+		rr, err := ParseRRule(*req.RecurrenceRule)
+		if err != nil {
+			http.Error(w, "Invalid recurrence_rule: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		normalized := rr.String()
+		recurrenceRule = &normalized
+	}
+
+	// Recurring tasks: pick the row the edit applies to
+	ref, err := resolveTask(r.Context(), h.db, userID, taskID)
+	if err != nil {
+		http.Error(w, "Task not found", http.StatusNotFound)
+		return
+	}
+	if ref.SeriesID != "" {
+		// Editing the master row itself means the whole series
+		if ref.IsSeries && r.URL.Query().Get("scope") == "" {
+			scope = ScopeAll
+		}
+		if scope == ScopeThis && recurrenceRule != nil {
+			http.Error(w, "recurrence_rule requires scope=all or scope=following", http.StatusBadRequest)
+			return
+		}
+		if scope != ScopeThis && req.Status != nil {
+			http.Error(w, "status can only be changed on a single occurrence", http.StatusBadRequest)
+			return
+		}
+
+		switch scope {
+		case ScopeThis:
+			if ref.TaskID == "" {
+				ref.TaskID, err = materializeOccurrence(r.Context(), h.db, userID, ref.SeriesID, ref.OccurrenceDate)
+			}
+			taskID = ref.TaskID
+		case ScopeFollowing:
+			taskID, err = h.splitSeries(r.Context(), userID, ref.SeriesID, ref.OccurrenceDate)
+		case ScopeAll:
+			taskID = ref.SeriesID
+		}
+		if err != nil {
+			log.Printf("[UpdateTask] Recurrence %s failed for %s: %v", scope, ref.SeriesID, err)
+			http.Error(w, "Task not found or update failed", http.StatusNotFound)
+			return
+		}
+	}
+
 	// Handle status change to completed
 	var completedAt *time.Time
 	if req.Status != nil && *req.Status == "completed" {
@@ -249,7 +337,7 @@ func (h *Handler) UpdateTask(w http.ResponseWriter, r *http.Request) {
 
 	var task Task
 	var scheduledStartStr, scheduledEndStr *string
-	err := h.db.QueryRow(r.Context(), `
+	err = h.db.QueryRow(r.Context(), `
 		UPDATE tasks
 		SET
 			title = COALESCE($3, title),
@@ -268,18 +356,21 @@ func (h *Handler) UpdateTask(w http.ResponseWriter, r *http.Request) {
 			quest_id = COALESCE($16, quest_id),
 			area_id = COALESCE($17, area_id),
 			is_private = COALESCE($18, is_private),
+			recurrence_rule = COALESCE($19, recurrence_rule),
 			updated_at = now()
 		WHERE id = $1 AND user_id = $2
 		RETURNING id, user_id, quest_id, area_id, title, description, date,
 			TO_CHAR(scheduled_start, 'HH24:MI') as scheduled_start,
 			TO_CHAR(scheduled_end, 'HH24:MI') as scheduled_end,
-			time_block, position, estimated_minutes, actual_minutes, priority, status, due_at, completed_at, is_ai_generated, ai_notes, is_private, created_at, updated_at
-	`, taskID, userID, req.Title, req.Description, req.Date, req.ScheduledStart, req.ScheduledEnd, req.TimeBlock, req.Position, req.EstimatedMinutes, req.ActualMinutes, req.Priority, req.Status, req.DueAt, completedAt, req.QuestID, req.AreaID, req.IsPrivate).Scan(
+			time_block, position, estimated_minutes, actual_minutes, priority, status, due_at, completed_at, is_ai_generated, ai_notes, is_private, created_at, updated_at,
+			recurrence_rule, recurrence_parent_id, TO_CHAR(recurrence_date, 'YYYY-MM-DD')
+	`, taskID, userID, req.Title, req.Description, req.Date, req.ScheduledStart, req.ScheduledEnd, req.TimeBlock, req.Position, req.EstimatedMinutes, req.ActualMinutes, req.Priority, req.Status, req.DueAt, completedAt, req.QuestID, req.AreaID, req.IsPrivate, recurrenceRule).Scan(
 		&task.ID, &task.UserID, &task.QuestID, &task.AreaID,
 		&task.Title, &task.Description, &task.Date, &scheduledStartStr, &scheduledEndStr,
 		&task.TimeBlock, &task.Position, &task.EstimatedMinutes, &task.ActualMinutes,
 		&task.Priority, &task.Status, &task.DueAt, &task.CompletedAt,
 		&task.IsAIGenerated, &task.AINotes, &task.IsPrivate, &task.CreatedAt, &task.UpdatedAt,
+		&task.RecurrenceRule, &task.SeriesID, &task.OccurrenceDate,
 	)
 
 	if err != nil {
@@ -294,14 +385,18 @@ func (h *Handler) UpdateTask(w http.ResponseWriter, r *http.Request) {
 	if scheduledEndStr != nil && *scheduledEndStr != "" {
 		task.ScheduledEnd = scheduledEndStr
 	}
+	if task.RecurrenceRule != nil {
+		task.SeriesID = &task.ID
+	}
+	task.IsRecurring = task.SeriesID != nil
 
 	// Update streak when task is completed via UpdateTask
 	if req.Status != nil && *req.Status == "completed" {
-		streak.UpdateUserStreak(r.Context(), h.db, userID)
+		streak.Record(r.Context(), h.db, userID, streak.ActivityTask)
 	}
 
-	// Sync to Google Calendar (async, don't block response)
-	if h.googleCalSvc != nil {
+	// Sync to Google Calendar (async, don't block response). Series are not mirrored.
+	if h.googleCalSvc != nil && task.RecurrenceRule == nil {
 		go func() {
 			ctx := context.Background()
 			err := h.googleCalSvc.SyncTaskToGoogleCalendar(ctx, userID, task.ID, task.Title, task.Description, task.Date, task.ScheduledStart, task.ScheduledEnd)
@@ -317,24 +412,32 @@ func (h *Handler) UpdateTask(w http.ResponseWriter, r *http.Request) {
 
 func (h *Handler) CompleteTask(w http.ResponseWriter, r *http.Request) {
 	userID := r.Context().Value(auth.UserContextKey).(string)
-	taskID := chi.URLParam(r, "id")
+
+	// Occurrences of a recurring task are completed individually
+	taskID, err := concreteTaskID(r.Context(), h.db, userID, chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Task not found", http.StatusNotFound)
+		return
+	}
 
 	var task Task
 	var scheduledStartStr, scheduledEndStr *string
-	err := h.db.QueryRow(r.Context(), `
+	err = h.db.QueryRow(r.Context(), `
 		UPDATE tasks
 		SET status = 'completed', completed_at = now(), updated_at = now()
 		WHERE id = $1 AND user_id = $2
 		RETURNING id, user_id, quest_id, area_id, title, description, date,
 			TO_CHAR(scheduled_start, 'HH24:MI') as scheduled_start,
 			TO_CHAR(scheduled_end, 'HH24:MI') as scheduled_end,
-			time_block, position, estimated_minutes, actual_minutes, priority, status, due_at, completed_at, is_ai_generated, ai_notes, created_at, updated_at
+			time_block, position, estimated_minutes, actual_minutes, priority, status, due_at, completed_at, is_ai_generated, ai_notes, created_at, updated_at,
+			recurrence_parent_id, TO_CHAR(recurrence_date, 'YYYY-MM-DD')
 	`, taskID, userID).Scan(
 		&task.ID, &task.UserID, &task.QuestID, &task.AreaID,
 		&task.Title, &task.Description, &task.Date, &scheduledStartStr, &scheduledEndStr,
 		&task.TimeBlock, &task.Position, &task.EstimatedMinutes, &task.ActualMinutes,
 		&task.Priority, &task.Status, &task.DueAt, &task.CompletedAt,
 		&task.IsAIGenerated, &task.AINotes, &task.CreatedAt, &task.UpdatedAt,
+		&task.SeriesID, &task.OccurrenceDate,
 	)
 
 	if err != nil {
@@ -349,6 +452,7 @@ func (h *Handler) CompleteTask(w http.ResponseWriter, r *http.Request) {
 	if scheduledEndStr != nil && *scheduledEndStr != "" {
 		task.ScheduledEnd = scheduledEndStr
 	}
+	task.IsRecurring = task.SeriesID != nil
 
 	// Update quest progress if linked
 	if task.QuestID != nil {
@@ -356,7 +460,18 @@ func (h *Handler) CompleteTask(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Update streak when a task is completed
-	streak.UpdateUserStreak(r.Context(), h.db, userID)
+	streak.Record(r.Context(), h.db, userID, streak.ActivityTask)
+
+	// Sync to Google Calendar (async, don't block response)
+	if h.googleCalSvc != nil {
+		go func() {
+			ctx := context.Background()
+			err := h.googleCalSvc.SyncTaskToGoogleCalendar(ctx, userID, task.ID, task.Title, task.Description, task.Date, task.ScheduledStart, task.ScheduledEnd)
+			if err != nil {
+				log.Printf("[CompleteTask] Google Calendar sync failed: %v", err)
+			}
+		}()
+	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(task)
@@ -364,24 +479,32 @@ func (h *Handler) CompleteTask(w http.ResponseWriter, r *http.Request) {
 
 func (h *Handler) UncompleteTask(w http.ResponseWriter, r *http.Request) {
 	userID := r.Context().Value(auth.UserContextKey).(string)
-	taskID := chi.URLParam(r, "id")
+
+	// Occurrences of a recurring task are completed individually
+	taskID, err := concreteTaskID(r.Context(), h.db, userID, chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Task not found", http.StatusNotFound)
+		return
+	}
 
 	var task Task
 	var scheduledStartStr, scheduledEndStr *string
-	err := h.db.QueryRow(r.Context(), `
+	err = h.db.QueryRow(r.Context(), `
 		UPDATE tasks
 		SET status = 'pending', completed_at = NULL, updated_at = now()
 		WHERE id = $1 AND user_id = $2
 		RETURNING id, user_id, quest_id, area_id, title, description, date,
 			TO_CHAR(scheduled_start, 'HH24:MI') as scheduled_start,
 			TO_CHAR(scheduled_end, 'HH24:MI') as scheduled_end,
-			time_block, position, estimated_minutes, actual_minutes, priority, status, due_at, completed_at, is_ai_generated, ai_notes, created_at, updated_at
+			time_block, position, estimated_minutes, actual_minutes, priority, status, due_at, completed_at, is_ai_generated, ai_notes, created_at, updated_at,
+			recurrence_parent_id, TO_CHAR(recurrence_date, 'YYYY-MM-DD')
 	`, taskID, userID).Scan(
 		&task.ID, &task.UserID, &task.QuestID, &task.AreaID,
 		&task.Title, &task.Description, &task.Date, &scheduledStartStr, &scheduledEndStr,
 		&task.TimeBlock, &task.Position, &task.EstimatedMinutes, &task.ActualMinutes,
 		&task.Priority, &task.Status, &task.DueAt, &task.CompletedAt,
 		&task.IsAIGenerated, &task.AINotes, &task.CreatedAt, &task.UpdatedAt,
+		&task.SeriesID, &task.OccurrenceDate,
 	)
 
 	if err != nil {
@@ -396,6 +519,18 @@ func (h *Handler) UncompleteTask(w http.ResponseWriter, r *http.Request) {
 	if scheduledEndStr != nil && *scheduledEndStr != "" {
 		task.ScheduledEnd = scheduledEndStr
 	}
+	task.IsRecurring = task.SeriesID != nil
+
+	// Sync to Google Calendar (async, don't block response)
+	if h.googleCalSvc != nil {
+		go func() {
+			ctx := context.Background()
+			err := h.googleCalSvc.SyncTaskToGoogleCalendar(ctx, userID, task.ID, task.Title, task.Description, task.Date, task.ScheduledStart, task.ScheduledEnd)
+			if err != nil {
+				log.Printf("[UncompleteTask] Google Calendar sync failed: %v", err)
+			}
+		}()
+	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(task)
@@ -405,6 +540,52 @@ func (h *Handler) DeleteTask(w http.ResponseWriter, r *http.Request) {
 	userID := r.Context().Value(auth.UserContextKey).(string)
 	taskID := chi.URLParam(r, "id")
 
+	scope, err := parseScope(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ref, err := resolveTask(r.Context(), h.db, userID, taskID)
+	if err != nil {
+		http.Error(w, "Task not found", http.StatusNotFound)
+		return
+	}
+
+	// Recurring tasks: skip one occurrence, end the series, or delete it all
+	if ref.SeriesID != "" {
+		if ref.IsSeries && r.URL.Query().Get("scope") == "" {
+			scope = ScopeAll
+		}
+		// Overrides pushed to Google Calendar go away with the rows
+		googleEventIDs := h.overrideEventIDs(r.Context(), userID, ref, scope)
+		switch scope {
+		case ScopeThis:
+			err = h.skipOccurrence(r.Context(), userID, ref.SeriesID, ref.OccurrenceDate)
+		case ScopeFollowing:
+			err = h.truncateSeries(r.Context(), userID, ref.SeriesID, ref.OccurrenceDate)
+		case ScopeAll:
+			_, err = h.db.Exec(r.Context(), `DELETE FROM tasks WHERE id = $1 AND user_id = $2`, ref.SeriesID, userID)
+		}
+		if err != nil {
+			log.Printf("[DeleteTask] Recurrence %s failed for %s: %v", scope, ref.SeriesID, err)
+			http.Error(w, "Failed to delete task", http.StatusInternalServerError)
+			return
+		}
+		if h.googleCalSvc != nil && len(googleEventIDs) > 0 {
+			go func() {
+				ctx := context.Background()
+				for _, eventID := range googleEventIDs {
+					if err := h.googleCalSvc.DeleteGoogleCalendarEvent(ctx, userID, eventID); err != nil {
+						log.Printf("[DeleteTask] Google Calendar delete failed: %v", err)
+					}
+				}
+			}()
+		}
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
 	// Get Google event ID before deleting
 	var googleEventID *string
 	h.db.QueryRow(r.Context(), `SELECT google_event_id FROM tasks WHERE id = $1 AND user_id = $2`, taskID, userID).Scan(&googleEventID)
@@ -448,11 +629,12 @@ func (h *Handler) getTasksForDay(ctx context.Context, userID, date string, dayPl
 			t.ai_notes, COALESCE(t.is_private, false) as is_private,
 			COALESCE(t.block_apps, false) as block_apps,
 			t.created_at, t.updated_at,
-			q.title as quest_title, a.name as area_name, a.icon as area_icon
+			q.title as quest_title, a.name as area_name, a.icon as area_icon,
+			t.recurrence_parent_id, TO_CHAR(t.recurrence_date, 'YYYY-MM-DD')
 		FROM tasks t
 		LEFT JOIN quests q ON t.quest_id = q.id
 		LEFT JOIN areas a ON t.area_id = a.id
-		WHERE t.user_id = $1 AND t.date = $2
+		WHERE t.user_id = $1 AND t.date = $2 AND t.recurrence_rule IS NULL
 		ORDER BY t.scheduled_start NULLS LAST, t.position
 	`, userID, date)
 
@@ -473,10 +655,12 @@ func (h *Handler) getTasksForDay(ctx context.Context, userID, date string, dayPl
 			&t.Priority, &t.Status, &t.DueAt, &t.CompletedAt, &t.IsAIGenerated,
 			&t.AINotes, &t.IsPrivate, &t.BlockApps, &t.CreatedAt, &t.UpdatedAt,
 			&t.QuestTitle, &t.AreaName, &t.AreaIcon,
+			&t.SeriesID, &t.OccurrenceDate,
 		)
 		if err != nil {
 			continue
 		}
+		t.IsRecurring = t.SeriesID != nil
 
 		if timeBlock != nil {
 			t.TimeBlock = *timeBlock
@@ -484,22 +668,20 @@ func (h *Handler) getTasksForDay(ctx context.Context, userID, date string, dayPl
 			t.TimeBlock = "morning"
 		}
 
-		if scheduledStart != nil && *scheduledStart != "" {
-			t.ScheduledStart = scheduledStart
-		} else {
-			defaultStart := getDefaultStartTime(t.TimeBlock)
-			t.ScheduledStart = &defaultStart
-		}
-		if scheduledEnd != nil && *scheduledEnd != "" {
-			t.ScheduledEnd = scheduledEnd
-		} else {
-			defaultEnd := getDefaultEndTime(t.TimeBlock)
-			t.ScheduledEnd = &defaultEnd
-		}
+		applyDefaultTimes(&t, scheduledStart, scheduledEnd)
 
 		tasks = append(tasks, t)
 	}
 
+	// Recurring tasks are expanded on the fly
+	occurrences, err := h.expandRecurring(ctx, userID, date, date)
+	if err != nil {
+		log.Printf("[getTasksForDay] Recurrence expansion error: %v", err)
+	} else if len(occurrences) > 0 {
+		tasks = append(tasks, occurrences...)
+		sortTasks(tasks)
+	}
+
 	if tasks == nil {
 		tasks = []Task{}
 	}
@@ -535,13 +717,7 @@ func (h *Handler) GetWeekView(w http.ResponseWriter, r *http.Request) {
 	// Default to current week's Monday
 	var startDate time.Time
 	if startDateStr == "" {
-		now := time.Now()
-		// Find Monday of current week
-		weekday := int(now.Weekday())
-		if weekday == 0 {
-			weekday = 7
-		}
-		startDate = now.AddDate(0, 0, -weekday+1)
+		startDate = userclock.MondayOf(userclock.For(r.Context(), h.db, userID).Now())
 	} else {
 		var err error
 		startDate, err = time.Parse("2006-01-02", startDateStr)
@@ -619,11 +795,12 @@ func (h *Handler) getTasksForWeek(ctx context.Context, userID, startDate, endDat
 			t.due_at, t.completed_at,
 			COALESCE(t.is_ai_generated, false) as is_ai_generated,
 			t.ai_notes, COALESCE(t.is_private, false) as is_private, t.created_at, t.updated_at,
-			q.title as quest_title, a.name as area_name, a.icon as area_icon
+			q.title as quest_title, a.name as area_name, a.icon as area_icon,
+			t.recurrence_parent_id, TO_CHAR(t.recurrence_date, 'YYYY-MM-DD')
 		FROM tasks t
 		LEFT JOIN quests q ON t.quest_id = q.id
 		LEFT JOIN areas a ON t.area_id = a.id
-		WHERE t.user_id = $1 AND t.date BETWEEN $2 AND $3
+		WHERE t.user_id = $1 AND t.date BETWEEN $2 AND $3 AND t.recurrence_rule IS NULL
 		ORDER BY t.date, t.scheduled_start NULLS LAST, t.position
 	`, userID, startDate, endDate)
 
@@ -645,30 +822,30 @@ func (h *Handler) getTasksForWeek(ctx context.Context, userID, startDate, endDat
 			&t.Priority, &t.Status, &t.DueAt, &t.CompletedAt, &t.IsAIGenerated,
 			&t.AINotes, &t.IsPrivate, &t.CreatedAt, &t.UpdatedAt,
 			&t.QuestTitle, &t.AreaName, &t.AreaIcon,
+			&t.SeriesID, &t.OccurrenceDate,
 		)
 		if err != nil {
 			log.Printf("[getTasksForWeek] Scan error: %v", err)
 			continue
 		}
 		t.TimeBlock = timeBlock
+		t.IsRecurring = t.SeriesID != nil
 
 		// Use string directly from SQL, or use default based on time_block
-		if scheduledStart != nil && *scheduledStart != "" {
-			t.ScheduledStart = scheduledStart
-		} else {
-			defaultStart := getDefaultStartTime(t.TimeBlock)
-			t.ScheduledStart = &defaultStart
-		}
-		if scheduledEnd != nil && *scheduledEnd != "" {
-			t.ScheduledEnd = scheduledEnd
-		} else {
-			defaultEnd := getDefaultEndTime(t.TimeBlock)
-			t.ScheduledEnd = &defaultEnd
-		}
+		applyDefaultTimes(&t, scheduledStart, scheduledEnd)
 
 		tasks = append(tasks, t)
 	}
 
+	// Recurring tasks are expanded on the fly
+	occurrences, err := h.expandRecurring(ctx, userID, startDate, endDate)
+	if err != nil {
+		log.Printf("[getTasksForWeek] Recurrence expansion error: %v", err)
+	} else if len(occurrences) > 0 {
+		tasks = append(tasks, occurrences...)
+		sortTasks(tasks)
+	}
+
 	if tasks == nil {
 		tasks = []Task{}
 	}
@@ -681,16 +858,31 @@ func (h *Handler) getTasksForWeek(ctx context.Context, userID, startDate, endDat
 // ==========================================
 
 type RescheduleTaskRequest struct {
-	Date           string  `json:"date"`
+	Date           string  `json:"date"`                      // YYYY-MM-DD, defaults to the current date
 	ScheduledStart *string `json:"scheduled_start,omitempty"` // HH:mm format
-	ScheduledEnd   *string `json:"scheduled_end,omitempty"`   // HH:mm format
+	ScheduledEnd   *string `json:"scheduled_end,omitempty"`   // HH:mm format, defaults to the current duration
+	AutoMove       bool    `json:"auto_move,omitempty"`       // On conflict, move to the next free slot in the same time block
+}
+
+// RescheduleConflictResponse is returned with 409 when the new slot is busy.
+type RescheduleConflictResponse struct {
+	Error        string     `json:"error"`
+	Conflicts    []Conflict `json:"conflicts"`
+	NextFreeSlot *string    `json:"nextFreeSlot,omitempty"` // HH:mm in the same time block
 }
 
-// RescheduleTask updates the date and scheduled time of a task (drag & drop)
+type RescheduleTaskResponse struct {
+	Task      Task `json:"task"`
+	AutoMoved bool `json:"autoMoved"`
+}
+
+// RescheduleTask updates the date and scheduled time of a task (drag & drop).
+// The new slot is checked against busy calendar events and other scheduled
+// tasks; conflicts are returned with 409 unless auto_move is set.
 // PATCH /calendar/tasks/{id}/reschedule
 func (h *Handler) RescheduleTask(w http.ResponseWriter, r *http.Request) {
 	userID := r.Context().Value(auth.UserContextKey).(string)
-	taskID := chi.URLParam(r, "id")
+	id := chi.URLParam(r, "id")
 
 	var req RescheduleTaskRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -698,9 +890,131 @@ func (h *Handler) RescheduleTask(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	ref, err := resolveTask(r.Context(), h.db, userID, id)
+	if err != nil {
+		http.Error(w, "Task not found", http.StatusNotFound)
+		return
+	}
+
+	// Current slot, read from the override/one-off row or from the series master
+	rowID := ref.TaskID
+	if rowID == "" {
+		rowID = ref.SeriesID
+	}
+	var curDate, timeBlock string
+	var curStart, curEnd *string
+	var estimatedMinutes *int
+	err = h.db.QueryRow(r.Context(), `
+		SELECT TO_CHAR(date, 'YYYY-MM-DD'),
+			TO_CHAR(scheduled_start, 'HH24:MI'), TO_CHAR(scheduled_end, 'HH24:MI'),
+			COALESCE(time_block, 'morning'), estimated_minutes
+		FROM tasks WHERE id = $1 AND user_id = $2
+	`, rowID, userID).Scan(&curDate, &curStart, &curEnd, &timeBlock, &estimatedMinutes)
+	if err != nil {
+		http.Error(w, "Task not found", http.StatusNotFound)
+		return
+	}
+	if ref.TaskID == "" {
+		curDate = ref.OccurrenceDate
+	}
+
+	date := req.Date
+	if date == "" {
+		date = curDate
+	}
+	if _, err := time.Parse("2006-01-02", date); err != nil {
+		http.Error(w, "Invalid date format (expected YYYY-MM-DD)", http.StatusBadRequest)
+		return
+	}
+	if req.ScheduledStart == nil {
+		http.Error(w, "scheduled_start is required", http.StatusBadRequest)
+		return
+	}
+	start, err := parseClock(*req.ScheduledStart)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Keep the current duration when no end is given
+	duration := 60
+	if curStart != nil && curEnd != nil {
+		s, err1 := parseClock(*curStart)
+		e, err2 := parseClock(*curEnd)
+		if err1 == nil && err2 == nil && e > s {
+			duration = e - s
+		}
+	} else if estimatedMinutes != nil && *estimatedMinutes > 0 {
+		duration = *estimatedMinutes
+	}
+	end := start + duration
+	if req.ScheduledEnd != nil {
+		if end, err = parseClock(*req.ScheduledEnd); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		duration = end - start
+	}
+	if end <= start || end > 24*60 {
+		http.Error(w, "scheduled_end must be after scheduled_start on the same day", http.StatusBadRequest)
+		return
+	}
+
+	// Conflict check against everything else on that day
+	exclude := []string{id, ref.TaskID}
+	if ref.SeriesID != "" {
+		exclude = append(exclude, occurrenceID(ref.SeriesID, ref.OccurrenceDate))
+	}
+	agenda, err := h.loadDayAgenda(r.Context(), userID, date, exclude...)
+	if err != nil {
+		log.Printf("[RescheduleTask] Failed to load agenda for %s: %v", date, err)
+		http.Error(w, "Failed to check availability", http.StatusInternalServerError)
+		return
+	}
+
+	autoMoved := false
+	if conflicts := agenda.conflicts(start, end); len(conflicts) > 0 {
+		notBefore := 0
+		if clock := userclock.For(r.Context(), h.db, userID); date == clock.Today() {
+			now := clock.Now()
+			notBefore = now.Hour()*60 + now.Minute()
+		}
+		windowStart, windowEnd := timeBlockWindow(timeBlock)
+		slot, found := agenda.nextFreeSlot(duration, windowStart, windowEnd, start, notBefore)
+
+		if !req.AutoMove || !found {
+			resp := RescheduleConflictResponse{Error: "Requested slot conflicts with your schedule", Conflicts: conflicts}
+			if found {
+				next := formatClock(slot)
+				resp.NextFreeSlot = &next
+			} else if req.AutoMove {
+				resp.Error = "No free slot left in the " + timeBlock + " block"
+			}
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusConflict)
+			json.NewEncoder(w).Encode(resp)
+			return
+		}
+
+		log.Printf("[RescheduleTask] Auto-moving %s from %s to %s on %s", id, formatClock(start), formatClock(slot), date)
+		start, end = slot, slot+duration
+		autoMoved = true
+	}
+
+	// Dragging an occurrence of a recurring task moves that occurrence only
+	taskID := ref.TaskID
+	if taskID == "" {
+		taskID, err = materializeOccurrence(r.Context(), h.db, userID, ref.SeriesID, ref.OccurrenceDate)
+		if err != nil {
+			http.Error(w, "Task not found or update failed", http.StatusNotFound)
+			return
+		}
+	}
+	newStart, newEnd := formatClock(start), formatClock(end)
+
 	var task Task
 	var scheduledStartStr, scheduledEndStr *string
-	err := h.db.QueryRow(r.Context(), `
+	err = h.db.QueryRow(r.Context(), `
 		UPDATE tasks
 		SET
 			date = COALESCE($3, date),
@@ -711,13 +1025,15 @@ func (h *Handler) RescheduleTask(w http.ResponseWriter, r *http.Request) {
 		RETURNING id, user_id, quest_id, area_id, title, description, date,
 			TO_CHAR(scheduled_start, 'HH24:MI') as scheduled_start,
 			TO_CHAR(scheduled_end, 'HH24:MI') as scheduled_end,
-			time_block, position, estimated_minutes, actual_minutes, priority, status, due_at, completed_at, is_ai_generated, ai_notes, created_at, updated_at
-	`, taskID, userID, req.Date, req.ScheduledStart, req.ScheduledEnd).Scan(
+			time_block, position, estimated_minutes, actual_minutes, priority, status, due_at, completed_at, is_ai_generated, ai_notes, created_at, updated_at,
+			recurrence_parent_id, TO_CHAR(recurrence_date, 'YYYY-MM-DD')
+	`, taskID, userID, date, newStart, newEnd).Scan(
 		&task.ID, &task.UserID, &task.QuestID, &task.AreaID,
 		&task.Title, &task.Description, &task.Date, &scheduledStartStr, &scheduledEndStr,
 		&task.TimeBlock, &task.Position, &task.EstimatedMinutes, &task.ActualMinutes,
 		&task.Priority, &task.Status, &task.DueAt, &task.CompletedAt,
 		&task.IsAIGenerated, &task.AINotes, &task.CreatedAt, &task.UpdatedAt,
+		&task.SeriesID, &task.OccurrenceDate,
 	)
 
 	if err != nil {
@@ -732,8 +1048,20 @@ func (h *Handler) RescheduleTask(w http.ResponseWriter, r *http.Request) {
 	if scheduledEndStr != nil && *scheduledEndStr != "" {
 		task.ScheduledEnd = scheduledEndStr
 	}
+	task.IsRecurring = task.SeriesID != nil
+
+	// Sync to Google Calendar (async, don't block response)
+	if h.googleCalSvc != nil {
+		go func() {
+			ctx := context.Background()
+			err := h.googleCalSvc.SyncTaskToGoogleCalendar(ctx, userID, task.ID, task.Title, task.Description, task.Date, task.ScheduledStart, task.ScheduledEnd)
+			if err != nil {
+				log.Printf("[RescheduleTask] Google Calendar sync failed: %v", err)
+			}
+		}()
+	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(task)
+	json.NewEncoder(w).Encode(RescheduleTaskResponse{Task: task, AutoMoved: autoMoved})
 }
 
@@ -0,0 +1,585 @@
+package calendar
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"firelevel-backend/internal/userclock"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// ==========================================
+// RECURRING TASKS
+// A series is one master row with recurrence_rule.
+// Occurrences are virtual until edited/completed,
+// then materialised as an override row pointing at
+// the master (recurrence_parent_id + recurrence_date).
+// Virtual occurrence IDs are "<seriesID>_<YYYY-MM-DD>".
+// ==========================================
+
+// Edit/delete scopes for recurring tasks (?scope=)
+const (
+	ScopeThis      = "this"
+	ScopeFollowing = "following"
+	ScopeAll       = "all"
+)
+
+var errTaskNotFound = errors.New("task not found")
+
+// taskRef identifies what a task ID points at.
+type taskRef struct {
+	TaskID         string // Concrete row ID ("" for a virtual occurrence)
+	SeriesID       string // Master row ID ("" for a one-off task)
+	OccurrenceDate string // YYYY-MM-DD of the occurrence within the series
+	IsSeries       bool   // ID was the master row itself
+}
+
+func occurrenceID(seriesID, date string) string {
+	return seriesID + "_" + date
+}
+
+// parseOccurrenceID splits a virtual occurrence ID.
+func parseOccurrenceID(id string) (seriesID, date string, ok bool) {
+	i := strings.LastIndex(id, "_")
+	if i <= 0 {
+		return "", "", false
+	}
+	if _, err := time.Parse("2006-01-02", id[i+1:]); err != nil {
+		return "", "", false
+	}
+	return id[:i], id[i+1:], true
+}
+
+// parseScope reads ?scope=, defaulting to this occurrence.
+func parseScope(r *http.Request) (string, error) {
+	switch scope := r.URL.Query().Get("scope"); scope {
+	case "", ScopeThis:
+		return ScopeThis, nil
+	case ScopeFollowing, ScopeAll:
+		return scope, nil
+	default:
+		return "", fmt.Errorf("invalid scope %q (use this, following or all)", scope)
+	}
+}
+
+// resolveTask works out whether id is a one-off task, a series master, an
+// override row or a virtual occurrence.
+func resolveTask(ctx context.Context, db userclock.Querier, userID, id string) (*taskRef, error) {
+	if seriesID, date, ok := parseOccurrenceID(id); ok {
+		var overrideID *string
+		err := db.QueryRow(ctx, `
+			SELECT (SELECT o.id FROM tasks o WHERE o.recurrence_parent_id = t.id AND o.recurrence_date = $3::date)
+			FROM tasks t
+			WHERE t.id = $1 AND t.user_id = $2 AND t.recurrence_rule IS NOT NULL
+		`, seriesID, userID, date).Scan(&overrideID)
+		if err != nil {
+			return nil, errTaskNotFound
+		}
+		ref := &taskRef{SeriesID: seriesID, OccurrenceDate: date}
+		if overrideID != nil {
+			ref.TaskID = *overrideID
+		}
+		return ref, nil
+	}
+
+	var parentID, recurrenceDate, rule *string
+	var date string
+	err := db.QueryRow(ctx, `
+		SELECT recurrence_parent_id, TO_CHAR(recurrence_date, 'YYYY-MM-DD'), recurrence_rule, TO_CHAR(date, 'YYYY-MM-DD')
+		FROM tasks WHERE id = $1 AND user_id = $2
+	`, id, userID).Scan(&parentID, &recurrenceDate, &rule, &date)
+	if err != nil {
+		return nil, errTaskNotFound
+	}
+
+	switch {
+	case parentID != nil:
+		ref := &taskRef{TaskID: id, SeriesID: *parentID}
+		if recurrenceDate != nil {
+			ref.OccurrenceDate = *recurrenceDate
+		}
+		return ref, nil
+	case rule != nil:
+		return &taskRef{SeriesID: id, OccurrenceDate: date, IsSeries: true}, nil
+	default:
+		return &taskRef{TaskID: id}, nil
+	}
+}
+
+// concreteTaskID resolves id to a real row, materialising a virtual occurrence
+// (or the first occurrence of a series master) when needed.
+func concreteTaskID(ctx context.Context, db userclock.Querier, userID, id string) (string, error) {
+	ref, err := resolveTask(ctx, db, userID, id)
+	if err != nil {
+		return "", err
+	}
+	if ref.TaskID != "" {
+		return ref.TaskID, nil
+	}
+	return materializeOccurrence(ctx, db, userID, ref.SeriesID, ref.OccurrenceDate)
+}
+
+// materializeOccurrence creates (or returns) the override row for one occurrence.
+func materializeOccurrence(ctx context.Context, db userclock.Querier, userID, seriesID, date string) (string, error) {
+	var rule, dtstart string
+	var exdates []string
+	err := db.QueryRow(ctx, `
+		SELECT recurrence_rule, TO_CHAR(date, 'YYYY-MM-DD'),
+		       ARRAY(SELECT TO_CHAR(d, 'YYYY-MM-DD') FROM unnest(recurrence_exdates) d)
+		FROM tasks WHERE id = $1 AND user_id = $2 AND recurrence_rule IS NOT NULL
+	`, seriesID, userID).Scan(&rule, &dtstart, &exdates)
+	if err != nil {
+		return "", errTaskNotFound
+	}
+
+	rr, err := ParseRRule(rule)
+	if err != nil {
+		return "", fmt.Errorf("series %s has an invalid rule: %w", seriesID, err)
+	}
+	start, err := time.Parse("2006-01-02", dtstart)
+	if err != nil {
+		return "", fmt.Errorf("series %s has an invalid start %q: %w", seriesID, dtstart, err)
+	}
+	day, err := time.Parse("2006-01-02", date)
+	if err != nil || !rr.Includes(start, day) || containsString(exdates, date) {
+		return "", errTaskNotFound
+	}
+
+	var id string
+	err = db.QueryRow(ctx, `
+		INSERT INTO tasks (user_id, quest_id, area_id, title, description, date, scheduled_start, scheduled_end,
+			time_block, position, estimated_minutes, priority, due_at, is_private, block_apps, is_ai_generated, ai_notes,
+			recurrence_parent_id, recurrence_date)
+		SELECT user_id, quest_id, area_id, title, description, $3::date, scheduled_start, scheduled_end,
+			time_block, position, estimated_minutes, priority, due_at, is_private, block_apps, is_ai_generated, ai_notes,
+			id, $3::date
+		FROM tasks WHERE id = $1 AND user_id = $2
+		ON CONFLICT (recurrence_parent_id, recurrence_date) WHERE recurrence_parent_id IS NOT NULL
+		DO UPDATE SET updated_at = tasks.updated_at
+		RETURNING id
+	`, seriesID, userID, date).Scan(&id)
+	if err != nil {
+		return "", fmt.Errorf("materialize occurrence: %w", err)
+	}
+
+	log.Printf("[Recurrence] Materialized occurrence %s of series %s", date, seriesID)
+	return id, nil
+}
+
+// splitSeries ends the series the day before date and starts a copy of it on
+// date. Overrides and exdates from date onwards move to the new series.
+// Returns the new series ID, or seriesID itself when date is the series start.
+func (h *Handler) splitSeries(ctx context.Context, userID, seriesID, date string) (string, error) {
+	tx, err := h.db.Begin(ctx)
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback(ctx)
+
+	oldRule, newRule, atStart, err := splitRules(ctx, tx, userID, seriesID, date)
+	if err != nil {
+		return "", err
+	}
+	if atStart {
+		return seriesID, nil
+	}
+
+	var newID string
+	err = tx.QueryRow(ctx, `
+		INSERT INTO tasks (user_id, quest_id, area_id, title, description, date, scheduled_start, scheduled_end,
+			time_block, position, estimated_minutes, priority, due_at, is_private, block_apps, is_ai_generated, ai_notes,
+			recurrence_rule, recurrence_exdates)
+		SELECT user_id, quest_id, area_id, title, description, $3::date, scheduled_start, scheduled_end,
+			time_block, position, estimated_minutes, priority, due_at, is_private, block_apps, is_ai_generated, ai_notes,
+			$4, ARRAY(SELECT d FROM unnest(recurrence_exdates) d WHERE d >= $3::date)
+		FROM tasks WHERE id = $1 AND user_id = $2
+		RETURNING id
+	`, seriesID, userID, date, newRule).Scan(&newID)
+	if err != nil {
+		return "", fmt.Errorf("create split series: %w", err)
+	}
+
+	if _, err := tx.Exec(ctx, `
+		UPDATE tasks SET
+			recurrence_rule = $3,
+			recurrence_exdates = ARRAY(SELECT d FROM unnest(recurrence_exdates) d WHERE d < $4::date),
+			updated_at = NOW()
+		WHERE id = $1 AND user_id = $2
+	`, seriesID, userID, oldRule, date); err != nil {
+		return "", fmt.Errorf("truncate series: %w", err)
+	}
+
+	if _, err := tx.Exec(ctx, `
+		UPDATE tasks SET recurrence_parent_id = $3
+		WHERE recurrence_parent_id = $1 AND user_id = $2 AND recurrence_date >= $4::date
+	`, seriesID, userID, newID, date); err != nil {
+		return "", fmt.Errorf("move overrides: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return "", err
+	}
+	log.Printf("[Recurrence] Split series %s at %s → %s", seriesID, date, newID)
+	return newID, nil
+}
+
+// truncateSeries ends the series the day before date and drops every override
+// from date onwards ("delete this and following").
+func (h *Handler) truncateSeries(ctx context.Context, userID, seriesID, date string) error {
+	tx, err := h.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	oldRule, _, atStart, err := splitRules(ctx, tx, userID, seriesID, date)
+	if err != nil {
+		return err
+	}
+	if atStart {
+		// Nothing left before date: drop the whole series (overrides cascade)
+		if _, err := tx.Exec(ctx, `DELETE FROM tasks WHERE id = $1 AND user_id = $2`, seriesID, userID); err != nil {
+			return fmt.Errorf("delete series: %w", err)
+		}
+		return tx.Commit(ctx)
+	}
+
+	if _, err := tx.Exec(ctx, `
+		UPDATE tasks SET
+			recurrence_rule = $3,
+			recurrence_exdates = ARRAY(SELECT d FROM unnest(recurrence_exdates) d WHERE d < $4::date),
+			updated_at = NOW()
+		WHERE id = $1 AND user_id = $2
+	`, seriesID, userID, oldRule, date); err != nil {
+		return fmt.Errorf("truncate series: %w", err)
+	}
+	if _, err := tx.Exec(ctx, `
+		DELETE FROM tasks WHERE recurrence_parent_id = $1 AND user_id = $2 AND recurrence_date >= $3::date
+	`, seriesID, userID, date); err != nil {
+		return fmt.Errorf("delete overrides: %w", err)
+	}
+
+	return tx.Commit(ctx)
+}
+
+// skipOccurrence removes a single occurrence ("delete this occurrence").
+func (h *Handler) skipOccurrence(ctx context.Context, userID, seriesID, date string) error {
+	tx, err := h.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(ctx, `
+		UPDATE tasks SET
+			recurrence_exdates = CASE
+				WHEN $3::date = ANY(recurrence_exdates) THEN recurrence_exdates
+				ELSE array_append(recurrence_exdates, $3::date)
+			END,
+			updated_at = NOW()
+		WHERE id = $1 AND user_id = $2 AND recurrence_rule IS NOT NULL
+	`, seriesID, userID, date)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return errTaskNotFound
+	}
+	if _, err := tx.Exec(ctx, `
+		DELETE FROM tasks WHERE recurrence_parent_id = $1 AND user_id = $2 AND recurrence_date = $3::date
+	`, seriesID, userID, date); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// overrideEventIDs lists the Google event IDs of the overrides a delete with
+// scope is about to remove.
+func (h *Handler) overrideEventIDs(ctx context.Context, userID string, ref *taskRef, scope string) []string {
+	from := ref.OccurrenceDate
+	switch scope {
+	case ScopeThis:
+		if ref.TaskID == "" {
+			return nil
+		}
+	case ScopeAll:
+		from = "0001-01-01"
+	}
+
+	rows, err := h.db.Query(ctx, `
+		SELECT google_event_id FROM tasks
+		WHERE recurrence_parent_id = $1 AND user_id = $2
+		  AND google_event_id IS NOT NULL
+		  AND recurrence_date >= $3::date
+		  AND (NOT $4::bool OR recurrence_date = $3::date)
+	`, ref.SeriesID, userID, from, scope == ScopeThis)
+	if err != nil {
+		log.Printf("[overrideEventIDs] Query error for %s: %v", ref.SeriesID, err)
+		return nil
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err == nil && id != "" {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// splitRules returns the rule of the series truncated before date, and the
+// rule for a continuation starting on date (COUNT is shared between both).
+// atStart is true when no occurrence is left before date (date on or before
+// the series start, or none matching the rule), so the whole series is
+// concerned.
+func splitRules(ctx context.Context, tx pgx.Tx, userID, seriesID, date string) (oldRule, newRule string, atStart bool, err error) {
+	var rule, dtstart string
+	err = tx.QueryRow(ctx, `
+		SELECT recurrence_rule, TO_CHAR(date, 'YYYY-MM-DD')
+		FROM tasks WHERE id = $1 AND user_id = $2 AND recurrence_rule IS NOT NULL
+		FOR UPDATE
+	`, seriesID, userID).Scan(&rule, &dtstart)
+	if err != nil {
+		return "", "", false, errTaskNotFound
+	}
+
+	rr, err := ParseRRule(rule)
+	if err != nil {
+		return "", "", false, err
+	}
+	start, err := time.Parse("2006-01-02", dtstart)
+	if err != nil {
+		return "", "", false, fmt.Errorf("series %s has an invalid start %q: %w", seriesID, dtstart, err)
+	}
+	splitAt, err := time.Parse("2006-01-02", date)
+	if err != nil {
+		return "", "", false, err
+	}
+	oldRule, newRule, atStart = splitRule(rr, start, splitAt)
+	return oldRule, newRule, atStart, nil
+}
+
+// splitRule splits rr (starting on start) at splitAt. See splitRules.
+func splitRule(rr *RRule, start, splitAt time.Time) (before, after string, atStart bool) {
+	n := 0
+	if splitAt.After(start) {
+		n = rr.CountBefore(start, splitAt)
+	}
+	if n == 0 {
+		// Nothing before the split: the series is not cut, it is replaced
+		return rr.String(), rr.String(), true
+	}
+
+	b := *rr
+	a := *rr
+	if rr.Count > 0 {
+		b.Count = n
+		a.Count = max(rr.Count-n, 1)
+	} else {
+		until := splitAt.AddDate(0, 0, -1)
+		b.Until = &until
+	}
+	return b.String(), a.String(), false
+}
+
+// ==========================================
+// EXPANSION
+// ==========================================
+
+// Occurrences returns the virtual occurrences of userID's recurring tasks
+// between from and to (inclusive, YYYY-MM-DD), for callers outside the
+// calendar API that list tasks.
+func Occurrences(ctx context.Context, db *pgxpool.Pool, userID, from, to string) ([]Task, error) {
+	return NewHandler(db).expandRecurring(ctx, userID, from, to)
+}
+
+// CountDay returns how many tasks userID has on date (YYYY-MM-DD) and how
+// many of them are completed, occurrences of recurring tasks included.
+func CountDay(ctx context.Context, db *pgxpool.Pool, userID, date string) (total, completed int, err error) {
+	// Series rows stand for their occurrences and are not tasks of their
+	// start date; overrides are regular rows.
+	err = db.QueryRow(ctx, `
+		SELECT COUNT(*), COUNT(*) FILTER (WHERE status = 'completed')
+		FROM tasks
+		WHERE user_id = $1 AND date = $2::date AND recurrence_rule IS NULL
+	`, userID, date).Scan(&total, &completed)
+	if err != nil {
+		return 0, 0, err
+	}
+	occurrences, err := Occurrences(ctx, db, userID, date, date)
+	if err != nil {
+		return 0, 0, fmt.Errorf("expand recurring tasks: %w", err)
+	}
+	for _, o := range occurrences {
+		total++
+		if o.Status == "completed" {
+			completed++
+		}
+	}
+	return total, completed, nil
+}
+
+// ConcreteTaskID resolves a task ID that may be a virtual occurrence to a
+// real row, materialising the occurrence when needed.
+func ConcreteTaskID(ctx context.Context, db *pgxpool.Pool, userID, id string) (string, error) {
+	return concreteTaskID(ctx, db, userID, id)
+}
+
+// expandRecurring returns the virtual occurrences of the user's series between
+// from and to (inclusive, YYYY-MM-DD). Overridden and skipped dates are left
+// out: overrides are regular rows returned by the normal task queries.
+func (h *Handler) expandRecurring(ctx context.Context, userID, from, to string) ([]Task, error) {
+	fromDate, err := time.Parse("2006-01-02", from)
+	if err != nil {
+		return nil, err
+	}
+	toDate, err := time.Parse("2006-01-02", to)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := h.db.Query(ctx, `
+		SELECT
+			t.id, t.user_id, t.quest_id, t.area_id,
+			t.title, t.description, TO_CHAR(t.date, 'YYYY-MM-DD'),
+			TO_CHAR(t.scheduled_start, 'HH24:MI') as scheduled_start,
+			TO_CHAR(t.scheduled_end, 'HH24:MI') as scheduled_end,
+			COALESCE(t.time_block, 'morning') as time_block,
+			COALESCE(t.position, 0) as position,
+			t.estimated_minutes,
+			COALESCE(t.priority, 'medium') as priority,
+			t.due_at,
+			COALESCE(t.is_ai_generated, false) as is_ai_generated,
+			t.ai_notes, COALESCE(t.is_private, false) as is_private,
+			COALESCE(t.block_apps, false) as block_apps,
+			t.created_at, t.updated_at,
+			q.title as quest_title, a.name as area_name, a.icon as area_icon,
+			t.recurrence_rule,
+			ARRAY(SELECT TO_CHAR(d, 'YYYY-MM-DD') FROM unnest(t.recurrence_exdates) d),
+			ARRAY(
+				SELECT TO_CHAR(o.recurrence_date, 'YYYY-MM-DD') FROM tasks o
+				WHERE o.recurrence_parent_id = t.id AND o.recurrence_date BETWEEN $2 AND $3
+			)
+		FROM tasks t
+		LEFT JOIN quests q ON t.quest_id = q.id
+		LEFT JOIN areas a ON t.area_id = a.id
+		WHERE t.user_id = $1 AND t.recurrence_rule IS NOT NULL AND t.date <= $3
+	`, userID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tasks []Task
+	for rows.Next() {
+		var series Task
+		var scheduledStart, scheduledEnd *string
+		var rule string
+		var exdates, overridden []string
+		err := rows.Scan(
+			&series.ID, &series.UserID, &series.QuestID, &series.AreaID,
+			&series.Title, &series.Description, &series.Date, &scheduledStart, &scheduledEnd,
+			&series.TimeBlock, &series.Position, &series.EstimatedMinutes,
+			&series.Priority, &series.DueAt, &series.IsAIGenerated,
+			&series.AINotes, &series.IsPrivate, &series.BlockApps, &series.CreatedAt, &series.UpdatedAt,
+			&series.QuestTitle, &series.AreaName, &series.AreaIcon,
+			&rule, &exdates, &overridden,
+		)
+		if err != nil {
+			log.Printf("[expandRecurring] Scan error: %v", err)
+			continue
+		}
+
+		rr, err := ParseRRule(rule)
+		if err != nil {
+			log.Printf("[expandRecurring] Invalid rule on series %s: %v", series.ID, err)
+			continue
+		}
+		dtstart, err := time.Parse("2006-01-02", series.Date)
+		if err != nil {
+			return nil, fmt.Errorf("series %s has an invalid start %q: %w", series.ID, series.Date, err)
+		}
+
+		applyDefaultTimes(&series, scheduledStart, scheduledEnd)
+
+		for _, d := range rr.Occurrences(dtstart, fromDate, toDate) {
+			date := d.Format("2006-01-02")
+			if containsString(exdates, date) || containsString(overridden, date) {
+				continue
+			}
+			t := series
+			seriesID := series.ID
+			occurrenceDate := date
+			t.ID = occurrenceID(series.ID, date)
+			t.Date = date
+			t.Status = "pending"
+			t.SeriesID = &seriesID
+			t.OccurrenceDate = &occurrenceDate
+			t.IsRecurring = true
+			t.RecurrenceRule = &rule
+			tasks = append(tasks, t)
+		}
+	}
+
+	return tasks, rows.Err()
+}
+
+// applyDefaultTimes fills scheduled times from the time block when missing.
+func applyDefaultTimes(t *Task, scheduledStart, scheduledEnd *string) {
+	if scheduledStart != nil && *scheduledStart != "" {
+		t.ScheduledStart = scheduledStart
+	} else {
+		defaultStart := getDefaultStartTime(t.TimeBlock)
+		t.ScheduledStart = &defaultStart
+		t.timesDefaulted = true
+	}
+	if scheduledEnd != nil && *scheduledEnd != "" {
+		t.ScheduledEnd = scheduledEnd
+	} else {
+		defaultEnd := getDefaultEndTime(t.TimeBlock)
+		t.ScheduledEnd = &defaultEnd
+		t.timesDefaulted = true
+	}
+}
+
+// sortTasks orders tasks like the SQL queries: date, start time, position.
+func sortTasks(tasks []Task) {
+	sort.SliceStable(tasks, func(i, j int) bool {
+		a, b := tasks[i], tasks[j]
+		if a.Date != b.Date {
+			return a.Date < b.Date
+		}
+		as, bs := derefString(a.ScheduledStart), derefString(b.ScheduledStart)
+		if as != bs {
+			return as < bs
+		}
+		return a.Position < b.Position
+	})
+}
+
+func derefString(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,53 @@
+package calendar
+
+import "testing"
+
+func TestSplitRule(t *testing.T) {
+	tests := []struct {
+		name        string
+		rule        string
+		start       string
+		splitAt     string
+		wantBefore  string
+		wantAfter   string
+		wantAtStart bool
+	}{
+		{
+			name: "open series", rule: "FREQ=DAILY", start: "2026-10-01", splitAt: "2026-10-10",
+			wantBefore: "FREQ=DAILY;UNTIL=20261009", wantAfter: "FREQ=DAILY",
+		},
+		{
+			name: "count is shared", rule: "FREQ=DAILY;COUNT=10", start: "2026-10-01", splitAt: "2026-10-04",
+			wantBefore: "FREQ=DAILY;COUNT=3", wantAfter: "FREQ=DAILY;COUNT=7",
+		},
+		{
+			name: "split on the start", rule: "FREQ=DAILY;COUNT=10", start: "2026-10-01", splitAt: "2026-10-01",
+			wantBefore: "FREQ=DAILY;COUNT=10", wantAfter: "FREQ=DAILY;COUNT=10", wantAtStart: true,
+		},
+		{
+			// Thursday start, Monday-only rule: the first occurrence is the 5th
+			name: "no occurrence before the split", rule: "FREQ=WEEKLY;BYDAY=MO;COUNT=4", start: "2026-10-01", splitAt: "2026-10-05",
+			wantBefore: "FREQ=WEEKLY;BYDAY=MO;COUNT=4", wantAfter: "FREQ=WEEKLY;BYDAY=MO;COUNT=4", wantAtStart: true,
+		},
+		{
+			name: "no occurrence before the split, open series", rule: "FREQ=WEEKLY;BYDAY=MO", start: "2026-10-01", splitAt: "2026-10-05",
+			wantBefore: "FREQ=WEEKLY;BYDAY=MO", wantAfter: "FREQ=WEEKLY;BYDAY=MO", wantAtStart: true,
+		},
+		{
+			name: "weekly count", rule: "FREQ=WEEKLY;BYDAY=MO,TH;COUNT=6", start: "2026-10-01", splitAt: "2026-10-12",
+			wantBefore: "FREQ=WEEKLY;BYDAY=MO,TH;COUNT=3", wantAfter: "FREQ=WEEKLY;BYDAY=MO,TH;COUNT=3",
+		},
+	}
+
+	for _, tt := range tests {
+		rr, err := ParseRRule(tt.rule)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		before, after, atStart := splitRule(rr, date(tt.start), date(tt.splitAt))
+		if before != tt.wantBefore || after != tt.wantAfter || atStart != tt.wantAtStart {
+			t.Errorf("%s: splitRule = %q, %q, %v; want %q, %q, %v",
+				tt.name, before, after, atStart, tt.wantBefore, tt.wantAfter, tt.wantAtStart)
+		}
+	}
+}
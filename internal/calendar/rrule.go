@@ -0,0 +1,247 @@
+package calendar
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ==========================================
+// RRULE (RFC 5545 subset)
+// FREQ=DAILY|WEEKLY|MONTHLY, INTERVAL, BYDAY,
+// COUNT, UNTIL. Weeks start on Monday.
+// ==========================================
+
+// maxOccurrences bounds expansion of open-ended rules.
+const maxOccurrences = 3660
+
+// RRule is a parsed recurrence rule.
+type RRule struct {
+	Freq     string // DAILY, WEEKLY, MONTHLY
+	Interval int
+	ByDay    []time.Weekday
+	Count    int
+	Until    *time.Time // Inclusive, date only
+}
+
+var rruleWeekdays = map[string]time.Weekday{
+	"MO": time.Monday, "TU": time.Tuesday, "WE": time.Wednesday, "TH": time.Thursday,
+	"FR": time.Friday, "SA": time.Saturday, "SU": time.Sunday,
+}
+
+// ParseRRule parses a rule such as "FREQ=WEEKLY;BYDAY=MO,WE;COUNT=10".
+// A leading "RRULE:" is accepted.
+func ParseRRule(s string) (*RRule, error) {
+	s = strings.TrimPrefix(strings.TrimSpace(s), "RRULE:")
+	if s == "" {
+		return nil, fmt.Errorf("empty recurrence rule")
+	}
+
+	rule := &RRule{Interval: 1}
+	for _, part := range strings.Split(s, ";") {
+		key, value, ok := strings.Cut(part, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid rule part %q", part)
+		}
+		switch strings.ToUpper(key) {
+		case "FREQ":
+			rule.Freq = strings.ToUpper(value)
+		case "INTERVAL":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 {
+				return nil, fmt.Errorf("invalid INTERVAL %q", value)
+			}
+			rule.Interval = n
+		case "COUNT":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 {
+				return nil, fmt.Errorf("invalid COUNT %q", value)
+			}
+			rule.Count = n
+		case "UNTIL":
+			until, err := parseRRuleDate(value)
+			if err != nil {
+				return nil, err
+			}
+			rule.Until = &until
+		case "BYDAY":
+			for _, d := range strings.Split(value, ",") {
+				wd, ok := rruleWeekdays[strings.ToUpper(d)]
+				if !ok {
+					return nil, fmt.Errorf("unsupported BYDAY value %q", d)
+				}
+				rule.ByDay = append(rule.ByDay, wd)
+			}
+		case "WKST":
+			// Weeks always start on Monday
+		default:
+			return nil, fmt.Errorf("unsupported rule part %q", key)
+		}
+	}
+
+	switch rule.Freq {
+	case "DAILY", "WEEKLY", "MONTHLY":
+	case "":
+		return nil, fmt.Errorf("FREQ is required")
+	default:
+		return nil, fmt.Errorf("unsupported FREQ %q", rule.Freq)
+	}
+	if rule.Count > 0 && rule.Until != nil {
+		return nil, fmt.Errorf("COUNT and UNTIL cannot both be set")
+	}
+
+	sort.Slice(rule.ByDay, func(i, j int) bool { return mondayIndex(rule.ByDay[i]) < mondayIndex(rule.ByDay[j]) })
+	return rule, nil
+}
+
+// String serializes the rule back to RRULE syntax (without the "RRULE:" prefix).
+func (r *RRule) String() string {
+	parts := []string{"FREQ=" + r.Freq}
+	if r.Interval > 1 {
+		parts = append(parts, "INTERVAL="+strconv.Itoa(r.Interval))
+	}
+	if len(r.ByDay) > 0 {
+		days := make([]string, len(r.ByDay))
+		for i, wd := range r.ByDay {
+			days[i] = strings.ToUpper(wd.String()[:2])
+		}
+		parts = append(parts, "BYDAY="+strings.Join(days, ","))
+	}
+	if r.Count > 0 {
+		parts = append(parts, "COUNT="+strconv.Itoa(r.Count))
+	}
+	if r.Until != nil {
+		parts = append(parts, "UNTIL="+r.Until.Format("20060102"))
+	}
+	return strings.Join(parts, ";")
+}
+
+// Occurrences returns the occurrence dates of a series starting on dtstart
+// that fall within [from, to]. All dates are calendar days (UTC midnight).
+func (r *RRule) Occurrences(dtstart, from, to time.Time) []time.Time {
+	var out []time.Time
+	r.each(dtstart, func(d time.Time) bool {
+		if d.After(to) {
+			return false
+		}
+		if !d.Before(from) {
+			out = append(out, d)
+		}
+		return true
+	})
+	return out
+}
+
+// CountBefore returns how many occurrences happen strictly before date.
+func (r *RRule) CountBefore(dtstart, date time.Time) int {
+	n := 0
+	r.each(dtstart, func(d time.Time) bool {
+		if !d.Before(date) {
+			return false
+		}
+		n++
+		return true
+	})
+	return n
+}
+
+// Includes reports whether date is an occurrence of the series.
+func (r *RRule) Includes(dtstart, date time.Time) bool {
+	return len(r.Occurrences(dtstart, date, date)) == 1
+}
+
+// each calls fn for every occurrence in order until fn returns false or the
+// rule is exhausted (COUNT, UNTIL or maxOccurrences).
+func (r *RRule) each(dtstart time.Time, fn func(time.Time) bool) {
+	emitted := 0
+	emit := func(d time.Time) bool {
+		if d.Before(dtstart) {
+			return true
+		}
+		if r.Until != nil && d.After(*r.Until) {
+			return false
+		}
+		if r.Count > 0 && emitted >= r.Count {
+			return false
+		}
+		emitted++
+		return fn(d)
+	}
+
+	for period := 0; emitted < maxOccurrences && period < maxOccurrences; period++ {
+		for _, d := range r.periodDates(dtstart, period) {
+			if !emit(d) {
+				return
+			}
+		}
+	}
+}
+
+// periodDates returns the candidate dates of the n-th period (day, week or month).
+func (r *RRule) periodDates(dtstart time.Time, n int) []time.Time {
+	switch r.Freq {
+	case "DAILY":
+		d := dtstart.AddDate(0, 0, n*r.Interval)
+		if len(r.ByDay) > 0 && !r.hasDay(d.Weekday()) {
+			return nil
+		}
+		return []time.Time{d}
+
+	case "WEEKLY":
+		weekStart := dtstart.AddDate(0, 0, -mondayIndex(dtstart.Weekday())+7*n*r.Interval)
+		days := r.ByDay
+		if len(days) == 0 {
+			days = []time.Weekday{dtstart.Weekday()}
+		}
+		dates := make([]time.Time, 0, len(days))
+		for _, wd := range days {
+			dates = append(dates, weekStart.AddDate(0, 0, mondayIndex(wd)))
+		}
+		return dates
+
+	case "MONTHLY":
+		first := time.Date(dtstart.Year(), dtstart.Month()+time.Month(n*r.Interval), 1, 0, 0, 0, 0, time.UTC)
+		if len(r.ByDay) == 0 {
+			d := first.AddDate(0, 0, dtstart.Day()-1)
+			if d.Month() != first.Month() {
+				// e.g. the 31st in a 30-day month: no occurrence (RFC 5545)
+				return nil
+			}
+			return []time.Time{d}
+		}
+		var dates []time.Time
+		for d := first; d.Month() == first.Month(); d = d.AddDate(0, 0, 1) {
+			if r.hasDay(d.Weekday()) {
+				dates = append(dates, d)
+			}
+		}
+		return dates
+	}
+	return nil
+}
+
+func (r *RRule) hasDay(wd time.Weekday) bool {
+	for _, d := range r.ByDay {
+		if d == wd {
+			return true
+		}
+	}
+	return false
+}
+
+// mondayIndex maps Monday..Sunday to 0..6.
+func mondayIndex(wd time.Weekday) int {
+	return (int(wd) + 6) % 7
+}
+
+// parseRRuleDate accepts UNTIL as YYYYMMDD, YYYYMMDDTHHMMSS(Z) or YYYY-MM-DD.
+func parseRRuleDate(s string) (time.Time, error) {
+	for _, layout := range []string{"20060102T150405Z", "20060102T150405", "20060102", "2006-01-02"} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid UNTIL %q", s)
+}
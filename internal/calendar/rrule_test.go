@@ -0,0 +1,170 @@
+package calendar
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func date(s string) time.Time {
+	t, err := time.Parse("2006-01-02", s)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func formatDates(dates []time.Time) string {
+	out := make([]string, len(dates))
+	for i, d := range dates {
+		out[i] = d.Format("2006-01-02")
+	}
+	return strings.Join(out, ",")
+}
+
+func TestParseRRule(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    string // String() of the parsed rule, "" when an error is expected
+		wantErr string
+	}{
+		{in: "FREQ=DAILY", want: "FREQ=DAILY"},
+		{in: "RRULE:FREQ=WEEKLY;BYDAY=WE,MO", want: "FREQ=WEEKLY;BYDAY=MO,WE"},
+		{in: "freq=weekly;byday=fr;interval=2;count=5", want: "FREQ=WEEKLY;INTERVAL=2;BYDAY=FR;COUNT=5"},
+		{in: "FREQ=MONTHLY;UNTIL=20261231T235959Z", want: "FREQ=MONTHLY;UNTIL=20261231"},
+		{in: "FREQ=DAILY;UNTIL=2026-03-01;WKST=SU", want: "FREQ=DAILY;UNTIL=20260301"},
+		{in: "", wantErr: "empty"},
+		{in: "BYDAY=MO", wantErr: "FREQ is required"},
+		{in: "FREQ=YEARLY", wantErr: "unsupported FREQ"},
+		{in: "FREQ=DAILY;INTERVAL=0", wantErr: "INTERVAL"},
+		{in: "FREQ=DAILY;COUNT=-1", wantErr: "COUNT"},
+		{in: "FREQ=WEEKLY;BYDAY=1MO", wantErr: "BYDAY"},
+		{in: "FREQ=DAILY;COUNT=3;UNTIL=20260301", wantErr: "cannot both be set"},
+		{in: "FREQ=DAILY;BYHOUR=9", wantErr: "unsupported rule part"},
+		{in: "FREQ", wantErr: "invalid rule part"},
+	}
+
+	for _, tt := range tests {
+		rule, err := ParseRRule(tt.in)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ParseRRule(%q) error = %v, want it to contain %q", tt.in, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParseRRule(%q) unexpected error: %v", tt.in, err)
+			continue
+		}
+		if got := rule.String(); got != tt.want {
+			t.Errorf("ParseRRule(%q).String() = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestRRuleOccurrences(t *testing.T) {
+	tests := []struct {
+		name     string
+		rule     string
+		dtstart  string // 2026-01-05 is a Monday
+		from, to string
+		want     string
+	}{
+		{
+			name: "daily", rule: "FREQ=DAILY",
+			dtstart: "2026-01-05", from: "2026-01-05", to: "2026-01-08",
+			want: "2026-01-05,2026-01-06,2026-01-07,2026-01-08",
+		},
+		{
+			name: "daily window after start", rule: "FREQ=DAILY;INTERVAL=3",
+			dtstart: "2026-01-05", from: "2026-01-09", to: "2026-01-15",
+			want: "2026-01-11,2026-01-14",
+		},
+		{
+			name: "daily restricted to weekdays", rule: "FREQ=DAILY;BYDAY=MO,TU,WE,TH,FR",
+			dtstart: "2026-01-08", from: "2026-01-08", to: "2026-01-13",
+			want: "2026-01-08,2026-01-09,2026-01-12,2026-01-13",
+		},
+		{
+			name: "weekly defaults to start weekday", rule: "FREQ=WEEKLY",
+			dtstart: "2026-01-07", from: "2026-01-01", to: "2026-01-31",
+			want: "2026-01-07,2026-01-14,2026-01-21,2026-01-28",
+		},
+		{
+			name: "weekly byday skips days before start", rule: "FREQ=WEEKLY;BYDAY=MO,FR",
+			dtstart: "2026-01-07", from: "2026-01-01", to: "2026-01-19",
+			want: "2026-01-09,2026-01-12,2026-01-16,2026-01-19",
+		},
+		{
+			name: "biweekly", rule: "FREQ=WEEKLY;INTERVAL=2;BYDAY=TU",
+			dtstart: "2026-01-05", from: "2026-01-01", to: "2026-02-10",
+			want: "2026-01-06,2026-01-20,2026-02-03",
+		},
+		{
+			name: "count limits the series", rule: "FREQ=WEEKLY;BYDAY=MO,WE;COUNT=3",
+			dtstart: "2026-01-05", from: "2026-01-01", to: "2026-12-31",
+			want: "2026-01-05,2026-01-07,2026-01-12",
+		},
+		{
+			name: "count is consumed before the window", rule: "FREQ=DAILY;COUNT=5",
+			dtstart: "2026-01-05", from: "2026-01-08", to: "2026-01-31",
+			want: "2026-01-08,2026-01-09",
+		},
+		{
+			name: "until is inclusive", rule: "FREQ=DAILY;UNTIL=20260107",
+			dtstart: "2026-01-05", from: "2026-01-01", to: "2026-01-31",
+			want: "2026-01-05,2026-01-06,2026-01-07",
+		},
+		{
+			name: "monthly skips short months", rule: "FREQ=MONTHLY",
+			dtstart: "2026-01-31", from: "2026-01-01", to: "2026-05-31",
+			want: "2026-01-31,2026-03-31,2026-05-31",
+		},
+		{
+			name: "monthly byday", rule: "FREQ=MONTHLY;BYDAY=SU",
+			dtstart: "2026-02-01", from: "2026-02-01", to: "2026-02-28",
+			want: "2026-02-01,2026-02-08,2026-02-15,2026-02-22",
+		},
+		{
+			name: "window before start", rule: "FREQ=DAILY",
+			dtstart: "2026-01-05", from: "2025-12-01", to: "2026-01-04",
+			want: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule, err := ParseRRule(tt.rule)
+			if err != nil {
+				t.Fatalf("ParseRRule(%q): %v", tt.rule, err)
+			}
+			got := formatDates(rule.Occurrences(date(tt.dtstart), date(tt.from), date(tt.to)))
+			if got != tt.want {
+				t.Errorf("Occurrences = %s\nwant          %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRRuleIncludesAndCountBefore(t *testing.T) {
+	rule, err := ParseRRule("FREQ=WEEKLY;BYDAY=MO,TH")
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := date("2026-01-05")
+
+	for day, want := range map[string]bool{
+		"2026-01-05": true,
+		"2026-01-08": true,
+		"2026-01-09": false,
+		"2026-01-04": false,
+	} {
+		if got := rule.Includes(start, date(day)); got != want {
+			t.Errorf("Includes(%s) = %v, want %v", day, got, want)
+		}
+	}
+
+	if got := rule.CountBefore(start, date("2026-01-15")); got != 3 {
+		t.Errorf("CountBefore = %d, want 3", got)
+	}
+}
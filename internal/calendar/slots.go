@@ -0,0 +1,204 @@
+package calendar
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"firelevel-backend/internal/userclock"
+)
+
+// ==========================================
+// FREE / BUSY SLOTS
+// Busy time on a day = confirmed busy calendar_events
+// + app-blocking windows (block_apps events)
+// + other scheduled tasks. Times are minutes since
+// local midnight in the user's timezone.
+// ==========================================
+
+// slotStep is the granularity used when looking for a free slot.
+const slotStep = 15
+
+// Conflict is something already occupying the requested slot.
+type Conflict struct {
+	Source  string `json:"source"` // calendar_event, task
+	ID      string `json:"id"`
+	Title   string `json:"title"`
+	StartAt string `json:"startAt"` // RFC3339
+	EndAt   string `json:"endAt"`   // RFC3339
+}
+
+// busyInterval is a [start, end) range of minutes on one day.
+type busyInterval struct {
+	start, end int
+	conflict   Conflict
+}
+
+// dayAgenda holds the busy intervals of one day for one user.
+type dayAgenda struct {
+	date string
+	loc  *time.Location
+	busy []busyInterval
+}
+
+// timeBlockWindow returns the [start, end) minutes of a time block.
+func timeBlockWindow(timeBlock string) (int, int) {
+	switch timeBlock {
+	case "afternoon":
+		return 12 * 60, 18 * 60
+	case "evening":
+		return 18 * 60, 23 * 60
+	default:
+		return 6 * 60, 12 * 60
+	}
+}
+
+// parseClock parses HH:mm into minutes since midnight.
+func parseClock(s string) (int, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %q (expected HH:mm)", s)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// formatClock formats minutes since midnight as HH:mm.
+func formatClock(m int) string {
+	return fmt.Sprintf("%02d:%02d", m/60, m%60)
+}
+
+// loadDayAgenda collects busy intervals for date. Tasks whose ID is in
+// exclude (the task being moved), completed tasks and tasks without a
+// user-chosen time are ignored.
+func (h *Handler) loadDayAgenda(ctx context.Context, userID, date string, exclude ...string) (*dayAgenda, error) {
+	clock := userclock.For(ctx, h.db, userID)
+	dayStart, dayEnd, err := clock.DayBounds(date)
+	if err != nil {
+		return nil, err
+	}
+	agenda := &dayAgenda{date: date, loc: clock.Location()}
+
+	rows, err := h.db.Query(ctx, `
+		SELECT ce.id, ce.title, ce.start_at, ce.end_at
+		FROM public.calendar_events ce
+		WHERE ce.user_id = $1
+		  AND (ce.is_busy = true OR ce.block_apps = true)
+		  AND ce.event_status = 'confirmed'
+		  AND ce.start_at < $3 AND ce.end_at > $2
+		ORDER BY ce.start_at ASC
+	`, userID, dayStart, dayEnd)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var id, title string
+		var startAt, endAt time.Time
+		if err := rows.Scan(&id, &title, &startAt, &endAt); err != nil {
+			continue
+		}
+		start, end := 0, 24*60
+		if startAt.After(dayStart) {
+			local := startAt.In(agenda.loc)
+			start = local.Hour()*60 + local.Minute()
+		}
+		if endAt.Before(dayEnd) {
+			local := endAt.In(agenda.loc)
+			end = local.Hour()*60 + local.Minute()
+		}
+		agenda.busy = append(agenda.busy, busyInterval{start: start, end: end, conflict: Conflict{
+			Source:  "calendar_event",
+			ID:      id,
+			Title:   title,
+			StartAt: startAt.Format(time.RFC3339),
+			EndAt:   endAt.Format(time.RFC3339),
+		}})
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	tasks, err := h.getTasksForDay(ctx, userID, date, nil)
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range tasks {
+		if t.timesDefaulted || t.Status == "completed" || containsString(exclude, t.ID) {
+			continue
+		}
+		start, err1 := parseClock(derefString(t.ScheduledStart))
+		end, err2 := parseClock(derefString(t.ScheduledEnd))
+		if err1 != nil || err2 != nil || end <= start {
+			continue
+		}
+		agenda.busy = append(agenda.busy, busyInterval{start: start, end: end, conflict: Conflict{
+			Source:  "task",
+			ID:      t.ID,
+			Title:   t.Title,
+			StartAt: agenda.at(start).Format(time.RFC3339),
+			EndAt:   agenda.at(end).Format(time.RFC3339),
+		}})
+	}
+
+	sort.Slice(agenda.busy, func(i, j int) bool { return agenda.busy[i].start < agenda.busy[j].start })
+	return agenda, nil
+}
+
+// at converts minutes since midnight to a time on the agenda's day.
+func (a *dayAgenda) at(m int) time.Time {
+	d, _ := time.ParseInLocation("2006-01-02", a.date, a.loc)
+	return time.Date(d.Year(), d.Month(), d.Day(), 0, m, 0, 0, a.loc)
+}
+
+// conflicts returns everything overlapping [start, end).
+func (a *dayAgenda) conflicts(start, end int) []Conflict {
+	var out []Conflict
+	for _, b := range a.busy {
+		if b.start < end && start < b.end {
+			out = append(out, b.conflict)
+		}
+	}
+	return out
+}
+
+// nextFreeSlot finds a start for a slot of duration minutes inside
+// [windowStart, windowEnd), not before notBefore. It prefers the first free
+// start at or after preferred, then the latest free start before it.
+func (a *dayAgenda) nextFreeSlot(duration, windowStart, windowEnd, preferred, notBefore int) (int, bool) {
+	if windowStart < notBefore {
+		windowStart = notBefore
+	}
+
+	// Candidates: the preferred start, the window start, the end of every
+	// busy interval and a regular grid.
+	seen := map[int]bool{}
+	var candidates []int
+	add := func(m int) {
+		if m >= windowStart && m+duration <= windowEnd && !seen[m] {
+			seen[m] = true
+			candidates = append(candidates, m)
+		}
+	}
+	add(preferred)
+	add(windowStart)
+	for _, b := range a.busy {
+		add(b.end)
+	}
+	for m := windowStart - windowStart%slotStep; m < windowEnd; m += slotStep {
+		add(m)
+	}
+	sort.Ints(candidates)
+
+	best, found := 0, false
+	for _, m := range candidates {
+		if len(a.conflicts(m, m+duration)) > 0 {
+			continue
+		}
+		if m >= preferred {
+			return m, true
+		}
+		best, found = m, true
+	}
+	return best, found
+}
@@ -0,0 +1,126 @@
+package calendar
+
+import "testing"
+
+func TestNextFreeSlot(t *testing.T) {
+	busy := func(ranges ...[2]int) *dayAgenda {
+		a := &dayAgenda{date: "2026-10-16"}
+		for _, r := range ranges {
+			a.busy = append(a.busy, busyInterval{start: r[0], end: r[1]})
+		}
+		return a
+	}
+	morningStart, morningEnd := timeBlockWindow("morning")
+	eveningStart, eveningEnd := timeBlockWindow("evening")
+
+	tests := []struct {
+		name        string
+		agenda      *dayAgenda
+		duration    int
+		windowStart int
+		windowEnd   int
+		preferred   int
+		notBefore   int
+		want        string // "" when no slot fits
+	}{
+		{
+			name:     "empty day takes the preferred start",
+			agenda:   busy(),
+			duration: 60, windowStart: morningStart, windowEnd: morningEnd, preferred: 9*60 + 30,
+			want: "09:30",
+		},
+		{
+			name:     "right after an event covering the preferred start",
+			agenda:   busy([2]int{9 * 60, 10 * 60}),
+			duration: 30, windowStart: morningStart, windowEnd: morningEnd, preferred: 9*60 + 30,
+			want: "10:00",
+		},
+		{
+			name:     "skips a gap that is too short",
+			agenda:   busy([2]int{9 * 60, 10 * 60}, [2]int{10*60 + 30, 11 * 60}),
+			duration: 60, windowStart: morningStart, windowEnd: morningEnd, preferred: 9*60 + 30,
+			want: "11:00",
+		},
+		{
+			name:     "off-grid event end is a candidate",
+			agenda:   busy([2]int{9 * 60, 10*60 + 7}),
+			duration: 30, windowStart: morningStart, windowEnd: morningEnd, preferred: 9*60 + 30,
+			want: "10:07",
+		},
+		{
+			name:     "nothing after the preferred start, latest slot before it",
+			agenda:   busy([2]int{10 * 60, 12 * 60}),
+			duration: 60, windowStart: morningStart, windowEnd: morningEnd, preferred: 9*60 + 30,
+			want: "09:00",
+		},
+		{
+			name:     "not before now",
+			agenda:   busy(),
+			duration: 30, windowStart: morningStart, windowEnd: morningEnd, preferred: 9*60 + 30, notBefore: 10*60 + 13,
+			want: "10:13",
+		},
+		{
+			name:     "now is past the window",
+			agenda:   busy(),
+			duration: 30, windowStart: morningStart, windowEnd: morningEnd, preferred: 9*60 + 30, notBefore: 12*60 + 5,
+			want: "",
+		},
+		{
+			name:     "slot must end inside the window",
+			agenda:   busy(),
+			duration: 60, windowStart: eveningStart, windowEnd: eveningEnd, preferred: 22*60 + 30,
+			want: "22:00",
+		},
+		{
+			name:     "longer than the window",
+			agenda:   busy(),
+			duration: 7 * 60, windowStart: morningStart, windowEnd: morningEnd, preferred: 9*60 + 30,
+			want: "",
+		},
+		{
+			name:     "no gap long enough",
+			agenda:   busy([2]int{18*60 + 30, 19 * 60}, [2]int{19*60 + 45, 21 * 60}, [2]int{22 * 60, 23 * 60}),
+			duration: 90, windowStart: eveningStart, windowEnd: eveningEnd, preferred: 19 * 60,
+			want: "",
+		},
+		{
+			name:     "all-day event",
+			agenda:   busy([2]int{0, 24 * 60}),
+			duration: 15, windowStart: morningStart, windowEnd: morningEnd, preferred: 9*60 + 30,
+			want: "",
+		},
+	}
+
+	for _, tt := range tests {
+		start, found := tt.agenda.nextFreeSlot(tt.duration, tt.windowStart, tt.windowEnd, tt.preferred, tt.notBefore)
+		got := ""
+		if found {
+			got = formatClock(start)
+		}
+		if got != tt.want {
+			t.Errorf("%s: nextFreeSlot = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestConflicts(t *testing.T) {
+	a := &dayAgenda{busy: []busyInterval{
+		{start: 9 * 60, end: 10 * 60, conflict: Conflict{ID: "meeting"}},
+		{start: 11 * 60, end: 12 * 60, conflict: Conflict{ID: "lunch"}},
+	}}
+	tests := []struct {
+		start, end int
+		want       int
+	}{
+		{8 * 60, 9 * 60, 0},         // Ends when the meeting starts
+		{10 * 60, 11 * 60, 0},       // Fits exactly between the two
+		{9*60 + 59, 10 * 60, 1},     // One minute of overlap
+		{8 * 60, 13 * 60, 2},        // Spans both
+		{11*60 + 15, 11*60 + 30, 1}, // Inside lunch
+	}
+	for _, tt := range tests {
+		if got := len(a.conflicts(tt.start, tt.end)); got != tt.want {
+			t.Errorf("conflicts(%s, %s) = %d, want %d", formatClock(tt.start), formatClock(tt.end), got, tt.want)
+		}
+	}
+}
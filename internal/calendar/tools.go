@@ -0,0 +1,536 @@
+package calendar
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"firelevel-backend/internal/streak"
+	"firelevel-backend/internal/tools"
+	"firelevel-backend/internal/userclock"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// ==========================================
+// AI TOOLS
+// Task and auto-schedule tools Kai can call.
+// ==========================================
+
+// RegisterTools adds the calendar tools to reg.
+func RegisterTools(reg *tools.Registry) error {
+	return reg.Register(
+		tools.Func[tools.NoArgs]{
+			Name:        "get_today_tasks",
+			Description: "Récupère la liste des tâches du jour avec statut, bloc horaire et priorité.",
+			Run:         runGetTodayTasks,
+		},
+		tools.Func[tasksForDateArgs]{
+			Name:        "get_tasks_for_date",
+			Description: "Récupère les tâches pour une date spécifique (demain, la semaine prochaine, etc). Utilise-le pour voir les tâches futures avant d'en créer.",
+			Run:         runGetTasksForDate,
+		},
+		tools.Func[createTaskArgs]{
+			Name:        "create_task",
+			Description: "Crée une nouvelle tâche. Peut être pour aujourd'hui ou n'importe quelle date future (date au format YYYY-MM-DD).",
+			Run:         runCreateTask,
+		},
+		tools.Func[completeTaskArgs]{
+			Name:        "complete_task",
+			Description: "Marque une tâche comme complétée. IMPORTANT: appelle TOUJOURS get_today_tasks ou get_tasks_for_date AVANT pour obtenir le vrai task_id. Ne devine JAMAIS un ID.",
+			Run:         runCompleteTask,
+		},
+		tools.Func[taskIDArgs]{
+			Name:        "uncomplete_task",
+			Description: "Marque une tâche comme non complétée.",
+			Run:         runUncompleteTask,
+		},
+		tools.Func[updateTaskArgs]{
+			Name:        "update_task",
+			Description: "Modifie une tâche existante.",
+			Run:         runUpdateTask,
+		},
+		tools.Func[taskIDArgs]{
+			Name:        "delete_task",
+			Description: "Supprime une tâche.",
+			Policy:      tools.PolicyConfirm,
+			Run:         runDeleteTask,
+			Preview:     previewDeleteTask,
+		},
+		tools.Func[createTasksBatchArgs]{
+			Name:        "create_tasks_batch",
+			Description: "Crée plusieurs tâches d'un coup. Utilise après une session de planification pour créer toutes les tâches en une seule fois. Quand tu spécifies scheduled_start/scheduled_end avec block_apps=true, les apps sont automatiquement bloquées pendant ces créneaux.",
+			Policy:      tools.PolicyConfirm,
+			Run:         runCreateTasksBatch,
+			Preview:     previewCreateTasksBatch,
+		},
+		tools.Func[proposeScheduleArgs]{
+			Name:        "propose_schedule",
+			Description: "Propose un horaire pour les tâches SANS heure d'un jour : évite les événements calendrier occupés, les créneaux de blocage et les tâches déjà planifiées, place les tâches prioritaires pendant le pic de productivité. N'écrit rien : montre la proposition à l'utilisateur.",
+			Run:         runProposeSchedule,
+		},
+		tools.Func[acceptScheduleArgs]{
+			Name:        "accept_schedule",
+			Description: "Applique d'un coup une proposition de propose_schedule, UNIQUEMENT après accord explicite de l'utilisateur. Si l'agenda a changé entre temps, rien n'est appliqué : relance propose_schedule.",
+			Run:         runAcceptSchedule,
+		},
+	)
+}
+
+// ==========================================
+// Arguments
+// ==========================================
+
+type tasksForDateArgs struct {
+	Date string `json:"date" desc:"Date au format YYYY-MM-DD" format:"date" required:"true"`
+}
+
+type createTaskArgs struct {
+	Title     string `json:"title" desc:"Le titre de la tâche" required:"true"`
+	Date      string `json:"date,omitempty" desc:"Date YYYY-MM-DD (défaut: aujourd'hui)" format:"date"`
+	Priority  string `json:"priority,omitempty" desc:"Priorité" enum:"high,medium,low"`
+	TimeBlock string `json:"time_block,omitempty" desc:"Bloc horaire" enum:"morning,afternoon,evening"`
+}
+
+type completeTaskArgs struct {
+	TaskID    string `json:"task_id" desc:"L'ID exact de la tâche (UUID, obtenu via get_today_tasks)" required:"true"`
+	TaskTitle string `json:"task_title,omitempty" desc:"Le titre de la tâche (fallback si l'ID est inconnu)"`
+}
+
+type taskIDArgs struct {
+	TaskID string `json:"task_id" desc:"L'ID de la tâche" required:"true"`
+}
+
+type updateTaskArgs struct {
+	TaskID    string `json:"task_id" desc:"L'ID de la tâche" required:"true"`
+	Title     string `json:"title,omitempty" desc:"Nouveau titre"`
+	Date      string `json:"date,omitempty" desc:"Nouvelle date YYYY-MM-DD" format:"date"`
+	Priority  string `json:"priority,omitempty" desc:"Nouvelle priorité" enum:"high,medium,low"`
+	TimeBlock string `json:"time_block,omitempty" desc:"Nouveau bloc horaire" enum:"morning,afternoon,evening"`
+}
+
+type batchTask struct {
+	Title            string `json:"title" desc:"Titre de la tâche" required:"true"`
+	Date             string `json:"date,omitempty" desc:"Date YYYY-MM-DD (défaut: aujourd'hui)" format:"date"`
+	TimeBlock        string `json:"time_block,omitempty" desc:"Bloc horaire" enum:"morning,afternoon,evening"`
+	Priority         string `json:"priority,omitempty" desc:"Priorité" enum:"high,medium,low"`
+	EstimatedMinutes int    `json:"estimated_minutes,omitempty" desc:"Durée estimée en minutes" min:"0"`
+	ScheduledStart   string `json:"scheduled_start,omitempty" desc:"Heure de début HH:mm" format:"time"`
+	ScheduledEnd     string `json:"scheduled_end,omitempty" desc:"Heure de fin HH:mm" format:"time"`
+	BlockApps        bool   `json:"block_apps,omitempty" desc:"true pour bloquer les apps pendant cette tâche"`
+}
+
+type createTasksBatchArgs struct {
+	Tasks []batchTask `json:"tasks" desc:"Liste des tâches à créer" required:"true"`
+}
+
+type proposeScheduleArgs struct {
+	Date string `json:"date,omitempty" desc:"Date YYYY-MM-DD (défaut: aujourd'hui)" format:"date"`
+}
+
+type acceptScheduleArgs struct {
+	ProposalID string `json:"proposal_id" desc:"L'ID de la proposition (obtenu via propose_schedule)" required:"true"`
+}
+
+// ==========================================
+// Implementations
+// ==========================================
+
+// TaskSummary is a task as shown to Kai.
+type TaskSummary struct {
+	ID        string `json:"id"`
+	Title     string `json:"title"`
+	Status    string `json:"status"`
+	TimeBlock string `json:"time_block"`
+	Priority  string `json:"priority"`
+	Date      string `json:"date"`
+}
+
+// TasksForDate returns the tasks of date in display order, followed by the
+// occurrences of recurring series that fall on it. Occurrences carry their
+// virtual "<seriesID>_<date>" ID, which the task tools accept.
+func TasksForDate(ctx context.Context, db *pgxpool.Pool, userID, date string) ([]TaskSummary, error) {
+	rows, err := db.Query(ctx, `
+		SELECT id, title, COALESCE(status, 'pending'), COALESCE(time_block, ''), COALESCE(priority, 'medium'), date::text
+		FROM tasks WHERE user_id = $1 AND date = $2 AND recurrence_rule IS NULL
+		ORDER BY position, created_at
+	`, userID, date)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tasks := []TaskSummary{}
+	for rows.Next() {
+		var t TaskSummary
+		if err := rows.Scan(&t.ID, &t.Title, &t.Status, &t.TimeBlock, &t.Priority, &t.Date); err != nil {
+			continue
+		}
+		tasks = append(tasks, t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	occurrences, err := NewHandler(db).expandRecurring(ctx, userID, date, date)
+	if err != nil {
+		return nil, fmt.Errorf("expand recurring tasks: %w", err)
+	}
+	for _, o := range occurrences {
+		tasks = append(tasks, TaskSummary{ID: o.ID, Title: o.Title, Status: o.Status, TimeBlock: o.TimeBlock, Priority: o.Priority, Date: o.Date})
+	}
+	return tasks, nil
+}
+
+func runGetTodayTasks(ctx context.Context, call *tools.Call, _ tools.NoArgs) (tools.Result, error) {
+	today := userclock.For(ctx, call.DB, call.UserID).Today()
+	tasks, err := TasksForDate(ctx, call.DB, call.UserID, today)
+	if err != nil {
+		return tools.Result{}, err
+	}
+	return tools.Result{Output: map[string]interface{}{"date": today, "tasks": tasks}}, nil
+}
+
+func runGetTasksForDate(ctx context.Context, call *tools.Call, args tasksForDateArgs) (tools.Result, error) {
+	tasks, err := TasksForDate(ctx, call.DB, call.UserID, args.Date)
+	if err != nil {
+		return tools.Result{}, err
+	}
+	res := tools.Result{Output: map[string]interface{}{"date": args.Date, "tasks": tasks}}
+	if args.Date != userclock.For(ctx, call.DB, call.UserID).Today() {
+		res.SideEffects = []tools.SideEffect{tools.NewSideEffectWithData("queried_future_date", map[string]string{"date": args.Date})}
+	}
+	return res, nil
+}
+
+func runCreateTask(ctx context.Context, call *tools.Call, args createTaskArgs) (tools.Result, error) {
+	date := args.Date
+	if date == "" {
+		date = userclock.For(ctx, call.DB, call.UserID).Today()
+	}
+	priority := orDefault(args.Priority, "medium")
+	timeBlock := orDefault(args.TimeBlock, "morning")
+
+	var taskID string
+	err := call.DB.QueryRow(ctx, `
+		INSERT INTO tasks (user_id, title, date, priority, time_block, is_ai_generated)
+		VALUES ($1, $2, $3, $4, $5, true)
+		RETURNING id
+	`, call.UserID, args.Title, date, priority, timeBlock).Scan(&taskID)
+	if err != nil {
+		return tools.Result{}, fmt.Errorf("create task: %w", err)
+	}
+
+	return tools.Result{
+		Output:      map[string]interface{}{"created": true, "task_id": taskID, "title": args.Title},
+		SideEffects: []tools.SideEffect{tools.RefreshTasks, tools.CalendarNeedsRefresh, tools.ShowCard("tasks")},
+		Changes:     []tools.Change{call.Created(ctx, "tasks", taskID)},
+		Summary:     "Tâche créée : " + args.Title,
+	}, nil
+}
+
+func runCompleteTask(ctx context.Context, call *tools.Call, args completeTaskArgs) (tools.Result, error) {
+	taskID, before, materialized, err := resolveToolTask(ctx, call, args.TaskID)
+	if err != nil {
+		return tools.Result{}, fmt.Errorf("complete task: %w", err)
+	}
+
+	var title string
+	err = call.DB.QueryRow(ctx, `
+		UPDATE tasks SET status = 'completed', completed_at = now(), updated_at = now()
+		WHERE id = $1 AND user_id = $2
+		RETURNING title
+	`, taskID, call.UserID).Scan(&title)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return tools.Result{}, fmt.Errorf("task not found: %s", args.TaskID)
+	}
+	if err != nil {
+		return tools.Result{}, fmt.Errorf("complete task: %w", err)
+	}
+	streak.Record(ctx, call.DB, call.UserID, streak.ActivityTask)
+
+	return tools.Result{
+		Output:      map[string]interface{}{"completed": true, "task_id": taskID},
+		SideEffects: []tools.SideEffect{tools.RefreshTasks, tools.CalendarNeedsRefresh},
+		Changes:     []tools.Change{toolTaskChange(ctx, call, taskID, before, materialized)},
+		Summary:     "Tâche terminée : " + title,
+	}, nil
+}
+
+func runUncompleteTask(ctx context.Context, call *tools.Call, args taskIDArgs) (tools.Result, error) {
+	taskID, before, materialized, err := resolveToolTask(ctx, call, args.TaskID)
+	if err != nil {
+		return tools.Result{}, fmt.Errorf("uncomplete task: %w", err)
+	}
+
+	var title string
+	err = call.DB.QueryRow(ctx, `
+		UPDATE tasks SET status = 'pending', completed_at = NULL, updated_at = now()
+		WHERE id = $1 AND user_id = $2
+		RETURNING title
+	`, taskID, call.UserID).Scan(&title)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return tools.Result{}, fmt.Errorf("task not found: %s", args.TaskID)
+	}
+	if err != nil {
+		return tools.Result{}, fmt.Errorf("uncomplete task: %w", err)
+	}
+
+	return tools.Result{
+		Output:      map[string]interface{}{"uncompleted": true, "task_id": taskID},
+		SideEffects: []tools.SideEffect{tools.RefreshTasks, tools.CalendarNeedsRefresh},
+		Changes:     []tools.Change{toolTaskChange(ctx, call, taskID, before, materialized)},
+		Summary:     "Tâche rouverte : " + title,
+	}, nil
+}
+
+func runUpdateTask(ctx context.Context, call *tools.Call, args updateTaskArgs) (tools.Result, error) {
+	// Build dynamic SET clause from the fields that were sent
+	sets := []string{}
+	sqlArgs := []interface{}{}
+	for _, f := range []struct{ column, value string }{
+		{"title", args.Title},
+		{"date", args.Date},
+		{"priority", args.Priority},
+		{"time_block", args.TimeBlock},
+	} {
+		if f.value == "" {
+			continue
+		}
+		sqlArgs = append(sqlArgs, f.value)
+		sets = append(sets, fmt.Sprintf("%s = $%d", f.column, len(sqlArgs)))
+	}
+
+	if len(sets) == 0 {
+		return tools.Result{Output: map[string]interface{}{"updated": false, "reason": "no fields to update"}}, nil
+	}
+
+	taskID, before, materialized, err := resolveToolTask(ctx, call, args.TaskID)
+	if err != nil {
+		return tools.Result{}, fmt.Errorf("update task: %w", err)
+	}
+
+	sets = append(sets, "updated_at = now()")
+	query := fmt.Sprintf("UPDATE tasks SET %s WHERE id = $%d AND user_id = $%d RETURNING title",
+		strings.Join(sets, ", "), len(sqlArgs)+1, len(sqlArgs)+2)
+	sqlArgs = append(sqlArgs, taskID, call.UserID)
+
+	var title string
+	err = call.DB.QueryRow(ctx, query, sqlArgs...).Scan(&title)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return tools.Result{}, fmt.Errorf("task not found: %s", args.TaskID)
+	}
+	if err != nil {
+		return tools.Result{}, fmt.Errorf("update task: %w", err)
+	}
+
+	return tools.Result{
+		Output:      map[string]interface{}{"updated": true, "task_id": taskID},
+		SideEffects: []tools.SideEffect{tools.RefreshTasks, tools.CalendarNeedsRefresh},
+		Changes:     []tools.Change{toolTaskChange(ctx, call, taskID, before, materialized)},
+		Summary:     "Tâche modifiée : " + title,
+	}, nil
+}
+
+func runDeleteTask(ctx context.Context, call *tools.Call, args taskIDArgs) (tools.Result, error) {
+	h := NewHandler(call.DB)
+	ref, err := resolveTask(ctx, h.db, call.UserID, args.TaskID)
+	if err != nil {
+		return tools.Result{}, fmt.Errorf("task not found: %s", args.TaskID)
+	}
+	if ref.SeriesID != "" && !ref.IsSeries {
+		return skipToolOccurrence(ctx, call, h, ref)
+	}
+
+	before, err := tools.Snapshot(ctx, call.DB, "tasks", call.UserID, args.TaskID)
+	if err != nil {
+		return tools.Result{}, fmt.Errorf("delete task: %w", err)
+	}
+
+	var title string
+	err = call.DB.QueryRow(ctx, "DELETE FROM tasks WHERE id = $1 AND user_id = $2 RETURNING title", args.TaskID, call.UserID).Scan(&title)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return tools.Result{}, fmt.Errorf("task not found: %s", args.TaskID)
+	}
+	if err != nil {
+		return tools.Result{}, fmt.Errorf("delete task: %w", err)
+	}
+
+	return tools.Result{
+		Output:      map[string]interface{}{"deleted": true, "task_id": args.TaskID},
+		SideEffects: []tools.SideEffect{tools.RefreshTasks, tools.CalendarNeedsRefresh},
+		Changes:     []tools.Change{call.Deleted("tasks", args.TaskID, before)},
+		Summary:     "Tâche supprimée : " + title,
+	}, nil
+}
+
+func previewDeleteTask(ctx context.Context, call *tools.Call, args taskIDArgs) (tools.Preview, error) {
+	ref, err := resolveTask(ctx, call.DB, call.UserID, args.TaskID)
+	if err != nil {
+		return tools.Preview{}, fmt.Errorf("task not found: %s", args.TaskID)
+	}
+	rowID := ref.TaskID
+	if rowID == "" {
+		rowID = ref.SeriesID
+	}
+
+	var t TaskSummary
+	err = call.DB.QueryRow(ctx, `
+		SELECT id, title, COALESCE(status, 'pending'), COALESCE(time_block, ''), COALESCE(priority, 'medium'), date::text
+		FROM tasks WHERE id = $1 AND user_id = $2
+	`, rowID, call.UserID).Scan(&t.ID, &t.Title, &t.Status, &t.TimeBlock, &t.Priority, &t.Date)
+	if ref.OccurrenceDate != "" && !ref.IsSeries {
+		t.ID, t.Date = args.TaskID, ref.OccurrenceDate
+	}
+	if errors.Is(err, pgx.ErrNoRows) {
+		return tools.Preview{}, fmt.Errorf("task not found: %s", args.TaskID)
+	}
+	if err != nil {
+		return tools.Preview{}, err
+	}
+	return tools.Preview{Summary: "Supprimer la tâche « " + t.Title + " »", Details: t}, nil
+}
+
+func previewCreateTasksBatch(ctx context.Context, call *tools.Call, args createTasksBatchArgs) (tools.Preview, error) {
+	return tools.Preview{
+		Summary: fmt.Sprintf("Créer %d tâches", len(args.Tasks)),
+		Details: map[string]interface{}{"tasks": args.Tasks},
+	}, nil
+}
+
+func runCreateTasksBatch(ctx context.Context, call *tools.Call, args createTasksBatchArgs) (tools.Result, error) {
+	today := userclock.For(ctx, call.DB, call.UserID).Today()
+	created := []map[string]interface{}{}
+	var changes []tools.Change
+
+	for _, t := range args.Tasks {
+		date := orDefault(t.Date, today)
+		priority := orDefault(t.Priority, "medium")
+		timeBlock := orDefault(t.TimeBlock, "morning")
+
+		var taskID string
+		err := call.DB.QueryRow(ctx, `
+			INSERT INTO tasks (user_id, title, date, priority, time_block, estimated_minutes, scheduled_start, scheduled_end, block_apps, is_ai_generated)
+			VALUES ($1, $2, $3, $4, $5, NULLIF($6, 0), NULLIF($7, '')::time, NULLIF($8, '')::time, $9, true)
+			RETURNING id
+		`, call.UserID, t.Title, date, priority, timeBlock, t.EstimatedMinutes, t.ScheduledStart, t.ScheduledEnd, t.BlockApps).Scan(&taskID)
+		if err != nil {
+			log.Printf("create_tasks_batch: failed to create task '%s': %v", t.Title, err)
+			continue
+		}
+
+		changes = append(changes, call.Created(ctx, "tasks", taskID))
+		created = append(created, map[string]interface{}{
+			"id":         taskID,
+			"title":      t.Title,
+			"date":       date,
+			"time_block": timeBlock,
+			"priority":   priority,
+		})
+	}
+
+	return tools.Result{
+		Output:      map[string]interface{}{"created": len(created), "tasks": created},
+		SideEffects: []tools.SideEffect{tools.RefreshTasks, tools.CalendarNeedsRefresh, tools.ShowCard("planning")},
+		Changes:     changes,
+		Summary:     fmt.Sprintf("%d tâches créées", len(created)),
+	}, nil
+}
+
+func runProposeSchedule(ctx context.Context, call *tools.Call, args proposeScheduleArgs) (tools.Result, error) {
+	date := args.Date
+	if date == "" {
+		date = userclock.For(ctx, call.DB, call.UserID).Today()
+	}
+	plan, err := NewHandler(call.DB).proposeSchedule(ctx, call.UserID, date)
+	if err != nil {
+		return tools.Result{}, fmt.Errorf("propose schedule: %w", err)
+	}
+	return tools.Result{Output: plan}, nil
+}
+
+func runAcceptSchedule(ctx context.Context, call *tools.Call, args acceptScheduleArgs) (tools.Result, error) {
+	plan, changes, err := NewHandler(call.DB).acceptSchedule(ctx, call.UserID, args.ProposalID)
+	if err != nil {
+		if errors.Is(err, ErrProposalStale) {
+			return tools.Result{}, fmt.Errorf("%w, call propose_schedule again", err)
+		}
+		return tools.Result{}, err
+	}
+	return tools.Result{
+		Output:      map[string]interface{}{"accepted": true, "date": plan.Date, "scheduled": len(plan.Slots), "slots": plan.Slots},
+		SideEffects: []tools.SideEffect{tools.RefreshTasks, tools.CalendarNeedsRefresh},
+		Changes:     changes,
+		Summary:     fmt.Sprintf("%d tâches planifiées", len(plan.Slots)),
+	}, nil
+}
+
+// resolveToolTask turns a task ID from get_today_tasks into a real row,
+// materialising a virtual occurrence of a recurring task first. before is
+// the row's snapshot, nil when the row was just materialised.
+func resolveToolTask(ctx context.Context, call *tools.Call, id string) (taskID string, before json.RawMessage, materialized bool, err error) {
+	h := NewHandler(call.DB)
+	ref, err := resolveTask(ctx, h.db, call.UserID, id)
+	if err != nil {
+		return "", nil, false, fmt.Errorf("task not found: %s", id)
+	}
+	if ref.TaskID != "" {
+		before, err := tools.Snapshot(ctx, call.DB, "tasks", call.UserID, ref.TaskID)
+		return ref.TaskID, before, false, err
+	}
+	taskID, err = materializeOccurrence(ctx, h.db, call.UserID, ref.SeriesID, ref.OccurrenceDate)
+	if err != nil {
+		return "", nil, false, err
+	}
+	return taskID, nil, true, nil
+}
+
+// toolTaskChange records a write to taskID. A materialised occurrence is
+// recorded as created, so undoing it turns it back into a virtual one.
+func toolTaskChange(ctx context.Context, call *tools.Call, taskID string, before json.RawMessage, materialized bool) tools.Change {
+	if materialized {
+		return call.Created(ctx, "tasks", taskID)
+	}
+	return call.Updated(ctx, "tasks", taskID, before)
+}
+
+// skipToolOccurrence deletes a single occurrence of a series by adding it to
+// the series' exdates and dropping its override row, if any.
+func skipToolOccurrence(ctx context.Context, call *tools.Call, h *Handler, ref *taskRef) (tools.Result, error) {
+	seriesBefore, err := tools.Snapshot(ctx, call.DB, "tasks", call.UserID, ref.SeriesID)
+	if err != nil {
+		return tools.Result{}, fmt.Errorf("delete task: %w", err)
+	}
+	var overrideBefore json.RawMessage
+	if ref.TaskID != "" {
+		if overrideBefore, err = tools.Snapshot(ctx, call.DB, "tasks", call.UserID, ref.TaskID); err != nil {
+			return tools.Result{}, fmt.Errorf("delete task: %w", err)
+		}
+	}
+
+	if err := h.skipOccurrence(ctx, call.UserID, ref.SeriesID, ref.OccurrenceDate); err != nil {
+		return tools.Result{}, fmt.Errorf("delete task: %w", err)
+	}
+
+	changes := []tools.Change{call.Updated(ctx, "tasks", ref.SeriesID, seriesBefore)}
+	if ref.TaskID != "" {
+		changes = append(changes, call.Deleted("tasks", ref.TaskID, overrideBefore))
+	}
+	return tools.Result{
+		Output:      map[string]interface{}{"deleted": true, "task_id": occurrenceID(ref.SeriesID, ref.OccurrenceDate)},
+		SideEffects: []tools.SideEffect{tools.RefreshTasks, tools.CalendarNeedsRefresh},
+		Changes:     changes,
+		Summary:     "Occurrence supprimée du " + ref.OccurrenceDate,
+	}, nil
+}
+
+func orDefault(s, def string) string {
+	if s == "" {
+		return def
+	}
+	return s
+}
@@ -0,0 +1,84 @@
+package calendarevents
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// =============================================
+// CALDAV CREDENTIALS AT REST
+// =============================================
+// CalDAV passwords are sealed with AES-256-GCM before they
+// are written to calendar_providers.access_token, with the
+// key from CALENDAR_CREDENTIALS_KEY (base64, 32 bytes).
+// =============================================
+
+// sealedPrefix marks an encrypted access_token value.
+const sealedPrefix = "enc:v1:"
+
+// ErrNoCredentialKey means CalDAV credentials can't be stored or read
+// because no encryption key was configured.
+var ErrNoCredentialKey = errors.New("calendar credential key is not configured")
+
+// ParseCredentialKey decodes a base64 AES-256 key.
+func ParseCredentialKey(encoded string) ([]byte, error) {
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+	if err != nil {
+		return nil, fmt.Errorf("credential key is not valid base64: %w", err)
+	}
+	if len(key) != 32 {
+		return nil, fmt.Errorf("credential key must be 32 bytes, got %d", len(key))
+	}
+	return key, nil
+}
+
+func credentialAEAD(key []byte) (cipher.AEAD, error) {
+	if len(key) == 0 {
+		return nil, ErrNoCredentialKey
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// sealCredential encrypts a secret for storage.
+func sealCredential(key []byte, secret string) (string, error) {
+	aead, err := credentialAEAD(key)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("generate nonce: %w", err)
+	}
+	sealed := aead.Seal(nonce, nonce, []byte(secret), nil)
+	return sealedPrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// openCredential decrypts a stored secret. Values written before encryption
+// was introduced have no prefix and are returned as-is.
+func openCredential(key []byte, stored string) (string, error) {
+	if !strings.HasPrefix(stored, sealedPrefix) {
+		return stored, nil
+	}
+	aead, err := credentialAEAD(key)
+	if err != nil {
+		return "", err
+	}
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(stored, sealedPrefix))
+	if err != nil || len(raw) < aead.NonceSize() {
+		return "", errors.New("malformed sealed credential")
+	}
+	plain, err := aead.Open(nil, raw[:aead.NonceSize()], raw[aead.NonceSize():], nil)
+	if err != nil {
+		return "", fmt.Errorf("decrypt credential: %w", err)
+	}
+	return string(plain), nil
+}
@@ -0,0 +1,320 @@
+package calendarevents
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"firelevel-backend/internal/userclock"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// =============================================
+// ICS SUBSCRIPTIONS + CALDAV
+// =============================================
+// ics:    GET the feed
+// caldav: REPORT calendar-query for the sync window, basic
+//         auth with provider_email / access_token
+// Both feeds are authoritative for the window: events not
+// seen anymore are deleted.
+// =============================================
+
+const (
+	// feedPastDays / feedFutureDays bound the synced window.
+	feedPastDays   = 7
+	feedFutureDays = 90
+
+	// maxFeedSize caps a downloaded calendar.
+	maxFeedSize = 10 << 20
+)
+
+// FeedSyncer syncs ICS subscription and CalDAV providers.
+type FeedSyncer struct {
+	db            *pgxpool.Pool
+	client        *http.Client
+	credentialKey []byte // Opens sealed CalDAV passwords (see credentials.go)
+}
+
+// NewFeedSyncer creates a syncer; a nil client uses newFeedClient, which
+// only reaches public addresses.
+func NewFeedSyncer(db *pgxpool.Pool, client *http.Client) *FeedSyncer {
+	if client == nil {
+		client = newFeedClient()
+	}
+	return &FeedSyncer{db: db, client: client}
+}
+
+// feedSource is one selected calendar of an ICS/CalDAV provider.
+type feedSource struct {
+	id         string
+	calendarID string // Feed URL
+}
+
+// SyncProvider implements Syncer.
+func (f *FeedSyncer) SyncProvider(ctx context.Context, providerID string) error {
+	var userID, providerType string
+	var username, password *string
+	err := f.db.QueryRow(ctx, `
+		SELECT user_id, provider_type, provider_email, access_token
+		FROM public.calendar_providers
+		WHERE id = $1 AND provider_type IN ('ics', 'caldav') AND is_connected = true
+	`, providerID).Scan(&userID, &providerType, &username, &password)
+	if err != nil {
+		return fmt.Errorf("provider %s not found: %w", providerID, err)
+	}
+	if password != nil {
+		plain, err := openCredential(f.credentialKey, *password)
+		if err != nil {
+			return fmt.Errorf("open credentials of provider %s: %w", providerID, err)
+		}
+		password = &plain
+	}
+
+	rows, err := f.db.Query(ctx, `
+		SELECT id, external_calendar_id
+		FROM public.calendar_sources
+		WHERE provider_id = $1 AND is_selected = true
+	`, providerID)
+	if err != nil {
+		return fmt.Errorf("load calendars of provider %s: %w", providerID, err)
+	}
+	var sources []feedSource
+	for rows.Next() {
+		var s feedSource
+		if err := rows.Scan(&s.id, &s.calendarID); err != nil {
+			rows.Close()
+			return fmt.Errorf("scan calendar of provider %s: %w", providerID, err)
+		}
+		sources = append(sources, s)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("load calendars of provider %s: %w", providerID, err)
+	}
+
+	clock := userclock.For(ctx, f.db, userID)
+	from, _ := clock.TodayBounds()
+	from = from.AddDate(0, 0, -feedPastDays)
+	to := from.AddDate(0, 0, feedPastDays+feedFutureDays)
+
+	var syncErr error
+	for _, src := range sources {
+		var err error
+		if providerType == ProviderCalDAV {
+			err = f.syncCalDAV(ctx, userID, providerID, src, username, password, clock.Location(), from, to)
+		} else {
+			err = f.syncICS(ctx, userID, providerID, src, clock.Location(), from, to)
+		}
+		f.markSource(src.id, err)
+		if err != nil {
+			log.Printf("❌ %s calendar sync failed for source %s: %v", providerType, src.id, err)
+			syncErr = errors.Join(syncErr, err)
+		}
+	}
+
+	status, message := "success", (*string)(nil)
+	if syncErr != nil {
+		status = "error"
+		msg := syncErr.Error()
+		message = &msg
+	}
+	if _, err := f.db.Exec(context.Background(), `
+		UPDATE public.calendar_providers
+		SET last_sync_at = NOW(), last_sync_status = $2, last_sync_error = $3, updated_at = NOW()
+		WHERE id = $1
+	`, providerID, status, message); err != nil {
+		log.Printf("Failed to record sync status for provider %s: %v", providerID, err)
+	}
+	return syncErr
+}
+
+// syncICS downloads an ICS feed. It is parsed every time (no ETag) because
+// recurring events are expanded against a window that moves daily.
+func (f *FeedSyncer) syncICS(ctx context.Context, userID, providerID string, src feedSource, loc *time.Location, from, to time.Time) error {
+	events, err := f.fetchICS(ctx, src, loc, from, to)
+	if err != nil {
+		return err
+	}
+	return f.storeEvents(ctx, userID, providerID, src, events)
+}
+
+// fetchICS downloads and parses an ICS feed.
+func (f *FeedSyncer) fetchICS(ctx context.Context, src feedSource, loc *time.Location, from, to time.Time) ([]icsEvent, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, src.calendarID, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "text/calendar")
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("feed returned %s", resp.Status)
+	}
+	body, err := readFeedBody(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	return parseICS(string(body), loc, from, to)
+}
+
+// davMultistatus is the subset of a CalDAV REPORT response we read.
+type davMultistatus struct {
+	Responses []struct {
+		Href      string `xml:"href"`
+		Propstats []struct {
+			CalendarData string `xml:"prop>calendar-data"`
+			Status       string `xml:"status"`
+		} `xml:"propstat"`
+	} `xml:"response"`
+}
+
+const calDAVQuery = `<?xml version="1.0" encoding="utf-8"?>
+<c:calendar-query xmlns:d="DAV:" xmlns:c="urn:ietf:params:xml:ns:caldav">
+  <d:prop><c:calendar-data/></d:prop>
+  <c:filter>
+    <c:comp-filter name="VCALENDAR">
+      <c:comp-filter name="VEVENT">
+        <c:time-range start="%s" end="%s"/>
+      </c:comp-filter>
+    </c:comp-filter>
+  </c:filter>
+</c:calendar-query>`
+
+// syncCalDAV queries a CalDAV collection for the window.
+func (f *FeedSyncer) syncCalDAV(ctx context.Context, userID, providerID string, src feedSource, username, password *string, loc *time.Location, from, to time.Time) error {
+	events, err := f.fetchCalDAV(ctx, src, username, password, loc, from, to)
+	if err != nil {
+		return err
+	}
+	return f.storeEvents(ctx, userID, providerID, src, events)
+}
+
+// fetchCalDAV runs the calendar-query REPORT and parses the returned
+// objects. An object that fails to parse is logged and skipped.
+func (f *FeedSyncer) fetchCalDAV(ctx context.Context, src feedSource, username, password *string, loc *time.Location, from, to time.Time) ([]icsEvent, error) {
+	body := fmt.Sprintf(calDAVQuery, from.UTC().Format("20060102T150405Z"), to.UTC().Format("20060102T150405Z"))
+	req, err := http.NewRequestWithContext(ctx, "REPORT", src.calendarID, strings.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/xml; charset=utf-8")
+	req.Header.Set("Depth", "1")
+	if username != nil {
+		pass := ""
+		if password != nil {
+			pass = *password
+		}
+		req.SetBasicAuth(*username, pass)
+	}
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusMultiStatus && resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("CalDAV server returned %s", resp.Status)
+	}
+
+	raw, err := readFeedBody(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	var ms davMultistatus
+	if err := xml.Unmarshal(raw, &ms); err != nil {
+		return nil, fmt.Errorf("invalid CalDAV response: %w", err)
+	}
+
+	var events []icsEvent
+	for _, r := range ms.Responses {
+		for _, ps := range r.Propstats {
+			if ps.CalendarData == "" || (ps.Status != "" && !strings.Contains(ps.Status, " 200 ")) {
+				continue
+			}
+			parsed, err := parseICS(ps.CalendarData, loc, from, to)
+			if err != nil {
+				log.Printf("⚠️ Skipping CalDAV object %s of source %s: %v", r.Href, src.id, err)
+				continue
+			}
+			events = append(events, parsed...)
+		}
+	}
+	return events, nil
+}
+
+// storeEvents upserts the instances of one source and deletes the ones that
+// disappeared from the feed.
+func (f *FeedSyncer) storeEvents(ctx context.Context, userID, providerID string, src feedSource, events []icsEvent) error {
+	var syncStart time.Time
+	if err := f.db.QueryRow(ctx, `SELECT NOW()`).Scan(&syncStart); err != nil {
+		return err
+	}
+
+	for _, ev := range events {
+		if ev.Status == "cancelled" {
+			continue
+		}
+		_, err := f.db.Exec(ctx, `
+			INSERT INTO public.calendar_events (
+				user_id, provider_id, source_id, external_event_id, external_calendar_id,
+				title, description, location, start_at, end_at,
+				is_all_day, timezone, event_status, is_busy, event_type,
+				synced_at, created_at, updated_at
+			)
+			VALUES ($1, $2, $3, $4, $5, $6, NULLIF($7, ''), NULLIF($8, ''), $9, $10, $11, NULLIF($12, ''), $13, $14, 'default', NOW(), NOW(), NOW())
+			ON CONFLICT (source_id, external_event_id)
+			DO UPDATE SET
+				title = EXCLUDED.title,
+				description = EXCLUDED.description,
+				location = EXCLUDED.location,
+				start_at = EXCLUDED.start_at,
+				end_at = EXCLUDED.end_at,
+				is_all_day = EXCLUDED.is_all_day,
+				timezone = EXCLUDED.timezone,
+				event_status = EXCLUDED.event_status,
+				is_busy = EXCLUDED.is_busy,
+				synced_at = NOW(),
+				updated_at = NOW()
+		`,
+			userID, providerID, src.id, ev.UID, src.calendarID,
+			ev.Summary, ev.Description, ev.Location, ev.Start, ev.End,
+			ev.IsAllDay, ev.Timezone, ev.Status, ev.IsBusy,
+		)
+		if err != nil {
+			return fmt.Errorf("store event %s: %w", ev.UID, err)
+		}
+	}
+
+	_, err := f.db.Exec(ctx, `
+		DELETE FROM public.calendar_events WHERE source_id = $1 AND synced_at < $2
+	`, src.id, syncStart)
+	return err
+}
+
+func (f *FeedSyncer) markSource(sourceID string, syncErr error) {
+	status, message := "success", (*string)(nil)
+	if syncErr != nil {
+		status = "error"
+		msg := syncErr.Error()
+		message = &msg
+	}
+	if _, err := f.db.Exec(context.Background(), `
+		UPDATE public.calendar_sources
+		SET last_sync_at = NOW(), last_sync_status = $2, last_sync_error = $3, updated_at = NOW()
+		WHERE id = $1
+	`, sourceID, status, message); err != nil {
+		log.Printf("Failed to record sync status for calendar %s: %v", sourceID, err)
+	}
+}
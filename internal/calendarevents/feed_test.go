@@ -0,0 +1,104 @@
+package calendarevents
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+const fixtureICS = "BEGIN:VCALENDAR\r\nVERSION:2.0\r\n" +
+	"BEGIN:VEVENT\r\nUID:standup\r\nDTSTART:20261012T080000Z\r\nDTEND:20261012T081500Z\r\nRRULE:FREQ=DAILY;COUNT=2\r\nSUMMARY:Standup\r\nEND:VEVENT\r\n" +
+	"END:VCALENDAR\r\n"
+
+func feedWindow() (time.Time, time.Time) {
+	from := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	return from, from.AddDate(0, 1, 0)
+}
+
+func TestFetchICS(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/cal.ics":
+			if r.Header.Get("Accept") != "text/calendar" {
+				t.Errorf("Accept = %q", r.Header.Get("Accept"))
+			}
+			w.Write([]byte(fixtureICS))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	f := NewFeedSyncer(nil, srv.Client())
+	from, to := feedWindow()
+
+	events, err := f.fetchICS(context.Background(), feedSource{id: "s1", calendarID: srv.URL + "/cal.ics"}, time.UTC, from, to)
+	if err != nil {
+		t.Fatalf("fetchICS: %v", err)
+	}
+	if len(events) != 2 || events[0].Summary != "Standup" {
+		t.Fatalf("events = %v", summarize(events))
+	}
+
+	if _, err := f.fetchICS(context.Background(), feedSource{id: "s2", calendarID: srv.URL + "/missing.ics"}, time.UTC, from, to); err == nil || !strings.Contains(err.Error(), "404") {
+		t.Errorf("missing feed error = %v, want a 404", err)
+	}
+}
+
+func TestFetchCalDAV(t *testing.T) {
+	var gotBody string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "REPORT" || r.Header.Get("Depth") != "1" {
+			t.Errorf("request = %s Depth=%q", r.Method, r.Header.Get("Depth"))
+		}
+		if user, pass, ok := r.BasicAuth(); !ok || user != "alice" || pass != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		gotBody = string(body)
+
+		w.WriteHeader(http.StatusMultiStatus)
+		w.Write([]byte(`<?xml version="1.0"?>
+<d:multistatus xmlns:d="DAV:" xmlns:c="urn:ietf:params:xml:ns:caldav">
+  <d:response>
+    <d:href>/cal/standup.ics</d:href>
+    <d:propstat><d:prop><c:calendar-data>` + fixtureICS + `</c:calendar-data></d:prop><d:status>HTTP/1.1 200 OK</d:status></d:propstat>
+  </d:response>
+  <d:response>
+    <d:href>/cal/broken.ics</d:href>
+    <d:propstat><d:prop><c:calendar-data>garbage</c:calendar-data></d:prop><d:status>HTTP/1.1 200 OK</d:status></d:propstat>
+  </d:response>
+  <d:response>
+    <d:href>/cal/forbidden.ics</d:href>
+    <d:propstat><d:prop><c:calendar-data>` + fixtureICS + `</c:calendar-data></d:prop><d:status>HTTP/1.1 403 Forbidden</d:status></d:propstat>
+  </d:response>
+</d:multistatus>`))
+	}))
+	defer srv.Close()
+
+	f := NewFeedSyncer(nil, srv.Client())
+	from, to := feedWindow()
+	user, pass := "alice", "secret"
+	src := feedSource{id: "s1", calendarID: srv.URL + "/cal/"}
+
+	events, err := f.fetchCalDAV(context.Background(), src, &user, &pass, time.UTC, from, to)
+	if err != nil {
+		t.Fatalf("fetchCalDAV: %v", err)
+	}
+	if len(events) != 2 {
+		t.Fatalf("events = %v, want the 2 standup instances only", summarize(events))
+	}
+	if !strings.Contains(gotBody, `start="20261001T000000Z"`) || !strings.Contains(gotBody, `end="20261101T000000Z"`) {
+		t.Errorf("time-range not in query: %s", gotBody)
+	}
+
+	wrong := "nope"
+	if _, err := f.fetchCalDAV(context.Background(), src, &user, &wrong, time.UTC, from, to); err == nil {
+		t.Error("fetchCalDAV should fail on 401")
+	}
+}
@@ -1,12 +1,14 @@
 package calendarevents
 
 import (
+	"context"
 	"encoding/json"
 	"log"
 	"net/http"
 	"time"
 
 	"firelevel-backend/internal/auth"
+	"firelevel-backend/internal/userclock"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
@@ -19,7 +21,7 @@ import (
 // cached calendar events and manage blocking:
 //   GET   /calendar/events?date=YYYY-MM-DD
 //   POST  /calendar/sync-events
-//   GET   /calendar/providers
+//   GET   /calendar/providers (with their calendars)
 //   GET   /calendar/blocking-schedule?date=YYYY-MM-DD
 //   PATCH /calendar/events/{id}/blocking
 // =============================================
@@ -39,6 +41,7 @@ type CalendarEventResponse struct {
 	IsBusy       bool    `json:"is_busy"`
 	ProviderType string  `json:"provider_type"`
 	ProviderEmail *string `json:"provider_email,omitempty"`
+	CalendarName *string `json:"calendar_name,omitempty"`
 }
 
 // ProviderResponse for /calendar/providers
@@ -51,6 +54,9 @@ type ProviderResponse struct {
 	SyncDirection  string  `json:"sync_direction"`
 	LastSyncAt     *string `json:"last_sync_at,omitempty"`
 	LastSyncStatus string  `json:"last_sync_status"`
+	Name           *string `json:"name,omitempty"` // ICS/CalDAV display name
+	URL            *string `json:"url,omitempty"`  // ICS/CalDAV feed
+	Calendars      []SourceResponse `json:"calendars"`
 }
 
 // BlockingWindow for /calendar/blocking-schedule
@@ -71,12 +77,26 @@ type UpdateBlockingRequest struct {
 
 // Handler holds dependencies
 type Handler struct {
-	db *pgxpool.Pool
+	db            *pgxpool.Pool
+	syncers       map[string]Syncer
+	feeds         *FeedSyncer
+	credentialKey []byte
 }
 
 // NewHandler creates a new calendar events handler
 func NewHandler(db *pgxpool.Pool) *Handler {
-	return &Handler{db: db}
+	feeds := NewFeedSyncer(db, nil)
+	return &Handler{db: db, feeds: feeds, syncers: map[string]Syncer{
+		ProviderICS:    feeds,
+		ProviderCalDAV: feeds,
+	}}
+}
+
+// SetCredentialKey sets the AES-256 key that seals CalDAV passwords.
+// Without it, CalDAV providers with a password can't be added.
+func (h *Handler) SetCredentialKey(key []byte) {
+	h.credentialKey = key
+	h.feeds.credentialKey = key
 }
 
 // ListEvents returns cached calendar events for a date
@@ -85,20 +105,18 @@ func (h *Handler) ListEvents(w http.ResponseWriter, r *http.Request) {
 	userID := r.Context().Value(auth.UserContextKey).(string)
 	dateStr := r.URL.Query().Get("date")
 
+	clock := userclock.For(r.Context(), h.db, userID)
 	// Default to today
 	if dateStr == "" {
-		dateStr = time.Now().Format("2006-01-02")
+		dateStr = clock.Today()
 	}
 
-	date, err := time.Parse("2006-01-02", dateStr)
+	startOfDay, endOfDay, err := clock.DayBounds(dateStr)
 	if err != nil {
 		http.Error(w, "Invalid date format (use YYYY-MM-DD)", http.StatusBadRequest)
 		return
 	}
 
-	startOfDay := date
-	endOfDay := date.AddDate(0, 0, 1)
-
 	// Check if cache is stale (> 15 min since last sync)
 	var lastSyncAt *time.Time
 	h.db.QueryRow(r.Context(), `
@@ -113,9 +131,10 @@ func (h *Handler) ListEvents(w http.ResponseWriter, r *http.Request) {
 		SELECT ce.id, ce.title, ce.description, ce.location,
 			   ce.start_at, ce.end_at, ce.is_all_day,
 			   ce.event_type, ce.event_status, ce.block_apps, ce.is_busy,
-			   cp.provider_type, cp.provider_email
+			   cp.provider_type, cp.provider_email, cs.name
 		FROM public.calendar_events ce
 		JOIN public.calendar_providers cp ON cp.id = ce.provider_id
+		LEFT JOIN public.calendar_sources cs ON cs.id = ce.source_id
 		WHERE ce.user_id = $1
 		  AND ce.event_status != 'cancelled'
 		  AND (
@@ -141,7 +160,7 @@ func (h *Handler) ListEvents(w http.ResponseWriter, r *http.Request) {
 			&ev.ID, &ev.Title, &ev.Description, &ev.Location,
 			&startAt, &endAt, &ev.IsAllDay,
 			&ev.EventType, &ev.EventStatus, &ev.BlockApps, &ev.IsBusy,
-			&ev.ProviderType, &ev.ProviderEmail,
+			&ev.ProviderType, &ev.ProviderEmail, &ev.CalendarName,
 		)
 		if err != nil {
 			continue
@@ -160,24 +179,38 @@ func (h *Handler) ListEvents(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
-// ListProviders returns connected calendar providers
+// ListProviders returns connected calendar providers and their calendars
 // GET /calendar/providers
 func (h *Handler) ListProviders(w http.ResponseWriter, r *http.Request) {
 	userID := r.Context().Value(auth.UserContextKey).(string)
 
+	providers, err := h.listProviders(r.Context(), userID)
+	if err != nil {
+		http.Error(w, "Failed to fetch providers", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"providers": providers,
+		"count":     len(providers),
+	})
+}
+
+func (h *Handler) listProviders(ctx context.Context, userID string) ([]ProviderResponse, error) {
 	query := `
 		SELECT id, provider_type, provider_email, is_connected,
 			   is_active, COALESCE(sync_direction, 'from_provider'),
-			   last_sync_at, COALESCE(last_sync_status, 'never')
+			   last_sync_at, COALESCE(last_sync_status, 'never'),
+			   provider_config->>'name', provider_config->>'url'
 		FROM public.calendar_providers
 		WHERE user_id = $1
 		ORDER BY created_at ASC
 	`
 
-	rows, err := h.db.Query(r.Context(), query, userID)
+	rows, err := h.db.Query(ctx, query, userID)
 	if err != nil {
-		http.Error(w, "Failed to fetch providers", http.StatusInternalServerError)
-		return
+		return nil, err
 	}
 	defer rows.Close()
 
@@ -188,6 +221,7 @@ func (h *Handler) ListProviders(w http.ResponseWriter, r *http.Request) {
 		err := rows.Scan(
 			&p.ID, &p.ProviderType, &p.ProviderEmail, &p.IsConnected,
 			&p.IsActive, &p.SyncDirection, &lastSyncAt, &p.LastSyncStatus,
+			&p.Name, &p.URL,
 		)
 		if err != nil {
 			continue
@@ -198,12 +232,19 @@ func (h *Handler) ListProviders(w http.ResponseWriter, r *http.Request) {
 		}
 		providers = append(providers, p)
 	}
+	rows.Close()
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]interface{}{
-		"providers": providers,
-		"count":     len(providers),
-	})
+	sources, err := h.loadSources(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range providers {
+		providers[i].Calendars = sources[providers[i].ID]
+		if providers[i].Calendars == nil {
+			providers[i].Calendars = []SourceResponse{}
+		}
+	}
+	return providers, nil
 }
 
 // GetBlockingSchedule returns merged blocking windows (calendar events + tasks with blockApps)
@@ -212,19 +253,17 @@ func (h *Handler) GetBlockingSchedule(w http.ResponseWriter, r *http.Request) {
 	userID := r.Context().Value(auth.UserContextKey).(string)
 	dateStr := r.URL.Query().Get("date")
 
+	clock := userclock.For(r.Context(), h.db, userID)
 	if dateStr == "" {
-		dateStr = time.Now().Format("2006-01-02")
+		dateStr = clock.Today()
 	}
 
-	date, err := time.Parse("2006-01-02", dateStr)
+	startOfDay, endOfDay, err := clock.DayBounds(dateStr)
 	if err != nil {
 		http.Error(w, "Invalid date format", http.StatusBadRequest)
 		return
 	}
 
-	startOfDay := date
-	endOfDay := date.AddDate(0, 0, 1)
-
 	windows := []BlockingWindow{}
 
 	// 1. Calendar events with block_apps = true
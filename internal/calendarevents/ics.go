@@ -0,0 +1,326 @@
+package calendarevents
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"firelevel-backend/internal/calendar"
+)
+
+// =============================================
+// ICS (RFC 5545) PARSING
+// =============================================
+// Enough of iCalendar for subscription feeds and CalDAV:
+// VEVENT with DTSTART/DTEND/DURATION (UTC, TZID, floating
+// or all-day), SUMMARY, DESCRIPTION, LOCATION, STATUS,
+// TRANSP, RRULE (the subset calendar.ParseRRule handles),
+// EXDATE and RECURRENCE-ID overrides.
+// =============================================
+
+// icsEvent is one concrete event instance.
+type icsEvent struct {
+	UID         string // Unique per instance (UID, plus the start for recurring instances)
+	Summary     string
+	Description string
+	Location    string
+	Start       time.Time
+	End         time.Time
+	IsAllDay    bool
+	Timezone    string
+	Status      string // confirmed, tentative, cancelled
+	IsBusy      bool
+}
+
+// icsComponent is a raw VEVENT: property name -> occurrences.
+type icsComponent map[string][]icsProperty
+
+type icsProperty struct {
+	Params map[string]string
+	Value  string
+}
+
+func (c icsComponent) get(name string) (icsProperty, bool) {
+	props := c[name]
+	if len(props) == 0 {
+		return icsProperty{}, false
+	}
+	return props[0], true
+}
+
+func (c icsComponent) text(name string) string {
+	p, _ := c.get(name)
+	return unescapeICSText(p.Value)
+}
+
+// parseICS returns the event instances of an iCalendar document that overlap
+// [from, to). Floating times are read in loc.
+func parseICS(data string, loc *time.Location, from, to time.Time) ([]icsEvent, error) {
+	components, err := readVEvents(data)
+	if err != nil {
+		return nil, err
+	}
+
+	// RECURRENCE-ID overrides replace one generated instance
+	overridden := map[string]bool{}
+	for _, c := range components {
+		if rid, ok := c.get("RECURRENCE-ID"); ok {
+			if t, _, err := parseICSTime(rid, loc); err == nil {
+				overridden[instanceID(c.text("UID"), t)] = true
+			}
+		}
+	}
+
+	var events []icsEvent
+	for _, c := range components {
+		base, err := baseEvent(c, loc)
+		if err != nil {
+			continue
+		}
+		duration := base.End.Sub(base.Start)
+
+		if rid, ok := c.get("RECURRENCE-ID"); ok {
+			t, _, err := parseICSTime(rid, loc)
+			if err != nil {
+				continue
+			}
+			base.UID = instanceID(base.UID, t)
+			if overlaps(base, from, to) {
+				events = append(events, base)
+			}
+			continue
+		}
+
+		ruleProp, recurring := c.get("RRULE")
+		if !recurring {
+			if overlaps(base, from, to) {
+				events = append(events, base)
+			}
+			continue
+		}
+
+		rule, err := calendar.ParseRRule(ruleProp.Value)
+		if err != nil {
+			// Unsupported rule: keep the first instance only
+			if overlaps(base, from, to) {
+				events = append(events, base)
+			}
+			continue
+		}
+
+		exdates := map[string]bool{}
+		for _, ex := range c["EXDATE"] {
+			for _, v := range strings.Split(ex.Value, ",") {
+				if t, _, err := parseICSTime(icsProperty{Params: ex.Params, Value: v}, loc); err == nil {
+					exdates[instanceID("", t)] = true
+				}
+			}
+		}
+
+		startLoc := base.Start.Location()
+		dtstart := dateOnly(base.Start)
+		for _, day := range rule.Occurrences(dtstart, dateOnly(from.In(startLoc)).AddDate(0, 0, -1), dateOnly(to.In(startLoc))) {
+			start := time.Date(day.Year(), day.Month(), day.Day(),
+				base.Start.Hour(), base.Start.Minute(), base.Start.Second(), 0, startLoc)
+			if exdates[instanceID("", start)] || overridden[instanceID(base.UID, start)] {
+				continue
+			}
+			instance := base
+			instance.UID = instanceID(base.UID, start)
+			instance.Start = start
+			instance.End = start.Add(duration)
+			if overlaps(instance, from, to) {
+				events = append(events, instance)
+			}
+		}
+	}
+	return events, nil
+}
+
+// readVEvents unfolds the document and splits it into VEVENT components.
+func readVEvents(data string) ([]icsComponent, error) {
+	data = strings.ReplaceAll(data, "\r\n", "\n")
+	data = strings.ReplaceAll(data, "\n ", "")
+	data = strings.ReplaceAll(data, "\n\t", "")
+
+	if !strings.Contains(data, "BEGIN:VCALENDAR") {
+		return nil, fmt.Errorf("not an iCalendar document")
+	}
+
+	var components []icsComponent
+	var current icsComponent
+	depth := 0 // Nested components inside a VEVENT (VALARM)
+	for _, line := range strings.Split(data, "\n") {
+		line = strings.TrimRight(line, "\r")
+		switch {
+		case line == "BEGIN:VEVENT":
+			current = icsComponent{}
+			depth = 0
+			continue
+		case line == "END:VEVENT":
+			if current != nil {
+				components = append(components, current)
+			}
+			current = nil
+			continue
+		case current == nil:
+			continue
+		case strings.HasPrefix(line, "BEGIN:"):
+			depth++
+			continue
+		case strings.HasPrefix(line, "END:"):
+			depth--
+			continue
+		case depth > 0:
+			continue
+		}
+
+		name, prop, ok := parseICSLine(line)
+		if ok {
+			current[name] = append(current[name], prop)
+		}
+	}
+	return components, nil
+}
+
+// parseICSLine splits "NAME;PARAM=x:value".
+func parseICSLine(line string) (string, icsProperty, bool) {
+	colon := -1
+	inQuotes := false
+	for i, r := range line {
+		if r == '"' {
+			inQuotes = !inQuotes
+		} else if r == ':' && !inQuotes {
+			colon = i
+			break
+		}
+	}
+	if colon <= 0 {
+		return "", icsProperty{}, false
+	}
+
+	head := strings.Split(line[:colon], ";")
+	prop := icsProperty{Params: map[string]string{}, Value: line[colon+1:]}
+	for _, param := range head[1:] {
+		if k, v, ok := strings.Cut(param, "="); ok {
+			prop.Params[strings.ToUpper(k)] = strings.Trim(v, `"`)
+		}
+	}
+	return strings.ToUpper(head[0]), prop, true
+}
+
+// baseEvent reads the fields shared by every instance of a VEVENT.
+func baseEvent(c icsComponent, loc *time.Location) (icsEvent, error) {
+	uid := c.text("UID")
+	startProp, ok := c.get("DTSTART")
+	if uid == "" || !ok {
+		return icsEvent{}, fmt.Errorf("VEVENT without UID or DTSTART")
+	}
+	start, allDay, err := parseICSTime(startProp, loc)
+	if err != nil {
+		return icsEvent{}, err
+	}
+
+	var end time.Time
+	if endProp, ok := c.get("DTEND"); ok {
+		if end, _, err = parseICSTime(endProp, loc); err != nil {
+			return icsEvent{}, err
+		}
+	} else if durProp, ok := c.get("DURATION"); ok {
+		d, err := parseICSDuration(durProp.Value)
+		if err != nil {
+			return icsEvent{}, err
+		}
+		end = start.Add(d)
+	} else if allDay {
+		end = start.AddDate(0, 0, 1)
+	} else {
+		end = start
+	}
+
+	status := "confirmed"
+	switch strings.ToUpper(c.text("STATUS")) {
+	case "TENTATIVE":
+		status = "tentative"
+	case "CANCELLED":
+		status = "cancelled"
+	}
+
+	return icsEvent{
+		UID:         uid,
+		Summary:     c.text("SUMMARY"),
+		Description: c.text("DESCRIPTION"),
+		Location:    c.text("LOCATION"),
+		Start:       start,
+		End:         end,
+		IsAllDay:    allDay,
+		Timezone:    startProp.Params["TZID"],
+		Status:      status,
+		IsBusy:      strings.ToUpper(c.text("TRANSP")) != "TRANSPARENT",
+	}, nil
+}
+
+// parseICSTime parses DATE and DATE-TIME values (UTC, TZID or floating).
+func parseICSTime(p icsProperty, loc *time.Location) (time.Time, bool, error) {
+	v := strings.TrimSpace(p.Value)
+	if p.Params["VALUE"] == "DATE" || len(v) == 8 {
+		t, err := time.ParseInLocation("20060102", v, loc)
+		return t, true, err
+	}
+	if strings.HasSuffix(v, "Z") {
+		t, err := time.Parse("20060102T150405Z", v)
+		return t, false, err
+	}
+	if tzid := p.Params["TZID"]; tzid != "" {
+		if tz, err := time.LoadLocation(tzid); err == nil {
+			loc = tz
+		}
+	}
+	t, err := time.ParseInLocation("20060102T150405", v, loc)
+	return t, false, err
+}
+
+var icsDurationRe = regexp.MustCompile(`^([+-])?P(?:(\d+)W)?(?:(\d+)D)?(?:T(?:(\d+)H)?(?:(\d+)M)?(?:(\d+)S)?)?$`)
+
+// parseICSDuration parses RFC 5545 durations such as PT1H30M or P1D.
+func parseICSDuration(v string) (time.Duration, error) {
+	m := icsDurationRe.FindStringSubmatch(strings.TrimSpace(v))
+	if m == nil {
+		return 0, fmt.Errorf("invalid DURATION %q", v)
+	}
+	units := []time.Duration{7 * 24 * time.Hour, 24 * time.Hour, time.Hour, time.Minute, time.Second}
+	var d time.Duration
+	for i, unit := range units {
+		if m[i+2] != "" {
+			n, _ := strconv.Atoi(m[i+2])
+			d += time.Duration(n) * unit
+		}
+	}
+	if m[1] == "-" {
+		d = -d
+	}
+	return d, nil
+}
+
+func unescapeICSText(s string) string {
+	r := strings.NewReplacer(`\n`, "\n", `\N`, "\n", `\,`, ",", `\;`, ";", `\\`, `\`)
+	return r.Replace(s)
+}
+
+func instanceID(uid string, start time.Time) string {
+	return uid + "_" + start.UTC().Format("20060102T150405Z")
+}
+
+func dateOnly(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+func overlaps(e icsEvent, from, to time.Time) bool {
+	end := e.End
+	if !end.After(e.Start) {
+		end = e.Start.Add(time.Second)
+	}
+	return e.Start.Before(to) && end.After(from)
+}
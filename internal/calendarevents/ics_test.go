@@ -0,0 +1,170 @@
+package calendarevents
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// vcalendar wraps VEVENT lines in a CRLF iCalendar document.
+func vcalendar(lines ...string) string {
+	all := append([]string{"BEGIN:VCALENDAR", "VERSION:2.0"}, lines...)
+	all = append(all, "END:VCALENDAR")
+	return strings.Join(all, "\r\n") + "\r\n"
+}
+
+// summarize renders events as "UID start→end" in UTC.
+func summarize(events []icsEvent) []string {
+	out := make([]string, len(events))
+	for i, e := range events {
+		out[i] = e.UID + " " + e.Start.UTC().Format("2006-01-02T15:04") + "→" + e.End.UTC().Format("2006-01-02T15:04")
+	}
+	return out
+}
+
+func TestParseICS(t *testing.T) {
+	paris, err := time.LoadLocation("Europe/Paris")
+	if err != nil {
+		t.Skip("tzdata unavailable")
+	}
+	from := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name  string
+		lines []string
+		want  []string
+	}{
+		{
+			name: "UTC event",
+			lines: []string{
+				"BEGIN:VEVENT", "UID:a", "DTSTART:20261016T090000Z", "DTEND:20261016T100000Z", "SUMMARY:Call", "END:VEVENT",
+			},
+			want: []string{"a 2026-10-16T09:00→2026-10-16T10:00"},
+		},
+		{
+			name: "TZID",
+			lines: []string{
+				"BEGIN:VEVENT", "UID:b", "DTSTART;TZID=America/New_York:20261016T090000",
+				"DTEND;TZID=America/New_York:20261016T093000", "END:VEVENT",
+			},
+			want: []string{"b 2026-10-16T13:00→2026-10-16T13:30"},
+		},
+		{
+			name: "floating time in the user's timezone, with DURATION",
+			lines: []string{
+				"BEGIN:VEVENT", "UID:c", "DTSTART:20261016T090000", "DURATION:PT1H30M", "END:VEVENT",
+			},
+			want: []string{"c 2026-10-16T07:00→2026-10-16T08:30"},
+		},
+		{
+			name: "all-day event",
+			lines: []string{
+				"BEGIN:VEVENT", "UID:d", "DTSTART;VALUE=DATE:20261016", "END:VEVENT",
+			},
+			want: []string{"d 2026-10-15T22:00→2026-10-16T22:00"},
+		},
+		{
+			name: "RRULE with COUNT",
+			lines: []string{
+				"BEGIN:VEVENT", "UID:e", "DTSTART:20261012T080000Z", "DTEND:20261012T083000Z",
+				"RRULE:FREQ=DAILY;COUNT=3", "END:VEVENT",
+			},
+			want: []string{
+				"e_20261012T080000Z 2026-10-12T08:00→2026-10-12T08:30",
+				"e_20261013T080000Z 2026-10-13T08:00→2026-10-13T08:30",
+				"e_20261014T080000Z 2026-10-14T08:00→2026-10-14T08:30",
+			},
+		},
+		{
+			name: "EXDATE skips an instance",
+			lines: []string{
+				"BEGIN:VEVENT", "UID:f", "DTSTART:20261012T080000Z", "DTEND:20261012T090000Z",
+				"RRULE:FREQ=DAILY;COUNT=3", "EXDATE:20261013T080000Z", "END:VEVENT",
+			},
+			want: []string{
+				"f_20261012T080000Z 2026-10-12T08:00→2026-10-12T09:00",
+				"f_20261014T080000Z 2026-10-14T08:00→2026-10-14T09:00",
+			},
+		},
+		{
+			name: "RECURRENCE-ID moves an instance",
+			lines: []string{
+				"BEGIN:VEVENT", "UID:g", "DTSTART:20261012T080000Z", "DTEND:20261012T090000Z",
+				"RRULE:FREQ=WEEKLY;COUNT=2", "END:VEVENT",
+				"BEGIN:VEVENT", "UID:g", "RECURRENCE-ID:20261019T080000Z",
+				"DTSTART:20261019T140000Z", "DTEND:20261019T150000Z", "END:VEVENT",
+			},
+			want: []string{
+				"g_20261012T080000Z 2026-10-12T08:00→2026-10-12T09:00",
+				"g_20261019T080000Z 2026-10-19T14:00→2026-10-19T15:00",
+			},
+		},
+		{
+			name: "recurring with TZID keeps the local time across DST",
+			lines: []string{
+				"BEGIN:VEVENT", "UID:h", "DTSTART;TZID=Europe/Paris:20261024T090000",
+				"DTEND;TZID=Europe/Paris:20261024T100000", "RRULE:FREQ=DAILY;COUNT=2", "END:VEVENT",
+			},
+			want: []string{
+				"h_20261024T070000Z 2026-10-24T07:00→2026-10-24T08:00",
+				"h_20261025T080000Z 2026-10-25T08:00→2026-10-25T09:00",
+			},
+		},
+		{
+			name: "outside the window",
+			lines: []string{
+				"BEGIN:VEVENT", "UID:i", "DTSTART:20261216T090000Z", "DTEND:20261216T100000Z", "END:VEVENT",
+			},
+			want: []string{},
+		},
+		{
+			name: "VEVENT without DTSTART is skipped",
+			lines: []string{
+				"BEGIN:VEVENT", "UID:j", "SUMMARY:Broken", "END:VEVENT",
+			},
+			want: []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		events, err := parseICS(vcalendar(tt.lines...), paris, from, to)
+		if err != nil {
+			t.Fatalf("%s: parseICS: %v", tt.name, err)
+		}
+		got := summarize(events)
+		if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+			t.Errorf("%s:\ngot  %v\nwant %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestParseICSFields(t *testing.T) {
+	from := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, 1, 0)
+	doc := vcalendar(
+		"BEGIN:VEVENT", "UID:x", "DTSTART;TZID=Europe/Paris:20261016T090000", "DTEND;TZID=Europe/Paris:20261016T100000",
+		`SUMMARY:Déjeuner\, équipe`, `DESCRIPTION:Ligne 1\nLigne 2`, "LOCATION:Paris",
+		"STATUS:TENTATIVE", "TRANSP:TRANSPARENT",
+		"BEGIN:VALARM", "TRIGGER:-PT15M", "SUMMARY:Alarm", "END:VALARM",
+		"END:VEVENT",
+	)
+	events, err := parseICS(doc, time.UTC, from, to)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 1 {
+		t.Fatalf("got %d events", len(events))
+	}
+	e := events[0]
+	if e.Summary != "Déjeuner, équipe" || e.Description != "Ligne 1\nLigne 2" || e.Location != "Paris" {
+		t.Errorf("text fields = %q, %q, %q", e.Summary, e.Description, e.Location)
+	}
+	if e.Status != "tentative" || e.IsBusy || e.IsAllDay || e.Timezone != "Europe/Paris" {
+		t.Errorf("status=%s busy=%v allDay=%v tz=%s", e.Status, e.IsBusy, e.IsAllDay, e.Timezone)
+	}
+
+	if _, err := parseICS("not a calendar", time.UTC, from, to); err == nil {
+		t.Error("parseICS should reject a document without VCALENDAR")
+	}
+}
@@ -0,0 +1,489 @@
+package calendarevents
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"firelevel-backend/internal/auth"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// =============================================
+// CALENDAR PROVIDERS — one abstraction per source type
+// =============================================
+// A calendar_providers row is an account (Google, an ICS
+// subscription, a CalDAV collection). Its calendar_sources
+// rows are the calendars inside it; only selected sources
+// are synced into calendar_events.
+//
+// Each provider_type has a Syncer. Google registers
+// gcalendar.Handler from main; ICS and CalDAV are built in.
+//   POST   /calendar/providers           (ics, caldav)
+//   DELETE /calendar/providers/{id}      (ics, caldav)
+//   PATCH  /calendar/sources/{id}        select / unselect a calendar
+//   POST   /calendar/sync-events         sync every provider now
+// =============================================
+
+// Provider types stored in calendar_providers.provider_type
+const (
+	ProviderGoogle = "google"
+	ProviderICS    = "ics"
+	ProviderCalDAV = "caldav"
+)
+
+const (
+	// workerInterval is how often SyncWorker looks for queued syncs.
+	workerInterval = 15 * time.Second
+
+	// pollInterval is how often providers without push notifications
+	// (ICS, CalDAV) are re-synced.
+	pollInterval = 30 * time.Minute
+
+	// syncBatchSize bounds how many providers one worker tick syncs.
+	syncBatchSize = 20
+
+	// syncTimeout bounds a single provider sync.
+	syncTimeout = 2 * time.Minute
+)
+
+// Syncer pulls the selected calendars of one calendar_providers row into
+// calendar_events.
+type Syncer interface {
+	SyncProvider(ctx context.Context, providerID string) error
+}
+
+// RegisterSyncer sets the Syncer used for a provider type
+func (h *Handler) RegisterSyncer(providerType string, syncer Syncer) {
+	h.syncers[providerType] = syncer
+}
+
+// SourceResponse is one calendar inside a provider
+type SourceResponse struct {
+	ID                 string  `json:"id"`
+	ExternalCalendarID string  `json:"external_calendar_id"`
+	Name               *string `json:"name,omitempty"`
+	Color              *string `json:"color,omitempty"`
+	IsPrimary          bool    `json:"is_primary"`
+	IsSelected         bool    `json:"is_selected"`
+	LastSyncAt         *string `json:"last_sync_at,omitempty"`
+	LastSyncStatus     string  `json:"last_sync_status"`
+	LastSyncError      *string `json:"last_sync_error,omitempty"`
+}
+
+// CreateProviderRequest for POST /calendar/providers
+type CreateProviderRequest struct {
+	ProviderType string `json:"provider_type"` // ics, caldav
+	URL          string `json:"url"`           // ICS feed (http, https, webcal) or CalDAV collection
+	Name         string `json:"name,omitempty"`
+	Username     string `json:"username,omitempty"` // CalDAV only
+	Password     string `json:"password,omitempty"` // CalDAV only
+}
+
+// UpdateSourceRequest for PATCH /calendar/sources/{id}
+type UpdateSourceRequest struct {
+	IsSelected *bool `json:"is_selected,omitempty"`
+}
+
+// ProviderSyncResult for POST /calendar/sync-events
+type ProviderSyncResult struct {
+	ProviderID   string  `json:"provider_id"`
+	ProviderType string  `json:"provider_type"`
+	Success      bool    `json:"success"`
+	Error        *string `json:"error,omitempty"`
+}
+
+// loadSources returns the calendars of the given providers, keyed by provider ID.
+func (h *Handler) loadSources(ctx context.Context, userID string) (map[string][]SourceResponse, error) {
+	rows, err := h.db.Query(ctx, `
+		SELECT id, provider_id, external_calendar_id, name, color, is_primary, is_selected,
+			   last_sync_at, COALESCE(last_sync_status, 'never'), last_sync_error
+		FROM public.calendar_sources
+		WHERE user_id = $1
+		ORDER BY is_primary DESC, name ASC NULLS LAST
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sources := map[string][]SourceResponse{}
+	for rows.Next() {
+		var s SourceResponse
+		var providerID string
+		var lastSyncAt *time.Time
+		if err := rows.Scan(&s.ID, &providerID, &s.ExternalCalendarID, &s.Name, &s.Color, &s.IsPrimary, &s.IsSelected,
+			&lastSyncAt, &s.LastSyncStatus, &s.LastSyncError); err != nil {
+			continue
+		}
+		if lastSyncAt != nil {
+			str := lastSyncAt.Format(time.RFC3339)
+			s.LastSyncAt = &str
+		}
+		sources[providerID] = append(sources[providerID], s)
+	}
+	return sources, rows.Err()
+}
+
+// CreateProvider subscribes to an ICS feed or a CalDAV collection and runs a
+// first sync so a bad URL or bad credentials fail right away
+// POST /calendar/providers
+func (h *Handler) CreateProvider(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(auth.UserContextKey).(string)
+
+	var req CreateProviderRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if req.ProviderType != ProviderICS && req.ProviderType != ProviderCalDAV {
+		http.Error(w, "provider_type must be ics or caldav", http.StatusBadRequest)
+		return
+	}
+	feedURL, err := normalizeFeedURL(req.URL)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := checkFeedURL(r.Context(), feedURL); err != nil {
+		log.Printf("⚠️ Rejected calendar URL host %s for user %s: %v", feedURL.Hostname(), userID, err)
+		http.Error(w, "url must point to a reachable public host", http.StatusBadRequest)
+		return
+	}
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		name = feedURL.Host
+	}
+
+	var username, password *string
+	if req.ProviderType == ProviderCalDAV && req.Username != "" {
+		sealed, err := sealCredential(h.credentialKey, req.Password)
+		if errors.Is(err, ErrNoCredentialKey) {
+			http.Error(w, "CalDAV accounts are not available", http.StatusServiceUnavailable)
+			return
+		}
+		if err != nil {
+			log.Printf("❌ Failed to seal CalDAV password: %v", err)
+			http.Error(w, "Failed to add calendar", http.StatusInternalServerError)
+			return
+		}
+		username, password = &req.Username, &sealed
+	}
+
+	tx, err := h.db.Begin(r.Context())
+	if err != nil {
+		http.Error(w, "Failed to add calendar", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback(r.Context())
+
+	var providerID string
+	err = tx.QueryRow(r.Context(), `
+		INSERT INTO public.calendar_providers (
+			user_id, provider_type, provider_email, access_token, provider_config,
+			is_active, is_connected, sync_direction, created_at, updated_at
+		)
+		VALUES ($1, $2, $3, $4, jsonb_build_object('url', $5::text, 'name', $6::text), true, true, 'from_provider', NOW(), NOW())
+		RETURNING id
+	`, userID, req.ProviderType, username, password, feedURL.String(), name).Scan(&providerID)
+	if err != nil {
+		log.Printf("❌ Calendar provider insert error: %v", err)
+		http.Error(w, "Failed to add calendar", http.StatusInternalServerError)
+		return
+	}
+	if _, err := tx.Exec(r.Context(), `
+		INSERT INTO public.calendar_sources (user_id, provider_id, external_calendar_id, name, is_primary, is_selected)
+		VALUES ($1, $2, $3, $4, true, true)
+	`, userID, providerID, feedURL.String(), name); err != nil {
+		log.Printf("❌ Calendar source insert error: %v", err)
+		http.Error(w, "Failed to add calendar", http.StatusInternalServerError)
+		return
+	}
+	if err := tx.Commit(r.Context()); err != nil {
+		http.Error(w, "Failed to add calendar", http.StatusInternalServerError)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), syncTimeout)
+	defer cancel()
+	if err := h.syncers[req.ProviderType].SyncProvider(ctx, providerID); err != nil {
+		log.Printf("⚠️ First sync of %s calendar failed for user %s: %v", req.ProviderType, userID, err)
+		if _, err := h.db.Exec(context.Background(), `DELETE FROM public.calendar_providers WHERE id = $1`, providerID); err != nil {
+			log.Printf("❌ Failed to remove unreadable calendar provider %s: %v", providerID, err)
+		}
+		http.Error(w, "Could not read calendar, check the URL and credentials", http.StatusUnprocessableEntity)
+		return
+	}
+
+	log.Printf("✅ %s calendar added for user %s: %s", req.ProviderType, userID, feedURL.Host)
+
+	provider, err := h.getProvider(r.Context(), userID, providerID)
+	if err != nil {
+		http.Error(w, "Failed to load calendar", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(provider)
+}
+
+// DeleteProvider removes an ICS/CalDAV provider and its cached events
+// (Google is disconnected through DELETE /google-calendar/config)
+// DELETE /calendar/providers/{id}
+func (h *Handler) DeleteProvider(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(auth.UserContextKey).(string)
+	providerID := chi.URLParam(r, "id")
+
+	if _, err := h.db.Exec(r.Context(), `
+		DELETE FROM public.calendar_events
+		WHERE user_id = $1 AND provider_id = $2
+		  AND provider_id IN (SELECT id FROM public.calendar_providers WHERE provider_type IN ('ics', 'caldav'))
+	`, userID, providerID); err != nil {
+		log.Printf("Failed to delete events of provider %s: %v", providerID, err)
+	}
+
+	result, err := h.db.Exec(r.Context(), `
+		DELETE FROM public.calendar_providers
+		WHERE id = $1 AND user_id = $2 AND provider_type IN ('ics', 'caldav')
+	`, providerID, userID)
+	if err != nil {
+		http.Error(w, "Failed to delete calendar", http.StatusInternalServerError)
+		return
+	}
+	if result.RowsAffected() == 0 {
+		http.Error(w, "Calendar not found", http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// UpdateSource selects or unselects a calendar. Unselecting drops its cached
+// events; selecting queues a sync of the provider.
+// PATCH /calendar/sources/{id}
+func (h *Handler) UpdateSource(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(auth.UserContextKey).(string)
+	sourceID := chi.URLParam(r, "id")
+
+	var req UpdateSourceRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.IsSelected == nil {
+		http.Error(w, "is_selected is required", http.StatusBadRequest)
+		return
+	}
+
+	var providerID string
+	err := h.db.QueryRow(r.Context(), `
+		UPDATE public.calendar_sources
+		SET is_selected = $3,
+			sync_token = CASE WHEN $3 THEN sync_token ELSE NULL END,
+			updated_at = NOW()
+		WHERE id = $1 AND user_id = $2
+		RETURNING provider_id
+	`, sourceID, userID, *req.IsSelected).Scan(&providerID)
+	if err != nil {
+		http.Error(w, "Calendar not found", http.StatusNotFound)
+		return
+	}
+
+	if *req.IsSelected {
+		if _, err := h.db.Exec(r.Context(), `
+			UPDATE public.calendar_providers SET sync_requested_at = COALESCE(sync_requested_at, NOW()) WHERE id = $1
+		`, providerID); err != nil {
+			log.Printf("Failed to queue sync for provider %s: %v", providerID, err)
+		}
+	} else {
+		if _, err := h.db.Exec(r.Context(), `
+			DELETE FROM public.calendar_events WHERE source_id = $1 AND user_id = $2
+		`, sourceID, userID); err != nil {
+			log.Printf("Failed to delete events of calendar %s: %v", sourceID, err)
+		}
+	}
+
+	provider, err := h.getProvider(r.Context(), userID, providerID)
+	if err != nil {
+		http.Error(w, "Failed to load calendar", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(provider)
+}
+
+// SyncEvents syncs every connected provider of the user now
+// POST /calendar/sync-events
+func (h *Handler) SyncEvents(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(auth.UserContextKey).(string)
+
+	rows, err := h.db.Query(r.Context(), `
+		SELECT id, provider_type FROM public.calendar_providers
+		WHERE user_id = $1 AND is_connected = true AND is_active = true
+		ORDER BY created_at ASC
+	`, userID)
+	if err != nil {
+		http.Error(w, "Failed to fetch providers", http.StatusInternalServerError)
+		return
+	}
+	results := []ProviderSyncResult{}
+	for rows.Next() {
+		var res ProviderSyncResult
+		if err := rows.Scan(&res.ProviderID, &res.ProviderType); err == nil {
+			results = append(results, res)
+		}
+	}
+	rows.Close()
+
+	ctx, cancel := context.WithTimeout(r.Context(), syncTimeout)
+	defer cancel()
+	for i := range results {
+		syncer, ok := h.syncers[results[i].ProviderType]
+		if !ok {
+			msg := "unsupported provider type"
+			results[i].Error = &msg
+			continue
+		}
+		if err := syncer.SyncProvider(ctx, results[i].ProviderID); err != nil {
+			msg := err.Error()
+			results[i].Error = &msg
+			continue
+		}
+		results[i].Success = true
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"results": results,
+		"count":   len(results),
+	})
+}
+
+// getProvider returns one provider with its calendars.
+func (h *Handler) getProvider(ctx context.Context, userID, providerID string) (*ProviderResponse, error) {
+	providers, err := h.listProviders(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range providers {
+		if providers[i].ID == providerID {
+			return &providers[i], nil
+		}
+	}
+	return nil, fmt.Errorf("provider %s not found", providerID)
+}
+
+// normalizeFeedURL accepts http(s) and webcal(s) URLs.
+func normalizeFeedURL(raw string) (*url.URL, error) {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("url must be an absolute http(s) or webcal URL")
+	}
+	switch u.Scheme {
+	case "webcal", "webcals":
+		u.Scheme = "https"
+	case "http", "https":
+	default:
+		return nil, fmt.Errorf("url must be an absolute http(s) or webcal URL")
+	}
+	return u, nil
+}
+
+// =============================================
+// BACKGROUND WORKER
+// =============================================
+
+// SyncWorker runs queued provider syncs (Google webhooks, newly selected
+// calendars) and polls providers that have no push notifications.
+type SyncWorker struct {
+	h *Handler
+}
+
+// NewSyncWorker creates the background sync worker for h.
+func NewSyncWorker(h *Handler) *SyncWorker {
+	return &SyncWorker{h: h}
+}
+
+// Run blocks until ctx is cancelled.
+func (s *SyncWorker) Run(ctx context.Context) {
+	log.Printf("📅 Calendar sync worker started (every %s)", workerInterval)
+
+	ticker := time.NewTicker(workerInterval)
+	defer ticker.Stop()
+
+	for {
+		s.tick(ctx)
+
+		select {
+		case <-ctx.Done():
+			log.Println("📅 Calendar sync worker stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *SyncWorker) tick(ctx context.Context) {
+	// Polled providers are queued like webhook-triggered ones
+	if _, err := s.h.db.Exec(ctx, `
+		UPDATE public.calendar_providers
+		SET sync_requested_at = NOW()
+		WHERE provider_type IN ('ics', 'caldav')
+		  AND is_connected = true AND is_active = true
+		  AND sync_requested_at IS NULL
+		  AND (last_sync_at IS NULL OR last_sync_at < NOW() - $1::interval)
+	`, fmt.Sprintf("%d seconds", int(pollInterval.Seconds()))); err != nil && ctx.Err() == nil {
+		log.Printf("Calendar poll queue error: %v", err)
+	}
+
+	// Claim with SKIP LOCKED so several instances can run the worker
+	rows, err := s.h.db.Query(ctx, `
+		UPDATE public.calendar_providers SET sync_requested_at = NULL
+		WHERE id IN (
+			SELECT id FROM public.calendar_providers
+			WHERE sync_requested_at IS NOT NULL AND is_connected = true
+			ORDER BY sync_requested_at
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id, provider_type, user_id
+	`, syncBatchSize)
+	if err != nil {
+		if ctx.Err() == nil {
+			log.Printf("Calendar sync queue error: %v", err)
+		}
+		return
+	}
+	type queued struct{ id, providerType, userID string }
+	var claimed []queued
+	for rows.Next() {
+		var q queued
+		if err := rows.Scan(&q.id, &q.providerType, &q.userID); err == nil {
+			claimed = append(claimed, q)
+		}
+	}
+	rows.Close()
+
+	for _, q := range claimed {
+		if ctx.Err() != nil {
+			return
+		}
+		syncer, ok := s.h.syncers[q.providerType]
+		if !ok {
+			continue
+		}
+		syncCtx, cancel := context.WithTimeout(ctx, syncTimeout)
+		if err := syncer.SyncProvider(syncCtx, q.id); err != nil {
+			log.Printf("❌ Background %s calendar sync failed for user %s: %v", q.providerType, q.userID, err)
+		}
+		cancel()
+	}
+}
@@ -0,0 +1,122 @@
+package calendarevents
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"syscall"
+	"time"
+)
+
+// =============================================
+// OUTBOUND FEED REQUESTS
+// =============================================
+// Feed URLs come from users, so every request only
+// reaches public addresses: the IP is checked when the
+// URL is added, on every redirect and again when the
+// connection is dialed (DNS may have changed since).
+// =============================================
+
+// maxFeedRedirects bounds the redirects followed for one feed request.
+const maxFeedRedirects = 5
+
+var (
+	errFeedAddressBlocked = errors.New("calendar URL points to a private or local address")
+	errFeedTooLarge       = errors.New("calendar is too large")
+)
+
+// cgnatPrefix is the carrier-grade NAT range, not covered by netip.IsPrivate.
+var cgnatPrefix = netip.MustParsePrefix("100.64.0.0/10")
+
+// isPublicAddr reports whether addr is a globally routable unicast address.
+func isPublicAddr(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	return addr.IsValid() &&
+		addr.IsGlobalUnicast() &&
+		!addr.IsPrivate() &&
+		!addr.IsLoopback() &&
+		!addr.IsLinkLocalUnicast() &&
+		!cgnatPrefix.Contains(addr)
+}
+
+// checkFeedHost resolves host and fails unless every address is public.
+func checkFeedHost(ctx context.Context, host string) error {
+	if addr, err := netip.ParseAddr(host); err == nil {
+		if !isPublicAddr(addr) {
+			return errFeedAddressBlocked
+		}
+		return nil
+	}
+
+	addrs, err := net.DefaultResolver.LookupNetIP(ctx, "ip", host)
+	if err != nil {
+		return fmt.Errorf("could not resolve %s: %w", host, err)
+	}
+	for _, addr := range addrs {
+		if !isPublicAddr(addr) {
+			return errFeedAddressBlocked
+		}
+	}
+	return nil
+}
+
+// checkFeedURL validates the scheme and host of a feed URL or redirect target.
+func checkFeedURL(ctx context.Context, u *url.URL) error {
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("unsupported URL scheme %q", u.Scheme)
+	}
+	return checkFeedHost(ctx, u.Hostname())
+}
+
+// newFeedClient returns an HTTP client that refuses non-public addresses at
+// dial time and on redirects, and never goes through an environment proxy.
+func newFeedClient() *http.Client {
+	dialer := &net.Dialer{
+		Timeout: 10 * time.Second,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			addrPort, err := netip.ParseAddrPort(address)
+			if err != nil {
+				return err
+			}
+			if !isPublicAddr(addrPort.Addr()) {
+				return errFeedAddressBlocked
+			}
+			return nil
+		},
+	}
+
+	return &http.Client{
+		Timeout: 30 * time.Second,
+		Transport: &http.Transport{
+			Proxy:               nil,
+			DialContext:         dialer.DialContext,
+			TLSHandshakeTimeout: 10 * time.Second,
+			MaxIdleConns:        20,
+			IdleConnTimeout:     90 * time.Second,
+		},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= maxFeedRedirects {
+				return fmt.Errorf("stopped after %d redirects", maxFeedRedirects)
+			}
+			return checkFeedURL(req.Context(), req.URL)
+		},
+	}
+}
+
+// readFeedBody reads at most maxFeedSize bytes and fails on larger bodies
+// instead of parsing a truncated calendar.
+func readFeedBody(r io.Reader) ([]byte, error) {
+	body, err := io.ReadAll(io.LimitReader(r, maxFeedSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(body) > maxFeedSize {
+		return nil, errFeedTooLarge
+	}
+	return body, nil
+}
@@ -0,0 +1,104 @@
+package calendarevents
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strings"
+	"testing"
+)
+
+func TestIsPublicAddr(t *testing.T) {
+	for addr, want := range map[string]bool{
+		"93.184.216.34":   true,
+		"2606:4700::1111": true,
+		"127.0.0.1":       false,
+		"10.1.2.3":        false,
+		"172.16.0.1":      false,
+		"192.168.1.10":    false,
+		"169.254.169.254": false,
+		"100.64.0.1":      false,
+		"0.0.0.0":         false,
+		"::1":             false,
+		"fd00::1":         false,
+		"fe80::1":         false,
+		"::ffff:10.0.0.1": false,
+		"224.0.0.1":       false,
+	} {
+		if got := isPublicAddr(netip.MustParseAddr(addr)); got != want {
+			t.Errorf("isPublicAddr(%s) = %v, want %v", addr, got, want)
+		}
+	}
+}
+
+func TestFeedClientRefusesLocalAddresses(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("BEGIN:VCALENDAR\r\nEND:VCALENDAR\r\n"))
+	}))
+	defer srv.Close()
+
+	_, err := newFeedClient().Get(srv.URL)
+	if !errors.Is(err, errFeedAddressBlocked) {
+		t.Fatalf("Get(%s) error = %v, want errFeedAddressBlocked", srv.URL, err)
+	}
+}
+
+func TestFeedClientRefusesRedirectsToLocalAddresses(t *testing.T) {
+	client := newFeedClient()
+	req := httptest.NewRequest(http.MethodGet, "http://169.254.169.254/latest/meta-data/", nil)
+	err := client.CheckRedirect(req, []*http.Request{req})
+	if !errors.Is(err, errFeedAddressBlocked) {
+		t.Errorf("CheckRedirect error = %v, want errFeedAddressBlocked", err)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "file:///etc/passwd", nil)
+	if err := client.CheckRedirect(req, []*http.Request{req}); err == nil {
+		t.Error("CheckRedirect allowed a file:// target")
+	}
+}
+
+func TestCheckFeedHostLiteralAddresses(t *testing.T) {
+	if err := checkFeedHost(context.Background(), "127.0.0.1"); !errors.Is(err, errFeedAddressBlocked) {
+		t.Errorf("127.0.0.1: err = %v", err)
+	}
+	if err := checkFeedHost(context.Background(), "93.184.216.34"); err != nil {
+		t.Errorf("93.184.216.34: err = %v", err)
+	}
+}
+
+func TestReadFeedBody(t *testing.T) {
+	if _, err := readFeedBody(strings.NewReader(strings.Repeat("a", maxFeedSize+1))); !errors.Is(err, errFeedTooLarge) {
+		t.Errorf("oversized body: err = %v", err)
+	}
+	body, err := readFeedBody(strings.NewReader("BEGIN:VCALENDAR"))
+	if err != nil || string(body) != "BEGIN:VCALENDAR" {
+		t.Errorf("body = %q, err = %v", body, err)
+	}
+}
+
+func TestCredentialSealing(t *testing.T) {
+	key := make([]byte, 32)
+	sealed, err := sealCredential(key, "hunter2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(sealed, sealedPrefix) || strings.Contains(sealed, "hunter2") {
+		t.Fatalf("sealed = %q", sealed)
+	}
+	if plain, err := openCredential(key, sealed); err != nil || plain != "hunter2" {
+		t.Errorf("openCredential = %q, %v", plain, err)
+	}
+	if plain, err := openCredential(nil, "legacy-plaintext"); err != nil || plain != "legacy-plaintext" {
+		t.Errorf("legacy value = %q, %v", plain, err)
+	}
+	if _, err := sealCredential(nil, "hunter2"); !errors.Is(err, ErrNoCredentialKey) {
+		t.Errorf("sealing without a key: err = %v", err)
+	}
+	other := make([]byte, 32)
+	other[0] = 1
+	if _, err := openCredential(other, sealed); err == nil {
+		t.Error("opened a credential with the wrong key")
+	}
+}
@@ -0,0 +1,85 @@
+package chat
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"firelevel-backend/internal/auth"
+	"firelevel-backend/internal/backboard"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// ==========================================
+// Tool confirmations
+// ==========================================
+// When Kai calls a tool that needs approval (delete_task, ...), the reply
+// carries a pending_confirmation side effect. The app answers it here and
+// gets the rest of the turn back like a normal /chat/v2/message reply.
+// ==========================================
+
+type ConfirmToolCallsRequest struct {
+	Approved      bool                     `json:"approved"`
+	DeviceContext *backboard.DeviceContext `json:"device_context,omitempty"`
+}
+
+// ConfirmToolCallsV2 handles POST /chat/v2/confirmations/{id} — approves
+// or refuses the paused tool calls and resumes the run.
+func (h *Handler) ConfirmToolCallsV2(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(auth.UserContextKey).(string)
+	if !ok || userID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req ConfirmToolCallsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	provider, bbClient, err := h.chatProvider()
+	if err != nil {
+		var pe *pipelineError
+		errors.As(err, &pe)
+		http.Error(w, pe.message, pe.status)
+		return
+	}
+
+	// Same budget as SendMessageV2: the resumed run may call more tools
+	ctx, cancel := context.WithTimeout(r.Context(), 55*time.Second)
+	defer cancel()
+
+	confirmationID := chi.URLParam(r, "id")
+	executor := backboard.NewExecutor(h.db, bbClient, h.toolRegistry)
+	reply, sideEffects, err := executor.ResumeConfirmation(ctx, provider, userID, confirmationID, req.Approved, req.DeviceContext)
+	switch {
+	case errors.Is(err, backboard.ErrConfirmationNotFound):
+		http.Error(w, "Confirmation not found or expired", http.StatusNotFound)
+		return
+	case errors.Is(err, backboard.ErrConfirmationProvider):
+		http.Error(w, "Confirmation can no longer be resumed", http.StatusGone)
+		return
+	case err != nil:
+		log.Printf("❌ Resuming confirmation %s failed for user %s: %v", confirmationID, userID, err)
+		if len(sideEffects) > 0 {
+			reply = fallbackReplyFromSideEffects(sideEffects)
+		} else {
+			reply = "Désolé, j'ai un souci technique. Tu peux réessayer ?"
+		}
+	}
+
+	turn := chatReply{text: reply, sideEffects: sideEffects}
+	turn.messageID = h.recordTranscriptMessage(ctx, transcriptMessage{
+		userID:      userID,
+		content:     reply,
+		sideEffects: sideEffects,
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(turn.response())
+}
@@ -0,0 +1,157 @@
+package chat
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"strings"
+
+	"firelevel-backend/internal/backboard"
+	"firelevel-backend/internal/llm"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// ==========================================
+// LLM provider selection
+// ==========================================
+// /chat/v2 runs on Backboard unless another llm.Provider is set. Whatever
+// the provider, tools come from backboard.Executor and run the same way.
+// ==========================================
+
+// Recent turns sent with each message to stateless providers.
+const llmHistoryLimit = 20
+
+// SetLLMProvider makes /chat/v2 run on provider instead of Backboard
+// (called from main.go when LLM_PROVIDER is set).
+func (h *Handler) SetLLMProvider(provider llm.Provider) {
+	h.llmProvider = provider
+}
+
+// openConversation prepares where the turn is sent. On Backboard it ensures
+// the user's assistant and thread; other providers get the system prompt,
+// history and tool registry with the turn.
+func (h *Handler) openConversation(ctx context.Context, userID string, provider llm.Provider, bbClient *backboard.Client, executor *backboard.Executor, history []llm.Message) (*llm.Conversation, error) {
+	conv := &llm.Conversation{UserID: userID}
+
+	if _, onBackboard := provider.(*backboard.LLMProvider); onBackboard {
+		assistantID, err := h.ensureBackboardAssistant(ctx, userID, bbClient)
+		if err != nil {
+			log.Printf("❌ ensureAssistant failed for user %s: %v", userID, err)
+			return nil, &pipelineError{status: http.StatusInternalServerError, message: "Failed to setup AI assistant", err: err}
+		}
+		threadID, err := h.ensureBackboardThread(ctx, userID, assistantID, bbClient)
+		if err != nil {
+			log.Printf("❌ ensureThread failed for user %s: %v", userID, err)
+			return nil, &pipelineError{status: http.StatusInternalServerError, message: "Failed to setup conversation", err: err}
+		}
+		conv.AssistantID = assistantID
+		conv.ThreadID = threadID
+		return conv, nil
+	}
+
+	var companionName, timezone string
+	var harshMode bool
+	if err := h.db.QueryRow(ctx, `
+		SELECT COALESCE(companion_name, 'Kai'), COALESCE(timezone, 'Europe/Paris'), COALESCE(coach_harsh_mode, false)
+		FROM public.users WHERE id = $1
+	`, userID).Scan(&companionName, &timezone, &harshMode); err != nil {
+		log.Printf("Failed to fetch companion config for user %s: %v", userID, err)
+	}
+
+	// Memories still live on the Backboard assistant when one exists
+	if bbClient != nil {
+		var assistantID *string
+		if err := h.db.QueryRow(ctx, "SELECT backboard_assistant_id FROM public.users WHERE id = $1", userID).Scan(&assistantID); err == nil && assistantID != nil {
+			conv.AssistantID = *assistantID
+		}
+	}
+
+	conv.SystemPrompt = backboard.BuildAssistantConfig(h.toolRegistry, companionName, harshMode, timezone).SystemPrompt
+	conv.History = history
+	conv.Tools = executor.Tools()
+	return conv, nil
+}
+
+// resetBackboardThread deletes a thread that can't take messages anymore
+// (see backboard.NeedsNewThread) and points conv at a fresh one.
+func (h *Handler) resetBackboardThread(ctx context.Context, conv *llm.Conversation, bbClient *backboard.Client) error {
+	_ = bbClient.DeleteThread(ctx, conv.ThreadID)
+	if _, err := h.db.Exec(ctx, "UPDATE public.users SET backboard_thread_id = NULL WHERE id = $1", conv.UserID); err != nil {
+		log.Printf("Failed to clear corrupted backboard_thread_id for user %s: %v", conv.UserID, err)
+	}
+	log.Printf("🗑️ Deleted corrupted thread %s for user %s", conv.ThreadID, conv.UserID)
+
+	threadID, err := h.ensureBackboardThread(ctx, conv.UserID, conv.AssistantID, bbClient)
+	if err != nil {
+		return err
+	}
+	conv.ThreadID = threadID
+	return nil
+}
+
+// proactivePreamble returns the messages Kai sent on its own (source
+// coach, see the proactive package) since the user last wrote, formatted
+// to go in front of their message: the Backboard thread never saw them.
+func (h *Handler) proactivePreamble(ctx context.Context, userID string) string {
+	rows, err := h.db.Query(ctx, `
+		SELECT content FROM public.chat_messages
+		WHERE user_id = $1 AND source = 'coach'
+		  AND created_at > COALESCE(
+			(SELECT MAX(created_at) FROM public.chat_messages WHERE user_id = $1 AND is_from_user),
+			'-infinity')
+		ORDER BY created_at
+	`, userID)
+	if err != nil {
+		log.Printf("Failed to load proactive messages for user %s: %v", userID, err)
+		return ""
+	}
+	sent, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil || len(sent) == 0 {
+		return ""
+	}
+
+	var b strings.Builder
+	b.WriteString("[Message(s) que tu lui as envoyé(s) de ta propre initiative depuis son dernier message :\n")
+	for _, m := range sent {
+		b.WriteString("- « " + m + " »\n")
+	}
+	b.WriteString("]\n\n")
+	return b.String()
+}
+
+// recentHistory returns the user's last limit transcript messages, oldest
+// first.
+func (h *Handler) recentHistory(ctx context.Context, userID string, limit int) []llm.Message {
+	rows, err := h.db.Query(ctx, `
+		SELECT content, is_from_user FROM (
+			SELECT content, is_from_user, created_at, id
+			FROM public.chat_messages
+			WHERE user_id = $1
+			ORDER BY created_at DESC, id DESC
+			LIMIT $2
+		) recent
+		ORDER BY created_at, id
+	`, userID, limit)
+	if err != nil {
+		log.Printf("Failed to load chat history for user %s: %v", userID, err)
+		return nil
+	}
+	defer rows.Close()
+
+	var history []llm.Message
+	for rows.Next() {
+		var content string
+		var fromUser bool
+		if err := rows.Scan(&content, &fromUser); err != nil {
+			log.Printf("Failed to scan chat history for user %s: %v", userID, err)
+			return nil
+		}
+		role := llm.RoleAssistant
+		if fromUser {
+			role = llm.RoleUser
+		}
+		history = append(history, llm.Message{Role: role, Content: content})
+	}
+	return history
+}
@@ -14,7 +14,15 @@ import (
 	"time"
 
 	"firelevel-backend/internal/auth"
+	"firelevel-backend/internal/backboard"
+	"firelevel-backend/internal/calendar"
+	"firelevel-backend/internal/checkins"
+	"firelevel-backend/internal/llm"
+	"firelevel-backend/internal/stats"
 	"firelevel-backend/internal/streak"
+	"firelevel-backend/internal/tools"
+	"firelevel-backend/internal/userclock"
+	"firelevel-backend/internal/weeklygoals"
 
 	gradium "github.com/cydanix/go-gradium"
 	"github.com/cydanix/go-gradium/tts"
@@ -31,11 +39,13 @@ import (
 // ===========================================
 
 type Handler struct {
-	db *pgxpool.Pool
+	db           *pgxpool.Pool
+	llmProvider  llm.Provider    // nil: Backboard
+	toolRegistry *tools.Registry // Tools Kai can call (backboard.NewRegistry)
 }
 
-func NewHandler(db *pgxpool.Pool) *Handler {
-	return &Handler{db: db}
+func NewHandler(db *pgxpool.Pool, toolRegistry *tools.Registry) *Handler {
+	return &Handler{db: db, toolRegistry: toolRegistry}
 }
 
 // ============================================
@@ -432,7 +442,7 @@ func (h *Handler) SendMessage(w http.ResponseWriter, r *http.Request) {
 	userInfo.DistractionCount = req.DistractionCount
 
 	// Update streak (user engaged today by sending a message)
-	streak.UpdateUserStreak(r.Context(), h.db, userID)
+	streak.Record(r.Context(), h.db, userID, streak.ActivityChat)
 
 	// Chat history (managed by Backboard)
 	var history []ChatMessage
@@ -522,7 +532,7 @@ RÈGLES:
 		log.Printf("FALLBACK MATCH: taskMentioned=%v aiConfirmed=%v", taskMentioned, aiConfirmed)
 		if taskMentioned && aiConfirmed {
 			log.Printf("Task fallback triggered for: %s", req.Content)
-			fallbackTask := h.extractTaskFromMessage(req.Content)
+			fallbackTask := h.extractTaskFromMessage(r.Context(), userID, req.Content)
 			if fallbackTask != nil {
 				taskID, err := h.createCalendarTask(r.Context(), userID, fallbackTask)
 				if err != nil {
@@ -776,6 +786,8 @@ type UserInfo struct {
 	SatisfactionScore int
 	// Coach mode
 	CoachHarshMode bool
+	// User's local clock ("today" in users.timezone)
+	Clock userclock.Clock
 }
 
 type TaskSummary struct {
@@ -832,7 +844,9 @@ func (h *Handler) ensureUserExists(ctx context.Context, userID string) {
 }
 
 func (h *Handler) getUserInfo(ctx context.Context, userID string) *UserInfo {
-	info := &UserInfo{}
+	clock := userclock.For(ctx, h.db, userID)
+	info := &UserInfo{Clock: clock}
+	today := clock.Today()
 
 	// User profile + companion name + streak + satisfaction score
 	var scoreDate *time.Time
@@ -847,40 +861,31 @@ func (h *Handler) getUserInfo(ctx context.Context, userID string) *UserInfo {
 	`, userID).Scan(&info.Name, &info.CompanionName, &info.CurrentStreak, &info.SatisfactionScore, &scoreDate, &info.CoachHarshMode)
 
 	// Reset satisfaction score if it's from a previous day
-	now := time.Now()
-	if scoreDate == nil || scoreDate.Format("2006-01-02") != now.Format("2006-01-02") {
+	if scoreDate == nil || scoreDate.Format("2006-01-02") != today {
 		info.SatisfactionScore = 45
-		if _, err := h.db.Exec(ctx, `UPDATE users SET satisfaction_score = 45, satisfaction_score_date = CURRENT_DATE WHERE id = $1`, userID); err != nil {
+		if _, err := h.db.Exec(ctx, `UPDATE users SET satisfaction_score = 45, satisfaction_score_date = $2::date WHERE id = $1`, userID, today); err != nil {
 			log.Printf("Failed to reset satisfaction_score for user %s: %v", userID, err)
 		}
 	}
 
-	// Focus stats
-	h.db.QueryRow(ctx, `
-		SELECT COALESCE(SUM(duration_minutes), 0) FROM focus_sessions
-		WHERE user_id = $1 AND DATE(started_at) = CURRENT_DATE AND status = 'completed'
-	`, userID).Scan(&info.FocusToday)
-
-	h.db.QueryRow(ctx, `
-		SELECT COALESCE(SUM(duration_minutes), 0) FROM focus_sessions
-		WHERE user_id = $1 AND started_at >= DATE_TRUNC('week', CURRENT_DATE) AND status = 'completed'
-	`, userID).Scan(&info.FocusWeek)
-
-	// Tasks count
-	h.db.QueryRow(ctx, `
-		SELECT COUNT(*), COUNT(*) FILTER (WHERE status = 'completed')
-		FROM tasks WHERE user_id = $1 AND date = CURRENT_DATE
-	`, userID).Scan(&info.TasksToday, &info.TasksCompleted)
+	// Focus, tasks and streak
+	if day, err := stats.GetToday(ctx, h.db, userID, clock); err != nil {
+		log.Printf("Failed to get today's stats for user %s: %v", userID, err)
+	} else {
+		info.FocusToday, info.FocusWeek = day.FocusMinutes, day.FocusMinutesWeek
+		info.TasksToday, info.TasksCompleted = day.TasksTotal, day.TasksCompleted
+		info.CurrentStreak = day.CurrentStreak
+	}
 
 	// Today's tasks (detailed, max 10)
 	taskRows, err := h.db.Query(ctx, `
 		SELECT title, COALESCE(status, 'pending'), COALESCE(time_block, 'morning'),
 		       CASE WHEN scheduled_start IS NOT NULL THEN to_char(scheduled_start, 'HH24:MI') END
-		FROM tasks WHERE user_id = $1 AND date = CURRENT_DATE
+		FROM tasks WHERE user_id = $1 AND date = $2::date AND recurrence_rule IS NULL
 		ORDER BY CASE time_block WHEN 'morning' THEN 1 WHEN 'afternoon' THEN 2 WHEN 'evening' THEN 3 ELSE 4 END,
 		         scheduled_start NULLS LAST
 		LIMIT 10
-	`, userID)
+	`, userID, today)
 	if err == nil {
 		defer taskRows.Close()
 		for taskRows.Next() {
@@ -890,43 +895,32 @@ func (h *Handler) getUserInfo(ctx context.Context, userID string) *UserInfo {
 		}
 	}
 
+	// Occurrences of recurring tasks on today
+	if occurrences, err := calendar.Occurrences(ctx, h.db, userID, today, today); err != nil {
+		log.Printf("Failed to expand recurring tasks for user %s: %v", userID, err)
+	} else {
+		for _, o := range occurrences {
+			if len(info.Tasks) == 10 {
+				break
+			}
+			info.Tasks = append(info.Tasks, TaskSummary{Title: o.Title, Status: o.Status, TimeBlock: o.TimeBlock, StartTime: o.ScheduledStart})
+		}
+	}
+
 	// Routines with today's completion status
-	routineRows, err := h.db.Query(ctx, `
-		SELECT r.title,
-		       EXISTS(
-		           SELECT 1 FROM routine_completions rc
-		           WHERE rc.routine_id = r.id AND rc.user_id = $1
-		           AND DATE(rc.completed_at) = CURRENT_DATE
-		       ) as is_completed
-		FROM routines r
-		WHERE r.user_id = $1
-		ORDER BY r.created_at
-		LIMIT 10
-	`, userID)
-	if err == nil {
-		defer routineRows.Close()
-		for routineRows.Next() {
-			var r RoutineSummary
-			routineRows.Scan(&r.Title, &r.IsCompleted)
-			info.Routines = append(info.Routines, r)
+	if routines, err := stats.RoutinesOn(ctx, h.db, userID, today); err == nil {
+		for i, r := range routines {
+			if i == 10 {
+				break
+			}
+			info.Routines = append(info.Routines, RoutineSummary{Title: r.Title, IsCompleted: r.Completed})
 		}
 	}
 
 	// Active quests with progress
-	questRows, err := h.db.Query(ctx, `
-		SELECT q.title, q.current_value, q.target_value, a.name
-		FROM quests q
-		LEFT JOIN areas a ON a.id = q.area_id
-		WHERE q.user_id = $1 AND q.status = 'active'
-		ORDER BY q.created_at
-		LIMIT 8
-	`, userID)
-	if err == nil {
-		defer questRows.Close()
-		for questRows.Next() {
-			var q QuestSummary
-			questRows.Scan(&q.Title, &q.CurrentValue, &q.TargetValue, &q.AreaName)
-			info.Quests = append(info.Quests, q)
+	if quests, err := stats.ActiveQuests(ctx, h.db, userID, 8); err == nil {
+		for _, q := range quests {
+			info.Quests = append(info.Quests, QuestSummary{Title: q.Title, CurrentValue: q.CurrentValue, TargetValue: q.TargetValue, AreaName: q.AreaName})
 		}
 	}
 
@@ -936,10 +930,10 @@ func (h *Handler) getUserInfo(ctx context.Context, userID string) *UserInfo {
 		FROM weekly_goal_items wgi
 		JOIN weekly_goals wg ON wg.id = wgi.weekly_goal_id
 		WHERE wg.user_id = $1
-		AND wg.week_start_date = DATE_TRUNC('week', CURRENT_DATE)::date
+		AND wg.week_start_date = $2::date
 		ORDER BY wgi.position
 		LIMIT 5
-	`, userID)
+	`, userID, clock.WeekStart())
 	if err == nil {
 		defer weeklyRows.Close()
 		for weeklyRows.Next() {
@@ -964,27 +958,60 @@ func (h *Handler) getRelevantMemories(ctx context.Context, userID, message strin
 // AI RESPONSE GENERATION
 // ===========================================
 
-func (h *Handler) generateResponse(ctx context.Context, userID, message string, userInfo *UserInfo, memories []SemanticMemory, history []ChatMessage, opts ...string) (*SendMessageResponse, error) {
-	// Optional source parameter (first opt)
-	source := ""
-	if len(opts) > 0 {
-		source = opts[0]
+// legacyChatProvider is the model behind /chat/message and /chat/voice: the
+// configured provider when it is stateless, Gemini otherwise (the prompt and
+// history go with each turn, which a Backboard thread doesn't take).
+func (h *Handler) legacyChatProvider() (llm.Provider, error) {
+	if h.llmProvider != nil {
+		if _, onBackboard := h.llmProvider.(*backboard.LLMProvider); !onBackboard {
+			return h.llmProvider, nil
+		}
 	}
 	apiKey := os.Getenv("GEMINI_API_KEY")
 	if apiKey == "" {
 		return nil, fmt.Errorf("GEMINI_API_KEY not set")
 	}
+	return llm.NewGemini(apiKey, llm.DefaultGeminiModel), nil
+}
 
-	client, err := genai.NewClient(ctx, option.WithAPIKey(apiKey))
+// legacyHistory converts the last 10 messages for the provider.
+func legacyHistory(history []ChatMessage) []llm.Message {
+	start := 0
+	if len(history) > 10 {
+		start = len(history) - 10
+	}
+	messages := make([]llm.Message, 0, len(history)-start)
+	for _, m := range history[start:] {
+		role := llm.RoleAssistant
+		if m.IsFromUser {
+			role = llm.RoleUser
+		}
+		messages = append(messages, llm.Message{Role: role, Content: m.Content})
+	}
+	return messages
+}
+
+// autoTools keeps the tools that run without the user's approval.
+func autoTools(registry *tools.Registry, all []llm.Tool) []llm.Tool {
+	var auto []llm.Tool
+	for _, t := range all {
+		if registry.Policy(t.Name) == tools.PolicyAuto {
+			auto = append(auto, t)
+		}
+	}
+	return auto
+}
+
+func (h *Handler) generateResponse(ctx context.Context, userID, message string, userInfo *UserInfo, memories []SemanticMemory, history []ChatMessage, opts ...string) (*SendMessageResponse, error) {
+	// Optional source parameter (first opt)
+	source := ""
+	if len(opts) > 0 {
+		source = opts[0]
+	}
+	provider, err := h.legacyChatProvider()
 	if err != nil {
 		return nil, err
 	}
-	defer client.Close()
-
-	model := client.GenerativeModel("gemini-2.0-flash")
-	model.SetTemperature(0.8)
-	model.SetMaxOutputTokens(1000)
-	model.ResponseMIMEType = "application/json"
 
 	// Build rich coach context
 	streakStr := ""
@@ -1018,7 +1045,7 @@ func (h *Handler) generateResponse(ctx context.Context, userID, message string,
 
 	satisfactionStr := fmt.Sprintf("\n- Score de satisfaction actuel: %d/100", userInfo.SatisfactionScore)
 
-	now := time.Now()
+	now := userInfo.Clock.Now()
 	tomorrow := now.AddDate(0, 0, 1)
 	contextStr := fmt.Sprintf(`
 CONTEXTE:
@@ -1117,24 +1144,6 @@ CONTEXTE:
 		}
 	}
 
-	// Build history
-	historyStr := ""
-	if len(history) > 0 {
-		historyStr = "\nCONVERSATION RÉCENTE:\n"
-		// Only use last 10 messages for context
-		start := 0
-		if len(history) > 10 {
-			start = len(history) - 10
-		}
-		for _, m := range history[start:] {
-			if m.IsFromUser {
-				historyStr += fmt.Sprintf("Lui: %s\n", m.Content)
-			} else {
-				historyStr += fmt.Sprintf("Toi: %s\n", m.Content)
-			}
-		}
-	}
-
 	// Build system prompt with dynamic companion name
 	systemPrompt := fmt.Sprintf(kaiSystemPromptTemplate, userInfo.CompanionName)
 
@@ -1173,27 +1182,27 @@ RÈGLES:
 	}
 
 	prompt := fmt.Sprintf(`%s
-%s
-%s
 MESSAGE: %s
 
-Réponds en JSON:`, systemPrompt, contextStr, historyStr, message)
+Réponds en JSON:`, contextStr, message)
 
-	resp, err := model.GenerateContent(ctx, genai.Text(prompt))
-	if err != nil {
-		return nil, err
+	// Same tool loop as /chat/v2. Confirm tools are left out: this endpoint
+	// has no way to ask for the user's approval.
+	executor := backboard.NewExecutor(h.db, nil, h.toolRegistry)
+	conv := &llm.Conversation{
+		UserID:       userID,
+		SystemPrompt: systemPrompt,
+		History:      legacyHistory(history),
+		Tools:        autoTools(h.toolRegistry, executor.Tools()),
 	}
 
-	if len(resp.Candidates) == 0 || len(resp.Candidates[0].Content.Parts) == 0 {
-		return nil, fmt.Errorf("empty response")
+	first, err := provider.Send(ctx, conv, prompt)
+	if err != nil {
+		return nil, fmt.Errorf("%s send: %w", provider.Name(), err)
 	}
-
-	// Extract text
-	responseText := ""
-	for _, part := range resp.Candidates[0].Content.Parts {
-		if text, ok := part.(genai.Text); ok {
-			responseText += string(text)
-		}
+	responseText, _, err := executor.RunToolLoop(ctx, provider, conv, first, nil)
+	if err != nil {
+		return nil, err
 	}
 
 	// Clean JSON
@@ -1266,7 +1275,7 @@ Réponds en JSON:`, systemPrompt, contextStr, historyStr, message)
 
 	// Persist satisfaction score to DB (with today's date for daily reset)
 	if aiResp.SatisfactionScore != nil {
-		if _, err := h.db.Exec(ctx, `UPDATE users SET satisfaction_score = $1, satisfaction_score_date = CURRENT_DATE WHERE id = $2`, *aiResp.SatisfactionScore, userID); err != nil {
+		if _, err := h.db.Exec(ctx, `UPDATE users SET satisfaction_score = $1, satisfaction_score_date = $3::date WHERE id = $2`, *aiResp.SatisfactionScore, userID, userInfo.Clock.Today()); err != nil {
 			log.Printf("Failed to persist satisfaction_score for user %s: %v", userID, err)
 		}
 	}
@@ -1277,7 +1286,7 @@ Réponds en JSON:`, systemPrompt, contextStr, historyStr, message)
 			Type: "focus_scheduled",
 			TaskData: &TaskData{
 				Title:          aiResp.FocusIntent.Title,
-				Date:           time.Now().Format("2006-01-02"),
+				Date:           userInfo.Clock.Today(),
 				ScheduledStart: aiResp.FocusIntent.StartTime,
 				ScheduledEnd:   aiResp.FocusIntent.EndTime,
 				BlockApps:      aiResp.FocusIntent.BlockApps,
@@ -1702,28 +1711,67 @@ func (h *Handler) updateQuestProgress(ctx context.Context, userID, questTitle st
 // ===========================================
 
 func (h *Handler) completeTaskByTitle(ctx context.Context, userID, title string) error {
+	today := userclock.For(ctx, h.db, userID).Today()
 	result, err := h.db.Exec(ctx, `
 		UPDATE tasks SET status = 'completed', completed_at = NOW()
 		WHERE id = (
 			SELECT id FROM tasks
-			WHERE user_id = $1 AND date = CURRENT_DATE AND status != 'completed'
+			WHERE user_id = $1 AND date = $3::date AND status != 'completed' AND recurrence_rule IS NULL
 			AND LOWER(title) LIKE '%' || LOWER($2) || '%'
 			ORDER BY
 				CASE WHEN LOWER(title) = LOWER($2) THEN 0 ELSE 1 END,
 				created_at DESC
 			LIMIT 1
 		)
-	`, userID, title)
+	`, userID, title, today)
 	if err != nil {
 		return err
 	}
 	if result.RowsAffected() == 0 {
-		return fmt.Errorf("no pending task matching '%s'", title)
+		return h.completeOccurrenceByTitle(ctx, userID, title, today)
 	}
 	log.Printf("Task completed: '%s'", title)
 	return nil
 }
 
+// completeOccurrenceByTitle completes today's occurrence of a recurring task
+// whose title matches, materialising it first.
+func (h *Handler) completeOccurrenceByTitle(ctx context.Context, userID, title, today string) error {
+	occurrences, err := calendar.Occurrences(ctx, h.db, userID, today, today)
+	if err != nil {
+		return err
+	}
+
+	wanted := strings.ToLower(title)
+	var match *calendar.Task
+	for i, o := range occurrences {
+		name := strings.ToLower(o.Title)
+		if name == wanted {
+			match = &occurrences[i]
+			break
+		}
+		if match == nil && strings.Contains(name, wanted) {
+			match = &occurrences[i]
+		}
+	}
+	if match == nil {
+		return fmt.Errorf("no pending task matching '%s'", title)
+	}
+
+	taskID, err := calendar.ConcreteTaskID(ctx, h.db, userID, match.ID)
+	if err != nil {
+		return err
+	}
+	if _, err := h.db.Exec(ctx, `
+		UPDATE tasks SET status = 'completed', completed_at = NOW(), updated_at = NOW()
+		WHERE id = $1 AND user_id = $2
+	`, taskID, userID); err != nil {
+		return err
+	}
+	log.Printf("Recurring task occurrence completed: '%s' on %s", match.Title, today)
+	return nil
+}
+
 // ===========================================
 // ROUTINE COMPLETION (from coach chat)
 // ===========================================
@@ -1745,21 +1793,22 @@ func (h *Handler) completeRoutineByTitle(ctx context.Context, userID, title stri
 	}
 
 	// Check if already completed today
+	today := userclock.For(ctx, h.db, userID).Today()
 	var exists bool
 	h.db.QueryRow(ctx, `
 		SELECT EXISTS(
 			SELECT 1 FROM routine_completions
-			WHERE user_id = $1 AND routine_id = $2 AND DATE(completed_at) = CURRENT_DATE
+			WHERE user_id = $1 AND routine_id = $2 AND completion_date = $3::date
 		)
-	`, userID, routineID).Scan(&exists)
+	`, userID, routineID, today).Scan(&exists)
 	if exists {
 		return nil // Already done today, idempotent
 	}
 
 	_, err = h.db.Exec(ctx, `
-		INSERT INTO routine_completions (id, user_id, routine_id, completed_at)
-		VALUES ($1, $2, $3, NOW())
-	`, uuid.New().String(), userID, routineID)
+		INSERT INTO routine_completions (id, user_id, routine_id, completed_at, completion_date)
+		VALUES ($1, $2, $3, NOW(), $4::date)
+	`, uuid.New().String(), userID, routineID, today)
 	if err != nil {
 		return err
 	}
@@ -1821,9 +1870,6 @@ func (h *Handler) deleteRoutineByTitle(ctx context.Context, userID, title string
 	return nil
 }
 
-// ===========================================
-// (morning_checkins table removed — data saved via evening_checkins/reflections)
-
 // ===========================================
 // EVENING CHECK-IN (from coach chat)
 // ===========================================
@@ -1833,39 +1879,18 @@ func (h *Handler) saveEveningCheckin(ctx context.Context, userID string, mood in
 		mood = 3
 	}
 
-	// Get today's stats for the evening check-in
-	var ritualsCompleted, tasksCompleted, focusMinutes int
-	h.db.QueryRow(ctx, `
-		SELECT COUNT(*) FROM routine_completions
-		WHERE user_id = $1 AND DATE(completed_at) = CURRENT_DATE
-	`, userID).Scan(&ritualsCompleted)
-	h.db.QueryRow(ctx, `
-		SELECT COUNT(*) FROM tasks
-		WHERE user_id = $1 AND date = CURRENT_DATE AND status = 'completed'
-	`, userID).Scan(&tasksCompleted)
-	h.db.QueryRow(ctx, `
-		SELECT COALESCE(SUM(duration_minutes), 0) FROM focus_sessions
-		WHERE user_id = $1 AND DATE(started_at) = CURRENT_DATE AND status = 'completed'
-	`, userID).Scan(&focusMinutes)
-
-	_, err := h.db.Exec(ctx, `
-		INSERT INTO evening_checkins (id, user_id, date, evening_mood, biggest_win, blockers, rituals_completed, tasks_completed, focus_minutes, goal_for_tomorrow, grateful_for)
-		VALUES ($1, $2, CURRENT_DATE, $3, $4, $5, $6, $7, $8, $9, $10)
-		ON CONFLICT (user_id, date) DO UPDATE SET
-			evening_mood = EXCLUDED.evening_mood,
-			biggest_win = EXCLUDED.biggest_win,
-			blockers = EXCLUDED.blockers,
-			rituals_completed = EXCLUDED.rituals_completed,
-			tasks_completed = EXCLUDED.tasks_completed,
-			focus_minutes = EXCLUDED.focus_minutes,
-			goal_for_tomorrow = EXCLUDED.goal_for_tomorrow,
-			grateful_for = EXCLUDED.grateful_for,
-			updated_at = NOW()
-	`, uuid.New().String(), userID, mood, biggestWin, blockers, ritualsCompleted, tasksCompleted, focusMinutes, goalForTomorrow, gratefulFor)
+	today := userclock.For(ctx, h.db, userID).Today()
+	e, err := checkins.SaveEvening(ctx, h.db, userID, today, checkins.EveningInput{
+		Mood:            &mood,
+		BiggestWin:      &biggestWin,
+		Blockers:        &blockers,
+		GoalForTomorrow: &goalForTomorrow,
+		GratefulFor:     &gratefulFor,
+	})
 	if err != nil {
 		return err
 	}
-	log.Printf("Evening check-in saved (mood: %d, rituals: %d, tasks: %d, focus: %dm)", mood, ritualsCompleted, tasksCompleted, focusMinutes)
+	log.Printf("Evening check-in saved (mood: %d, rituals: %d, tasks: %d, focus: %dm)", mood, e.RitualsCompleted, e.TasksCompleted, e.FocusMinutes)
 	return nil
 }
 
@@ -1874,79 +1899,20 @@ func (h *Handler) saveEveningCheckin(ctx context.Context, userID string, mood in
 // ===========================================
 
 func (h *Handler) createWeeklyGoals(ctx context.Context, userID string, goals []string) error {
-	// Get Monday of current week
-	now := time.Now()
-	weekday := int(now.Weekday())
-	if weekday == 0 {
-		weekday = 7
-	}
-	monday := now.AddDate(0, 0, -(weekday - 1))
-	weekStart := monday.Format("2006-01-02")
-
-	// Create or get weekly_goals record
-	var weeklyGoalID string
-	err := h.db.QueryRow(ctx, `
-		INSERT INTO weekly_goals (id, user_id, week_start_date)
-		VALUES ($1, $2, $3::date)
-		ON CONFLICT (user_id, week_start_date) DO UPDATE SET updated_at = NOW()
-		RETURNING id
-	`, uuid.New().String(), userID, weekStart).Scan(&weeklyGoalID)
+	weekStart := userclock.For(ctx, h.db, userID).WeekStart()
+	count, _, err := weeklygoals.SetGoals(ctx, h.db, userID, weekStart, goals)
 	if err != nil {
-		return fmt.Errorf("failed to create weekly_goals: %w", err)
-	}
-
-	// Delete existing items for this week (replace all)
-	if _, err := h.db.Exec(ctx, `DELETE FROM weekly_goal_items WHERE weekly_goal_id = $1`, weeklyGoalID); err != nil {
-		log.Printf("Failed to delete existing weekly goal items for %s: %v", weeklyGoalID, err)
+		return fmt.Errorf("failed to create weekly goals: %w", err)
 	}
-
-	// Insert new goals
-	for i, goal := range goals {
-		if goal == "" {
-			continue
-		}
-		_, err := h.db.Exec(ctx, `
-			INSERT INTO weekly_goal_items (id, weekly_goal_id, content, position, is_completed)
-			VALUES ($1, $2, $3, $4, false)
-		`, uuid.New().String(), weeklyGoalID, goal, i)
-		if err != nil {
-			log.Printf("Failed to create weekly goal item '%s': %v", goal, err)
-		}
-	}
-
-	log.Printf("Weekly goals created: %d items", len(goals))
+	log.Printf("Weekly goals created: %d items", count)
 	return nil
 }
 
 func (h *Handler) completeWeeklyGoal(ctx context.Context, userID, content string) error {
-	// Get Monday of current week
-	now := time.Now()
-	weekday := int(now.Weekday())
-	if weekday == 0 {
-		weekday = 7
-	}
-	monday := now.AddDate(0, 0, -(weekday - 1))
-	weekStart := monday.Format("2006-01-02")
-
-	result, err := h.db.Exec(ctx, `
-		UPDATE weekly_goal_items SET is_completed = true
-		WHERE id = (
-			SELECT wgi.id FROM weekly_goal_items wgi
-			JOIN weekly_goals wg ON wg.id = wgi.weekly_goal_id
-			WHERE wg.user_id = $1 AND wg.week_start_date = $2::date
-			AND LOWER(wgi.content) LIKE '%' || LOWER($3) || '%'
-			AND wgi.is_completed = false
-			ORDER BY
-				CASE WHEN LOWER(wgi.content) = LOWER($3) THEN 0 ELSE 1 END
-			LIMIT 1
-		)
-	`, userID, weekStart, content)
-	if err != nil {
+	weekStart := userclock.For(ctx, h.db, userID).WeekStart()
+	if err := weeklygoals.CompleteByContent(ctx, h.db, userID, weekStart, content); err != nil {
 		return err
 	}
-	if result.RowsAffected() == 0 {
-		return fmt.Errorf("no weekly goal matching '%s'", content)
-	}
 	log.Printf("Weekly goal completed: '%s'", content)
 	return nil
 }
@@ -1967,7 +1933,7 @@ type ChatTaskInput struct {
 func (h *Handler) createCalendarTask(ctx context.Context, userID string, task *ChatTaskInput) (string, error) {
 	date := task.Date
 	if date == "" {
-		date = time.Now().Format("2006-01-02")
+		date = userclock.For(ctx, h.db, userID).Today()
 	}
 	timeBlock := task.TimeBlock
 	if timeBlock == "" {
@@ -2004,7 +1970,7 @@ func (h *Handler) createCalendarTask(ctx context.Context, userID string, task *C
 
 // extractTaskFromMessage parses the user's message to extract task info
 // when the AI forgot to include create_task in its JSON response
-func (h *Handler) extractTaskFromMessage(msg string) *ChatTaskInput {
+func (h *Handler) extractTaskFromMessage(ctx context.Context, userID, msg string) *ChatTaskInput {
 	msgLower := strings.ToLower(msg)
 
 	// Try to find the task title after common patterns
@@ -2030,15 +1996,15 @@ func (h *Handler) extractTaskFromMessage(msg string) *ChatTaskInput {
 		return nil
 	}
 
-	now := time.Now()
-	date := now.Format("2006-01-02")
+	clock := userclock.For(ctx, h.db, userID)
+	date := clock.Today()
 	timeBlock := "morning"
 	scheduledStart := ""
 	scheduledEnd := ""
 
 	// Detect "demain"
 	if strings.Contains(msgLower, "demain") {
-		date = now.AddDate(0, 0, 1).Format("2006-01-02")
+		date = clock.AddDays(1)
 	}
 
 	// Extract time like "14h", "14h30", "à 9h"
@@ -4,12 +4,15 @@ import (
 	"context"
 	"time"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
 
 	"firelevel-backend/internal/auth"
 	"firelevel-backend/internal/backboard"
+	"firelevel-backend/internal/llm"
+	"firelevel-backend/internal/streak"
 
 	"github.com/google/uuid"
 )
@@ -32,14 +35,17 @@ type BackboardSendMessageResponse struct {
 }
 
 type BackboardHistoryResponse struct {
-	Messages []BackboardHistoryMessage `json:"messages"`
+	Messages   []BackboardHistoryMessage `json:"messages"`
+	NextCursor string                    `json:"next_cursor,omitempty"` // Pass as ?before= for older messages
 }
 
 type BackboardHistoryMessage struct {
-	MessageID string `json:"message_id,omitempty"`
-	Role      string `json:"role"` // "user" or "assistant"
-	Content   string `json:"content"`
-	CreatedAt string `json:"created_at,omitempty"`
+	MessageID   string                 `json:"message_id,omitempty"`
+	Role        string                 `json:"role"` // "user" or "assistant"
+	Content     string                 `json:"content"`
+	Source      string                 `json:"source,omitempty"` // "app", "web", "whatsapp"
+	SideEffects []backboard.SideEffect `json:"side_effects,omitempty"`
+	CreatedAt   string                 `json:"created_at,omitempty"`
 }
 
 // ==========================================
@@ -67,84 +73,25 @@ func (h *Handler) SendMessageV2(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	bbClient := h.getBackboardClient()
-	if bbClient == nil {
-		http.Error(w, "AI service not configured", http.StatusServiceUnavailable)
-		return
-	}
-
 	// Tool call flows need two LLM round trips (~10-15s each). Give enough
 	// headroom so block_apps + start_focus_session can complete.
 	ctx, cancel := context.WithTimeout(r.Context(), 55*time.Second)
 	defer cancel()
 
-	// 1. Ensure the user has a Backboard assistant
-	assistantID, err := h.ensureBackboardAssistant(ctx, userID, bbClient)
-	if err != nil {
-		log.Printf("❌ ensureAssistant failed for user %s: %v", userID, err)
-		http.Error(w, "Failed to setup AI assistant", http.StatusInternalServerError)
-		return
-	}
-
-	// 2. Ensure the user has a thread
-	threadID, err := h.ensureBackboardThread(ctx, userID, assistantID, bbClient)
-	if err != nil {
-		log.Printf("❌ ensureThread failed for user %s: %v", userID, err)
-		http.Error(w, "Failed to setup conversation", http.StatusInternalServerError)
-		return
-	}
-
-	// 3. Send the message to Backboard
-	response, err := bbClient.SendMessage(ctx, threadID, req.Content)
-	if err != nil {
-		log.Printf("⚠️ SendMessage failed for user %s (thread %s): %v — attempting thread reset", userID, threadID, err)
-
-		// Thread is likely corrupted (stuck run, 500 error). Delete and recreate.
-		_ = bbClient.DeleteThread(ctx, threadID)
-		if _, err := h.db.Exec(ctx, "UPDATE public.users SET backboard_thread_id = NULL WHERE id = $1", userID); err != nil {
-			log.Printf("Failed to clear corrupted backboard_thread_id for user %s: %v", userID, err)
-		}
-		log.Printf("🗑️ Deleted corrupted thread %s for user %s", threadID, userID)
-
-		// Create fresh thread and retry once
-		newThreadID, err2 := h.ensureBackboardThread(ctx, userID, assistantID, bbClient)
-		if err2 != nil {
-			log.Printf("❌ Thread recreation failed for user %s: %v", userID, err2)
-			http.Error(w, "AI service error", http.StatusBadGateway)
-			return
-		}
-		threadID = newThreadID
-
-		response, err = bbClient.SendMessage(ctx, threadID, req.Content)
-		if err != nil {
-			log.Printf("❌ SendMessage retry failed for user %s: %v", userID, err)
-			http.Error(w, "AI service error", http.StatusBadGateway)
-			return
-		}
-		log.Printf("✅ SendMessage succeeded after thread reset for user %s (new thread: %s)", userID, threadID)
-	}
-
-	// 4. Execute the tool call loop
-	executor := backboard.NewExecutor(h.db, bbClient)
-	reply, sideEffects, err := executor.RunToolLoop(ctx, userID, threadID, assistantID, response, req.DeviceContext)
+	reply, err := h.runChat(ctx, transcriptMessage{userID: userID, content: req.Content, fromUser: true, source: req.Source}, req.DeviceContext, nil)
 	if err != nil {
-		log.Printf("❌ Tool loop failed for user %s: %v", userID, err)
-		// If tools executed successfully but we timed out waiting for the AI's
-		// text reply, generate a contextual fallback instead of a generic error.
-		if len(sideEffects) > 0 {
-			reply = fallbackReplyFromSideEffects(sideEffects)
+		var pe *pipelineError
+		if errors.As(err, &pe) {
+			http.Error(w, pe.message, pe.status)
 		} else {
-			reply = "Désolé, j'ai un souci technique. Tu peux réessayer ?"
+			http.Error(w, "AI service error", http.StatusBadGateway)
 		}
+		return
 	}
 
 	// 5. Return the response
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(BackboardSendMessageResponse{
-		Reply:       reply,
-		MessageID:   uuid.New().String(),
-		SideEffects: sideEffects,
-	})
+	json.NewEncoder(w).Encode(reply.response())
 }
 
 // fallbackReplyFromSideEffects generates a contextual reply when the tool loop
@@ -173,73 +120,155 @@ func fallbackReplyFromSideEffects(effects []backboard.SideEffect) string {
 	}
 }
 
-// GetHistoryV2 handles GET /chat/v2/history — returns messages from Backboard thread.
-func (h *Handler) GetHistoryV2(w http.ResponseWriter, r *http.Request) {
-	userID, ok := r.Context().Value(auth.UserContextKey).(string)
-	if !ok || userID == "" {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
-		return
+// pipelineError is a chat pipeline failure with the HTTP status to report.
+type pipelineError struct {
+	status  int
+	message string
+	err     error
+}
+
+func (e *pipelineError) Error() string {
+	if e.err != nil {
+		return e.message + ": " + e.err.Error()
 	}
+	return e.message
+}
 
-	bbClient := h.getBackboardClient()
-	if bbClient == nil {
-		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(BackboardHistoryResponse{Messages: []BackboardHistoryMessage{}})
-		return
+func (e *pipelineError) Unwrap() error { return e.err }
+
+// Reply runs one user message through the chat pipeline for a channel
+// other than the app (e.g. WhatsApp) and returns Kai's text reply. Device
+// side effects are dropped since there is no app to apply them.
+func (h *Handler) Reply(ctx context.Context, userID, content, source string) (string, error) {
+	reply, err := h.runChat(ctx, transcriptMessage{userID: userID, content: content, fromUser: true, source: source}, nil, nil)
+	if err != nil {
+		return "", err
 	}
+	log.Printf("💬 %s reply for user %s (%d side effects ignored)", source, userID, len(reply.sideEffects))
+	return reply.text, nil
+}
 
-	ctx := r.Context()
+// chatReply is the outcome of one chat turn.
+type chatReply struct {
+	text        string
+	messageID   string // chat_messages row of the assistant reply
+	sideEffects []backboard.SideEffect
+}
 
-	// Get thread ID from user profile
-	var threadID *string
-	if err := h.db.QueryRow(ctx, "SELECT backboard_thread_id FROM public.users WHERE id = $1", userID).Scan(&threadID); err != nil {
-		log.Printf("Failed to fetch backboard_thread_id for user %s: %v", userID, err)
+func (c *chatReply) response() BackboardSendMessageResponse {
+	messageID := c.messageID
+	if messageID == "" {
+		messageID = uuid.New().String()
 	}
+	return BackboardSendMessageResponse{
+		Reply:       c.text,
+		MessageID:   messageID,
+		SideEffects: c.sideEffects,
+	}
+}
 
-	if threadID == nil || *threadID == "" {
-		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(BackboardHistoryResponse{Messages: []BackboardHistoryMessage{}})
-		return
+// runChat sends a message through the configured LLM provider and runs the
+// tool call loop. On Backboard a corrupted thread is reset once. Both sides
+// of the turn are saved to chat_messages. Tool loop progress is reported to
+// sink when non-nil.
+func (h *Handler) runChat(ctx context.Context, msg transcriptMessage, deviceCtx *backboard.DeviceContext, sink backboard.EventSink) (*chatReply, error) {
+	userID := msg.userID
+	provider, bbClient, err := h.chatProvider()
+	if err != nil {
+		return nil, err
 	}
 
-	thread, err := bbClient.GetThread(ctx, *threadID)
+	executor := backboard.NewExecutor(h.db, bbClient, h.toolRegistry)
+	executor.SetEventSink(sink)
+
+	// A confirmation left unanswered is declined by moving on
+	executor.SupersedePendingRuns(ctx, provider, userID)
+
+	// Stateless providers get recent turns with each message. Read before
+	// saving this message so it isn't sent twice.
+	// Backboard only needs the proactive messages its thread hasn't seen.
+	content := msg.content
+	var history []llm.Message
+	if _, onBackboard := provider.(*backboard.LLMProvider); !onBackboard {
+		history = h.recentHistory(ctx, userID, llmHistoryLimit)
+	} else {
+		content = h.proactivePreamble(ctx, userID) + content
+	}
+
+	// Saved first so the message is kept even if the provider fails
+	h.recordTranscriptMessage(ctx, msg)
+
+	// 1. Set up the conversation (Backboard assistant and thread, or prompt + tools)
+	conv, err := h.openConversation(ctx, userID, provider, bbClient, executor, history)
 	if err != nil {
-		log.Printf("⚠️ GetThread failed for user %s: %v", userID, err)
-		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(BackboardHistoryResponse{Messages: []BackboardHistoryMessage{}})
-		return
+		return nil, err
 	}
 
-	var messages []BackboardHistoryMessage
-	for _, msg := range thread.Messages {
-		if msg.Content == nil || *msg.Content == "" {
-			continue
-		}
-		// Only include user and assistant messages
-		if msg.Role != "user" && msg.Role != "assistant" {
-			continue
-		}
-		m := BackboardHistoryMessage{
-			Role:    msg.Role,
-			Content: *msg.Content,
+	// 2. Send the message. A stuck or deleted thread is replaced once;
+	// outages and rate limits are not the thread's fault and keep it.
+	response, err := provider.Send(ctx, conv, content)
+	if err != nil && conv.ThreadID != "" && backboard.NeedsNewThread(err) {
+		log.Printf("⚠️ SendMessage failed for user %s (thread %s): %v — attempting thread reset", userID, conv.ThreadID, err)
+
+		if err2 := h.resetBackboardThread(ctx, conv, bbClient); err2 != nil {
+			log.Printf("❌ Thread recreation failed for user %s: %v", userID, err2)
+			return nil, &pipelineError{status: http.StatusBadGateway, message: "AI service error", err: err2}
 		}
-		if msg.MessageID != nil {
-			m.MessageID = *msg.MessageID
+
+		response, err = provider.Send(ctx, conv, content)
+		if err == nil {
+			log.Printf("✅ SendMessage succeeded after thread reset for user %s (new thread: %s)", userID, conv.ThreadID)
 		}
-		if msg.CreatedAt != nil {
-			m.CreatedAt = *msg.CreatedAt
+	}
+	if err != nil {
+		log.Printf("❌ %s send failed for user %s: %v", provider.Name(), userID, err)
+		if errors.Is(err, backboard.ErrCircuitOpen) || errors.Is(err, backboard.ErrRateLimited) {
+			return nil, &pipelineError{status: http.StatusServiceUnavailable, message: "AI service temporarily unavailable", err: err}
 		}
-		messages = append(messages, m)
+		return nil, &pipelineError{status: http.StatusBadGateway, message: "AI service error", err: err}
 	}
-	if messages == nil {
-		messages = []BackboardHistoryMessage{}
+
+	// The user engaged today by sending a message
+	streak.Record(ctx, h.db, userID, streak.ActivityChat)
+
+	// 3. Execute the tool call loop
+	reply, sideEffects, err := executor.RunToolLoop(ctx, provider, conv, response, deviceCtx)
+	if err != nil {
+		log.Printf("❌ Tool loop failed for user %s: %v", userID, err)
+		// If tools executed successfully but we timed out waiting for the AI's
+		// text reply, generate a contextual fallback instead of a generic error.
+		if len(sideEffects) > 0 {
+			reply = fallbackReplyFromSideEffects(sideEffects)
+		} else {
+			reply = "Désolé, j'ai un souci technique. Tu peux réessayer ?"
+		}
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(BackboardHistoryResponse{Messages: messages})
+	messageID := h.recordTranscriptMessage(ctx, transcriptMessage{
+		userID:      userID,
+		content:     reply,
+		source:      msg.source,
+		sideEffects: sideEffects,
+	})
+	return &chatReply{text: reply, messageID: messageID, sideEffects: sideEffects}, nil
 }
 
-// ClearHistoryV2 handles DELETE /chat/v2/history — deletes the Backboard thread.
+// chatProvider returns the configured LLM provider and the Backboard client
+// (nil without an API key).
+func (h *Handler) chatProvider() (llm.Provider, *backboard.Client, error) {
+	bbClient := h.getBackboardClient()
+	provider := h.llmProvider
+	if provider == nil {
+		if bbClient == nil {
+			return nil, nil, &pipelineError{status: http.StatusServiceUnavailable, message: "AI service not configured"}
+		}
+		provider = backboard.NewLLMProvider(bbClient)
+	}
+	return provider, bbClient, nil
+}
+
+// ClearHistoryV2 handles DELETE /chat/v2/history — deletes the transcript
+// and the Backboard thread.
 func (h *Handler) ClearHistoryV2(w http.ResponseWriter, r *http.Request) {
 	userID, ok := r.Context().Value(auth.UserContextKey).(string)
 	if !ok || userID == "" {
@@ -247,14 +276,20 @@ func (h *Handler) ClearHistoryV2(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	ctx := r.Context()
+
+	if _, err := h.db.Exec(ctx, "DELETE FROM public.chat_messages WHERE user_id = $1", userID); err != nil {
+		log.Printf("Failed to delete chat_messages for user %s: %v", userID, err)
+		http.Error(w, "Failed to clear history", http.StatusInternalServerError)
+		return
+	}
+
 	bbClient := h.getBackboardClient()
 	if bbClient == nil {
 		w.WriteHeader(http.StatusNoContent)
 		return
 	}
 
-	ctx := r.Context()
-
 	var threadID *string
 	if err := h.db.QueryRow(ctx, "SELECT backboard_thread_id FROM public.users WHERE id = $1", userID).Scan(&threadID); err != nil {
 		log.Printf("Failed to fetch backboard_thread_id for delete, user %s: %v", userID, err)
@@ -274,7 +309,7 @@ func (h *Handler) ClearHistoryV2(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusNoContent)
 }
 
-// SendVoiceMessageV2 handles POST /chat/v2/voice — transcribes then processes through Backboard.
+// SendVoiceMessageV2 handles POST /chat/v2/voice — transcribes then processes through the chat pipeline.
 func (h *Handler) SendVoiceMessageV2(w http.ResponseWriter, r *http.Request) {
 	userID, ok := r.Context().Value(auth.UserContextKey).(string)
 	if !ok || userID == "" {
@@ -315,32 +350,6 @@ func (h *Handler) SendVoiceMessageV2(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Process through Backboard (same as text message)
-	bbClient := h.getBackboardClient()
-	if bbClient == nil {
-		http.Error(w, "AI service not configured", http.StatusServiceUnavailable)
-		return
-	}
-
-	ctx := r.Context()
-	assistantID, err := h.ensureBackboardAssistant(ctx, userID, bbClient)
-	if err != nil {
-		http.Error(w, "Failed to setup AI assistant", http.StatusInternalServerError)
-		return
-	}
-
-	threadID, err := h.ensureBackboardThread(ctx, userID, assistantID, bbClient)
-	if err != nil {
-		http.Error(w, "Failed to setup conversation", http.StatusInternalServerError)
-		return
-	}
-
-	response, err := bbClient.SendMessage(ctx, threadID, transcript)
-	if err != nil {
-		http.Error(w, "AI service error", http.StatusBadGateway)
-		return
-	}
-
 	// Parse device context from form values
 	var deviceCtx *backboard.DeviceContext
 	if dcJSON := r.FormValue("device_context"); dcJSON != "" {
@@ -348,16 +357,25 @@ func (h *Handler) SendVoiceMessageV2(w http.ResponseWriter, r *http.Request) {
 		json.Unmarshal([]byte(dcJSON), deviceCtx)
 	}
 
-	executor := backboard.NewExecutor(h.db, bbClient)
-	reply, sideEffects, err := executor.RunToolLoop(ctx, userID, threadID, assistantID, response, deviceCtx)
+	// Process through the chat pipeline (same as text message)
+	ctx := r.Context()
+	turn, err := h.runChat(ctx, transcriptMessage{
+		userID:          userID,
+		content:         transcript,
+		fromUser:        true,
+		messageType:     "voice",
+		voiceTranscript: transcript,
+	}, deviceCtx, nil)
 	if err != nil {
-		log.Printf("❌ Voice tool loop failed for user %s: %v", userID, err)
-		if len(sideEffects) > 0 {
-			reply = fallbackReplyFromSideEffects(sideEffects)
+		var pe *pipelineError
+		if errors.As(err, &pe) {
+			http.Error(w, pe.message, pe.status)
 		} else {
-			reply = "Désolé, j'ai un souci technique. Tu peux réessayer ?"
+			http.Error(w, "AI service error", http.StatusBadGateway)
 		}
+		return
 	}
+	resp := turn.response()
 
 	// Increment voice counter
 	var updatedCount int
@@ -370,10 +388,10 @@ func (h *Handler) SendVoiceMessageV2(w http.ResponseWriter, r *http.Request) {
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]interface{}{
-		"reply":                    reply,
+		"reply":                    resp.Reply,
 		"transcript":               transcript,
-		"message_id":               uuid.New().String(),
-		"side_effects":             sideEffects,
+		"message_id":               resp.MessageID,
+		"side_effects":             resp.SideEffects,
 		"free_voice_messages_used": updatedCount,
 	})
 }
@@ -402,52 +420,39 @@ func SetBackboardAPIKey(key string) {
 	bbAPIKey = key
 }
 
-// ensureBackboardAssistant ensures the user has a Backboard assistant, creating one if needed.
+// ensureBackboardAssistant ensures the user has a Backboard assistant
+// running their current config, creating or migrating it if needed.
 func (h *Handler) ensureBackboardAssistant(ctx context.Context, userID string, bbClient *backboard.Client) (string, error) {
-	// Check DB for existing assistant ID
-	var assistantID *string
-	if err := h.db.QueryRow(ctx, "SELECT backboard_assistant_id FROM public.users WHERE id = $1", userID).Scan(&assistantID); err != nil {
-		log.Printf("Failed to fetch backboard_assistant_id for user %s: %v", userID, err)
+	var assistantID, configHash *string
+	var companionName, timezone string
+	var harshMode bool
+	if err := h.db.QueryRow(ctx, `
+		SELECT backboard_assistant_id, backboard_assistant_config_hash,
+		       COALESCE(companion_name, 'Kai'), COALESCE(timezone, 'Europe/Paris'), COALESCE(coach_harsh_mode, false)
+		FROM public.users WHERE id = $1
+	`, userID).Scan(&assistantID, &configHash, &companionName, &timezone, &harshMode); err != nil {
+		log.Printf("Failed to fetch assistant config for user %s: %v", userID, err)
 	}
 
+	hash := backboard.AssistantConfigHash(h.toolRegistry, companionName, harshMode, timezone)
 	if assistantID != nil && *assistantID != "" {
-		// Update the assistant with fresh date/time
-		var companionName, timezone string
-		var harshMode bool
-		if err := h.db.QueryRow(ctx, `
-			SELECT COALESCE(companion_name, 'Kai'), COALESCE(timezone, 'Europe/Paris'), COALESCE(coach_harsh_mode, false)
-			FROM public.users WHERE id = $1
-		`, userID).Scan(&companionName, &timezone, &harshMode); err != nil {
-			log.Printf("Failed to fetch companion config for user %s: %v", userID, err)
+		if configHash != nil && *configHash == hash {
+			return *assistantID, nil
 		}
-
-		// Note: UpdateAssistant (PATCH) returns 405 from Backboard — disabled for now
-		// config := backboard.BuildAssistantConfig(companionName, harshMode, timezone)
-		// bbClient.UpdateAssistant(ctx, *assistantID, config)
-		_ = companionName
-		_ = timezone
-		_ = harshMode
-		return *assistantID, nil
+		return h.syncBackboardAssistant(ctx, userID, *assistantID, hash, companionName, harshMode, timezone, bbClient)
 	}
 
 	// Create new assistant
-	var companionName, timezone string
-	var harshMode bool
-	if err := h.db.QueryRow(ctx, `
-		SELECT COALESCE(companion_name, 'Kai'), COALESCE(timezone, 'Europe/Paris'), COALESCE(coach_harsh_mode, false)
-		FROM public.users WHERE id = $1
-	`, userID).Scan(&companionName, &timezone, &harshMode); err != nil {
-		log.Printf("Failed to fetch companion config for new assistant, user %s: %v", userID, err)
-	}
-
-	config := backboard.BuildAssistantConfig(companionName, harshMode, timezone)
+	config := backboard.BuildAssistantConfig(h.toolRegistry, companionName, harshMode, timezone)
 	newID, err := bbClient.CreateAssistant(ctx, config)
 	if err != nil {
 		return "", fmt.Errorf("create assistant: %w", err)
 	}
 
 	// Save to user profile
-	if _, err := h.db.Exec(ctx, "UPDATE public.users SET backboard_assistant_id = $1 WHERE id = $2", newID, userID); err != nil {
+	if _, err := h.db.Exec(ctx, `
+		UPDATE public.users SET backboard_assistant_id = $1, backboard_assistant_config_hash = $2 WHERE id = $3
+	`, newID, hash, userID); err != nil {
 		log.Printf("Failed to save backboard_assistant_id for user %s: %v", userID, err)
 	}
 	log.Printf("🤖 Created Backboard assistant %s for user %s", newID, userID)
@@ -455,6 +460,54 @@ func (h *Handler) ensureBackboardAssistant(ctx context.Context, userID string, b
 	return newID, nil
 }
 
+// syncBackboardAssistant brings an assistant created with an older config
+// up to date (see backboard.SyncAssistant). When that means a new
+// assistant, the user switches to it with a fresh thread, unless a
+// concurrent request already migrated them. On failure the user keeps
+// chatting with the old assistant and the next message tries again.
+func (h *Handler) syncBackboardAssistant(ctx context.Context, userID, assistantID, hash, companionName string, harshMode bool, timezone string, bbClient *backboard.Client) (string, error) {
+	config := backboard.BuildAssistantConfig(h.toolRegistry, companionName, harshMode, timezone)
+	newID, err := backboard.SyncAssistant(ctx, bbClient, assistantID, config)
+	if err != nil {
+		log.Printf("⚠️ Failed to sync assistant %s for user %s, keeping it: %v", assistantID, userID, err)
+		return assistantID, nil
+	}
+
+	if newID == assistantID {
+		if _, err := h.db.Exec(ctx, "UPDATE public.users SET backboard_assistant_config_hash = $1 WHERE id = $2", hash, userID); err != nil {
+			log.Printf("Failed to save assistant config hash for user %s: %v", userID, err)
+		}
+		log.Printf("🤖 Updated Backboard assistant %s for user %s", assistantID, userID)
+		return assistantID, nil
+	}
+
+	tag, err := h.db.Exec(ctx, `
+		UPDATE public.users
+		SET backboard_assistant_id = $1, backboard_assistant_config_hash = $2, backboard_thread_id = NULL
+		WHERE id = $3 AND backboard_assistant_id = $4
+	`, newID, hash, userID, assistantID)
+	if err != nil || tag.RowsAffected() == 0 {
+		// Not saved, or another request got there first: drop ours
+		if err != nil {
+			log.Printf("Failed to save migrated assistant for user %s: %v", userID, err)
+		}
+		if delErr := bbClient.DeleteAssistant(ctx, newID); delErr != nil {
+			log.Printf("⚠️ Failed to delete unused assistant %s: %v", newID, delErr)
+		}
+		var current *string
+		if err := h.db.QueryRow(ctx, "SELECT backboard_assistant_id FROM public.users WHERE id = $1", userID).Scan(&current); err == nil && current != nil && *current != "" {
+			return *current, nil
+		}
+		return assistantID, nil
+	}
+
+	if err := bbClient.DeleteAssistant(ctx, assistantID); err != nil && !errors.Is(err, backboard.ErrNotFound) {
+		log.Printf("⚠️ Failed to delete old assistant %s for user %s: %v", assistantID, userID, err)
+	}
+	log.Printf("🤖 Moved user %s to Backboard assistant %s (config changed)", userID, newID)
+	return newID, nil
+}
+
 // ensureBackboardThread ensures the user has a conversation thread, creating one if needed.
 func (h *Handler) ensureBackboardThread(ctx context.Context, userID, assistantID string, bbClient *backboard.Client) (string, error) {
 	// Check DB for existing thread ID
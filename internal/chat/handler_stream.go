@@ -0,0 +1,152 @@
+package chat
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"firelevel-backend/internal/auth"
+	"firelevel-backend/internal/backboard"
+)
+
+// ==========================================
+// Streaming chat (Server-Sent Events)
+// ==========================================
+// Same pipeline as SendMessageV2, but progress is pushed as it happens:
+//
+//	event: text        {"type":"text","round":1,"content":"..."}
+//	event: tool_start  {"type":"tool_start","round":1,"tool":"create_tasks","tool_call_id":"..."}
+//	event: tool_end    {"type":"tool_end","round":1,"tool":"create_tasks","tool_call_id":"...","side_effects":[...]}
+//	event: done        {"reply":"...","message_id":"...","side_effects":[...]}
+//	event: error       {"error":"...","status":502}
+//
+// The stream always ends with exactly one done or error event.
+// ==========================================
+
+const (
+	// A streamed reply keeps the connection busy, so it can afford more tool
+	// rounds than the 55s of SendMessageV2.
+	streamTimeout = 90 * time.Second
+
+	// Comment lines keep proxies from closing an idle stream during long
+	// LLM round trips.
+	streamHeartbeat = 15 * time.Second
+)
+
+type streamErrorEvent struct {
+	Error  string `json:"error"`
+	Status int    `json:"status"`
+}
+
+type streamResult struct {
+	reply *chatReply
+	err   error
+}
+
+// StreamMessageV2 handles POST /chat/v2/message/stream — SendMessageV2 over SSE.
+func (h *Handler) StreamMessageV2(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(auth.UserContextKey).(string)
+	if !ok || userID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req BackboardSendMessageRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if req.Content == "" {
+		http.Error(w, "Content is required", http.StatusBadRequest)
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	ctx, cancel := context.WithTimeout(r.Context(), streamTimeout)
+	defer cancel()
+
+	// The pipeline runs in its own goroutine so heartbeats can be written
+	// while it waits on the model. Only this goroutine writes to w.
+	events := make(chan backboard.StreamEvent, 16)
+	result := make(chan streamResult, 1)
+	sink := func(ev backboard.StreamEvent) {
+		select {
+		case events <- ev:
+		case <-ctx.Done():
+		}
+	}
+	go func() {
+		reply, err := h.runChat(ctx, transcriptMessage{userID: userID, content: req.Content, fromUser: true, source: req.Source}, req.DeviceContext, sink)
+		result <- streamResult{reply: reply, err: err}
+	}()
+
+	heartbeat := time.NewTicker(streamHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case ev := <-events:
+			writeSSE(w, flusher, ev.Type, ev)
+
+		case <-heartbeat.C:
+			fmt.Fprint(w, ": ping\n\n")
+			flusher.Flush()
+
+		case res := <-result:
+			// Events sent just before the pipeline returned come first
+			for drained := false; !drained; {
+				select {
+				case ev := <-events:
+					writeSSE(w, flusher, ev.Type, ev)
+				default:
+					drained = true
+				}
+			}
+
+			if res.err != nil {
+				status, message := http.StatusBadGateway, "AI service error"
+				var pe *pipelineError
+				if errors.As(res.err, &pe) {
+					status, message = pe.status, pe.message
+				}
+				writeSSE(w, flusher, "error", streamErrorEvent{Error: message, Status: status})
+				return
+			}
+			writeSSE(w, flusher, "done", res.reply.response())
+			return
+
+		case <-r.Context().Done():
+			// runChat stops on its own since ctx derives from r.Context()
+			log.Printf("📡 Chat stream closed by client for user %s", userID)
+			return
+		}
+	}
+}
+
+// writeSSE writes one event and flushes it to the client.
+func writeSSE(w http.ResponseWriter, flusher http.Flusher, event string, data interface{}) {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		log.Printf("Failed to marshal SSE %s event: %v", event, err)
+		return
+	}
+	fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, payload)
+	flusher.Flush()
+}
@@ -0,0 +1,140 @@
+package chat
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"firelevel-backend/internal/llm"
+	"firelevel-backend/internal/tools"
+	"firelevel-backend/internal/userclock"
+)
+
+type echoArgs struct {
+	Text string `json:"text" required:"true"`
+}
+
+// testRegistry has one tool that runs right away and one that needs the
+// user's approval. Neither touches the database.
+func testRegistry(t *testing.T) *tools.Registry {
+	t.Helper()
+	reg := tools.NewRegistry()
+	err := reg.Register(
+		tools.Func[echoArgs]{
+			Name:        "echo",
+			Description: "Répète le texte.",
+			Run: func(ctx context.Context, call *tools.Call, args echoArgs) (tools.Result, error) {
+				return tools.Result{Output: map[string]string{"echo": args.Text}}, nil
+			},
+		},
+		tools.Func[tools.NoArgs]{
+			Name:        "wipe_everything",
+			Description: "Supprime tout.",
+			Policy:      tools.PolicyConfirm,
+			Run: func(ctx context.Context, call *tools.Call, _ tools.NoArgs) (tools.Result, error) {
+				t.Error("confirm tool ran")
+				return tools.Result{}, nil
+			},
+		},
+	)
+	if err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	return reg
+}
+
+// recordingProvider is a Scripted provider that keeps the conversation it
+// was sent.
+type recordingProvider struct {
+	*llm.Scripted
+	conv *llm.Conversation
+}
+
+func (p *recordingProvider) Send(ctx context.Context, conv *llm.Conversation, content string) (*llm.Response, error) {
+	p.conv = conv
+	return p.Scripted.Send(ctx, conv, content)
+}
+
+func testUserInfo() *UserInfo {
+	return &UserInfo{
+		Name:          "Léa",
+		CompanionName: "Kai",
+		Clock:         userclock.At(time.Date(2026, 10, 16, 9, 30, 0, 0, time.UTC), "Europe/Paris"),
+	}
+}
+
+func TestGenerateResponseRunsToolLoop(t *testing.T) {
+	provider := &recordingProvider{Scripted: llm.NewScripted(
+		llm.Response{RunID: "run1", ToolCalls: []llm.ToolCall{
+			{ID: "call1", Name: "echo", Arguments: `{"text":"bonjour"}`},
+			{ID: "call2", Name: "not_a_tool", Arguments: `{}`},
+		}},
+		llm.Response{Content: "```json\n{\"reply\": \"C'est noté 💪\"}\n```"},
+	)}
+	h := &Handler{llmProvider: provider, toolRegistry: testRegistry(t)}
+
+	history := []ChatMessage{
+		{Content: "Salut Kai", IsFromUser: true},
+		{Content: "Salut Léa !", IsFromUser: false},
+	}
+	resp, err := h.generateResponse(context.Background(), "user-1", "Je vais courir", testUserInfo(), nil, history)
+	if err != nil {
+		t.Fatalf("generateResponse: %v", err)
+	}
+	if resp.Reply != "C'est noté 💪" {
+		t.Errorf("Reply = %q", resp.Reply)
+	}
+
+	sent := provider.Sent()
+	if len(sent) != 1 || !strings.Contains(sent[0], "MESSAGE: Je vais courir") {
+		t.Errorf("sent = %q", sent)
+	}
+
+	// Tool results, errors included, go back to the model
+	outputs := provider.Outputs()
+	if len(outputs) != 1 || len(outputs[0]) != 2 {
+		t.Fatalf("outputs = %+v", outputs)
+	}
+	if outputs[0][0].ToolCallID != "call1" || outputs[0][0].Output != `{"echo":"bonjour"}` {
+		t.Errorf("echo output = %+v", outputs[0][0])
+	}
+	if outputs[0][1].ToolCallID != "call2" || !strings.Contains(outputs[0][1].Output, "unknown tool") {
+		t.Errorf("unknown tool output = %+v", outputs[0][1])
+	}
+
+	conv := provider.conv
+	if conv.UserID != "user-1" || !strings.Contains(conv.SystemPrompt, "Kai") {
+		t.Errorf("conversation = %+v", conv)
+	}
+	if len(conv.History) != 2 || conv.History[0].Role != llm.RoleUser || conv.History[1].Role != llm.RoleAssistant {
+		t.Errorf("history = %+v", conv.History)
+	}
+	// The confirm tool is left out: the legacy endpoints can't ask
+	if len(conv.Tools) != 1 || conv.Tools[0].Name != "echo" {
+		t.Errorf("tools = %+v", conv.Tools)
+	}
+}
+
+func TestGenerateResponseKeepsPlainText(t *testing.T) {
+	h := &Handler{llmProvider: llm.NewScripted(llm.Response{Content: "Pas du JSON"}), toolRegistry: testRegistry(t)}
+
+	resp, err := h.generateResponse(context.Background(), "user-1", "Salut", testUserInfo(), nil, nil)
+	if err != nil {
+		t.Fatalf("generateResponse: %v", err)
+	}
+	if resp.Reply != "Pas du JSON" {
+		t.Errorf("Reply = %q", resp.Reply)
+	}
+}
+
+func TestLegacyHistoryKeepsLastTen(t *testing.T) {
+	var history []ChatMessage
+	for i := 0; i < 15; i++ {
+		history = append(history, ChatMessage{Content: string(rune('a' + i)), IsFromUser: i%2 == 0})
+	}
+	got := legacyHistory(history)
+	if len(got) != 10 || got[0].Content != "f" || got[9].Content != "o" {
+		t.Errorf("history = %+v", got)
+	}
+}
@@ -0,0 +1,402 @@
+package chat
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"firelevel-backend/internal/auth"
+	"firelevel-backend/internal/backboard"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// ==========================================
+// Memories
+// ==========================================
+// What Kai remembers about the user lives on their Backboard assistant
+// (save_memory writes there). These endpoints let the user read, correct,
+// export and wipe it. Categories are those of SemanticMemory.
+// ==========================================
+
+const maxMemoryLength = 1000 // Runes
+
+// MemoryItem is a memory as the app shows it.
+type MemoryItem struct {
+	ID        string `json:"id"`
+	Category  string `json:"category"` // personal, work, goals, preferences
+	Content   string `json:"content"`
+	CreatedAt string `json:"created_at,omitempty"`
+	UpdatedAt string `json:"updated_at,omitempty"`
+}
+
+type MemoryRequest struct {
+	Category string `json:"category"`
+	Content  string `json:"content"`
+}
+
+type MemoryUpdateRequest struct {
+	Category *string `json:"category"`
+	Content  *string `json:"content"`
+}
+
+type MemoriesResponse struct {
+	Memories   []MemoryItem `json:"memories"`
+	Categories []string     `json:"categories"`
+}
+
+type MemoriesExport struct {
+	ExportedAt string       `json:"exported_at"`
+	Memories   []MemoryItem `json:"memories"`
+}
+
+func toMemoryItem(m backboard.Memory) MemoryItem {
+	category, content := backboard.ParseMemory(m.Content)
+	return MemoryItem{
+		ID:        m.ID,
+		Category:  category,
+		Content:   content,
+		CreatedAt: m.CreatedAt,
+		UpdatedAt: m.UpdatedAt,
+	}
+}
+
+// validateMemory normalizes a category/content pair coming from the app.
+func validateMemory(category, content string) (string, string, error) {
+	c, ok := backboard.NormalizeMemoryCategory(category)
+	if !ok {
+		return "", "", fmt.Errorf("category must be one of %s", strings.Join(backboard.MemoryCategories, ", "))
+	}
+	content = strings.TrimSpace(content)
+	if content == "" {
+		return "", "", errors.New("content is required")
+	}
+	if utf8.RuneCountInString(content) > maxMemoryLength {
+		return "", "", fmt.Errorf("content is limited to %d characters", maxMemoryLength)
+	}
+	return c, content, nil
+}
+
+// userAssistantID returns the user's Backboard assistant, "" if they have
+// none yet.
+func (h *Handler) userAssistantID(ctx context.Context, userID string) (string, error) {
+	var assistantID *string
+	if err := h.db.QueryRow(ctx, "SELECT backboard_assistant_id FROM public.users WHERE id = $1", userID).Scan(&assistantID); err != nil {
+		return "", err
+	}
+	if assistantID == nil {
+		return "", nil
+	}
+	return *assistantID, nil
+}
+
+// listMemories returns the user's memories, newest first.
+func (h *Handler) listMemories(ctx context.Context, userID string, bbClient *backboard.Client) ([]MemoryItem, error) {
+	items := []MemoryItem{}
+	assistantID, err := h.userAssistantID(ctx, userID)
+	if err != nil || assistantID == "" {
+		return items, err
+	}
+	memories, err := bbClient.ListMemories(ctx, assistantID)
+	if err != nil {
+		return nil, err
+	}
+	for _, m := range memories {
+		items = append(items, toMemoryItem(m))
+	}
+	sort.SliceStable(items, func(i, j int) bool { return items[i].CreatedAt > items[j].CreatedAt })
+	return items, nil
+}
+
+// memoryClient returns the Backboard client, answering 503 when it isn't
+// configured.
+func (h *Handler) memoryClient(w http.ResponseWriter) *backboard.Client {
+	bbClient := h.getBackboardClient()
+	if bbClient == nil {
+		http.Error(w, "AI service not configured", http.StatusServiceUnavailable)
+	}
+	return bbClient
+}
+
+// ListMemories returns what Kai remembers about the user
+// GET /chat/memories?category=work
+func (h *Handler) ListMemories(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(auth.UserContextKey).(string)
+	bbClient := h.memoryClient(w)
+	if bbClient == nil {
+		return
+	}
+
+	var category string
+	if c := r.URL.Query().Get("category"); c != "" {
+		var ok bool
+		if category, ok = backboard.NormalizeMemoryCategory(c); !ok {
+			http.Error(w, "Invalid category", http.StatusBadRequest)
+			return
+		}
+	}
+
+	items, err := h.listMemories(r.Context(), userID, bbClient)
+	if err != nil {
+		log.Printf("Failed to list memories for user %s: %v", userID, err)
+		http.Error(w, "Failed to list memories", http.StatusBadGateway)
+		return
+	}
+	if category != "" {
+		filtered := []MemoryItem{}
+		for _, item := range items {
+			if item.Category == category {
+				filtered = append(filtered, item)
+			}
+		}
+		items = filtered
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(MemoriesResponse{Memories: items, Categories: backboard.MemoryCategories})
+}
+
+// ExportMemories downloads every memory as a JSON file
+// GET /chat/memories/export
+func (h *Handler) ExportMemories(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(auth.UserContextKey).(string)
+	bbClient := h.memoryClient(w)
+	if bbClient == nil {
+		return
+	}
+
+	items, err := h.listMemories(r.Context(), userID, bbClient)
+	if err != nil {
+		log.Printf("Failed to export memories for user %s: %v", userID, err)
+		http.Error(w, "Failed to export memories", http.StatusBadGateway)
+		return
+	}
+
+	now := time.Now().UTC()
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="memories-%s.json"`, now.Format("2006-01-02")))
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.Encode(MemoriesExport{ExportedAt: now.Format(time.RFC3339), Memories: items})
+}
+
+// CreateMemory teaches Kai something about the user
+// POST /chat/memories
+func (h *Handler) CreateMemory(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(auth.UserContextKey).(string)
+	bbClient := h.memoryClient(w)
+	if bbClient == nil {
+		return
+	}
+
+	var req MemoryRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	category, content, err := validateMemory(req.Category, req.Content)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	assistantID, err := h.ensureBackboardAssistant(r.Context(), userID, bbClient)
+	if err != nil {
+		log.Printf("Failed to setup assistant for memory, user %s: %v", userID, err)
+		http.Error(w, "Failed to save memory", http.StatusBadGateway)
+		return
+	}
+	memory, err := bbClient.CreateMemory(r.Context(), assistantID, backboard.FormatMemory(category, content))
+	if err != nil {
+		log.Printf("Failed to create memory for user %s: %v", userID, err)
+		http.Error(w, "Failed to save memory", http.StatusBadGateway)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(toMemoryItem(*memory))
+}
+
+// UpdateMemory corrects a memory. Backboard can't edit a memory in place,
+// so it is saved again and the old one deleted: the ID changes.
+// PATCH /chat/memories/{id}
+func (h *Handler) UpdateMemory(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(auth.UserContextKey).(string)
+	memoryID := chi.URLParam(r, "id")
+	bbClient := h.memoryClient(w)
+	if bbClient == nil {
+		return
+	}
+
+	var req MemoryUpdateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	items, err := h.listMemories(r.Context(), userID, bbClient)
+	if err != nil {
+		log.Printf("Failed to load memories for user %s: %v", userID, err)
+		http.Error(w, "Failed to update memory", http.StatusBadGateway)
+		return
+	}
+	var current *MemoryItem
+	for i := range items {
+		if items[i].ID == memoryID {
+			current = &items[i]
+			break
+		}
+	}
+	if current == nil {
+		http.Error(w, "Memory not found", http.StatusNotFound)
+		return
+	}
+
+	category, content := current.Category, current.Content
+	if req.Category != nil {
+		category = *req.Category
+	}
+	if req.Content != nil {
+		content = *req.Content
+	}
+	category, content, err = validateMemory(category, content)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if category == current.Category && content == current.Content {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(current)
+		return
+	}
+
+	assistantID, err := h.userAssistantID(r.Context(), userID)
+	if err != nil {
+		log.Printf("Failed to fetch assistant for user %s: %v", userID, err)
+		http.Error(w, "Failed to update memory", http.StatusInternalServerError)
+		return
+	}
+	// Save the new version first: a failure then leaves the old one intact
+	memory, err := bbClient.CreateMemory(r.Context(), assistantID, backboard.FormatMemory(category, content))
+	if err != nil {
+		log.Printf("Failed to save edited memory %s for user %s: %v", memoryID, userID, err)
+		http.Error(w, "Failed to update memory", http.StatusBadGateway)
+		return
+	}
+	if err := bbClient.DeleteMemory(r.Context(), assistantID, memoryID); err != nil && !errors.Is(err, backboard.ErrNotFound) {
+		log.Printf("Failed to delete replaced memory %s for user %s: %v", memoryID, userID, err)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(toMemoryItem(*memory))
+}
+
+// DeleteMemory makes Kai forget one thing
+// DELETE /chat/memories/{id}
+func (h *Handler) DeleteMemory(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(auth.UserContextKey).(string)
+	memoryID := chi.URLParam(r, "id")
+	bbClient := h.memoryClient(w)
+	if bbClient == nil {
+		return
+	}
+
+	assistantID, err := h.userAssistantID(r.Context(), userID)
+	if err != nil {
+		log.Printf("Failed to fetch assistant for user %s: %v", userID, err)
+		http.Error(w, "Failed to delete memory", http.StatusInternalServerError)
+		return
+	}
+	if assistantID == "" {
+		http.Error(w, "Memory not found", http.StatusNotFound)
+		return
+	}
+
+	err = bbClient.DeleteMemory(r.Context(), assistantID, memoryID)
+	switch {
+	case errors.Is(err, backboard.ErrNotFound):
+		http.Error(w, "Memory not found", http.StatusNotFound)
+		return
+	case err != nil:
+		log.Printf("Failed to delete memory %s for user %s: %v", memoryID, userID, err)
+		http.Error(w, "Failed to delete memory", http.StatusBadGateway)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ForgetMemories makes Kai forget everything about the user
+// DELETE /chat/memories
+func (h *Handler) ForgetMemories(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(auth.UserContextKey).(string)
+	bbClient := h.memoryClient(w)
+	if bbClient == nil {
+		return
+	}
+
+	deleted, err := h.forgetEverything(r.Context(), userID, bbClient)
+	if err != nil {
+		log.Printf("Failed to forget memories for user %s (%d deleted): %v", userID, deleted, err)
+		http.Error(w, "Failed to delete memories", http.StatusBadGateway)
+		return
+	}
+	log.Printf("🧹 Forgot %d memories for user %s", deleted, userID)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]int{"deleted": deleted})
+}
+
+// forgetEverything deletes every memory of the user's assistant and
+// returns how many went.
+func (h *Handler) forgetEverything(ctx context.Context, userID string, bbClient *backboard.Client) (int, error) {
+	assistantID, err := h.userAssistantID(ctx, userID)
+	if err != nil || assistantID == "" {
+		return 0, err
+	}
+	memories, err := bbClient.ListMemories(ctx, assistantID)
+	if err != nil {
+		return 0, err
+	}
+
+	deleted := 0
+	var firstErr error
+	for _, m := range memories {
+		if err := bbClient.DeleteMemory(ctx, assistantID, m.ID); err != nil && !errors.Is(err, backboard.ErrNotFound) {
+			if firstErr == nil {
+				firstErr = fmt.Errorf("delete memory %s: %w", m.ID, err)
+			}
+			continue
+		}
+		deleted++
+	}
+	return deleted, firstErr
+}
+
+// ForgetUser erases what Kai knows about a user whose account is being
+// deleted: every memory, then the assistant with its threads.
+func (h *Handler) ForgetUser(ctx context.Context, userID string) error {
+	bbClient := h.getBackboardClient()
+	if bbClient == nil {
+		return nil
+	}
+	if _, err := h.forgetEverything(ctx, userID, bbClient); err != nil {
+		return err
+	}
+	assistantID, err := h.userAssistantID(ctx, userID)
+	if err != nil || assistantID == "" {
+		return err
+	}
+	if err := bbClient.DeleteAssistant(ctx, assistantID); err != nil && !errors.Is(err, backboard.ErrNotFound) {
+		return fmt.Errorf("delete assistant: %w", err)
+	}
+	return nil
+}
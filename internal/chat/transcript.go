@@ -0,0 +1,406 @@
+package chat
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"firelevel-backend/internal/auth"
+	"firelevel-backend/internal/backboard"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// ==========================================
+// Chat transcript (public.chat_messages)
+// ==========================================
+// Every /chat/v2 turn is written here, so history survives Backboard thread
+// resets and is served from Postgres instead of the vendor.
+// ==========================================
+
+const (
+	historyDefaultLimit = 50
+	historyMaxLimit     = 100
+	searchDefaultLimit  = 20
+)
+
+// transcriptMessage is one row to append to chat_messages.
+type transcriptMessage struct {
+	userID          string
+	content         string
+	fromUser        bool
+	messageType     string // text, voice
+	source          string // app, web, whatsapp (coach: proactive messages)
+	voiceTranscript string
+	sideEffects     []backboard.SideEffect
+}
+
+// saveTranscriptMessage appends a message and returns its ID.
+func (h *Handler) saveTranscriptMessage(ctx context.Context, m transcriptMessage) (string, error) {
+	if m.messageType == "" {
+		m.messageType = "text"
+	}
+	if m.source == "" {
+		m.source = "app"
+	}
+	effects := m.sideEffects
+	if effects == nil {
+		effects = []backboard.SideEffect{}
+	}
+	effectsJSON, err := json.Marshal(effects)
+	if err != nil {
+		return "", fmt.Errorf("marshal side effects: %w", err)
+	}
+
+	var id string
+	err = h.db.QueryRow(ctx, `
+		INSERT INTO public.chat_messages
+			(user_id, content, is_from_user, message_type, source, voice_transcript, side_effects)
+		VALUES ($1, $2, $3, $4, $5, NULLIF($6, ''), $7)
+		RETURNING id
+	`, m.userID, m.content, m.fromUser, m.messageType, m.source, m.voiceTranscript, effectsJSON).Scan(&id)
+	return id, err
+}
+
+// recordTranscriptMessage saves a message, logging failures: losing a
+// transcript row must not fail the chat turn itself.
+func (h *Handler) recordTranscriptMessage(ctx context.Context, m transcriptMessage) string {
+	id, err := h.saveTranscriptMessage(ctx, m)
+	if err != nil {
+		log.Printf("⚠️ Failed to save chat message for user %s: %v", m.userID, err)
+		return ""
+	}
+	return id
+}
+
+// ==========================================
+// Cursors
+// ==========================================
+
+// A history cursor points at the oldest message of the previous page:
+// base64url("<created_at RFC3339Nano>|<id>").
+
+func encodeHistoryCursor(createdAt time.Time, id string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(createdAt.UTC().Format(time.RFC3339Nano) + "|" + id))
+}
+
+func decodeHistoryCursor(cursor string) (time.Time, string, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return time.Time{}, "", err
+	}
+	ts, id, ok := strings.Cut(string(raw), "|")
+	if !ok || id == "" {
+		return time.Time{}, "", errors.New("malformed cursor")
+	}
+	createdAt, err := time.Parse(time.RFC3339Nano, ts)
+	if err != nil {
+		return time.Time{}, "", err
+	}
+	return createdAt, id, nil
+}
+
+// limitParam reads ?limit=, falling back to def and capping at max.
+func limitParam(r *http.Request, def, max int) int {
+	n, err := strconv.Atoi(r.URL.Query().Get("limit"))
+	if err != nil || n <= 0 {
+		return def
+	}
+	if n > max {
+		return max
+	}
+	return n
+}
+
+// ==========================================
+// History
+// ==========================================
+
+// GetHistoryV2 handles GET /chat/v2/history?limit=&before= — newest page
+// first, messages in each page in chronological order. Pass next_cursor as
+// before to load older messages.
+func (h *Handler) GetHistoryV2(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(auth.UserContextKey).(string)
+	if !ok || userID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	ctx := r.Context()
+	limit := limitParam(r, historyDefaultLimit, historyMaxLimit)
+
+	var rows pgx.Rows
+	var err error
+	if before := r.URL.Query().Get("before"); before != "" {
+		createdAt, id, cerr := decodeHistoryCursor(before)
+		if cerr != nil {
+			http.Error(w, "Invalid cursor", http.StatusBadRequest)
+			return
+		}
+		rows, err = h.db.Query(ctx, `
+			SELECT id, content, is_from_user, COALESCE(source, 'app'), side_effects, created_at
+			FROM public.chat_messages
+			WHERE user_id = $1 AND (created_at, id) < ($2, $3::uuid)
+			ORDER BY created_at DESC, id DESC
+			LIMIT $4
+		`, userID, createdAt, id, limit+1)
+	} else {
+		// Users who chatted before transcripts existed still have their
+		// conversation on Backboard: import it once.
+		h.importBackboardThread(ctx, userID)
+
+		rows, err = h.db.Query(ctx, `
+			SELECT id, content, is_from_user, COALESCE(source, 'app'), side_effects, created_at
+			FROM public.chat_messages
+			WHERE user_id = $1
+			ORDER BY created_at DESC, id DESC
+			LIMIT $2
+		`, userID, limit+1)
+	}
+	if err != nil {
+		log.Printf("Failed to load chat history for user %s: %v", userID, err)
+		http.Error(w, "Failed to load history", http.StatusInternalServerError)
+		return
+	}
+
+	messages, err := scanHistoryMessages(rows)
+	if err != nil {
+		log.Printf("Failed to scan chat history for user %s: %v", userID, err)
+		http.Error(w, "Failed to load history", http.StatusInternalServerError)
+		return
+	}
+
+	resp := BackboardHistoryResponse{Messages: []BackboardHistoryMessage{}}
+	if len(messages) > limit {
+		messages = messages[:limit]
+		oldest := messages[len(messages)-1]
+		resp.NextCursor = encodeHistoryCursor(oldest.createdAt, oldest.MessageID)
+	}
+	for i := len(messages) - 1; i >= 0; i-- {
+		resp.Messages = append(resp.Messages, messages[i].BackboardHistoryMessage)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+type historyRow struct {
+	BackboardHistoryMessage
+	createdAt time.Time
+}
+
+func scanHistoryMessages(rows pgx.Rows) ([]historyRow, error) {
+	defer rows.Close()
+
+	var messages []historyRow
+	for rows.Next() {
+		var m historyRow
+		var fromUser *bool
+		var effects []byte
+		if err := rows.Scan(&m.MessageID, &m.Content, &fromUser, &m.Source, &effects, &m.createdAt); err != nil {
+			return nil, err
+		}
+		m.Role = "assistant"
+		if fromUser == nil || *fromUser {
+			m.Role = "user"
+		}
+		m.CreatedAt = m.createdAt.UTC().Format(time.RFC3339)
+		if len(effects) > 0 {
+			json.Unmarshal(effects, &m.SideEffects)
+		}
+		messages = append(messages, m)
+	}
+	return messages, rows.Err()
+}
+
+// importBackboardThread copies the user's Backboard thread into chat_messages
+// once, then sets users.chat_history_imported_at. Turns already recorded in
+// the transcript (sent after it existed) are not copied twice.
+func (h *Handler) importBackboardThread(ctx context.Context, userID string) {
+	bbClient := h.getBackboardClient()
+	if bbClient == nil {
+		return
+	}
+
+	var importedAt *time.Time
+	var threadID *string
+	if err := h.db.QueryRow(ctx, `
+		SELECT chat_history_imported_at, backboard_thread_id FROM public.users WHERE id = $1
+	`, userID).Scan(&importedAt, &threadID); err != nil {
+		log.Printf("Failed to check chat transcript import for user %s: %v", userID, err)
+		return
+	}
+	if importedAt != nil {
+		return
+	}
+
+	var thread *backboard.Thread
+	if threadID != nil && *threadID != "" {
+		var err error
+		thread, err = bbClient.GetThread(ctx, *threadID)
+		if err != nil {
+			log.Printf("⚠️ GetThread failed for user %s: %v", userID, err)
+			return
+		}
+	}
+
+	imported, err := h.saveImportedThread(ctx, userID, thread)
+	if err != nil {
+		log.Printf("Failed to import Backboard thread for user %s: %v", userID, err)
+		return
+	}
+	if imported > 0 {
+		log.Printf("📥 Imported %d Backboard messages into chat_messages for user %s", imported, userID)
+	}
+}
+
+// saveImportedThread writes the thread messages and the import flag in one
+// transaction, so a failed import is retried whole on the next history load.
+func (h *Handler) saveImportedThread(ctx context.Context, userID string, thread *backboard.Thread) (int, error) {
+	tx, err := h.db.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("begin: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// Concurrent history loads: the first one imports, the others see the flag
+	var importedAt *time.Time
+	if err := tx.QueryRow(ctx, `
+		SELECT chat_history_imported_at FROM public.users WHERE id = $1 FOR UPDATE
+	`, userID).Scan(&importedAt); err != nil {
+		return 0, fmt.Errorf("lock user: %w", err)
+	}
+	if importedAt != nil {
+		return 0, nil
+	}
+
+	// Messages from this point on were recorded live
+	var firstRecorded *time.Time
+	if err := tx.QueryRow(ctx, `
+		SELECT MIN(created_at) FROM public.chat_messages WHERE user_id = $1
+	`, userID).Scan(&firstRecorded); err != nil {
+		return 0, fmt.Errorf("find first transcript message: %w", err)
+	}
+	anchor := time.Now()
+	if firstRecorded != nil {
+		anchor = *firstRecorded
+	}
+
+	imported := 0
+	if thread != nil {
+		for i, msg := range thread.Messages {
+			if msg.Content == nil || *msg.Content == "" {
+				continue
+			}
+			if msg.Role != "user" && msg.Role != "assistant" {
+				continue
+			}
+			// Keep the thread order when Backboard has no usable timestamp
+			createdAt := anchor.Add(time.Duration(i-len(thread.Messages)) * time.Millisecond)
+			if msg.CreatedAt != nil {
+				if t, err := time.Parse(time.RFC3339Nano, *msg.CreatedAt); err == nil {
+					createdAt = t
+				}
+			}
+			if !createdAt.Before(anchor) {
+				continue
+			}
+			if _, err := tx.Exec(ctx, `
+				INSERT INTO public.chat_messages (user_id, content, is_from_user, message_type, source, created_at)
+				VALUES ($1, $2, $3, 'text', 'app', $4)
+			`, userID, *msg.Content, msg.Role == "user", createdAt); err != nil {
+				return 0, fmt.Errorf("insert message: %w", err)
+			}
+			imported++
+		}
+	}
+
+	if _, err := tx.Exec(ctx, `
+		UPDATE public.users SET chat_history_imported_at = NOW() WHERE id = $1
+	`, userID); err != nil {
+		return 0, fmt.Errorf("set import flag: %w", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("commit: %w", err)
+	}
+	return imported, nil
+}
+
+// ==========================================
+// Search
+// ==========================================
+
+type ChatSearchResult struct {
+	BackboardHistoryMessage
+	Snippet string  `json:"snippet"`
+	Rank    float32 `json:"rank"`
+}
+
+type ChatSearchResponse struct {
+	Results []ChatSearchResult `json:"results"`
+}
+
+// SearchHistoryV2 handles GET /chat/v2/search?q=&limit= — full-text search
+// over the user's past conversations, best matches first.
+func (h *Handler) SearchHistoryV2(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(auth.UserContextKey).(string)
+	if !ok || userID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	q := strings.TrimSpace(r.URL.Query().Get("q"))
+	if q == "" {
+		http.Error(w, "q is required", http.StatusBadRequest)
+		return
+	}
+	limit := limitParam(r, searchDefaultLimit, historyMaxLimit)
+
+	rows, err := h.db.Query(r.Context(), `
+		SELECT id, content, is_from_user, COALESCE(source, 'app'), side_effects, created_at,
+		       ts_headline('simple', content, query, 'StartSel=**, StopSel=**, MaxFragments=2, MaxWords=20, MinWords=8'),
+		       ts_rank(search_vector, query)
+		FROM public.chat_messages, websearch_to_tsquery('simple', $2) AS query
+		WHERE user_id = $1 AND search_vector @@ query
+		ORDER BY ts_rank(search_vector, query) DESC, created_at DESC
+		LIMIT $3
+	`, userID, q, limit)
+	if err != nil {
+		log.Printf("Chat search failed for user %s: %v", userID, err)
+		http.Error(w, "Search failed", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	resp := ChatSearchResponse{Results: []ChatSearchResult{}}
+	for rows.Next() {
+		var res ChatSearchResult
+		var fromUser *bool
+		var effects []byte
+		var createdAt time.Time
+		if err := rows.Scan(&res.MessageID, &res.Content, &fromUser, &res.Source, &effects, &createdAt, &res.Snippet, &res.Rank); err != nil {
+			log.Printf("Chat search scan failed: %v", err)
+			http.Error(w, "Search failed", http.StatusInternalServerError)
+			return
+		}
+		res.Role = "assistant"
+		if fromUser == nil || *fromUser {
+			res.Role = "user"
+		}
+		res.CreatedAt = createdAt.UTC().Format(time.RFC3339)
+		if len(effects) > 0 {
+			json.Unmarshal(effects, &res.SideEffects)
+		}
+		resp.Results = append(resp.Results, res)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
@@ -0,0 +1,339 @@
+package checkins
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"firelevel-backend/internal/calendar"
+	"firelevel-backend/internal/userclock"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// ===========================================
+// DAILY CHECK-INS
+// One morning check-in and one evening review per
+// local day, in morning_checkins / evening_checkins.
+// The REST API, the chat tools and /reflections all
+// go through the functions below.
+//
+// Saves merge: a nil field keeps the stored value,
+// an empty string clears a text field.
+// ===========================================
+
+// ErrNotFound is returned when there is no check-in for the date.
+var ErrNotFound = errors.New("check-in not found")
+
+// Morning is a morning check-in.
+type Morning struct {
+	ID           string    `json:"id"`
+	Date         string    `json:"date"`
+	Mood         int       `json:"mood"` // 1-5
+	SleepQuality *int      `json:"sleep_quality"`
+	EnergyLevel  *int      `json:"energy_level"`
+	Intentions   []string  `json:"intentions"`
+	TopPriority  *string   `json:"top_priority"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// MorningInput is what a caller saves. Mood is required.
+type MorningInput struct {
+	Mood         int      `json:"mood"`
+	SleepQuality *int     `json:"sleep_quality"`
+	EnergyLevel  *int     `json:"energy_level"`
+	Intentions   []string `json:"intentions"` // nil: keep
+	TopPriority  *string  `json:"top_priority"`
+}
+
+// Validate checks the 1-5 scales.
+func (in MorningInput) Validate() error {
+	if in.Mood < 1 || in.Mood > 5 {
+		return errors.New("mood must be between 1 and 5")
+	}
+	if !optionalScale(in.SleepQuality) {
+		return errors.New("sleep_quality must be between 1 and 5")
+	}
+	if !optionalScale(in.EnergyLevel) {
+		return errors.New("energy_level must be between 1 and 5")
+	}
+	return nil
+}
+
+// Evening is an evening review. The completion counts are a snapshot of
+// the day taken when the review is saved.
+type Evening struct {
+	ID               string    `json:"id"`
+	Date             string    `json:"date"`
+	Mood             *int      `json:"mood"` // 1-5
+	BiggestWin       *string   `json:"biggest_win"`
+	Blockers         *string   `json:"blockers"`
+	BestMoment       *string   `json:"best_moment"`
+	GoalForTomorrow  *string   `json:"goal_for_tomorrow"`
+	GratefulFor      *string   `json:"grateful_for"`
+	RitualsCompleted int       `json:"rituals_completed"`
+	TasksCompleted   int       `json:"tasks_completed"`
+	FocusMinutes     int       `json:"focus_minutes"`
+	CreatedAt        time.Time `json:"created_at"`
+	UpdatedAt        time.Time `json:"updated_at"`
+}
+
+// EveningInput is what a caller saves; every field is optional.
+type EveningInput struct {
+	Mood            *int    `json:"mood"`
+	BiggestWin      *string `json:"biggest_win"`
+	Blockers        *string `json:"blockers"`
+	BestMoment      *string `json:"best_moment"`
+	GoalForTomorrow *string `json:"goal_for_tomorrow"`
+	GratefulFor     *string `json:"grateful_for"`
+}
+
+// Validate checks the mood scale.
+func (in EveningInput) Validate() error {
+	if !optionalScale(in.Mood) {
+		return errors.New("mood must be between 1 and 5")
+	}
+	return nil
+}
+
+func optionalScale(v *int) bool {
+	return v == nil || (*v >= 1 && *v <= 5)
+}
+
+// ===========================================
+// Morning
+// ===========================================
+
+const morningColumns = `id, date, morning_mood, sleep_quality, energy_level,
+	COALESCE(intentions, '[]'::jsonb), top_priority, created_at, updated_at`
+
+func scanMorning(row pgx.Row) (*Morning, error) {
+	var m Morning
+	var date time.Time
+	if err := row.Scan(&m.ID, &date, &m.Mood, &m.SleepQuality, &m.EnergyLevel,
+		&m.Intentions, &m.TopPriority, &m.CreatedAt, &m.UpdatedAt); err != nil {
+		return nil, err
+	}
+	m.Date = date.Format(userclock.DateLayout)
+	if m.Intentions == nil {
+		m.Intentions = []string{}
+	}
+	return &m, nil
+}
+
+// GetMorning returns the morning check-in of date.
+func GetMorning(ctx context.Context, db *pgxpool.Pool, userID, date string) (*Morning, error) {
+	m, err := scanMorning(db.QueryRow(ctx, `
+		SELECT `+morningColumns+`
+		FROM public.morning_checkins WHERE user_id = $1 AND date = $2::date
+	`, userID, date))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	return m, err
+}
+
+// SaveMorning creates or updates the morning check-in of date.
+func SaveMorning(ctx context.Context, db *pgxpool.Pool, userID, date string, in MorningInput) (*Morning, error) {
+	if err := in.Validate(); err != nil {
+		return nil, err
+	}
+	var intentions interface{}
+	if in.Intentions != nil {
+		intentions = in.Intentions
+	}
+	return scanMorning(db.QueryRow(ctx, `
+		INSERT INTO public.morning_checkins
+			(user_id, date, morning_mood, sleep_quality, energy_level, intentions, top_priority)
+		VALUES ($1, $2::date, $3, $4, $5, COALESCE($6::jsonb, '[]'::jsonb), NULLIF($7, ''))
+		ON CONFLICT (user_id, date) DO UPDATE SET
+			morning_mood  = EXCLUDED.morning_mood,
+			sleep_quality = COALESCE($4, morning_checkins.sleep_quality),
+			energy_level  = COALESCE($5, morning_checkins.energy_level),
+			intentions    = COALESCE($6::jsonb, morning_checkins.intentions),
+			top_priority  = CASE WHEN $7::text IS NULL THEN morning_checkins.top_priority ELSE NULLIF($7, '') END,
+			updated_at    = NOW()
+		RETURNING `+morningColumns,
+		userID, date, in.Mood, in.SleepQuality, in.EnergyLevel, intentions, in.TopPriority))
+}
+
+// DeleteMorning deletes the morning check-in of date.
+func DeleteMorning(ctx context.Context, db *pgxpool.Pool, userID, date string) error {
+	return deleteByDate(ctx, db, "morning_checkins", userID, date)
+}
+
+// ListMorning returns morning check-ins between from and to (inclusive),
+// newest first.
+func ListMorning(ctx context.Context, db *pgxpool.Pool, userID, from, to string, limit int) ([]Morning, error) {
+	rows, err := db.Query(ctx, `
+		SELECT `+morningColumns+`
+		FROM public.morning_checkins
+		WHERE user_id = $1 AND date >= $2::date AND date <= $3::date
+		ORDER BY date DESC
+		LIMIT $4
+	`, userID, from, to, limit)
+	if err != nil {
+		return nil, fmt.Errorf("list morning check-ins: %w", err)
+	}
+	defer rows.Close()
+
+	list := []Morning{}
+	for rows.Next() {
+		m, err := scanMorning(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan morning check-in: %w", err)
+		}
+		list = append(list, *m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("list morning check-ins: %w", err)
+	}
+	return list, nil
+}
+
+// ===========================================
+// Evening
+// ===========================================
+
+const eveningColumns = `id, date, evening_mood, biggest_win, blockers, best_moment,
+	goal_for_tomorrow, grateful_for, COALESCE(rituals_completed, 0),
+	COALESCE(tasks_completed, 0), COALESCE(focus_minutes, 0), created_at, updated_at`
+
+func scanEvening(row pgx.Row) (*Evening, error) {
+	var e Evening
+	var date time.Time
+	if err := row.Scan(&e.ID, &date, &e.Mood, &e.BiggestWin, &e.Blockers, &e.BestMoment,
+		&e.GoalForTomorrow, &e.GratefulFor, &e.RitualsCompleted, &e.TasksCompleted,
+		&e.FocusMinutes, &e.CreatedAt, &e.UpdatedAt); err != nil {
+		return nil, err
+	}
+	e.Date = date.Format(userclock.DateLayout)
+	return &e, nil
+}
+
+// GetEvening returns the evening review of date.
+func GetEvening(ctx context.Context, db *pgxpool.Pool, userID, date string) (*Evening, error) {
+	e, err := scanEvening(db.QueryRow(ctx, `
+		SELECT `+eveningColumns+`
+		FROM public.evening_checkins WHERE user_id = $1 AND date = $2::date
+	`, userID, date))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	return e, err
+}
+
+// SaveEvening creates or updates the evening review of date and refreshes
+// the day's completion snapshot.
+func SaveEvening(ctx context.Context, db *pgxpool.Pool, userID, date string, in EveningInput) (*Evening, error) {
+	if err := in.Validate(); err != nil {
+		return nil, err
+	}
+
+	clock := userclock.For(ctx, db, userID)
+	dayStart, dayEnd, err := clock.DayBounds(date)
+	if err != nil {
+		return nil, fmt.Errorf("invalid date %q: %w", date, err)
+	}
+
+	// Saving zeros would overwrite the day's real counts
+	var ritualsCompleted, focusMinutes int
+	if err := db.QueryRow(ctx, `
+		SELECT
+			(SELECT COUNT(*) FROM public.routine_completions WHERE user_id = $1 AND completion_date = $2::date),
+			(SELECT COALESCE(SUM(duration_minutes), 0) FROM public.focus_sessions
+			 WHERE user_id = $1 AND started_at >= $3 AND started_at < $4 AND status = 'completed')
+	`, userID, date, dayStart, dayEnd).Scan(&ritualsCompleted, &focusMinutes); err != nil {
+		return nil, fmt.Errorf("snapshot day %s: %w", date, err)
+	}
+	_, tasksCompleted, err := calendar.CountDay(ctx, db, userID, date)
+	if err != nil {
+		return nil, fmt.Errorf("snapshot day %s: %w", date, err)
+	}
+
+	return scanEvening(db.QueryRow(ctx, `
+		INSERT INTO public.evening_checkins
+			(user_id, date, evening_mood, biggest_win, blockers, best_moment, goal_for_tomorrow, grateful_for,
+			 rituals_completed, tasks_completed, focus_minutes)
+		VALUES ($1, $2::date, $3, NULLIF($4, ''), NULLIF($5, ''), NULLIF($6, ''), NULLIF($7, ''), NULLIF($8, ''), $9, $10, $11)
+		ON CONFLICT (user_id, date) DO UPDATE SET
+			evening_mood      = COALESCE($3, evening_checkins.evening_mood),
+			biggest_win       = CASE WHEN $4::text IS NULL THEN evening_checkins.biggest_win ELSE NULLIF($4, '') END,
+			blockers          = CASE WHEN $5::text IS NULL THEN evening_checkins.blockers ELSE NULLIF($5, '') END,
+			best_moment       = CASE WHEN $6::text IS NULL THEN evening_checkins.best_moment ELSE NULLIF($6, '') END,
+			goal_for_tomorrow = CASE WHEN $7::text IS NULL THEN evening_checkins.goal_for_tomorrow ELSE NULLIF($7, '') END,
+			grateful_for      = CASE WHEN $8::text IS NULL THEN evening_checkins.grateful_for ELSE NULLIF($8, '') END,
+			rituals_completed = EXCLUDED.rituals_completed,
+			tasks_completed   = EXCLUDED.tasks_completed,
+			focus_minutes     = EXCLUDED.focus_minutes,
+			updated_at        = NOW()
+		RETURNING `+eveningColumns,
+		userID, date, in.Mood, in.BiggestWin, in.Blockers, in.BestMoment, in.GoalForTomorrow, in.GratefulFor,
+		ritualsCompleted, tasksCompleted, focusMinutes))
+}
+
+// DeleteEvening deletes the evening review of date.
+func DeleteEvening(ctx context.Context, db *pgxpool.Pool, userID, date string) error {
+	return deleteByDate(ctx, db, "evening_checkins", userID, date)
+}
+
+// ListEvening returns evening reviews between from and to (inclusive),
+// newest first.
+func ListEvening(ctx context.Context, db *pgxpool.Pool, userID, from, to string, limit int) ([]Evening, error) {
+	return listEvening(ctx, db, userID, from, to, limit, "")
+}
+
+// listEvening lists evening reviews matching the extra SQL filter.
+func listEvening(ctx context.Context, db *pgxpool.Pool, userID, from, to string, limit int, filter string) ([]Evening, error) {
+	rows, err := db.Query(ctx, `
+		SELECT `+eveningColumns+`
+		FROM public.evening_checkins
+		WHERE user_id = $1 AND date >= $2::date AND date <= $3::date `+filter+`
+		ORDER BY date DESC
+		LIMIT $4
+	`, userID, from, to, limit)
+	if err != nil {
+		return nil, fmt.Errorf("list evening check-ins: %w", err)
+	}
+	defer rows.Close()
+
+	list := []Evening{}
+	for rows.Next() {
+		e, err := scanEvening(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan evening check-in: %w", err)
+		}
+		list = append(list, *e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("list evening check-ins: %w", err)
+	}
+	return list, nil
+}
+
+// table is one of the check-in tables, never user input.
+func deleteByDate(ctx context.Context, db *pgxpool.Pool, table, userID, date string) error {
+	tag, err := db.Exec(ctx, `DELETE FROM public.`+table+` WHERE user_id = $1 AND date = $2::date`, userID, date)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// Done reports whether the morning check-in and evening review of date
+// exist, for the chat context.
+func Done(ctx context.Context, db *pgxpool.Pool, userID, date string) (morning, evening bool, err error) {
+	err = db.QueryRow(ctx, `
+		SELECT
+			EXISTS(SELECT 1 FROM public.morning_checkins WHERE user_id = $1 AND date = $2::date),
+			EXISTS(SELECT 1 FROM public.evening_checkins WHERE user_id = $1 AND date = $2::date
+			       AND COALESCE(evening_mood::text, biggest_win, blockers, goal_for_tomorrow) IS NOT NULL)
+	`, userID, date).Scan(&morning, &evening)
+	return morning, evening, err
+}
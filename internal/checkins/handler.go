@@ -0,0 +1,309 @@
+package checkins
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"firelevel-backend/internal/auth"
+	"firelevel-backend/internal/userclock"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const (
+	defaultMoodDays  = 30  // Window of GetMood without ?from
+	defaultListLimit = 30  // Check-ins returned by the list endpoints
+	maxListLimit     = 366 // A year of check-ins
+)
+
+// Handler exposes the morning and evening check-ins over HTTP.
+type Handler struct {
+	db *pgxpool.Pool
+}
+
+// NewHandler creates a new check-ins handler
+func NewHandler(db *pgxpool.Pool) *Handler {
+	return &Handler{db: db}
+}
+
+// dateParam reads the {date} URL param and rejects future dates.
+func (h *Handler) dateParam(w http.ResponseWriter, r *http.Request, userID string) (string, bool) {
+	date := chi.URLParam(r, "date")
+	if _, err := time.Parse(userclock.DateLayout, date); err != nil {
+		http.Error(w, "Invalid date (use YYYY-MM-DD)", http.StatusBadRequest)
+		return "", false
+	}
+	if r.Method == http.MethodPut && date > userclock.For(r.Context(), h.db, userID).Today() {
+		http.Error(w, "Cannot check in for a future date", http.StatusBadRequest)
+		return "", false
+	}
+	return date, true
+}
+
+// rangeParams reads ?from, ?to and ?limit. to defaults to today and from
+// to defaultDays before it (no lower bound when defaultDays is 0).
+func (h *Handler) rangeParams(w http.ResponseWriter, r *http.Request, userID string, defaultDays, defaultLimit int) (from, to string, limit int, ok bool) {
+	q := r.URL.Query()
+	from, to = q.Get("from"), q.Get("to")
+
+	clock := userclock.For(r.Context(), h.db, userID)
+	if to == "" {
+		to = clock.Today()
+	}
+	if from == "" {
+		from = "0001-01-01"
+		if defaultDays > 0 {
+			from = clock.AddDays(-(defaultDays - 1))
+		}
+	}
+	if _, err := time.Parse(userclock.DateLayout, from); err != nil {
+		http.Error(w, "Invalid from date (use YYYY-MM-DD)", http.StatusBadRequest)
+		return "", "", 0, false
+	}
+	if _, err := time.Parse(userclock.DateLayout, to); err != nil {
+		http.Error(w, "Invalid to date (use YYYY-MM-DD)", http.StatusBadRequest)
+		return "", "", 0, false
+	}
+
+	limit = defaultLimit
+	if l := q.Get("limit"); l != "" {
+		n, err := strconv.Atoi(l)
+		if err != nil || n < 1 || n > maxListLimit {
+			http.Error(w, "Invalid limit", http.StatusBadRequest)
+			return "", "", 0, false
+		}
+		limit = n
+	}
+	return from, to, limit, true
+}
+
+// ===========================================
+// Morning
+// ===========================================
+
+// ListMorning returns morning check-ins, newest first
+// GET /checkins/morning?from=YYYY-MM-DD&to=YYYY-MM-DD&limit=30
+func (h *Handler) ListMorning(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(auth.UserContextKey).(string)
+	from, to, limit, ok := h.rangeParams(w, r, userID, 0, defaultListLimit)
+	if !ok {
+		return
+	}
+
+	list, err := ListMorning(r.Context(), h.db, userID, from, to, limit)
+	if err != nil {
+		log.Printf("Failed to list morning check-ins for user %s: %v", userID, err)
+		http.Error(w, "Failed to list check-ins", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(list)
+}
+
+// GetMorning returns the morning check-in of a date
+// GET /checkins/morning/{date}
+func (h *Handler) GetMorning(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(auth.UserContextKey).(string)
+	date, ok := h.dateParam(w, r, userID)
+	if !ok {
+		return
+	}
+
+	m, err := GetMorning(r.Context(), h.db, userID, date)
+	if errors.Is(err, ErrNotFound) {
+		http.Error(w, "Check-in not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to get morning check-in %s for user %s: %v", date, userID, err)
+		http.Error(w, "Failed to get check-in", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(m)
+}
+
+// SaveMorning creates or updates the morning check-in of a date
+// PUT /checkins/morning/{date}
+func (h *Handler) SaveMorning(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(auth.UserContextKey).(string)
+	date, ok := h.dateParam(w, r, userID)
+	if !ok {
+		return
+	}
+
+	var req MorningInput
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := req.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	m, err := SaveMorning(r.Context(), h.db, userID, date, req)
+	if err != nil {
+		log.Printf("Failed to save morning check-in %s for user %s: %v", date, userID, err)
+		http.Error(w, "Failed to save check-in", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(m)
+}
+
+// DeleteMorning deletes the morning check-in of a date
+// DELETE /checkins/morning/{date}
+func (h *Handler) DeleteMorning(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(auth.UserContextKey).(string)
+	date, ok := h.dateParam(w, r, userID)
+	if !ok {
+		return
+	}
+
+	err := DeleteMorning(r.Context(), h.db, userID, date)
+	if errors.Is(err, ErrNotFound) {
+		http.Error(w, "Check-in not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to delete morning check-in %s for user %s: %v", date, userID, err)
+		http.Error(w, "Failed to delete check-in", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ===========================================
+// Evening
+// ===========================================
+
+// ListEvening returns evening reviews, newest first
+// GET /checkins/evening?from=YYYY-MM-DD&to=YYYY-MM-DD&limit=30
+func (h *Handler) ListEvening(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(auth.UserContextKey).(string)
+	from, to, limit, ok := h.rangeParams(w, r, userID, 0, defaultListLimit)
+	if !ok {
+		return
+	}
+
+	list, err := ListEvening(r.Context(), h.db, userID, from, to, limit)
+	if err != nil {
+		log.Printf("Failed to list evening check-ins for user %s: %v", userID, err)
+		http.Error(w, "Failed to list check-ins", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(list)
+}
+
+// GetEvening returns the evening review of a date
+// GET /checkins/evening/{date}
+func (h *Handler) GetEvening(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(auth.UserContextKey).(string)
+	date, ok := h.dateParam(w, r, userID)
+	if !ok {
+		return
+	}
+
+	e, err := GetEvening(r.Context(), h.db, userID, date)
+	if errors.Is(err, ErrNotFound) {
+		http.Error(w, "Check-in not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to get evening check-in %s for user %s: %v", date, userID, err)
+		http.Error(w, "Failed to get check-in", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(e)
+}
+
+// SaveEvening creates or updates the evening review of a date
+// PUT /checkins/evening/{date}
+func (h *Handler) SaveEvening(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(auth.UserContextKey).(string)
+	date, ok := h.dateParam(w, r, userID)
+	if !ok {
+		return
+	}
+
+	var req EveningInput
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := req.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	e, err := SaveEvening(r.Context(), h.db, userID, date, req)
+	if err != nil {
+		log.Printf("Failed to save evening check-in %s for user %s: %v", date, userID, err)
+		http.Error(w, "Failed to save check-in", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(e)
+}
+
+// DeleteEvening deletes the evening review of a date
+// DELETE /checkins/evening/{date}
+func (h *Handler) DeleteEvening(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(auth.UserContextKey).(string)
+	date, ok := h.dateParam(w, r, userID)
+	if !ok {
+		return
+	}
+
+	err := DeleteEvening(r.Context(), h.db, userID, date)
+	if errors.Is(err, ErrNotFound) {
+		http.Error(w, "Check-in not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to delete evening check-in %s for user %s: %v", date, userID, err)
+		http.Error(w, "Failed to delete check-in", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ===========================================
+// Mood
+// ===========================================
+
+// GetMood returns the mood trend over a range (last 30 days by default)
+// GET /checkins/mood?from=YYYY-MM-DD&to=YYYY-MM-DD
+func (h *Handler) GetMood(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(auth.UserContextKey).(string)
+	from, to, _, ok := h.rangeParams(w, r, userID, defaultMoodDays, defaultListLimit)
+	if !ok {
+		return
+	}
+
+	trend, err := GetMoodTrend(r.Context(), h.db, userID, from, to)
+	if err != nil {
+		log.Printf("Failed to get mood trend for user %s: %v", userID, err)
+		http.Error(w, "Failed to get mood trend", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(trend)
+}
@@ -0,0 +1,173 @@
+package checkins
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"firelevel-backend/internal/userclock"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// trendThreshold is the mood change (1-5 scale) between the first and
+// second half of the range under which the trend is "stable".
+const trendThreshold = 0.3
+
+// MoodDay is the mood of one local day. Average is the mean of the
+// morning and evening moods that were recorded.
+type MoodDay struct {
+	Date    string   `json:"date"`
+	Morning *int     `json:"morning"`
+	Evening *int     `json:"evening"`
+	Average *float64 `json:"average"`
+}
+
+// MoodWeek is the average mood of one week (Monday start).
+type MoodWeek struct {
+	WeekStart string  `json:"week_start"`
+	Average   float64 `json:"average"`
+	Days      int     `json:"days"`
+}
+
+// MoodTrend aggregates moods over a date range.
+type MoodTrend struct {
+	From           string     `json:"from"`
+	To             string     `json:"to"`
+	Days           []MoodDay  `json:"days"` // Oldest first, only days with a mood
+	Weeks          []MoodWeek `json:"weeks"`
+	MorningAverage *float64   `json:"morning_average"`
+	EveningAverage *float64   `json:"evening_average"`
+	Average        *float64   `json:"average"`
+	Trend          string     `json:"trend"` // up, down, stable, insufficient_data
+}
+
+// GetMoodTrend returns the moods recorded between from and to (inclusive).
+func GetMoodTrend(ctx context.Context, db *pgxpool.Pool, userID, from, to string) (*MoodTrend, error) {
+	rows, err := db.Query(ctx, `
+		SELECT d.date, m.morning_mood, e.evening_mood
+		FROM (
+			SELECT date FROM public.morning_checkins WHERE user_id = $1 AND date >= $2::date AND date <= $3::date
+			UNION
+			SELECT date FROM public.evening_checkins WHERE user_id = $1 AND date >= $2::date AND date <= $3::date
+			  AND evening_mood IS NOT NULL
+		) d
+		LEFT JOIN public.morning_checkins m ON m.user_id = $1 AND m.date = d.date
+		LEFT JOIN public.evening_checkins e ON e.user_id = $1 AND e.date = d.date
+		ORDER BY d.date
+	`, userID, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("load moods: %w", err)
+	}
+	defer rows.Close()
+
+	var moods []dayMood
+	for rows.Next() {
+		var m dayMood
+		if err := rows.Scan(&m.date, &m.morning, &m.evening); err != nil {
+			return nil, fmt.Errorf("scan mood: %w", err)
+		}
+		moods = append(moods, m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("load moods: %w", err)
+	}
+	return aggregateMoods(from, to, moods), nil
+}
+
+// dayMood is the raw morning and evening mood of a day.
+type dayMood struct {
+	date             time.Time
+	morning, evening *int
+}
+
+// aggregateMoods builds the trend of moods, oldest first.
+func aggregateMoods(from, to string, moods []dayMood) *MoodTrend {
+	trend := &MoodTrend{From: from, To: to, Days: []MoodDay{}, Weeks: []MoodWeek{}}
+	var morningSum, eveningSum, morningCount, eveningCount int
+	var daily []float64
+	for _, m := range moods {
+		day := MoodDay{Date: m.date.Format(userclock.DateLayout), Morning: m.morning, Evening: m.evening}
+
+		sum, n := 0, 0
+		if day.Morning != nil {
+			sum += *day.Morning
+			n++
+			morningSum += *day.Morning
+			morningCount++
+		}
+		if day.Evening != nil {
+			sum += *day.Evening
+			n++
+			eveningSum += *day.Evening
+			eveningCount++
+		}
+		if n == 0 {
+			continue
+		}
+		avg := round1(float64(sum) / float64(n))
+		day.Average = &avg
+		daily = append(daily, avg)
+		trend.Days = append(trend.Days, day)
+
+		weekStart := userclock.MondayOf(m.date).Format(userclock.DateLayout)
+		if len(trend.Weeks) == 0 || trend.Weeks[len(trend.Weeks)-1].WeekStart != weekStart {
+			trend.Weeks = append(trend.Weeks, MoodWeek{WeekStart: weekStart})
+		}
+		w := &trend.Weeks[len(trend.Weeks)-1]
+		w.Average += avg
+		w.Days++
+	}
+
+	for i := range trend.Weeks {
+		trend.Weeks[i].Average = round1(trend.Weeks[i].Average / float64(trend.Weeks[i].Days))
+	}
+	trend.MorningAverage = average(morningSum, morningCount)
+	trend.EveningAverage = average(eveningSum, eveningCount)
+	if len(daily) > 0 {
+		total := 0.0
+		for _, v := range daily {
+			total += v
+		}
+		avg := round1(total / float64(len(daily)))
+		trend.Average = &avg
+	}
+	trend.Trend = direction(daily)
+	return trend
+}
+
+// direction compares the mean of the first and second half of the days.
+func direction(daily []float64) string {
+	if len(daily) < 4 {
+		return "insufficient_data"
+	}
+	half := len(daily) / 2
+	first, second := 0.0, 0.0
+	for _, v := range daily[:half] {
+		first += v
+	}
+	for _, v := range daily[len(daily)-half:] {
+		second += v
+	}
+	delta := (second - first) / float64(half)
+	switch {
+	case delta >= trendThreshold:
+		return "up"
+	case delta <= -trendThreshold:
+		return "down"
+	}
+	return "stable"
+}
+
+func average(sum, count int) *float64 {
+	if count == 0 {
+		return nil
+	}
+	avg := round1(float64(sum) / float64(count))
+	return &avg
+}
+
+func round1(v float64) float64 {
+	return math.Round(v*10) / 10
+}
@@ -0,0 +1,112 @@
+package checkins
+
+import (
+	"testing"
+	"time"
+)
+
+func mood(date string, morning, evening int) dayMood {
+	d, err := time.Parse("2006-01-02", date)
+	if err != nil {
+		panic(err)
+	}
+	m := dayMood{date: d}
+	if morning > 0 {
+		m.morning = &morning
+	}
+	if evening > 0 {
+		m.evening = &evening
+	}
+	return m
+}
+
+func TestAggregateMoods(t *testing.T) {
+	trend := aggregateMoods("2026-10-05", "2026-10-18", []dayMood{
+		mood("2026-10-08", 3, 4),
+		mood("2026-10-09", 2, 0),
+		mood("2026-10-10", 0, 0), // Evening review without a mood
+		mood("2026-10-12", 0, 5),
+		mood("2026-10-13", 4, 5),
+	})
+
+	wantDays := []struct {
+		date    string
+		average float64
+	}{
+		{"2026-10-08", 3.5}, {"2026-10-09", 2}, {"2026-10-12", 5}, {"2026-10-13", 4.5},
+	}
+	if len(trend.Days) != len(wantDays) {
+		t.Fatalf("days = %d, want %d", len(trend.Days), len(wantDays))
+	}
+	for i, w := range wantDays {
+		d := trend.Days[i]
+		if d.Date != w.date || d.Average == nil || *d.Average != w.average {
+			t.Errorf("day %d = %s %v, want %s %v", i, d.Date, d.Average, w.date, w.average)
+		}
+	}
+
+	wantWeeks := []MoodWeek{
+		{WeekStart: "2026-10-05", Average: 2.8, Days: 2},
+		{WeekStart: "2026-10-12", Average: 4.8, Days: 2},
+	}
+	if len(trend.Weeks) != len(wantWeeks) {
+		t.Fatalf("weeks = %v", trend.Weeks)
+	}
+	for i, w := range wantWeeks {
+		if trend.Weeks[i] != w {
+			t.Errorf("week %d = %+v, want %+v", i, trend.Weeks[i], w)
+		}
+	}
+
+	checks := []struct {
+		name string
+		got  *float64
+		want float64
+	}{
+		{"morning average", trend.MorningAverage, 3},
+		{"evening average", trend.EveningAverage, 4.7},
+		{"average", trend.Average, 3.8},
+	}
+	for _, c := range checks {
+		if c.got == nil || *c.got != c.want {
+			t.Errorf("%s = %v, want %v", c.name, c.got, c.want)
+		}
+	}
+	if trend.Trend != "up" {
+		t.Errorf("trend = %s, want up", trend.Trend)
+	}
+}
+
+func TestAggregateMoodsEmpty(t *testing.T) {
+	trend := aggregateMoods("2026-10-05", "2026-10-18", nil)
+	if len(trend.Days) != 0 || len(trend.Weeks) != 0 || trend.Days == nil || trend.Weeks == nil {
+		t.Errorf("days = %v, weeks = %v, want empty lists", trend.Days, trend.Weeks)
+	}
+	if trend.Average != nil || trend.MorningAverage != nil || trend.EveningAverage != nil {
+		t.Error("averages should be null without moods")
+	}
+	if trend.Trend != "insufficient_data" {
+		t.Errorf("trend = %s", trend.Trend)
+	}
+}
+
+func TestDirection(t *testing.T) {
+	tests := []struct {
+		daily []float64
+		want  string
+	}{
+		{nil, "insufficient_data"},
+		{[]float64{1, 5, 5}, "insufficient_data"},
+		{[]float64{3, 3, 3, 3}, "stable"},
+		{[]float64{3, 3, 3.2, 3.3}, "stable"},
+		{[]float64{3, 3, 3.5, 3.5}, "up"},
+		{[]float64{4, 4, 3, 3}, "down"},
+		{[]float64{2, 2, 5, 3, 3}, "up"}, // The middle day of an odd count is ignored
+		{[]float64{4, 4, 1, 4, 3.6}, "stable"},
+	}
+	for _, tt := range tests {
+		if got := direction(tt.daily); got != tt.want {
+			t.Errorf("direction(%v) = %s, want %s", tt.daily, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package checkins
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"firelevel-backend/internal/auth"
+)
+
+// ===========================================
+// REFLECTIONS (docs/reflections.md)
+// The daily journal is the text side of the evening
+// review: challenges is stored as blockers.
+// ===========================================
+
+const defaultReflectionsLimit = 10
+
+// reflectionFilter keeps evening reviews that have journal content.
+const reflectionFilter = `AND COALESCE(biggest_win, blockers, best_moment, goal_for_tomorrow) IS NOT NULL`
+
+// Reflection is a daily journal entry.
+type Reflection struct {
+	ID              string  `json:"id"`
+	Date            string  `json:"date"`
+	BiggestWin      *string `json:"biggest_win"`
+	Challenges      *string `json:"challenges"`
+	BestMoment      *string `json:"best_moment"`
+	GoalForTomorrow *string `json:"goal_for_tomorrow"`
+}
+
+// ReflectionRequest for PUT /reflections/{date}
+type ReflectionRequest struct {
+	BiggestWin      *string `json:"biggest_win"`
+	Challenges      *string `json:"challenges"`
+	BestMoment      *string `json:"best_moment"`
+	GoalForTomorrow *string `json:"goal_for_tomorrow"`
+}
+
+func reflectionOf(e *Evening) Reflection {
+	return Reflection{
+		ID:              e.ID,
+		Date:            e.Date,
+		BiggestWin:      e.BiggestWin,
+		Challenges:      e.Blockers,
+		BestMoment:      e.BestMoment,
+		GoalForTomorrow: e.GoalForTomorrow,
+	}
+}
+
+// GetReflection returns the reflection of a date
+// GET /reflections/{date}
+func (h *Handler) GetReflection(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(auth.UserContextKey).(string)
+	date, ok := h.dateParam(w, r, userID)
+	if !ok {
+		return
+	}
+
+	e, err := GetEvening(r.Context(), h.db, userID, date)
+	if err == nil && e.BiggestWin == nil && e.Blockers == nil && e.BestMoment == nil && e.GoalForTomorrow == nil {
+		err = ErrNotFound
+	}
+	if errors.Is(err, ErrNotFound) {
+		http.Error(w, "Reflection not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to get reflection %s for user %s: %v", date, userID, err)
+		http.Error(w, "Failed to get reflection", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(reflectionOf(e))
+}
+
+// UpsertReflection creates or updates the reflection of a date
+// PUT /reflections/{date}
+func (h *Handler) UpsertReflection(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(auth.UserContextKey).(string)
+	date, ok := h.dateParam(w, r, userID)
+	if !ok {
+		return
+	}
+
+	var req ReflectionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	e, err := SaveEvening(r.Context(), h.db, userID, date, EveningInput{
+		BiggestWin:      req.BiggestWin,
+		Blockers:        req.Challenges,
+		BestMoment:      req.BestMoment,
+		GoalForTomorrow: req.GoalForTomorrow,
+	})
+	if err != nil {
+		log.Printf("Failed to save reflection %s for user %s: %v", date, userID, err)
+		http.Error(w, "Failed to save reflection", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(reflectionOf(e))
+}
+
+// ListReflections returns past reflections, newest first
+// GET /reflections?from=YYYY-MM-DD&to=YYYY-MM-DD&limit=10
+func (h *Handler) ListReflections(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(auth.UserContextKey).(string)
+	from, to, limit, ok := h.rangeParams(w, r, userID, 0, defaultReflectionsLimit)
+	if !ok {
+		return
+	}
+
+	list, err := listEvening(r.Context(), h.db, userID, from, to, limit, reflectionFilter)
+	if err != nil {
+		log.Printf("Failed to list reflections for user %s: %v", userID, err)
+		http.Error(w, "Failed to list reflections", http.StatusInternalServerError)
+		return
+	}
+
+	reflections := make([]Reflection, 0, len(list))
+	for i := range list {
+		reflections = append(reflections, reflectionOf(&list[i]))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(reflections)
+}
@@ -98,7 +98,7 @@ func (h *Handler) Start(w http.ResponseWriter, r *http.Request) {
 
 	// Update streak when a focus session is completed
 	if status == "completed" {
-		streak.UpdateUserStreak(r.Context(), h.db, userID)
+		streak.Record(r.Context(), h.db, userID, streak.ActivityFocus)
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -163,7 +163,7 @@ func (h *Handler) Update(w http.ResponseWriter, r *http.Request) {
 
 	// Update streak when session is completed
 	if req.Status != nil && *req.Status == "completed" {
-		streak.UpdateUserStreak(r.Context(), h.db, userID)
+		streak.Record(r.Context(), h.db, userID, streak.ActivityFocus)
 	}
 
 	w.Header().Set("Content-Type", "application/json")
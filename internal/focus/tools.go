@@ -0,0 +1,136 @@
+package focus
+
+import (
+	"context"
+
+	"firelevel-backend/internal/tools"
+)
+
+// ==========================================
+// AI TOOLS
+// Focus and app blocking tools Kai can call. They run on the
+// device: the backend only answers the model and returns side
+// effects for the app to apply.
+// ==========================================
+
+// RegisterTools adds the focus tools to reg.
+func RegisterTools(reg *tools.Registry) error {
+	return reg.Register(
+		tools.Func[startFocusArgs]{
+			Name:        "start_focus_session",
+			Description: "Affiche le planning du jour en mode focus : les tâches avec boutons de sélection, choix de durée et timer intégré.",
+			Run:         runStartFocusSession,
+		},
+		tools.Func[blockAppsArgs]{
+			Name:        "block_apps",
+			Description: "Active le blocage d'apps pour la concentration.",
+			Run:         runBlockApps,
+		},
+		tools.Func[tools.NoArgs]{
+			Name:        "unblock_apps",
+			Description: "Désactive le blocage d'apps.",
+			Run: func(ctx context.Context, call *tools.Call, _ tools.NoArgs) (tools.Result, error) {
+				return tools.Result{
+					Output:      map[string]interface{}{"unblocked": true},
+					SideEffects: []tools.SideEffect{tools.NewSideEffect("unblock_apps")},
+				}, nil
+			},
+		},
+		tools.Func[tools.NoArgs]{
+			Name:        "show_force_unblock_card",
+			Description: "Affiche un bouton interactif pour forcer le déblocage quand l'utilisateur insiste que ses apps sont encore bloquées.",
+			Run: func(ctx context.Context, call *tools.Call, _ tools.NoArgs) (tools.Result, error) {
+				return tools.Result{
+					Output:      map[string]interface{}{"card_shown": true},
+					SideEffects: []tools.SideEffect{tools.NewSideEffect("show_force_unblock_card")},
+				}, nil
+			},
+		},
+		tools.Func[morningBlockArgs]{
+			Name:        "set_morning_block",
+			Description: "Configure le blocage automatique matinal.",
+			Run: func(ctx context.Context, call *tools.Call, args morningBlockArgs) (tools.Result, error) {
+				return tools.Result{
+					Output:      map[string]interface{}{"configured": true},
+					SideEffects: []tools.SideEffect{tools.NewSideEffectWithData("set_morning_block", args)},
+				}, nil
+			},
+		},
+		tools.Func[tools.NoArgs]{
+			Name:        "get_morning_block_status",
+			Description: "Vérifie si le blocage matinal est configuré et retourne la plage horaire.",
+			Run:         runGetMorningBlockStatus,
+		},
+	)
+}
+
+type startFocusArgs struct {
+	DurationMinutes int    `json:"duration_minutes,omitempty" desc:"Durée en minutes" min:"0"`
+	TaskID          string `json:"task_id,omitempty" desc:"ID de la tâche (optionnel)"`
+	TaskTitle       string `json:"task_title,omitempty" desc:"Titre de la tâche (optionnel)"`
+}
+
+type blockAppsArgs struct {
+	DurationMinutes int `json:"duration_minutes,omitempty" desc:"Durée en minutes (optionnel)" min:"0"`
+}
+
+// morningBlockArgs is forwarded as-is to the app. Pointers keep the
+// fields the model left out, so the app applies its own defaults.
+type morningBlockArgs struct {
+	Enabled     *bool `json:"enabled,omitempty" desc:"Activer/désactiver"`
+	StartHour   *int  `json:"start_hour,omitempty" desc:"Heure de début (0-23, défaut: 6)" min:"0" max:"23"`
+	StartMinute *int  `json:"start_minute,omitempty" desc:"Minute de début (0-59, défaut: 0)" min:"0" max:"59"`
+	EndHour     *int  `json:"end_hour,omitempty" desc:"Heure de fin (0-23, défaut: 9)" min:"0" max:"23"`
+	EndMinute   *int  `json:"end_minute,omitempty" desc:"Minute de fin (0-59, défaut: 0)" min:"0" max:"59"`
+}
+
+func runStartFocusSession(ctx context.Context, call *tools.Call, args startFocusArgs) (tools.Result, error) {
+	return tools.Result{
+		Output: map[string]interface{}{"started": true},
+		SideEffects: []tools.SideEffect{
+			tools.NewSideEffectWithData("start_focus_session", map[string]interface{}{
+				"duration_minutes": args.DurationMinutes,
+				"task_id":          args.TaskID,
+				"task_title":       args.TaskTitle,
+			}),
+		},
+	}, nil
+}
+
+func runBlockApps(ctx context.Context, call *tools.Call, args blockAppsArgs) (tools.Result, error) {
+	alreadyBlocked := call.Device != nil && call.Device.AppsBlocked
+	configured := call.Device != nil && call.Device.AppBlockingAvailable
+	result := map[string]interface{}{
+		"already_blocked": alreadyBlocked,
+		"configured":      configured,
+	}
+	if alreadyBlocked {
+		result["message"] = "Les apps sont déjà bloquées."
+	} else if configured {
+		result["blocked"] = true
+		result["message"] = "Blocage activé."
+	} else {
+		result["blocked"] = false
+		result["message"] = "L'utilisateur n'a pas encore configuré le blocage d'apps sur son appareil. Dis-lui d'aller dans les réglages pour sélectionner les apps à bloquer."
+	}
+
+	res := tools.Result{Output: result}
+	if configured && !alreadyBlocked {
+		res.SideEffects = []tools.SideEffect{
+			tools.NewSideEffectWithData("block_apps", map[string]interface{}{"duration_minutes": args.DurationMinutes}),
+		}
+	}
+	return res, nil
+}
+
+func runGetMorningBlockStatus(ctx context.Context, call *tools.Call, _ tools.NoArgs) (tools.Result, error) {
+	// Only the device knows its blocking schedule
+	if call.Device == nil {
+		return tools.Result{Output: map[string]interface{}{"enabled": false}}, nil
+	}
+	return tools.Result{Output: map[string]interface{}{
+		"enabled": call.Device.MorningBlockEnabled,
+		"start":   call.Device.MorningBlockStart,
+		"end":     call.Device.MorningBlockEnd,
+	}}, nil
+}
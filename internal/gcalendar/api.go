@@ -0,0 +1,213 @@
+package gcalendar
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"golang.org/x/oauth2"
+	googlecalendar "google.golang.org/api/calendar/v3"
+	"google.golang.org/api/googleapi"
+	"google.golang.org/api/option"
+)
+
+// =============================================
+// GOOGLE CALENDAR API CLIENT
+// =============================================
+// Everything that talks to Google goes through EventsAPI
+// so the real client can be swapped for a local fake.
+// =============================================
+
+// ErrEventNotFound is returned by EventsAPI when the event no longer exists
+// on the calendar (deleted by the user in Google Calendar).
+var ErrEventNotFound = errors.New("google calendar event not found")
+
+// ErrSyncTokenExpired is returned by EventsAPI.List when Google answers 410:
+// the sync token is no longer valid and a full resync is required.
+var ErrSyncTokenExpired = errors.New("google calendar sync token expired")
+
+// ListOptions selects a page of events. SyncToken and TimeMin are exclusive.
+type ListOptions struct {
+	SyncToken string
+	PageToken string
+	TimeMin   time.Time
+}
+
+// EventsAPI is the subset of the Google Calendar API used by this package.
+// Swap it with SetEventsAPIFactory to run against a local fake.
+type EventsAPI interface {
+	Insert(ctx context.Context, calendarID string, event *googlecalendar.Event) (*googlecalendar.Event, error)
+	Update(ctx context.Context, calendarID, eventID string, event *googlecalendar.Event) (*googlecalendar.Event, error)
+	Delete(ctx context.Context, calendarID, eventID string) error
+	List(ctx context.Context, calendarID string, opts ListOptions) (*googlecalendar.Events, error)
+	ListCalendars(ctx context.Context) ([]*googlecalendar.CalendarListEntry, error)
+	Watch(ctx context.Context, calendarID string, channel *googlecalendar.Channel) (*googlecalendar.Channel, error)
+	StopChannel(ctx context.Context, channelID, resourceID string) error
+}
+
+// EventsAPIFactory builds an EventsAPI authenticated with ts.
+type EventsAPIFactory func(ctx context.Context, ts oauth2.TokenSource) (EventsAPI, error)
+
+// SetEventsAPIFactory replaces the Google API client (e.g. with a fake in tests)
+func (h *Handler) SetEventsAPIFactory(factory EventsAPIFactory) {
+	h.newEventsAPI = factory
+}
+
+// googleEventsAPI is the real EventsAPI backed by calendar/v3.
+type googleEventsAPI struct {
+	svc *googlecalendar.Service
+}
+
+func newGoogleEventsAPI(ctx context.Context, ts oauth2.TokenSource) (EventsAPI, error) {
+	svc, err := googlecalendar.NewService(ctx, option.WithTokenSource(ts))
+	if err != nil {
+		return nil, err
+	}
+	return &googleEventsAPI{svc: svc}, nil
+}
+
+func (g *googleEventsAPI) Insert(ctx context.Context, calendarID string, event *googlecalendar.Event) (*googlecalendar.Event, error) {
+	return g.svc.Events.Insert(calendarID, event).Context(ctx).Do()
+}
+
+func (g *googleEventsAPI) Update(ctx context.Context, calendarID, eventID string, event *googlecalendar.Event) (*googlecalendar.Event, error) {
+	ev, err := g.svc.Events.Update(calendarID, eventID, event).Context(ctx).Do()
+	return ev, translateGoogleError(err)
+}
+
+func (g *googleEventsAPI) Delete(ctx context.Context, calendarID, eventID string) error {
+	return translateGoogleError(g.svc.Events.Delete(calendarID, eventID).Context(ctx).Do())
+}
+
+func (g *googleEventsAPI) List(ctx context.Context, calendarID string, opts ListOptions) (*googlecalendar.Events, error) {
+	call := g.svc.Events.List(calendarID).
+		SingleEvents(true).
+		ShowDeleted(true).
+		MaxResults(250).
+		Context(ctx)
+	if opts.SyncToken != "" {
+		call = call.SyncToken(opts.SyncToken)
+	} else if !opts.TimeMin.IsZero() {
+		call = call.TimeMin(opts.TimeMin.Format(time.RFC3339))
+	}
+	if opts.PageToken != "" {
+		call = call.PageToken(opts.PageToken)
+	}
+
+	events, err := call.Do()
+	var gerr *googleapi.Error
+	if errors.As(err, &gerr) && gerr.Code == http.StatusGone {
+		return nil, ErrSyncTokenExpired
+	}
+	return events, err
+}
+
+func (g *googleEventsAPI) ListCalendars(ctx context.Context) ([]*googlecalendar.CalendarListEntry, error) {
+	var entries []*googlecalendar.CalendarListEntry
+	err := g.svc.CalendarList.List().Context(ctx).Pages(ctx, func(page *googlecalendar.CalendarList) error {
+		entries = append(entries, page.Items...)
+		return nil
+	})
+	return entries, err
+}
+
+func (g *googleEventsAPI) Watch(ctx context.Context, calendarID string, channel *googlecalendar.Channel) (*googlecalendar.Channel, error) {
+	return g.svc.Events.Watch(calendarID, channel).Context(ctx).Do()
+}
+
+func (g *googleEventsAPI) StopChannel(ctx context.Context, channelID, resourceID string) error {
+	err := g.svc.Channels.Stop(&googlecalendar.Channel{Id: channelID, ResourceId: resourceID}).Context(ctx).Do()
+	return translateGoogleError(err)
+}
+
+// translateGoogleError maps 404/410 to ErrEventNotFound.
+func translateGoogleError(err error) error {
+	var gerr *googleapi.Error
+	if errors.As(err, &gerr) && (gerr.Code == http.StatusNotFound || gerr.Code == http.StatusGone) {
+		return ErrEventNotFound
+	}
+	return err
+}
+
+// provider is a connected Google row of calendar_providers. Its synced
+// calendars are calendar_sources rows (see sync.go).
+type provider struct {
+	id            string
+	userID        string
+	calendarID    string // Calendar tasks are pushed to (provider_config.calendar_id)
+	syncDirection string
+	isActive      bool
+	token         *oauth2.Token
+}
+
+const providerColumns = `
+	id, user_id, COALESCE(provider_config->>'calendar_id', 'primary'),
+	COALESCE(sync_direction, 'bidirectional'), is_active,
+	COALESCE(access_token, ''), COALESCE(refresh_token, ''), token_expiry
+`
+
+func scanProvider(row interface{ Scan(...any) error }) (*provider, error) {
+	var p provider
+	var accessToken, refreshToken string
+	var tokenExpiry *time.Time
+	err := row.Scan(&p.id, &p.userID, &p.calendarID, &p.syncDirection, &p.isActive,
+		&accessToken, &refreshToken, &tokenExpiry)
+	if err != nil {
+		return nil, err
+	}
+	if accessToken == "" {
+		return nil, errors.New("google calendar has no access token")
+	}
+	p.token = &oauth2.Token{AccessToken: accessToken, RefreshToken: refreshToken}
+	if tokenExpiry != nil {
+		p.token.Expiry = *tokenExpiry
+	}
+	return &p, nil
+}
+
+// loadProvider returns the user's connected Google provider.
+func (h *Handler) loadProvider(ctx context.Context, userID string) (*provider, error) {
+	return scanProvider(h.db.QueryRow(ctx, `
+		SELECT `+providerColumns+`
+		FROM public.calendar_providers
+		WHERE user_id = $1 AND provider_type = 'google' AND is_connected = true
+		ORDER BY updated_at DESC LIMIT 1
+	`, userID))
+}
+
+// loadProviderByID returns a connected Google provider by its row ID.
+func (h *Handler) loadProviderByID(ctx context.Context, providerID string) (*provider, error) {
+	return scanProvider(h.db.QueryRow(ctx, `
+		SELECT `+providerColumns+`
+		FROM public.calendar_providers
+		WHERE id = $1 AND provider_type = 'google' AND is_connected = true
+	`, providerID))
+}
+
+// eventsAPI builds a client for p. Call the returned func once done to
+// persist a refreshed access token.
+func (h *Handler) eventsAPI(ctx context.Context, p *provider) (EventsAPI, func(), error) {
+	ts := oauth2.ReuseTokenSource(p.token, h.oauthConfig.TokenSource(ctx, p.token))
+	api, err := h.newEventsAPI(ctx, ts)
+	if err != nil {
+		return nil, nil, err
+	}
+	done := func() {
+		newToken, err := ts.Token()
+		if err != nil || newToken.AccessToken == p.token.AccessToken {
+			return
+		}
+		if _, err := h.db.Exec(context.Background(), `
+			UPDATE public.calendar_providers
+			SET access_token = $1, token_expiry = $2, updated_at = NOW()
+			WHERE id = $3
+		`, newToken.AccessToken, newToken.Expiry, p.id); err != nil {
+			log.Printf("Failed to save refreshed Google Calendar token for provider %s: %v", p.id, err)
+		} else {
+			log.Printf("🔄 Google Calendar token refreshed for user %s", p.userID)
+		}
+	}
+	return api, done, nil
+}
@@ -10,12 +10,12 @@ import (
 	"time"
 
 	"firelevel-backend/internal/auth"
+	"firelevel-backend/internal/userclock"
 
 	"github.com/jackc/pgx/v5/pgxpool"
 	"golang.org/x/oauth2"
 	"golang.org/x/oauth2/google"
 	googlecalendar "google.golang.org/api/calendar/v3"
-	"google.golang.org/api/option"
 )
 
 // =============================================
@@ -27,6 +27,11 @@ import (
 //   PATCH  /google-calendar/config
 //   DELETE /google-calendar/config
 //   POST   /google-calendar/sync
+//   GET    /google-calendar/calendars
+// Google push notifications (public):
+//   POST   /webhooks/google-calendar
+// Tasks are pushed the other way via SyncTaskToGoogleCalendar
+// (see push.go); incremental sync lives in sync.go.
 // =============================================
 
 // ConfigResponse matches iOS GoogleCalendarConfigResponse
@@ -58,14 +63,27 @@ type UpdateConfigRequest struct {
 type SyncResult struct {
 	TasksSynced    int      `json:"tasks_synced"`
 	EventsImported int      `json:"events_imported"`
+	EventsDeleted  int      `json:"events_deleted"`
 	Errors         []string `json:"errors,omitempty"`
 	LastSyncAt     string   `json:"last_sync_at"`
 }
 
+// CalendarResponse is one Google calendar of the account
+type CalendarResponse struct {
+	ID         string `json:"id"` // calendar_sources ID, for PATCH /calendar/sources/{id}
+	CalendarID string `json:"calendar_id"`
+	Name       string `json:"name"`
+	Color      string `json:"color,omitempty"`
+	IsPrimary  bool   `json:"is_primary"`
+	IsSelected bool   `json:"is_selected"`
+}
+
 // Handler holds dependencies
 type Handler struct {
-	db          *pgxpool.Pool
-	oauthConfig *oauth2.Config
+	db           *pgxpool.Pool
+	oauthConfig  *oauth2.Config
+	newEventsAPI EventsAPIFactory
+	webhookURL   string // Public URL of Webhook; push channels are off when empty
 }
 
 // NewHandler creates a new Google Calendar handler
@@ -79,7 +97,12 @@ func NewHandler(db *pgxpool.Pool) *Handler {
 		},
 		Endpoint: google.Endpoint,
 	}
-	return &Handler{db: db, oauthConfig: config}
+	return &Handler{
+		db:           db,
+		oauthConfig:  config,
+		newEventsAPI: newGoogleEventsAPI,
+		webhookURL:   os.Getenv("GOOGLE_CALENDAR_WEBHOOK_URL"),
+	}
 }
 
 // GetConfig returns Google Calendar config for the user
@@ -123,9 +146,9 @@ func (h *Handler) SaveTokens(w http.ResponseWriter, r *http.Request) {
 		INSERT INTO public.calendar_providers (
 			user_id, provider_type, provider_email, access_token,
 			refresh_token, token_expiry, is_active, is_connected,
-			sync_direction, created_at, updated_at
+			sync_direction, sync_requested_at, created_at, updated_at
 		)
-		VALUES ($1, 'google', $2, $3, $4, $5, true, true, 'from_provider', NOW(), NOW())
+		VALUES ($1, 'google', $2, $3, $4, $5, true, true, $6, NOW(), NOW(), NOW())
 		ON CONFLICT (user_id, provider_type, provider_email)
 		DO UPDATE SET
 			is_connected = true,
@@ -133,11 +156,12 @@ func (h *Handler) SaveTokens(w http.ResponseWriter, r *http.Request) {
 			access_token = EXCLUDED.access_token,
 			refresh_token = COALESCE(NULLIF(EXCLUDED.refresh_token, ''), calendar_providers.refresh_token),
 			token_expiry = EXCLUDED.token_expiry,
+			sync_requested_at = NOW(),
 			updated_at = NOW()
 	`
 
 	_, err := h.db.Exec(r.Context(), query,
-		userID, req.GoogleEmail, req.AccessToken, req.RefreshToken, expiry,
+		userID, req.GoogleEmail, req.AccessToken, req.RefreshToken, expiry, defaultSyncDirection,
 	)
 	if err != nil {
 		log.Printf("❌ Google Calendar tokens save error: %v", err)
@@ -204,12 +228,18 @@ func (h *Handler) UpdateConfig(w http.ResponseWriter, r *http.Request) {
 func (h *Handler) Disconnect(w http.ResponseWriter, r *http.Request) {
 	userID := r.Context().Value(auth.UserContextKey).(string)
 
+	// Stop push notifications while the tokens are still around
+	if p, err := h.loadProvider(r.Context(), userID); err == nil {
+		h.stopChannels(r.Context(), p)
+	}
+
 	query := `
 		UPDATE public.calendar_providers
 		SET is_connected = false,
 			is_active = false,
 			access_token = NULL,
 			refresh_token = NULL,
+			sync_requested_at = NULL,
 			updated_at = NOW()
 		WHERE user_id = $1 AND provider_type = 'google'
 	`
@@ -219,7 +249,7 @@ func (h *Handler) Disconnect(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Clean up cached events
+	// Clean up cached events and the calendar list (a reconnect fetches it again)
 	cleanQuery := `
 		DELETE FROM public.calendar_events
 		WHERE provider_id IN (
@@ -230,224 +260,147 @@ func (h *Handler) Disconnect(w http.ResponseWriter, r *http.Request) {
 	if _, err := h.db.Exec(r.Context(), cleanQuery, userID); err != nil {
 		log.Printf("Failed to clean up calendar events for user %s: %v", userID, err)
 	}
+	if _, err := h.db.Exec(r.Context(), `
+		DELETE FROM public.calendar_sources
+		WHERE provider_id IN (
+			SELECT id FROM public.calendar_providers
+			WHERE user_id = $1 AND provider_type = 'google'
+		)
+	`, userID); err != nil {
+		log.Printf("Failed to clean up calendar sources for user %s: %v", userID, err)
+	}
 
 	w.WriteHeader(http.StatusNoContent)
 }
 
-// Sync fetches events from Google Calendar API and caches them
-// POST /google-calendar/sync
-func (h *Handler) Sync(w http.ResponseWriter, r *http.Request) {
+// ListCalendars refreshes the user's Google calendar list into
+// calendar_sources and returns it. New calendars start unselected except the
+// primary one; PATCH /calendar/sources/{id} changes the selection.
+// GET /google-calendar/calendars
+func (h *Handler) ListCalendars(w http.ResponseWriter, r *http.Request) {
 	userID := r.Context().Value(auth.UserContextKey).(string)
-	log.Printf("📅 Starting Google Calendar sync for user %s", userID)
-
-	// Get provider info and tokens
-	var providerID, accessToken, refreshToken string
-	var tokenExpiry time.Time
-	var calendarID string
 
-	query := `
-		SELECT id, access_token, COALESCE(refresh_token, ''), token_expiry,
-			   COALESCE(provider_config->>'calendar_id', 'primary')
-		FROM public.calendar_providers
-		WHERE user_id = $1 AND provider_type = 'google' AND is_connected = true
-	`
-	err := h.db.QueryRow(r.Context(), query, userID).Scan(
-		&providerID, &accessToken, &refreshToken, &tokenExpiry, &calendarID,
-	)
+	p, err := h.loadProvider(r.Context(), userID)
 	if err != nil {
-		log.Printf("❌ Google Calendar not connected: %v", err)
-		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(SyncResult{
-			Errors:     []string{"Google Calendar not connected"},
-			LastSyncAt: time.Now().Format(time.RFC3339),
-		})
+		http.Error(w, "Google Calendar not connected", http.StatusNotFound)
 		return
 	}
 
-	// Mark sync in progress
-	if _, err := h.db.Exec(r.Context(), `
-		UPDATE public.calendar_providers
-		SET last_sync_status = 'in_progress', updated_at = NOW()
-		WHERE id = $1
-	`, providerID); err != nil {
-		log.Printf("Failed to mark sync in_progress for provider %s: %v", providerID, err)
-	}
-
-	// Create Google Calendar client
-	token := &oauth2.Token{
-		AccessToken:  accessToken,
-		RefreshToken: refreshToken,
-		Expiry:       tokenExpiry,
-	}
-
-	ctx := context.Background()
-	client := h.oauthConfig.Client(ctx, token)
-
-	calService, err := googlecalendar.NewService(ctx, option.WithHTTPClient(client))
+	api, done, err := h.eventsAPI(r.Context(), p)
 	if err != nil {
-		h.markSyncError(r.Context(), providerID, err)
-		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(SyncResult{
-			Errors:     []string{"Failed to create Calendar service"},
-			LastSyncAt: time.Now().Format(time.RFC3339),
-		})
+		http.Error(w, "Failed to reach Google Calendar", http.StatusBadGateway)
 		return
 	}
+	defer done()
 
-	// Fetch events for the next 7 days
-	now := time.Now()
-	timeMin := now.Format(time.RFC3339)
-	timeMax := now.AddDate(0, 0, 7).Format(time.RFC3339)
-
-	events, err := calService.Events.List(calendarID).
-		TimeMin(timeMin).
-		TimeMax(timeMax).
-		SingleEvents(true).
-		OrderBy("startTime").
-		MaxResults(100).
-		Do()
-
+	entries, err := api.ListCalendars(r.Context())
 	if err != nil {
-		h.markSyncError(r.Context(), providerID, err)
-		log.Printf("❌ Google Calendar fetch error: %v", err)
-		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(SyncResult{
-			Errors:     []string{fmt.Sprintf("Failed to fetch events: %v", err)},
-			LastSyncAt: time.Now().Format(time.RFC3339),
-		})
+		log.Printf("❌ Google calendar list error for user %s: %v", userID, err)
+		http.Error(w, "Failed to fetch calendars", http.StatusBadGateway)
 		return
 	}
 
-	// If token was refreshed by the OAuth client, save the new token
-	newToken, err := client.Transport.(*oauth2.Transport).Source.Token()
-	if err == nil && newToken.AccessToken != accessToken {
-		if _, err := h.db.Exec(r.Context(), `
-			UPDATE public.calendar_providers
-			SET access_token = $1, token_expiry = $2, updated_at = NOW()
-			WHERE id = $3
-		`, newToken.AccessToken, newToken.Expiry, providerID); err != nil {
-			log.Printf("Failed to save refreshed Google Calendar token for user %s: %v", userID, err)
-		} else {
-			log.Printf("🔄 Google Calendar token refreshed for user %s", userID)
-		}
-	}
-
-	// Upsert events into calendar_events
-	imported := 0
-	var syncErrors []string
-
-	log.Printf("📅 Google Calendar: %d events fetched from API for user %s", len(events.Items), userID)
-
-	for _, event := range events.Items {
-		if event.Status == "cancelled" {
-			// Mark cancelled events
-			if _, err := h.db.Exec(r.Context(), `
-				UPDATE public.calendar_events
-				SET event_status = 'cancelled', updated_at = NOW()
-				WHERE provider_id = $1 AND external_event_id = $2
-			`, providerID, event.Id); err != nil {
-				log.Printf("Failed to mark event %s as cancelled: %v", event.Id, err)
-			}
+	for _, entry := range entries {
+		if entry.Deleted {
 			continue
 		}
-
-		startAt, endAt, isAllDay := parseGoogleDateTime(event.Start, event.End)
-		if startAt.IsZero() {
-			log.Printf("⚠️ Skipping event '%s': startAt is zero (Start=%+v)", event.Summary, event.Start)
-			continue
-		}
-		log.Printf("📝 Processing event '%s': %s -> %s (allDay=%v)", event.Summary, startAt, endAt, isAllDay)
-
-		eventType := "default"
-		if event.EventType != "" {
-			eventType = event.EventType
+		// The legacy "primary" alias and the primary calendar's real ID are the same calendar
+		calendarID := entry.Id
+		if entry.Primary {
+			var existing string
+			if err := h.db.QueryRow(r.Context(), `
+				SELECT external_calendar_id FROM public.calendar_sources
+				WHERE provider_id = $1 AND external_calendar_id = 'primary'
+			`, p.id).Scan(&existing); err == nil {
+				calendarID = existing
+			}
 		}
-
-		// Auto-enable blocking for focusTime events
-		autoBlock := eventType == "focusTime"
-
-		rawDataBytes, _ := json.Marshal(event)
-		rawData := string(rawDataBytes)
-
-		upsertQuery := `
-			INSERT INTO public.calendar_events (
-				user_id, provider_id, external_event_id, external_calendar_id,
-				title, description, location, start_at, end_at,
-				is_all_day, timezone, event_status, is_busy, event_type,
-				synced_at, raw_data, created_at, updated_at
+		if _, err := h.db.Exec(r.Context(), `
+			INSERT INTO public.calendar_sources (
+				user_id, provider_id, external_calendar_id, name, color, is_primary, is_selected
 			)
-			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, NOW(), $15, NOW(), NOW())
-			ON CONFLICT (provider_id, external_event_id)
+			VALUES ($1, $2, $3, NULLIF($4, ''), NULLIF($5, ''), $6, $6)
+			ON CONFLICT (provider_id, external_calendar_id)
 			DO UPDATE SET
-				title = EXCLUDED.title,
-				description = EXCLUDED.description,
-				location = EXCLUDED.location,
-				start_at = EXCLUDED.start_at,
-				end_at = EXCLUDED.end_at,
-				is_all_day = EXCLUDED.is_all_day,
-				timezone = EXCLUDED.timezone,
-				event_status = EXCLUDED.event_status,
-				is_busy = EXCLUDED.is_busy,
-				event_type = EXCLUDED.event_type,
-				synced_at = NOW(),
-				raw_data = EXCLUDED.raw_data,
+				name = EXCLUDED.name,
+				color = EXCLUDED.color,
+				is_primary = EXCLUDED.is_primary,
 				updated_at = NOW()
-		`
-		// Note: block_apps is NOT overwritten during sync — user control is preserved
-
-		status := "confirmed"
-		if event.Status == "tentative" {
-			status = "tentative"
+		`, userID, p.id, calendarID, entry.Summary, entry.BackgroundColor, entry.Primary); err != nil {
+			log.Printf("Failed to save calendar %s for user %s: %v", entry.Id, userID, err)
 		}
+	}
 
-		isBusy := event.Transparency != "transparent"
-
-		_, err := h.db.Exec(r.Context(), upsertQuery,
-			userID, providerID, event.Id, calendarID,
-			event.Summary, event.Description, event.Location,
-			startAt, endAt, isAllDay,
-			getTimezone(event.Start), status, isBusy, eventType,
-			rawData,
-		)
+	rows, err := h.db.Query(r.Context(), `
+		SELECT id, external_calendar_id, COALESCE(name, ''), COALESCE(color, ''), is_primary, is_selected
+		FROM public.calendar_sources
+		WHERE provider_id = $1
+		ORDER BY is_primary DESC, name ASC NULLS LAST
+	`, p.id)
+	if err != nil {
+		http.Error(w, "Failed to fetch calendars", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
 
-		if err != nil {
-			log.Printf("❌ Upsert error for event '%s' (id=%s): %v", event.Summary, event.Id, err)
-			syncErrors = append(syncErrors, fmt.Sprintf("Event %s: %v", event.Id, err))
+	calendars := []CalendarResponse{}
+	for rows.Next() {
+		var c CalendarResponse
+		if err := rows.Scan(&c.ID, &c.CalendarID, &c.Name, &c.Color, &c.IsPrimary, &c.IsSelected); err != nil {
 			continue
 		}
+		calendars = append(calendars, c)
+	}
 
-		// If auto-block and event is new (not updated), set block_apps
-		if autoBlock {
-			if _, err := h.db.Exec(r.Context(), `
-				UPDATE public.calendar_events
-				SET block_apps = true, block_apps_source = 'auto'
-				WHERE provider_id = $1 AND external_event_id = $2
-				  AND block_apps = false AND block_apps_source = 'manual'
-			`, providerID, event.Id); err != nil {
-				log.Printf("Failed to auto-block event %s: %v", event.Id, err)
-			}
-		}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"calendars": calendars,
+		"count":     len(calendars),
+	})
+}
 
-		imported++
+// Sync pulls changes from Google Calendar (incrementally once a sync token
+// is stored) and pushes scheduled tasks that aren't mirrored yet
+// POST /google-calendar/sync
+func (h *Handler) Sync(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(auth.UserContextKey).(string)
+	log.Printf("📅 Starting Google Calendar sync for user %s", userID)
+
+	p, err := h.loadProvider(r.Context(), userID)
+	if err != nil {
+		log.Printf("❌ Google Calendar not connected: %v", err)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(SyncResult{
+			Errors:     []string{"Google Calendar not connected"},
+			LastSyncAt: time.Now().Format(time.RFC3339),
+		})
+		return
 	}
 
-	// Mark sync success
-	if _, err := h.db.Exec(r.Context(), `
-		UPDATE public.calendar_providers
-		SET last_sync_at = NOW(),
-			last_sync_status = 'success',
-			last_sync_error = NULL,
-			updated_at = NOW()
-		WHERE id = $1
-	`, providerID); err != nil {
-		log.Printf("Failed to mark sync success for provider %s: %v", providerID, err)
+	ctx, cancel := context.WithTimeout(r.Context(), syncTimeout)
+	defer cancel()
+
+	stats, err := h.syncProvider(ctx, p)
+	if err != nil {
+		log.Printf("❌ Google Calendar sync error: %v", err)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(SyncResult{
+			Errors:     []string{fmt.Sprintf("Failed to fetch events: %v", err)},
+			LastSyncAt: time.Now().Format(time.RFC3339),
+		})
+		return
 	}
+	syncErrors := stats.errors
 
-	log.Printf("✅ Google Calendar sync: %d events imported for user %s", imported, userID)
+	// Push scheduled tasks of the coming week that aren't mirrored yet
+	clock := userclock.For(ctx, h.db, userID)
+	tasksSynced, pushErrors := h.pushPendingTasks(ctx, userID, clock.Today(), clock.AddDays(7))
+	syncErrors = append(syncErrors, pushErrors...)
 
 	result := SyncResult{
-		TasksSynced:    0,
-		EventsImported: imported,
+		TasksSynced:    tasksSynced,
+		EventsImported: stats.imported,
+		EventsDeleted:  stats.deleted,
 		LastSyncAt:     time.Now().Format(time.RFC3339),
 	}
 	if len(syncErrors) > 0 {
@@ -491,14 +444,14 @@ func (h *Handler) getConfigFromDB(ctx context.Context, userID string) ConfigResp
 
 	query := `
 		SELECT is_connected, is_active, COALESCE(provider_email, ''),
-			   COALESCE(sync_direction, 'from_provider'),
+			   COALESCE(sync_direction, $2),
 			   COALESCE(provider_config->>'calendar_id', 'primary'),
 			   last_sync_at
 		FROM public.calendar_providers
 		WHERE user_id = $1 AND provider_type = 'google'
 		ORDER BY updated_at DESC LIMIT 1
 	`
-	err := h.db.QueryRow(ctx, query, userID).Scan(
+	err := h.db.QueryRow(ctx, query, userID, defaultSyncDirection).Scan(
 		&isConnected, &isActive, &email, &syncDirection, &calendarID, &lastSyncAt,
 	)
 
@@ -506,7 +459,7 @@ func (h *Handler) getConfigFromDB(ctx context.Context, userID string) ConfigResp
 		return ConfigResponse{
 			IsConnected:   false,
 			IsEnabled:     false,
-			SyncDirection: "from_provider",
+			SyncDirection: defaultSyncDirection,
 			CalendarID:    "primary",
 		}
 	}
@@ -0,0 +1,252 @@
+package gcalendar
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"log"
+	"sync"
+	"time"
+
+	"firelevel-backend/internal/userclock"
+
+	googlecalendar "google.golang.org/api/calendar/v3"
+)
+
+// =============================================
+// TASK → GOOGLE CALENDAR PUSH
+// =============================================
+// Implements calendar.GoogleCalendarSyncer. A task with a
+// scheduled slot is mirrored as one Google event; its ID is
+// stored in tasks.google_event_id. Pushing only happens when
+// the provider is active and sync_direction != 'from_provider'.
+// New connections are two-way (defaultSyncDirection).
+// =============================================
+
+// taskIDProperty tags events created from Focus tasks so Sync doesn't
+// import them back as external calendar events.
+const taskIDProperty = "firelevel_task_id"
+
+// defaultSyncDirection is the sync_direction of a new Google connection,
+// as the column default: events are imported and tasks pushed.
+const defaultSyncDirection = "bidirectional"
+
+// errPushDisabled means the user has no active Google provider that accepts pushes.
+var errPushDisabled = errors.New("google calendar push disabled")
+
+// taskLocks serialises pushes per task so concurrent create/update
+// goroutines don't insert the same event twice.
+var taskLocks [64]sync.Mutex
+
+func lockTask(taskID string) func() {
+	hash := fnv.New32a()
+	hash.Write([]byte(taskID))
+	mu := &taskLocks[hash.Sum32()%uint32(len(taskLocks))]
+	mu.Lock()
+	return mu.Unlock
+}
+
+// loadPushTarget returns the user's active Google provider, or errPushDisabled.
+func (h *Handler) loadPushTarget(ctx context.Context, userID string) (*provider, error) {
+	p, err := h.loadProvider(ctx, userID)
+	if err != nil || !p.pushEnabled() {
+		return nil, errPushDisabled
+	}
+	return p, nil
+}
+
+// pushEnabled reports whether tasks are mirrored to this provider.
+func (p *provider) pushEnabled() bool {
+	return p.isActive && p.syncDirection != "from_provider"
+}
+
+// SyncTaskToGoogleCalendar creates or updates the Google event mirroring a task.
+// A task without a scheduled slot has its event removed.
+func (h *Handler) SyncTaskToGoogleCalendar(ctx context.Context, userID, taskID, title string, description *string, date string, startTime, endTime *string) error {
+	target, err := h.loadPushTarget(ctx, userID)
+	if err != nil {
+		return nil // Not connected or push disabled: nothing to do
+	}
+
+	unlock := lockTask(taskID)
+	defer unlock()
+
+	var eventID *string
+	var status string
+	if err := h.db.QueryRow(ctx, `
+		SELECT google_event_id, COALESCE(status, 'pending') FROM tasks WHERE id = $1 AND user_id = $2
+	`, taskID, userID).Scan(&eventID, &status); err != nil {
+		return fmt.Errorf("load task %s: %w", taskID, err)
+	}
+
+	loc := userclock.For(ctx, h.db, userID).Location()
+	event, err := buildTaskEvent(taskID, title, description, status, date, startTime, endTime, loc)
+	if err != nil {
+		return err
+	}
+	if event == nil && eventID == nil {
+		return nil
+	}
+
+	api, done, err := h.eventsAPI(ctx, target)
+	if err != nil {
+		return err
+	}
+	defer done()
+
+	newEventID, err := pushTaskEvent(ctx, api, target.calendarID, eventID, event)
+	if err != nil {
+		return err
+	}
+	if sameEventID(newEventID, eventID) {
+		return nil
+	}
+	if newEventID != nil {
+		log.Printf("📤 Task %s pushed to Google Calendar as %s", taskID, *newEventID)
+	}
+	return h.setTaskEventID(ctx, userID, taskID, newEventID)
+}
+
+// buildTaskEvent returns the Google event mirroring a task, or nil when the
+// task has no scheduled slot.
+func buildTaskEvent(taskID, title string, description *string, status, date string, startTime, endTime *string, loc *time.Location) (*googlecalendar.Event, error) {
+	if startTime == nil || endTime == nil {
+		return nil, nil
+	}
+
+	start, err := time.ParseInLocation("2006-01-02 15:04", date+" "+*startTime, loc)
+	if err != nil {
+		return nil, fmt.Errorf("invalid start for task %s: %w", taskID, err)
+	}
+	end, err := time.ParseInLocation("2006-01-02 15:04", date+" "+*endTime, loc)
+	if err != nil {
+		return nil, fmt.Errorf("invalid end for task %s: %w", taskID, err)
+	}
+
+	summary := title
+	if status == "completed" {
+		summary = "✅ " + title
+	}
+	event := &googlecalendar.Event{
+		Summary: summary,
+		Start:   &googlecalendar.EventDateTime{DateTime: start.Format(time.RFC3339), TimeZone: loc.String()},
+		End:     &googlecalendar.EventDateTime{DateTime: end.Format(time.RFC3339), TimeZone: loc.String()},
+		ExtendedProperties: &googlecalendar.EventExtendedProperties{
+			Private: map[string]string{taskIDProperty: taskID},
+		},
+	}
+	if description != nil {
+		event.Description = *description
+	}
+	return event, nil
+}
+
+// pushTaskEvent mirrors event on the calendar and returns the event ID the
+// task should store. A nil event removes the mirrored one; an event deleted
+// on Google's side is recreated.
+func pushTaskEvent(ctx context.Context, api EventsAPI, calendarID string, eventID *string, event *googlecalendar.Event) (*string, error) {
+	if event == nil {
+		if eventID == nil {
+			return nil, nil
+		}
+		if err := api.Delete(ctx, calendarID, *eventID); err != nil && !errors.Is(err, ErrEventNotFound) {
+			return eventID, err
+		}
+		return nil, nil
+	}
+
+	if eventID != nil && *eventID != "" {
+		_, err := api.Update(ctx, calendarID, *eventID, event)
+		if err == nil {
+			return eventID, nil
+		}
+		if !errors.Is(err, ErrEventNotFound) {
+			return eventID, err
+		}
+		// Deleted on Google's side: recreate it below
+	}
+
+	created, err := api.Insert(ctx, calendarID, event)
+	if err != nil {
+		return eventID, err
+	}
+	return &created.Id, nil
+}
+
+// DeleteGoogleCalendarEvent removes a mirrored event. Missing events are ignored.
+func (h *Handler) DeleteGoogleCalendarEvent(ctx context.Context, userID, googleEventID string) error {
+	target, err := h.loadPushTarget(ctx, userID)
+	if err != nil {
+		return nil
+	}
+	api, done, err := h.eventsAPI(ctx, target)
+	if err != nil {
+		return err
+	}
+	defer done()
+
+	if err := api.Delete(ctx, target.calendarID, googleEventID); err != nil && !errors.Is(err, ErrEventNotFound) {
+		return err
+	}
+	return nil
+}
+
+func sameEventID(a, b *string) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+func (h *Handler) setTaskEventID(ctx context.Context, userID, taskID string, eventID *string) error {
+	_, err := h.db.Exec(ctx, `
+		UPDATE tasks SET google_event_id = $3 WHERE id = $1 AND user_id = $2
+	`, taskID, userID, eventID)
+	return err
+}
+
+// pushPendingTasks mirrors scheduled tasks in [from, to] that have no Google
+// event yet. Returns how many were pushed.
+func (h *Handler) pushPendingTasks(ctx context.Context, userID, from, to string) (int, []string) {
+	if _, err := h.loadPushTarget(ctx, userID); err != nil {
+		return 0, nil
+	}
+
+	rows, err := h.db.Query(ctx, `
+		SELECT id, title, description, TO_CHAR(date, 'YYYY-MM-DD'),
+			TO_CHAR(scheduled_start, 'HH24:MI'), TO_CHAR(scheduled_end, 'HH24:MI')
+		FROM tasks
+		WHERE user_id = $1 AND date BETWEEN $2 AND $3
+		  AND scheduled_start IS NOT NULL AND scheduled_end IS NOT NULL
+		  AND google_event_id IS NULL AND recurrence_rule IS NULL
+	`, userID, from, to)
+	if err != nil {
+		return 0, []string{fmt.Sprintf("Failed to load tasks: %v", err)}
+	}
+
+	type pendingTask struct {
+		id, title, date string
+		description     *string
+		start, end      *string
+	}
+	var pending []pendingTask
+	for rows.Next() {
+		var t pendingTask
+		if err := rows.Scan(&t.id, &t.title, &t.description, &t.date, &t.start, &t.end); err == nil {
+			pending = append(pending, t)
+		}
+	}
+	rows.Close()
+
+	synced := 0
+	var errs []string
+	for _, t := range pending {
+		if err := h.SyncTaskToGoogleCalendar(ctx, userID, t.id, t.title, t.description, t.date, t.start, t.end); err != nil {
+			errs = append(errs, fmt.Sprintf("Task %s: %v", t.id, err))
+			continue
+		}
+		synced++
+	}
+	return synced, errs
+}
@@ -0,0 +1,232 @@
+package gcalendar
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	googlecalendar "google.golang.org/api/calendar/v3"
+)
+
+// fakeEventsAPI is an in-memory calendar keyed by event ID.
+type fakeEventsAPI struct {
+	events  map[string]*googlecalendar.Event
+	nextID  int
+	calls   []string
+	failAll error
+}
+
+func newFakeEventsAPI() *fakeEventsAPI {
+	return &fakeEventsAPI{events: map[string]*googlecalendar.Event{}}
+}
+
+func (f *fakeEventsAPI) Insert(ctx context.Context, calendarID string, event *googlecalendar.Event) (*googlecalendar.Event, error) {
+	f.calls = append(f.calls, "insert")
+	if f.failAll != nil {
+		return nil, f.failAll
+	}
+	f.nextID++
+	created := *event
+	created.Id = fmt.Sprintf("evt%d", f.nextID)
+	f.events[created.Id] = &created
+	return &created, nil
+}
+
+func (f *fakeEventsAPI) Update(ctx context.Context, calendarID, eventID string, event *googlecalendar.Event) (*googlecalendar.Event, error) {
+	f.calls = append(f.calls, "update")
+	if f.failAll != nil {
+		return nil, f.failAll
+	}
+	if _, ok := f.events[eventID]; !ok {
+		return nil, ErrEventNotFound
+	}
+	updated := *event
+	updated.Id = eventID
+	f.events[eventID] = &updated
+	return &updated, nil
+}
+
+func (f *fakeEventsAPI) Delete(ctx context.Context, calendarID, eventID string) error {
+	f.calls = append(f.calls, "delete")
+	if f.failAll != nil {
+		return f.failAll
+	}
+	if _, ok := f.events[eventID]; !ok {
+		return ErrEventNotFound
+	}
+	delete(f.events, eventID)
+	return nil
+}
+
+func (f *fakeEventsAPI) List(ctx context.Context, calendarID string, opts ListOptions) (*googlecalendar.Events, error) {
+	return &googlecalendar.Events{}, nil
+}
+
+func (f *fakeEventsAPI) ListCalendars(ctx context.Context) ([]*googlecalendar.CalendarListEntry, error) {
+	return nil, nil
+}
+
+func (f *fakeEventsAPI) Watch(ctx context.Context, calendarID string, channel *googlecalendar.Channel) (*googlecalendar.Channel, error) {
+	return channel, nil
+}
+
+func (f *fakeEventsAPI) StopChannel(ctx context.Context, channelID, resourceID string) error {
+	return nil
+}
+
+func strPtr(s string) *string { return &s }
+
+func TestBuildTaskEvent(t *testing.T) {
+	paris, err := time.LoadLocation("Europe/Paris")
+	if err != nil {
+		t.Skip("tzdata unavailable")
+	}
+
+	event, err := buildTaskEvent("task1", "Sport", strPtr("Salle"), "completed", "2026-10-16", strPtr("09:00"), strPtr("10:30"), paris)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if event.Summary != "✅ Sport" {
+		t.Errorf("Summary = %q", event.Summary)
+	}
+	if event.Description != "Salle" {
+		t.Errorf("Description = %q", event.Description)
+	}
+	if event.Start.DateTime != "2026-10-16T09:00:00+02:00" || event.End.DateTime != "2026-10-16T10:30:00+02:00" {
+		t.Errorf("slot = %s → %s", event.Start.DateTime, event.End.DateTime)
+	}
+	if event.ExtendedProperties.Private[taskIDProperty] != "task1" {
+		t.Errorf("event is not tagged with the task ID: %v", event.ExtendedProperties.Private)
+	}
+
+	if event, err := buildTaskEvent("task1", "Sport", nil, "pending", "2026-10-16", nil, nil, paris); err != nil || event != nil {
+		t.Errorf("unscheduled task: event = %v, err = %v", event, err)
+	}
+	if _, err := buildTaskEvent("task1", "Sport", nil, "pending", "2026-10-16", strPtr("9h"), strPtr("10:00"), paris); err == nil {
+		t.Error("expected an error for an invalid start time")
+	}
+}
+
+func TestPushTaskEvent(t *testing.T) {
+	ctx := context.Background()
+	event := &googlecalendar.Event{Summary: "Sport"}
+
+	t.Run("creates a missing event", func(t *testing.T) {
+		api := newFakeEventsAPI()
+		id, err := pushTaskEvent(ctx, api, "primary", nil, event)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if id == nil || api.events[*id] == nil {
+			t.Fatalf("event not created, id = %v", id)
+		}
+	})
+
+	t.Run("updates an existing event in place", func(t *testing.T) {
+		api := newFakeEventsAPI()
+		api.events["evt9"] = &googlecalendar.Event{Id: "evt9", Summary: "Old"}
+		id, err := pushTaskEvent(ctx, api, "primary", strPtr("evt9"), event)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if id == nil || *id != "evt9" || api.events["evt9"].Summary != "Sport" {
+			t.Errorf("id = %v, event = %+v", id, api.events["evt9"])
+		}
+		if len(api.calls) != 1 {
+			t.Errorf("calls = %v, want a single update", api.calls)
+		}
+	})
+
+	t.Run("recreates an event deleted on Google", func(t *testing.T) {
+		api := newFakeEventsAPI()
+		id, err := pushTaskEvent(ctx, api, "primary", strPtr("gone"), event)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if id == nil || *id == "gone" {
+			t.Errorf("id = %v, want a new event ID", id)
+		}
+		if fmt.Sprint(api.calls) != "[update insert]" {
+			t.Errorf("calls = %v", api.calls)
+		}
+	})
+
+	t.Run("removes the event when the slot is cleared", func(t *testing.T) {
+		api := newFakeEventsAPI()
+		api.events["evt1"] = &googlecalendar.Event{Id: "evt1"}
+		id, err := pushTaskEvent(ctx, api, "primary", strPtr("evt1"), nil)
+		if err != nil || id != nil {
+			t.Fatalf("id = %v, err = %v", id, err)
+		}
+		if len(api.events) != 0 {
+			t.Error("event was not deleted")
+		}
+	})
+
+	t.Run("ignores events already gone when removing", func(t *testing.T) {
+		api := newFakeEventsAPI()
+		id, err := pushTaskEvent(ctx, api, "primary", strPtr("gone"), nil)
+		if err != nil || id != nil {
+			t.Errorf("id = %v, err = %v", id, err)
+		}
+	})
+
+	t.Run("keeps the stored ID on API errors", func(t *testing.T) {
+		api := newFakeEventsAPI()
+		api.failAll = errors.New("quota exceeded")
+		id, err := pushTaskEvent(ctx, api, "primary", strPtr("evt1"), event)
+		if err == nil {
+			t.Fatal("expected an error")
+		}
+		if id == nil || *id != "evt1" {
+			t.Errorf("id = %v, want evt1", id)
+		}
+	})
+}
+
+func TestSameEventID(t *testing.T) {
+	if !sameEventID(nil, nil) || !sameEventID(strPtr("a"), strPtr("a")) {
+		t.Error("equal IDs reported as different")
+	}
+	if sameEventID(nil, strPtr("a")) || sameEventID(strPtr("a"), strPtr("b")) {
+		t.Error("different IDs reported as equal")
+	}
+}
+
+func TestFreshConnectionPushesTasks(t *testing.T) {
+	// The provider row SaveTokens creates for a new connection
+	fresh := &provider{calendarID: "primary", syncDirection: defaultSyncDirection, isActive: true}
+	if !fresh.pushEnabled() {
+		t.Fatalf("a new connection (%s) does not push tasks", defaultSyncDirection)
+	}
+
+	event, err := buildTaskEvent("task1", "Sport", nil, "pending", "2026-10-16", strPtr("09:00"), strPtr("10:00"), time.UTC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	api := newFakeEventsAPI()
+	id, err := pushTaskEvent(context.Background(), api, fresh.calendarID, nil, event)
+	if err != nil {
+		t.Fatalf("pushTaskEvent: %v", err)
+	}
+	if id == nil || api.events[*id] == nil {
+		t.Fatalf("task not pushed: id=%v calls=%v", id, api.calls)
+	}
+
+	tests := []struct {
+		name string
+		p    provider
+		want bool
+	}{
+		{"two-way", provider{syncDirection: "bidirectional", isActive: true}, true},
+		{"import only", provider{syncDirection: "from_provider", isActive: true}, false},
+		{"disabled", provider{syncDirection: "bidirectional", isActive: false}, false},
+	}
+	for _, tt := range tests {
+		if got := tt.p.pushEnabled(); got != tt.want {
+			t.Errorf("%s: pushEnabled = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,561 @@
+package gcalendar
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"firelevel-backend/internal/userclock"
+
+	"github.com/google/uuid"
+	googlecalendar "google.golang.org/api/calendar/v3"
+)
+
+// =============================================
+// INCREMENTAL SYNC + PUSH CHANNELS
+// =============================================
+// Each selected calendar (calendar_sources) is synced on its
+// own: the first sync lists events from the start of today
+// and stores Google's nextSyncToken on the source; later
+// syncs only fetch what changed. A 410 drops the token and
+// runs a full resync, sweeping events Google no longer has.
+//
+// Google push channels (events.watch, one per calendar) call
+// POST /webhooks/google-calendar, which only flags the
+// provider (sync_requested_at). calendarevents.SyncWorker
+// picks flagged providers up in the background; ChannelWorker
+// renews channels before they expire.
+// =============================================
+
+const (
+	// channelInterval is how often channels are checked for renewal.
+	channelInterval = 30 * time.Minute
+
+	// channelTTL is the lifetime asked for new channels (Google caps it).
+	channelTTL = 7 * 24 * time.Hour
+
+	// channelRenewBefore renews a channel this long before it expires.
+	channelRenewBefore = 24 * time.Hour
+
+	// syncBatchSize bounds how many channels one worker tick renews.
+	syncBatchSize = 20
+
+	// syncTimeout bounds a single provider sync.
+	syncTimeout = 2 * time.Minute
+)
+
+// syncStats summarises one provider sync.
+type syncStats struct {
+	imported int
+	deleted  int
+	errors   []string
+}
+
+// calendarSource is one selected Google calendar of a provider
+// (a calendar_sources row).
+type calendarSource struct {
+	id                string
+	calendarID        string
+	syncToken         string
+	channelID         string
+	channelResourceID string
+}
+
+const sourceColumns = `
+	id, external_calendar_id, COALESCE(sync_token, ''),
+	COALESCE(channel_id, ''), COALESCE(channel_resource_id, '')
+`
+
+func scanSource(row interface{ Scan(...any) error }) (*calendarSource, error) {
+	var src calendarSource
+	if err := row.Scan(&src.id, &src.calendarID, &src.syncToken, &src.channelID, &src.channelResourceID); err != nil {
+		return nil, err
+	}
+	return &src, nil
+}
+
+// selectedSources returns the calendars of p to sync. A provider without any
+// source yet (calendar list never fetched) gets its push calendar as primary.
+func (h *Handler) selectedSources(ctx context.Context, p *provider) ([]*calendarSource, error) {
+	if _, err := h.db.Exec(ctx, `
+		INSERT INTO public.calendar_sources (user_id, provider_id, external_calendar_id, is_primary, is_selected)
+		SELECT $1, $2, $3, true, true
+		WHERE NOT EXISTS (SELECT 1 FROM public.calendar_sources WHERE provider_id = $2)
+		ON CONFLICT (provider_id, external_calendar_id) DO NOTHING
+	`, p.userID, p.id, p.calendarID); err != nil {
+		return nil, err
+	}
+
+	rows, err := h.db.Query(ctx, `
+		SELECT `+sourceColumns+`
+		FROM public.calendar_sources
+		WHERE provider_id = $1 AND is_selected = true
+		ORDER BY is_primary DESC, created_at ASC
+	`, p.id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var sources []*calendarSource
+	for rows.Next() {
+		src, err := scanSource(rows)
+		if err != nil {
+			return nil, err
+		}
+		sources = append(sources, src)
+	}
+	return sources, rows.Err()
+}
+
+// SyncProvider implements calendarevents.Syncer for Google providers.
+func (h *Handler) SyncProvider(ctx context.Context, providerID string) error {
+	p, err := h.loadProviderByID(ctx, providerID)
+	if err != nil {
+		return err
+	}
+	_, err = h.syncProvider(ctx, p)
+	return err
+}
+
+// syncProvider runs an incremental sync (or a full one when there is no
+// valid sync token) of every selected calendar and records the outcome on
+// the provider and source rows.
+func (h *Handler) syncProvider(ctx context.Context, p *provider) (*syncStats, error) {
+	if _, err := h.db.Exec(ctx, `
+		UPDATE public.calendar_providers
+		SET last_sync_status = 'in_progress', updated_at = NOW()
+		WHERE id = $1
+	`, p.id); err != nil {
+		log.Printf("Failed to mark sync in_progress for provider %s: %v", p.id, err)
+	}
+
+	sources, err := h.selectedSources(ctx, p)
+	if err != nil {
+		h.markSyncError(context.Background(), p.id, err)
+		return nil, err
+	}
+
+	api, done, err := h.eventsAPI(ctx, p)
+	if err != nil {
+		h.markSyncError(context.Background(), p.id, err)
+		return nil, err
+	}
+	defer done()
+
+	stats := &syncStats{}
+	var syncErr error
+	for _, src := range sources {
+		err := h.syncEvents(ctx, api, p, src, stats)
+		if errors.Is(err, ErrSyncTokenExpired) {
+			log.Printf("🔁 Google Calendar sync token expired for calendar %s of user %s, running a full resync", src.calendarID, p.userID)
+			src.syncToken = ""
+			err = h.syncEvents(ctx, api, p, src, stats)
+		}
+		h.markSource(src.id, err)
+		if err != nil {
+			log.Printf("❌ Google Calendar sync failed for calendar %s of user %s: %v", src.calendarID, p.userID, err)
+			syncErr = errors.Join(syncErr, err)
+		}
+	}
+	if syncErr != nil {
+		h.markSyncError(context.Background(), p.id, syncErr)
+		return stats, syncErr
+	}
+
+	if _, err := h.db.Exec(ctx, `
+		UPDATE public.calendar_providers
+		SET last_sync_at = NOW(),
+			last_sync_status = 'success',
+			last_sync_error = NULL,
+			updated_at = NOW()
+		WHERE id = $1
+	`, p.id); err != nil {
+		log.Printf("Failed to mark sync success for provider %s: %v", p.id, err)
+	}
+
+	log.Printf("✅ Google Calendar sync: %d events imported, %d deleted from %d calendars for user %s", stats.imported, stats.deleted, len(sources), p.userID)
+	return stats, nil
+}
+
+// markSource records the outcome of one calendar sync.
+func (h *Handler) markSource(sourceID string, syncErr error) {
+	status, message := "success", (*string)(nil)
+	if syncErr != nil {
+		status = "error"
+		msg := syncErr.Error()
+		message = &msg
+	}
+	if _, err := h.db.Exec(context.Background(), `
+		UPDATE public.calendar_sources
+		SET last_sync_at = NOW(), last_sync_status = $2, last_sync_error = $3, updated_at = NOW()
+		WHERE id = $1
+	`, sourceID, status, message); err != nil {
+		log.Printf("Failed to record sync status for calendar %s: %v", sourceID, err)
+	}
+}
+
+// syncEvents pages through the changes of one calendar since src.syncToken
+// (or every event from today when empty) and stores the next sync token.
+func (h *Handler) syncEvents(ctx context.Context, api EventsAPI, p *provider, src *calendarSource, stats *syncStats) error {
+	fullSync := src.syncToken == ""
+
+	// Database time, so the sweep below compares against synced_at consistently
+	var syncStart time.Time
+	if err := h.db.QueryRow(ctx, `SELECT NOW()`).Scan(&syncStart); err != nil {
+		return err
+	}
+
+	opts := ListOptions{SyncToken: src.syncToken}
+	if fullSync {
+		opts.TimeMin, _ = userclock.For(ctx, h.db, p.userID).TodayBounds()
+	}
+
+	var nextSyncToken string
+	for {
+		page, err := api.List(ctx, src.calendarID, opts)
+		if err != nil {
+			return err
+		}
+
+		for _, event := range page.Items {
+			if event.Status == "cancelled" {
+				// Removed upstream
+				tag, err := h.db.Exec(ctx, `
+					DELETE FROM public.calendar_events
+					WHERE source_id = $1 AND external_event_id = $2
+				`, src.id, event.Id)
+				if err != nil {
+					log.Printf("Failed to delete cancelled event %s: %v", event.Id, err)
+					continue
+				}
+				stats.deleted += int(tag.RowsAffected())
+				continue
+			}
+
+			// Events we pushed from Focus tasks are already on the calendar as tasks
+			if event.ExtendedProperties != nil && event.ExtendedProperties.Private[taskIDProperty] != "" {
+				continue
+			}
+
+			if err := h.upsertEvent(ctx, p, src, event); err != nil {
+				log.Printf("❌ Upsert error for event '%s' (id=%s): %v", event.Summary, event.Id, err)
+				stats.errors = append(stats.errors, fmt.Sprintf("Event %s: %v", event.Id, err))
+				continue
+			}
+			stats.imported++
+		}
+
+		if page.NextPageToken == "" {
+			nextSyncToken = page.NextSyncToken
+			break
+		}
+		opts.PageToken = page.NextPageToken
+	}
+
+	// A full listing is authoritative: anything from today on that
+	// wasn't seen is gone upstream
+	if fullSync {
+		tag, err := h.db.Exec(ctx, `
+			DELETE FROM public.calendar_events
+			WHERE source_id = $1 AND synced_at < $2 AND end_at >= $3
+		`, src.id, syncStart, opts.TimeMin)
+		if err != nil {
+			log.Printf("Failed to sweep stale events for calendar %s: %v", src.id, err)
+		} else {
+			stats.deleted += int(tag.RowsAffected())
+		}
+	}
+
+	if _, err := h.db.Exec(ctx, `
+		UPDATE public.calendar_sources SET sync_token = NULLIF($2, ''), updated_at = NOW() WHERE id = $1
+	`, src.id, nextSyncToken); err != nil {
+		return fmt.Errorf("save sync token: %w", err)
+	}
+	src.syncToken = nextSyncToken
+	return nil
+}
+
+// upsertEvent caches one Google event in calendar_events.
+func (h *Handler) upsertEvent(ctx context.Context, p *provider, src *calendarSource, event *googlecalendar.Event) error {
+	startAt, endAt, isAllDay := parseGoogleDateTime(event.Start, event.End)
+	if startAt.IsZero() {
+		log.Printf("⚠️ Skipping event '%s': startAt is zero (Start=%+v)", event.Summary, event.Start)
+		return nil
+	}
+
+	eventType := "default"
+	if event.EventType != "" {
+		eventType = event.EventType
+	}
+
+	// Auto-enable blocking for focusTime events
+	autoBlock := eventType == "focusTime"
+
+	rawDataBytes, _ := json.Marshal(event)
+	rawData := string(rawDataBytes)
+
+	status := "confirmed"
+	if event.Status == "tentative" {
+		status = "tentative"
+	}
+
+	isBusy := event.Transparency != "transparent"
+
+	// Note: block_apps is NOT overwritten during sync — user control is preserved
+	_, err := h.db.Exec(ctx, `
+		INSERT INTO public.calendar_events (
+			user_id, provider_id, source_id, external_event_id, external_calendar_id,
+			title, description, location, start_at, end_at,
+			is_all_day, timezone, event_status, is_busy, event_type,
+			synced_at, raw_data, created_at, updated_at
+		)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, NOW(), $16, NOW(), NOW())
+		ON CONFLICT (source_id, external_event_id)
+		DO UPDATE SET
+			title = EXCLUDED.title,
+			description = EXCLUDED.description,
+			location = EXCLUDED.location,
+			start_at = EXCLUDED.start_at,
+			end_at = EXCLUDED.end_at,
+			is_all_day = EXCLUDED.is_all_day,
+			timezone = EXCLUDED.timezone,
+			event_status = EXCLUDED.event_status,
+			is_busy = EXCLUDED.is_busy,
+			event_type = EXCLUDED.event_type,
+			synced_at = NOW(),
+			raw_data = EXCLUDED.raw_data,
+			updated_at = NOW()
+	`,
+		p.userID, p.id, src.id, event.Id, src.calendarID,
+		event.Summary, event.Description, event.Location,
+		startAt, endAt, isAllDay,
+		getTimezone(event.Start), status, isBusy, eventType,
+		rawData,
+	)
+	if err != nil {
+		return err
+	}
+
+	// If auto-block and event is new (not updated), set block_apps
+	if autoBlock {
+		if _, err := h.db.Exec(ctx, `
+			UPDATE public.calendar_events
+			SET block_apps = true, block_apps_source = 'auto'
+			WHERE source_id = $1 AND external_event_id = $2
+			  AND block_apps = false AND block_apps_source = 'manual'
+		`, src.id, event.Id); err != nil {
+			log.Printf("Failed to auto-block event %s: %v", event.Id, err)
+		}
+	}
+	return nil
+}
+
+// Webhook receives Google Calendar push notifications and queues a sync.
+// Google only sends headers; the body is empty.
+// POST /webhooks/google-calendar (public, authenticated by channel token)
+func (h *Handler) Webhook(w http.ResponseWriter, r *http.Request) {
+	channelID := r.Header.Get("X-Goog-Channel-ID")
+	channelToken := r.Header.Get("X-Goog-Channel-Token")
+	state := r.Header.Get("X-Goog-Resource-State")
+	if channelID == "" || channelToken == "" {
+		http.Error(w, "Missing channel headers", http.StatusBadRequest)
+		return
+	}
+
+	// "sync" is the handshake sent when the channel is created
+	if state == "sync" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	tag, err := h.db.Exec(r.Context(), `
+		UPDATE public.calendar_providers cp
+		SET sync_requested_at = COALESCE(cp.sync_requested_at, NOW())
+		FROM public.calendar_sources cs
+		WHERE cs.provider_id = cp.id
+		  AND cs.channel_id = $1 AND cs.channel_token = $2
+		  AND cs.is_selected = true AND cp.is_connected = true
+	`, channelID, channelToken)
+	if err != nil {
+		log.Printf("❌ Google Calendar webhook error for channel %s: %v", channelID, err)
+		http.Error(w, "Failed to queue sync", http.StatusInternalServerError)
+		return
+	}
+	if tag.RowsAffected() == 0 {
+		// Stale or forged channel: acknowledge so Google stops retrying
+		log.Printf("⚠️ Google Calendar webhook for unknown channel %s", channelID)
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// ensureChannel (re)creates the push channel of one calendar, stopping the
+// previous one.
+func (h *Handler) ensureChannel(ctx context.Context, p *provider, src *calendarSource) error {
+	api, done, err := h.eventsAPI(ctx, p)
+	if err != nil {
+		return err
+	}
+	defer done()
+
+	if src.channelID != "" {
+		if err := api.StopChannel(ctx, src.channelID, src.channelResourceID); err != nil && !errors.Is(err, ErrEventNotFound) {
+			log.Printf("Failed to stop Google Calendar channel %s: %v", src.channelID, err)
+		}
+	}
+
+	channel, err := api.Watch(ctx, src.calendarID, &googlecalendar.Channel{
+		Id:         uuid.New().String(),
+		Type:       "web_hook",
+		Address:    h.webhookURL,
+		Token:      uuid.New().String(),
+		Expiration: time.Now().Add(channelTTL).UnixMilli(),
+	})
+	if err != nil {
+		return err
+	}
+
+	expiresAt := time.Now().Add(channelTTL)
+	if channel.Expiration > 0 {
+		expiresAt = time.UnixMilli(channel.Expiration)
+	}
+	_, err = h.db.Exec(ctx, `
+		UPDATE public.calendar_sources
+		SET channel_id = $2, channel_resource_id = $3, channel_token = $4, channel_expires_at = $5, updated_at = NOW()
+		WHERE id = $1
+	`, src.id, channel.Id, channel.ResourceId, channel.Token, expiresAt)
+	if err != nil {
+		return err
+	}
+	log.Printf("📡 Google Calendar channel %s watching %s for user %s until %s", channel.Id, src.calendarID, p.userID, expiresAt.Format(time.RFC3339))
+	return nil
+}
+
+// stopChannels stops the push channels of every calendar of p. Best effort.
+func (h *Handler) stopChannels(ctx context.Context, p *provider) {
+	rows, err := h.db.Query(ctx, `
+		SELECT `+sourceColumns+`
+		FROM public.calendar_sources
+		WHERE provider_id = $1 AND channel_id IS NOT NULL
+	`, p.id)
+	if err != nil {
+		return
+	}
+	var sources []*calendarSource
+	for rows.Next() {
+		if src, err := scanSource(rows); err == nil {
+			sources = append(sources, src)
+		}
+	}
+	rows.Close()
+	if len(sources) == 0 {
+		return
+	}
+
+	api, done, err := h.eventsAPI(ctx, p)
+	if err != nil {
+		return
+	}
+	defer done()
+	for _, src := range sources {
+		if err := api.StopChannel(ctx, src.channelID, src.channelResourceID); err != nil && !errors.Is(err, ErrEventNotFound) {
+			log.Printf("Failed to stop Google Calendar channel %s: %v", src.channelID, err)
+		}
+	}
+}
+
+// =============================================
+// BACKGROUND WORKER
+// =============================================
+// Queued syncs are run by calendarevents.SyncWorker, which
+// dispatches Google providers to Handler.SyncProvider.
+
+// ChannelWorker keeps a push channel alive on every selected calendar.
+type ChannelWorker struct {
+	h *Handler
+}
+
+// NewChannelWorker creates the channel renewal worker for h.
+func NewChannelWorker(h *Handler) *ChannelWorker {
+	return &ChannelWorker{h: h}
+}
+
+// Run blocks until ctx is cancelled.
+func (c *ChannelWorker) Run(ctx context.Context) {
+	if c.h.webhookURL == "" {
+		log.Println("⚠️ GOOGLE_CALENDAR_WEBHOOK_URL not set — Google Calendar push channels disabled")
+		return
+	}
+	log.Printf("📡 Google Calendar channel worker started (every %s)", channelInterval)
+
+	ticker := time.NewTicker(channelInterval)
+	defer ticker.Stop()
+
+	for {
+		c.renewChannels(ctx)
+
+		select {
+		case <-ctx.Done():
+			log.Println("📡 Google Calendar channel worker stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// renewChannels creates missing channels and renews those about to expire.
+// Claimed sources get a one-hour lease on channel_expires_at so other
+// instances skip them; a successful renewal overwrites it with the real expiry.
+func (c *ChannelWorker) renewChannels(ctx context.Context) {
+	rows, err := c.h.db.Query(ctx, `
+		UPDATE public.calendar_sources
+		SET channel_expires_at = NOW() + $2::interval + interval '1 hour'
+		WHERE id IN (
+			SELECT cs.id FROM public.calendar_sources cs
+			JOIN public.calendar_providers cp ON cp.id = cs.provider_id
+			WHERE cp.provider_type = 'google' AND cp.is_connected = true AND cp.is_active = true
+			  AND cs.is_selected = true
+			  AND (cs.channel_expires_at IS NULL OR cs.channel_expires_at < NOW() + $2::interval)
+			LIMIT $1
+			FOR UPDATE OF cs SKIP LOCKED
+		)
+		RETURNING provider_id, `+sourceColumns+`
+	`, syncBatchSize, fmt.Sprintf("%d seconds", int(channelRenewBefore.Seconds())))
+	if err != nil {
+		if ctx.Err() == nil {
+			log.Printf("Google Calendar channel renewal query error: %v", err)
+		}
+		return
+	}
+	type claimed struct {
+		providerID string
+		src        *calendarSource
+	}
+	var sources []claimed
+	for rows.Next() {
+		var src calendarSource
+		var providerID string
+		if err := rows.Scan(&providerID, &src.id, &src.calendarID, &src.syncToken, &src.channelID, &src.channelResourceID); err == nil {
+			sources = append(sources, claimed{providerID, &src})
+		}
+	}
+	rows.Close()
+
+	for _, cl := range sources {
+		if ctx.Err() != nil {
+			return
+		}
+		p, err := c.h.loadProviderByID(ctx, cl.providerID)
+		if err != nil {
+			continue
+		}
+		if err := c.h.ensureChannel(ctx, p, cl.src); err != nil {
+			log.Printf("❌ Google Calendar channel renewal failed for user %s: %v", p.userID, err)
+		}
+	}
+}
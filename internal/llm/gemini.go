@@ -0,0 +1,361 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/generative-ai-go/genai"
+	"github.com/google/uuid"
+	"google.golang.org/api/option"
+)
+
+// DefaultGeminiModel is used when NewGemini gets no model name.
+const DefaultGeminiModel = "gemini-2.0-flash"
+
+// A run left waiting for tool outputs longer than this is dropped.
+const geminiRunTTL = 5 * time.Minute
+
+// Gemini is a Provider backed by the Gemini API. Gemini is stateless, so
+// each turn carries the system prompt, recent history and tool
+// declarations from the Conversation. A turn waiting for tool outputs is
+// kept in memory under a generated run ID; SaveRun and RestoreRun move it
+// in and out of storage.
+type Gemini struct {
+	apiKey string
+	model  string
+
+	mu   sync.Mutex
+	runs map[string]*geminiRun
+}
+
+// geminiRun is a chat session paused on function calls.
+type geminiRun struct {
+	client       *genai.Client
+	session      *genai.ChatSession
+	systemPrompt string
+	tools        []Tool
+	toolNames    map[string]string // tool call ID → function name
+	createdAt    time.Time
+}
+
+// NewGemini creates a Gemini provider. model defaults to DefaultGeminiModel.
+func NewGemini(apiKey, model string) *Gemini {
+	if model == "" {
+		model = DefaultGeminiModel
+	}
+	return &Gemini{apiKey: apiKey, model: model, runs: make(map[string]*geminiRun)}
+}
+
+// Name implements Provider.
+func (g *Gemini) Name() string { return "gemini" }
+
+// Send implements Provider.
+func (g *Gemini) Send(ctx context.Context, conv *Conversation, content string) (*Response, error) {
+	g.dropExpiredRuns()
+
+	run, err := g.newRun(ctx, conv.SystemPrompt, conv.Tools)
+	if err != nil {
+		return nil, err
+	}
+	for _, m := range conv.History {
+		role := "user"
+		if m.Role == RoleAssistant {
+			role = "model"
+		}
+		run.session.History = append(run.session.History, &genai.Content{Role: role, Parts: []genai.Part{genai.Text(m.Content)}})
+	}
+
+	resp, err := run.session.SendMessage(ctx, genai.Text(content))
+	if err != nil {
+		run.client.Close()
+		return nil, fmt.Errorf("gemini send: %w", err)
+	}
+	return g.handle(run, resp)
+}
+
+// newRun opens a client and starts a chat session configured with the
+// system prompt and tools.
+func (g *Gemini) newRun(ctx context.Context, systemPrompt string, tools []Tool) (*geminiRun, error) {
+	client, err := genai.NewClient(ctx, option.WithAPIKey(g.apiKey))
+	if err != nil {
+		return nil, fmt.Errorf("gemini client: %w", err)
+	}
+
+	model := client.GenerativeModel(g.model)
+	model.SetTemperature(0.8)
+	if systemPrompt != "" {
+		model.SystemInstruction = genai.NewUserContent(genai.Text(systemPrompt))
+	}
+	if len(tools) > 0 {
+		decls := make([]*genai.FunctionDeclaration, 0, len(tools))
+		for _, t := range tools {
+			decls = append(decls, geminiDeclaration(t))
+		}
+		model.Tools = []*genai.Tool{{FunctionDeclarations: decls}}
+	}
+
+	return &geminiRun{
+		client:       client,
+		session:      model.StartChat(),
+		systemPrompt: systemPrompt,
+		tools:        tools,
+	}, nil
+}
+
+// SubmitToolOutputs implements Provider.
+func (g *Gemini) SubmitToolOutputs(ctx context.Context, conv *Conversation, runID string, outputs []ToolOutput) (*Response, error) {
+	g.mu.Lock()
+	run, ok := g.runs[runID]
+	delete(g.runs, runID)
+	g.mu.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("gemini: unknown or expired run %s", runID)
+	}
+
+	parts := make([]genai.Part, 0, len(outputs))
+	for _, out := range outputs {
+		var result map[string]any
+		if err := json.Unmarshal([]byte(out.Output), &result); err != nil {
+			result = map[string]any{"output": out.Output}
+		}
+		parts = append(parts, genai.FunctionResponse{Name: run.toolNames[out.ToolCallID], Response: result})
+	}
+
+	resp, err := run.session.SendMessage(ctx, parts...)
+	if err != nil {
+		run.client.Close()
+		return nil, fmt.Errorf("gemini submit tool outputs: %w", err)
+	}
+	return g.handle(run, resp)
+}
+
+// handle converts a Gemini reply, parking the session when it asks for tools.
+func (g *Gemini) handle(run *geminiRun, resp *genai.GenerateContentResponse) (*Response, error) {
+	if len(resp.Candidates) == 0 || resp.Candidates[0].Content == nil {
+		run.client.Close()
+		return nil, fmt.Errorf("gemini: empty response")
+	}
+	cand := resp.Candidates[0]
+
+	var text strings.Builder
+	for _, part := range cand.Content.Parts {
+		if t, ok := part.(genai.Text); ok {
+			text.WriteString(string(t))
+		}
+	}
+	out := &Response{Content: strings.TrimSpace(text.String())}
+
+	calls := cand.FunctionCalls()
+	if len(calls) == 0 {
+		run.client.Close()
+		return out, nil
+	}
+
+	// Gemini function calls carry no ID; answers are matched back by name
+	run.toolNames = make(map[string]string, len(calls))
+	run.createdAt = time.Now()
+	for _, fc := range calls {
+		args, err := json.Marshal(fc.Args)
+		if err != nil {
+			args = []byte("{}")
+		}
+		id := "call_" + uuid.New().String()
+		run.toolNames[id] = fc.Name
+		out.ToolCalls = append(out.ToolCalls, ToolCall{ID: id, Name: fc.Name, Arguments: string(args)})
+	}
+	out.RunID = "run_" + uuid.New().String()
+
+	g.mu.Lock()
+	g.runs[out.RunID] = run
+	g.mu.Unlock()
+	return out, nil
+}
+
+// dropExpiredRuns closes sessions whose tool loop was abandoned.
+func (g *Gemini) dropExpiredRuns() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	for id, run := range g.runs {
+		if time.Since(run.createdAt) > geminiRunTTL {
+			run.client.Close()
+			delete(g.runs, id)
+		}
+	}
+}
+
+// geminiRunState is the stored form of a paused run.
+type geminiRunState struct {
+	SystemPrompt string            `json:"system_prompt,omitempty"`
+	Tools        []Tool            `json:"tools,omitempty"`
+	History      []geminiContent   `json:"history"`
+	ToolNames    map[string]string `json:"tool_names"`
+}
+
+type geminiContent struct {
+	Role  string       `json:"role"`
+	Parts []geminiPart `json:"parts"`
+}
+
+// geminiPart holds one of a text, a function call (Data = arguments) or a
+// function response (Data = response).
+type geminiPart struct {
+	Text             string         `json:"text,omitempty"`
+	FunctionCall     string         `json:"function_call,omitempty"`
+	FunctionResponse string         `json:"function_response,omitempty"`
+	Data             map[string]any `json:"data,omitempty"`
+}
+
+// SaveRun implements Resumable. The run stays in memory.
+func (g *Gemini) SaveRun(runID string) ([]byte, error) {
+	g.mu.Lock()
+	run, ok := g.runs[runID]
+	g.mu.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("gemini: unknown or expired run %s", runID)
+	}
+
+	state := geminiRunState{SystemPrompt: run.systemPrompt, Tools: run.tools, ToolNames: run.toolNames}
+	for _, c := range run.session.History {
+		content := geminiContent{Role: c.Role}
+		for _, part := range c.Parts {
+			switch p := part.(type) {
+			case genai.Text:
+				content.Parts = append(content.Parts, geminiPart{Text: string(p)})
+			case genai.FunctionCall:
+				content.Parts = append(content.Parts, geminiPart{FunctionCall: p.Name, Data: p.Args})
+			case genai.FunctionResponse:
+				content.Parts = append(content.Parts, geminiPart{FunctionResponse: p.Name, Data: p.Response})
+			default:
+				return nil, fmt.Errorf("gemini: cannot save %T part", part)
+			}
+		}
+		state.History = append(state.History, content)
+	}
+	return json.Marshal(state)
+}
+
+// RestoreRun implements Resumable. A run still in memory is kept as is.
+func (g *Gemini) RestoreRun(ctx context.Context, runID string, data []byte) error {
+	g.mu.Lock()
+	_, ok := g.runs[runID]
+	g.mu.Unlock()
+	if ok {
+		return nil
+	}
+
+	var state geminiRunState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return fmt.Errorf("gemini: decode run %s: %w", runID, err)
+	}
+	run, err := g.newRun(ctx, state.SystemPrompt, state.Tools)
+	if err != nil {
+		return err
+	}
+	for _, c := range state.History {
+		content := &genai.Content{Role: c.Role}
+		for _, p := range c.Parts {
+			switch {
+			case p.FunctionCall != "":
+				content.Parts = append(content.Parts, genai.FunctionCall{Name: p.FunctionCall, Args: p.Data})
+			case p.FunctionResponse != "":
+				content.Parts = append(content.Parts, genai.FunctionResponse{Name: p.FunctionResponse, Response: p.Data})
+			default:
+				content.Parts = append(content.Parts, genai.Text(p.Text))
+			}
+		}
+		run.session.History = append(run.session.History, content)
+	}
+	run.toolNames = state.ToolNames
+	run.createdAt = time.Now()
+
+	g.mu.Lock()
+	g.runs[runID] = run
+	g.mu.Unlock()
+	return nil
+}
+
+// geminiDeclaration converts a Tool to a Gemini function declaration.
+func geminiDeclaration(t Tool) *genai.FunctionDeclaration {
+	decl := &genai.FunctionDeclaration{Name: t.Name, Description: t.Description}
+	if len(t.Parameters) == 0 {
+		return decl
+	}
+	props := make(map[string]*genai.Schema, len(t.Parameters))
+	for name, raw := range t.Parameters {
+		if schema, ok := raw.(map[string]interface{}); ok {
+			props[name] = geminiSchema(schema)
+		}
+	}
+	decl.Parameters = &genai.Schema{Type: genai.TypeObject, Properties: props, Required: t.Required}
+	return decl
+}
+
+// geminiSchema converts a JSON schema fragment. Gemini rejects arrays
+// without items, so those default to strings.
+func geminiSchema(s map[string]interface{}) *genai.Schema {
+	out := &genai.Schema{}
+	out.Description, _ = s["description"].(string)
+
+	switch s["type"] {
+	case "string":
+		out.Type = genai.TypeString
+	case "integer":
+		out.Type = genai.TypeInteger
+	case "number":
+		out.Type = genai.TypeNumber
+	case "boolean":
+		out.Type = genai.TypeBoolean
+	case "array":
+		out.Type = genai.TypeArray
+		if items, ok := s["items"].(map[string]interface{}); ok {
+			out.Items = geminiSchema(items)
+		} else {
+			out.Items = &genai.Schema{Type: genai.TypeString}
+		}
+	case "object":
+		out.Type = genai.TypeObject
+		if props, ok := s["properties"].(map[string]interface{}); ok {
+			out.Properties = make(map[string]*genai.Schema, len(props))
+			for name, raw := range props {
+				if p, ok := raw.(map[string]interface{}); ok {
+					out.Properties[name] = geminiSchema(p)
+				}
+			}
+		}
+		if req, ok := stringList(s["required"]); ok {
+			out.Required = req
+		}
+	default:
+		out.Type = genai.TypeString
+	}
+
+	if enum, ok := stringList(s["enum"]); ok {
+		out.Enum = enum
+		out.Format = "enum"
+	}
+	return out
+}
+
+// stringList reads a list of strings from a schema, built in Go ([]string)
+// or decoded from JSON ([]interface{}) when a run is restored.
+func stringList(v interface{}) ([]string, bool) {
+	switch list := v.(type) {
+	case []string:
+		return list, true
+	case []interface{}:
+		out := make([]string, 0, len(list))
+		for _, item := range list {
+			s, ok := item.(string)
+			if !ok {
+				return nil, false
+			}
+			out = append(out, s)
+		}
+		return out, true
+	}
+	return nil, false
+}
@@ -0,0 +1,68 @@
+package llm
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/google/generative-ai-go/genai"
+)
+
+func TestGeminiSaveRestoreRun(t *testing.T) {
+	g := NewGemini("test-key", "")
+	ctx := context.Background()
+
+	tools := []Tool{{
+		Name:        "delete_task",
+		Description: "Supprime une tâche",
+		Parameters: map[string]interface{}{
+			"task_id": map[string]interface{}{"type": "string"},
+			"scope":   map[string]interface{}{"type": "string", "enum": []string{"one", "all"}},
+		},
+		Required: []string{"task_id"},
+	}}
+	run, err := g.newRun(ctx, "Tu es Kai.", tools)
+	if err != nil {
+		t.Fatalf("newRun: %v", err)
+	}
+	run.session.History = []*genai.Content{
+		{Role: "user", Parts: []genai.Part{genai.Text("Supprime ma tâche")}},
+		{Role: "model", Parts: []genai.Part{genai.FunctionCall{Name: "delete_task", Args: map[string]any{"task_id": "t1"}}}},
+	}
+	run.toolNames = map[string]string{"call_1": "delete_task"}
+	run.createdAt = time.Now()
+	g.runs["run_1"] = run
+
+	state, err := g.SaveRun("run_1")
+	if err != nil {
+		t.Fatalf("SaveRun: %v", err)
+	}
+
+	// A fresh provider stands for the server after a restart.
+	restarted := NewGemini("test-key", "")
+	if err := restarted.RestoreRun(ctx, "run_1", state); err != nil {
+		t.Fatalf("RestoreRun: %v", err)
+	}
+	got := restarted.runs["run_1"]
+	if got == nil {
+		t.Fatal("run not restored")
+	}
+	if got.systemPrompt != "Tu es Kai." {
+		t.Errorf("system prompt = %q", got.systemPrompt)
+	}
+	if !reflect.DeepEqual(got.toolNames, run.toolNames) {
+		t.Errorf("tool names = %v, want %v", got.toolNames, run.toolNames)
+	}
+	if !reflect.DeepEqual(got.session.History, run.session.History) {
+		t.Errorf("history = %#v, want %#v", got.session.History, run.session.History)
+	}
+	enum := geminiDeclaration(got.tools[0]).Parameters.Properties["scope"].Enum
+	if !reflect.DeepEqual(enum, []string{"one", "all"}) {
+		t.Errorf("restored enum = %v", enum)
+	}
+
+	if _, err := g.SaveRun("run_unknown"); err == nil {
+		t.Error("SaveRun of an unknown run should fail")
+	}
+}
@@ -0,0 +1,96 @@
+package llm
+
+import "context"
+
+// ==========================================
+// Provider-agnostic chat model interface
+// ==========================================
+// A chat turn is one Send, followed by SubmitToolOutputs for as long as the
+// model asks for tools. The tool loop itself lives in backboard.Executor and
+// only talks to this interface, so the model behind it (Backboard, Gemini,
+// a scripted fake) is configuration.
+// ==========================================
+
+// Roles used in Message.Role.
+const (
+	RoleUser      = "user"
+	RoleAssistant = "assistant"
+)
+
+// Message is one earlier turn of the conversation. Only stateless providers
+// (Gemini) read it; Backboard keeps the history in its thread.
+type Message struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// Tool describes a function the model may call. Parameters maps each
+// argument name to its JSON schema.
+type Tool struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description"`
+	Parameters  map[string]interface{} `json:"parameters,omitempty"`
+	Required    []string               `json:"required,omitempty"`
+}
+
+// ToolCall is a tool invocation requested by the model.
+type ToolCall struct {
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	Arguments string `json:"arguments"` // JSON object
+}
+
+// ToolOutput is the result of a ToolCall, sent back to the model.
+type ToolOutput struct {
+	ToolCallID string `json:"tool_call_id"`
+	Output     string `json:"output"` // JSON
+}
+
+// Conversation is where a turn is sent. Providers use the fields they need
+// and ignore the rest.
+type Conversation struct {
+	UserID string
+
+	// Backboard: the assistant and thread hold the prompt, tools and history.
+	AssistantID string
+	ThreadID    string
+
+	// Stateless providers: everything is sent with each turn.
+	SystemPrompt string
+	History      []Message
+	Tools        []Tool
+}
+
+// Response is the model's answer to Send or SubmitToolOutputs.
+type Response struct {
+	Content   string     `json:"content"`
+	RunID     string     `json:"run_id,omitempty"` // Set while the model waits for tool outputs
+	ToolCalls []ToolCall `json:"tool_calls,omitempty"`
+}
+
+// RequiresAction reports whether the model is waiting for tool outputs.
+func (r *Response) RequiresAction() bool {
+	return r != nil && r.RunID != "" && len(r.ToolCalls) > 0
+}
+
+// Provider is a chat model able to call tools.
+type Provider interface {
+	// Name identifies the provider in logs ("backboard", "gemini", ...).
+	Name() string
+
+	// Send adds a user message to the conversation and returns the reply.
+	Send(ctx context.Context, conv *Conversation, content string) (*Response, error)
+
+	// SubmitToolOutputs answers the tool calls of run runID and returns the
+	// next reply, which may itself require action.
+	SubmitToolOutputs(ctx context.Context, conv *Conversation, runID string, outputs []ToolOutput) (*Response, error)
+}
+
+// Resumable is implemented by providers that keep paused runs in process
+// memory (Gemini). SaveRun exports the state of a run waiting for tool
+// outputs; RestoreRun loads it back, so a run stored with a pending
+// confirmation can be resumed after a restart.
+type Resumable interface {
+	SaveRun(runID string) ([]byte, error)
+	RestoreRun(ctx context.Context, runID string, state []byte) error
+}
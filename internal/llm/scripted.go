@@ -0,0 +1,69 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// Scripted is a deterministic Provider that plays back canned responses in
+// order, one per Send or SubmitToolOutputs call. It records what it was
+// sent so the tool loop can be exercised offline.
+type Scripted struct {
+	mu      sync.Mutex
+	steps   []Response
+	next    int
+	sent    []string
+	outputs [][]ToolOutput
+}
+
+// NewScripted returns a Scripted provider that answers with steps.
+func NewScripted(steps ...Response) *Scripted {
+	return &Scripted{steps: steps}
+}
+
+// Name implements Provider.
+func (s *Scripted) Name() string { return "scripted" }
+
+// Send implements Provider.
+func (s *Scripted) Send(ctx context.Context, conv *Conversation, content string) (*Response, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sent = append(s.sent, content)
+	return s.pop()
+}
+
+// SubmitToolOutputs implements Provider. runID must match the RunID of the
+// previous step.
+func (s *Scripted) SubmitToolOutputs(ctx context.Context, conv *Conversation, runID string, outputs []ToolOutput) (*Response, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.next == 0 || s.steps[s.next-1].RunID != runID {
+		return nil, fmt.Errorf("scripted: unexpected tool outputs for run %q", runID)
+	}
+	s.outputs = append(s.outputs, append([]ToolOutput(nil), outputs...))
+	return s.pop()
+}
+
+func (s *Scripted) pop() (*Response, error) {
+	if s.next >= len(s.steps) {
+		return nil, fmt.Errorf("scripted: no response left after %d steps", len(s.steps))
+	}
+	resp := s.steps[s.next]
+	s.next++
+	return &resp, nil
+}
+
+// Sent returns the user messages received so far.
+func (s *Scripted) Sent() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.sent...)
+}
+
+// Outputs returns the tool outputs received so far, one slice per round.
+func (s *Scripted) Outputs() [][]ToolOutput {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([][]ToolOutput(nil), s.outputs...)
+}
@@ -0,0 +1,200 @@
+package notifications
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// ===========================================
+// APNs HTTP/2 CLIENT
+// Token-based auth (.p8 key) against api.push.apple.com
+// ===========================================
+
+const (
+	apnsProductionURL = "https://api.push.apple.com"
+	apnsSandboxURL    = "https://api.sandbox.push.apple.com"
+
+	// Apple rejects provider tokens older than one hour and throttles
+	// tokens refreshed more than once every 20 minutes.
+	apnsTokenTTL = 45 * time.Minute
+)
+
+// APNsConfig holds the credentials from the Apple Developer account.
+type APNsConfig struct {
+	KeyID      string // 10-char key identifier of the .p8 key
+	TeamID     string // 10-char Apple team identifier
+	BundleID   string // apns-topic, e.g. com.volta.focus
+	PrivateKey string // PEM contents of the .p8 key
+	Production bool   // false → sandbox gateway
+	BaseURL    string // Optional override (local stand-in server)
+}
+
+// APNsClient sends notifications through the APNs provider API.
+type APNsClient struct {
+	baseURL    string
+	keyID      string
+	teamID     string
+	bundleID   string
+	signingKey *ecdsa.PrivateKey
+	httpClient *http.Client
+
+	mu          sync.Mutex
+	bearer      string
+	bearerIssue time.Time
+}
+
+// NewAPNsClient creates an APNs client from a .p8 key.
+func NewAPNsClient(cfg APNsConfig) (*APNsClient, error) {
+	if cfg.KeyID == "" || cfg.TeamID == "" || cfg.BundleID == "" {
+		return nil, fmt.Errorf("APNs key ID, team ID and bundle ID are required")
+	}
+
+	key, err := jwt.ParseECPrivateKeyFromPEM([]byte(cfg.PrivateKey))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse APNs private key: %w", err)
+	}
+
+	baseURL := cfg.BaseURL
+	if baseURL == "" {
+		baseURL = apnsSandboxURL
+		if cfg.Production {
+			baseURL = apnsProductionURL
+		}
+	}
+
+	return &APNsClient{
+		baseURL:    baseURL,
+		keyID:      cfg.KeyID,
+		teamID:     cfg.TeamID,
+		bundleID:   cfg.BundleID,
+		signingKey: key,
+		httpClient: &http.Client{
+			// net/http negotiates HTTP/2 over TLS, which APNs requires
+			Transport: &http.Transport{ForceAttemptHTTP2: true},
+			Timeout:   15 * time.Second,
+		},
+	}, nil
+}
+
+// apnsPayload is the JSON body sent to APNs.
+type apnsPayload map[string]interface{}
+
+// apnsErrorResponse is the JSON body APNs returns on failure.
+type apnsErrorResponse struct {
+	Reason    string `json:"reason"`
+	Timestamp int64  `json:"timestamp,omitempty"`
+}
+
+// Push sends one notification to one device token.
+func (c *APNsClient) Push(ctx context.Context, deviceToken string, n Notification) error {
+	body, err := json.Marshal(buildAPNsPayload(n))
+	if err != nil {
+		return fmt.Errorf("marshal APNs payload: %w", err)
+	}
+
+	bearer, err := c.providerToken()
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/3/device/"+deviceToken, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("build APNs request: %w", err)
+	}
+	req.Header.Set("authorization", "bearer "+bearer)
+	req.Header.Set("apns-topic", c.bundleID)
+	req.Header.Set("apns-push-type", "alert")
+	req.Header.Set("apns-priority", "10")
+	req.Header.Set("Content-Type", "application/json")
+	if n.CollapseID != "" {
+		req.Header.Set("apns-collapse-id", n.CollapseID)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("APNs request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusOK {
+		return nil
+	}
+
+	respBody, _ := io.ReadAll(resp.Body)
+	var apnsErr apnsErrorResponse
+	_ = json.Unmarshal(respBody, &apnsErr)
+
+	// 410 = token inactive; BadDeviceToken = token for another environment or garbage
+	if resp.StatusCode == http.StatusGone || apnsErr.Reason == "Unregistered" || apnsErr.Reason == "BadDeviceToken" {
+		return fmt.Errorf("%w (%s)", ErrUnregistered, apnsErr.Reason)
+	}
+
+	// Provider token rejected: force a new one on the next push
+	if apnsErr.Reason == "ExpiredProviderToken" || apnsErr.Reason == "InvalidProviderToken" {
+		c.mu.Lock()
+		c.bearer = ""
+		c.mu.Unlock()
+	}
+
+	return fmt.Errorf("APNs returned %d: %s", resp.StatusCode, apnsErr.Reason)
+}
+
+// providerToken returns a cached ES256 provider token, refreshing it when stale.
+func (c *APNsClient) providerToken() (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.bearer != "" && time.Since(c.bearerIssue) < apnsTokenTTL {
+		return c.bearer, nil
+	}
+
+	now := time.Now()
+	token := jwt.NewWithClaims(jwt.SigningMethodES256, jwt.MapClaims{
+		"iss": c.teamID,
+		"iat": now.Unix(),
+	})
+	token.Header["kid"] = c.keyID
+
+	signed, err := token.SignedString(c.signingKey)
+	if err != nil {
+		return "", fmt.Errorf("sign APNs provider token: %w", err)
+	}
+
+	c.bearer = signed
+	c.bearerIssue = now
+	return signed, nil
+}
+
+func buildAPNsPayload(n Notification) apnsPayload {
+	aps := map[string]interface{}{
+		"alert": map[string]string{
+			"title": n.Title,
+			"body":  n.Body,
+		},
+		"sound": "default",
+	}
+	if n.ThreadID != "" {
+		aps["thread-id"] = n.ThreadID
+	}
+	if n.Category != "" {
+		aps["category"] = string(n.Category)
+	}
+
+	payload := apnsPayload{"aps": aps}
+	for k, v := range n.Data {
+		if k == "aps" {
+			continue
+		}
+		payload[k] = v
+	}
+	return payload
+}
@@ -0,0 +1,145 @@
+package notifications
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func newTestAPNsClient(t *testing.T, handler http.HandlerFunc) *APNsClient {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+
+	srv := httptest.NewTLSServer(handler)
+	t.Cleanup(srv.Close)
+
+	client, err := NewAPNsClient(APNsConfig{
+		KeyID:      "KEY1234567",
+		TeamID:     "TEAM123456",
+		BundleID:   "com.volta.focus",
+		PrivateKey: string(keyPEM),
+		BaseURL:    srv.URL,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.httpClient = srv.Client()
+	return client
+}
+
+func TestAPNsPush(t *testing.T) {
+	var gotPath, gotTopic, gotAuth string
+	var gotBody map[string]interface{}
+	client := newTestAPNsClient(t, func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+		gotTopic = r.Header.Get("apns-topic")
+		gotAuth = r.Header.Get("authorization")
+		if err := json.NewDecoder(r.Body).Decode(&gotBody); err != nil {
+			t.Errorf("decode body: %v", err)
+		}
+	})
+
+	err := client.Push(context.Background(), "abc123", Notification{
+		Title:    "Ritual time",
+		Body:     "Morning stretch",
+		Category: CategoryRitualReminders,
+		Data:     map[string]interface{}{"routine_id": "r1", "aps": "ignored"},
+	})
+	if err != nil {
+		t.Fatalf("Push: %v", err)
+	}
+
+	if gotPath != "/3/device/abc123" {
+		t.Errorf("path = %q", gotPath)
+	}
+	if gotTopic != "com.volta.focus" {
+		t.Errorf("apns-topic = %q", gotTopic)
+	}
+	if !strings.HasPrefix(gotAuth, "bearer ") {
+		t.Errorf("authorization = %q", gotAuth)
+	}
+	aps, ok := gotBody["aps"].(map[string]interface{})
+	if !ok || aps["category"] != string(CategoryRitualReminders) {
+		t.Errorf("aps = %v", gotBody["aps"])
+	}
+	if gotBody["routine_id"] != "r1" {
+		t.Errorf("custom data not delivered: %v", gotBody)
+	}
+}
+
+func TestAPNsPushErrors(t *testing.T) {
+	tests := []struct {
+		name             string
+		status           int
+		reason           string
+		wantUnregistered bool
+	}{
+		{"gone", http.StatusGone, "Unregistered", true},
+		{"bad token", http.StatusBadRequest, "BadDeviceToken", true},
+		{"throttled", http.StatusTooManyRequests, "TooManyRequests", false},
+		{"expired provider token", http.StatusForbidden, "ExpiredProviderToken", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newTestAPNsClient(t, func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				json.NewEncoder(w).Encode(apnsErrorResponse{Reason: tt.reason})
+			})
+
+			err := client.Push(context.Background(), "abc123", Notification{Title: "t"})
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+			if got := errors.Is(err, ErrUnregistered); got != tt.wantUnregistered {
+				t.Errorf("errors.Is(err, ErrUnregistered) = %v, want %v (err: %v)", got, tt.wantUnregistered, err)
+			}
+		})
+	}
+}
+
+func TestAPNsProviderTokenRefreshedAfterRejection(t *testing.T) {
+	var auths []string
+	client := newTestAPNsClient(t, func(w http.ResponseWriter, r *http.Request) {
+		auths = append(auths, r.Header.Get("authorization"))
+		if len(auths) == 2 {
+			w.WriteHeader(http.StatusForbidden)
+			json.NewEncoder(w).Encode(apnsErrorResponse{Reason: "ExpiredProviderToken"})
+		}
+	})
+
+	client.Push(context.Background(), "abc123", Notification{Title: "t"})
+	client.Push(context.Background(), "abc123", Notification{Title: "t"})
+
+	if auths[0] != auths[1] {
+		t.Error("provider token should be cached between pushes")
+	}
+	client.mu.Lock()
+	cleared := client.bearer == ""
+	client.mu.Unlock()
+	if !cleared {
+		t.Error("provider token should be dropped after APNs rejects it")
+	}
+
+	if err := client.Push(context.Background(), "abc123", Notification{Title: "t"}); err != nil {
+		t.Fatalf("Push after refresh: %v", err)
+	}
+}
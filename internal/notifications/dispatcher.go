@@ -0,0 +1,115 @@
+package notifications
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// ===========================================
+// DISPATCHER - Server-side push delivery
+// Honours notification_settings, fans out to
+// every registered device, prunes dead tokens
+// ===========================================
+
+// Dispatcher sends notifications to all of a user's registered devices.
+type Dispatcher struct {
+	db     *pgxpool.Pool
+	pusher Pusher
+}
+
+// NewDispatcher creates a dispatcher backed by the given Pusher.
+func NewDispatcher(db *pgxpool.Pool, pusher Pusher) *Dispatcher {
+	return &Dispatcher{db: db, pusher: pusher}
+}
+
+// SendResult summarises a fan-out to a user's devices.
+type SendResult struct {
+	Skipped bool `json:"skipped"` // User disabled notifications or this category
+	Sent    int  `json:"sent"`
+	Failed  int  `json:"failed"`
+	Pruned  int  `json:"pruned"` // Tokens removed because APNs reported them unregistered
+}
+
+// Send delivers n to every device of userID, unless the user has turned
+// notifications off globally or for the notification's category.
+func (d *Dispatcher) Send(ctx context.Context, userID string, n Notification) (SendResult, error) {
+	var result SendResult
+
+	allowed, err := d.isAllowed(ctx, userID, n.Category)
+	if err != nil {
+		return result, err
+	}
+	if !allowed {
+		result.Skipped = true
+		return result, nil
+	}
+
+	rows, err := d.db.Query(ctx, `SELECT token FROM public.device_tokens WHERE user_id = $1`, userID)
+	if err != nil {
+		return result, fmt.Errorf("failed to load device tokens: %w", err)
+	}
+	var tokens []string
+	for rows.Next() {
+		var token string
+		if err := rows.Scan(&token); err != nil {
+			log.Printf("Scan device token error: %v", err)
+			continue
+		}
+		tokens = append(tokens, token)
+	}
+	rows.Close()
+
+	d.fanOut(ctx, tokens, n, &result, func(token string) error {
+		_, err := d.db.Exec(ctx, `DELETE FROM public.device_tokens WHERE user_id = $1 AND token = $2`, userID, token)
+		return err
+	})
+
+	return result, nil
+}
+
+// fanOut pushes n to each token and tallies the outcome into result.
+// Tokens the Pusher reports as unregistered are handed to prune.
+func (d *Dispatcher) fanOut(ctx context.Context, tokens []string, n Notification, result *SendResult, prune func(token string) error) {
+	for _, token := range tokens {
+		err := d.pusher.Push(ctx, token, n)
+		switch {
+		case err == nil:
+			result.Sent++
+		case errors.Is(err, ErrUnregistered):
+			result.Failed++
+			if pruneErr := prune(token); pruneErr != nil {
+				log.Printf("Failed to prune device token %s: %v", truncateToken(token), pruneErr)
+			} else {
+				result.Pruned++
+				log.Printf("🗑️ Pruned unregistered device token %s", truncateToken(token))
+			}
+		default:
+			result.Failed++
+			log.Printf("Push to %s failed: %v", truncateToken(token), err)
+		}
+	}
+}
+
+// isAllowed checks users.notifications_enabled and the per-category flag in
+// notification_settings. Missing flags default to true, like GetSettings.
+func (d *Dispatcher) isAllowed(ctx context.Context, userID string, category Category) (bool, error) {
+	var enabled, categoryEnabled bool
+	err := d.db.QueryRow(ctx, `
+		SELECT COALESCE(notifications_enabled, true),
+		       COALESCE((notification_settings->>$2)::boolean, true)
+		FROM public.users
+		WHERE id = $1
+	`, userID, string(category)).Scan(&enabled, &categoryEnabled)
+	if err != nil {
+		return false, fmt.Errorf("failed to load notification settings: %w", err)
+	}
+
+	if category == "" {
+		return enabled, nil
+	}
+	return enabled && categoryEnabled, nil
+}
@@ -0,0 +1,52 @@
+package notifications
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+// fakePusher records pushes and returns a canned error per token.
+type fakePusher struct {
+	errs   map[string]error
+	pushed []string
+}
+
+func (f *fakePusher) Push(ctx context.Context, deviceToken string, n Notification) error {
+	f.pushed = append(f.pushed, deviceToken)
+	return f.errs[deviceToken]
+}
+
+func TestFanOut(t *testing.T) {
+	pusher := &fakePusher{errs: map[string]error{
+		"gone":   fmt.Errorf("%w (Unregistered)", ErrUnregistered),
+		"flaky":  errors.New("APNs returned 500: InternalServerError"),
+		"stuck":  fmt.Errorf("%w (BadDeviceToken)", ErrUnregistered),
+		"active": nil,
+	}}
+	d := &Dispatcher{pusher: pusher}
+
+	var pruned []string
+	prune := func(token string) error {
+		if token == "stuck" {
+			return errors.New("db down")
+		}
+		pruned = append(pruned, token)
+		return nil
+	}
+
+	var result SendResult
+	d.fanOut(context.Background(), []string{"active", "gone", "flaky", "stuck"}, Notification{Title: "t"}, &result, prune)
+
+	if len(pusher.pushed) != 4 {
+		t.Fatalf("pushed to %d tokens, want 4", len(pusher.pushed))
+	}
+	want := SendResult{Sent: 1, Failed: 3, Pruned: 1}
+	if result != want {
+		t.Errorf("result = %+v, want %+v", result, want)
+	}
+	if len(pruned) != 1 || pruned[0] != "gone" {
+		t.Errorf("pruned = %v, want [gone]", pruned)
+	}
+}
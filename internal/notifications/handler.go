@@ -17,13 +17,19 @@ import (
 // ===========================================
 
 type Handler struct {
-	db *pgxpool.Pool
+	db         *pgxpool.Pool
+	dispatcher *Dispatcher
 }
 
 func NewHandler(db *pgxpool.Pool) *Handler {
 	return &Handler{db: db}
 }
 
+// SetDispatcher sets the push dispatcher used by SendTest
+func (h *Handler) SetDispatcher(dispatcher *Dispatcher) {
+	h.dispatcher = dispatcher
+}
+
 // DeviceToken represents a registered APNs device
 type DeviceToken struct {
 	ID        string    `json:"id"`
@@ -127,7 +133,8 @@ func (h *Handler) GetSettings(w http.ResponseWriter, r *http.Request) {
 			"ritual_reminders": true,
 			"evening_checkin": true,
 			"streak_alerts": true,
-			"quest_milestones": true
+			"quest_milestones": true,
+			"coach_messages": true
 		}'::jsonb) as settings
 		FROM public.users
 		WHERE id = $1
@@ -173,3 +180,27 @@ func (h *Handler) UpdateSettings(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	w.Write(updatedJSON)
 }
+
+// SendTest pushes a test notification to all of the user's devices
+// POST /notifications/test
+func (h *Handler) SendTest(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(auth.UserContextKey).(string)
+
+	if h.dispatcher == nil {
+		http.Error(w, "Push notifications not configured", http.StatusServiceUnavailable)
+		return
+	}
+
+	result, err := h.dispatcher.Send(r.Context(), userID, Notification{
+		Title: "Focus",
+		Body:  "Les notifications fonctionnent 🔥",
+	})
+	if err != nil {
+		log.Printf("Test notification error for user %s: %v", userID, err)
+		http.Error(w, "Failed to send test notification", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
@@ -0,0 +1,62 @@
+package notifications
+
+import (
+	"context"
+	"errors"
+	"log"
+)
+
+// ===========================================
+// PUSHER - Transport abstraction for pushes
+// APNs in production, LogPusher when unconfigured
+// ===========================================
+
+// Category is a notification category that users can toggle in
+// notification_settings (see GetSettings for the defaults).
+type Category string
+
+const (
+	CategoryFocusReminders  Category = "focus_reminders"
+	CategoryRitualReminders Category = "ritual_reminders"
+	CategoryEveningCheckin  Category = "evening_checkin"
+	CategoryStreakAlerts    Category = "streak_alerts"
+	CategoryQuestMilestones Category = "quest_milestones"
+	CategoryCoachMessages   Category = "coach_messages"
+)
+
+// Notification is the platform-agnostic payload handed to a Pusher.
+type Notification struct {
+	Title      string                 `json:"title"`
+	Body       string                 `json:"body"`
+	Category   Category               `json:"category,omitempty"`
+	ThreadID   string                 `json:"thread_id,omitempty"`   // Groups notifications on the lock screen
+	CollapseID string                 `json:"collapse_id,omitempty"` // Replaces a previous notification with the same ID
+	Data       map[string]interface{} `json:"data,omitempty"`        // Custom keys delivered next to "aps"
+}
+
+// ErrUnregistered is returned (wrapped) by a Pusher when the device token is
+// no longer valid and should be removed from device_tokens.
+var ErrUnregistered = errors.New("device token is no longer registered")
+
+// Pusher delivers a single notification to a single device token.
+type Pusher interface {
+	Push(ctx context.Context, deviceToken string, n Notification) error
+}
+
+// LogPusher only logs notifications. Used when APNs credentials are not set
+// (local development) so the rest of the pipeline still runs.
+type LogPusher struct{}
+
+// Push logs the notification instead of sending it.
+func (LogPusher) Push(ctx context.Context, deviceToken string, n Notification) error {
+	log.Printf("🔕 [LogPusher] %s → %s: %s", truncateToken(deviceToken), n.Title, n.Body)
+	return nil
+}
+
+// truncateToken keeps device tokens out of the logs in full.
+func truncateToken(token string) string {
+	if len(token) <= 8 {
+		return token
+	}
+	return token[:8] + "…"
+}
@@ -0,0 +1,116 @@
+package proactive
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"unicode/utf8"
+
+	"firelevel-backend/internal/backboard"
+	"firelevel-backend/internal/llm"
+)
+
+// maxMessageLength caps what the model writes: it is also a push body.
+const maxMessageLength = 280 // Runes
+
+const writerPrompt = `Tu es %s, le coach de vie de l'utilisateur. Tu lui écris DE TA PROPRE INITIATIVE : il ne t'a rien demandé, ton message arrive en notification puis dans votre conversation.
+
+- 1 à 2 phrases, 220 caractères max, tutoiement, pas de markdown, 1 emoji max.
+- Appelle-le par son prénom (user_name) s'il est connu. Appuie-toi sur le contexte fourni, n'invente rien.
+- Termine par une question ou une action simple qu'il peut faire tout de suite.
+- Réponds uniquement avec le message.`
+
+const writerHarshAddon = `
+- Mode coach dur activé : direct et exigeant, sans culpabiliser.`
+
+// instructions tells the model why it is writing.
+func instructions(c claimed) string {
+	switch c.Kind {
+	case KindMorningCheckin:
+		return "C'est le matin. Propose-lui de faire son check-in et de choisir sa priorité du jour."
+	case KindNoActivity:
+		return "Il est midi et il n'a encore rien fait aujourd'hui (ni tâche, ni rituel, ni focus). Relance-le en douceur avec une micro-action."
+	case KindEveningReview:
+		return "C'est le soir. Invite-le à faire sa review : sa victoire du jour, ce qui a bloqué, son objectif pour demain."
+	case KindStreakAtRisk:
+		return "Sa série (current_streak) se perd à minuit s'il ne fait rien aujourd'hui. Motive-le avec une action de 2 minutes."
+	case KindFocusCompleted:
+		what := ""
+		if c.FocusDescription != "" {
+			what = fmt.Sprintf(" sur « %s »", c.FocusDescription)
+		}
+		return fmt.Sprintf("Il vient de terminer une session de focus de %d min%s. Félicite-le et demande comment ça s'est passé ou ce qu'il enchaîne.", c.FocusMinutes, what)
+	}
+	return ""
+}
+
+// compose writes the message: by the model from get_user_context when a
+// writer is set, from a template otherwise or when the model fails.
+func (e *Engine) compose(ctx context.Context, c claimed) string {
+	userCtx, err := backboard.UserContext(ctx, e.db, c.UserID)
+	if err != nil {
+		log.Printf("Failed to build context for proactive message, user %s: %v", c.UserID, err)
+		userCtx = map[string]interface{}{}
+	}
+
+	if e.writer != nil {
+		text, err := e.write(ctx, c, userCtx)
+		if err == nil {
+			return text
+		}
+		log.Printf("⚠️ %s failed to write %s message for user %s, using template: %v", e.writer.Name(), c.Kind, c.UserID, err)
+	}
+	return template(c, userCtx)
+}
+
+func (e *Engine) write(ctx context.Context, c claimed, userCtx map[string]interface{}) (string, error) {
+	prompt := fmt.Sprintf(writerPrompt, c.CompanionName)
+	if c.HarshMode {
+		prompt += writerHarshAddon
+	}
+	contextJSON, _ := json.Marshal(userCtx)
+
+	conv := &llm.Conversation{UserID: c.UserID, SystemPrompt: prompt}
+	resp, err := e.writer.Send(ctx, conv, fmt.Sprintf("%s\n\nContexte (get_user_context) :\n%s", instructions(c), contextJSON))
+	if err != nil {
+		return "", err
+	}
+	text := strings.TrimSpace(resp.Content)
+	switch {
+	case resp.RequiresAction():
+		return "", fmt.Errorf("model asked for tools")
+	case text == "":
+		return "", fmt.Errorf("empty message")
+	case utf8.RuneCountInString(text) > maxMessageLength:
+		return "", fmt.Errorf("message too long (%d runes)", utf8.RuneCountInString(text))
+	}
+	return text, nil
+}
+
+// template is the message used without a model.
+func template(c claimed, userCtx map[string]interface{}) string {
+	name := ""
+	if n, ok := userCtx["user_name"].(string); ok && n != "" {
+		name = " " + n
+	}
+
+	switch c.Kind {
+	case KindMorningCheckin:
+		return fmt.Sprintf("Salut%s ☀️ On fait le point ? Dis-moi ta priorité du jour.", name)
+	case KindNoActivity:
+		return fmt.Sprintf("Hey%s, la matinée est passée. Une petite action de 5 minutes pour lancer ta journée ?", name)
+	case KindEveningReview:
+		return fmt.Sprintf("Alors%s, c'est quoi ta plus grande victoire aujourd'hui ?", name)
+	case KindStreakAtRisk:
+		streak := "Ta série"
+		if n, ok := userCtx["current_streak"].(int); ok && n > 0 {
+			streak = fmt.Sprintf("Ta série de %d jours", n)
+		}
+		return fmt.Sprintf("Hey%s ! %s est en jeu 🔥 Une tâche, un rituel ou 10 min de focus avant minuit et elle continue.", name, streak)
+	case KindFocusCompleted:
+		return fmt.Sprintf("Bien joué%s pour ces %d minutes de focus 💪 Comment ça s'est passé ?", name, c.FocusMinutes)
+	}
+	return fmt.Sprintf("Hey%s, comment ça va aujourd'hui ?", name)
+}
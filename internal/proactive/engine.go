@@ -0,0 +1,446 @@
+package proactive
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"firelevel-backend/internal/llm"
+	"firelevel-backend/internal/notifications"
+	"firelevel-backend/internal/userclock"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// ===========================================
+// PROACTIVE COACH - Kai writes first
+// Every minute: find users due for a scheduled
+// check-in or hit by a trigger, claim the message
+// (quiet hours, daily cap, once per day), then
+// write it from get_user_context and deliver it
+// to the chat transcript and through push.
+// ===========================================
+
+// Kind is what made Kai write.
+type Kind string
+
+const (
+	KindMorningCheckin Kind = "morning_checkin" // Scheduled
+	KindNoActivity     Kind = "no_activity"     // Nothing done by noon
+	KindEveningReview  Kind = "evening_review"  // Scheduled
+	KindStreakAtRisk   Kind = "streak_at_risk"  // Streak alive, nothing done by 21:00
+	KindFocusCompleted Kind = "focus_completed" // A focus session just ended
+)
+
+const (
+	// tickInterval is how often due messages are scanned.
+	tickInterval = time.Minute
+
+	// catchUpWindow bounds how late a message may go out (e.g. after a
+	// deploy). Older ones are dropped rather than sent stale.
+	catchUpWindow = 15 * time.Minute
+
+	// busyWindow: no proactive message while the user is chatting.
+	busyWindow = 30 * time.Minute
+
+	// proactiveLockKey is the pg advisory lock shared by every instance so
+	// only one of them scans per tick (see reminders.Scheduler).
+	proactiveLockKey int64 = 0x636f_6163_68 // "coach"
+
+	// sendTimeout covers writing the message with the LLM and delivering it.
+	sendTimeout = 45 * time.Second
+
+	// sendWorkers bounds how many messages are written and sent at once.
+	sendWorkers = 8
+)
+
+// slot is a message sent at a fixed local time when condition holds.
+// condition is SQL over $1 = user_id and $3 = local date.
+type slot struct {
+	kind      Kind
+	at        string // HH:MM local
+	condition string
+}
+
+const noActivityToday = `NOT EXISTS (
+	SELECT 1 FROM public.streak_days
+	WHERE user_id = $1 AND day = $3::date AND NOT frozen
+)`
+
+var schedule = []slot{
+	{KindMorningCheckin, "09:00", `NOT EXISTS (
+		SELECT 1 FROM public.morning_checkins
+		WHERE user_id = $1 AND date = $3::date
+	)`},
+	{KindNoActivity, "12:00", noActivityToday},
+	{KindEveningReview, "20:00", `NOT EXISTS (
+		SELECT 1 FROM public.evening_checkins
+		WHERE user_id = $1 AND date = $3::date
+		  AND COALESCE(evening_mood::text, biggest_win, blockers, goal_for_tomorrow) IS NOT NULL
+	)`},
+	{KindStreakAtRisk, "21:00", noActivityToday + ` AND EXISTS (
+		SELECT 1 FROM public.users WHERE id = $1 AND COALESCE(current_streak, 0) > 0
+	)`},
+}
+
+// Engine sends proactive coach messages.
+type Engine struct {
+	db         *pgxpool.Pool
+	dispatcher *notifications.Dispatcher
+	writer     llm.Provider // nil: templates only
+}
+
+// NewEngine creates a proactive coach engine.
+func NewEngine(db *pgxpool.Pool, dispatcher *notifications.Dispatcher) *Engine {
+	return &Engine{db: db, dispatcher: dispatcher}
+}
+
+// SetWriter sets the model that writes messages. It must be stateless
+// (Gemini): the message is not part of a Backboard thread. Without one,
+// or when it fails, messages come from templates.
+func (e *Engine) SetWriter(writer llm.Provider) {
+	e.writer = writer
+}
+
+// recipient is a user the engine may write to, with their settings.
+type recipient struct {
+	UserID        string
+	Timezone      string
+	QuietStart    string
+	QuietEnd      string
+	DailyCap      int
+	CompanionName string
+	HarshMode     bool
+}
+
+// claimed is a message claimed in proactive_messages, waiting to be sent.
+type claimed struct {
+	recipient
+	ID        string
+	Kind      Kind
+	LocalDate string
+	Ref       string
+
+	// focus_completed
+	FocusMinutes     int
+	FocusDescription string
+}
+
+// Run blocks until ctx is cancelled, scanning every minute.
+func (e *Engine) Run(ctx context.Context) {
+	log.Printf("💬 Proactive coach started (every %s)", tickInterval)
+
+	ticker := time.NewTicker(tickInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := e.tick(ctx, time.Now()); err != nil && ctx.Err() == nil {
+			log.Printf("Proactive coach tick failed: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			log.Println("💬 Proactive coach stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// tick claims due messages under the advisory lock, then writes and sends
+// them once the transaction is committed.
+func (e *Engine) tick(ctx context.Context, now time.Time) error {
+	tx, err := e.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin proactive transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var locked bool
+	if err := tx.QueryRow(ctx, `SELECT pg_try_advisory_xact_lock($1)`, proactiveLockKey).Scan(&locked); err != nil {
+		return fmt.Errorf("failed to take proactive lock: %w", err)
+	}
+	if !locked {
+		// Another instance is handling this tick
+		return nil
+	}
+
+	candidates, err := e.findScheduled(ctx, tx, now)
+	if err != nil {
+		return err
+	}
+	focus, err := e.findFocusCompletions(ctx, tx, now)
+	if err != nil {
+		return err
+	}
+	candidates = append(candidates, focus...)
+
+	var ready []claimed
+	for _, c := range candidates {
+		condition := "true"
+		for _, s := range schedule {
+			if s.kind == c.Kind {
+				condition = s.condition
+			}
+		}
+		ok, err := claimSavepoint(ctx, tx, &c, condition)
+		if err != nil {
+			log.Printf("Failed to claim %s message for user %s: %v", c.Kind, c.UserID, err)
+			continue
+		}
+		if ok {
+			ready = append(ready, c)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit proactive claims: %w", err)
+	}
+
+	// Claimed messages are sent even if shutdown has begun
+	e.sendAll(context.WithoutCancel(ctx), ready)
+
+	if len(ready) > 0 {
+		log.Printf("💬 Sent %d proactive message(s)", len(ready))
+	}
+	return nil
+}
+
+// claimSavepoint runs claim in a savepoint, so a failed claim only undoes
+// itself instead of aborting the tick's transaction and every other claim.
+func claimSavepoint(ctx context.Context, tx pgx.Tx, c *claimed, condition string) (bool, error) {
+	sp, err := tx.Begin(ctx)
+	if err != nil {
+		return false, fmt.Errorf("failed to create savepoint: %w", err)
+	}
+	defer sp.Rollback(ctx)
+
+	ok, err := claim(ctx, sp, c, condition)
+	if err != nil {
+		return false, err
+	}
+	if err := sp.Commit(ctx); err != nil {
+		return false, fmt.Errorf("failed to release savepoint: %w", err)
+	}
+	return ok, nil
+}
+
+// sendAll sends the claimed messages with at most sendWorkers at a time:
+// each one may wait on the LLM for up to sendTimeout.
+func (e *Engine) sendAll(ctx context.Context, ready []claimed) {
+	queue := make(chan claimed)
+	var wg sync.WaitGroup
+	for i := 0; i < min(sendWorkers, len(ready)); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for c := range queue {
+				e.send(ctx, c)
+			}
+		}()
+	}
+	for _, c := range ready {
+		queue <- c
+	}
+	close(queue)
+	wg.Wait()
+}
+
+const recipientColumns = `
+	u.id, COALESCE(u.timezone, 'Europe/Paris'),
+	u.quiet_hours_start, u.quiet_hours_end, u.proactive_daily_cap,
+	COALESCE(u.companion_name, 'Kai'), COALESCE(u.coach_harsh_mode, false)`
+
+func scanRecipient(rows pgx.Rows, r *recipient, extra ...any) error {
+	return rows.Scan(append([]any{&r.UserID, &r.Timezone, &r.QuietStart, &r.QuietEnd, &r.DailyCap, &r.CompanionName, &r.HarshMode}, extra...)...)
+}
+
+// findScheduled returns the slots whose time passed less than
+// catchUpWindow ago in each user's timezone, outside quiet hours. The
+// query only returns users with a slot in that window; unknown timezones
+// count as userclock.DefaultTimezone, as in Go.
+func (e *Engine) findScheduled(ctx context.Context, tx pgx.Tx, now time.Time) ([]claimed, error) {
+	slotTimes := make([]string, 0, len(schedule))
+	for _, s := range schedule {
+		slotTimes = append(slotTimes, s.at)
+	}
+
+	rows, err := tx.Query(ctx, `
+		WITH zones AS (SELECT name FROM pg_timezone_names)
+		SELECT `+recipientColumns+`
+		FROM public.users u
+		LEFT JOIN zones z ON z.name = u.timezone
+		CROSS JOIN LATERAL (
+			SELECT ($1::timestamptz AT TIME ZONE COALESCE(z.name, $4))::time AS local_time
+		) l
+		WHERE u.proactive_enabled AND u.proactive_daily_cap > 0
+		  AND EXISTS (
+			SELECT 1 FROM unnest($2::text[]) AS s(at)
+			WHERE l.local_time - s.at::time >= interval '0'
+			  AND l.local_time - s.at::time < $3::interval
+		  )
+	`, now, slotTimes, fmt.Sprintf("%d seconds", int(catchUpWindow.Seconds())), userclock.DefaultTimezone)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query proactive users: %w", err)
+	}
+	defer rows.Close()
+
+	var due []claimed
+	for rows.Next() {
+		var r recipient
+		if err := scanRecipient(rows, &r); err != nil {
+			log.Printf("Scan proactive user error: %v", err)
+			continue
+		}
+
+		due = append(due, dueSlots(r, now)...)
+	}
+	return due, rows.Err()
+}
+
+// dueSlots returns the slots of r whose local time passed less than
+// catchUpWindow before now, unless r is in quiet hours.
+func dueSlots(r recipient, now time.Time) []claimed {
+	localNow := userclock.At(now, r.Timezone).Now()
+	if inQuietHours(localNow, r.QuietStart, r.QuietEnd) {
+		return nil
+	}
+	var due []claimed
+	for _, s := range schedule {
+		at, ok := localTime(localNow, s.at)
+		if !ok || localNow.Before(at) || localNow.Sub(at) >= catchUpWindow {
+			continue
+		}
+		due = append(due, claimed{recipient: r, Kind: s.kind, LocalDate: localNow.Format(userclock.DateLayout)})
+	}
+	return due
+}
+
+// findFocusCompletions returns focus sessions completed less than
+// catchUpWindow ago by users outside their quiet hours.
+func (e *Engine) findFocusCompletions(ctx context.Context, tx pgx.Tx, now time.Time) ([]claimed, error) {
+	rows, err := tx.Query(ctx, `
+		SELECT `+recipientColumns+`,
+		       f.id, COALESCE(f.duration_minutes, 0), COALESCE(f.description, '')
+		FROM public.focus_sessions f
+		JOIN public.users u ON u.id = f.user_id
+		WHERE f.status = 'completed' AND f.completed_at > $1
+		  AND u.proactive_enabled AND u.proactive_daily_cap > 0
+	`, now.Add(-catchUpWindow))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query completed focus sessions: %w", err)
+	}
+	defer rows.Close()
+
+	var due []claimed
+	for rows.Next() {
+		c := claimed{Kind: KindFocusCompleted}
+		if err := scanRecipient(rows, &c.recipient, &c.Ref, &c.FocusMinutes, &c.FocusDescription); err != nil {
+			log.Printf("Scan focus completion error: %v", err)
+			continue
+		}
+		localNow := userclock.At(now, c.Timezone).Now()
+		if inQuietHours(localNow, c.QuietStart, c.QuietEnd) {
+			continue
+		}
+		c.LocalDate = localNow.Format(userclock.DateLayout)
+		due = append(due, c)
+	}
+	return due, rows.Err()
+}
+
+// claim records the message unless the daily cap is reached, the user is
+// chatting right now, condition no longer holds or it was already sent.
+func claim(ctx context.Context, tx pgx.Tx, c *claimed, condition string) (bool, error) {
+	err := tx.QueryRow(ctx, `
+		INSERT INTO public.proactive_messages (user_id, kind, local_date, ref)
+		SELECT $1, $2, $3::date, $4
+		WHERE (
+			SELECT COUNT(*) FROM public.proactive_messages
+			WHERE user_id = $1 AND local_date = $3::date
+		) < $5
+		AND NOT EXISTS (
+			SELECT 1 FROM public.chat_messages
+			WHERE user_id = $1 AND is_from_user AND created_at > now() - $6::interval
+		)
+		AND (`+condition+`)
+		ON CONFLICT (user_id, kind, local_date, ref) DO NOTHING
+		RETURNING id
+	`, c.UserID, string(c.Kind), c.LocalDate, c.Ref, c.DailyCap,
+		fmt.Sprintf("%d seconds", int(busyWindow.Seconds()))).Scan(&c.ID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// send writes the message, appends it to the transcript and pushes it.
+func (e *Engine) send(ctx context.Context, c claimed) {
+	ctx, cancel := context.WithTimeout(ctx, sendTimeout)
+	defer cancel()
+
+	text := e.compose(ctx, c)
+
+	var messageID string
+	err := e.db.QueryRow(ctx, `
+		INSERT INTO public.chat_messages (user_id, content, is_from_user, message_type, source)
+		VALUES ($1, $2, false, 'text', 'coach')
+		RETURNING id
+	`, c.UserID, text).Scan(&messageID)
+	if err != nil {
+		log.Printf("Failed to save proactive message for user %s: %v", c.UserID, err)
+		return
+	}
+	if _, err := e.db.Exec(ctx, `
+		UPDATE public.proactive_messages SET chat_message_id = $1 WHERE id = $2
+	`, messageID, c.ID); err != nil {
+		log.Printf("Failed to link proactive message %s: %v", c.ID, err)
+	}
+
+	if e.dispatcher == nil {
+		return
+	}
+	_, err = e.dispatcher.Send(ctx, c.UserID, notifications.Notification{
+		Title:      c.CompanionName,
+		Body:       text,
+		Category:   notifications.CategoryCoachMessages,
+		ThreadID:   "coach",
+		CollapseID: "coach-" + string(c.Kind),
+		Data: map[string]interface{}{
+			"type":       "coach_message",
+			"kind":       string(c.Kind),
+			"message_id": messageID,
+		},
+	})
+	if err != nil {
+		log.Printf("Failed to push proactive message for user %s: %v", c.UserID, err)
+	}
+}
+
+// localTime returns today's hh:mm in localNow's location.
+func localTime(localNow time.Time, hhmm string) (time.Time, bool) {
+	t, err := time.Parse("15:04", strings.TrimSpace(hhmm))
+	if err != nil {
+		return time.Time{}, false
+	}
+	return time.Date(localNow.Year(), localNow.Month(), localNow.Day(), t.Hour(), t.Minute(), 0, 0, localNow.Location()), true
+}
+
+// inQuietHours reports whether localNow falls in [start, end), which may
+// wrap midnight (22:00 → 08:00). Equal or invalid bounds mean none.
+func inQuietHours(localNow time.Time, start, end string) bool {
+	s, ok1 := localTime(localNow, start)
+	e, ok2 := localTime(localNow, end)
+	if !ok1 || !ok2 || s.Equal(e) {
+		return false
+	}
+	if s.Before(e) {
+		return !localNow.Before(s) && localNow.Before(e)
+	}
+	return !localNow.Before(s) || localNow.Before(e)
+}
@@ -0,0 +1,115 @@
+package proactive
+
+import (
+	"testing"
+	"time"
+)
+
+func TestInQuietHours(t *testing.T) {
+	tests := []struct {
+		start, end string
+		at         string // local HH:MM
+		want       bool
+	}{
+		// Wrapping past midnight
+		{"22:00", "07:00", "21:59", false},
+		{"22:00", "07:00", "22:00", true},
+		{"22:00", "07:00", "23:30", true},
+		{"22:00", "07:00", "00:00", true},
+		{"22:00", "07:00", "03:00", true},
+		{"22:00", "07:00", "06:59", true},
+		{"22:00", "07:00", "07:00", false},
+		{"22:00", "07:00", "12:00", false},
+		// Within the day
+		{"13:00", "14:30", "12:59", false},
+		{"13:00", "14:30", "13:00", true},
+		{"13:00", "14:30", "14:29", true},
+		{"13:00", "14:30", "14:30", false},
+		// Disabled or invalid
+		{"08:00", "08:00", "08:00", false},
+		{"", "07:00", "03:00", false},
+		{"22:00", "7h", "23:00", false},
+		{" 22:00 ", "07:00", "23:00", true},
+	}
+	for _, tt := range tests {
+		clock, err := time.Parse("15:04", tt.at)
+		if err != nil {
+			t.Fatal(err)
+		}
+		localNow := time.Date(2026, 10, 16, clock.Hour(), clock.Minute(), 0, 0, time.UTC)
+		if got := inQuietHours(localNow, tt.start, tt.end); got != tt.want {
+			t.Errorf("inQuietHours(%s, %q–%q) = %v, want %v", tt.at, tt.start, tt.end, got, tt.want)
+		}
+	}
+}
+
+func TestLocalTime(t *testing.T) {
+	paris, err := time.LoadLocation("Europe/Paris")
+	if err != nil {
+		t.Skip("tzdata unavailable")
+	}
+
+	tests := []struct {
+		localNow time.Time
+		hhmm     string
+		want     string // UTC, "" when invalid
+	}{
+		{time.Date(2026, 10, 16, 23, 50, 0, 0, paris), "09:00", "2026-10-16T07:00"},
+		{time.Date(2026, 10, 25, 1, 0, 0, 0, paris), "09:00", "2026-10-25T08:00"}, // After the fall back
+		{time.Date(2026, 3, 29, 12, 0, 0, 0, paris), "01:30", "2026-03-29T00:30"}, // Before spring forward
+		{time.Date(2026, 10, 16, 8, 0, 0, 0, time.UTC), " 21:15", "2026-10-16T21:15"},
+		{time.Date(2026, 10, 16, 8, 0, 0, 0, time.UTC), "9pm", ""},
+	}
+	for _, tt := range tests {
+		at, ok := localTime(tt.localNow, tt.hhmm)
+		got := ""
+		if ok {
+			got = at.UTC().Format("2006-01-02T15:04")
+		}
+		if got != tt.want {
+			t.Errorf("localTime(%s, %q) = %q, want %q", tt.localNow, tt.hhmm, got, tt.want)
+		}
+	}
+}
+
+func TestDueSlots(t *testing.T) {
+	if _, err := time.LoadLocation("Europe/Paris"); err != nil {
+		t.Skip("tzdata unavailable")
+	}
+
+	tests := []struct {
+		name     string
+		timezone string
+		quiet    [2]string
+		utc      string
+		want     Kind // "" when nothing is due
+		wantDate string
+	}{
+		{"Paris morning check-in", "Europe/Paris", [2]string{"22:00", "08:00"}, "2026-10-16T07:05:00Z", KindMorningCheckin, "2026-10-16"},
+		{"same instant in New York is night", "America/New_York", [2]string{"22:00", "08:00"}, "2026-10-16T07:05:00Z", "", ""},
+		{"same instant in Auckland is evening", "Pacific/Auckland", [2]string{"22:00", "08:00"}, "2026-10-16T07:05:00Z", KindEveningReview, "2026-10-16"},
+		{"New York evening is the next UTC day", "America/New_York", [2]string{"22:00", "08:00"}, "2026-10-17T00:05:00Z", KindEveningReview, "2026-10-16"},
+		{"Tokyo streak at risk", "Asia/Tokyo", [2]string{"23:00", "07:00"}, "2026-10-16T12:10:00Z", KindStreakAtRisk, "2026-10-16"},
+		{"unknown timezone counts as Paris", "Mars/Olympus", [2]string{"22:00", "08:00"}, "2026-10-16T10:00:00Z", KindNoActivity, "2026-10-16"},
+		{"catch-up window is over", "Europe/Paris", [2]string{"22:00", "08:00"}, "2026-10-16T07:15:00Z", "", ""},
+		{"slot inside quiet hours", "Europe/Paris", [2]string{"19:30", "23:00"}, "2026-10-16T18:05:00Z", "", ""},
+		{"quiet hours wrapping past midnight", "Europe/Paris", [2]string{"20:30", "09:30"}, "2026-10-16T07:05:00Z", "", ""},
+		{"winter time after the fall back", "Europe/Paris", [2]string{"22:00", "08:00"}, "2026-10-25T08:05:00Z", KindMorningCheckin, "2026-10-25"},
+		{"summer offset no longer applies", "Europe/Paris", [2]string{"22:00", "08:00"}, "2026-10-25T07:05:00Z", "", ""},
+	}
+	for _, tt := range tests {
+		now, err := time.Parse(time.RFC3339, tt.utc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r := recipient{UserID: "u1", Timezone: tt.timezone, QuietStart: tt.quiet[0], QuietEnd: tt.quiet[1], DailyCap: 3}
+		due := dueSlots(r, now)
+
+		switch {
+		case tt.want == "" && len(due) != 0:
+			t.Errorf("%s: due = %v, want nothing", tt.name, due)
+		case tt.want != "" && (len(due) != 1 || due[0].Kind != tt.want || due[0].LocalDate != tt.wantDate):
+			t.Errorf("%s: due = %v, want %s on %s", tt.name, due, tt.want, tt.wantDate)
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package proactive
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"firelevel-backend/internal/auth"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// maxDailyCap bounds proactive_daily_cap.
+const maxDailyCap = 10
+
+// Handler exposes the proactive coach settings.
+type Handler struct {
+	db *pgxpool.Pool
+}
+
+// NewHandler creates a new proactive coach handler
+func NewHandler(db *pgxpool.Pool) *Handler {
+	return &Handler{db: db}
+}
+
+// Settings are the user's proactive coach preferences.
+type Settings struct {
+	Enabled         bool   `json:"enabled"`
+	QuietHoursStart string `json:"quiet_hours_start"` // HH:MM local
+	QuietHoursEnd   string `json:"quiet_hours_end"`   // HH:MM local
+	DailyCap        int    `json:"daily_cap"`         // Proactive messages per local day
+}
+
+// UpdateSettingsRequest for PATCH /coach/settings
+type UpdateSettingsRequest struct {
+	Enabled         *bool   `json:"enabled"`
+	QuietHoursStart *string `json:"quiet_hours_start"`
+	QuietHoursEnd   *string `json:"quiet_hours_end"`
+	DailyCap        *int    `json:"daily_cap"`
+}
+
+func validHHMM(s string) bool {
+	_, err := time.Parse("15:04", strings.TrimSpace(s))
+	return err == nil
+}
+
+// GetSettings returns the proactive coach settings
+// GET /coach/settings
+func (h *Handler) GetSettings(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(auth.UserContextKey).(string)
+
+	var s Settings
+	err := h.db.QueryRow(r.Context(), `
+		SELECT proactive_enabled, quiet_hours_start, quiet_hours_end, proactive_daily_cap
+		FROM public.users WHERE id = $1
+	`, userID).Scan(&s.Enabled, &s.QuietHoursStart, &s.QuietHoursEnd, &s.DailyCap)
+	if err != nil {
+		log.Printf("Failed to fetch proactive settings for user %s: %v", userID, err)
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s)
+}
+
+// UpdateSettings changes the proactive coach settings
+// PATCH /coach/settings
+func (h *Handler) UpdateSettings(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(auth.UserContextKey).(string)
+
+	var req UpdateSettingsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.QuietHoursStart != nil && !validHHMM(*req.QuietHoursStart) {
+		http.Error(w, "quiet_hours_start must be HH:MM", http.StatusBadRequest)
+		return
+	}
+	if req.QuietHoursEnd != nil && !validHHMM(*req.QuietHoursEnd) {
+		http.Error(w, "quiet_hours_end must be HH:MM", http.StatusBadRequest)
+		return
+	}
+	if req.DailyCap != nil && (*req.DailyCap < 0 || *req.DailyCap > maxDailyCap) {
+		http.Error(w, fmt.Sprintf("daily_cap must be between 0 and %d", maxDailyCap), http.StatusBadRequest)
+		return
+	}
+
+	var s Settings
+	err := h.db.QueryRow(r.Context(), `
+		UPDATE public.users SET
+			proactive_enabled   = COALESCE($2, proactive_enabled),
+			quiet_hours_start   = COALESCE($3, quiet_hours_start),
+			quiet_hours_end     = COALESCE($4, quiet_hours_end),
+			proactive_daily_cap = COALESCE($5, proactive_daily_cap)
+		WHERE id = $1
+		RETURNING proactive_enabled, quiet_hours_start, quiet_hours_end, proactive_daily_cap
+	`, userID, req.Enabled, req.QuietHoursStart, req.QuietHoursEnd, req.DailyCap).Scan(
+		&s.Enabled, &s.QuietHoursStart, &s.QuietHoursEnd, &s.DailyCap)
+	if err != nil {
+		log.Printf("Failed to update proactive settings for user %s: %v", userID, err)
+		http.Error(w, "Failed to update settings", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s)
+}
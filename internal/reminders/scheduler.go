@@ -0,0 +1,235 @@
+package reminders
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"firelevel-backend/internal/notifications"
+	"firelevel-backend/internal/userclock"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// ===========================================
+// RITUAL REMINDERS - In-process scheduler
+// Every minute: find routines whose scheduled_time
+// has passed in the user's timezone, that are not
+// completed today, and push a reminder once.
+// ===========================================
+
+const (
+	// tickInterval is how often due routines are scanned.
+	tickInterval = time.Minute
+
+	// catchUpWindow bounds how late a reminder may go out (e.g. after a
+	// deploy). Older reminders are dropped rather than sent stale.
+	catchUpWindow = 15 * time.Minute
+
+	// ritualLockKey is the pg advisory lock shared by every instance so only
+	// one of them scans per tick. Transaction-scoped so it works behind
+	// PgBouncer/Supavisor in transaction mode.
+	ritualLockKey int64 = 0x7269_7475_616c // "ritual"
+
+	sendTimeout = 10 * time.Second
+)
+
+// Scheduler sends ritual reminders for routines with a scheduled_time.
+type Scheduler struct {
+	db         *pgxpool.Pool
+	dispatcher *notifications.Dispatcher
+}
+
+// NewScheduler creates a ritual reminder scheduler.
+func NewScheduler(db *pgxpool.Pool, dispatcher *notifications.Dispatcher) *Scheduler {
+	return &Scheduler{db: db, dispatcher: dispatcher}
+}
+
+// dueReminder is a routine whose reminder has been claimed for today.
+type dueReminder struct {
+	RoutineID string
+	UserID    string
+	Title     string
+	Icon      string
+	LocalDate string
+}
+
+// Run blocks until ctx is cancelled, scanning for due reminders every minute.
+func (s *Scheduler) Run(ctx context.Context) {
+	log.Printf("⏰ Ritual reminder scheduler started (every %s)", tickInterval)
+
+	ticker := time.NewTicker(tickInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := s.tick(ctx, time.Now()); err != nil && ctx.Err() == nil {
+			log.Printf("Ritual reminder tick failed: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			log.Println("⏰ Ritual reminder scheduler stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// tick claims due reminders under the advisory lock, then sends them once the
+// transaction is committed so the lock is never held during network I/O.
+func (s *Scheduler) tick(ctx context.Context, now time.Time) error {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin reminder transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var locked bool
+	if err := tx.QueryRow(ctx, `SELECT pg_try_advisory_xact_lock($1)`, ritualLockKey).Scan(&locked); err != nil {
+		return fmt.Errorf("failed to take reminder lock: %w", err)
+	}
+	if !locked {
+		// Another instance is handling this tick
+		return nil
+	}
+
+	candidates, err := s.findDueRoutines(ctx, tx, now)
+	if err != nil {
+		return err
+	}
+
+	var claimed []dueReminder
+	for _, c := range candidates {
+		ok, err := claimReminder(ctx, tx, c)
+		if err != nil {
+			log.Printf("Failed to claim ritual reminder for routine %s: %v", c.RoutineID, err)
+			continue
+		}
+		if ok {
+			claimed = append(claimed, c)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit reminder claims: %w", err)
+	}
+
+	// Claimed reminders are sent even if shutdown has begun
+	sendCtx := context.WithoutCancel(ctx)
+	for _, c := range claimed {
+		s.send(sendCtx, c)
+	}
+
+	if len(claimed) > 0 {
+		log.Printf("⏰ Sent %d ritual reminder(s)", len(claimed))
+	}
+	return nil
+}
+
+// findDueRoutines returns routines whose scheduled_time passed less than
+// catchUpWindow ago in their owner's timezone.
+func (s *Scheduler) findDueRoutines(ctx context.Context, tx pgx.Tx, now time.Time) ([]dueReminder, error) {
+	rows, err := tx.Query(ctx, `
+		SELECT r.id, r.user_id, r.title, COALESCE(r.icon, ''),
+		       COALESCE(r.frequency, 'daily'), r.scheduled_time,
+		       COALESCE(u.timezone, 'Europe/Paris'), COALESCE(r.created_at, now())
+		FROM public.routines r
+		JOIN public.users u ON u.id = r.user_id
+		WHERE r.scheduled_time IS NOT NULL AND r.scheduled_time != ''
+		  AND COALESCE(u.notifications_enabled, true)
+		  AND COALESCE((u.notification_settings->>'ritual_reminders')::boolean, true)
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query scheduled routines: %w", err)
+	}
+	defer rows.Close()
+
+	var due []dueReminder
+	for rows.Next() {
+		var d dueReminder
+		var frequency, scheduledTime, timezone string
+		var createdAt time.Time
+		if err := rows.Scan(&d.RoutineID, &d.UserID, &d.Title, &d.Icon, &frequency, &scheduledTime, &timezone, &createdAt); err != nil {
+			log.Printf("Scan scheduled routine error: %v", err)
+			continue
+		}
+
+		clock := userclock.At(now, timezone)
+		localNow := clock.Now()
+		if !runsOn(frequency, localNow.Weekday(), createdAt.In(clock.Location()).Weekday()) {
+			continue
+		}
+
+		at, err := time.ParseInLocation("15:04", scheduledTime, localNow.Location())
+		if err != nil {
+			continue
+		}
+		scheduledAt := time.Date(localNow.Year(), localNow.Month(), localNow.Day(), at.Hour(), at.Minute(), 0, 0, localNow.Location())
+
+		if localNow.Before(scheduledAt) || localNow.Sub(scheduledAt) >= catchUpWindow {
+			continue
+		}
+
+		d.LocalDate = localNow.Format(userclock.DateLayout)
+		due = append(due, d)
+	}
+
+	return due, rows.Err()
+}
+
+// claimReminder records the delivery for today unless the routine is already
+// completed or another instance claimed it first.
+func claimReminder(ctx context.Context, tx pgx.Tx, d dueReminder) (bool, error) {
+	tag, err := tx.Exec(ctx, `
+		INSERT INTO public.ritual_reminder_deliveries (routine_id, user_id, reminder_date)
+		SELECT $1, $2, $3::date
+		WHERE NOT EXISTS (
+			SELECT 1 FROM public.routine_completions
+			WHERE routine_id = $1 AND user_id = $2 AND completion_date = $3::date
+		)
+		ON CONFLICT (routine_id, reminder_date) DO NOTHING
+	`, d.RoutineID, d.UserID, d.LocalDate)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() == 1, nil
+}
+
+func (s *Scheduler) send(ctx context.Context, d dueReminder) {
+	ctx, cancel := context.WithTimeout(ctx, sendTimeout)
+	defer cancel()
+
+	_, err := s.dispatcher.Send(ctx, d.UserID, notifications.Notification{
+		Title:      d.Title,
+		Body:       "C'est l'heure de ton rituel ✨",
+		Category:   notifications.CategoryRitualReminders,
+		ThreadID:   "rituals",
+		CollapseID: "ritual-" + d.RoutineID,
+		Data: map[string]interface{}{
+			"type":       "ritual_reminder",
+			"routine_id": d.RoutineID,
+			"date":       d.LocalDate,
+		},
+	})
+	if err != nil {
+		log.Printf("Failed to send ritual reminder for routine %s: %v", d.RoutineID, err)
+	}
+}
+
+// runsOn reports whether a routine with the given frequency is due on weekday.
+// Weekly routines recur on anchor, the weekday they were created on.
+func runsOn(frequency string, weekday, anchor time.Weekday) bool {
+	isWeekend := weekday == time.Saturday || weekday == time.Sunday
+	switch frequency {
+	case "weekly":
+		return weekday == anchor
+	case "weekdays":
+		return !isWeekend
+	case "weekends":
+		return isWeekend
+	default:
+		return true
+	}
+}
@@ -0,0 +1,31 @@
+package reminders
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRunsOn(t *testing.T) {
+	tests := []struct {
+		frequency string
+		weekday   time.Weekday
+		anchor    time.Weekday
+		want      bool
+	}{
+		{"daily", time.Monday, time.Thursday, true},
+		{"daily", time.Sunday, time.Thursday, true},
+		{"weekdays", time.Friday, time.Monday, true},
+		{"weekdays", time.Saturday, time.Monday, false},
+		{"weekends", time.Sunday, time.Monday, true},
+		{"weekends", time.Wednesday, time.Monday, false},
+		{"weekly", time.Thursday, time.Thursday, true},
+		{"weekly", time.Friday, time.Thursday, false},
+		{"", time.Tuesday, time.Monday, true},
+	}
+
+	for _, tt := range tests {
+		if got := runsOn(tt.frequency, tt.weekday, tt.anchor); got != tt.want {
+			t.Errorf("runsOn(%q, %v, %v) = %v, want %v", tt.frequency, tt.weekday, tt.anchor, got, tt.want)
+		}
+	}
+}
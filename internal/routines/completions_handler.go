@@ -43,13 +43,13 @@ func (h *CompletionHandler) List(w http.ResponseWriter, r *http.Request) {
 
 	if fromDate != "" {
 		argCount++
-		query += fmt.Sprintf(" AND DATE(completed_at) >= $%d::date", argCount)
+		query += fmt.Sprintf(" AND completion_date >= $%d::date", argCount)
 		args = append(args, fromDate)
 	}
 
 	if toDate != "" {
 		argCount++
-		query += fmt.Sprintf(" AND DATE(completed_at) <= $%d::date", argCount)
+		query += fmt.Sprintf(" AND completion_date <= $%d::date", argCount)
 		args = append(args, toDate)
 	}
 
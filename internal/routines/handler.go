@@ -10,6 +10,7 @@ import (
 
 	"firelevel-backend/internal/auth"
 	"firelevel-backend/internal/streak"
+	"firelevel-backend/internal/userclock"
 	"github.com/go-chi/chi/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
@@ -254,7 +255,7 @@ func (h *Handler) Complete(w http.ResponseWriter, r *http.Request) {
 	// Default to today if no date provided
 	completionDate := req.Date
 	if completionDate == "" {
-		completionDate = time.Now().Format("2006-01-02")
+		completionDate = userclock.For(r.Context(), h.db, userID).Today()
 	}
 
 	log.Printf("Completing routine %s for user %s on date %s", routineID, userID, completionDate)
@@ -279,7 +280,7 @@ func (h *Handler) Complete(w http.ResponseWriter, r *http.Request) {
 	} else {
 		log.Printf("Routine completion created successfully for %s", completionDate)
 		// Update streak when a routine is completed
-		streak.UpdateUserStreak(r.Context(), h.db, userID)
+		streak.Record(r.Context(), h.db, userID, streak.ActivityRoutine)
 	}
 
 	w.WriteHeader(http.StatusOK)
@@ -308,6 +309,7 @@ func (h *Handler) BatchComplete(w http.ResponseWriter, r *http.Request) {
 	defer tx.Rollback(r.Context())
 	
 	now := time.Now()
+	today := userclock.For(r.Context(), h.db, userID).Today()
 	for _, id := range req.RoutineIDs {
 		// Verify the routine belongs to the user implicitly via foreign key constraints?
 		// No, we should verify ownership or rely on the fact that user_id is part of the insert.
@@ -323,12 +325,12 @@ func (h *Handler) BatchComplete(w http.ResponseWriter, r *http.Request) {
 		// INSERT INTO routine_completions ... SELECT ... FROM routines WHERE id = $2 AND user_id = $1
 		
 		safeQuery := `
-			INSERT INTO public.routine_completions (user_id, routine_id, completed_at)
-			SELECT $1, id, $3 FROM public.routines WHERE id = $2 AND user_id = $1
+			INSERT INTO public.routine_completions (user_id, routine_id, completed_at, completion_date)
+			SELECT $1, id, $3, $4::date FROM public.routines WHERE id = $2 AND user_id = $1
 			ON CONFLICT DO NOTHING
 		`
 
-		if _, err := tx.Exec(r.Context(), safeQuery, userID, id, now); err != nil {
+		if _, err := tx.Exec(r.Context(), safeQuery, userID, id, now, today); err != nil {
 			// If one fails, fail all? Or ignore?
 			// Let's fail all for consistency.
 			http.Error(w, "Failed to batch complete", http.StatusInternalServerError)
@@ -342,7 +344,7 @@ func (h *Handler) BatchComplete(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Update streak when routines are batch completed
-	streak.UpdateUserStreak(r.Context(), h.db, userID)
+	streak.Record(r.Context(), h.db, userID, streak.ActivityRoutine)
 
 	w.WriteHeader(http.StatusOK)
 }
@@ -0,0 +1,198 @@
+package routines
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+
+	"firelevel-backend/internal/streak"
+	"firelevel-backend/internal/tools"
+	"firelevel-backend/internal/userclock"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// ==========================================
+// AI TOOLS
+// Ritual tools Kai can call.
+// ==========================================
+
+// RegisterTools adds the routine tools to reg.
+func RegisterTools(reg *tools.Registry) error {
+	return reg.Register(
+		tools.Func[tools.NoArgs]{
+			Name:        "get_rituals",
+			Description: "Récupère la liste des rituels quotidiens avec statut de complétion.",
+			Run:         runGetRituals,
+		},
+		tools.Func[createRoutineArgs]{
+			Name:        "create_routine",
+			Description: "Crée un nouveau rituel quotidien.",
+			Run:         runCreateRoutine,
+		},
+		tools.Func[routineIDArgs]{
+			Name:        "complete_routine",
+			Description: "Marque un rituel comme complété pour aujourd'hui.",
+			Run:         runCompleteRoutine,
+		},
+		tools.Func[routineIDArgs]{
+			Name:        "delete_routine",
+			Description: "Supprime un rituel.",
+			Policy:      tools.PolicyConfirm,
+			Run:         runDeleteRoutine,
+			Preview:     previewDeleteRoutine,
+		},
+	)
+}
+
+type createRoutineArgs struct {
+	Title         string `json:"title" desc:"Le titre du rituel" required:"true"`
+	Icon          string `json:"icon,omitempty" desc:"Nom du SF Symbol (défaut: star)"`
+	Frequency     string `json:"frequency,omitempty" desc:"Fréquence" enum:"daily,weekdays,weekends"`
+	ScheduledTime string `json:"scheduled_time,omitempty" desc:"Heure programmée HH:MM (optionnel)" format:"time"`
+}
+
+type routineIDArgs struct {
+	RoutineID string `json:"routine_id" desc:"L'ID du rituel" required:"true"`
+}
+
+// RitualSummary is a routine and whether it is done today, as shown to Kai.
+type RitualSummary struct {
+	ID          string `json:"id"`
+	Title       string `json:"title"`
+	Icon        string `json:"icon"`
+	IsCompleted bool   `json:"is_completed"`
+}
+
+// RitualsForToday returns the user's routines with today's completion.
+func RitualsForToday(ctx context.Context, db *pgxpool.Pool, userID string) ([]RitualSummary, error) {
+	today := userclock.For(ctx, db, userID).Today()
+	rows, err := db.Query(ctx, `
+		SELECT r.id, r.title, COALESCE(r.icon, '✨'),
+		       EXISTS(SELECT 1 FROM routine_completions rc WHERE rc.routine_id = r.id AND rc.user_id = $1 AND rc.completion_date = $2) as is_completed
+		FROM routines r
+		WHERE r.user_id = $1
+		ORDER BY r.created_at
+	`, userID, today)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	rituals := []RitualSummary{}
+	for rows.Next() {
+		var r RitualSummary
+		if err := rows.Scan(&r.ID, &r.Title, &r.Icon, &r.IsCompleted); err != nil {
+			continue
+		}
+		rituals = append(rituals, r)
+	}
+	return rituals, rows.Err()
+}
+
+func runGetRituals(ctx context.Context, call *tools.Call, _ tools.NoArgs) (tools.Result, error) {
+	rituals, err := RitualsForToday(ctx, call.DB, call.UserID)
+	if err != nil {
+		return tools.Result{}, err
+	}
+	return tools.Result{Output: map[string]interface{}{"rituals": rituals}}, nil
+}
+
+func runCreateRoutine(ctx context.Context, call *tools.Call, args createRoutineArgs) (tools.Result, error) {
+	icon := args.Icon
+	if icon == "" {
+		icon = "star"
+	}
+	frequency := args.Frequency
+	if frequency == "" {
+		frequency = "daily"
+	}
+
+	// Get first area as default
+	var areaID string
+	if err := call.DB.QueryRow(ctx, "SELECT id FROM areas WHERE user_id = $1 LIMIT 1", call.UserID).Scan(&areaID); err != nil {
+		log.Printf("Failed to fetch default area for user %s: %v", call.UserID, err)
+	}
+
+	var routineID string
+	err := call.DB.QueryRow(ctx, `
+		INSERT INTO routines (user_id, area_id, title, frequency, icon, scheduled_time)
+		VALUES ($1, NULLIF($2, '')::uuid, $3, $4, $5, NULLIF($6, '')::time) RETURNING id
+	`, call.UserID, areaID, args.Title, frequency, icon, args.ScheduledTime).Scan(&routineID)
+	if err != nil {
+		return tools.Result{}, fmt.Errorf("create routine: %w", err)
+	}
+
+	return tools.Result{
+		Output:      map[string]interface{}{"created": true, "routine_id": routineID},
+		SideEffects: []tools.SideEffect{tools.RefreshRituals, tools.ShowCard("routines")},
+		Changes:     []tools.Change{call.Created(ctx, "routines", routineID)},
+		Summary:     "Rituel créé : " + args.Title,
+	}, nil
+}
+
+func runCompleteRoutine(ctx context.Context, call *tools.Call, args routineIDArgs) (tools.Result, error) {
+	today := userclock.For(ctx, call.DB, call.UserID).Today()
+	var completionID string
+	err := call.DB.QueryRow(ctx, `
+		INSERT INTO routine_completions (user_id, routine_id, completion_date)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (user_id, routine_id, completion_date) DO NOTHING
+		RETURNING id
+	`, call.UserID, args.RoutineID, today).Scan(&completionID)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return tools.Result{}, fmt.Errorf("complete routine: %w", err)
+	}
+
+	res := tools.Result{
+		Output:      map[string]interface{}{"completed": true, "routine_id": args.RoutineID},
+		SideEffects: []tools.SideEffect{tools.RefreshRituals},
+	}
+	// Already done today: nothing was written, nothing to undo
+	if completionID != "" {
+		streak.Record(ctx, call.DB, call.UserID, streak.ActivityRoutine)
+		res.Changes = []tools.Change{call.Created(ctx, "routine_completions", completionID)}
+		res.Summary = "Rituel complété"
+	}
+	return res, nil
+}
+
+func previewDeleteRoutine(ctx context.Context, call *tools.Call, args routineIDArgs) (tools.Preview, error) {
+	var r RitualSummary
+	err := call.DB.QueryRow(ctx, `
+		SELECT id, title, COALESCE(icon, '✨') FROM routines WHERE id = $1 AND user_id = $2
+	`, args.RoutineID, call.UserID).Scan(&r.ID, &r.Title, &r.Icon)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return tools.Preview{}, fmt.Errorf("routine not found: %s", args.RoutineID)
+	}
+	if err != nil {
+		return tools.Preview{}, err
+	}
+	return tools.Preview{Summary: "Supprimer le rituel « " + r.Title + " »", Details: r}, nil
+}
+
+func runDeleteRoutine(ctx context.Context, call *tools.Call, args routineIDArgs) (tools.Result, error) {
+	before, err := tools.Snapshot(ctx, call.DB, "routines", call.UserID, args.RoutineID)
+	if err != nil {
+		return tools.Result{}, fmt.Errorf("delete routine: %w", err)
+	}
+
+	// Undo restores the routine itself; its completion history goes with
+	// the ON DELETE CASCADE.
+	var title string
+	err = call.DB.QueryRow(ctx, "DELETE FROM routines WHERE id = $1 AND user_id = $2 RETURNING title", args.RoutineID, call.UserID).Scan(&title)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return tools.Result{}, fmt.Errorf("routine not found: %s", args.RoutineID)
+	}
+	if err != nil {
+		return tools.Result{}, fmt.Errorf("delete routine: %w", err)
+	}
+	return tools.Result{
+		Output:      map[string]interface{}{"deleted": true, "routine_id": args.RoutineID},
+		SideEffects: []tools.SideEffect{tools.RefreshRituals},
+		Changes:     []tools.Change{call.Deleted("routines", args.RoutineID, before)},
+		Summary:     "Rituel supprimé : " + title,
+	}, nil
+}
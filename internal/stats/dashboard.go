@@ -0,0 +1,124 @@
+package stats
+
+import (
+	"context"
+
+	"firelevel-backend/internal/userclock"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// maxActiveQuests caps the quests shown on the dashboard and fire mode.
+const maxActiveQuests = 20
+
+// DashboardUser identifies the user on the home screen.
+type DashboardUser struct {
+	ID       string `json:"id"`
+	FullName string `json:"full_name"`
+}
+
+// DashboardStats are the headline numbers of the home screen.
+type DashboardStats struct {
+	FocusedToday int `json:"focused_today"` // Minutes
+	StreakDays   int `json:"streak_days"`
+}
+
+// Dashboard is the home screen in one response (docs/stats.md).
+type Dashboard struct {
+	User           DashboardUser  `json:"user"`
+	Areas          []Area         `json:"areas"`
+	ActiveQuests   []Quest        `json:"active_quests"`
+	TodaysRoutines []Routine      `json:"todays_routines"`
+	Stats          DashboardStats `json:"stats"`
+	SessionsLast7  []Bucket       `json:"sessions_last_7"`
+	Today          *Today         `json:"today"`
+	Period         *Period        `json:"period"` // The requested range
+}
+
+// FireMode is the focus tracking screen (docs/stats.md).
+type FireMode struct {
+	MinutesToday  int     `json:"minutes_today"`
+	SessionsToday int     `json:"sessions_today"`
+	MinutesWeek   int     `json:"minutes_week"` // Since Monday
+	SessionsWeek  int     `json:"sessions_week"`
+	MinutesLast7  int     `json:"minutes_last_7"`
+	SessionsLast7 int     `json:"sessions_last_7"`
+	ActiveQuests  []Quest `json:"active_quests"`
+	Period        *Period `json:"period"` // The requested range
+}
+
+// periods returns the last 7 days and the requested range, sharing the
+// query when the range is the week.
+func periods(ctx context.Context, db *pgxpool.Pool, userID string, clock userclock.Clock, r Range) (last7, period *Period, err error) {
+	last7, err = GetPeriod(ctx, db, userID, clock, RangeWeek)
+	if err != nil || r == RangeWeek {
+		return last7, last7, err
+	}
+	period, err = GetPeriod(ctx, db, userID, clock, r)
+	return last7, period, err
+}
+
+// GetDashboard assembles the home screen.
+func GetDashboard(ctx context.Context, db *pgxpool.Pool, userID string, r Range) (*Dashboard, error) {
+	clock := userclock.For(ctx, db, userID)
+	d := &Dashboard{User: DashboardUser{ID: userID}}
+
+	err := db.QueryRow(ctx, `
+		SELECT COALESCE(NULLIF(TRIM(CONCAT_WS(' ', first_name, last_name)), ''), pseudo, '')
+		FROM public.users WHERE id = $1
+	`, userID).Scan(&d.User.FullName)
+	if err != nil {
+		return nil, err
+	}
+
+	if d.Today, err = GetToday(ctx, db, userID, clock); err != nil {
+		return nil, err
+	}
+	d.Stats = DashboardStats{FocusedToday: d.Today.FocusMinutes, StreakDays: d.Today.CurrentStreak}
+
+	last7, period, err := periods(ctx, db, userID, clock, r)
+	if err != nil {
+		return nil, err
+	}
+	d.SessionsLast7, d.Period = last7.Series, period
+
+	if d.Areas, err = Areas(ctx, db, userID); err != nil {
+		return nil, err
+	}
+	if d.ActiveQuests, err = ActiveQuests(ctx, db, userID, maxActiveQuests); err != nil {
+		return nil, err
+	}
+	if d.TodaysRoutines, err = RoutinesOn(ctx, db, userID, d.Today.Date); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// GetFireMode assembles the fire mode screen.
+func GetFireMode(ctx context.Context, db *pgxpool.Pool, userID string, r Range) (*FireMode, error) {
+	clock := userclock.For(ctx, db, userID)
+
+	today, err := GetToday(ctx, db, userID, clock)
+	if err != nil {
+		return nil, err
+	}
+	last7, period, err := periods(ctx, db, userID, clock, r)
+	if err != nil {
+		return nil, err
+	}
+	quests, err := ActiveQuests(ctx, db, userID, maxActiveQuests)
+	if err != nil {
+		return nil, err
+	}
+
+	return &FireMode{
+		MinutesToday:  today.FocusMinutes,
+		SessionsToday: today.FocusSessions,
+		MinutesWeek:   today.FocusMinutesWeek,
+		SessionsWeek:  today.FocusSessionsWeek,
+		MinutesLast7:  last7.FocusMinutes,
+		SessionsLast7: last7.FocusSessions,
+		ActiveQuests:  quests,
+		Period:        period,
+	}, nil
+}
@@ -0,0 +1,63 @@
+package stats
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"firelevel-backend/internal/auth"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Handler exposes the aggregated stats over HTTP.
+type Handler struct {
+	db *pgxpool.Pool
+}
+
+// NewHandler creates a new stats handler
+func NewHandler(db *pgxpool.Pool) *Handler {
+	return &Handler{db: db}
+}
+
+// Dashboard returns the home screen data in one request
+// GET /dashboard?range=week|month|year
+func (h *Handler) Dashboard(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(auth.UserContextKey).(string)
+	rng, err := ParseRange(r.URL.Query().Get("range"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	dashboard, err := GetDashboard(r.Context(), h.db, userID, rng)
+	if err != nil {
+		log.Printf("Failed to get dashboard for user %s: %v", userID, err)
+		http.Error(w, "Failed to get dashboard", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(dashboard)
+}
+
+// FireMode returns the focus stats of the fire mode screen
+// GET /firemode?range=week|month|year
+func (h *Handler) FireMode(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(auth.UserContextKey).(string)
+	rng, err := ParseRange(r.URL.Query().Get("range"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	fireMode, err := GetFireMode(r.Context(), h.db, userID, rng)
+	if err != nil {
+		log.Printf("Failed to get fire mode stats for user %s: %v", userID, err)
+		http.Error(w, "Failed to get fire mode stats", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(fireMode)
+}
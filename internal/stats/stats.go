@@ -0,0 +1,299 @@
+package stats
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"firelevel-backend/internal/calendar"
+	"firelevel-backend/internal/streak"
+	"firelevel-backend/internal/userclock"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// ===========================================
+// STATS AGGREGATOR
+// The user's numbers computed in a few aggregate
+// queries: today at a glance, and a period (week,
+// month, year) with its focus series. /dashboard,
+// /firemode and the chat context all read them here.
+// Days are local (users.timezone); focus counts
+// completed sessions only.
+// ===========================================
+
+// Range is the period covered by the totals and the series.
+type Range string
+
+const (
+	RangeWeek  Range = "week"  // Last 7 days, per day
+	RangeMonth Range = "month" // Last 30 days, per day
+	RangeYear  Range = "year"  // Last 12 months, per month
+)
+
+// ParseRange reads a ?range value; empty means week.
+func ParseRange(s string) (Range, error) {
+	switch Range(s) {
+	case "", RangeWeek:
+		return RangeWeek, nil
+	case RangeMonth, RangeYear:
+		return Range(s), nil
+	}
+	return "", fmt.Errorf("invalid range %q (use week, month or year)", s)
+}
+
+// Today is the user's day at a glance.
+type Today struct {
+	Date              string `json:"date"`
+	FocusMinutes      int    `json:"focus_minutes"`
+	FocusSessions     int    `json:"focus_sessions"`
+	FocusMinutesWeek  int    `json:"focus_minutes_week"` // Since Monday
+	FocusSessionsWeek int    `json:"focus_sessions_week"`
+	TasksTotal        int    `json:"tasks_total"`
+	TasksCompleted    int    `json:"tasks_completed"`
+	RitualsTotal      int    `json:"rituals_total"`
+	RitualsCompleted  int    `json:"rituals_completed"`
+	CurrentStreak     int    `json:"current_streak"` // 0 once broken
+}
+
+// Bucket is the focus of one day, or one month for RangeYear.
+type Bucket struct {
+	Date     string `json:"date"` // First day of the bucket
+	Minutes  int    `json:"minutes"`
+	Sessions int    `json:"sessions"`
+}
+
+// Period is the totals of a range ending today.
+type Period struct {
+	Range            Range    `json:"range"`
+	From             string   `json:"from"`
+	To               string   `json:"to"`
+	FocusMinutes     int      `json:"focus_minutes"`
+	FocusSessions    int      `json:"focus_sessions"`
+	TasksCompleted   int      `json:"tasks_completed"`
+	RitualsCompleted int      `json:"rituals_completed"`
+	ActiveDays       int      `json:"active_days"` // Days that counted for the streak
+	Series           []Bucket `json:"series"`
+}
+
+// GetToday aggregates today's focus, tasks, rituals and streak.
+func GetToday(ctx context.Context, db *pgxpool.Pool, userID string, clock userclock.Clock) (*Today, error) {
+	t := &Today{Date: clock.Today()}
+	dayStart, dayEnd := clock.TodayBounds()
+	weekStart, weekEnd := clock.WeekBounds()
+
+	err := db.QueryRow(ctx, `
+		WITH focus AS (
+			SELECT
+				COALESCE(SUM(duration_minutes) FILTER (WHERE started_at >= $3 AND started_at < $4), 0) AS minutes_today,
+				COUNT(*) FILTER (WHERE started_at >= $3 AND started_at < $4) AS sessions_today,
+				COALESCE(SUM(duration_minutes), 0) AS minutes_week,
+				COUNT(*) AS sessions_week
+			FROM public.focus_sessions
+			WHERE user_id = $1 AND status = 'completed' AND started_at >= $5 AND started_at < $6
+		)
+		SELECT focus.minutes_today, focus.sessions_today, focus.minutes_week, focus.sessions_week,
+		       (SELECT COUNT(*) FROM public.routines WHERE user_id = $1),
+		       (SELECT COUNT(DISTINCT routine_id) FROM public.routine_completions
+		        WHERE user_id = $1 AND completion_date = $2::date)
+		FROM focus
+	`, userID, t.Date, dayStart, dayEnd, weekStart, weekEnd).Scan(
+		&t.FocusMinutes, &t.FocusSessions, &t.FocusMinutesWeek, &t.FocusSessionsWeek,
+		&t.RitualsTotal, &t.RitualsCompleted)
+	if err != nil {
+		return nil, err
+	}
+
+	t.TasksTotal, t.TasksCompleted, err = calendar.CountDay(ctx, db, userID, t.Date)
+	if err != nil {
+		return nil, fmt.Errorf("count today's tasks: %w", err)
+	}
+
+	status, err := streak.GetStatus(ctx, db, userID)
+	if err != nil {
+		log.Printf("Failed to get streak for user %s: %v", userID, err)
+	} else {
+		t.CurrentStreak = status.CurrentStreak
+	}
+	return t, nil
+}
+
+// GetPeriod aggregates the range ending today.
+func GetPeriod(ctx context.Context, db *pgxpool.Pool, userID string, clock userclock.Clock, r Range) (*Period, error) {
+	p := &Period{Range: r, To: clock.Today(), Series: []Bucket{}}
+	unit, step := "day", "1 day"
+	switch r {
+	case RangeMonth:
+		p.From = clock.AddDays(-29)
+	case RangeYear:
+		now := clock.Now()
+		p.From = time.Date(now.Year(), now.Month()-11, 1, 0, 0, 0, 0, clock.Location()).Format(userclock.DateLayout)
+		unit, step = "month", "1 month"
+	default:
+		p.From = clock.AddDays(-6)
+	}
+
+	from, _, err := clock.DayBounds(p.From)
+	if err != nil {
+		return nil, err
+	}
+	_, to, err := clock.DayBounds(p.To)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := db.Query(ctx, `
+		WITH focus AS (
+			SELECT date_trunc($4, started_at AT TIME ZONE $5)::date AS bucket, duration_minutes
+			FROM public.focus_sessions
+			WHERE user_id = $1 AND status = 'completed' AND started_at >= $2 AND started_at < $3
+		)
+		SELECT d::date, COALESCE(SUM(focus.duration_minutes), 0), COUNT(focus.bucket)
+		FROM generate_series($6::date::timestamp, $7::date::timestamp, $8::interval) d
+		LEFT JOIN focus ON focus.bucket = d::date
+		GROUP BY d
+		ORDER BY d
+	`, userID, from, to, unit, clock.Location().String(), p.From, p.To, step)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var b Bucket
+		var date time.Time
+		if err := rows.Scan(&date, &b.Minutes, &b.Sessions); err != nil {
+			return nil, err
+		}
+		b.Date = date.Format(userclock.DateLayout)
+		p.FocusMinutes += b.Minutes
+		p.FocusSessions += b.Sessions
+		p.Series = append(p.Series, b)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	err = db.QueryRow(ctx, `
+		SELECT
+			(SELECT COUNT(*) FROM public.tasks
+			 WHERE user_id = $1 AND date >= $2::date AND date <= $3::date
+			   AND status = 'completed' AND recurrence_rule IS NULL),
+			(SELECT COUNT(*) FROM public.routine_completions
+			 WHERE user_id = $1 AND completion_date >= $2::date AND completion_date <= $3::date),
+			(SELECT COUNT(*) FROM public.streak_days
+			 WHERE user_id = $1 AND day >= $2::date AND day <= $3::date AND NOT frozen)
+	`, userID, p.From, p.To).Scan(&p.TasksCompleted, &p.RitualsCompleted, &p.ActiveDays)
+	if err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// ===========================================
+// Lists
+// ===========================================
+
+// Area is a life area.
+type Area struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	Icon string `json:"icon"`
+}
+
+// Quest is an active quest with its progress.
+type Quest struct {
+	ID           string  `json:"id"`
+	Title        string  `json:"title"`
+	CurrentValue int     `json:"current_value"`
+	TargetValue  int     `json:"target_value"`
+	AreaID       *string `json:"area_id"`
+	AreaName     *string `json:"area_name"`
+}
+
+// Routine is a routine and whether it is done today.
+type Routine struct {
+	ID        string `json:"id"`
+	Title     string `json:"title"`
+	Icon      string `json:"icon"`
+	Completed bool   `json:"completed"`
+}
+
+// Areas returns the user's areas.
+func Areas(ctx context.Context, db *pgxpool.Pool, userID string) ([]Area, error) {
+	rows, err := db.Query(ctx, `
+		SELECT id, name, COALESCE(icon, 'star') FROM public.areas
+		WHERE user_id = $1
+		ORDER BY created_at
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	areas := []Area{}
+	for rows.Next() {
+		var a Area
+		if err := rows.Scan(&a.ID, &a.Name, &a.Icon); err != nil {
+			log.Printf("Scan area error: %v", err)
+			continue
+		}
+		areas = append(areas, a)
+	}
+	return areas, rows.Err()
+}
+
+// ActiveQuests returns up to limit active quests, oldest first.
+func ActiveQuests(ctx context.Context, db *pgxpool.Pool, userID string, limit int) ([]Quest, error) {
+	rows, err := db.Query(ctx, `
+		SELECT q.id, q.title, COALESCE(q.current_value, 0), COALESCE(q.target_value, 0), q.area_id, a.name
+		FROM public.quests q
+		LEFT JOIN public.areas a ON a.id = q.area_id
+		WHERE q.user_id = $1 AND q.status = 'active'
+		ORDER BY q.created_at
+		LIMIT $2
+	`, userID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	quests := []Quest{}
+	for rows.Next() {
+		var q Quest
+		if err := rows.Scan(&q.ID, &q.Title, &q.CurrentValue, &q.TargetValue, &q.AreaID, &q.AreaName); err != nil {
+			log.Printf("Scan quest error: %v", err)
+			continue
+		}
+		quests = append(quests, q)
+	}
+	return quests, rows.Err()
+}
+
+// RoutinesOn returns the user's routines with their completion on date.
+func RoutinesOn(ctx context.Context, db *pgxpool.Pool, userID, date string) ([]Routine, error) {
+	rows, err := db.Query(ctx, `
+		SELECT r.id, r.title, COALESCE(r.icon, '✨'),
+		       EXISTS(SELECT 1 FROM public.routine_completions rc
+		              WHERE rc.routine_id = r.id AND rc.user_id = $1 AND rc.completion_date = $2::date)
+		FROM public.routines r
+		WHERE r.user_id = $1
+		ORDER BY r.created_at
+	`, userID, date)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	routines := []Routine{}
+	for rows.Next() {
+		var r Routine
+		if err := rows.Scan(&r.ID, &r.Title, &r.Icon, &r.Completed); err != nil {
+			log.Printf("Scan routine error: %v", err)
+			continue
+		}
+		routines = append(routines, r)
+	}
+	return routines, rows.Err()
+}
@@ -0,0 +1,81 @@
+package streak
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"time"
+
+	"firelevel-backend/internal/auth"
+	"firelevel-backend/internal/userclock"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// defaultHistoryDays is the window returned by GetHistory without ?from.
+const defaultHistoryDays = 30
+
+// Handler exposes the streak over HTTP.
+type Handler struct {
+	db *pgxpool.Pool
+}
+
+// NewHandler creates a new streak handler
+func NewHandler(db *pgxpool.Pool) *Handler {
+	return &Handler{db: db}
+}
+
+// GetStreak returns the current streak, freezes and today's state
+// GET /streak
+func (h *Handler) GetStreak(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(auth.UserContextKey).(string)
+
+	status, err := GetStatus(r.Context(), h.db, userID)
+	if err != nil {
+		log.Printf("Failed to get streak for user %s: %v", userID, err)
+		http.Error(w, "Failed to get streak", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(status)
+}
+
+// GetHistory returns the days that counted for the streak
+// GET /streak/history?from=YYYY-MM-DD&to=YYYY-MM-DD
+func (h *Handler) GetHistory(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(auth.UserContextKey).(string)
+	from := r.URL.Query().Get("from")
+	to := r.URL.Query().Get("to")
+
+	clock := userclock.For(r.Context(), h.db, userID)
+	if to == "" {
+		to = clock.Today()
+	}
+	if from == "" {
+		from = clock.AddDays(-(defaultHistoryDays - 1))
+	}
+	if _, err := time.Parse(userclock.DateLayout, from); err != nil {
+		http.Error(w, "Invalid from date (use YYYY-MM-DD)", http.StatusBadRequest)
+		return
+	}
+	if _, err := time.Parse(userclock.DateLayout, to); err != nil {
+		http.Error(w, "Invalid to date (use YYYY-MM-DD)", http.StatusBadRequest)
+		return
+	}
+
+	days, err := GetHistory(r.Context(), h.db, userID, from, to)
+	if err != nil {
+		log.Printf("Failed to get streak history for user %s: %v", userID, err)
+		http.Error(w, "Failed to get streak history", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"days":  days,
+		"count": len(days),
+		"from":  from,
+		"to":    to,
+	})
+}
@@ -2,34 +2,357 @@ package streak
 
 import (
 	"context"
+	"fmt"
 	"log"
+	"time"
 
+	"firelevel-backend/internal/userclock"
+
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
-// UpdateUserStreak updates the user's streak based on daily engagement.
+// ===========================================
+// STREAK ENGINE
+// A day counts when the user chats, completes a
+// task or routine, or finishes a focus session.
+// Days are local (users.timezone). Missed days can
+// be covered by freeze tokens earned along the way.
+// ===========================================
+
+// Activity is what qualified a day for the streak.
+type Activity string
+
+const (
+	ActivityChat    Activity = "chat"
+	ActivityTask    Activity = "task"
+	ActivityRoutine Activity = "routine"
+	ActivityFocus   Activity = "focus"
+)
+
+const (
+	// FreezeEarnEvery awards one freeze token every N consecutive days.
+	FreezeEarnEvery = 7
+
+	// MaxFreezes caps how many freeze tokens a user can hold.
+	MaxFreezes = 2
+
+	// GraceHours lets late-night activity (before 03:00 local) count for the
+	// previous day when that day has no activity yet.
+	GraceHours = 3
+)
+
+// Record marks today as active for userID and advances the streak.
 // Should be called when: user sends a chat message, completes a task,
 // completes a routine, or finishes a focus session.
-func UpdateUserStreak(ctx context.Context, db *pgxpool.Pool, userID string) {
-	_, err := db.Exec(ctx, `
-		UPDATE users SET
-			current_streak = CASE
-				WHEN last_active_date = CURRENT_DATE THEN current_streak
-				WHEN last_active_date = CURRENT_DATE - 1 THEN COALESCE(current_streak, 0) + 1
-				ELSE 1
+// Errors are logged, never surfaced: a streak glitch must not fail the action.
+func Record(ctx context.Context, db *pgxpool.Pool, userID string, activity Activity) {
+	if err := record(ctx, db, userID, activity); err != nil {
+		log.Printf("Failed to update streak for user %s: %v", userID, err)
+	}
+}
+
+func record(ctx context.Context, db *pgxpool.Pool, userID string, activity Activity) error {
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("begin: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// Lock the user row so concurrent activities don't double-count a day
+	var currentStreak, longestStreak, freezes int
+	var lastActive *time.Time
+	err = tx.QueryRow(ctx, `
+		SELECT COALESCE(current_streak, 0), COALESCE(longest_streak, 0),
+		       last_active_date, COALESCE(streak_freezes, 0)
+		FROM public.users WHERE id = $1
+		FOR UPDATE
+	`, userID).Scan(&currentStreak, &longestStreak, &lastActive, &freezes)
+	if err != nil {
+		return fmt.Errorf("load streak: %w", err)
+	}
+
+	clock := userclock.For(ctx, tx, userID)
+	day, err := activityDay(ctx, tx, userID, clock)
+	if err != nil {
+		return err
+	}
+
+	// A freeze already covering this day is refunded once real activity lands on it
+	var wasFrozen bool
+	err = tx.QueryRow(ctx, `
+		SELECT COALESCE(bool_or(frozen), false) FROM public.streak_days WHERE user_id = $1 AND day = $2::date
+	`, userID, day).Scan(&wasFrozen)
+	if err != nil {
+		return fmt.Errorf("check frozen day: %w", err)
+	}
+
+	// Upsert the day; xmax = 0 only for a freshly inserted row
+	var inserted bool
+	err = tx.QueryRow(ctx, `
+		INSERT INTO public.streak_days (user_id, day, activities)
+		VALUES ($1, $2::date, ARRAY[$3::text])
+		ON CONFLICT (user_id, day) DO UPDATE SET
+			activities = CASE
+				WHEN $3::text = ANY(public.streak_days.activities) THEN public.streak_days.activities
+				ELSE array_append(public.streak_days.activities, $3::text)
 			END,
-			longest_streak = GREATEST(
-				COALESCE(longest_streak, 0),
-				CASE
-					WHEN last_active_date = CURRENT_DATE THEN COALESCE(current_streak, 0)
-					WHEN last_active_date = CURRENT_DATE - 1 THEN COALESCE(current_streak, 0) + 1
-					ELSE 1
-				END
-			),
-			last_active_date = CURRENT_DATE
+			frozen = false,
+			updated_at = NOW()
+		RETURNING (xmax = 0)
+	`, userID, day, string(activity)).Scan(&inserted)
+	if err != nil {
+		return fmt.Errorf("upsert streak day: %w", err)
+	}
+
+	dayDate, err := time.Parse(userclock.DateLayout, day)
+	if err != nil {
+		return fmt.Errorf("parse activity day %q: %w", day, err)
+	}
+	before := progress{current: currentStreak, longest: longestStreak, freezes: freezes}
+	if lastActive != nil {
+		last := truncateDate(*lastActive)
+		before.lastActive = &last
+	}
+	after, frozenDays, changed := advance(before, dayDate, wasFrozen, inserted)
+	if !changed {
+		// Day already counted, or history only
+		return tx.Commit(ctx)
+	}
+
+	for _, d := range frozenDays {
+		if _, err := tx.Exec(ctx, `
+			INSERT INTO public.streak_days (user_id, day, frozen)
+			VALUES ($1, $2::date, true)
+			ON CONFLICT (user_id, day) DO NOTHING
+		`, userID, d.Format(userclock.DateLayout)); err != nil {
+			return fmt.Errorf("insert frozen day: %w", err)
+		}
+	}
+	switch {
+	case wasFrozen:
+		log.Printf("🧊 User %s got a streak freeze refunded for %s", userID, day)
+	case len(frozenDays) > 0:
+		log.Printf("🧊 User %s used %d streak freeze(s)", userID, len(frozenDays))
+	}
+	if after.freezes > before.freezes && !wasFrozen {
+		log.Printf("🧊 User %s earned a streak freeze (%d days)", userID, after.current)
+	}
+
+	if _, err := tx.Exec(ctx, `
+		UPDATE public.users SET
+			current_streak = $2,
+			longest_streak = $3,
+			last_active_date = $4::date,
+			streak_freezes = $5
 		WHERE id = $1
-	`, userID)
+	`, userID, after.current, after.longest, after.lastActive.Format(userclock.DateLayout), after.freezes); err != nil {
+		return fmt.Errorf("update streak: %w", err)
+	}
+
+	return tx.Commit(ctx)
+}
+
+// progress is the streak stored on the user row.
+type progress struct {
+	current    int
+	longest    int
+	freezes    int
+	lastActive *time.Time // UTC midnight, nil if never active
+}
+
+// advance applies real activity on day (UTC midnight) to p. frozen
+// reports that a freeze covered the day, fresh that it had no row yet.
+// It returns the new progress, the missed days to cover with freezes, and
+// whether anything changed.
+func advance(p progress, day time.Time, frozen, fresh bool) (progress, []time.Time, bool) {
+	if frozen {
+		// The day now counts for real: give the token back and count the day
+		p.freezes = min(p.freezes+1, MaxFreezes)
+		p.current++
+		p.longest = max(p.longest, p.current)
+		if p.lastActive == nil || day.After(*p.lastActive) {
+			p.lastActive = &day
+		}
+		return p, nil, true
+	}
+	if !fresh {
+		return p, nil, false
+	}
+
+	var frozenDays []time.Time
+	switch {
+	case p.lastActive == nil:
+		p.current = 1
+	default:
+		gap := int(day.Sub(*p.lastActive).Hours() / 24)
+		switch {
+		case gap <= 0:
+			// Same or earlier day than last_active_date: history only
+			return p, nil, false
+		case gap == 1:
+			p.current++
+		case gap-1 <= p.freezes:
+			// Spend one freeze per missed day and keep the streak alive
+			for i := 1; i < gap; i++ {
+				frozenDays = append(frozenDays, p.lastActive.AddDate(0, 0, i))
+			}
+			p.freezes -= gap - 1
+			p.current++
+		default:
+			// Too many missed days
+			p.current = 1
+		}
+	}
+
+	if p.current%FreezeEarnEvery == 0 && p.freezes < MaxFreezes {
+		p.freezes++
+	}
+	p.longest = max(p.longest, p.current)
+	p.lastActive = &day
+	return p, frozenDays, true
+}
+
+// activityDay returns the local day an activity counts for, applying the
+// late-night grace period.
+func activityDay(ctx context.Context, tx pgx.Tx, userID string, clock userclock.Clock) (string, error) {
+	if clock.Now().Hour() >= GraceHours {
+		return clock.Today(), nil
+	}
+
+	// A day only covered by a freeze can still be saved by real activity
+	var yesterdayActive bool
+	err := tx.QueryRow(ctx, `
+		SELECT EXISTS(SELECT 1 FROM public.streak_days WHERE user_id = $1 AND day = $2::date AND NOT frozen)
+	`, userID, clock.Yesterday()).Scan(&yesterdayActive)
 	if err != nil {
-		log.Printf("Failed to update streak for user %s: %v", userID, err)
+		return "", fmt.Errorf("check yesterday: %w", err)
+	}
+	return dayFor(clock, yesterdayActive), nil
+}
+
+// dayFor picks the day of an activity: within the grace hours it goes to
+// yesterday unless yesterday already has real activity.
+func dayFor(clock userclock.Clock, yesterdayActive bool) string {
+	if clock.Now().Hour() >= GraceHours || yesterdayActive {
+		return clock.Today()
 	}
+	return clock.Yesterday()
+}
+
+// ===========================================
+// READ SIDE
+// ===========================================
+
+// Status is the user's streak as seen today.
+type Status struct {
+	CurrentStreak  int     `json:"current_streak"`
+	LongestStreak  int     `json:"longest_streak"`
+	LastActiveDate *string `json:"last_active_date"`
+	ActiveToday    bool    `json:"active_today"`
+	// "active" (done today), "pending" (not yet today, streak still alive),
+	// "frozen" (missed days will be covered by freezes), "broken"
+	State               string `json:"state"`
+	FreezesAvailable    int    `json:"freezes_available"`
+	MaxFreezes          int    `json:"max_freezes"`
+	DaysUntilNextFreeze int    `json:"days_until_next_freeze"`
+	Today               string `json:"today"`
+}
+
+// GetStatus computes the streak status without modifying anything. The stored
+// current_streak only moves on activity, so a broken streak reads as 0 here.
+func GetStatus(ctx context.Context, db *pgxpool.Pool, userID string) (*Status, error) {
+	var s Status
+	var lastActive *time.Time
+	err := db.QueryRow(ctx, `
+		SELECT COALESCE(current_streak, 0), COALESCE(longest_streak, 0),
+		       last_active_date, COALESCE(streak_freezes, 0)
+		FROM public.users WHERE id = $1
+	`, userID).Scan(&s.CurrentStreak, &s.LongestStreak, &lastActive, &s.FreezesAvailable)
+	if err != nil {
+		return nil, err
+	}
+
+	clock := userclock.For(ctx, db, userID)
+	s.Today = clock.Today()
+	s.MaxFreezes = MaxFreezes
+
+	if lastActive == nil {
+		s.CurrentStreak = 0
+		s.State = "broken"
+	} else {
+		last := truncateDate(*lastActive).Format(userclock.DateLayout)
+		s.LastActiveDate = &last
+
+		today, err := time.Parse(userclock.DateLayout, s.Today)
+		if err != nil {
+			return nil, fmt.Errorf("parse today %q: %w", s.Today, err)
+		}
+		gap := int(today.Sub(truncateDate(*lastActive)).Hours() / 24)
+		// Within the grace period, yesterday can still be saved
+		if clock.Now().Hour() < GraceHours && gap > 1 {
+			gap--
+		}
+
+		switch {
+		case gap <= 0:
+			s.ActiveToday = true
+			s.State = "active"
+		case gap == 1:
+			s.State = "pending"
+		case gap-1 <= s.FreezesAvailable:
+			s.State = "frozen"
+		default:
+			s.CurrentStreak = 0
+			s.State = "broken"
+		}
+	}
+
+	if s.FreezesAvailable < MaxFreezes {
+		s.DaysUntilNextFreeze = FreezeEarnEvery - s.CurrentStreak%FreezeEarnEvery
+	}
+
+	return &s, nil
+}
+
+// Day is one row of the streak history.
+type Day struct {
+	Date       string   `json:"date"`
+	Activities []string `json:"activities"`
+	Frozen     bool     `json:"frozen"`
+}
+
+// GetHistory returns streak days between from and to (inclusive), newest first.
+func GetHistory(ctx context.Context, db *pgxpool.Pool, userID, from, to string) ([]Day, error) {
+	rows, err := db.Query(ctx, `
+		SELECT day, activities, frozen
+		FROM public.streak_days
+		WHERE user_id = $1 AND day >= $2::date AND day <= $3::date
+		ORDER BY day DESC
+	`, userID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	days := []Day{}
+	for rows.Next() {
+		var d Day
+		var day time.Time
+		if err := rows.Scan(&day, &d.Activities, &d.Frozen); err != nil {
+			log.Printf("Scan streak day error: %v", err)
+			continue
+		}
+		d.Date = day.Format(userclock.DateLayout)
+		if d.Activities == nil {
+			d.Activities = []string{}
+		}
+		days = append(days, d)
+	}
+	return days, rows.Err()
+}
+
+// truncateDate drops the time and location of a DATE column value.
+func truncateDate(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
 }
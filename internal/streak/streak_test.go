@@ -0,0 +1,171 @@
+package streak
+
+import (
+	"testing"
+	"time"
+
+	"firelevel-backend/internal/userclock"
+)
+
+func day(s string) time.Time {
+	d, err := time.Parse(userclock.DateLayout, s)
+	if err != nil {
+		panic(err)
+	}
+	return d
+}
+
+func dayPtr(s string) *time.Time {
+	d := day(s)
+	return &d
+}
+
+func TestAdvance(t *testing.T) {
+	tests := []struct {
+		name        string
+		before      progress
+		day         string
+		frozen      bool
+		fresh       bool
+		want        progress
+		wantFrozen  []string
+		wantChanged bool
+	}{
+		{
+			name:        "first activity",
+			before:      progress{},
+			day:         "2026-10-16",
+			fresh:       true,
+			want:        progress{current: 1, longest: 1, lastActive: dayPtr("2026-10-16")},
+			wantChanged: true,
+		},
+		{
+			name:        "next day",
+			before:      progress{current: 3, longest: 5, lastActive: dayPtr("2026-10-15")},
+			day:         "2026-10-16",
+			fresh:       true,
+			want:        progress{current: 4, longest: 5, lastActive: dayPtr("2026-10-16")},
+			wantChanged: true,
+		},
+		{
+			name:   "same day again",
+			before: progress{current: 3, longest: 5, lastActive: dayPtr("2026-10-16")},
+			day:    "2026-10-16",
+			want:   progress{current: 3, longest: 5, lastActive: dayPtr("2026-10-16")},
+		},
+		{
+			name:   "earlier day is history only",
+			before: progress{current: 3, longest: 5, lastActive: dayPtr("2026-10-16")},
+			day:    "2026-10-14",
+			fresh:  true,
+			want:   progress{current: 3, longest: 5, lastActive: dayPtr("2026-10-16")},
+		},
+		{
+			name:        "one missed day uses a freeze",
+			before:      progress{current: 3, longest: 3, freezes: 1, lastActive: dayPtr("2026-10-14")},
+			day:         "2026-10-16",
+			fresh:       true,
+			want:        progress{current: 4, longest: 4, freezes: 0, lastActive: dayPtr("2026-10-16")},
+			wantFrozen:  []string{"2026-10-15"},
+			wantChanged: true,
+		},
+		{
+			name:        "two missed days use two freezes",
+			before:      progress{current: 3, longest: 3, freezes: 2, lastActive: dayPtr("2026-10-13")},
+			day:         "2026-10-16",
+			fresh:       true,
+			want:        progress{current: 4, longest: 4, freezes: 0, lastActive: dayPtr("2026-10-16")},
+			wantFrozen:  []string{"2026-10-14", "2026-10-15"},
+			wantChanged: true,
+		},
+		{
+			name:        "not enough freezes breaks the streak",
+			before:      progress{current: 9, longest: 9, freezes: 1, lastActive: dayPtr("2026-10-13")},
+			day:         "2026-10-16",
+			fresh:       true,
+			want:        progress{current: 1, longest: 9, freezes: 1, lastActive: dayPtr("2026-10-16")},
+			wantChanged: true,
+		},
+		{
+			name:        "seventh day earns a freeze",
+			before:      progress{current: 6, longest: 6, lastActive: dayPtr("2026-10-15")},
+			day:         "2026-10-16",
+			fresh:       true,
+			want:        progress{current: 7, longest: 7, freezes: 1, lastActive: dayPtr("2026-10-16")},
+			wantChanged: true,
+		},
+		{
+			name:        "freezes are capped",
+			before:      progress{current: 13, longest: 13, freezes: MaxFreezes, lastActive: dayPtr("2026-10-15")},
+			day:         "2026-10-16",
+			fresh:       true,
+			want:        progress{current: 14, longest: 14, freezes: MaxFreezes, lastActive: dayPtr("2026-10-16")},
+			wantChanged: true,
+		},
+		{
+			name:        "activity on a frozen day refunds the freeze",
+			before:      progress{current: 4, longest: 4, freezes: 0, lastActive: dayPtr("2026-10-16")},
+			day:         "2026-10-15",
+			frozen:      true,
+			want:        progress{current: 5, longest: 5, freezes: 1, lastActive: dayPtr("2026-10-16")},
+			wantChanged: true,
+		},
+		{
+			name:        "refund respects the cap",
+			before:      progress{current: 4, longest: 8, freezes: MaxFreezes, lastActive: dayPtr("2026-10-16")},
+			day:         "2026-10-15",
+			frozen:      true,
+			want:        progress{current: 5, longest: 8, freezes: MaxFreezes, lastActive: dayPtr("2026-10-16")},
+			wantChanged: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, frozenDays, changed := advance(tt.before, day(tt.day), tt.frozen, tt.fresh)
+			if changed != tt.wantChanged {
+				t.Errorf("changed = %v, want %v", changed, tt.wantChanged)
+			}
+			if got.current != tt.want.current || got.longest != tt.want.longest || got.freezes != tt.want.freezes {
+				t.Errorf("progress = %d/%d/%d freezes, want %d/%d/%d",
+					got.current, got.longest, got.freezes, tt.want.current, tt.want.longest, tt.want.freezes)
+			}
+			if (got.lastActive == nil) != (tt.want.lastActive == nil) ||
+				(got.lastActive != nil && !got.lastActive.Equal(*tt.want.lastActive)) {
+				t.Errorf("lastActive = %v, want %v", got.lastActive, tt.want.lastActive)
+			}
+			if len(frozenDays) != len(tt.wantFrozen) {
+				t.Fatalf("frozen days = %v, want %v", frozenDays, tt.wantFrozen)
+			}
+			for i, d := range frozenDays {
+				if f := d.Format(userclock.DateLayout); f != tt.wantFrozen[i] {
+					t.Errorf("frozen day %d = %s, want %s", i, f, tt.wantFrozen[i])
+				}
+			}
+		})
+	}
+}
+
+func TestDayFor(t *testing.T) {
+	tests := []struct {
+		name            string
+		utc             string // UTC clock of the activity, user in UTC
+		yesterdayActive bool
+		want            string
+	}{
+		{"daytime", "2026-10-16T10:00:00Z", false, "2026-10-16"},
+		{"late night saves yesterday", "2026-10-16T01:30:00Z", false, "2026-10-15"},
+		{"late night, yesterday already active", "2026-10-16T01:30:00Z", true, "2026-10-16"},
+		{"grace ends at 03:00", "2026-10-16T03:00:00Z", false, "2026-10-16"},
+		{"just before the end of grace", "2026-10-16T02:59:00Z", false, "2026-10-15"},
+	}
+	for _, tt := range tests {
+		now, err := time.Parse(time.RFC3339, tt.utc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := dayFor(userclock.At(now, "UTC"), tt.yesterdayActive); got != tt.want {
+			t.Errorf("%s: dayFor = %s, want %s", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// ==========================================
+// Recorded changes
+// ==========================================
+// Tools that write to the database report each row they touched as a
+// Change. The executor stores them in ai_actions so the user can review
+// what Kai did and undo it.
+// ==========================================
+
+// Change is one row written by a tool, as JSON snapshots of the whole row.
+type Change struct {
+	Table  string          `json:"table"`
+	RowID  string          `json:"row_id"`
+	Before json.RawMessage `json:"before,omitempty"` // nil: the tool created the row
+	After  json.RawMessage `json:"after,omitempty"`  // nil: the tool deleted the row
+}
+
+// Querier is satisfied by *pgxpool.Pool and pgx.Tx, so snapshots can be
+// taken inside the transaction that writes the row.
+type Querier interface {
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+// Snapshot returns row id of table as JSON, or nil if it does not exist or
+// belongs to another user. table is always a constant from the calling tool
+// and must have a user_id column.
+func Snapshot(ctx context.Context, db Querier, table, userID, id string) (json.RawMessage, error) {
+	var row json.RawMessage
+	err := db.QueryRow(ctx,
+		fmt.Sprintf("SELECT to_jsonb(t) FROM %s t WHERE id = $1 AND user_id = $2", pgx.Identifier{table}.Sanitize()),
+		id, userID,
+	).Scan(&row)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	return row, err
+}
+
+// Created records a row the tool inserted.
+func (c *Call) Created(ctx context.Context, table, id string) Change {
+	return Change{Table: table, RowID: id, After: c.snapshot(ctx, table, id)}
+}
+
+// Updated records a row the tool modified; before was taken with Snapshot
+// prior to the write.
+func (c *Call) Updated(ctx context.Context, table, id string, before json.RawMessage) Change {
+	return Change{Table: table, RowID: id, Before: before, After: c.snapshot(ctx, table, id)}
+}
+
+// Deleted records a row the tool removed; before was taken with Snapshot
+// prior to the write.
+func (c *Call) Deleted(table, id string, before json.RawMessage) Change {
+	return Change{Table: table, RowID: id, Before: before}
+}
+
+// snapshot reads the state a write left behind. The write already
+// happened, so a failed read is logged rather than failing the tool.
+func (c *Call) snapshot(ctx context.Context, table, id string) json.RawMessage {
+	row, err := Snapshot(ctx, c.DB, table, c.UserID, id)
+	if err != nil {
+		log.Printf("⚠️ Failed to snapshot %s %s: %v", table, id, err)
+		return nil
+	}
+	return row
+}
@@ -0,0 +1,210 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// ==========================================
+// AI tool registry
+// ==========================================
+// Every tool Kai can call is a Func with a typed argument struct. The JSON
+// schema sent to the model is generated from that struct and arguments are
+// validated against it before the tool runs, so the declaration and the
+// implementation cannot drift apart.
+//
+// Domain packages register their own tools (calendar.RegisterTools,
+// routines.RegisterTools, ...); backboard.NewRegistry assembles them at
+// startup.
+// ==========================================
+
+// ErrUnknownTool is returned by Registry.Execute for a name nobody registered.
+var ErrUnknownTool = errors.New("unknown tool")
+
+// Call is what a tool runs against: the user it acts for and the state the
+// app sent along with the message.
+type Call struct {
+	DB     *pgxpool.Pool
+	UserID string
+	Device *DeviceContext // nil outside the app (WhatsApp, web)
+	Memory MemoryWriter   // nil when long-term memory is unavailable
+}
+
+// MemoryWriter stores a long-term memory about the user.
+type MemoryWriter interface {
+	AddMemory(ctx context.Context, content string) error
+}
+
+// Result is what a tool returns: Output goes back to the model as JSON,
+// SideEffects go to the app. Tools that write report the rows they
+// touched in Changes, with a short Summary the app can show ("Tâche
+// supprimée : Sport"), so the write can be undone.
+type Result struct {
+	Output      interface{}
+	SideEffects []SideEffect
+	Changes     []Change
+	Summary     string
+}
+
+// Policy says whether a tool runs as soon as the model calls it.
+type Policy string
+
+const (
+	PolicyAuto    Policy = "auto"    // Run right away (default)
+	PolicyConfirm Policy = "confirm" // Pause the run until the user approves
+	PolicyDeny    Policy = "deny"    // Never run; the model gets an error
+)
+
+// Preview describes a tool call waiting for the user's approval.
+type Preview struct {
+	Summary string      `json:"summary"`           // "Supprimer la tâche « Sport »"
+	Details interface{} `json:"details,omitempty"` // Tool-specific, e.g. the tasks to create
+}
+
+// Definition is the declaration of a tool sent to the model. Parameters
+// maps each argument to its JSON schema. Policy is never sent.
+type Definition struct {
+	Name        string
+	Description string
+	Parameters  map[string]interface{}
+	Required    []string
+	Policy      Policy
+}
+
+// Tool is one function the model can call.
+type Tool interface {
+	Definition() Definition
+	Execute(ctx context.Context, call *Call, rawArgs json.RawMessage) (Result, error)
+	// Describe previews a call without running it (PolicyConfirm tools).
+	Describe(ctx context.Context, call *Call, rawArgs json.RawMessage) (Preview, error)
+}
+
+// Func is a Tool whose arguments decode into A. The schema is generated
+// from A's fields (see schema.go for the supported tags).
+type Func[A any] struct {
+	Name        string
+	Description string
+	Policy      Policy // Empty means PolicyAuto
+	Run         func(ctx context.Context, call *Call, args A) (Result, error)
+	// Preview is shown before a PolicyConfirm call runs. Without it the
+	// user sees the tool name and its arguments.
+	Preview func(ctx context.Context, call *Call, args A) (Preview, error)
+}
+
+// Definition implements Tool.
+func (f Func[A]) Definition() Definition {
+	var zero A
+	props, required := objectSchema(typeOf(zero))
+	if len(props) == 0 {
+		props = nil
+	}
+	policy := f.Policy
+	if policy == "" {
+		policy = PolicyAuto
+	}
+	return Definition{Name: f.Name, Description: f.Description, Parameters: props, Required: required, Policy: policy}
+}
+
+// Execute implements Tool: it decodes and validates rawArgs, then runs f.
+func (f Func[A]) Execute(ctx context.Context, call *Call, rawArgs json.RawMessage) (Result, error) {
+	args, err := f.decode(rawArgs)
+	if err != nil {
+		return Result{}, err
+	}
+	return f.Run(ctx, call, args)
+}
+
+// Describe implements Tool.
+func (f Func[A]) Describe(ctx context.Context, call *Call, rawArgs json.RawMessage) (Preview, error) {
+	args, err := f.decode(rawArgs)
+	if err != nil {
+		return Preview{}, err
+	}
+	if f.Preview == nil {
+		return Preview{Summary: f.Name, Details: args}, nil
+	}
+	return f.Preview(ctx, call, args)
+}
+
+func (f Func[A]) decode(rawArgs json.RawMessage) (A, error) {
+	var args A
+	if len(strings.TrimSpace(string(rawArgs))) > 0 {
+		if err := json.Unmarshal(rawArgs, &args); err != nil {
+			return args, fmt.Errorf("invalid arguments for %s: %w", f.Name, err)
+		}
+	}
+	if err := validate(args); err != nil {
+		return args, fmt.Errorf("invalid arguments for %s: %w", f.Name, err)
+	}
+	return args, nil
+}
+
+// NoArgs is the argument type of tools that take none.
+type NoArgs struct{}
+
+// Registry holds tools by name, in registration order.
+type Registry struct {
+	byName map[string]Tool
+	order  []string
+}
+
+// NewRegistry creates an empty registry.
+func NewRegistry() *Registry {
+	return &Registry{byName: make(map[string]Tool)}
+}
+
+// Register adds tools. Names must be unique: a duplicate is reported and
+// the tools after it are not added.
+func (r *Registry) Register(ts ...Tool) error {
+	for _, t := range ts {
+		name := t.Definition().Name
+		if _, dup := r.byName[name]; dup {
+			return fmt.Errorf("tools: duplicate tool %s", name)
+		}
+		r.byName[name] = t
+		r.order = append(r.order, name)
+	}
+	return nil
+}
+
+// Definitions returns the declarations of all tools, in registration order.
+func (r *Registry) Definitions() []Definition {
+	defs := make([]Definition, 0, len(r.order))
+	for _, name := range r.order {
+		defs = append(defs, r.byName[name].Definition())
+	}
+	return defs
+}
+
+// Execute runs the tool called name.
+func (r *Registry) Execute(ctx context.Context, call *Call, name string, rawArgs json.RawMessage) (Result, error) {
+	t, ok := r.byName[name]
+	if !ok {
+		return Result{}, fmt.Errorf("%w: %s", ErrUnknownTool, name)
+	}
+	return t.Execute(ctx, call, rawArgs)
+}
+
+// Policy returns the policy of the tool called name. Unknown tools are
+// PolicyAuto: Execute reports them.
+func (r *Registry) Policy(name string) Policy {
+	t, ok := r.byName[name]
+	if !ok {
+		return PolicyAuto
+	}
+	return t.Definition().Policy
+}
+
+// Describe previews a call to the tool called name.
+func (r *Registry) Describe(ctx context.Context, call *Call, name string, rawArgs json.RawMessage) (Preview, error) {
+	t, ok := r.byName[name]
+	if !ok {
+		return Preview{}, fmt.Errorf("%w: %s", ErrUnknownTool, name)
+	}
+	return t.Describe(ctx, call, rawArgs)
+}
@@ -0,0 +1,61 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+type greetArgs struct {
+	Name string `json:"name" required:"true"`
+}
+
+func greetTool(name string) Func[greetArgs] {
+	return Func[greetArgs]{
+		Name:        name,
+		Description: "Dit bonjour.",
+		Run: func(ctx context.Context, call *Call, args greetArgs) (Result, error) {
+			return Result{Output: "bonjour " + args.Name}, nil
+		},
+	}
+}
+
+func TestRegisterRejectsDuplicates(t *testing.T) {
+	reg := NewRegistry()
+	if err := reg.Register(greetTool("greet")); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+
+	err := reg.Register(greetTool("wave"), greetTool("greet"), greetTool("nod"))
+	if err == nil || !strings.Contains(err.Error(), "greet") {
+		t.Fatalf("err = %v, want a duplicate error", err)
+	}
+
+	var names []string
+	for _, d := range reg.Definitions() {
+		names = append(names, d.Name)
+	}
+	if strings.Join(names, ",") != "greet,wave" {
+		t.Errorf("registered %v, want the tools before the duplicate", names)
+	}
+}
+
+func TestExecute(t *testing.T) {
+	reg := NewRegistry()
+	if err := reg.Register(greetTool("greet")); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+
+	res, err := reg.Execute(context.Background(), &Call{}, "greet", json.RawMessage(`{"name":"Léa"}`))
+	if err != nil || res.Output != "bonjour Léa" {
+		t.Errorf("got %v, %v", res.Output, err)
+	}
+	if _, err := reg.Execute(context.Background(), &Call{}, "greet", json.RawMessage(`{}`)); err == nil {
+		t.Error("missing required argument accepted")
+	}
+	if _, err := reg.Execute(context.Background(), &Call{}, "shout", nil); !errors.Is(err, ErrUnknownTool) {
+		t.Errorf("err = %v, want ErrUnknownTool", err)
+	}
+}
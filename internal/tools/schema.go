@@ -0,0 +1,255 @@
+package tools
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ==========================================
+// Schema generation and validation
+// ==========================================
+// Argument structs describe themselves with field tags:
+//
+//	json:"name"       argument name (fields without a json name are skipped)
+//	desc:"..."        description shown to the model
+//	required:"true"   must be present and non-empty
+//	enum:"a,b,c"      allowed values
+//	format:"date"     YYYY-MM-DD (also "time": HH:MM)
+//	min:"1" max:"5"   bounds for numbers, item counts for arrays
+//
+// Supported field types: string, ints, floats, bool, slices and nested
+// structs. Pointers are optional values of their element type.
+// ==========================================
+
+func typeOf(v interface{}) reflect.Type {
+	t := reflect.TypeOf(v)
+	for t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return t
+}
+
+// argField is a struct field exposed to the model.
+type argField struct {
+	index int
+	name  string
+	tag   reflect.StructTag
+}
+
+func argFields(t reflect.Type) []argField {
+	var fields []argField
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "" || name == "-" {
+			continue
+		}
+		fields = append(fields, argField{index: i, name: name, tag: f.Tag})
+	}
+	return fields
+}
+
+// objectSchema returns the properties and required names of struct t.
+func objectSchema(t reflect.Type) (map[string]interface{}, []string) {
+	props := make(map[string]interface{})
+	var required []string
+	if t == nil || t.Kind() != reflect.Struct {
+		return props, nil
+	}
+	for _, f := range argFields(t) {
+		schema := fieldSchema(t.Field(f.index).Type)
+		if d := f.tag.Get("desc"); d != "" {
+			schema["description"] = d
+		}
+		// enum and format describe the items of an array
+		values := schema
+		if items, ok := schema["items"].(map[string]interface{}); ok {
+			values = items
+		}
+		if e := f.tag.Get("enum"); e != "" {
+			values["enum"] = strings.Split(e, ",")
+		}
+		if fm := f.tag.Get("format"); fm != "" {
+			values["format"] = fm
+		}
+		props[f.name] = schema
+		if f.tag.Get("required") == "true" {
+			required = append(required, f.name)
+		}
+	}
+	return props, required
+}
+
+func fieldSchema(t reflect.Type) map[string]interface{} {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		return map[string]interface{}{"type": "array", "items": fieldSchema(t.Elem())}
+	case reflect.Struct:
+		props, required := objectSchema(t)
+		schema := map[string]interface{}{"type": "object", "properties": props}
+		if len(required) > 0 {
+			schema["required"] = required
+		}
+		return schema
+	default:
+		return map[string]interface{}{"type": "string"}
+	}
+}
+
+// validate checks decoded arguments against their tags.
+func validate(args interface{}) error {
+	v := reflect.ValueOf(args)
+	for v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return nil
+	}
+	return validateStruct(v, "")
+}
+
+func validateStruct(v reflect.Value, prefix string) error {
+	for _, f := range argFields(v.Type()) {
+		fv := v.Field(f.index)
+		path := prefix + f.name
+
+		if fv.Kind() == reflect.Pointer {
+			if fv.IsNil() {
+				if f.tag.Get("required") == "true" {
+					return fmt.Errorf("%s is required", path)
+				}
+				continue
+			}
+			fv = fv.Elem()
+		}
+
+		if f.tag.Get("required") == "true" && isEmpty(fv) {
+			return fmt.Errorf("%s is required", path)
+		}
+		if err := validateValue(fv, f.tag, path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func validateValue(v reflect.Value, tag reflect.StructTag, path string) error {
+	switch v.Kind() {
+	case reflect.String:
+		s := v.String()
+		if s == "" {
+			return nil
+		}
+		if enum := tag.Get("enum"); enum != "" && !contains(strings.Split(enum, ","), s) {
+			return fmt.Errorf("%s must be one of %s, got %q", path, enum, s)
+		}
+		switch tag.Get("format") {
+		case "date":
+			if _, err := time.Parse("2006-01-02", s); err != nil {
+				return fmt.Errorf("%s must be a date YYYY-MM-DD, got %q", path, s)
+			}
+		case "time":
+			if _, err := time.Parse("15:04", s); err != nil {
+				if _, err := time.Parse("15:04:05", s); err != nil {
+					return fmt.Errorf("%s must be a time HH:MM, got %q", path, s)
+				}
+			}
+		}
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return checkBounds(float64(v.Int()), tag, path)
+
+	case reflect.Float32, reflect.Float64:
+		return checkBounds(v.Float(), tag, path)
+
+	case reflect.Slice, reflect.Array:
+		if err := checkBounds(float64(v.Len()), tag, path+" item count"); err != nil {
+			return err
+		}
+		for i := 0; i < v.Len(); i++ {
+			item := v.Index(i)
+			itemPath := fmt.Sprintf("%s[%d]", path, i)
+			if item.Kind() == reflect.Struct {
+				if err := validateStruct(item, itemPath+"."); err != nil {
+					return err
+				}
+				continue
+			}
+			// enum/format tags of the slice apply to its items
+			if err := validateValue(item, reflect.StructTag(itemTags(tag)), itemPath); err != nil {
+				return err
+			}
+		}
+
+	case reflect.Struct:
+		return validateStruct(v, path+".")
+	}
+	return nil
+}
+
+// itemTags keeps the tags of a slice field that apply to each item.
+func itemTags(tag reflect.StructTag) string {
+	var parts []string
+	for _, key := range []string{"enum", "format"} {
+		if val, ok := tag.Lookup(key); ok {
+			parts = append(parts, fmt.Sprintf("%s:%q", key, val))
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+func checkBounds(n float64, tag reflect.StructTag, path string) error {
+	if s := tag.Get("min"); s != "" {
+		if min, err := strconv.ParseFloat(s, 64); err == nil && n < min {
+			return fmt.Errorf("%s must be at least %s", path, s)
+		}
+	}
+	if s := tag.Get("max"); s != "" {
+		if max, err := strconv.ParseFloat(s, 64); err == nil && n > max {
+			return fmt.Errorf("%s must be at most %s", path, s)
+		}
+	}
+	return nil
+}
+
+// isEmpty reports a missing required value. false is a valid boolean.
+func isEmpty(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Bool:
+		return false
+	case reflect.Slice, reflect.Map:
+		return v.Len() == 0
+	default:
+		return v.IsZero()
+	}
+}
+
+func contains(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,102 @@
+package tools
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type stepArgs struct {
+	Title   string `json:"title" required:"true"`
+	Minutes *int   `json:"minutes" min:"5" max:"240"`
+}
+
+type planArgs struct {
+	Date     string     `json:"date" required:"true" format:"date"`
+	Start    *string    `json:"start" format:"time"`
+	Block    string     `json:"block" enum:"morning,afternoon,evening"`
+	Priority *string    `json:"priority" enum:"low,medium,high"`
+	Energy   float64    `json:"energy" min:"0" max:"1"`
+	Done     bool       `json:"done" required:"true"`
+	Days     []string   `json:"days" format:"date" max:"3"`
+	Tags     []string   `json:"tags" enum:"work,home"`
+	Steps    []stepArgs `json:"steps" min:"1"`
+	Focus    *stepArgs  `json:"focus"`
+	Note     string     `json:"-"`
+}
+
+func TestValidate(t *testing.T) {
+	const base = `"date": "2026-10-16", "done": false, "steps": [{"title": "Draft"}]`
+
+	tests := []struct {
+		name    string
+		json    string
+		wantErr string // "" when valid
+	}{
+		{"minimal", base, ""},
+		{"all fields", base + `, "start": "09:30", "block": "evening", "priority": "high", "energy": 0.5,
+			"days": ["2026-10-17"], "tags": ["work", "home"], "focus": {"title": "Deep work", "minutes": 90}`, ""},
+		{"time with seconds", base + `, "start": "09:30:00"`, ""},
+		{"empty optional enum", base + `, "block": ""`, ""},
+
+		{"missing required string", `"done": true, "steps": [{"title": "a"}]`, "date is required"},
+		{"missing required bool", `"date": "2026-10-16", "steps": [{"title": "a"}]`, ""},
+		{"bad date", `"date": "16/10/2026", "done": true, "steps": [{"title": "a"}]`, "date must be a date"},
+		{"bad time", base + `, "start": "9h30"`, "start must be a time"},
+		{"enum", base + `, "block": "night"`, "block must be one of"},
+		{"enum through a pointer", base + `, "priority": "urgent"`, "priority must be one of"},
+		{"float below min", base + `, "energy": -0.1`, "energy must be at least 0"},
+		{"float above max", base + `, "energy": 1.5`, "energy must be at most 1"},
+
+		{"too few items", `"date": "2026-10-16", "done": true, "steps": []`, "steps item count must be at least 1"},
+		{"too many items", base + `, "days": ["2026-10-17", "2026-10-18", "2026-10-19", "2026-10-20"]`, "days item count must be at most 3"},
+		{"item format", base + `, "days": ["2026-10-17", "tomorrow"]`, "days[1] must be a date"},
+		{"item enum", base + `, "tags": ["work", "gym"]`, "tags[1] must be one of"},
+
+		{"nested required", `"date": "2026-10-16", "done": true, "steps": [{"title": "a"}, {"minutes": 30}]`, "steps[1].title is required"},
+		{"nested min", `"date": "2026-10-16", "done": true, "steps": [{"title": "a", "minutes": 2}]`, "steps[0].minutes must be at least 5"},
+		{"nested pointer struct", base + `, "focus": {"title": "Deep work", "minutes": 300}`, "focus.minutes must be at most 240"},
+	}
+
+	for _, tt := range tests {
+		var args planArgs
+		if err := json.Unmarshal([]byte("{"+tt.json+"}"), &args); err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		err := validate(&args)
+		switch {
+		case tt.wantErr == "" && err != nil:
+			t.Errorf("%s: unexpected error %v", tt.name, err)
+		case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+			t.Errorf("%s: err = %v, want %q", tt.name, err, tt.wantErr)
+		}
+	}
+}
+
+func TestObjectSchema(t *testing.T) {
+	props, required := objectSchema(typeOf(planArgs{}))
+
+	if !reflect.DeepEqual(required, []string{"date", "done"}) {
+		t.Errorf("required = %v", required)
+	}
+	if _, ok := props["Note"]; ok || len(props) != 10 {
+		t.Errorf("properties = %v", props)
+	}
+
+	days := props["days"].(map[string]interface{})
+	if days["type"] != "array" || days["items"].(map[string]interface{})["format"] != "date" {
+		t.Errorf("days = %v, want an array of dates", days)
+	}
+	priority := props["priority"].(map[string]interface{})
+	if !reflect.DeepEqual(priority["enum"], []string{"low", "medium", "high"}) {
+		t.Errorf("priority = %v", priority)
+	}
+	steps := props["steps"].(map[string]interface{})["items"].(map[string]interface{})
+	if steps["type"] != "object" || !reflect.DeepEqual(steps["required"], []string{"title"}) {
+		t.Errorf("steps items = %v", steps)
+	}
+	if energy := props["energy"].(map[string]interface{}); energy["type"] != "number" {
+		t.Errorf("energy = %v", energy)
+	}
+}
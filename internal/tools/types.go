@@ -0,0 +1,41 @@
+package tools
+
+import "encoding/json"
+
+// SideEffect represents an action the frontend should take after an AI response.
+type SideEffect struct {
+	Type string          `json:"type"`
+	Data json.RawMessage `json:"data,omitempty"`
+}
+
+// NewSideEffect creates a side effect with no data.
+func NewSideEffect(typ string) SideEffect {
+	return SideEffect{Type: typ}
+}
+
+// NewSideEffectWithData creates a side effect with JSON data.
+func NewSideEffectWithData(typ string, data interface{}) SideEffect {
+	b, _ := json.Marshal(data)
+	return SideEffect{Type: typ, Data: b}
+}
+
+// DeviceContext holds device-only state sent by the frontend.
+type DeviceContext struct {
+	AppsBlocked          bool   `json:"apps_blocked"`
+	AppBlockingAvailable bool   `json:"app_blocking_available"`
+	MorningBlockEnabled  bool   `json:"morning_block_enabled"`
+	MorningBlockStart    string `json:"morning_block_start"`
+	MorningBlockEnd      string `json:"morning_block_end"`
+}
+
+// Side effects shared by several tools.
+var (
+	RefreshTasks         = NewSideEffect("refresh_tasks")
+	CalendarNeedsRefresh = NewSideEffect("calendar_needs_refresh")
+	RefreshRituals       = NewSideEffect("refresh_rituals")
+)
+
+// ShowCard asks the app to display an interactive card in the chat.
+func ShowCard(cardType string) SideEffect {
+	return NewSideEffectWithData("show_card", map[string]string{"card_type": cardType})
+}
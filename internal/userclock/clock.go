@@ -0,0 +1,143 @@
+package userclock
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// ===========================================
+// USER CLOCK - "today" in the user's timezone
+// The server runs in UTC; users live in
+// users.timezone. Every "today", "this week"
+// or day-range filter goes through here.
+// ===========================================
+
+// DefaultTimezone matches the users.timezone column default.
+const DefaultTimezone = "Europe/Paris"
+
+// DateLayout is the YYYY-MM-DD layout used by date columns and the API.
+const DateLayout = "2006-01-02"
+
+// Querier is satisfied by *pgxpool.Pool, *pgxpool.Conn and pgx.Tx.
+type Querier interface {
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+// Clock is a point in time seen from a user's timezone.
+type Clock struct {
+	now time.Time // Already converted to loc
+	loc *time.Location
+}
+
+// For loads userID's timezone and returns their clock at the current instant.
+// Falls back to DefaultTimezone when the user or timezone cannot be resolved.
+func For(ctx context.Context, db Querier, userID string) Clock {
+	var tz string
+	if err := db.QueryRow(ctx, `SELECT COALESCE(timezone, '') FROM public.users WHERE id = $1`, userID).Scan(&tz); err != nil {
+		log.Printf("Failed to fetch timezone for user %s: %v", userID, err)
+	}
+	return At(time.Now(), tz)
+}
+
+// At returns the clock for timezone tz at instant t.
+func At(t time.Time, tz string) Clock {
+	loc := LoadLocation(tz)
+	return Clock{now: t.In(loc), loc: loc}
+}
+
+// LoadLocation resolves an IANA timezone name, falling back to
+// DefaultTimezone and then UTC.
+func LoadLocation(tz string) *time.Location {
+	if tz != "" {
+		if loc, err := time.LoadLocation(tz); err == nil {
+			return loc
+		}
+	}
+	if loc, err := time.LoadLocation(DefaultTimezone); err == nil {
+		return loc
+	}
+	return time.UTC
+}
+
+// Now returns the current time in the user's timezone.
+func (c Clock) Now() time.Time {
+	return c.now
+}
+
+// Location returns the user's timezone.
+func (c Clock) Location() *time.Location {
+	return c.loc
+}
+
+// Today returns the user's local date as YYYY-MM-DD.
+func (c Clock) Today() string {
+	return c.now.Format(DateLayout)
+}
+
+// Yesterday returns the day before Today as YYYY-MM-DD.
+func (c Clock) Yesterday() string {
+	return c.now.AddDate(0, 0, -1).Format(DateLayout)
+}
+
+// AddDays returns Today shifted by n days as YYYY-MM-DD.
+func (c Clock) AddDays(n int) string {
+	return c.now.AddDate(0, 0, n).Format(DateLayout)
+}
+
+// WeekStart returns the Monday of the user's current week as YYYY-MM-DD.
+func (c Clock) WeekStart() string {
+	return MondayOf(c.now).Format(DateLayout)
+}
+
+// TodayBounds returns [start, end) of the user's local day as instants,
+// for filtering timestamptz columns such as focus_sessions.started_at.
+func (c Clock) TodayBounds() (time.Time, time.Time) {
+	start := startOfDay(c.now)
+	return start, start.AddDate(0, 0, 1)
+}
+
+// WeekBounds returns [Monday 00:00, next Monday 00:00) in the user's timezone.
+func (c Clock) WeekBounds() (time.Time, time.Time) {
+	start := startOfDay(MondayOf(c.now))
+	return start, start.AddDate(0, 0, 7)
+}
+
+// DayBounds returns [start, end) of a YYYY-MM-DD date in the user's timezone.
+func (c Clock) DayBounds(date string) (time.Time, time.Time, error) {
+	d, err := time.ParseInLocation(DateLayout, date, c.loc)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	return d, d.AddDate(0, 0, 1), nil
+}
+
+// TimeOfDay buckets the local hour into morning/afternoon/evening/night.
+func (c Clock) TimeOfDay() string {
+	hour := c.now.Hour()
+	switch {
+	case hour >= 5 && hour < 12:
+		return "morning"
+	case hour >= 12 && hour < 18:
+		return "afternoon"
+	case hour >= 18 && hour < 22:
+		return "evening"
+	default:
+		return "night"
+	}
+}
+
+// MondayOf returns the Monday of t's week (same clock time, same location).
+func MondayOf(t time.Time) time.Time {
+	weekday := int(t.Weekday())
+	if weekday == 0 {
+		weekday = 7
+	}
+	return t.AddDate(0, 0, -(weekday - 1))
+}
+
+func startOfDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
@@ -0,0 +1,154 @@
+package userclock
+
+import (
+	"testing"
+	"time"
+)
+
+func mustLoad(t *testing.T, name string) *time.Location {
+	t.Helper()
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		t.Skip("tzdata unavailable")
+	}
+	return loc
+}
+
+func TestLoadLocation(t *testing.T) {
+	mustLoad(t, DefaultTimezone)
+
+	tests := []struct {
+		tz   string
+		want string
+	}{
+		{"America/New_York", "America/New_York"},
+		{"Asia/Tokyo", "Asia/Tokyo"},
+		{"", DefaultTimezone},
+		{"Not/AZone", DefaultTimezone},
+		{"+02:00", DefaultTimezone},
+	}
+	for _, tt := range tests {
+		if got := LoadLocation(tt.tz).String(); got != tt.want {
+			t.Errorf("LoadLocation(%q) = %s, want %s", tt.tz, got, tt.want)
+		}
+	}
+}
+
+func TestClockToday(t *testing.T) {
+	mustLoad(t, DefaultTimezone)
+
+	// 23:30 UTC is already the next day in Paris and still the same day in New York.
+	instant := time.Date(2026, 10, 16, 23, 30, 0, 0, time.UTC)
+	tests := []struct {
+		tz        string
+		today     string
+		yesterday string
+		weekStart string
+	}{
+		{"Europe/Paris", "2026-10-17", "2026-10-16", "2026-10-12"},
+		{"America/New_York", "2026-10-16", "2026-10-15", "2026-10-12"},
+		{"Pacific/Auckland", "2026-10-17", "2026-10-16", "2026-10-12"},
+		{"bogus", "2026-10-17", "2026-10-16", "2026-10-12"},
+		{"UTC", "2026-10-16", "2026-10-15", "2026-10-12"},
+	}
+	for _, tt := range tests {
+		c := At(instant, tt.tz)
+		if got := c.Today(); got != tt.today {
+			t.Errorf("%s: Today = %s, want %s", tt.tz, got, tt.today)
+		}
+		if got := c.Yesterday(); got != tt.yesterday {
+			t.Errorf("%s: Yesterday = %s, want %s", tt.tz, got, tt.yesterday)
+		}
+		if got := c.WeekStart(); got != tt.weekStart {
+			t.Errorf("%s: WeekStart = %s, want %s", tt.tz, got, tt.weekStart)
+		}
+	}
+}
+
+func TestMondayOf(t *testing.T) {
+	tests := []struct {
+		date string
+		want string
+	}{
+		{"2026-10-12", "2026-10-12"}, // Monday
+		{"2026-10-16", "2026-10-12"}, // Friday
+		{"2026-10-18", "2026-10-12"}, // Sunday belongs to the week before
+		{"2026-10-19", "2026-10-19"},
+		{"2027-01-01", "2026-12-28"}, // Across a year
+	}
+	for _, tt := range tests {
+		d, _ := time.Parse(DateLayout, tt.date)
+		if got := MondayOf(d).Format(DateLayout); got != tt.want {
+			t.Errorf("MondayOf(%s) = %s, want %s", tt.date, got, tt.want)
+		}
+	}
+}
+
+func TestDayBounds(t *testing.T) {
+	paris := mustLoad(t, "Europe/Paris")
+	c := At(time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC), "Europe/Paris")
+
+	tests := []struct {
+		date  string
+		start time.Time
+		hours float64
+	}{
+		{"2026-10-16", time.Date(2026, 10, 16, 0, 0, 0, 0, paris), 24},
+		{"2026-03-29", time.Date(2026, 3, 29, 0, 0, 0, 0, paris), 23},  // Spring forward
+		{"2026-10-25", time.Date(2026, 10, 25, 0, 0, 0, 0, paris), 25}, // Fall back
+	}
+	for _, tt := range tests {
+		start, end, err := c.DayBounds(tt.date)
+		if err != nil {
+			t.Fatalf("DayBounds(%s): %v", tt.date, err)
+		}
+		if !start.Equal(tt.start) {
+			t.Errorf("DayBounds(%s) start = %s, want %s", tt.date, start, tt.start)
+		}
+		if got := end.Sub(start).Hours(); got != tt.hours {
+			t.Errorf("DayBounds(%s) spans %vh, want %vh", tt.date, got, tt.hours)
+		}
+	}
+
+	if _, _, err := c.DayBounds("16/10/2026"); err == nil {
+		t.Error("DayBounds should reject a malformed date")
+	}
+}
+
+func TestWeekBoundsAcrossDST(t *testing.T) {
+	paris := mustLoad(t, "Europe/Paris")
+
+	// The week of the October change is one hour longer.
+	c := At(time.Date(2026, 10, 22, 10, 0, 0, 0, time.UTC), "Europe/Paris")
+	start, end := c.WeekBounds()
+	if want := time.Date(2026, 10, 19, 0, 0, 0, 0, paris); !start.Equal(want) {
+		t.Errorf("start = %s, want %s", start, want)
+	}
+	if want := time.Date(2026, 10, 26, 0, 0, 0, 0, paris); !end.Equal(want) {
+		t.Errorf("end = %s, want %s", end, want)
+	}
+	if got := end.Sub(start); got != 7*24*time.Hour+time.Hour {
+		t.Errorf("week spans %s", got)
+	}
+
+	start, end = c.TodayBounds()
+	if want := time.Date(2026, 10, 22, 0, 0, 0, 0, paris); !start.Equal(want) || end.Sub(start) != 24*time.Hour {
+		t.Errorf("TodayBounds = [%s, %s)", start, end)
+	}
+}
+
+func TestTimeOfDay(t *testing.T) {
+	tests := []struct {
+		hour int
+		want string
+	}{
+		{4, "night"}, {5, "morning"}, {11, "morning"}, {12, "afternoon"},
+		{17, "afternoon"}, {18, "evening"}, {21, "evening"}, {22, "night"},
+	}
+	for _, tt := range tests {
+		c := At(time.Date(2026, 10, 16, tt.hour, 30, 0, 0, time.UTC), "UTC")
+		if got := c.TimeOfDay(); got != tt.want {
+			t.Errorf("TimeOfDay at %02d:30 = %s, want %s", tt.hour, got, tt.want)
+		}
+	}
+}
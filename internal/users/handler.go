@@ -1,6 +1,7 @@
 package users
 
 import (
+	"context"
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
@@ -85,7 +86,20 @@ type UpdateUserRequest struct {
 
 // 3. The Handler: Holds dependencies (the database client)
 type Handler struct {
-	db *pgxpool.Pool // Changed from *supabase.Client
+	db           *pgxpool.Pool // Changed from *supabase.Client
+	otpSender    OTPSender     // Delivers phone linking codes (nil: linking unavailable)
+	otpSecret    []byte        // Keys the phone linking code HMAC
+	memoryEraser MemoryEraser  // Erases the AI memories on account deletion (nil: none)
+}
+
+// MemoryEraser erases what the AI coach remembers about a user.
+type MemoryEraser interface {
+	ForgetUser(ctx context.Context, userID string) error
+}
+
+// SetMemoryEraser sets what DeleteAccount calls to erase AI memories
+func (h *Handler) SetMemoryEraser(eraser MemoryEraser) {
+	h.memoryEraser = eraser
 }
 
 // Factory function to create a new Handler
@@ -305,6 +319,8 @@ func (h *Handler) UpdateProfile(w http.ResponseWriter, r *http.Request) {
 		setParts = append(setParts, fmt.Sprintf("backboard_assistant_id = $%d", argId))
 		args = append(args, *req.BackboardAssistantID)
 		argId++
+		// Unknown config: the next chat brings it up to date
+		setParts = append(setParts, "backboard_assistant_config_hash = NULL")
 	}
 
 	if req.BackboardThreadID != nil {
@@ -632,6 +648,9 @@ func (h *Handler) DeleteAccount(w http.ResponseWriter, r *http.Request) {
 		{`DELETE FROM public.routine_completions WHERE user_id = $1`, "routine_completions"},
 		{`DELETE FROM public.routines WHERE user_id = $1`, "routines"},
 
+		// ── Streak history ──
+		{`DELETE FROM public.streak_days WHERE user_id = $1`, "streak_days"},
+
 		// ── Quests & Areas ──
 		{`DELETE FROM public.quests WHERE user_id = $1`, "quests"},
 		{`DELETE FROM public.areas WHERE user_id = $1`, "areas"},
@@ -641,6 +660,7 @@ func (h *Handler) DeleteAccount(w http.ResponseWriter, r *http.Request) {
 		{`DELETE FROM public.weekly_goals WHERE user_id = $1`, "weekly_goals"},
 
 		// ── Daily tracking ──
+		{`DELETE FROM public.morning_checkins WHERE user_id = $1`, "morning_checkins"},
 		{`DELETE FROM public.evening_checkins WHERE user_id = $1`, "evening_checkins"},
 
 		// ── Integrations ──
@@ -656,6 +676,19 @@ func (h *Handler) DeleteAccount(w http.ResponseWriter, r *http.Request) {
 	// Execute each deletion independently (no transaction)
 	// so a missing/failing table doesn't block the rest
 	var failedTables []string
+
+	// AI memories first: they are found through public.users, so once the
+	// rows below are gone they could never be erased. Nothing is deleted
+	// until they are; the user can retry.
+	if h.memoryEraser != nil {
+		if err := h.memoryEraser.ForgetUser(r.Context(), userID); err != nil {
+			log.Printf("Failed to erase AI memories, account deletion aborted for %s: %v", userID, err)
+			http.Error(w, "Failed to erase AI memories, please retry", http.StatusBadGateway)
+			return
+		}
+		log.Printf("Erased AI memories")
+	}
+
 	for _, d := range deletions {
 		_, err := h.db.Exec(r.Context(), d.query, userID)
 		if err != nil {
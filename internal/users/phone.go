@@ -0,0 +1,503 @@
+package users
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"firelevel-backend/internal/auth"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// =============================================
+// PHONE LINKING (WhatsApp)
+// =============================================
+// POST   /me/phone         - send a one-time code to the number
+// POST   /me/phone/verify  - check the code and link the number
+// DELETE /me/phone         - unlink the number
+//
+// Codes are stored as an HMAC in phone_linking_otps and delivered through an
+// OTPSender (WhatsApp when configured, LogOTPSender in development). Without
+// a sender POST /me/phone answers 503.
+// =============================================
+
+const (
+	otpLength      = 6
+	otpTTL         = 10 * time.Minute
+	otpCooldown    = time.Minute // Between two codes for the same user
+	otpRateWindow  = time.Hour
+	otpMaxPerUser  = 5 // Codes per user per otpRateWindow
+	otpMaxPerPhone = 5 // Codes per number per otpRateWindow, across users
+	otpMaxAttempts = 5 // Wrong guesses before a code is burned
+
+	otpSweepInterval = 15 * time.Minute
+)
+
+// e164Pattern matches an international number once separators are stripped.
+var e164Pattern = regexp.MustCompile(`^\+[1-9][0-9]{7,14}$`)
+
+// OTPSender delivers a phone linking code to a phone number (E.164).
+type OTPSender interface {
+	SendOTP(ctx context.Context, phone, code string) error
+}
+
+// LogOTPSender only logs codes. Only meant for local development, where no
+// messaging channel is configured, so the linking flow can still be tested.
+type LogOTPSender struct{}
+
+// SendOTP logs the code instead of sending it.
+func (LogOTPSender) SendOTP(ctx context.Context, phone, code string) error {
+	log.Printf("🔕 [LogOTPSender] %s → %s", phone, code)
+	return nil
+}
+
+// SetOTPSender sets the sender used by RequestPhoneLink
+func (h *Handler) SetOTPSender(sender OTPSender) {
+	h.otpSender = sender
+}
+
+// normalizePhone strips common separators and checks the E.164 format.
+func normalizePhone(raw string) (string, bool) {
+	phone := strings.NewReplacer(" ", "", "-", "", ".", "", "(", "", ")", "").Replace(strings.TrimSpace(raw))
+	if strings.HasPrefix(phone, "00") {
+		phone = "+" + phone[2:]
+	}
+	return phone, e164Pattern.MatchString(phone)
+}
+
+// SetOTPSecret sets the server-side key codes are hashed with. Without it a
+// leaked phone_linking_otps row could be brute-forced offline (10^6 codes).
+func (h *Handler) SetOTPSecret(secret []byte) {
+	h.otpSecret = secret
+}
+
+// hashOTP is an HMAC of the code keyed with the server secret. It binds the
+// code to the user and number so a leaked row can't be replayed for another
+// account.
+func (h *Handler) hashOTP(userID, phone, code string) string {
+	mac := hmac.New(sha256.New, h.otpSecret)
+	mac.Write([]byte(userID + ":" + phone + ":" + code))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// pendingOTP is the latest unverified code of a user.
+type pendingOTP struct {
+	id        string
+	phone     string
+	hash      string
+	attempts  int
+	expiresAt time.Time
+}
+
+type otpCheck int
+
+const (
+	otpValid   otpCheck = iota
+	otpExpired          // Expired or burned: a new code is needed
+	otpWrong            // Wrong code, attempts left
+	otpBurned           // Wrong code and no attempts left
+)
+
+// checkOTP compares code with the pending one at now (database time).
+func (h *Handler) checkOTP(userID string, p pendingOTP, code string, now time.Time) otpCheck {
+	if !now.Before(p.expiresAt) || p.attempts >= otpMaxAttempts {
+		return otpExpired
+	}
+	if subtle.ConstantTimeCompare([]byte(h.hashOTP(userID, p.phone, code)), []byte(p.hash)) == 1 {
+		return otpValid
+	}
+	if p.attempts+1 >= otpMaxAttempts {
+		return otpBurned
+	}
+	return otpWrong
+}
+
+func generateOTP() (string, error) {
+	max := big.NewInt(1)
+	for i := 0; i < otpLength; i++ {
+		max.Mul(max, big.NewInt(10))
+	}
+	n, err := rand.Int(rand.Reader, max)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%0*d", otpLength, n), nil
+}
+
+// ---------------------------------------------------------
+// POST /me/phone - Send a linking code
+// ---------------------------------------------------------
+type PhoneLinkRequest struct {
+	PhoneNumber string `json:"phone_number"`
+}
+
+type PhoneLinkResponse struct {
+	Status      string    `json:"status"`
+	PhoneNumber string    `json:"phone_number"`
+	ExpiresAt   time.Time `json:"expires_at"`
+}
+
+func (h *Handler) RequestPhoneLink(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(auth.UserContextKey).(string)
+
+	if h.otpSender == nil {
+		http.Error(w, "Phone linking is not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	var req PhoneLinkRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	phone, ok := normalizePhone(req.PhoneNumber)
+	if !ok {
+		http.Error(w, "phone_number must be in international format (e.g. +33612345678)", http.StatusBadRequest)
+		return
+	}
+
+	// Refuse numbers already verified on any account
+	var ownerID string
+	err := h.db.QueryRow(r.Context(), `
+		SELECT id FROM public.users WHERE phone_number = $1 AND phone_verified = true
+	`, phone).Scan(&ownerID)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		log.Printf("Phone link lookup failed: %v", err)
+		http.Error(w, "Failed to send code", http.StatusInternalServerError)
+		return
+	}
+	if ownerID == userID {
+		http.Error(w, "Phone number already linked", http.StatusConflict)
+		return
+	}
+	if ownerID != "" {
+		http.Error(w, "Phone number already linked to another account", http.StatusConflict)
+		return
+	}
+
+	code, err := generateOTP()
+	if err != nil {
+		log.Printf("OTP generation failed: %v", err)
+		http.Error(w, "Failed to send code", http.StatusInternalServerError)
+		return
+	}
+
+	tx, err := h.db.Begin(r.Context())
+	if err != nil {
+		http.Error(w, "Failed to send code", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback(r.Context())
+
+	// Serialise requests for the same user and the same number so concurrent
+	// calls can't all pass the rate check. The user lock is always taken
+	// first, so two requests can't deadlock.
+	for _, key := range []string{"phone_otp_user:" + userID, "phone_otp_phone:" + phone} {
+		if _, err := tx.Exec(r.Context(), `SELECT pg_advisory_xact_lock(hashtext($1))`, key); err != nil {
+			log.Printf("Phone link lock failed: %v", err)
+			http.Error(w, "Failed to send code", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	// Rate limits: cooldown and hourly caps per user and per number
+	var userCount, phoneCount int
+	var lastSent *time.Time
+	err = tx.QueryRow(r.Context(), `
+		SELECT
+			COUNT(*) FILTER (WHERE user_id = $1),
+			COUNT(*) FILTER (WHERE phone_number = $2),
+			MAX(created_at) FILTER (WHERE user_id = $1)
+		FROM public.phone_linking_otps
+		WHERE (user_id = $1 OR phone_number = $2)
+		  AND created_at > NOW() - $3::interval
+	`, userID, phone, fmt.Sprintf("%d seconds", int(otpRateWindow.Seconds()))).Scan(&userCount, &phoneCount, &lastSent)
+	if err != nil {
+		log.Printf("Phone link rate check failed: %v", err)
+		http.Error(w, "Failed to send code", http.StatusInternalServerError)
+		return
+	}
+	if lastSent != nil && time.Since(*lastSent) < otpCooldown {
+		w.Header().Set("Retry-After", fmt.Sprintf("%d", int((otpCooldown-time.Since(*lastSent)).Seconds())+1))
+		http.Error(w, "Please wait before requesting a new code", http.StatusTooManyRequests)
+		return
+	}
+	if userCount >= otpMaxPerUser || phoneCount >= otpMaxPerPhone {
+		http.Error(w, "Too many codes requested, try again later", http.StatusTooManyRequests)
+		return
+	}
+
+	// A new code replaces any pending one for this user
+	if _, err := tx.Exec(r.Context(), `
+		UPDATE public.phone_linking_otps SET expires_at = NOW()
+		WHERE user_id = $1 AND verified = false AND expires_at > NOW()
+	`, userID); err != nil {
+		log.Printf("Failed to expire previous OTPs: %v", err)
+		http.Error(w, "Failed to send code", http.StatusInternalServerError)
+		return
+	}
+
+	var otpID string
+	var expiresAt time.Time
+	err = tx.QueryRow(r.Context(), `
+		INSERT INTO public.phone_linking_otps (user_id, phone_number, otp_code, expires_at)
+		VALUES ($1, $2, $3, NOW() + $4::interval)
+		RETURNING id, expires_at
+	`, userID, phone, h.hashOTP(userID, phone, code), fmt.Sprintf("%d seconds", int(otpTTL.Seconds()))).Scan(&otpID, &expiresAt)
+	if err != nil {
+		log.Printf("Failed to store OTP: %v", err)
+		http.Error(w, "Failed to send code", http.StatusInternalServerError)
+		return
+	}
+	if err := tx.Commit(r.Context()); err != nil {
+		http.Error(w, "Failed to send code", http.StatusInternalServerError)
+		return
+	}
+
+	if err := h.otpSender.SendOTP(r.Context(), phone, code); err != nil {
+		log.Printf("❌ Failed to send OTP to user %s: %v", userID, err)
+		// The code never reached the user: don't count it against their limits
+		if _, err := h.db.Exec(r.Context(), `DELETE FROM public.phone_linking_otps WHERE id = $1`, otpID); err != nil {
+			log.Printf("Failed to delete undelivered OTP %s: %v", otpID, err)
+		}
+		http.Error(w, "Failed to deliver code", http.StatusBadGateway)
+		return
+	}
+
+	log.Printf("📱 Phone linking code sent for user %s", userID)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(PhoneLinkResponse{
+		Status:      "sent",
+		PhoneNumber: phone,
+		ExpiresAt:   expiresAt,
+	})
+}
+
+// ---------------------------------------------------------
+// POST /me/phone/verify - Check the code and link the number
+// ---------------------------------------------------------
+type PhoneVerifyRequest struct {
+	Code string `json:"code"`
+}
+
+func (h *Handler) VerifyPhoneLink(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(auth.UserContextKey).(string)
+
+	var req PhoneVerifyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	code := strings.TrimSpace(req.Code)
+	if code == "" {
+		http.Error(w, "code is required", http.StatusBadRequest)
+		return
+	}
+
+	tx, err := h.db.Begin(r.Context())
+	if err != nil {
+		http.Error(w, "Failed to verify code", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback(r.Context())
+
+	// Lock the pending code so concurrent guesses are counted one by one
+	var otp pendingOTP
+	var now time.Time
+	err = tx.QueryRow(r.Context(), `
+		SELECT id, phone_number, otp_code, attempts, expires_at, NOW()
+		FROM public.phone_linking_otps
+		WHERE user_id = $1 AND verified = false
+		ORDER BY created_at DESC
+		LIMIT 1
+		FOR UPDATE
+	`, userID).Scan(&otp.id, &otp.phone, &otp.hash, &otp.attempts, &otp.expiresAt, &now)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		log.Printf("OTP lookup failed: %v", err)
+		http.Error(w, "Failed to verify code", http.StatusInternalServerError)
+		return
+	}
+	result := otpExpired
+	if err == nil {
+		result = h.checkOTP(userID, otp, code, now)
+	}
+
+	switch result {
+	case otpExpired:
+		http.Error(w, "No pending code, request a new one", http.StatusGone)
+		return
+	case otpWrong, otpBurned:
+		// Burn the code once it has been guessed at too often
+		if _, err := tx.Exec(r.Context(), `
+			UPDATE public.phone_linking_otps
+			SET attempts = attempts + 1,
+			    expires_at = CASE WHEN $2 THEN NOW() ELSE expires_at END
+			WHERE id = $1
+		`, otp.id, result == otpBurned); err != nil {
+			log.Printf("Failed to record OTP attempt: %v", err)
+			http.Error(w, "Failed to verify code", http.StatusInternalServerError)
+			return
+		}
+		if err := tx.Commit(r.Context()); err != nil {
+			log.Printf("Failed to record OTP attempt: %v", err)
+			http.Error(w, "Failed to verify code", http.StatusInternalServerError)
+			return
+		}
+		if result == otpBurned {
+			http.Error(w, "Invalid code, too many attempts: request a new one", http.StatusBadRequest)
+			return
+		}
+		http.Error(w, "Invalid code", http.StatusBadRequest)
+		return
+	}
+	phone, otpID := otp.phone, otp.id
+
+	// The number may have been verified elsewhere since the code was sent
+	var taken bool
+	err = tx.QueryRow(r.Context(), `
+		SELECT EXISTS (
+			SELECT 1 FROM public.users
+			WHERE phone_number = $1 AND phone_verified = true AND id != $2
+		)
+	`, phone, userID).Scan(&taken)
+	if err != nil {
+		http.Error(w, "Failed to verify code", http.StatusInternalServerError)
+		return
+	}
+	if taken {
+		http.Error(w, "Phone number already linked to another account", http.StatusConflict)
+		return
+	}
+
+	// phone_number is UNIQUE: release it from accounts that never verified it
+	if _, err := tx.Exec(r.Context(), `
+		UPDATE public.users
+		SET phone_number = NULL, phone_verified = false, whatsapp_linked_at = NULL
+		WHERE phone_number = $1 AND id != $2
+	`, phone, userID); err != nil {
+		log.Printf("Failed to release phone number: %v", err)
+		http.Error(w, "Failed to link phone number", http.StatusInternalServerError)
+		return
+	}
+	if _, err := tx.Exec(r.Context(), `SELECT link_whatsapp_account($1, $2)`, userID, phone); err != nil {
+		log.Printf("link_whatsapp_account failed for user %s: %v", userID, err)
+		http.Error(w, "Failed to link phone number", http.StatusInternalServerError)
+		return
+	}
+	if _, err := tx.Exec(r.Context(), `
+		UPDATE public.phone_linking_otps SET verified = true WHERE id = $1
+	`, otpID); err != nil {
+		http.Error(w, "Failed to link phone number", http.StatusInternalServerError)
+		return
+	}
+	// Other pending codes for this number are now useless
+	if _, err := tx.Exec(r.Context(), `
+		UPDATE public.phone_linking_otps SET expires_at = NOW()
+		WHERE phone_number = $1 AND verified = false AND expires_at > NOW()
+	`, phone); err != nil {
+		http.Error(w, "Failed to link phone number", http.StatusInternalServerError)
+		return
+	}
+	if err := tx.Commit(r.Context()); err != nil {
+		http.Error(w, "Failed to link phone number", http.StatusInternalServerError)
+		return
+	}
+
+	log.Printf("✅ Phone number linked for user %s", userID)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":         "linked",
+		"phone_number":   phone,
+		"phone_verified": true,
+	})
+}
+
+// ---------------------------------------------------------
+// DELETE /me/phone - Unlink the number
+// ---------------------------------------------------------
+func (h *Handler) UnlinkPhone(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(auth.UserContextKey).(string)
+
+	_, err := h.db.Exec(r.Context(), `
+		UPDATE public.users
+		SET phone_number = NULL, phone_verified = false, whatsapp_linked_at = NULL
+		WHERE id = $1
+	`, userID)
+	if err != nil {
+		log.Printf("Failed to unlink phone for user %s: %v", userID, err)
+		http.Error(w, "Failed to unlink phone number", http.StatusInternalServerError)
+		return
+	}
+	if _, err := h.db.Exec(r.Context(), `
+		UPDATE public.phone_linking_otps SET expires_at = NOW()
+		WHERE user_id = $1 AND verified = false AND expires_at > NOW()
+	`, userID); err != nil {
+		log.Printf("Failed to expire OTPs for user %s: %v", userID, err)
+	}
+
+	log.Printf("📵 Phone number unlinked for user %s", userID)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// =============================================
+// OTP SWEEPER
+// =============================================
+
+// OTPSweeper deletes expired linking codes. Rows are kept for otpRateWindow
+// after expiry since the rate limits count them.
+type OTPSweeper struct {
+	h *Handler
+}
+
+// NewOTPSweeper creates the background OTP sweeper for h.
+func NewOTPSweeper(h *Handler) *OTPSweeper {
+	return &OTPSweeper{h: h}
+}
+
+// Run blocks until ctx is cancelled.
+func (s *OTPSweeper) Run(ctx context.Context) {
+	log.Printf("📱 OTP sweeper started (every %s)", otpSweepInterval)
+
+	ticker := time.NewTicker(otpSweepInterval)
+	defer ticker.Stop()
+
+	for {
+		s.sweep(ctx)
+
+		select {
+		case <-ctx.Done():
+			log.Println("📱 OTP sweeper stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *OTPSweeper) sweep(ctx context.Context) {
+	tag, err := s.h.db.Exec(ctx, `
+		DELETE FROM public.phone_linking_otps
+		WHERE expires_at < NOW() - $1::interval
+	`, fmt.Sprintf("%d seconds", int(otpRateWindow.Seconds())))
+	if err != nil {
+		if ctx.Err() == nil {
+			log.Printf("OTP sweep failed: %v", err)
+		}
+		return
+	}
+	if n := tag.RowsAffected(); n > 0 {
+		log.Printf("📱 Deleted %d expired OTPs", n)
+	}
+}
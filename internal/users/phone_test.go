@@ -0,0 +1,121 @@
+package users
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"firelevel-backend/internal/auth"
+)
+
+func TestHashOTP(t *testing.T) {
+	h := &Handler{otpSecret: []byte("secret")}
+	base := h.hashOTP("u1", "+33612345678", "123456")
+
+	if len(base) != 64 {
+		t.Fatalf("hash = %q, want 64 hex characters", base)
+	}
+	if base != h.hashOTP("u1", "+33612345678", "123456") {
+		t.Error("hash is not deterministic")
+	}
+
+	other := &Handler{otpSecret: []byte("another secret")}
+	tests := []struct {
+		name string
+		hash string
+	}{
+		{"other code", h.hashOTP("u1", "+33612345678", "123457")},
+		{"other user", h.hashOTP("u2", "+33612345678", "123456")},
+		{"other number", h.hashOTP("u1", "+33612345679", "123456")},
+		{"other secret", other.hashOTP("u1", "+33612345678", "123456")},
+	}
+	for _, tt := range tests {
+		if tt.hash == base {
+			t.Errorf("%s: same hash", tt.name)
+		}
+	}
+}
+
+func TestCheckOTP(t *testing.T) {
+	h := &Handler{otpSecret: []byte("secret")}
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	pending := func(attempts int, expiresIn time.Duration) pendingOTP {
+		return pendingOTP{
+			id:        "otp1",
+			phone:     "+33612345678",
+			hash:      h.hashOTP("u1", "+33612345678", "123456"),
+			attempts:  attempts,
+			expiresAt: now.Add(expiresIn),
+		}
+	}
+
+	tests := []struct {
+		name   string
+		otp    pendingOTP
+		userID string
+		code   string
+		want   otpCheck
+	}{
+		{"right code", pending(0, otpTTL), "u1", "123456", otpValid},
+		{"right code after wrong guesses", pending(otpMaxAttempts-1, time.Minute), "u1", "123456", otpValid},
+		{"wrong code", pending(0, otpTTL), "u1", "654321", otpWrong},
+		{"code of another user", pending(0, otpTTL), "u2", "123456", otpWrong},
+		{"last attempt burns the code", pending(otpMaxAttempts-1, otpTTL), "u1", "654321", otpBurned},
+		{"burned code", pending(otpMaxAttempts, otpTTL), "u1", "123456", otpExpired},
+		{"expired", pending(0, -time.Second), "u1", "123456", otpExpired},
+		{"expires right now", pending(0, 0), "u1", "123456", otpExpired},
+	}
+	for _, tt := range tests {
+		if got := h.checkOTP(tt.userID, tt.otp, tt.code, now); got != tt.want {
+			t.Errorf("%s: checkOTP = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestGenerateOTP(t *testing.T) {
+	for i := 0; i < 100; i++ {
+		code, err := generateOTP()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(code) != otpLength || strings.Trim(code, "0123456789") != "" {
+			t.Fatalf("code = %q, want %d digits", code, otpLength)
+		}
+	}
+}
+
+func TestNormalizePhone(t *testing.T) {
+	tests := []struct {
+		raw    string
+		want   string
+		wantOK bool
+	}{
+		{"+33 6 12 34 56 78", "+33612345678", true},
+		{"0033 (6) 12-34-56-78", "+33612345678", true},
+		{"+1.415.555.0100", "+14155550100", true},
+		{"06 12 34 56 78", "0612345678", false},
+		{"+0612345678", "+0612345678", false},
+		{"+33", "+33", false},
+	}
+	for _, tt := range tests {
+		got, ok := normalizePhone(tt.raw)
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("normalizePhone(%q) = %q, %v; want %q, %v", tt.raw, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+func TestRequestPhoneLinkWithoutSender(t *testing.T) {
+	h := NewHandler(nil)
+	req := httptest.NewRequest(http.MethodPost, "/me/phone", strings.NewReader(`{"phone_number": "+33612345678"}`))
+	req = req.WithContext(context.WithValue(req.Context(), auth.UserContextKey, "u1"))
+	rec := httptest.NewRecorder()
+
+	h.RequestPhoneLink(rec, req)
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("status = %d, want 503", rec.Code)
+	}
+}
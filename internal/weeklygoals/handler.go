@@ -0,0 +1,298 @@
+package weeklygoals
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"firelevel-backend/internal/auth"
+	"firelevel-backend/internal/userclock"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const (
+	defaultHistoryWeeks = 12
+	maxHistoryWeeks     = 104
+)
+
+// Handler exposes weekly goals over HTTP.
+type Handler struct {
+	db *pgxpool.Pool
+}
+
+// NewHandler creates a new weekly goals handler
+func NewHandler(db *pgxpool.Pool) *Handler {
+	return &Handler{db: db}
+}
+
+// CreateItemRequest for POST /weekly-goals/items
+type CreateItemRequest struct {
+	Content   string  `json:"content"`
+	AreaID    *string `json:"area_id"`
+	WeekStart string  `json:"week_start"` // Any date of the week; default: current week
+}
+
+// ReorderRequest for PUT /weekly-goals/order
+type ReorderRequest struct {
+	WeekStart string   `json:"week_start"` // Default: current week
+	ItemIDs   []string `json:"item_ids"`
+}
+
+// ReviewRequest for POST /weekly-goals/review
+type ReviewRequest struct {
+	WeekStart string `json:"week_start"` // Default: current week
+}
+
+// weekParam resolves a date of the week to its Monday, defaulting to the
+// user's current week.
+func (h *Handler) weekParam(w http.ResponseWriter, r *http.Request, userID, date string) (string, bool) {
+	if date == "" {
+		return userclock.For(r.Context(), h.db, userID).WeekStart(), true
+	}
+	weekStart, err := WeekOf(date)
+	if err != nil {
+		http.Error(w, "Invalid week_start (use YYYY-MM-DD)", http.StatusBadRequest)
+		return "", false
+	}
+	return weekStart, true
+}
+
+// GetWeek returns the goals of a week (current week by default)
+// GET /weekly-goals?week=YYYY-MM-DD
+func (h *Handler) GetWeek(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(auth.UserContextKey).(string)
+	weekStart, ok := h.weekParam(w, r, userID, r.URL.Query().Get("week"))
+	if !ok {
+		return
+	}
+
+	week, err := GetWeek(r.Context(), h.db, userID, weekStart)
+	if err != nil {
+		log.Printf("Failed to get weekly goals %s for user %s: %v", weekStart, userID, err)
+		http.Error(w, "Failed to get weekly goals", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(week)
+}
+
+// GetHistory returns past weeks with their completion rate, newest first
+// GET /weekly-goals/history?limit=12
+func (h *Handler) GetHistory(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(auth.UserContextKey).(string)
+
+	limit := defaultHistoryWeeks
+	if l := r.URL.Query().Get("limit"); l != "" {
+		n, err := strconv.Atoi(l)
+		if err != nil || n < 1 || n > maxHistoryWeeks {
+			http.Error(w, "Invalid limit", http.StatusBadRequest)
+			return
+		}
+		limit = n
+	}
+
+	weekStart := userclock.For(r.Context(), h.db, userID).WeekStart()
+	weeks, err := History(r.Context(), h.db, userID, weekStart, limit)
+	if err != nil {
+		log.Printf("Failed to get weekly goals history for user %s: %v", userID, err)
+		http.Error(w, "Failed to get weekly goals history", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(weeks)
+}
+
+// CreateItem adds a goal to a week
+// POST /weekly-goals/items
+func (h *Handler) CreateItem(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(auth.UserContextKey).(string)
+
+	var req CreateItemRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	req.Content = strings.TrimSpace(req.Content)
+	if req.Content == "" {
+		http.Error(w, "content is required", http.StatusBadRequest)
+		return
+	}
+	weekStart, ok := h.weekParam(w, r, userID, req.WeekStart)
+	if !ok {
+		return
+	}
+
+	item, err := AddItem(r.Context(), h.db, userID, weekStart, req.Content, req.AreaID)
+	if err != nil {
+		log.Printf("Failed to create weekly goal for user %s: %v", userID, err)
+		http.Error(w, "Failed to create weekly goal", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(item)
+}
+
+// UpdateItem changes a goal's content, area or completion
+// PATCH /weekly-goals/items/{id}
+func (h *Handler) UpdateItem(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(auth.UserContextKey).(string)
+	itemID := chi.URLParam(r, "id")
+
+	var req ItemUpdate
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.Content != nil {
+		content := strings.TrimSpace(*req.Content)
+		if content == "" {
+			http.Error(w, "content cannot be empty", http.StatusBadRequest)
+			return
+		}
+		req.Content = &content
+	}
+
+	item, err := UpdateItem(r.Context(), h.db, userID, itemID, req)
+	h.writeItem(w, item, err, userID, itemID)
+}
+
+// CompleteItem marks a goal as completed
+// POST /weekly-goals/items/{id}/complete
+func (h *Handler) CompleteItem(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(auth.UserContextKey).(string)
+	itemID := chi.URLParam(r, "id")
+
+	item, err := SetCompleted(r.Context(), h.db, userID, itemID, true)
+	h.writeItem(w, item, err, userID, itemID)
+}
+
+// UncompleteItem marks a goal as not completed
+// DELETE /weekly-goals/items/{id}/complete
+func (h *Handler) UncompleteItem(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(auth.UserContextKey).(string)
+	itemID := chi.URLParam(r, "id")
+
+	item, err := SetCompleted(r.Context(), h.db, userID, itemID, false)
+	h.writeItem(w, item, err, userID, itemID)
+}
+
+func (h *Handler) writeItem(w http.ResponseWriter, item *Item, err error, userID, itemID string) {
+	if errors.Is(err, ErrNotFound) {
+		http.Error(w, "Weekly goal not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to update weekly goal %s for user %s: %v", itemID, userID, err)
+		http.Error(w, "Failed to update weekly goal", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(item)
+}
+
+// DeleteItem deletes a goal
+// DELETE /weekly-goals/items/{id}
+func (h *Handler) DeleteItem(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(auth.UserContextKey).(string)
+	itemID := chi.URLParam(r, "id")
+
+	err := DeleteItem(r.Context(), h.db, userID, itemID)
+	if errors.Is(err, ErrNotFound) {
+		http.Error(w, "Weekly goal not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to delete weekly goal %s for user %s: %v", itemID, userID, err)
+		http.Error(w, "Failed to delete weekly goal", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Reorder sets the order of a week's goals
+// PUT /weekly-goals/order
+func (h *Handler) Reorder(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(auth.UserContextKey).(string)
+
+	var req ReorderRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	weekStart, ok := h.weekParam(w, r, userID, req.WeekStart)
+	if !ok {
+		return
+	}
+
+	err := Reorder(r.Context(), h.db, userID, weekStart, req.ItemIDs)
+	switch {
+	case errors.Is(err, ErrNotFound):
+		http.Error(w, "No weekly goals for this week", http.StatusNotFound)
+		return
+	case errors.Is(err, ErrInvalidOrder):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	case err != nil:
+		log.Printf("Failed to reorder weekly goals %s for user %s: %v", weekStart, userID, err)
+		http.Error(w, "Failed to reorder weekly goals", http.StatusInternalServerError)
+		return
+	}
+
+	week, err := GetWeek(r.Context(), h.db, userID, weekStart)
+	if err != nil {
+		log.Printf("Failed to get weekly goals %s for user %s: %v", weekStart, userID, err)
+		http.Error(w, "Failed to get weekly goals", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(week)
+}
+
+// Review closes a week: records its completion rate and rolls unfinished
+// goals into the next week
+// POST /weekly-goals/review
+func (h *Handler) Review(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(auth.UserContextKey).(string)
+
+	var req ReviewRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+	}
+	weekStart, ok := h.weekParam(w, r, userID, req.WeekStart)
+	if !ok {
+		return
+	}
+	if weekStart > userclock.For(r.Context(), h.db, userID).WeekStart() {
+		http.Error(w, "Cannot review a future week", http.StatusBadRequest)
+		return
+	}
+
+	review, err := ReviewWeek(r.Context(), h.db, userID, weekStart)
+	if errors.Is(err, ErrNotFound) {
+		http.Error(w, "No weekly goals for this week", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to review week %s for user %s: %v", weekStart, userID, err)
+		http.Error(w, "Failed to review week", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(review)
+}
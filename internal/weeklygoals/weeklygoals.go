@@ -0,0 +1,524 @@
+package weeklygoals
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"firelevel-backend/internal/tools"
+	"firelevel-backend/internal/userclock"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// ===========================================
+// WEEKLY GOALS
+// One weekly_goals row per user and week (Monday,
+// users.timezone), holding ordered weekly_goal_items.
+// The week row is created on the first write.
+//
+// The end-of-week review records the completion rate
+// on the week and rolls unfinished items into the next
+// one; carried_over_from makes it safe to run twice.
+// ===========================================
+
+var (
+	// ErrNotFound is returned for an unknown item, or a week without goals.
+	ErrNotFound = errors.New("weekly goal not found")
+	// ErrInvalidOrder is returned when a reorder does not list every item
+	// of the week exactly once.
+	ErrInvalidOrder = errors.New("item_ids must list every item of the week once")
+)
+
+// Item is one goal of a week.
+type Item struct {
+	ID              string     `json:"id"`
+	Content         string     `json:"content"`
+	AreaID          *string    `json:"area_id"`
+	Position        int        `json:"position"`
+	IsCompleted     bool       `json:"is_completed"`
+	CompletedAt     *time.Time `json:"completed_at"`
+	CarriedOverFrom *string    `json:"carried_over_from"` // Item of the previous week it was rolled over from
+	CreatedAt       time.Time  `json:"created_at"`
+}
+
+// Week is a week of goals. Once reviewed, the counts are the ones recorded
+// by the review; before, they are computed from the items.
+type Week struct {
+	ID             *string    `json:"id"` // nil until the week has goals
+	WeekStart      string     `json:"week_start"`
+	Items          []Item     `json:"items"`
+	Total          int        `json:"total"`
+	Completed      int        `json:"completed"`
+	CompletionRate int        `json:"completion_rate"` // 0-100
+	ReviewedAt     *time.Time `json:"reviewed_at"`
+}
+
+// Review is the result of an end-of-week review.
+type Review struct {
+	WeekStart      string `json:"week_start"`
+	Total          int    `json:"total"`
+	Completed      int    `json:"completed"`
+	CompletionRate int    `json:"completion_rate"` // 0-100
+	RolledOver     int    `json:"rolled_over"`     // Items copied into NextWeek by this run
+	NextWeek       *Week  `json:"next_week"`
+}
+
+// ItemUpdate is a partial item update; nil fields are kept and an empty
+// AreaID clears the area.
+type ItemUpdate struct {
+	Content     *string `json:"content"`
+	AreaID      *string `json:"area_id"`
+	IsCompleted *bool   `json:"is_completed"`
+}
+
+// WeekOf returns the Monday of the week containing date (YYYY-MM-DD).
+func WeekOf(date string) (string, error) {
+	t, err := time.Parse(userclock.DateLayout, date)
+	if err != nil {
+		return "", err
+	}
+	return userclock.MondayOf(t).Format(userclock.DateLayout), nil
+}
+
+func addWeeks(weekStart string, n int) string {
+	t, _ := time.Parse(userclock.DateLayout, weekStart)
+	return t.AddDate(0, 0, 7*n).Format(userclock.DateLayout)
+}
+
+func completionRate(completed, total int) int {
+	if total == 0 {
+		return 0
+	}
+	return completed * 100 / total
+}
+
+// ensureWeek returns the id of the week, creating it if needed.
+func ensureWeek(ctx context.Context, db userclock.Querier, userID, weekStart string) (string, error) {
+	var id string
+	err := db.QueryRow(ctx, `
+		INSERT INTO public.weekly_goals (user_id, week_start_date)
+		VALUES ($1, $2::date)
+		ON CONFLICT (user_id, week_start_date) DO UPDATE SET updated_at = NOW()
+		RETURNING id
+	`, userID, weekStart).Scan(&id)
+	return id, err
+}
+
+// ===========================================
+// Weeks
+// ===========================================
+
+const itemColumns = `i.id, i.content, i.area_id, COALESCE(i.position, 0), COALESCE(i.is_completed, false),
+	i.completed_at, i.carried_over_from, i.created_at`
+
+func scanItem(row pgx.Row) (*Item, error) {
+	var it Item
+	if err := row.Scan(&it.ID, &it.Content, &it.AreaID, &it.Position, &it.IsCompleted,
+		&it.CompletedAt, &it.CarriedOverFrom, &it.CreatedAt); err != nil {
+		return nil, err
+	}
+	return &it, nil
+}
+
+// GetWeek returns the goals of the week starting on weekStart. A week
+// without goals is returned empty, not as ErrNotFound.
+func GetWeek(ctx context.Context, db *pgxpool.Pool, userID, weekStart string) (*Week, error) {
+	weeks, err := listWeeks(ctx, db, userID, `AND week_start_date = $2::date`, weekStart, 1)
+	if err != nil {
+		return nil, err
+	}
+	if len(weeks) == 0 {
+		return &Week{WeekStart: weekStart, Items: []Item{}}, nil
+	}
+	return &weeks[0], nil
+}
+
+// History returns the weeks that started before weekStart, newest first.
+func History(ctx context.Context, db *pgxpool.Pool, userID, weekStart string, limit int) ([]Week, error) {
+	return listWeeks(ctx, db, userID, `AND week_start_date < $2::date`, weekStart, limit)
+}
+
+// listWeeks loads the weeks matching filter ($2 is weekStart) with their items.
+func listWeeks(ctx context.Context, db *pgxpool.Pool, userID, filter, weekStart string, limit int) ([]Week, error) {
+	rows, err := db.Query(ctx, `
+		SELECT id, week_start_date, reviewed_at, items_total, items_completed, completion_rate
+		FROM public.weekly_goals
+		WHERE user_id = $1 `+filter+`
+		ORDER BY week_start_date DESC
+		LIMIT $3
+	`, userID, weekStart, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	weeks := []Week{}
+	index := map[string]int{}
+	var ids []string
+	for rows.Next() {
+		var w Week
+		var id string
+		var start time.Time
+		var total, completed, rate *int
+		if err := rows.Scan(&id, &start, &w.ReviewedAt, &total, &completed, &rate); err != nil {
+			return nil, err
+		}
+		w.ID = &id
+		w.WeekStart = start.Format(userclock.DateLayout)
+		w.Items = []Item{}
+		if w.ReviewedAt != nil && total != nil && completed != nil && rate != nil {
+			w.Total, w.Completed, w.CompletionRate = *total, *completed, *rate
+		}
+		index[id] = len(weeks)
+		ids = append(ids, id)
+		weeks = append(weeks, w)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(ids) == 0 {
+		return weeks, nil
+	}
+
+	itemRows, err := db.Query(ctx, `
+		SELECT i.weekly_goal_id, `+itemColumns+`
+		FROM public.weekly_goal_items i
+		WHERE i.weekly_goal_id = ANY($1::uuid[])
+		ORDER BY i.position, i.created_at
+	`, ids)
+	if err != nil {
+		return nil, err
+	}
+	defer itemRows.Close()
+
+	for itemRows.Next() {
+		var weekID string
+		var it Item
+		if err := itemRows.Scan(&weekID, &it.ID, &it.Content, &it.AreaID, &it.Position, &it.IsCompleted,
+			&it.CompletedAt, &it.CarriedOverFrom, &it.CreatedAt); err != nil {
+			log.Printf("Scan weekly goal item error: %v", err)
+			continue
+		}
+		w := &weeks[index[weekID]]
+		w.Items = append(w.Items, it)
+	}
+	if err := itemRows.Err(); err != nil {
+		return nil, err
+	}
+
+	for i := range weeks {
+		w := &weeks[i]
+		if w.ReviewedAt != nil {
+			continue
+		}
+		w.Total, w.Completed = len(w.Items), 0
+		for _, it := range w.Items {
+			if it.IsCompleted {
+				w.Completed++
+			}
+		}
+		w.CompletionRate = completionRate(w.Completed, w.Total)
+	}
+	return weeks, nil
+}
+
+// SetGoals replaces the goals of the week with contents, in order. It
+// returns how many goals were saved and the rows it wrote, snapshotted
+// inside the transaction (for the AI action log).
+func SetGoals(ctx context.Context, db *pgxpool.Pool, userID, weekStart string, contents []string) (int, []tools.Change, error) {
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		return 0, nil, fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var weekBefore json.RawMessage
+	err = tx.QueryRow(ctx, `
+		SELECT to_jsonb(w) FROM public.weekly_goals w WHERE user_id = $1 AND week_start_date = $2::date
+	`, userID, weekStart).Scan(&weekBefore)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return 0, nil, fmt.Errorf("snapshot week: %w", err)
+	}
+
+	weekID, err := ensureWeek(ctx, tx, userID, weekStart)
+	if err != nil {
+		return 0, nil, fmt.Errorf("ensure week: %w", err)
+	}
+	var weekAfter json.RawMessage
+	if err := tx.QueryRow(ctx, `SELECT to_jsonb(w) FROM public.weekly_goals w WHERE id = $1`, weekID).Scan(&weekAfter); err != nil {
+		return 0, nil, fmt.Errorf("snapshot week: %w", err)
+	}
+	changes := []tools.Change{{Table: "weekly_goals", RowID: weekID, Before: weekBefore, After: weekAfter}}
+
+	rows, err := tx.Query(ctx, `
+		DELETE FROM public.weekly_goal_items WHERE weekly_goal_id = $1
+		RETURNING id, to_jsonb(weekly_goal_items.*)
+	`, weekID)
+	if err != nil {
+		return 0, nil, fmt.Errorf("delete goals: %w", err)
+	}
+	for rows.Next() {
+		c := tools.Change{Table: "weekly_goal_items"}
+		if err := rows.Scan(&c.RowID, &c.Before); err != nil {
+			rows.Close()
+			return 0, nil, fmt.Errorf("scan deleted goal: %w", err)
+		}
+		changes = append(changes, c)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, nil, fmt.Errorf("delete goals: %w", err)
+	}
+
+	count := 0
+	for _, content := range contents {
+		content = strings.TrimSpace(content)
+		if content == "" {
+			continue
+		}
+		c := tools.Change{Table: "weekly_goal_items"}
+		if err := tx.QueryRow(ctx, `
+			INSERT INTO public.weekly_goal_items (weekly_goal_id, content, position, is_completed)
+			VALUES ($1, $2, $3, false)
+			RETURNING id, to_jsonb(weekly_goal_items.*)
+		`, weekID, content, count).Scan(&c.RowID, &c.After); err != nil {
+			return 0, nil, fmt.Errorf("insert goal: %w", err)
+		}
+		changes = append(changes, c)
+		count++
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return 0, nil, fmt.Errorf("commit goals: %w", err)
+	}
+	return count, changes, nil
+}
+
+// Reorder sets the positions of the week's items to the order of itemIDs,
+// which must list each of them exactly once.
+func Reorder(ctx context.Context, db *pgxpool.Pool, userID, weekStart string, itemIDs []string) error {
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var weekID string
+	var current []string
+	err = tx.QueryRow(ctx, `
+		SELECT wg.id, COALESCE(array_agg(i.id::text) FILTER (WHERE i.id IS NOT NULL), '{}')
+		FROM public.weekly_goals wg
+		LEFT JOIN public.weekly_goal_items i ON i.weekly_goal_id = wg.id
+		WHERE wg.user_id = $1 AND wg.week_start_date = $2::date
+		GROUP BY wg.id
+	`, userID, weekStart).Scan(&weekID, &current)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("load week items: %w", err)
+	}
+
+	if err := checkOrder(current, itemIDs); err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(ctx, `
+		UPDATE public.weekly_goal_items i SET position = o.ord - 1
+		FROM unnest($2::uuid[]) WITH ORDINALITY AS o(id, ord)
+		WHERE i.id = o.id AND i.weekly_goal_id = $1
+	`, weekID, itemIDs); err != nil {
+		return fmt.Errorf("update positions: %w", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("commit order: %w", err)
+	}
+	return nil
+}
+
+// checkOrder makes sure itemIDs lists each of the current items once.
+func checkOrder(current, itemIDs []string) error {
+	if len(itemIDs) != len(current) {
+		return ErrInvalidOrder
+	}
+	remaining := make(map[string]bool, len(current))
+	for _, id := range current {
+		remaining[id] = true
+	}
+	for _, id := range itemIDs {
+		if !remaining[id] {
+			return ErrInvalidOrder
+		}
+		delete(remaining, id)
+	}
+	return nil
+}
+
+// ===========================================
+// Items
+// ===========================================
+
+// AddItem appends a goal to the week.
+func AddItem(ctx context.Context, db *pgxpool.Pool, userID, weekStart, content string, areaID *string) (*Item, error) {
+	weekID, err := ensureWeek(ctx, db, userID, weekStart)
+	if err != nil {
+		return nil, err
+	}
+	return scanItem(db.QueryRow(ctx, `
+		INSERT INTO public.weekly_goal_items AS i (weekly_goal_id, content, area_id, position, is_completed)
+		VALUES ($1, $2, NULLIF($3, '')::uuid,
+			(SELECT COALESCE(MAX(position), -1) + 1 FROM public.weekly_goal_items WHERE weekly_goal_id = $1),
+			false)
+		RETURNING `+itemColumns,
+		weekID, content, areaID))
+}
+
+// UpdateItem changes an item of the user.
+func UpdateItem(ctx context.Context, db *pgxpool.Pool, userID, itemID string, in ItemUpdate) (*Item, error) {
+	it, err := scanItem(db.QueryRow(ctx, `
+		UPDATE public.weekly_goal_items i SET
+			content      = COALESCE($3, i.content),
+			area_id      = CASE WHEN $4::text IS NULL THEN i.area_id ELSE NULLIF($4, '')::uuid END,
+			is_completed = COALESCE($5, i.is_completed),
+			completed_at = CASE
+				WHEN $5::boolean IS NULL THEN i.completed_at
+				WHEN $5 AND i.is_completed THEN i.completed_at
+				WHEN $5 THEN NOW()
+				ELSE NULL
+			END
+		FROM public.weekly_goals wg
+		WHERE i.id = $1 AND wg.id = i.weekly_goal_id AND wg.user_id = $2
+		RETURNING `+itemColumns,
+		itemID, userID, in.Content, in.AreaID, in.IsCompleted))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	return it, err
+}
+
+// SetCompleted marks an item as completed or not.
+func SetCompleted(ctx context.Context, db *pgxpool.Pool, userID, itemID string, completed bool) (*Item, error) {
+	return UpdateItem(ctx, db, userID, itemID, ItemUpdate{IsCompleted: &completed})
+}
+
+// DeleteItem deletes an item of the user.
+func DeleteItem(ctx context.Context, db *pgxpool.Pool, userID, itemID string) error {
+	tag, err := db.Exec(ctx, `
+		DELETE FROM public.weekly_goal_items i
+		USING public.weekly_goals wg
+		WHERE i.id = $1 AND wg.id = i.weekly_goal_id AND wg.user_id = $2
+	`, itemID, userID)
+	if err != nil {
+		return fmt.Errorf("delete goal item %s: %w", itemID, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// CompleteByContent completes the first open goal of the week whose text
+// matches content, preferring an exact match.
+func CompleteByContent(ctx context.Context, db *pgxpool.Pool, userID, weekStart, content string) error {
+	result, err := db.Exec(ctx, `
+		UPDATE public.weekly_goal_items SET is_completed = true, completed_at = NOW()
+		WHERE id = (
+			SELECT wgi.id FROM public.weekly_goal_items wgi
+			JOIN public.weekly_goals wg ON wg.id = wgi.weekly_goal_id
+			WHERE wg.user_id = $1 AND wg.week_start_date = $2::date
+			AND LOWER(wgi.content) LIKE '%' || LOWER($3) || '%'
+			AND wgi.is_completed = false
+			ORDER BY
+				CASE WHEN LOWER(wgi.content) = LOWER($3) THEN 0 ELSE 1 END
+			LIMIT 1
+		)
+	`, userID, weekStart, content)
+	if err != nil {
+		return fmt.Errorf("complete goal matching '%s': %w", content, err)
+	}
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("no weekly goal matching '%s'", content)
+	}
+	return nil
+}
+
+// ===========================================
+// Review
+// ===========================================
+
+// ReviewWeek records the completion rate of the week and rolls its
+// unfinished items into the following week, after the goals already there.
+// Running it again refreshes the rate and only rolls over new items.
+func ReviewWeek(ctx context.Context, db *pgxpool.Pool, userID, weekStart string) (*Review, error) {
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var weekID string
+	err = tx.QueryRow(ctx, `
+		SELECT id FROM public.weekly_goals
+		WHERE user_id = $1 AND week_start_date = $2::date
+		FOR UPDATE
+	`, userID, weekStart).Scan(&weekID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("lock week: %w", err)
+	}
+
+	review := &Review{WeekStart: weekStart}
+	if err := tx.QueryRow(ctx, `
+		SELECT COUNT(*), COUNT(*) FILTER (WHERE is_completed)
+		FROM public.weekly_goal_items WHERE weekly_goal_id = $1
+	`, weekID).Scan(&review.Total, &review.Completed); err != nil {
+		return nil, fmt.Errorf("count items: %w", err)
+	}
+	review.CompletionRate = completionRate(review.Completed, review.Total)
+
+	if _, err := tx.Exec(ctx, `
+		UPDATE public.weekly_goals SET
+			reviewed_at = NOW(), items_total = $2, items_completed = $3, completion_rate = $4, updated_at = NOW()
+		WHERE id = $1
+	`, weekID, review.Total, review.Completed, review.CompletionRate); err != nil {
+		return nil, fmt.Errorf("save review: %w", err)
+	}
+
+	nextWeek := addWeeks(weekStart, 1)
+	if review.Completed < review.Total {
+		nextID, err := ensureWeek(ctx, tx, userID, nextWeek)
+		if err != nil {
+			return nil, fmt.Errorf("ensure next week: %w", err)
+		}
+		tag, err := tx.Exec(ctx, `
+			INSERT INTO public.weekly_goal_items (weekly_goal_id, area_id, content, position, is_completed, carried_over_from)
+			SELECT $2, i.area_id, i.content,
+				base.last + ROW_NUMBER() OVER (ORDER BY i.position, i.created_at), false, i.id
+			FROM public.weekly_goal_items i,
+				(SELECT COALESCE(MAX(position), -1) AS last FROM public.weekly_goal_items WHERE weekly_goal_id = $2) base
+			WHERE i.weekly_goal_id = $1 AND NOT COALESCE(i.is_completed, false)
+			ON CONFLICT (carried_over_from) WHERE carried_over_from IS NOT NULL DO NOTHING
+		`, weekID, nextID)
+		if err != nil {
+			return nil, fmt.Errorf("roll over items: %w", err)
+		}
+		review.RolledOver = int(tag.RowsAffected())
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("commit review: %w", err)
+	}
+
+	review.NextWeek, err = GetWeek(ctx, db, userID, nextWeek)
+	if err != nil {
+		log.Printf("Failed to load week %s after review for user %s: %v", nextWeek, userID, err)
+	}
+	return review, nil
+}
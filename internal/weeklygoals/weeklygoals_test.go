@@ -0,0 +1,93 @@
+package weeklygoals
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestWeekOf(t *testing.T) {
+	tests := []struct {
+		date    string
+		want    string
+		wantErr bool
+	}{
+		{"2026-10-12", "2026-10-12", false}, // Monday
+		{"2026-10-16", "2026-10-12", false},
+		{"2026-10-18", "2026-10-12", false}, // Sunday closes the week
+		{"2026-10-19", "2026-10-19", false},
+		{"2026-01-01", "2025-12-29", false}, // Across a year
+		{"16/10/2026", "", true},
+		{"", "", true},
+	}
+	for _, tt := range tests {
+		got, err := WeekOf(tt.date)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("WeekOf(%q) = %q, %v; want %q, error %v", tt.date, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestAddWeeks(t *testing.T) {
+	tests := []struct {
+		weekStart string
+		n         int
+		want      string
+	}{
+		{"2026-10-12", 1, "2026-10-19"},
+		{"2026-10-12", -1, "2026-10-05"},
+		{"2026-10-12", 0, "2026-10-12"},
+		{"2026-10-19", 1, "2026-10-26"}, // Across the fall back
+		{"2026-12-28", 1, "2027-01-04"},
+		{"2026-02-23", 1, "2026-03-02"},
+	}
+	for _, tt := range tests {
+		if got := addWeeks(tt.weekStart, tt.n); got != tt.want {
+			t.Errorf("addWeeks(%s, %d) = %s, want %s", tt.weekStart, tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestCompletionRate(t *testing.T) {
+	tests := []struct {
+		completed, total, want int
+	}{
+		{0, 0, 0},
+		{0, 4, 0},
+		{1, 3, 33},
+		{2, 3, 66},
+		{3, 4, 75},
+		{5, 5, 100},
+	}
+	for _, tt := range tests {
+		if got := completionRate(tt.completed, tt.total); got != tt.want {
+			t.Errorf("completionRate(%d, %d) = %d, want %d", tt.completed, tt.total, got, tt.want)
+		}
+	}
+}
+
+func TestCheckOrder(t *testing.T) {
+	current := []string{"a", "b", "c"}
+	tests := []struct {
+		name    string
+		current []string
+		order   []string
+		valid   bool
+	}{
+		{"same order", current, []string{"a", "b", "c"}, true},
+		{"new order", current, []string{"c", "a", "b"}, true},
+		{"empty week", []string{}, []string{}, true},
+		{"missing item", current, []string{"a", "b"}, false},
+		{"extra item", current, []string{"a", "b", "c", "d"}, false},
+		{"unknown item", current, []string{"a", "b", "x"}, false},
+		{"duplicate", current, []string{"a", "a", "b"}, false},
+	}
+	for _, tt := range tests {
+		err := checkOrder(tt.current, tt.order)
+		if tt.valid && err != nil {
+			t.Errorf("%s: unexpected error %v", tt.name, err)
+		}
+		if !tt.valid && !errors.Is(err, ErrInvalidOrder) {
+			t.Errorf("%s: err = %v, want ErrInvalidOrder", tt.name, err)
+		}
+	}
+}
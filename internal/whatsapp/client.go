@@ -0,0 +1,144 @@
+package whatsapp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// =============================================
+// WHATSAPP CLOUD API CLIENT (outbound)
+// =============================================
+// POST {BaseURL}/{phone_number_id}/messages
+// BaseURL can point at a local stub server.
+// =============================================
+
+const (
+	defaultBaseURL = "https://graph.facebook.com/v18.0"
+
+	// maxTextLength is WhatsApp's limit for a text message body.
+	maxTextLength = 4096
+)
+
+// Config holds the WhatsApp Business credentials.
+type Config struct {
+	PhoneNumberID string // Sender phone number ID
+	AccessToken   string // System user access token
+	AppSecret     string // Meta app secret, signs inbound webhooks
+	VerifyToken   string // Echoed by the GET subscription handshake
+	BaseURL       string // Optional override (local stub server)
+}
+
+// Client sends messages through the WhatsApp Cloud API.
+type Client struct {
+	baseURL       string
+	phoneNumberID string
+	accessToken   string
+	httpClient    *http.Client
+}
+
+// NewClient creates a Cloud API client.
+func NewClient(cfg Config) (*Client, error) {
+	if cfg.PhoneNumberID == "" || cfg.AccessToken == "" {
+		return nil, fmt.Errorf("WhatsApp phone number ID and access token are required")
+	}
+	baseURL := cfg.BaseURL
+	if baseURL == "" {
+		baseURL = defaultBaseURL
+	}
+	return &Client{
+		baseURL:       baseURL,
+		phoneNumberID: cfg.PhoneNumberID,
+		accessToken:   cfg.AccessToken,
+		httpClient:    &http.Client{Timeout: 15 * time.Second},
+	}, nil
+}
+
+// SendText sends a text message, split in several messages when longer than
+// WhatsApp allows.
+func (c *Client) SendText(ctx context.Context, to, body string) error {
+	for _, part := range splitText(body, maxTextLength) {
+		err := c.post(ctx, outboundMessage{
+			MessagingProduct: "whatsapp",
+			RecipientType:    "individual",
+			To:               to,
+			Type:             "text",
+			Text:             &outboundText{Body: part},
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SendOTP sends a phone linking code. It implements users.OTPSender.
+func (c *Client) SendOTP(ctx context.Context, phone, code string) error {
+	text := fmt.Sprintf("Ton code Focus : %s\nNe le partage avec personne.", code)
+	return c.SendText(ctx, strings.TrimPrefix(phone, "+"), text)
+}
+
+// MarkRead marks an inbound message as read (blue ticks).
+func (c *Client) MarkRead(ctx context.Context, messageID string) error {
+	return c.post(ctx, outboundMessage{
+		MessagingProduct: "whatsapp",
+		Status:           "read",
+		MessageID:        messageID,
+	})
+}
+
+func (c *Client) post(ctx context.Context, msg outboundMessage) error {
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("marshal WhatsApp message: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/"+c.phoneNumberID+"/messages", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+c.accessToken)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("WhatsApp API request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		var apiErr apiErrorResponse
+		if json.Unmarshal(respBody, &apiErr) == nil && apiErr.Error.Message != "" {
+			return fmt.Errorf("WhatsApp API error %d (code %d): %s", resp.StatusCode, apiErr.Error.Code, apiErr.Error.Message)
+		}
+		return fmt.Errorf("WhatsApp API error %d: %s", resp.StatusCode, string(respBody))
+	}
+	return nil
+}
+
+// splitText cuts s into chunks of at most max runes, preferring line breaks.
+func splitText(s string, max int) []string {
+	runes := []rune(s)
+	var parts []string
+	for len(runes) > max {
+		cut := max
+		for i := max; i > max/2; i-- {
+			if runes[i-1] == '\n' {
+				cut = i
+				break
+			}
+		}
+		parts = append(parts, string(runes[:cut]))
+		runes = runes[cut:]
+	}
+	if len(runes) > 0 {
+		parts = append(parts, string(runes))
+	}
+	return parts
+}
@@ -0,0 +1,144 @@
+package whatsapp
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// stubGraph records the messages posted to a fake Graph API.
+type stubGraph struct {
+	mu       sync.Mutex
+	paths    []string
+	auth     []string
+	messages []outboundMessage
+	status   int
+	body     string
+}
+
+func newStubGraph(t *testing.T) (*stubGraph, *Client) {
+	t.Helper()
+	stub := &stubGraph{status: http.StatusOK, body: `{"messages":[{"id":"wamid.out"}]}`}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var msg outboundMessage
+		if err := json.NewDecoder(r.Body).Decode(&msg); err != nil {
+			t.Errorf("decode request: %v", err)
+		}
+		stub.mu.Lock()
+		stub.paths = append(stub.paths, r.Method+" "+r.URL.Path)
+		stub.auth = append(stub.auth, r.Header.Get("Authorization"))
+		stub.messages = append(stub.messages, msg)
+		status, body := stub.status, stub.body
+		stub.mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		w.Write([]byte(body))
+	}))
+	t.Cleanup(srv.Close)
+
+	client, err := NewClient(Config{PhoneNumberID: "12345", AccessToken: "token", BaseURL: srv.URL})
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	return stub, client
+}
+
+func TestNewClientRequiresCredentials(t *testing.T) {
+	if _, err := NewClient(Config{PhoneNumberID: "12345"}); err == nil {
+		t.Fatal("expected an error without access token")
+	}
+	if _, err := NewClient(Config{AccessToken: "token"}); err == nil {
+		t.Fatal("expected an error without phone number ID")
+	}
+}
+
+func TestSendText(t *testing.T) {
+	stub, client := newStubGraph(t)
+
+	if err := client.SendText(context.Background(), "33612345678", "Salut"); err != nil {
+		t.Fatalf("SendText: %v", err)
+	}
+	if len(stub.messages) != 1 {
+		t.Fatalf("got %d requests, want 1", len(stub.messages))
+	}
+	if stub.paths[0] != "POST /12345/messages" {
+		t.Errorf("path = %q", stub.paths[0])
+	}
+	if stub.auth[0] != "Bearer token" {
+		t.Errorf("Authorization = %q", stub.auth[0])
+	}
+	msg := stub.messages[0]
+	if msg.MessagingProduct != "whatsapp" || msg.To != "33612345678" || msg.Type != "text" {
+		t.Errorf("unexpected message %+v", msg)
+	}
+	if msg.Text == nil || msg.Text.Body != "Salut" {
+		t.Errorf("unexpected text %+v", msg.Text)
+	}
+}
+
+func TestSendTextSplitsLongMessages(t *testing.T) {
+	stub, client := newStubGraph(t)
+
+	body := strings.Repeat("a", maxTextLength) + strings.Repeat("b", 10)
+	if err := client.SendText(context.Background(), "33612345678", body); err != nil {
+		t.Fatalf("SendText: %v", err)
+	}
+	if len(stub.messages) != 2 {
+		t.Fatalf("got %d requests, want 2", len(stub.messages))
+	}
+	if got := stub.messages[1].Text.Body; got != strings.Repeat("b", 10) {
+		t.Errorf("second part = %q", got)
+	}
+}
+
+func TestSendOTPStripsPlus(t *testing.T) {
+	stub, client := newStubGraph(t)
+
+	if err := client.SendOTP(context.Background(), "+33612345678", "123456"); err != nil {
+		t.Fatalf("SendOTP: %v", err)
+	}
+	msg := stub.messages[0]
+	if msg.To != "33612345678" {
+		t.Errorf("To = %q", msg.To)
+	}
+	if !strings.Contains(msg.Text.Body, "123456") {
+		t.Errorf("code missing from %q", msg.Text.Body)
+	}
+}
+
+func TestMarkRead(t *testing.T) {
+	stub, client := newStubGraph(t)
+
+	if err := client.MarkRead(context.Background(), "wamid.in"); err != nil {
+		t.Fatalf("MarkRead: %v", err)
+	}
+	msg := stub.messages[0]
+	if msg.Status != "read" || msg.MessageID != "wamid.in" || msg.Text != nil {
+		t.Errorf("unexpected message %+v", msg)
+	}
+}
+
+func TestAPIError(t *testing.T) {
+	stub, client := newStubGraph(t)
+	stub.status = http.StatusBadRequest
+	stub.body = `{"error":{"message":"Recipient not allowed","type":"OAuthException","code":131030}}`
+
+	err := client.SendText(context.Background(), "33612345678", "Salut")
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	if !strings.Contains(err.Error(), "131030") || !strings.Contains(err.Error(), "Recipient not allowed") {
+		t.Errorf("error = %v", err)
+	}
+}
+
+func TestSplitTextPrefersLineBreaks(t *testing.T) {
+	parts := splitText("aaaa\nbbb", 6)
+	if len(parts) != 2 || parts[0] != "aaaa\n" || parts[1] != "bbb" {
+		t.Errorf("parts = %q", parts)
+	}
+}
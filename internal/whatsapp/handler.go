@@ -0,0 +1,254 @@
+package whatsapp
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"hash/fnv"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// =============================================
+// WHATSAPP WEBHOOK — chat with Kai over WhatsApp
+// =============================================
+// Public endpoints called by Meta:
+//   GET  /webhooks/whatsapp   subscription handshake
+//   POST /webhooks/whatsapp   inbound messages (X-Hub-Signature-256)
+// The sender is resolved by users.phone_number; text goes
+// through the same Backboard pipeline as /chat/v2/message
+// and the reply is sent back with Client.
+// =============================================
+
+// replyTimeout bounds the handling of one inbound message (the Backboard tool
+// loop alone can take ~55s).
+const replyTimeout = 90 * time.Second
+
+const (
+	msgNotLinked   = "Salut ! Je suis Kai, ton coach Focus 🔥\nPour qu'on discute ici, lie ton numéro dans l'app Focus (Profil → WhatsApp)."
+	msgUnsupported = "Pour l'instant je ne lis que les messages texte. Écris-moi !"
+	msgError       = "Désolé, j'ai un souci technique. Tu peux réessayer ?"
+)
+
+// Responder produces Kai's reply to a message (chat.Handler).
+type Responder interface {
+	Reply(ctx context.Context, userID, content, source string) (string, error)
+}
+
+// Handler holds dependencies
+type Handler struct {
+	db          *pgxpool.Pool
+	client      *Client
+	responder   Responder
+	appSecret   string
+	verifyToken string
+
+	// One conversation turn at a time per sender, so replies stay in order
+	phoneLocks [64]sync.Mutex
+
+	// Replies still running in the background, drained on shutdown
+	inflight sync.WaitGroup
+}
+
+// NewHandler creates a new WhatsApp webhook handler
+func NewHandler(db *pgxpool.Pool, client *Client, responder Responder, cfg Config) *Handler {
+	return &Handler{
+		db:          db,
+		client:      client,
+		responder:   responder,
+		appSecret:   cfg.AppSecret,
+		verifyToken: cfg.VerifyToken,
+	}
+}
+
+// Verify answers Meta's subscription handshake
+// GET /webhooks/whatsapp
+func (h *Handler) Verify(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	if h.verifyToken == "" || q.Get("hub.mode") != "subscribe" ||
+		!hmac.Equal([]byte(q.Get("hub.verify_token")), []byte(h.verifyToken)) {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain")
+	w.Write([]byte(q.Get("hub.challenge")))
+}
+
+// Webhook receives inbound messages. It acknowledges right away (Meta retries
+// slow webhooks) and replies in the background.
+// POST /webhooks/whatsapp
+func (h *Handler) Webhook(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(io.LimitReader(r.Body, 1<<20))
+	if err != nil {
+		http.Error(w, "Failed to read body", http.StatusBadRequest)
+		return
+	}
+	if !h.validSignature(body, r.Header.Get("X-Hub-Signature-256")) {
+		log.Printf("⚠️ WhatsApp webhook with invalid signature from %s", r.RemoteAddr)
+		http.Error(w, "Invalid signature", http.StatusUnauthorized)
+		return
+	}
+
+	var payload webhookPayload
+	if err := json.Unmarshal(body, &payload); err != nil {
+		http.Error(w, "Invalid payload", http.StatusBadRequest)
+		return
+	}
+
+	// A message that could not be recorded is left for Meta's retry; the
+	// ones already claimed are deduplicated then.
+	claimFailed := false
+	for _, entry := range payload.Entry {
+		for _, change := range entry.Changes {
+			if change.Field != "messages" {
+				continue
+			}
+			names := map[string]string{}
+			for _, c := range change.Value.Contacts {
+				names[c.WaID] = c.Profile.Name
+			}
+			// Status updates (sent, delivered, read) come without messages
+			for _, msg := range change.Value.Messages {
+				claimed, err := h.claimMessage(r.Context(), msg)
+				if err != nil {
+					log.Printf("Failed to record WhatsApp message %s: %v", msg.ID, err)
+					claimFailed = true
+					continue
+				}
+				if !claimed {
+					continue
+				}
+				h.inflight.Add(1)
+				go func(msg inboundMessage, name string) {
+					defer h.inflight.Done()
+					h.handleMessage(msg, name)
+				}(msg, names[msg.From])
+			}
+		}
+	}
+
+	if claimFailed {
+		http.Error(w, "Failed to record message", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// Wait blocks until the replies started by Webhook are done or ctx expires.
+// Call it after the HTTP server stopped accepting requests.
+func (h *Handler) Wait(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		h.inflight.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// validSignature checks X-Hub-Signature-256 (HMAC-SHA256 of the raw body
+// with the app secret).
+func (h *Handler) validSignature(body []byte, header string) bool {
+	if h.appSecret == "" {
+		return false
+	}
+	sig, ok := strings.CutPrefix(header, "sha256=")
+	if !ok {
+		return false
+	}
+	got, err := hex.DecodeString(sig)
+	if err != nil {
+		return false
+	}
+	mac := hmac.New(sha256.New, []byte(h.appSecret))
+	mac.Write(body)
+	return hmac.Equal(got, mac.Sum(nil))
+}
+
+// claimMessage records the message ID and reports whether it is new. Meta
+// delivers at least once, so retries are dropped here.
+func (h *Handler) claimMessage(ctx context.Context, msg inboundMessage) (bool, error) {
+	tag, err := h.db.Exec(ctx, `
+		INSERT INTO public.whatsapp_inbound_messages (message_id, phone_number, message_type)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (message_id) DO NOTHING
+	`, msg.ID, e164(msg.From), msg.Type)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() == 1, nil
+}
+
+// handleMessage answers one inbound message.
+func (h *Handler) handleMessage(msg inboundMessage, displayName string) {
+	unlock := h.lockPhone(msg.From)
+	defer unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), replyTimeout)
+	defer cancel()
+
+	if err := h.client.MarkRead(ctx, msg.ID); err != nil {
+		log.Printf("Failed to mark WhatsApp message %s as read: %v", msg.ID, err)
+	}
+
+	userID, err := h.resolveUser(ctx, msg.From)
+	if errors.Is(err, errNotLinked) {
+		if err := h.recordPendingUser(ctx, msg.From, displayName); err != nil {
+			log.Printf("Failed to record WhatsApp pending user: %v", err)
+		}
+		h.send(ctx, msg.From, msgNotLinked)
+		return
+	}
+	if err != nil {
+		log.Printf("❌ WhatsApp user lookup failed: %v", err)
+		h.send(ctx, msg.From, msgError)
+		return
+	}
+
+	if _, err := h.db.Exec(ctx, `
+		UPDATE public.whatsapp_inbound_messages SET user_id = $2 WHERE message_id = $1
+	`, msg.ID, userID); err != nil {
+		log.Printf("Failed to attach WhatsApp message %s to user: %v", msg.ID, err)
+	}
+
+	if msg.Type != "text" || msg.Text == nil || strings.TrimSpace(msg.Text.Body) == "" {
+		h.send(ctx, msg.From, msgUnsupported)
+		return
+	}
+
+	log.Printf("📱 WhatsApp message from user %s", userID)
+	reply, err := h.responder.Reply(ctx, userID, msg.Text.Body, "whatsapp")
+	if err != nil {
+		log.Printf("❌ WhatsApp reply failed for user %s: %v", userID, err)
+		reply = msgError
+	}
+	h.send(ctx, msg.From, reply)
+}
+
+func (h *Handler) send(ctx context.Context, to, text string) {
+	if err := h.client.SendText(ctx, to, text); err != nil {
+		log.Printf("❌ WhatsApp send failed: %v", err)
+	}
+}
+
+// lockPhone serialises the handling of one sender's messages.
+func (h *Handler) lockPhone(waID string) func() {
+	hash := fnv.New32a()
+	hash.Write([]byte(waID))
+	mu := &h.phoneLocks[hash.Sum32()%uint32(len(h.phoneLocks))]
+	mu.Lock()
+	return mu.Unlock
+}
@@ -0,0 +1,102 @@
+package whatsapp
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func sign(secret, body string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(body))
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestValidSignature(t *testing.T) {
+	h := &Handler{appSecret: "secret"}
+	body := `{"object":"whatsapp_business_account"}`
+
+	if !h.validSignature([]byte(body), sign("secret", body)) {
+		t.Error("valid signature rejected")
+	}
+	for name, header := range map[string]string{
+		"wrong secret":  sign("other", body),
+		"missing":       "",
+		"no prefix":     strings.TrimPrefix(sign("secret", body), "sha256="),
+		"not hex":       "sha256=zz",
+		"tampered body": sign("secret", body+" "),
+	} {
+		if h.validSignature([]byte(body), header) {
+			t.Errorf("%s: signature accepted", name)
+		}
+	}
+
+	if (&Handler{}).validSignature([]byte(body), sign("", body)) {
+		t.Error("signature accepted without app secret")
+	}
+}
+
+func TestWebhookRejectsInvalidSignature(t *testing.T) {
+	h := &Handler{appSecret: "secret"}
+	body := `{"object":"whatsapp_business_account","entry":[]}`
+
+	req := httptest.NewRequest(http.MethodPost, "/webhooks/whatsapp", strings.NewReader(body))
+	req.Header.Set("X-Hub-Signature-256", sign("other", body))
+	rec := httptest.NewRecorder()
+	h.Webhook(rec, req)
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("status = %d, want 401", rec.Code)
+	}
+
+	// Signed payload without messages (status updates) needs no database
+	req = httptest.NewRequest(http.MethodPost, "/webhooks/whatsapp", strings.NewReader(body))
+	req.Header.Set("X-Hub-Signature-256", sign("secret", body))
+	rec = httptest.NewRecorder()
+	h.Webhook(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", rec.Code)
+	}
+}
+
+func TestVerify(t *testing.T) {
+	h := &Handler{verifyToken: "verify"}
+
+	rec := httptest.NewRecorder()
+	h.Verify(rec, httptest.NewRequest(http.MethodGet, "/webhooks/whatsapp?hub.mode=subscribe&hub.verify_token=verify&hub.challenge=42", nil))
+	if rec.Code != http.StatusOK || rec.Body.String() != "42" {
+		t.Errorf("got %d %q, want 200 \"42\"", rec.Code, rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	h.Verify(rec, httptest.NewRequest(http.MethodGet, "/webhooks/whatsapp?hub.mode=subscribe&hub.verify_token=nope&hub.challenge=42", nil))
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("status = %d, want 403", rec.Code)
+	}
+}
+
+func TestWaitDrainsReplies(t *testing.T) {
+	h := &Handler{}
+	release := make(chan struct{})
+	h.inflight.Add(1)
+	go func() {
+		defer h.inflight.Done()
+		<-release
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := h.Wait(ctx); err == nil {
+		t.Fatal("Wait returned before the reply finished")
+	}
+
+	close(release)
+	if err := h.Wait(context.Background()); err != nil {
+		t.Fatalf("Wait: %v", err)
+	}
+}
@@ -0,0 +1,47 @@
+package whatsapp
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// errNotLinked is returned when no verified user has the phone number.
+var errNotLinked = errors.New("phone number not linked to a Focus account")
+
+// e164 turns a wa_id ("33612345678") into "+33612345678".
+func e164(waID string) string {
+	if strings.HasPrefix(waID, "+") {
+		return waID
+	}
+	return "+" + waID
+}
+
+// resolveUser returns the user whose verified phone_number is waID. Numbers
+// are stored in E.164, but rows saved without the "+" also match.
+func (h *Handler) resolveUser(ctx context.Context, waID string) (string, error) {
+	var userID string
+	err := h.db.QueryRow(ctx, `
+		SELECT id FROM public.users
+		WHERE phone_number IN ($1, $2) AND phone_verified = true
+		LIMIT 1
+	`, e164(waID), strings.TrimPrefix(waID, "+")).Scan(&userID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", errNotLinked
+	}
+	return userID, err
+}
+
+// recordPendingUser remembers an unknown number so the account can be linked
+// later (see link_whatsapp_account).
+func (h *Handler) recordPendingUser(ctx context.Context, waID, displayName string) error {
+	_, err := h.db.Exec(ctx, `
+		INSERT INTO public.whatsapp_pending_users (phone_number, display_name)
+		VALUES ($1, NULLIF($2, ''))
+		ON CONFLICT (phone_number)
+		DO UPDATE SET display_name = COALESCE(EXCLUDED.display_name, whatsapp_pending_users.display_name)
+	`, e164(waID), displayName)
+	return err
+}
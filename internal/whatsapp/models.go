@@ -0,0 +1,75 @@
+package whatsapp
+
+// =============================================
+// WHATSAPP CLOUD API TYPES
+// =============================================
+
+// webhookPayload is the body Meta posts to the webhook.
+type webhookPayload struct {
+	Object string         `json:"object"` // whatsapp_business_account
+	Entry  []webhookEntry `json:"entry"`
+}
+
+type webhookEntry struct {
+	ID      string          `json:"id"`
+	Changes []webhookChange `json:"changes"`
+}
+
+type webhookChange struct {
+	Field string       `json:"field"` // messages
+	Value webhookValue `json:"value"`
+}
+
+type webhookValue struct {
+	MessagingProduct string           `json:"messaging_product"`
+	Metadata         webhookMetadata  `json:"metadata"`
+	Contacts         []webhookContact `json:"contacts"`
+	Messages         []inboundMessage `json:"messages"`
+}
+
+type webhookMetadata struct {
+	DisplayPhoneNumber string `json:"display_phone_number"`
+	PhoneNumberID      string `json:"phone_number_id"`
+}
+
+type webhookContact struct {
+	WaID    string `json:"wa_id"`
+	Profile struct {
+		Name string `json:"name"`
+	} `json:"profile"`
+}
+
+// inboundMessage is one message sent by a user. Only text is handled.
+type inboundMessage struct {
+	From      string `json:"from"` // wa_id: international number without "+"
+	ID        string `json:"id"`   // wamid.xxx
+	Timestamp string `json:"timestamp"`
+	Type      string `json:"type"` // text, audio, image, interactive, ...
+	Text      *struct {
+		Body string `json:"body"`
+	} `json:"text,omitempty"`
+}
+
+// outboundMessage is the body of POST /{phone_number_id}/messages.
+type outboundMessage struct {
+	MessagingProduct string        `json:"messaging_product"`
+	RecipientType    string        `json:"recipient_type,omitempty"`
+	To               string        `json:"to,omitempty"`
+	Type             string        `json:"type,omitempty"`
+	Text             *outboundText `json:"text,omitempty"`
+	Status           string        `json:"status,omitempty"`     // "read" to mark a message as read
+	MessageID        string        `json:"message_id,omitempty"` // With Status
+}
+
+type outboundText struct {
+	Body string `json:"body"`
+}
+
+// apiErrorResponse is the error body returned by the Graph API.
+type apiErrorResponse struct {
+	Error struct {
+		Message string `json:"message"`
+		Type    string `json:"type"`
+		Code    int    `json:"code"`
+	} `json:"error"`
+}
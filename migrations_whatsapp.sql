@@ -63,7 +63,7 @@ CREATE INDEX IF NOT EXISTS idx_pending_phone ON public.whatsapp_pending_users(ph
 
 -- ==========================================
 -- 6. Cleanup job for expired OTPs
--- Run periodically via cron
+-- Run periodically by the API (users.OTPSweeper)
 -- ==========================================
 -- DELETE FROM public.phone_linking_otps WHERE expires_at < NOW();
 
@@ -0,0 +1,17 @@
+-- Ritual reminders: one row per routine per local day once a reminder is sent.
+-- The unique constraint makes the scheduler idempotent across server instances.
+CREATE TABLE IF NOT EXISTS public.ritual_reminder_deliveries (
+    id            uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    routine_id    uuid NOT NULL REFERENCES public.routines(id) ON DELETE CASCADE,
+    user_id       uuid NOT NULL REFERENCES auth.users(id) ON DELETE CASCADE,
+    reminder_date date NOT NULL,   -- Local date in the user's timezone
+    sent_at       timestamptz NOT NULL DEFAULT now(),
+    UNIQUE (routine_id, reminder_date)
+);
+
+CREATE INDEX IF NOT EXISTS idx_ritual_reminder_deliveries_user ON public.ritual_reminder_deliveries(user_id, reminder_date DESC);
+
+ALTER TABLE public.ritual_reminder_deliveries ENABLE ROW LEVEL SECURITY;
+
+CREATE POLICY "Users can read own ritual reminder deliveries" ON public.ritual_reminder_deliveries
+    FOR SELECT USING (user_id = auth.uid());
@@ -0,0 +1,30 @@
+-- Streak engine: per-day history + freeze tokens
+-- One row per local day (users.timezone) on which the user did something that
+-- counts for the streak, or on which a freeze token covered a missed day.
+
+-- 1. History table
+CREATE TABLE IF NOT EXISTS public.streak_days (
+    id          uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id     uuid NOT NULL REFERENCES auth.users(id) ON DELETE CASCADE,
+    day         date NOT NULL,
+    activities  text[] NOT NULL DEFAULT '{}',            -- chat, task, routine, focus
+    frozen      boolean NOT NULL DEFAULT false,          -- true = missed day covered by a freeze token
+    created_at  timestamptz NOT NULL DEFAULT now(),
+    updated_at  timestamptz NOT NULL DEFAULT now(),
+    UNIQUE(user_id, day)
+);
+
+CREATE INDEX IF NOT EXISTS idx_streak_days_user_day ON public.streak_days(user_id, day DESC);
+
+-- 2. Freeze tokens on the user
+ALTER TABLE public.users
+    ADD COLUMN IF NOT EXISTS streak_freezes int NOT NULL DEFAULT 0;
+
+-- 3. RLS
+ALTER TABLE public.streak_days ENABLE ROW LEVEL SECURITY;
+
+CREATE POLICY "Users can read their streak days" ON public.streak_days
+    FOR SELECT USING (user_id = auth.uid());
+
+CREATE POLICY "Service can manage streak days" ON public.streak_days
+    FOR ALL TO service_role USING (true) WITH CHECK (true);
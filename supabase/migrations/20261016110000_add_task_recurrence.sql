@@ -0,0 +1,22 @@
+-- Recurring tasks (RFC 5545 RRULE subset)
+-- A series is a single "master" row in tasks with recurrence_rule set; its date
+-- is the series start (DTSTART). Occurrences are expanded on read and never
+-- pre-materialised. An occurrence only gets its own row (an override) once it
+-- is edited, completed or rescheduled; skipped occurrences are listed in
+-- recurrence_exdates.
+
+ALTER TABLE public.tasks
+    ADD COLUMN IF NOT EXISTS recurrence_rule      text,
+    ADD COLUMN IF NOT EXISTS recurrence_exdates   date[] NOT NULL DEFAULT '{}',
+    ADD COLUMN IF NOT EXISTS recurrence_parent_id uuid REFERENCES public.tasks(id) ON DELETE CASCADE,
+    ADD COLUMN IF NOT EXISTS recurrence_date      date;   -- Original occurrence date of an override
+
+-- One override per occurrence
+CREATE UNIQUE INDEX IF NOT EXISTS idx_tasks_recurrence_override
+    ON public.tasks(recurrence_parent_id, recurrence_date)
+    WHERE recurrence_parent_id IS NOT NULL;
+
+-- Series lookup when expanding a day/week
+CREATE INDEX IF NOT EXISTS idx_tasks_recurring_series
+    ON public.tasks(user_id, date)
+    WHERE recurrence_rule IS NOT NULL;
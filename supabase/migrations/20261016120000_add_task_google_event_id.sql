@@ -0,0 +1,9 @@
+-- Google Calendar push: the event mirroring a scheduled task
+-- Set by gcalendar.SyncTaskToGoogleCalendar, cleared when the task loses its slot.
+
+ALTER TABLE public.tasks
+    ADD COLUMN IF NOT EXISTS google_event_id text;
+
+CREATE INDEX IF NOT EXISTS idx_tasks_google_event_id
+    ON public.tasks(google_event_id)
+    WHERE google_event_id IS NOT NULL;
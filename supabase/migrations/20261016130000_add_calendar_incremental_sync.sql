@@ -0,0 +1,22 @@
+-- Incremental Google Calendar sync + push notification channels
+-- sync_token: Google's nextSyncToken; NULL forces a full sync
+-- sync_requested_at: set by POST /webhooks/google-calendar, cleared by the sync worker
+-- channel_*: the active events.watch channel, renewed before channel_expires_at
+
+ALTER TABLE public.calendar_providers
+    ADD COLUMN IF NOT EXISTS sync_token          text,
+    ADD COLUMN IF NOT EXISTS sync_requested_at   timestamptz,
+    ADD COLUMN IF NOT EXISTS channel_id          text,
+    ADD COLUMN IF NOT EXISTS channel_resource_id text,
+    ADD COLUMN IF NOT EXISTS channel_token       text,
+    ADD COLUMN IF NOT EXISTS channel_expires_at  timestamptz;
+
+-- Webhook lookup
+CREATE UNIQUE INDEX IF NOT EXISTS idx_calendar_providers_channel
+    ON public.calendar_providers(channel_id)
+    WHERE channel_id IS NOT NULL;
+
+-- Sync queue
+CREATE INDEX IF NOT EXISTS idx_calendar_providers_sync_requested
+    ON public.calendar_providers(sync_requested_at)
+    WHERE sync_requested_at IS NOT NULL;
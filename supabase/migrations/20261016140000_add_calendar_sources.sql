@@ -0,0 +1,77 @@
+-- Calendar sources: the calendars synced inside one provider account
+-- A Google account can expose several calendars (user picks which ones via
+-- is_selected); an ICS subscription or CalDAV collection has exactly one.
+-- Sync tokens and Google push channels are per calendar, so they move here
+-- from calendar_providers.
+
+-- 1. Sources
+CREATE TABLE IF NOT EXISTS public.calendar_sources (
+    id                   uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id              uuid NOT NULL REFERENCES auth.users(id) ON DELETE CASCADE,
+    provider_id          uuid NOT NULL REFERENCES public.calendar_providers(id) ON DELETE CASCADE,
+    external_calendar_id text NOT NULL,                    -- Google calendar ID, ICS/CalDAV URL
+    name                 text,
+    color                text,
+    is_primary           boolean NOT NULL DEFAULT false,
+    is_selected          boolean NOT NULL DEFAULT true,    -- Only selected calendars are synced
+    sync_token           text,
+    channel_id           text,
+    channel_resource_id  text,
+    channel_token        text,
+    channel_expires_at   timestamptz,
+    last_sync_at         timestamptz,
+    last_sync_status     text,
+    last_sync_error      text,
+    created_at           timestamptz NOT NULL DEFAULT now(),
+    updated_at           timestamptz NOT NULL DEFAULT now(),
+    UNIQUE(provider_id, external_calendar_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_calendar_sources_user ON public.calendar_sources(user_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_calendar_sources_channel
+    ON public.calendar_sources(channel_id)
+    WHERE channel_id IS NOT NULL;
+
+-- 2. Existing Google providers: their configured calendar becomes the first source
+INSERT INTO public.calendar_sources (
+    user_id, provider_id, external_calendar_id, is_primary, is_selected,
+    sync_token, channel_id, channel_resource_id, channel_token, channel_expires_at
+)
+SELECT user_id, id, COALESCE(provider_config->>'calendar_id', 'primary'), true, true,
+       sync_token, channel_id, channel_resource_id, channel_token, channel_expires_at
+FROM public.calendar_providers
+WHERE provider_type = 'google'
+ON CONFLICT (provider_id, external_calendar_id) DO NOTHING;
+
+DROP INDEX IF EXISTS public.idx_calendar_providers_channel;
+ALTER TABLE public.calendar_providers
+    DROP COLUMN IF EXISTS sync_token,
+    DROP COLUMN IF EXISTS channel_id,
+    DROP COLUMN IF EXISTS channel_resource_id,
+    DROP COLUMN IF EXISTS channel_token,
+    DROP COLUMN IF EXISTS channel_expires_at;
+
+-- 3. Events belong to a source; the same event ID can appear in two calendars
+ALTER TABLE public.calendar_events
+    ADD COLUMN IF NOT EXISTS source_id uuid REFERENCES public.calendar_sources(id) ON DELETE CASCADE;
+
+UPDATE public.calendar_events ce
+SET source_id = cs.id
+FROM public.calendar_sources cs
+WHERE cs.provider_id = ce.provider_id
+  AND cs.external_calendar_id = ce.external_calendar_id
+  AND ce.source_id IS NULL;
+
+ALTER TABLE public.calendar_events
+    DROP CONSTRAINT IF EXISTS calendar_events_provider_id_external_event_id_key;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_calendar_events_source_event
+    ON public.calendar_events(source_id, external_event_id);
+
+-- 4. RLS
+ALTER TABLE public.calendar_sources ENABLE ROW LEVEL SECURITY;
+
+CREATE POLICY "Users can read their calendar sources" ON public.calendar_sources
+    FOR SELECT USING (user_id = auth.uid());
+
+CREATE POLICY "Service can manage calendar sources" ON public.calendar_sources
+    FOR ALL TO service_role USING (true) WITH CHECK (true);
@@ -0,0 +1,26 @@
+-- Auto-scheduler proposals
+-- A proposed timetable for the unscheduled tasks of one day. Nothing is written
+-- to tasks until the proposal is accepted, which applies every slot at once.
+
+CREATE TABLE IF NOT EXISTS public.schedule_proposals (
+    id          uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id     uuid NOT NULL REFERENCES auth.users(id) ON DELETE CASCADE,
+    date        date NOT NULL,
+    slots       jsonb NOT NULL DEFAULT '[]',             -- [{taskId, timeBlock, scheduledStart, scheduledEnd, ...}]
+    unplaced    jsonb NOT NULL DEFAULT '[]',             -- Tasks that didn't fit anywhere
+    status      text NOT NULL DEFAULT 'pending',         -- pending, accepted, superseded
+    expires_at  timestamptz NOT NULL,
+    accepted_at timestamptz,
+    created_at  timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_schedule_proposals_user_date
+    ON public.schedule_proposals(user_id, date, created_at DESC);
+
+ALTER TABLE public.schedule_proposals ENABLE ROW LEVEL SECURITY;
+
+CREATE POLICY "Users can read their schedule proposals" ON public.schedule_proposals
+    FOR SELECT USING (user_id = auth.uid());
+
+CREATE POLICY "Service can manage schedule proposals" ON public.schedule_proposals
+    FOR ALL TO service_role USING (true) WITH CHECK (true);
@@ -0,0 +1,19 @@
+-- WhatsApp webhook: inbound message log
+-- Meta delivers webhooks at least once; the message ID (wamid) is claimed here
+-- before replying so a retried delivery is not answered twice.
+
+CREATE TABLE IF NOT EXISTS public.whatsapp_inbound_messages (
+    message_id    text PRIMARY KEY,                        -- wamid.xxx
+    phone_number  text NOT NULL,                           -- E.164 sender
+    user_id       uuid REFERENCES auth.users(id) ON DELETE SET NULL,
+    message_type  text,                                    -- text, audio, image, ...
+    received_at   timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_whatsapp_inbound_messages_received
+    ON public.whatsapp_inbound_messages(received_at);
+
+ALTER TABLE public.whatsapp_inbound_messages ENABLE ROW LEVEL SECURITY;
+
+CREATE POLICY "Service can manage WhatsApp messages" ON public.whatsapp_inbound_messages
+    FOR ALL TO service_role USING (true) WITH CHECK (true);
@@ -0,0 +1,16 @@
+-- Phone linking OTPs
+-- otp_code now holds an HMAC-SHA256 of the code keyed with PHONE_OTP_SECRET.
+-- attempts counts wrong guesses; the code is expired once it reaches the limit.
+-- Expired rows are deleted by the API's OTP sweeper.
+
+ALTER TABLE public.phone_linking_otps
+    ADD COLUMN IF NOT EXISTS attempts integer NOT NULL DEFAULT 0;
+
+CREATE INDEX IF NOT EXISTS idx_otp_user_created
+    ON public.phone_linking_otps(user_id, created_at DESC);
+
+-- Codes are hashed but still shouldn't be readable or writable from clients
+DROP POLICY IF EXISTS "Users can manage own OTPs" ON public.phone_linking_otps;
+
+CREATE POLICY "Service can manage phone linking OTPs" ON public.phone_linking_otps
+    FOR ALL TO service_role USING (true) WITH CHECK (true);
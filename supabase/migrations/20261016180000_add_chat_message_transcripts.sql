@@ -0,0 +1,25 @@
+-- Chat transcripts
+-- /chat/v2 turns are stored in chat_messages (user and assistant side) so
+-- history no longer depends on the Backboard thread.
+
+ALTER TABLE public.chat_messages
+    ADD COLUMN IF NOT EXISTS side_effects jsonb NOT NULL DEFAULT '[]';  -- [{type, data}] applied by the app
+
+-- 'simple' config: conversations mix French and English
+ALTER TABLE public.chat_messages
+    ADD COLUMN IF NOT EXISTS search_vector tsvector
+    GENERATED ALWAYS AS (
+        to_tsvector('simple', content || ' ' || COALESCE(voice_transcript, ''))
+    ) STORED;
+
+-- Cursor pagination: (created_at, id) < cursor ORDER BY created_at DESC, id DESC
+CREATE INDEX IF NOT EXISTS idx_chat_messages_user_cursor
+    ON public.chat_messages(user_id, created_at DESC, id DESC);
+
+CREATE INDEX IF NOT EXISTS idx_chat_messages_search
+    ON public.chat_messages USING GIN (search_vector);
+
+-- Set once the user's pre-transcript Backboard thread has been copied into
+-- chat_messages (NULL: not imported yet)
+ALTER TABLE public.users
+    ADD COLUMN IF NOT EXISTS chat_history_imported_at timestamptz;
@@ -0,0 +1,29 @@
+-- AI action audit log
+-- Every write Kai makes through a tool, with full-row snapshots of what it
+-- changed so the user can review it and undo it.
+
+CREATE TABLE IF NOT EXISTS public.ai_actions (
+    id           uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id      uuid NOT NULL REFERENCES auth.users(id) ON DELETE CASCADE,
+    tool         text NOT NULL,                      -- delete_task, update_task, ...
+    arguments    jsonb NOT NULL DEFAULT '{}',        -- Arguments the model sent
+    summary      text NOT NULL DEFAULT '',           -- "Tâche supprimée : Sport"
+    changes      jsonb NOT NULL DEFAULT '[]',        -- [{table, row_id, before, after}] in write order
+    thread_id    text,                               -- Backboard thread (NULL on stateless providers)
+    run_id       text,
+    tool_call_id text,
+    status       text NOT NULL DEFAULT 'applied',    -- applied, undone
+    undone_at    timestamptz,
+    created_at   timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_ai_actions_user_created
+    ON public.ai_actions(user_id, created_at DESC);
+
+ALTER TABLE public.ai_actions ENABLE ROW LEVEL SECURITY;
+
+CREATE POLICY "Users can read their AI actions" ON public.ai_actions
+    FOR SELECT USING (user_id = auth.uid());
+
+CREATE POLICY "Service can manage AI actions" ON public.ai_actions
+    FOR ALL TO service_role USING (true) WITH CHECK (true);
@@ -0,0 +1,35 @@
+-- Tool confirmations
+-- A run paused because Kai called a tool that needs the user's approval
+-- (delete_task, create_tasks_batch, ...). Kept in the database so the
+-- approval still works after a restart; the run resumes with the stored
+-- outputs plus those of the approved calls. Gemini keeps no run on its
+-- side, so its session is stored in provider_state.
+
+CREATE TABLE IF NOT EXISTS public.pending_tool_runs (
+    id             uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id        uuid NOT NULL REFERENCES auth.users(id) ON DELETE CASCADE,
+    provider       text NOT NULL,                     -- backboard, gemini
+    assistant_id   text,
+    thread_id      text,
+    run_id         text NOT NULL,
+    pending_calls  jsonb NOT NULL DEFAULT '[]',       -- [{tool_call, preview}] waiting for approval
+    outputs        jsonb NOT NULL DEFAULT '[]',       -- [{tool_call_id, output}] of the calls already run
+    device_context jsonb,
+    provider_state jsonb,                             -- paused run of providers that keep it in memory (gemini)
+    status         text NOT NULL DEFAULT 'pending',   -- pending, approved, declined, superseded
+    expires_at     timestamptz NOT NULL,
+    resolved_at    timestamptz,
+    created_at     timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_pending_tool_runs_user_pending
+    ON public.pending_tool_runs(user_id, created_at DESC)
+    WHERE status = 'pending';
+
+ALTER TABLE public.pending_tool_runs ENABLE ROW LEVEL SECURITY;
+
+CREATE POLICY "Users can read their pending tool runs" ON public.pending_tool_runs
+    FOR SELECT USING (user_id = auth.uid());
+
+CREATE POLICY "Service can manage pending tool runs" ON public.pending_tool_runs
+    FOR ALL TO service_role USING (true) WITH CHECK (true);
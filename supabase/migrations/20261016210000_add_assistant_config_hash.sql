@@ -0,0 +1,9 @@
+-- Versioned Backboard assistant config
+-- Hash of the config (persona, harsh mode, timezone, prompt, tools) the
+-- user's assistant was created with. When the server computes a different
+-- one, the assistant is updated or replaced with its memories copied.
+-- NULL for assistants created before this column: they get migrated on
+-- the next message.
+
+ALTER TABLE public.users
+    ADD COLUMN IF NOT EXISTS backboard_assistant_config_hash text;
@@ -0,0 +1,40 @@
+-- Proactive coach messages
+-- Kai writes first on a schedule (morning check-in, evening review) and
+-- under triggers (no activity by noon, streak at risk, focus session just
+-- completed). One row per message actually claimed; the unique constraint
+-- makes the engine idempotent across server instances and the rows of a
+-- local day count toward the user's daily cap.
+
+CREATE TABLE IF NOT EXISTS public.proactive_messages (
+    id              uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id         uuid NOT NULL REFERENCES auth.users(id) ON DELETE CASCADE,
+    kind            text NOT NULL,                  -- morning_checkin, no_activity, evening_review, streak_at_risk, focus_completed
+    local_date      date NOT NULL,                  -- Local date in the user's timezone
+    ref             text NOT NULL DEFAULT '',       -- focus_completed: the focus session ID
+    chat_message_id uuid REFERENCES public.chat_messages(id) ON DELETE SET NULL,
+    sent_at         timestamptz NOT NULL DEFAULT now(),
+    UNIQUE (user_id, kind, local_date, ref)
+);
+
+CREATE INDEX IF NOT EXISTS idx_proactive_messages_user_date
+    ON public.proactive_messages(user_id, local_date DESC);
+
+ALTER TABLE public.proactive_messages ENABLE ROW LEVEL SECURITY;
+
+CREATE POLICY "Users can read their proactive messages" ON public.proactive_messages
+    FOR SELECT USING (user_id = auth.uid());
+
+CREATE POLICY "Service can manage proactive messages" ON public.proactive_messages
+    FOR ALL TO service_role USING (true) WITH CHECK (true);
+
+-- Per-user settings: quiet hours are local "HH:MM", like routines.scheduled_time.
+-- Opt-in: nobody gets messages until they turn them on in the app.
+ALTER TABLE public.users
+    ADD COLUMN IF NOT EXISTS proactive_enabled boolean NOT NULL DEFAULT false,
+    ADD COLUMN IF NOT EXISTS quiet_hours_start text NOT NULL DEFAULT '22:00',
+    ADD COLUMN IF NOT EXISTS quiet_hours_end text NOT NULL DEFAULT '08:00',
+    ADD COLUMN IF NOT EXISTS proactive_daily_cap int NOT NULL DEFAULT 3;
+
+CREATE INDEX IF NOT EXISTS idx_users_proactive_enabled
+    ON public.users(id)
+    WHERE proactive_enabled;
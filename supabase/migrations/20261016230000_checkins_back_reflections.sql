@@ -0,0 +1,67 @@
+-- Morning check-ins and evening reviews
+-- morning_checkins and evening_checkins become the single store for the
+-- daily check-ins: the /checkins API, the chat tools and the /reflections
+-- API (docs/reflections.md) all read and write them. Tables are created
+-- here for databases that never ran migrations_v2.sql.
+
+CREATE TABLE IF NOT EXISTS public.morning_checkins (
+    id            uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id       uuid NOT NULL REFERENCES auth.users(id) ON DELETE CASCADE,
+    date          date NOT NULL DEFAULT CURRENT_DATE,
+    morning_mood  integer NOT NULL CHECK (morning_mood BETWEEN 1 AND 5),
+    sleep_quality integer CHECK (sleep_quality BETWEEN 1 AND 5),
+    intentions    jsonb DEFAULT '[]'::jsonb,            -- Array of intention strings
+    top_priority  text,
+    energy_level  integer CHECK (energy_level BETWEEN 1 AND 5),
+    created_at    timestamptz DEFAULT now(),
+    updated_at    timestamptz DEFAULT now(),
+    UNIQUE (user_id, date)
+);
+
+CREATE TABLE IF NOT EXISTS public.evening_checkins (
+    id                uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id           uuid NOT NULL REFERENCES auth.users(id) ON DELETE CASCADE,
+    date              date NOT NULL DEFAULT CURRENT_DATE,
+    evening_mood      integer CHECK (evening_mood BETWEEN 1 AND 5),
+    biggest_win       text,
+    blockers          text,                               -- "challenges" in the reflections API
+    rituals_completed integer DEFAULT 0,                  -- Snapshot of the day when saved
+    tasks_completed   integer DEFAULT 0,
+    focus_minutes     integer DEFAULT 0,
+    goal_for_tomorrow text,
+    grateful_for      text,
+    created_at        timestamptz DEFAULT now(),
+    updated_at        timestamptz DEFAULT now(),
+    UNIQUE (user_id, date)
+);
+
+-- Reflections are written without a mood, chat check-ins without sleep
+ALTER TABLE public.evening_checkins ALTER COLUMN evening_mood DROP NOT NULL;
+ALTER TABLE public.morning_checkins ALTER COLUMN sleep_quality DROP NOT NULL;
+ALTER TABLE public.evening_checkins ADD COLUMN IF NOT EXISTS best_moment text;
+
+CREATE INDEX IF NOT EXISTS idx_morning_checkins_user_date ON public.morning_checkins(user_id, date DESC);
+CREATE INDEX IF NOT EXISTS idx_evening_checkins_user_date ON public.evening_checkins(user_id, date DESC);
+
+ALTER TABLE public.morning_checkins ENABLE ROW LEVEL SECURITY;
+ALTER TABLE public.evening_checkins ENABLE ROW LEVEL SECURITY;
+
+DROP POLICY IF EXISTS "Users can manage own morning_checkins" ON public.morning_checkins;
+CREATE POLICY "Users can manage own morning_checkins" ON public.morning_checkins
+    USING (auth.uid() = user_id);
+
+DROP POLICY IF EXISTS "Users can manage own evening_checkins" ON public.evening_checkins;
+CREATE POLICY "Users can manage own evening_checkins" ON public.evening_checkins
+    USING (auth.uid() = user_id);
+
+-- Carry over entries written to daily_reflections
+DO $$
+BEGIN
+    IF to_regclass('public.daily_reflections') IS NOT NULL THEN
+        INSERT INTO public.evening_checkins (user_id, date, biggest_win, blockers, best_moment, goal_for_tomorrow, created_at)
+        SELECT user_id, date, biggest_win, challenges, best_moment, goal_for_tomorrow, created_at
+        FROM public.daily_reflections
+        WHERE COALESCE(biggest_win, challenges, best_moment, goal_for_tomorrow) IS NOT NULL
+        ON CONFLICT (user_id, date) DO NOTHING;
+    END IF;
+END $$;
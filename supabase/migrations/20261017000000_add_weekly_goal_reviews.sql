@@ -0,0 +1,18 @@
+-- Weekly goals review
+-- The end-of-week review records how the week went on weekly_goals and
+-- rolls the unfinished items into the next week. A rolled-over item points
+-- at the item it was copied from, so a review can run twice safely.
+
+-- 1. Review results on the week
+ALTER TABLE public.weekly_goals
+    ADD COLUMN IF NOT EXISTS reviewed_at     timestamptz,
+    ADD COLUMN IF NOT EXISTS items_total     int,
+    ADD COLUMN IF NOT EXISTS items_completed int,
+    ADD COLUMN IF NOT EXISTS completion_rate int;        -- % of items completed (0-100)
+
+-- 2. Rolled-over items
+ALTER TABLE public.weekly_goal_items
+    ADD COLUMN IF NOT EXISTS carried_over_from uuid REFERENCES public.weekly_goal_items(id) ON DELETE SET NULL;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_weekly_goal_items_carried_over
+    ON public.weekly_goal_items(carried_over_from) WHERE carried_over_from IS NOT NULL;